@@ -18,12 +18,16 @@ package main
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"fmt"
+	"math/big"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"strings"
-	"sync"
+	"syscall"
+	"time"
 
 	challenger "github.com/chronicleprotocol/challenger/core"
 	logger "github.com/sirupsen/logrus"
@@ -36,6 +40,12 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 const (
@@ -48,16 +58,120 @@ type options struct {
 	Password        string
 	PasswordFile    string
 	RpcURL          string
+	FallbackRpcURLs []string
+	MaxBlockDrift   time.Duration
+	StaleHeadAfter  time.Duration
+	HedgeAfter      time.Duration
 	FlashbotRPCURL  string
+	FlashbotBlocks  uint64
+	BundleDelay     uint64
+	BatchMulticall  string
+	BatchWindow     time.Duration
+	BundlerURL      string
+	SmartAccount    string
+	EntryPoint      string
+	PaymasterURL    string
+	PaymasterData   string
+	UserOpTip       string
+	SafeAddress     string
+	SafeServiceURL  string
+	SafeAPIKey      string
+	SafeAlertBefore time.Duration
+	PendingFile     string
+	CheckpointFile  string
+	AuditLogFile    string
 	Address         []string
 	FromBlock       int64
+	ToBlock         int64
+	Once            bool
 	ChainID         uint64
+	Chain           string
+	BlockTime       time.Duration
+	SequencerFeed   string
+	SequencerLag    time.Duration
+	GasSymbol       string
+	GasDecimals     uint8
+	MinBalance      string
+	DuplicateCheck  bool
+	DuplicateRedis  string
+	DuplicateKey    string
+	ChallengeTag    string
 	TransactionType string
+	GasMultiplier   float64
+	TipMultiplier   float64
+	MaxGasPrice     string
+	MaxTip          string
+	FlashbotTxType  string
+	FlashbotGasMul  float64
+	FlashbotTipMul  float64
+	FlashbotMaxGas  string
+	FlashbotMaxTip  string
+	GasOracle       string
+	GasOracleKey    string
+	GasOracleURL    string
+	GasOraclePrice  string
+	GasOracleTip    string
+	GasOraclePctl   float64
 	MetricsAddr     string
 	LogLevel        string
+	LogSampling     []string
+	VerifyWorkers   int
+	VerifyTimeout   time.Duration
+	Confirmations   uint64
+	MinPokeAge      uint64
+	ValidateAge     bool
+	MaxStaleness    time.Duration
+	PriceRefs       []string
+	DeviationLimit  float64
+	WatchPokes      bool
+	WatchState      bool
+	NonceRepair     string
+	NonceGapBlocks  uint64
+	FallbackGas     uint64
+	MempoolRpcURL   string
+	ArchiveRpcURL   string
+	ArchiveBlockAge uint64
+	PrivateOnly     bool
+	SubmitJitter    time.Duration
+	TickJitter      time.Duration
+	FastTick        time.Duration
+	Watchdog        int
+	ExpiryAlert     float64
+	HeartbeatAfter  time.Duration
+	MaxPerHour      int
+	MaxSpendPerDay  string
+	CodeHashes      []string
+	VerifyAtPoke    bool
+	Reverify        bool
+	InstanceLabel   string
+	UserAgent       string
+	EventsToken     string
+	AdminToken      string
+	ForwarderAddr   string
+	ForwarderMethod string
+	ForwarderArgs   []string
+	SweepTo         string
+	SweepThreshold  string
+	KeeperMode      bool
+	ReadOnly        bool
+	KeeperWebhook   string
+	ChallengeExec   string
+	ChallengeArgs   []string
+	ExecTimeout     time.Duration
+	OTLPEndpoint    string
+	LeaderRedisURL  string
+	LeaderKey       string
+	LeaderLease     time.Duration
+	InstanceID      string
+	TxTimeout       time.Duration
+	TxPollInterval  time.Duration
+	ForkRpcURL      string
+	AnvilPath       string
+	LockRedisURL    string
+	LockPrefix      string
+	RPCConfig       string
 }
 
-
 // Checks and return private key based on given options
 func (o *options) getKey() (*wallet.PrivateKey, error) {
 	if o.SecretKey != "" {
@@ -93,10 +207,336 @@ func (o *options) getKey() (*wallet.PrivateKey, error) {
 	return wallet.NewKeyFromJSON(o.Key, password)
 }
 
+// Parses list of ScribeOptimistic contract addresses
+func (o *options) getAddresses() ([]types.Address, error) {
+	if len(o.Address) == 0 {
+		return nil, fmt.Errorf("please provide address using `--addresses` flag")
+	}
+	var addresses []types.Address
+	for _, address := range o.Address {
+		a, err := types.AddressFromHex(address)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse given address %s with error: %v", address, err)
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, nil
+}
+
+// Fills options not set explicitly by flags with the preset of the chain selected by --chain
+func (o *options) applyChainPreset(fs *pflag.FlagSet) error {
+	if o.Chain == "" {
+		return nil
+	}
+	preset, err := challenger.LookupChainPreset(o.Chain)
+	if err != nil {
+		return err
+	}
+	unset := func(name string) bool {
+		f := fs.Lookup(name)
+		return f != nil && !f.Changed
+	}
+	if unset("chain-id") {
+		o.ChainID = preset.ChainID
+	}
+	if unset("block-time") {
+		o.BlockTime = preset.BlockTime
+	}
+	if unset("confirmations") {
+		o.Confirmations = preset.Confirmations
+	}
+	if unset("tx-type") {
+		o.TransactionType = preset.TxType
+	}
+	if unset("flashbot-rpc-url") && preset.FlashbotRPCURL != "" {
+		o.FlashbotRPCURL = preset.FlashbotRPCURL
+	}
+	if unset("fallback-gas-limit") && preset.FallbackGasLimit != 0 {
+		o.FallbackGas = preset.FallbackGasLimit
+	}
+	if unset("gas-token-symbol") {
+		o.GasSymbol = preset.GasToken.Symbol
+	}
+	if unset("gas-token-decimals") {
+		o.GasDecimals = preset.GasToken.Decimals
+	}
+	logger.
+		WithField("chainId", o.ChainID).
+		WithField("blockTime", o.BlockTime).
+		Infof("Using %s chain preset", o.Chain)
+	return nil
+}
+
+// Returns basic transaction modifiers for configured chain id
+func (o *options) getTxModifiers() ([]rpc.TXModifier, error) {
+	txModifiers := []rpc.TXModifier{
+		txmodifier.NewNonceProvider(txmodifier.NonceProviderOptions{
+			UsePendingBlock: false,
+			Replace:         false,
+		}),
+	}
+	// Chain ID validation
+	if o.ChainID != 0 {
+		txModifiers = append(txModifiers, txmodifier.NewChainIDProvider(txmodifier.ChainIDProviderOptions{
+			ChainID: o.ChainID,
+			Replace: false,
+			Cache:   true,
+		}))
+	}
+	return txModifiers, nil
+}
+
+// Returns gas fee options of the public mempool and flashbots paths
+func (o *options) getGasConfig() (challenger.GasConfig, error) {
+	cfg := challenger.GasConfig{
+		Public: challenger.GasOptions{
+			TxType:                o.TransactionType,
+			GasPriceMultiplier:    o.GasMultiplier,
+			PriorityFeeMultiplier: o.TipMultiplier,
+		},
+		Flashbots: challenger.GasOptions{
+			TxType:                o.FlashbotTxType,
+			GasPriceMultiplier:    o.FlashbotGasMul,
+			PriorityFeeMultiplier: o.FlashbotTipMul,
+		},
+	}
+	var err error
+	for _, limit := range []struct {
+		value string
+		dest  **big.Int
+	}{
+		{o.MaxGasPrice, &cfg.Public.MaxGasPrice},
+		{o.MaxTip, &cfg.Public.MaxPriorityFeePerGas},
+		{o.FlashbotMaxGas, &cfg.Flashbots.MaxGasPrice},
+		{o.FlashbotMaxTip, &cfg.Flashbots.MaxPriorityFeePerGas},
+	} {
+		if *limit.dest, err = parseWei(limit.value); err != nil {
+			return cfg, err
+		}
+	}
+	return cfg, nil
+}
+
+// Returns the gas oracle selected by --gas-oracle for the client sending through the transport,
+// nil if fees are suggested by the node
+func (o *options) getGasOracle(t transport.Transport) (challenger.GasOracle, error) {
+	cfg := challenger.GasOracleConfig{
+		Name:       o.GasOracle,
+		Percentile: o.GasOraclePctl,
+		APIKey:     o.GasOracleKey,
+		URL:        o.GasOracleURL,
+		ChainID:    o.ChainID,
+	}
+	var err error
+	if cfg.GasPrice, err = parseWei(o.GasOraclePrice); err != nil {
+		return nil, err
+	}
+	if cfg.PriorityFeePerGas, err = parseWei(o.GasOracleTip); err != nil {
+		return nil, err
+	}
+	return cfg.GasOracle(t)
+}
+
+// Parses amount in wei, empty string means no amount
+func parseWei(value string) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	amount, ok := new(big.Int).SetString(value, 10)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid amount %q, has to be amount in wei", value)
+	}
+	return amount, nil
+}
+
+// Returns transaction modifiers of a client sending with the given fee options.
+// maxGas limits the estimated gas limit, 0 means no limit.
+func newTxModifiers(base []rpc.TXModifier, gas challenger.GasOptions, maxGas uint64, fallbackGas uint64) ([]rpc.TXModifier, error) {
+	txModifiers := append([]rpc.TXModifier{}, base...)
+	feeEstimator, err := gas.TxModifier()
+	if err != nil {
+		return nil, err
+	}
+	if feeEstimator != nil {
+		txModifiers = append(txModifiers, feeEstimator)
+	}
+	gasLimitEstimator := txmodifier.NewGasLimitEstimator(txmodifier.GasLimitEstimatorOptions{
+		MaxGas:     maxGas,
+		Multiplier: defaultGasLimitMultiplier,
+		Replace:    false,
+	})
+	return append(txModifiers, challenger.NewGasLimitFallback(gasLimitEstimator, fallbackGas)), nil
+}
+
+// Returns provider options, e.g. routing challenges through forwarder contract.
+// Challenges are sent from the address of the current key, so the provider doesn't need to ask the node for it.
+func (o *options) getProviderOptions(keys *challenger.KeyRing) ([]challenger.ProviderOption, error) {
+	providerOpts := []challenger.ProviderOption{challenger.WithKeyRing(keys)}
+	if o.ForwarderAddr != "" {
+		forwarderAddr, err := types.AddressFromHex(o.ForwarderAddr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse forwarder address %s with error: %v", o.ForwarderAddr, err)
+		}
+		forwarder, err := challenger.NewForwarderConfig(forwarderAddr, o.ForwarderMethod, o.ForwarderArgs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid forwarder configuration: %v", err)
+		}
+		providerOpts = append(providerOpts, challenger.WithForwarder(forwarder))
+	}
+	if o.VerifyAtPoke {
+		providerOpts = append(providerOpts, challenger.WithPokeBlockVerification())
+	}
+	tag, err := o.getChallengeTag()
+	if err != nil {
+		return nil, fmt.Errorf("invalid challenge tag: %v", err)
+	}
+	if tag != nil {
+		providerOpts = append(providerOpts, challenger.WithChallengeTag(tag))
+	}
+	return providerOpts, nil
+}
+
+// Returns the tag appended to opChallenge calldata from --challenge-tag, nil if not provided
+func (o *options) getChallengeTag() ([]byte, error) {
+	if o.ChallengeTag == "" {
+		return nil, nil
+	}
+	return challenger.NewChallengeTag(o.ChallengeTag)
+}
+
+// Returns transport configurations of RPC endpoints from --rpc-config, empty if not provided
+func (o *options) getTransportConfigs() (challenger.TransportConfigs, error) {
+	if o.RPCConfig == "" {
+		return challenger.TransportConfigs{}, nil
+	}
+	return challenger.LoadTransportConfigs(o.RPCConfig)
+}
+
+// Creates JSON-RPC client signing transactions with keys of the ring, fees are suggested by --gas-oracle.
+// maxGas limits the estimated gas limit, 0 means no limit.
+// Websocket (ws://, wss://) and IPC (ipc://) URLs enable new heads subscription for faster confirmations.
+func (o *options) newRPCClient(
+	ctx context.Context,
+	url string,
+	cfg challenger.TransportConfig,
+	keys *challenger.KeyRing,
+	base []rpc.TXModifier,
+	gas challenger.GasOptions,
+	maxGas uint64,
+	fallbackGas uint64,
+) (*challenger.SigningClient, error) {
+	t, err := challenger.NewTransport(ctx, url, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create transport: %v", err)
+	}
+	if gas.Oracle, err = o.getGasOracle(t); err != nil {
+		return nil, fmt.Errorf("invalid gas oracle: %v", err)
+	}
+	txModifiers, err := newTxModifiers(base, gas, maxGas, fallbackGas)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gas configuration: %v", err)
+	}
+	c, err := rpc.NewClient(
+		rpc.WithTransport(t),
+		rpc.WithTXModifiers(txModifiers...),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return challenger.NewSigningClient(c, keys), nil
+}
+
+// Verifies RPC endpoints serve the chain of expected ID, or the one of the first reachable endpoint if it's 0,
+// so challenges are not sent to the wrong network. Unreachable endpoints are verified once they are active.
+// Returns the verified chain ID, 0 if no endpoint is reachable.
+func verifyChainID(ctx context.Context, expected uint64, endpoints []challenger.RPCEndpoint) (uint64, error) {
+	for _, e := range endpoints {
+		if expected == 0 {
+			chainID, err := e.Client.ChainID(ctx)
+			if err != nil {
+				logger.WithField("endpoint", e.Name).Warnf("Failed to get chain ID: %v", err)
+				continue
+			}
+			logger.WithField("endpoint", e.Name).Infof("Using chain ID %d of the RPC endpoint", chainID)
+			expected = chainID
+			continue
+		}
+		err := challenger.CheckChainID(ctx, e.Client, expected)
+		if errors.Is(err, challenger.ErrChainMismatch) {
+			return 0, fmt.Errorf("RPC endpoint %s: %w", e.Name, err)
+		}
+		if err != nil {
+			logger.WithField("endpoint", e.Name).Warnf("Failed to verify chain ID, it's verified once the endpoint is active: %v", err)
+		}
+	}
+	return expected, nil
+}
+
+// Verifies nothing needing the key or submitting challenges is configured in read-only mode.
+func (o *options) checkReadOnly() error {
+	conflicts := []struct {
+		flag string
+		set  bool
+	}{
+		{"--sweep-to", o.SweepTo != ""},
+		{"--nonce-repair", o.NonceRepair != ""},
+		{"--duplicate-check", o.DuplicateCheck},
+		{"--batch-multicall-address", o.BatchMulticall != ""},
+		{"--bundler-url", o.BundlerURL != ""},
+		{"--safe-address", o.SafeAddress != ""},
+		{"--keeper-mode", o.KeeperMode},
+		{"--challenge-exec", o.ChallengeExec != ""},
+		{"--admin-token", o.AdminToken != ""},
+		{"--leader-election-redis", o.LeaderRedisURL != ""},
+		{"--challenge-lock-redis", o.LockRedisURL != ""},
+	}
+	for _, c := range conflicts {
+		if c.set {
+			return fmt.Errorf("read-only mode can't be combined with `%s`", c.flag)
+		}
+	}
+	return nil
+}
+
+// Returns unique id of this instance, defaults to hostname
+func (o *options) getInstanceID() (string, error) {
+	if o.InstanceID != "" {
+		return o.InstanceID, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get hostname, please provide `--instance-id` flag: %v", err)
+	}
+	return hostname, nil
+}
+
+// Configures global tracer provider exporting spans via OTLP over HTTP.
+// Returned function flushes pending spans and has to be called on shutdown.
+func setupTracing(ctx context.Context, endpointURL string) (func(), error) {
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpointURL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "challenger"))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return func() {
+		if err := tp.Shutdown(context.Background()); err != nil {
+			logger.WithError(err).Error("tracer provider shutdown error")
+		}
+	}, nil
+}
+
 func main() {
 	var opts options
-	cmd := &cobra.Command{
+	// Exit status set by commands, the process exits with it once deferred cleanup of the command ran.
+	exitStatus := exitClean
+	runCmd := &cobra.Command{
 		Use:     "run",
+		Short:   "Monitors ScribeOptimistic contracts and challenges invalid pokes",
 		Args:    cobra.NoArgs,
 		Aliases: []string{"agent"},
 		Run: func(cmd *cobra.Command, args []string) {
@@ -105,169 +545,701 @@ func main() {
 				logger.Fatalf("Invalid log level %q: %v", opts.LogLevel, err)
 			}
 			logger.SetLevel(lvl)
+			if err := challenger.ParseLogSampling(opts.LogSampling); err != nil {
+				logger.Fatalf("Invalid log sampling: %v", err)
+			}
+			if err := opts.applyChainPreset(cmd.Flags()); err != nil {
+				logger.Fatalf("Invalid chain: %v", err)
+			}
+			challenger.NativeToken = challenger.GasToken{Symbol: opts.GasSymbol, Decimals: opts.GasDecimals}
+			challenger.UserAgent = opts.UserAgent
+			challengeTag, err := opts.getChallengeTag()
+			if err != nil {
+				logger.Fatalf("Invalid challenge tag: %v", err)
+			}
+
+			if opts.TxTimeout <= 0 || opts.TxPollInterval <= 0 {
+				logger.Fatalf("Transaction confirmation timeout and poll interval have to be positive")
+			}
+			challenger.TxConfirmationTimeout = opts.TxTimeout
+			challenger.TxConfirmationPollInterval = opts.TxPollInterval
+			if opts.FlashbotBlocks == 0 {
+				logger.Fatalf("Flashbots inclusion blocks have to be positive")
+			}
 
 			logger.Debugf("Hello, Challenger!")
 
+			buildInfo := challenger.GetBuildInfo()
+			logger.
+				WithField("version", buildInfo.Version).
+				WithField("commit", buildInfo.Commit).
+				WithField("buildDate", buildInfo.BuildDate).
+				WithField("abiHash", buildInfo.ABIHash).
+				Infof("Starting challenger")
+
 			if opts.RpcURL == "" {
 				logger.Errorf("Please provide Rpc URL using `--rpc-url` flag")
 				return
 			}
 
 			// Parsing list of addresses
-			if len(opts.Address) == 0 {
-				logger.Errorf("Please provide address using `--addresses` flag")
+			addresses, err := opts.getAddresses()
+			if err != nil {
+				logger.Errorf("%v", err)
 				return
 			}
-			var addresses []types.Address
-			for _, address := range opts.Address {
-				a, err := types.AddressFromHex(address)
+
+			// Building context, cancelled on shutdown signals
+			ctx, ctxCancel := signal.NotifyContext(cmd.Context(), shutdownSignals...)
+			defer ctxCancel()
+
+			// Tracing
+			if opts.OTLPEndpoint != "" {
+				shutdown, err := setupTracing(ctx, opts.OTLPEndpoint)
 				if err != nil {
-					logger.Errorf("Failed to parse given address %s with error: %v", address, err)
-					return
+					logger.Fatalf("Failed to setup tracing: %v", err)
 				}
-				addresses = append(addresses, a)
+				defer shutdown()
 			}
 
-			// Building context
-			ctx := cmd.Context()
-			var ctxCancel context.CancelFunc
-
-			if ctx == nil {
-				ctx, ctxCancel = signal.NotifyContext(context.Background(), os.Interrupt)
-				defer ctxCancel()
+			// Key generation, transactions are signed with the current key of the ring, it can be rotated.
+			// In read-only mode there is no key, invalid pokes are only reported.
+			var key *wallet.PrivateKey
+			keys := challenger.NewKeyRing(nil)
+			if opts.ReadOnly {
+				if err := opts.checkReadOnly(); err != nil {
+					logger.Fatalf("%v", err)
+				}
+				if opts.Key != "" || opts.SecretKey != "" {
+					logger.Warnf("Key is ignored in read-only mode")
+				}
+				logger.Warnf("Running in read-only mode without a key, no challenge transactions are sent")
+			} else {
+				key, err = opts.getKey()
+				if err != nil {
+					logger.Fatalf("Failed to get private key: %v, use `--read-only` to run without a key", err)
+				}
+				keys = challenger.NewKeyRing(key)
 			}
 
-			// Key generation
-			key, err := opts.getKey()
+			// Basic TX modifiers
+			txModifiers, err := opts.getTxModifiers()
 			if err != nil {
-				logger.Fatalf("Failed to get private key: %v", err)
+				logger.Fatalf("%v", err)
+			}
+			gasConfig, err := opts.getGasConfig()
+			if err != nil {
+				logger.Fatalf("Invalid gas configuration: %v", err)
 			}
 
-			// Basic TX modifiers
-			txModifiers := []rpc.TXModifier{
-				txmodifier.NewNonceProvider(txmodifier.NonceProviderOptions{
-					UsePendingBlock: false,
-					Replace:         false,
-				}),
-			}
-			// Chain ID validation
-			if opts.ChainID != 0 {
-				txModifiers = append(txModifiers, txmodifier.NewChainIDProvider(txmodifier.ChainIDProviderOptions{
-					ChainID: opts.ChainID,
-					Replace: false,
-					Cache:   true,
-				}))
-			}
-
-			switch opts.TransactionType {
-			case "legacy":
-				txModifiers = append(txModifiers, txmodifier.NewLegacyGasFeeEstimator(txmodifier.LegacyGasFeeEstimatorOptions{
-					Multiplier:  1,
-					MinGasPrice: nil,
-					MaxGasPrice: nil,
-					Replace:     false,
-				}))
-			case "eip1559":
-				txModifiers = append(txModifiers, txmodifier.NewEIP1559GasFeeEstimator(txmodifier.EIP1559GasFeeEstimatorOptions{
-					GasPriceMultiplier:          1,
-					PriorityFeePerGasMultiplier: 1,
-					MinGasPrice:                 nil,
-					MaxGasPrice:                 nil,
-					MinPriorityFeePerGas:        nil,
-					MaxPriorityFeePerGas:        nil,
-					Replace:                     false,
-				}))
-			case "", "none":
-				// Do nothing
-			default:
-				logger.Fatalf("Unknown transaction type: %s. Have to be legacy, eip1559 or none", opts.TransactionType)
-			}
-
-			// Create a JSON-RPC client to mainnet.
-			t, err := transport.NewHTTP(transport.HTTPOptions{URL: opts.RpcURL})
+			transportConfigs, err := opts.getTransportConfigs()
 			if err != nil {
-				logger.Fatalf("Failed to create transport: %v", err)
+				logger.Fatalf("Invalid RPC configuration: %v", err)
 			}
 
-			// Set manual gas limit for flashbots, they might require more gas.
-			//nolint:gocritic
-			baseTxModifiers := append(txModifiers, txmodifier.NewGasLimitEstimator(txmodifier.GasLimitEstimatorOptions{
-				MaxGas:     0,
-				Multiplier: defaultGasLimitMultiplier,
-			}))
+			// Create a JSON-RPC client to mainnet and to each alternate endpoint.
+			endpoints := []challenger.RPCEndpoint{}
+			for i, url := range append([]string{opts.RpcURL}, opts.FallbackRpcURLs...) {
+				name := "primary"
+				if i > 0 {
+					name = fmt.Sprintf("fallback-%d", i)
+				}
+				c, err := opts.newRPCClient(ctx, url, transportConfigs.For(name), keys, txModifiers, gasConfig.Public, 0, opts.FallbackGas)
+				if err != nil {
+					logger.Fatalf("Failed to create RPC client: %v", err)
+				}
 
-			clientOptions := []rpc.ClientOptions{
-				rpc.WithTransport(t),
-				rpc.WithKeys(key),
-				rpc.WithDefaultAddress(key.Address()),
-				rpc.WithTXModifiers(baseTxModifiers...),
+				endpoints = append(endpoints, challenger.RPCEndpoint{Name: name, Client: c})
 			}
 
-			client, err := rpc.NewClient(clientOptions...)
+			client, err := challenger.NewFailoverClient(endpoints...)
 			if err != nil {
 				logger.Fatalf("Failed to create RPC client: %v", err)
 			}
+			client.MaxBlockDrift = opts.MaxBlockDrift
+			client.StaleHeadTimeout = opts.StaleHeadAfter
+			client.HedgeDelay = opts.HedgeAfter
+
+			// Refusing to start against the wrong network, endpoints failed over to are verified too
+			chainID, err := verifyChainID(ctx, opts.ChainID, endpoints)
+			if err != nil {
+				logger.Fatalf("Wrong chain: %v", err)
+			}
+			client.ExpectedChainID = chainID
+			if chainID != 0 {
+				err := challenger.CheckDeployed(ctx, client, addresses)
+				if errors.Is(err, challenger.ErrChainMismatch) {
+					logger.Fatalf("Wrong chain %d: %v", chainID, err)
+				}
+				if err != nil {
+					logger.Warnf("Failed to verify contracts are deployed: %v", err)
+				}
+			}
+			var sendTracker *challenger.SendTracker
+			if opts.DuplicateCheck {
+				sendTracker = &challenger.SendTracker{}
+				client.SendTracker = sendTracker
+			}
 
 			// Create a JSON-RPC client to flashbot.
-			var flashbotClient *rpc.Client
+			// Left as nil interface if flashbots relay is not configured.
+			var flashbotClient challenger.RPCClient
 			if opts.FlashbotRPCURL != "" {
-				flashbotTransport, err := transport.NewHTTP(transport.HTTPOptions{URL: opts.FlashbotRPCURL})
+				// Set manual gas limit for flashbots, they might require more gas.
+				// Fees are configured separately, flashbots transactions only pay on inclusion.
+				fc, err := opts.newRPCClient(ctx, opts.FlashbotRPCURL, transportConfigs.For("flashbots"), keys, txModifiers, gasConfig.Flashbots, challenger.MaxFlashbotGasLimit, 0)
 				if err != nil {
-					logger.Fatalf("Failed to create transport: %v", err)
+					logger.Fatalf("Failed to create flashbots RPC client: %v", err)
+				}
+				flashbotClient = fc
+				if sendTracker != nil {
+					flashbotClient = challenger.TrackSends(fc, sendTracker)
 				}
+			}
 
-				// Set manual gas limit for flashbots, they might require more gas.
-				//nolint:gocritic
-				flashbotTxModifiers := append(txModifiers, txmodifier.NewGasLimitEstimator(txmodifier.GasLimitEstimatorOptions{
-					MaxGas:     challenger.MaxFlashbotGasLimit,
-					Multiplier: defaultGasLimitMultiplier,
-					Replace:    false,
-				}))
-
-				// TODO: tx modifiers have to be similar ?
-				flashbotClientOptions := []rpc.ClientOptions{
-					rpc.WithTransport(flashbotTransport),
-					rpc.WithKeys(key),
-					rpc.WithDefaultAddress(key.Address()),
-					rpc.WithTXModifiers(flashbotTxModifiers...),
-				}
-
-				flashbotClient, err = rpc.NewClient(flashbotClientOptions...)
+			// Historical queries go to the archive node, the full node handles the hot path
+			var providerClient challenger.RPCClient = client
+			if opts.ArchiveRpcURL != "" {
+				t, err := challenger.NewTransport(ctx, opts.ArchiveRpcURL, transportConfigs.For("archive"))
 				if err != nil {
-					logger.Fatalf("Failed to create RPC client: %v", err)
+					logger.Fatalf("Failed to create archive transport: %v", err)
 				}
+				archiveClient, err := rpc.NewClient(rpc.WithTransport(t))
+				if err != nil {
+					logger.Fatalf("Failed to create archive client: %v", err)
+				}
+				if _, err := verifyChainID(ctx, chainID, []challenger.RPCEndpoint{{Name: "archive", Client: archiveClient}}); err != nil {
+					logger.Fatalf("Wrong chain: %v", err)
+				}
+				providerClient = challenger.NewArchiveClient(client, archiveClient, opts.ArchiveBlockAge)
 			}
 
-			// Spawning "challenger" for each address
-			var wg sync.WaitGroup
-			for _, address := range addresses {
-				wg.Add(1)
+			// Routing challenges through forwarder contract
+			providerOpts, err := opts.getProviderOptions(keys)
+			if err != nil {
+				logger.Fatalf("%v", err)
+			}
+			var pendingStore challenger.PendingStore
+			if opts.PendingFile != "" {
+				pendingStore = challenger.NewFilePendingStore(opts.PendingFile)
+				providerOpts = append(providerOpts, challenger.WithPendingStore(pendingStore))
+			}
+			if opts.PrivateOnly {
+				if flashbotClient == nil {
+					logger.Fatalf("Private-only mode requires flashbots relay, please provide `--flashbot-rpc-url` flag")
+				}
+				if opts.BatchMulticall != "" {
+					logger.Fatalf("Challenge batching can't be combined with private-only mode")
+				}
+				providerOpts = append(providerOpts, challenger.WithPrivateOnly())
+			}
+			if opts.SubmitJitter > 0 {
+				providerOpts = append(providerOpts, challenger.WithSubmissionJitter(opts.SubmitJitter))
+			}
+			if opts.BundleDelay > 0 && flashbotClient == nil {
+				logger.Fatalf("`--bundle-target-delay` requires flashbots relay, please provide `--flashbot-rpc-url` flag")
+			}
+			providerOpts = append(providerOpts, challenger.WithBundleTarget(challenger.BundleTarget{
+				Delay:  opts.BundleDelay,
+				Blocks: opts.FlashbotBlocks,
+			}))
+
+			challengerOpts := []challenger.ChallengerOption{
+				challenger.WithVerifyConcurrency(opts.VerifyWorkers),
+				challenger.WithVerifyTimeout(opts.VerifyTimeout),
+				challenger.WithConfirmations(opts.Confirmations),
+				challenger.WithMinPokeAge(opts.MinPokeAge),
+				challenger.WithBlockTime(opts.BlockTime),
+				challenger.WithTickJitter(opts.TickJitter),
+				challenger.WithFastTickInterval(opts.FastTick),
+				challenger.WithWatchdog(opts.Watchdog),
+				challenger.WithExpiryAlert(opts.ExpiryAlert),
+			}
+			if opts.ReadOnly {
+				challengerOpts = append(challengerOpts, challenger.WithReadOnly())
+			}
+			// Allowlist of code hashes verified on startup and before each challenge
+			if len(opts.CodeHashes) > 0 {
+				var hashes []types.Hash
+				for _, h := range opts.CodeHashes {
+					hash, err := types.HashFromHex(h, types.PadNone)
+					if err != nil {
+						logger.Fatalf("Invalid `--code-hash` %q: %v", h, err)
+					}
+					hashes = append(hashes, hash)
+				}
+				verifier := challenger.NewCodeVerifier(client, hashes)
+				for _, address := range addresses {
+					hash, err := verifier.Verify(ctx, address)
+					if err != nil {
+						logger.Fatalf("Failed to verify code of %v: %v", address, err)
+					}
+					logger.WithField("address", address).Infof("Code hash %v is allowed", hash)
+				}
+				challengerOpts = append(challengerOpts, challenger.WithCodeVerifier(verifier))
+			}
+			// Budget protecting the wallet from being drained
+			maxSpend, err := parseWei(opts.MaxSpendPerDay)
+			if err != nil {
+				logger.Fatalf("Invalid `--max-challenge-spend-per-day`: %v", err)
+			}
+			if opts.MaxPerHour > 0 || maxSpend != nil {
+				budget := challenger.NewChallengeBudget(opts.MaxPerHour, maxSpend)
+				challengerOpts = append(challengerOpts, challenger.WithChallengeBudget(budget))
+			}
+			// Heartbeat of ticks for systemd watchdog and health endpoint
+			heartbeat := challenger.NewHeartbeat(addresses, opts.HeartbeatAfter)
+			challengerOpts = append(challengerOpts, challenger.WithHeartbeat(heartbeat))
+			// Explanations of poke verifications for auditors
+			explainer := challenger.NewExplainer(challenger.DefaultExplanationsLimit)
+			challengerOpts = append(challengerOpts, challenger.WithExplainer(explainer))
+			// Per-address statistics for quick operational snapshots
+			stats := challenger.NewStats(addresses, challenger.DefaultStatsHistoryLimit)
+			challengerOpts = append(challengerOpts, challenger.WithStats(stats))
+			// Live stream of poke and challenge events
+			var events *challenger.EventStream
+			if opts.EventsToken != "" {
+				events = challenger.NewEventStream(opts.EventsToken)
+				challengerOpts = append(challengerOpts, challenger.WithChallengeHook(events))
+			}
+			if opts.CheckpointFile != "" {
+				checkpoints := challenger.NewFileCheckpointStore(opts.CheckpointFile)
+				challengerOpts = append(challengerOpts, challenger.WithCheckpointStore(checkpoints))
+			}
+			if opts.AuditLogFile != "" {
+				auditLog, err := challenger.OpenAuditLog(opts.AuditLogFile)
+				if err != nil {
+					logger.Fatalf("Failed to open audit log: %v", err)
+				}
+				defer auditLog.Close()
+				challengerOpts = append(challengerOpts, challenger.WithAuditLog(auditLog))
+			}
+			if opts.Reverify {
+				challengerOpts = append(challengerOpts, challenger.WithReverification())
+			}
+			if opts.WatchPokes {
+				challengerOpts = append(challengerOpts, challenger.WithRegularPokeWatching())
+			}
+			if opts.WatchState {
+				challengerOpts = append(challengerOpts, challenger.WithContractStateWatching())
+			}
+			if opts.ValidateAge {
+				challengerOpts = append(challengerOpts, challenger.WithPokeAgeValidation(opts.MaxStaleness))
+			}
+
+			// Sweeping rewards to the cold wallet
+			var sweeper *challenger.RewardSweeper
+			if opts.SweepTo != "" {
+				beneficiary, err := types.AddressFromHex(opts.SweepTo)
+				if err != nil {
+					logger.Fatalf("Failed to parse sweep beneficiary address %s with error: %v", opts.SweepTo, err)
+				}
+				threshold, ok := new(big.Int).SetString(opts.SweepThreshold, 10)
+				if !ok {
+					logger.Fatalf("Invalid sweep threshold %q, has to be amount in wei", opts.SweepThreshold)
+				}
+				sweeper = challenger.NewRewardSweeper(client, key.Address(), beneficiary, threshold)
+				challengerOpts = append(challengerOpts, challenger.WithRewardSweeper(sweeper))
+			}
+
+			// Watching balance of the challenger account
+			minBalance, err := parseWei(opts.MinBalance)
+			if err != nil {
+				logger.Fatalf("Invalid minimum balance: %v", err)
+			}
+			var balanceMonitor *challenger.BalanceMonitor
+			if !opts.ReadOnly {
+				balanceMonitor = challenger.NewBalanceMonitor(client, key.Address(), minBalance)
+				balanceMonitor.Stats = stats
+				go balanceMonitor.Run(ctx)
+			}
+
+			// Detecting transactions stuck in the mempool
+			var nonceMonitor *challenger.NonceMonitor
+			if opts.NonceRepair != "" {
+				nonceMonitor, err = challenger.NewNonceMonitor(client, key.Address(), opts.NonceGapBlocks, opts.NonceRepair)
+				if err != nil {
+					logger.Fatalf("%v", err)
+				}
+				nonceMonitor.Store = pendingStore
+				go nonceMonitor.Run(ctx)
+			}
+
+			// Detecting another instance using the same key
+			var detector *challenger.DuplicateDetector
+			if opts.DuplicateCheck {
+				if opts.LeaderRedisURL != "" || opts.LockRedisURL != "" {
+					logger.Fatalf("Duplicate instance detection can't be combined with leader election or challenge lock")
+				}
+				detector = challenger.NewDuplicateDetector(client, key.Address(), sendTracker)
+				if opts.DuplicateRedis != "" {
+					redisClient, err := challenger.NewRedisClient(opts.DuplicateRedis)
+					if err != nil {
+						logger.Fatalf("Failed to create Redis client: %v", err)
+					}
+					instanceID, err := opts.getInstanceID()
+					if err != nil {
+						logger.Fatalf("Failed to get instance id: %v", err)
+					}
+					detector.Redis, detector.Key, detector.ID = redisClient, opts.DuplicateKey, instanceID
+				}
+				go detector.Run(ctx)
+			}
+
+			// Detecting downtime of the L2 sequencer
+			if opts.SequencerFeed != "" || opts.SequencerLag > 0 {
+				var feed *types.Address
+				if opts.SequencerFeed != "" {
+					address, err := types.AddressFromHex(opts.SequencerFeed)
+					if err != nil {
+						logger.Fatalf("Invalid sequencer uptime feed address: %v", err)
+					}
+					feed = &address
+				}
+				monitor := challenger.NewSequencerMonitor(client, feed, opts.SequencerLag)
+				go monitor.Run(ctx)
+				challengerOpts = append(challengerOpts, challenger.WithSequencerMonitor(monitor))
+			}
+
+			// Exporting challenge payloads for external keeper network
+			if opts.KeeperMode {
+				exporter := challenger.NewPayloadExporter(opts.KeeperWebhook)
+				exporter.Instance = opts.InstanceLabel
+				exporter.Tag = challengeTag
+				http.Handle("/payloads", exporter)
+				challengerOpts = append(challengerOpts, challenger.WithPayloadExporter(exporter))
+			}
+
+			// Delegating challenges to an external command, e.g. a bespoke signing or submission pipeline
+			if opts.ChallengeExec != "" {
+				if opts.KeeperMode || opts.BatchMulticall != "" || opts.BundlerURL != "" || opts.SafeAddress != "" || opts.ForwarderAddr != "" {
+					logger.Fatalf("Challenge command can't be combined with keeper mode, challenge batching, user operations, Safe or forwarder")
+				}
+				executor, err := challenger.NewChallengeExecutor(opts.ChallengeExec, opts.ChallengeArgs)
+				if err != nil {
+					logger.Fatalf("%v", err)
+				}
+				executor.Timeout = opts.ExecTimeout
+				executor.ChainID = chainID
+				executor.Instance = opts.InstanceLabel
+				executor.Tag = challengeTag
+				challengerOpts = append(challengerOpts, challenger.WithChallengeExecutor(executor))
+				logger.Warnf("Challenges are delegated to the external command, no challenge transactions are sent")
+			}
+
+			// Active/standby pair, only the leader submits challenges
+			if opts.LeaderRedisURL != "" {
+				redisClient, err := challenger.NewRedisClient(opts.LeaderRedisURL)
+				if err != nil {
+					logger.Fatalf("Failed to create Redis client: %v", err)
+				}
+				instanceID, err := opts.getInstanceID()
+				if err != nil {
+					logger.Fatalf("Failed to get instance id: %v", err)
+				}
+				elector := challenger.NewRedisLeaderElector(redisClient, opts.LeaderKey, instanceID, opts.LeaderLease)
+				go elector.Run(ctx)
+				challengerOpts = append(challengerOpts, challenger.WithLeaderElector(elector))
+			}
+
+			// Shared lock per poke, so cooperating instances don't challenge the same poke
+			if opts.LockRedisURL != "" {
+				redisClient, err := challenger.NewRedisClient(opts.LockRedisURL)
+				if err != nil {
+					logger.Fatalf("Failed to create Redis client: %v", err)
+				}
+				instanceID, err := opts.getInstanceID()
+				if err != nil {
+					logger.Fatalf("Failed to get instance id: %v", err)
+				}
+				lock := challenger.NewRedisChallengeLock(redisClient, opts.LockPrefix, instanceID)
+				challengerOpts = append(challengerOpts, challenger.WithChallengeLock(lock))
+			}
 
-				p := challenger.NewScribeOptimisticRPCProvider(client, flashbotClient)
-				c := challenger.NewChallenger(ctx, address, p, opts.FromBlock, &wg)
+			// Batching challenges of different addresses into one multicall transaction
+			var batcher *challenger.ChallengeBatcher
+			if opts.BatchMulticall != "" {
+				multicall, err := types.AddressFromHex(opts.BatchMulticall)
+				if err != nil {
+					logger.Fatalf("Failed to parse multicall address %s with error: %v", opts.BatchMulticall, err)
+				}
+				if opts.ForwarderAddr != "" {
+					logger.Fatalf("Challenge batching can't be combined with forwarder")
+				}
+				if err := challenger.ValidateBatchContract(ctx, client, multicall, keys.Address()); err != nil {
+					logger.Fatalf("Challenges can't be batched through %v: %v", multicall, err)
+				}
+				if opts.PendingFile != "" || flashbotClient != nil {
+					logger.Warnf("Batched challenges are sent through --rpc-url only and are not persisted in --pending-file")
+				}
+				logger.
+					WithField("multicall", multicall).
+					Warnf("Challenges are batched, rewards of batched challenges are paid to the multicall contract owned by the challenger account")
+				batcher = challenger.NewChallengeBatcher(ctx, client, multicall, opts.BatchWindow)
+				batcher.Tag = challengeTag
+			}
 
-				go func(addr types.Address) {
-					err := c.Run()
+			// Sending challenges as ERC-4337 user operations of a smart account
+			var userOps *challenger.UserOpSubmitter
+			if opts.BundlerURL != "" {
+				sender, err := types.AddressFromHex(opts.SmartAccount)
+				if err != nil {
+					logger.Fatalf("Failed to parse smart account address %s with error: %v", opts.SmartAccount, err)
+				}
+				entryPoint, err := types.AddressFromHex(opts.EntryPoint)
+				if err != nil {
+					logger.Fatalf("Failed to parse entry point address %s with error: %v", opts.EntryPoint, err)
+				}
+				if batcher != nil || opts.ForwarderAddr != "" {
+					logger.Fatalf("User operations can't be combined with challenge batching or forwarder")
+				}
+				bundler, err := challenger.NewTransport(ctx, opts.BundlerURL, transportConfigs.For("bundler"))
+				if err != nil {
+					logger.Fatalf("Failed to create bundler transport: %v", err)
+				}
+				chainID := opts.ChainID
+				if chainID == 0 {
+					var n types.Number
+					if err := bundler.Call(ctx, &n, "eth_chainId"); err != nil {
+						logger.Fatalf("Failed to get chain id from bundler: %v", err)
+					}
+					chainID = n.Big().Uint64()
+				}
+				userOps = challenger.NewUserOpSubmitter(ctx, client, bundler, key, sender, entryPoint, chainID)
+				userOps.Tag = challengeTag
+				if opts.PaymasterURL != "" {
+					userOps.Paymaster, err = challenger.NewTransport(ctx, opts.PaymasterURL, transportConfigs.For("paymaster"))
 					if err != nil {
-						// Add error to metrics
-						challenger.ErrorsCounter.WithLabelValues(
-							addr.String(),
-							p.GetFrom(ctx).String(),
-						).Inc()
+						logger.Fatalf("Failed to create paymaster transport: %v", err)
+					}
+				}
+				if opts.PaymasterData != "" {
+					userOps.PaymasterAndData, err = types.BytesFromHex(opts.PaymasterData)
+					if err != nil {
+						logger.Fatalf("Failed to parse paymaster data %s with error: %v", opts.PaymasterData, err)
+					}
+				}
+				if opts.UserOpTip != "" {
+					tip, ok := new(big.Int).SetString(opts.UserOpTip, 10)
+					if !ok {
+						logger.Fatalf("Failed to parse user operation priority fee %s", opts.UserOpTip)
+					}
+					userOps.PriorityFee = tip
+				}
+				logger.
+					WithField("smartAccount", sender).
+					WithField("owner", key.Address()).
+					Infof("Challenges are sent as user operations, rewards are paid to the smart account")
+			}
+
+			// Proposing challenges to a Safe for co-signing by its owners
+			var safeProposer *challenger.SafeProposer
+			if opts.SafeAddress != "" {
+				safe, err := types.AddressFromHex(opts.SafeAddress)
+				if err != nil {
+					logger.Fatalf("Failed to parse Safe address %s with error: %v", opts.SafeAddress, err)
+				}
+				if opts.SafeServiceURL == "" {
+					logger.Fatalf("Safe propose-only mode requires Safe Transaction Service, please provide `--safe-tx-service-url` flag")
+				}
+				if batcher != nil || userOps != nil || opts.ForwarderAddr != "" || opts.KeeperMode {
+					logger.Fatalf("Safe propose-only mode can't be combined with challenge batching, user operations, forwarder or keeper mode")
+				}
+				safeProposer = challenger.NewSafeProposer(ctx, client, key, safe, opts.SafeServiceURL)
+				safeProposer.APIKey = opts.SafeAPIKey
+				safeProposer.AlertBefore = opts.SafeAlertBefore
+				safeProposer.Tag = challengeTag
+				logger.
+					WithField("safe", safe).
+					WithField("proposer", key.Address()).
+					Warnf("Challenges are only proposed to the Safe, they have to be confirmed and executed by its owners")
+			}
 
-						logger.Fatalf("Failed to run challenger: %v", err)
+			// Pre-verifying pokes while they are pending in the mempool
+			var watcher *challenger.MempoolWatcher
+			if opts.MempoolRpcURL != "" {
+				t, err := challenger.NewTransport(ctx, opts.MempoolRpcURL, transportConfigs.For("mempool"))
+				if err != nil {
+					logger.Fatalf("Failed to create mempool transport: %v", err)
+				}
+				mempoolClient, err := rpc.NewClient(rpc.WithTransport(t))
+				if err != nil {
+					logger.Fatalf("Failed to create mempool client: %v", err)
+				}
+				if _, err := verifyChainID(ctx, chainID, []challenger.RPCEndpoint{{Name: "mempool", Client: mempoolClient}}); err != nil {
+					logger.Fatalf("Wrong chain: %v", err)
+				}
+				verifier := challenger.NewScribeOptimisticRPCProvider(client, nil)
+				watcher = challenger.NewMempoolWatcher(mempoolClient, verifier, addresses)
+				challengerOpts = append(challengerOpts, challenger.WithMempoolWatcher(watcher))
+			}
+
+			// Comparing poked values with reference prices
+			if len(opts.PriceRefs) > 0 {
+				sources := make(map[types.Address]challenger.PriceSource)
+				for _, ref := range opts.PriceRefs {
+					a, source, ok := strings.Cut(ref, "=")
+					if !ok {
+						logger.Fatalf("Invalid price reference %s, expected ADDRESS=SOURCE", ref)
+					}
+					address, err := types.AddressFromHex(a)
+					if err != nil {
+						logger.Fatalf("Failed to parse price reference address %s with error: %v", a, err)
+					}
+					sources[address], err = challenger.ParsePriceSource(client, source)
+					if err != nil {
+						logger.Fatalf("%v", err)
 					}
-				}(address)
+				}
+				challengerOpts = append(challengerOpts, challenger.WithPriceDeviationMonitor(sources, opts.DeviationLimit))
+			}
+
+			newProvider := func(address types.Address) challenger.IScribeOptimisticProvider {
+				var p challenger.IScribeOptimisticProvider
+				p = challenger.NewScribeOptimisticRPCProvider(providerClient, flashbotClient, providerOpts...)
+				if batcher != nil {
+					p = batcher.Wrap(address, p)
+				}
+				if userOps != nil {
+					p = userOps.Wrap(address, p)
+				}
+				if safeProposer != nil {
+					p = safeProposer.Wrap(address, p)
+				}
+				return p
+			}
+			manager := challenger.NewManager(addresses, newProvider, opts.FromBlock, challengerOpts...)
+			manager.Registerer = challenger.WithInstanceLabel(prometheus.DefaultRegisterer, opts.InstanceLabel)
+
+			if opts.ToBlock != 0 && !opts.Once {
+				logger.Fatalf("`--to-block` requires `--once`")
+			}
+			if opts.ToBlock != 0 {
+				exitStatus = auditRange(ctx, manager, opts.ToBlock)
+				return
+			}
+			if opts.Once {
+				exitStatus = runOnce(ctx, manager)
+				return
+			}
+
+			if watcher != nil {
+				// Invalid poke landed, challenging it right away instead of waiting for the next tick
+				watcher.OnInvalidPokeLanded = func(types.Address) { manager.TriggerTick() }
+				go func() {
+					if err := watcher.Run(ctx); err != nil {
+						logger.Errorf("Mempool watching stopped: %v", err)
+					}
+				}()
+			}
+
+			// Rotating the signing key without restart, the new key is loaded the same way as on startup
+			rotator := challenger.NewKeyRotator(ctx, client, keys, minBalance)
+			rotator.Token = opts.AdminToken
+			rotator.Load = func() (wallet.Key, error) {
+				key, err := opts.getKey()
+				if err != nil {
+					return nil, err
+				}
+				return key, nil
+			}
+			if userOps != nil || safeProposer != nil {
+				// Both sign with the startup key, which is the owner of the smart account or the Safe proposer.
+				rotator.Load = func() (wallet.Key, error) {
+					return nil, fmt.Errorf("key rotation is not supported with user operations or Safe propose-only mode")
+				}
+			}
+			if batcher != nil || opts.ForwarderAddr != "" {
+				// The batch contract is validated to be owned by the startup key, and forwarders may only
+				// accept calls of it, so challenges sent from another key would fail or lose the reward.
+				rotator.Load = func() (wallet.Key, error) {
+					return nil, fmt.Errorf("key rotation is not supported with challenge batching or forwarder")
+				}
 			}
+			if opts.ReadOnly {
+				rotator.Load = func() (wallet.Key, error) {
+					return nil, fmt.Errorf("key rotation is not supported in read-only mode")
+				}
+			}
+			rotator.OnRotate = func(address types.Address) {
+				if balanceMonitor != nil {
+					balanceMonitor.SetFrom(address)
+				}
+				if sweeper != nil {
+					sweeper.SetFrom(address)
+				}
+				if nonceMonitor != nil {
+					nonceMonitor.SetFrom(address)
+				}
+				if detector != nil {
+					detector.SetFrom(address)
+				}
+			}
+
+			// SIGHUP reloads the key, not available on Windows
+			hup := make(chan os.Signal, 1)
+			notifyReloadSignal(hup)
+			defer signal.Stop(hup)
+			go func() {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-hup:
+						logger.Info("Received SIGHUP, reloading key")
+						if _, err := rotator.Reload(ctx); err != nil {
+							logger.Errorf("Failed to rotate key with error: %v", err)
+						}
+					}
+				}
+			}()
 
+			// SIGUSR1 triggers an immediate tick on all addresses, not available on Windows
+			usr1 := make(chan os.Signal, 1)
+			notifyTickSignal(usr1)
+			defer signal.Stop(usr1)
 			go func() {
-				prometheus.MustRegister(
-					challenger.ChallengeCounter,
-					challenger.ErrorsCounter,
-					challenger.LastScannedBlockGauge,
-				)
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-usr1:
+						logger.Info("Received SIGUSR1, triggering tick")
+						manager.TriggerTick()
+					}
+				}
+			}()
+
+			go func() {
+				challenger.BuildInfoGauge.WithLabelValues(
+					buildInfo.Version,
+					buildInfo.Commit,
+					buildInfo.BuildDate,
+					buildInfo.ABIHash,
+					buildInfo.GoVersion,
+				).Set(1)
 				http.Handle("/metrics", promhttp.Handler())
-				srv := &http.Server{Addr: opts.MetricsAddr} //nolint:gosec
+				if docs, err := challenger.NewMetricDocs(opts.InstanceLabel); err != nil {
+					logger.WithError(err).Error("Failed to generate metric docs")
+				} else {
+					http.Handle("/metrics/docs", docs)
+				}
+				http.Handle("/version", buildInfo)
+				http.Handle("/explain", explainer)
+				http.Handle("/health", heartbeat)
+				http.Handle("/stats", stats)
+				http.Handle("/ui", challenger.UI{})
+				if events != nil {
+					http.Handle("/events", events)
+				}
+				if opts.AdminToken != "" {
+					http.Handle("/admin/reload-key", rotator)
+				}
+				srv := &http.Server{ //nolint:gosec
+					Addr: opts.MetricsAddr,
+					// Requests end on shutdown, so event streams don't hold it up.
+					BaseContext: func(net.Listener) context.Context { return ctx },
+				}
 				go func() {
 					<-ctx.Done()
 					if err := srv.Shutdown(context.Background()); err != nil {
@@ -279,25 +1251,365 @@ func main() {
 				}
 			}()
 
-			wg.Wait()
+			go heartbeat.RunWatchdog(ctx)
+			if ok, err := challenger.SdNotify("READY=1"); err != nil {
+				logger.WithError(err).Warn("Failed to notify systemd")
+			} else if ok {
+				go func() {
+					<-ctx.Done()
+					_, _ = challenger.SdNotify("STOPPING=1")
+				}()
+			}
+
+			if err := manager.Run(ctx); err != nil {
+				logger.Fatalf("Failed to run challengers: %v", err)
+			}
+		},
+	}
+
+	selftestCmd := &cobra.Command{
+		Use:   "selftest",
+		Args:  cobra.NoArgs,
+		Short: "Verifies the full challenge path on a fork of the target chain before going live",
+		Run: func(cmd *cobra.Command, args []string) {
+			lvl, err := logger.ParseLevel(opts.LogLevel)
+			if err != nil {
+				logger.Fatalf("Invalid log level %q: %v", opts.LogLevel, err)
+			}
+			logger.SetLevel(lvl)
+			if err := challenger.ParseLogSampling(opts.LogSampling); err != nil {
+				logger.Fatalf("Invalid log sampling: %v", err)
+			}
+			if err := opts.applyChainPreset(cmd.Flags()); err != nil {
+				logger.Fatalf("Invalid chain: %v", err)
+			}
+			challenger.NativeToken = challenger.GasToken{Symbol: opts.GasSymbol, Decimals: opts.GasDecimals}
+			challenger.UserAgent = opts.UserAgent
+
+			if opts.TxTimeout <= 0 || opts.TxPollInterval <= 0 {
+				logger.Fatalf("Transaction confirmation timeout and poll interval have to be positive")
+			}
+			challenger.TxConfirmationTimeout = opts.TxTimeout
+			challenger.TxConfirmationPollInterval = opts.TxPollInterval
+
+			addresses, err := opts.getAddresses()
+			if err != nil {
+				logger.Fatalf("%v", err)
+			}
+			key, err := opts.getKey()
+			if err != nil {
+				logger.Fatalf("Failed to get private key: %v", err)
+			}
+			keys := challenger.NewKeyRing(key)
+			txModifiers, err := opts.getTxModifiers()
+			if err != nil {
+				logger.Fatalf("%v", err)
+			}
+			gasConfig, err := opts.getGasConfig()
+			if err != nil {
+				logger.Fatalf("Invalid gas configuration: %v", err)
+			}
+			providerOpts, err := opts.getProviderOptions(keys)
+			if err != nil {
+				logger.Fatalf("%v", err)
+			}
+
+			ctx, ctxCancel := signal.NotifyContext(cmd.Context(), shutdownSignals...)
+			defer ctxCancel()
+
+			// Forking the target chain, unless fork is provided
+			forkURL := opts.ForkRpcURL
+			stopFork := func() {}
+			if forkURL == "" {
+				if opts.RpcURL == "" {
+					logger.Fatalf("Please provide Rpc URL using `--rpc-url` or `--fork-rpc-url` flag")
+				}
+				logger.Infof("Starting Anvil fork of the target chain")
+				forkURL, stopFork, err = challenger.StartAnvilFork(ctx, opts.AnvilPath, opts.RpcURL)
+				if err != nil {
+					logger.Fatalf("Failed to fork the target chain: %v", err)
+				}
+			}
+			defer stopFork()
+
+			client, err := opts.newRPCClient(ctx, forkURL, challenger.TransportConfig{}, keys, txModifiers, gasConfig.Public, 0, 0)
+			if err != nil {
+				logger.Fatalf("Failed to create RPC client: %v", err)
+			}
+			forkTransport, err := transport.NewHTTP(transport.HTTPOptions{URL: forkURL})
+			if err != nil {
+				logger.Fatalf("Failed to create transport: %v", err)
+			}
+			if opts.FlashbotRPCURL != "" {
+				logger.Warnf("Flashbots relay can't be tested on a fork, challenges are sent to the fork node directly")
+			}
+
+			failed := false
+			for _, address := range addresses {
+				p := challenger.NewScribeOptimisticRPCProvider(client, nil, providerOpts...)
+				if err := challenger.NewSelfTest(forkTransport, client, p, address).Run(ctx); err != nil {
+					logger.WithField("address", address).Errorf("Self-test failed: %v", err)
+					failed = true
+					continue
+				}
+				logger.WithField("address", address).Infof("Self-test passed")
+			}
+			if failed {
+				exitStatus = exitError
+			}
 		},
 	}
 
-	cmd.PersistentFlags().StringVar(&opts.SecretKey, "secret-key", "", "Private key in format `0x******` or `*******`. If provided, no need to use --keystore")
-	cmd.PersistentFlags().StringVar(&opts.Key, "keystore", "", "Keystore file (NOT FOLDER), path to key .json file. If provided, no need to use --secret-key")
-	cmd.PersistentFlags().StringVar(&opts.Password, "password", "", "Key raw password as text")
-	cmd.PersistentFlags().StringVar(&opts.PasswordFile, "password-file", "", "Path to key password file")
-	cmd.PersistentFlags().StringVar(&opts.RpcURL, "rpc-url", "", "Node HTTP RPC_URL, normally starts with https://****")
-	cmd.PersistentFlags().StringVar(&opts.FlashbotRPCURL, "flashbot-rpc-url", "", "Flashbot Node HTTP RPC_URL, normally starts with https://****")
-	cmd.PersistentFlags().StringArrayVarP(&opts.Address, "addresses", "a", []string{}, "ScribeOptimistic contract address. Example: `0x891E368fE81cBa2aC6F6cc4b98e684c106e2EF4f`")
-	cmd.PersistentFlags().
+	runCmd.Flags().StringArrayVar(&opts.FallbackRpcURLs, "fallback-rpc-url", []string{}, "Alternate Node HTTP RPC_URL used when the primary one is stale or unavailable, can be repeated")
+	runCmd.Flags().DurationVar(&opts.MaxBlockDrift, "max-block-drift", challenger.DefaultMaxBlockDrift, "Max allowed lag of head block timestamp behind wall clock before RPC is considered stale, 0 disables the check")
+	runCmd.Flags().DurationVar(&opts.HedgeAfter, "hedge-after", 0, "Time after which logs of recent blocks and transactions are also requested from the next --fallback-rpc-url and the first success is used, 0 disables hedging")
+	runCmd.Flags().DurationVar(&opts.StaleHeadAfter, "stale-head-timeout", challenger.DefaultStaleHeadTimeout, "Max time head block number may stay unchanged before RPC is considered stale, 0 disables the check")
+	runCmd.Flags().
 		Int64Var(&opts.FromBlock, "from-block", 0, "Block number to start from. If not provided, binary will try to get it from given RPC")
-	cmd.PersistentFlags().Uint64Var(&opts.ChainID, "chain-id", 0, "If no chain_id provided binary will try to get chain_id from given RPC")
-	cmd.PersistentFlags().StringVar(&opts.TransactionType, "tx-type", "none", "Transaction type definition, possible values are: `legacy`, `eip1559` or `none`")
-	cmd.PersistentFlags().StringVar(&opts.MetricsAddr, "metrics-addr", ":9090", "Address for the Prometheus metrics server")
-	cmd.PersistentFlags().StringVar(&opts.LogLevel, "log-level", "info", "Log level: trace, debug, info, warn, error, fatal, panic")
+	runCmd.Flags().Int64Var(&opts.ToBlock, "to-block", 0, "Last block processed with --once, pokes from --from-block up to it are only verified and reported, not challenged")
+	runCmd.Flags().BoolVar(&opts.Once, "once", false, "Execute a single tick and exit, with status 0 if clean, 1 on error, 2 if invalid pokes are found and 3 if a challenge failed to be submitted")
+	gasDefaults := challenger.DefaultGasConfig()
+	runCmd.Flags().StringVar(&opts.FlashbotTxType, "flashbot-tx-type", gasDefaults.Flashbots.TxType, "Transaction type of challenges sent with flashbots, possible values are: `legacy`, `eip1559` or `none`")
+	runCmd.Flags().Float64Var(&opts.FlashbotGasMul, "flashbot-gas-price-multiplier", gasDefaults.Flashbots.GasPriceMultiplier, "Multiplier of the gas price (max fee per gas for eip1559) of challenges sent with flashbots")
+	runCmd.Flags().Float64Var(&opts.FlashbotTipMul, "flashbot-priority-fee-multiplier", gasDefaults.Flashbots.PriorityFeeMultiplier, "Multiplier of the priority fee of challenges sent with flashbots, they only pay on inclusion, so can bid higher")
+	runCmd.Flags().StringVar(&opts.FlashbotMaxGas, "flashbot-max-gas-price", "", "Cap of the gas price (max fee per gas for eip1559) in wei of challenges sent with flashbots")
+	runCmd.Flags().StringVar(&opts.FlashbotMaxTip, "flashbot-max-priority-fee", "", "Cap of the priority fee in wei of challenges sent with flashbots")
+	runCmd.Flags().StringVar(&opts.AuditLogFile, "audit-log", "", "Append-only file every poke seen, signature verdict and challenge is recorded in, chained by hashes")
+	runCmd.Flags().StringVar(&opts.CheckpointFile, "checkpoint-file", "", "JSON file the last processed block of each address is persisted to, so scanning resumes from it after restart")
+	runCmd.Flags().StringVar(&opts.PendingFile, "pending-file", "", "JSON file sent challenges are persisted to until their outcome is known, so they are resumed after restart")
+	runCmd.Flags().StringVar(&opts.BatchMulticall, "batch-multicall-address", "", "Multicall3 compatible contract owned by the challenger account challenges of different addresses found together are batched through, it receives the rewards, the public Multicall3 deployment is rejected")
+	runCmd.Flags().DurationVar(&opts.BatchWindow, "batch-window", challenger.DefaultBatchWindow, "Time challenges are collected for before they are sent in one batch")
+	runCmd.Flags().Uint64Var(&opts.FlashbotBlocks, "flashbot-inclusion-blocks", challenger.FlashbotInclusionBlocks, "Number of blocks flashbots has to include the challenge in, after that it's resubmitted to the public mempool with the same nonce")
+	runCmd.Flags().StringVar(&opts.MetricsAddr, "metrics-addr", ":9090", "Address for the Prometheus metrics server")
+	runCmd.Flags().IntVar(&opts.VerifyWorkers, "verify-concurrency", challenger.DefaultVerifyConcurrency, "Number of pokes verified in parallel within one tick")
+	runCmd.Flags().DurationVar(&opts.VerifyTimeout, "verify-timeout", challenger.DefaultVerifyTimeout, "Time limit for verifying a single poke, 0 disables the limit")
+	runCmd.Flags().Uint64Var(&opts.Confirmations, "confirmations", 0, "Number of block confirmations before a poke is acted on, pokes are processed earlier if their challenge window is about to close")
+	runCmd.Flags().DurationVar(&opts.BlockTime, "block-time", challenger.DefaultBlockTime, "Average time between blocks of the chain, used to find the start of the challenge window")
+	runCmd.Flags().StringVar(&opts.MinBalance, "min-balance", "", "Balance of the challenger account in wei below which an error is logged, challenges may fail to pay for gas")
+	runCmd.Flags().StringVar(&opts.SequencerFeed, "sequencer-uptime-feed", "", "Chainlink compatible L2 sequencer uptime feed address, challenges may not be included while it reports the sequencer down")
+	runCmd.Flags().DurationVar(&opts.SequencerLag, "sequencer-max-lag", 0, "Age of the latest block after which the L2 sequencer is considered down, 0 disables the check")
+	runCmd.Flags().Uint64Var(&opts.MinPokeAge, "min-poke-age-blocks", 0, "Number of blocks an invalid poke has to be deep before it's challenged, it's challenged earlier if its challenge window is about to close")
+	runCmd.Flags().BoolVar(&opts.ValidateAge, "validate-poke-age", false, "Alert on pokes with poke data in the future, not newer than the previous one or older than --max-poke-staleness")
+	runCmd.Flags().DurationVar(&opts.MaxStaleness, "max-poke-staleness", 0, "Maximum age of the poke data at the time it's poked, checked with --validate-poke-age, 0 disables the check")
+	runCmd.Flags().StringSliceVar(&opts.PriceRefs, "price-reference", nil, "Reference price of the address poked values are compared with, as ADDRESS=SOURCE, where SOURCE is Chainlink compatible contract address or URL#json.path")
+	runCmd.Flags().Float64Var(&opts.DeviationLimit, "price-deviation-threshold", 0.01, "Relative deviation of poked value from the reference price which is alerted on, e.g. 0.01 for 1%")
+	runCmd.Flags().BoolVar(&opts.WatchPokes, "watch-regular-pokes", false, "Also fetch regular Poked events, so time since the last feed update is exposed in challenger_last_update_age_seconds metric")
+	runCmd.Flags().BoolVar(&opts.WatchState, "watch-contract-state", false, "Alert on events changing configuration of the contract, e.g. dropped poke data, lifted or dropped feeds and auth changes")
+	runCmd.Flags().StringVar(&opts.NonceRepair, "nonce-repair", "", "Watch for transactions of the challenger account stuck in the mempool, possible values are: `alert`, `rebroadcast` or `cancel`")
+	runCmd.Flags().Uint64Var(&opts.NonceGapBlocks, "nonce-gap-blocks", challenger.DefaultNonceGapBlocks, "Number of blocks pending nonce can be ahead of confirmed one before the transaction is considered stuck")
+	runCmd.Flags().Uint64Var(&opts.FallbackGas, "fallback-gas-limit", challenger.MaxFlashbotGasLimit, "Gas limit of transactions used when gas estimation fails, e.g. reverts on transient state, 0 aborts the transaction instead")
+	runCmd.Flags().StringVar(&opts.BundlerURL, "bundler-url", "", "ERC-4337 bundler RPC URL, if provided challenges are sent as user operations of --smart-account signed by the key")
+	runCmd.Flags().StringVar(&opts.SmartAccount, "smart-account", "", "Address of the deployed ERC-4337 smart account challenges are sent from, the key has to be its owner")
+	runCmd.Flags().StringVar(&opts.EntryPoint, "entry-point", challenger.DefaultEntryPoint.String(), "ERC-4337 EntryPoint contract address")
+	runCmd.Flags().StringVar(&opts.PaymasterURL, "paymaster-url", "", "Paymaster RPC URL asked to sponsor user operations with pm_sponsorUserOperation")
+	runCmd.Flags().StringVar(&opts.PaymasterData, "paymaster-and-data", "", "Static paymasterAndData of user operations, used if --paymaster-url is not provided")
+	runCmd.Flags().StringVar(&opts.UserOpTip, "user-op-priority-fee", "", "Priority fee of user operations in wei, suggested by the bundler if not provided")
+	runCmd.Flags().StringVar(&opts.SafeAddress, "safe-address", "", "Safe multisig challenges are proposed to instead of being sent, the key has to be an owner or a delegate of the Safe")
+	runCmd.Flags().StringVar(&opts.SafeServiceURL, "safe-tx-service-url", "", "Safe Transaction Service URL of the chain, e.g. https://safe-transaction-mainnet.safe.global")
+	runCmd.Flags().StringVar(&opts.SafeAPIKey, "safe-api-key", "", "API key of the Safe Transaction Service, optional")
+	runCmd.Flags().DurationVar(&opts.SafeAlertBefore, "safe-alert-before", challenger.DefaultSafeAlertBefore, "Time before the challenge deadline a proposal not executed by the Safe owners is alerted on")
+	runCmd.Flags().StringVar(&opts.MempoolRpcURL, "mempool-rpc-url", "", "Websocket or IPC RPC URL pending transactions are watched on, pokes are verified while pending and invalid ones challenged the instant they land")
+	runCmd.Flags().StringVar(&opts.ArchiveRpcURL, "archive-rpc-url", "", "Archive Node RPC URL historical queries are sent to, e.g. backfill and verification at the poke block, the rest goes to --rpc-url")
+	runCmd.Flags().Uint64Var(&opts.ArchiveBlockAge, "archive-block-age", challenger.DefaultArchiveBlockAge, "Number of blocks behind the head after which queries are sent to --archive-rpc-url")
+	runCmd.Flags().Uint64Var(&opts.BundleDelay, "bundle-target-delay", 0, "Number of blocks skipped before the first block challenges sent to flashbots target, they are sent once the block before it is mined, 0 targets the next block")
+	runCmd.Flags().BoolVar(&opts.PrivateOnly, "private-only", false, "Send challenges only through the flashbots relay, never to the public mempool where they could be front-run")
+	runCmd.Flags().BoolVar(&opts.VerifyAtPoke, "verify-at-poke-block", false, "Also verify poke signatures against the state at the block of the poke and flag discrepancies, requires an archive node")
+	runCmd.Flags().BoolVar(&opts.Reverify, "reverify-valid-pokes", false, "Verify pokes found valid again on each tick until their challenge window closes")
+	runCmd.Flags().StringArrayVar(&opts.CodeHashes, "code-hash", []string{}, "Allowed keccak256 hash of the code deployed at monitored addresses or their EIP-1967 implementation, can be repeated, addresses are not verified if not provided")
+	runCmd.Flags().IntVar(&opts.MaxPerHour, "max-challenges-per-hour", 0, "Max number of challenges submitted per hour, further ones are paused until the budget refills, 0 means no limit")
+	runCmd.Flags().StringVar(&opts.MaxSpendPerDay, "max-challenge-spend-per-day", "", "Max fees in wei paid by challenges within 24 hours, further ones are paused until older spends leave the window")
+	runCmd.Flags().DurationVar(&opts.HeartbeatAfter, "heartbeat-timeout", challenger.DefaultHeartbeatTimeout, "Max time since the last tick of any address before the process is reported unhealthy on /health and systemd watchdog is not pinged")
+	runCmd.Flags().IntVar(&opts.Watchdog, "watchdog-intervals", challenger.DefaultWatchdogIntervals, "Number of tick intervals a tick may run for before the processing loop of the address is restarted, 0 disables the watchdog")
+	runCmd.Flags().DurationVar(&opts.FastTick, "fast-tick-interval", challenger.DefaultFastTickInterval, "Tick interval while an invalid poke is not challenged successfully yet or a poke waits for confirmations, 0 disables acceleration")
+	runCmd.Flags().Float64Var(&opts.ExpiryAlert, "expiry-alert-threshold", challenger.DefaultExpiryAlertThreshold, "Fraction of the challenge window remaining below which an invalid poke without confirmed challenge is alerted on, 0 disables alerts")
+	runCmd.Flags().DurationVar(&opts.TickJitter, "tick-jitter", 0, "Maximum random delay added to every tick, on top of ticks of the addresses being spread over the tick interval")
+	runCmd.Flags().DurationVar(&opts.SubmitJitter, "submission-jitter", 0, "Maximum random delay before each challenge is sent, so its timing is harder to predict")
+	runCmd.Flags().StringVar(&opts.InstanceLabel, "instance-label", "", "Name of this deployment added to all metrics as challenger_instance label and to webhook payloads")
+	runCmd.Flags().StringVar(&opts.EventsToken, "events-token", "", "Token subscribers of the /events stream of poke and challenge events have to present, the stream is disabled if not provided")
+	runCmd.Flags().StringVar(&opts.AdminToken, "admin-token", "", "Bearer token of the /admin/reload-key endpoint rotating the signing key, the endpoint is disabled if not provided")
+	runCmd.Flags().StringVar(&opts.SweepTo, "sweep-to", "", "Beneficiary (cold wallet) address rewards are swept to after each successful challenge")
+	runCmd.Flags().StringVar(&opts.SweepThreshold, "sweep-threshold", "100000000000000000", "Balance in wei kept on challenger account to pay for gas, only balance above it is swept")
+	runCmd.Flags().BoolVar(&opts.ReadOnly, "read-only", false, "Run without a key, invalid pokes are scanned, verified and reported by logs, metrics and the event stream, but never challenged")
+	runCmd.Flags().BoolVar(&opts.KeeperMode, "keeper-mode", false, "Do not submit challenges, export them as payloads on /payloads endpoint for an external keeper network")
+	runCmd.Flags().StringVar(&opts.KeeperWebhook, "keeper-webhook-url", "", "Webhook URL challenge payloads are POSTed to in keeper mode")
+	runCmd.Flags().StringVar(&opts.ChallengeExec, "challenge-exec", "", "Program challenges are delegated to instead of being sent, it gets the calldata as last argument and the context in CHALLENGER_* environment variables")
+	runCmd.Flags().StringArrayVar(&opts.ChallengeArgs, "challenge-exec-arg", []string{}, "Argument of the --challenge-exec program passed before the calldata, can be repeated")
+	runCmd.Flags().DurationVar(&opts.ExecTimeout, "challenge-exec-timeout", challenger.DefaultChallengeExecTimeout, "Time limit for the --challenge-exec command, it's killed afterwards")
+	runCmd.Flags().StringVar(&opts.OTLPEndpoint, "otlp-endpoint", "", "OpenTelemetry collector URL traces are exported to via OTLP/HTTP, e.g. http://localhost:4318")
+	runCmd.Flags().StringVar(&opts.LeaderRedisURL, "leader-election-redis", "", "Redis URL used for leader election between challenger instances, e.g. redis://localhost:6379/0")
+	runCmd.Flags().StringVar(&opts.LeaderKey, "leader-election-key", "challenger-leader", "Redis key holding the leader lease")
+	runCmd.Flags().DurationVar(&opts.LeaderLease, "leader-lease", challenger.DefaultLeaderLease, "Leader lease duration, standby instance takes over within it when the leader dies")
+	runCmd.Flags().StringVar(&opts.InstanceID, "instance-id", "", "Unique id of this instance used in leader election, challenge locks and duplicate detection, defaults to hostname")
+	runCmd.Flags().BoolVar(&opts.DuplicateCheck, "duplicate-check", false, "Warn about another instance using the same key, detected by the nonce advancing without transactions sent by this instance")
+	runCmd.Flags().StringVar(&opts.DuplicateRedis, "duplicate-check-redis", "", "Redis URL instances register in with --duplicate-check, so another instance using the same key is reported right away")
+	runCmd.Flags().StringVar(&opts.DuplicateKey, "duplicate-check-key", "challenger-instance", "Prefix of Redis keys instances register in")
+	runCmd.Flags().StringVar(&opts.LockRedisURL, "challenge-lock-redis", "", "Redis URL of the shared lock consulted before each challenge, so cooperating instances don't challenge the same poke")
+	runCmd.Flags().StringVar(&opts.LockPrefix, "challenge-lock-prefix", "challenger-lock", "Prefix of Redis keys used for challenge locks")
+	selftestCmd.Flags().StringVar(&opts.ForkRpcURL, "fork-rpc-url", "", "RPC URL of already running Anvil fork, if not provided the chain behind --rpc-url is forked with Anvil")
+	selftestCmd.Flags().StringVar(&opts.AnvilPath, "anvil-path", "anvil", "Path to Anvil binary used to fork the chain")
+	for _, c := range []*cobra.Command{runCmd, selftestCmd} {
+		addCommonFlags(c.Flags(), &opts)
+	}
+
+	cmd := &cobra.Command{Use: "challenger"}
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Args:  cobra.NoArgs,
+		Short: "Prints version, git commit, build date and hash of the embedded ScribeOptimistic ABI",
+		Run: func(cmd *cobra.Command, args []string) {
+			info := challenger.GetBuildInfo()
+			fmt.Printf("Version:    %s\n", info.Version)
+			fmt.Printf("Commit:     %s\n", info.Commit)
+			fmt.Printf("Build date: %s\n", info.BuildDate)
+			fmt.Printf("ABI hash:   %s\n", info.ABIHash)
+			fmt.Printf("Go version: %s\n", info.GoVersion)
+		},
+	}
+
+	verifyAuditLogCmd := &cobra.Command{
+		Use:   "verify-audit-log FILE",
+		Args:  cobra.ExactArgs(1),
+		Short: "Verifies the hash chain of the audit log written with --audit-log",
+		Run: func(cmd *cobra.Command, args []string) {
+			f, err := os.Open(args[0])
+			if err != nil {
+				logger.Fatalf("Failed to open audit log: %v", err)
+			}
+			defer f.Close()
+			last, err := challenger.VerifyAuditLog(f)
+			if err != nil {
+				logger.Fatalf("Audit log is broken: %v", err)
+			}
+			if last == nil {
+				fmt.Println("Audit log is empty")
+				return
+			}
+			fmt.Printf("Audit log is intact, %d records, last hash %s\n", last.Seq, last.Hash)
+		},
+	}
+
+	var dashboardTitle, dashboardOutput string
+	dashboardCmd := &cobra.Command{
+		Use:   "dashboard",
+		Args:  cobra.NoArgs,
+		Short: "Prints Grafana dashboard JSON with panels of all exported metrics, filtered by --addresses",
+		Run: func(cmd *cobra.Command, args []string) {
+			var addresses []types.Address
+			if len(opts.Address) > 0 {
+				var err error
+				if addresses, err = opts.getAddresses(); err != nil {
+					logger.Fatalf("%v", err)
+				}
+			}
+			dashboard, err := challenger.GrafanaDashboard(dashboardTitle, addresses)
+			if err != nil {
+				logger.Fatalf("Failed to generate dashboard: %v", err)
+			}
+			if dashboardOutput == "" {
+				fmt.Println(string(dashboard))
+				return
+			}
+			if err := os.WriteFile(dashboardOutput, dashboard, 0o644); err != nil {
+				logger.Fatalf("Failed to write dashboard: %v", err)
+			}
+		},
+	}
+	dashboardCmd.Flags().StringArrayVarP(&opts.Address, "addresses", "a", []string{}, "ScribeOptimistic contract address offered by the address variable, all addresses seen by Prometheus if not provided")
+	dashboardCmd.Flags().StringVar(&dashboardTitle, "title", challenger.DefaultDashboardTitle, "Title of the dashboard")
+	dashboardCmd.Flags().StringVarP(&dashboardOutput, "output", "o", "", "File the dashboard is written to, printed if not provided")
 
+	cmd.AddCommand(runCmd, selftestCmd, versionCmd, verifyAuditLogCmd, dashboardCmd)
 	if err := cmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitError)
 	}
+	os.Exit(exitStatus)
+}
+
+// Signals the process shuts down gracefully on. On Windows, SIGTERM stands for console close, logoff and
+// shutdown events.
+var shutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// Exit statuses of commands, `--once` mode tells its results apart with them
+const (
+	exitClean            = 0
+	exitError            = 1
+	exitInvalidPokes     = 2
+	exitChallengesFailed = 3
+)
+
+// runOnce executes a single tick for all addresses and returns the exit status. An address which failed
+// to be processed may hide invalid pokes, so the error takes precedence over statuses of other addresses.
+func runOnce(ctx context.Context, manager *challenger.Manager) int {
+	reports, err := manager.RunOnce(ctx)
+	status := exitClean
+	for _, report := range reports {
+		switch {
+		case report.FailedChallenges > 0:
+			status = exitChallengesFailed
+		case report.InvalidPokes > 0 && status == exitClean:
+			status = exitInvalidPokes
+		}
+	}
+	if err != nil {
+		logger.Errorf("Failed to execute tick: %v", err)
+		return exitError
+	}
+	return status
+}
+
+// auditRange processes the block range of all addresses once and returns the exit status, with the same precedence
+// as runOnce. Pokes in the range are not challenged, so challenges can't fail.
+func auditRange(ctx context.Context, manager *challenger.Manager, toBlock int64) int {
+	reports, err := manager.AuditRange(ctx, toBlock)
+	status := exitClean
+	for _, report := range reports {
+		for _, poke := range report.InvalidPokes {
+			logger.
+				WithField("address", report.Address).
+				WithField("pokeTxHash", poke.TxHash).
+				Errorf("Invalid poke in block %v was not challenged", poke.BlockNumber)
+			status = exitInvalidPokes
+		}
+	}
+	if err != nil {
+		logger.Errorf("Failed to process blocks: %v", err)
+		return exitError
+	}
+	return status
+}
+
+// Registers flags shared by all commands
+func addCommonFlags(fs *pflag.FlagSet, opts *options) {
+	fs.StringVar(&opts.SecretKey, "secret-key", "", "Private key in format `0x******` or `*******`. If provided, no need to use --keystore")
+	fs.StringVar(&opts.Key, "keystore", "", "Keystore file (NOT FOLDER), path to key .json file. If provided, no need to use --secret-key")
+	fs.StringVar(&opts.Password, "password", "", "Key raw password as text")
+	fs.StringVar(&opts.PasswordFile, "password-file", "", "Path to key password file")
+	fs.StringVar(&opts.RpcURL, "rpc-url", "", "Node RPC_URL, normally starts with https://****, or ipc:///path/to/geth.ipc of a node on the same host")
+	fs.StringVar(&opts.FlashbotRPCURL, "flashbot-rpc-url", "", "Flashbot Node HTTP RPC_URL, normally starts with https://****")
+	fs.StringVar(&opts.UserAgent, "user-agent", "", "User-Agent of requests to RPC endpoints, challenger/VERSION if not provided")
+	fs.StringVar(&opts.RPCConfig, "rpc-config", "", "JSON file with headers, basic auth credentials, proxies and TLS certificates of RPC endpoints by their name, e.g. primary, fallback-1 or flashbots")
+	fs.StringArrayVarP(&opts.Address, "addresses", "a", []string{}, "ScribeOptimistic contract address. Example: `0x891E368fE81cBa2aC6F6cc4b98e684c106e2EF4f`")
+	fs.Uint64Var(&opts.ChainID, "chain-id", 0, "Chain ID RPC endpoints are verified to serve, if not provided binary will try to get chain_id from given RPC")
+	fs.StringVar(&opts.GasSymbol, "gas-token-symbol", challenger.DefaultGasToken.Symbol, "Symbol of the token gas is paid in, balances and costs in metrics and logs are denominated in it")
+	fs.Uint8Var(&opts.GasDecimals, "gas-token-decimals", challenger.DefaultGasToken.Decimals, "Decimals of the token gas is paid in")
+	fs.StringVar(&opts.ChallengeTag, "challenge-tag", "", "Operator ID appended with the challenger version to opChallenge calldata, so challenges can be attributed on-chain")
+	fs.StringVar(&opts.Chain, "chain", "", "Chain preset setting defaults of chain ID, block time, confirmations, transaction type, relay and fallback gas limit, possible values are: "+strings.Join(challenger.ChainPresetNames(), ", "))
+	fs.StringVar(&opts.TransactionType, "tx-type", "none", "Transaction type definition, possible values are: `legacy`, `eip1559` or `none`")
+	fs.Float64Var(&opts.GasMultiplier, "gas-price-multiplier", 1, "Multiplier of the gas price (max fee per gas for eip1559) suggested by the gas oracle")
+	fs.Float64Var(&opts.TipMultiplier, "priority-fee-multiplier", 1, "Multiplier of the priority fee suggested by the gas oracle, eip1559 only")
+	fs.StringVar(&opts.MaxGasPrice, "max-gas-price", "", "Cap of the gas price (max fee per gas for eip1559) in wei")
+	fs.StringVar(&opts.MaxTip, "max-priority-fee", "", "Cap of the priority fee in wei, eip1559 only")
+	fs.StringVar(&opts.GasOracle, "gas-oracle", challenger.GasOracleNode, "Source of suggested fees of challenges, before multipliers and caps apply, possible values are: node, static, fee-history, blocknative, etherscan")
+	fs.StringVar(&opts.GasOracleKey, "gas-oracle-api-key", "", "API key of the blocknative or etherscan gas oracle")
+	fs.StringVar(&opts.GasOracleURL, "gas-oracle-url", "", "URL of the blocknative or etherscan gas oracle API, overrides the public one")
+	fs.StringVar(&opts.GasOraclePrice, "gas-oracle-gas-price", "", "Gas price (max fee per gas for eip1559) in wei of the static gas oracle")
+	fs.StringVar(&opts.GasOracleTip, "gas-oracle-priority-fee", "", "Priority fee in wei of the static gas oracle")
+	fs.Float64Var(&opts.GasOraclePctl, "gas-oracle-percentile", 50, "Percentile of priority fees in recent blocks of the fee-history gas oracle, confidence of the blocknative gas oracle")
+	fs.StringVar(&opts.ForwarderAddr, "forwarder-address", "", "Forwarder (relayer, multicall) contract address to route opChallenge through")
+	fs.StringVar(&opts.ForwarderMethod, "forwarder-method", "execute(address,bytes)", "Forwarder contract method signature")
+	fs.StringSliceVar(&opts.ForwarderArgs, "forwarder-args", []string{challenger.ForwarderTargetPlaceholder, challenger.ForwarderCalldataPlaceholder}, "Forwarder method arguments, supported placeholders: {target}, {calldata}, {from}")
+	fs.DurationVar(&opts.TxTimeout, "tx-confirmation-timeout", challenger.TxConfirmationTimeout, "Time limit for a challenge transaction to be mined")
+	fs.DurationVar(&opts.TxPollInterval, "tx-poll-interval", challenger.TxConfirmationPollInterval, "Interval of polling for transaction receipt, with websocket RPC receipt is also checked on every new block")
+	fs.StringVar(&opts.LogLevel, "log-level", "info", "Log level: trace, debug, info, warn, error, fatal, panic")
+	fs.StringSliceVar(&opts.LogSampling, "log-sampling", nil, "Sampling of noisy debug messages as category=rule, categories: tick, call, rule: N to log every Nth message or change to log only changes")
 }
@@ -0,0 +1,172 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/defiweb/go-eth/types"
+)
+
+// DefaultArchiveBlockAge is the number of recent blocks whose state full nodes keep by default.
+const DefaultArchiveBlockAge = 128
+
+// ArchiveClient implements RPCClient interface and routes historical queries to an archive node, while the rest,
+// including transactions and queries of recent blocks, go to the full node.
+// A query is historical if it's about a block more than MaxBlockAge blocks behind the head, or the earliest block.
+// The head is the last one returned by BlockNumber, until it's known all queries go to the full node.
+type ArchiveClient struct {
+	// Client is the full node handling the hot path.
+	Client RPCClient
+	// Archive is the archive node handling historical queries.
+	Archive RPCClient
+	// MaxBlockAge is the number of blocks behind the head still queried on the full node.
+	MaxBlockAge uint64
+
+	mu   sync.Mutex
+	head *big.Int
+}
+
+// NewArchiveClient creates a new instance of ArchiveClient.
+func NewArchiveClient(client, archive RPCClient, maxBlockAge uint64) *ArchiveClient {
+	return &ArchiveClient{Client: client, Archive: archive, MaxBlockAge: maxBlockAge}
+}
+
+// isHistorical returns true if the block is more than MaxBlockAge blocks behind the head.
+func (a *ArchiveClient) isHistorical(block *types.BlockNumber) bool {
+	if block == nil {
+		return false
+	}
+	if block.IsEarliest() {
+		return true
+	}
+	if block.IsTag() {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.head == nil {
+		return false
+	}
+	age := new(big.Int).Sub(a.head, block.Big())
+	return age.Cmp(new(big.Int).SetUint64(a.MaxBlockAge)) > 0
+}
+
+// route returns the client the query about the block is sent to.
+func (a *ArchiveClient) route(block *types.BlockNumber) RPCClient {
+	if a.isHistorical(block) {
+		return a.Archive
+	}
+	return a.Client
+}
+
+// BlockNumber returns the head block number of the full node and remembers it for routing.
+func (a *ArchiveClient) BlockNumber(ctx context.Context) (*big.Int, error) {
+	n, err := a.Client.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	a.head = new(big.Int).Set(n)
+	a.mu.Unlock()
+	return n, nil
+}
+
+func (a *ArchiveClient) Accounts(ctx context.Context) ([]types.Address, error) {
+	return a.Client.Accounts(ctx)
+}
+
+func (a *ArchiveClient) BlockByNumber(ctx context.Context, number types.BlockNumber, full bool) (*types.Block, error) {
+	return a.route(&number).BlockByNumber(ctx, number, full)
+}
+
+func (a *ArchiveClient) SendTransaction(ctx context.Context, tx *types.Transaction) (*types.Hash, *types.Transaction, error) {
+	return a.Client.SendTransaction(ctx, tx)
+}
+
+func (a *ArchiveClient) Call(ctx context.Context, call *types.Call, block types.BlockNumber) ([]byte, *types.Call, error) {
+	return a.route(&block).Call(ctx, call, block)
+}
+
+// GetLogs fetches logs from the archive node if the range starts at a historical block.
+func (a *ArchiveClient) GetLogs(ctx context.Context, query *types.FilterLogsQuery) ([]types.Log, error) {
+	if query != nil && query.BlockHash == nil {
+		return a.route(query.FromBlock).GetLogs(ctx, query)
+	}
+	return a.Client.GetLogs(ctx, query)
+}
+
+func (a *ArchiveClient) GetTransactionReceipt(ctx context.Context, hash types.Hash) (*types.TransactionReceipt, error) {
+	return a.Client.GetTransactionReceipt(ctx, hash)
+}
+
+func (a *ArchiveClient) GetBalance(ctx context.Context, address types.Address, block types.BlockNumber) (*big.Int, error) {
+	return a.route(&block).GetBalance(ctx, address, block)
+}
+
+// SubscribeNewHeads implements HeadSubscriber interface if the full node supports subscriptions.
+func (a *ArchiveClient) SubscribeNewHeads(ctx context.Context) (<-chan types.Block, error) {
+	s, ok := a.Client.(HeadSubscriber)
+	if !ok {
+		return nil, fmt.Errorf("full node does not support subscriptions")
+	}
+	return s.SubscribeNewHeads(ctx)
+}
+
+func (a *ArchiveClient) GetTransactionByHash(ctx context.Context, hash types.Hash) (*types.OnChainTransaction, error) {
+	return a.Client.GetTransactionByHash(ctx, hash)
+}
+
+func (a *ArchiveClient) ChainID(ctx context.Context) (uint64, error) {
+	return a.Client.ChainID(ctx)
+}
+
+func (a *ArchiveClient) SubscribeLogs(ctx context.Context, query *types.FilterLogsQuery) (<-chan types.Log, error) {
+	return a.Client.SubscribeLogs(ctx, query)
+}
+
+// GetTransactionCount implements NonceFetcher interface if the full node supports it.
+func (a *ArchiveClient) GetTransactionCount(ctx context.Context, account types.Address, block types.BlockNumber) (uint64, error) {
+	n, ok := a.Client.(NonceFetcher)
+	if !ok {
+		return 0, fmt.Errorf("full node does not support fetching transaction count")
+	}
+	return n.GetTransactionCount(ctx, account, block)
+}
+
+// GetCode implements CodeFetcher interface if the node the query is routed to supports it.
+func (a *ArchiveClient) GetCode(ctx context.Context, account types.Address, block types.BlockNumber) ([]byte, error) {
+	c, ok := a.route(&block).(CodeFetcher)
+	if !ok {
+		return nil, fmt.Errorf("node does not support fetching code")
+	}
+	return c.GetCode(ctx, account, block)
+}
+
+// GetStorageAt implements CodeFetcher interface if the node the query is routed to supports it.
+func (a *ArchiveClient) GetStorageAt(ctx context.Context, account types.Address, key types.Hash, block types.BlockNumber) (*types.Hash, error) {
+	c, ok := a.route(&block).(CodeFetcher)
+	if !ok {
+		return nil, fmt.Errorf("node does not support fetching storage")
+	}
+	return c.GetStorageAt(ctx, account, key, block)
+}
+
+// EstimateGas implements GasEstimator interface if the full node supports it.
+func (a *ArchiveClient) EstimateGas(ctx context.Context, call *types.Call, block types.BlockNumber) (uint64, *types.Call, error) {
+	g, ok := a.Client.(GasEstimator)
+	if !ok {
+		return 0, nil, fmt.Errorf("full node does not support gas estimation")
+	}
+	return g.EstimateGas(ctx, call, block)
+}
+
+// GasPrice implements GasEstimator interface if the full node supports it.
+func (a *ArchiveClient) GasPrice(ctx context.Context) (*big.Int, error) {
+	g, ok := a.Client.(GasEstimator)
+	if !ok {
+		return nil, fmt.Errorf("full node does not support gas estimation")
+	}
+	return g.GasPrice(ctx)
+}
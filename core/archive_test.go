@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveClient(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("queries go to full node until head is known", func(t *testing.T) {
+		full := new(mockRpcClient)
+		archive := new(mockRpcClient)
+		full.On("BlockByNumber", mock.Anything, types.BlockNumberFromUint64(1), false).
+			Return(&types.Block{Number: big.NewInt(1)}, nil)
+
+		a := NewArchiveClient(full, archive, DefaultArchiveBlockAge)
+		_, err := a.BlockByNumber(ctx, types.BlockNumberFromUint64(1), false)
+		require.NoError(t, err)
+		full.AssertExpectations(t)
+		archive.AssertNotCalled(t, "BlockByNumber", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("routes by block age", func(t *testing.T) {
+		full := new(mockRpcClient)
+		archive := new(mockRpcClient)
+		full.On("BlockNumber", mock.Anything).Return(big.NewInt(1000), nil)
+		full.On("BlockByNumber", mock.Anything, mock.Anything, false).Return(&types.Block{}, nil)
+		archive.On("BlockByNumber", mock.Anything, mock.Anything, false).Return(&types.Block{}, nil)
+		full.On("Call", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, &types.Call{}, nil)
+		archive.On("Call", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, &types.Call{}, nil)
+
+		a := NewArchiveClient(full, archive, 100)
+		n, err := a.BlockNumber(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(1000), n)
+
+		for _, block := range []types.BlockNumber{
+			types.LatestBlockNumber,
+			types.PendingBlockNumber,
+			types.BlockNumberFromUint64(900),
+			types.BlockNumberFromUint64(1000),
+		} {
+			_, err := a.BlockByNumber(ctx, block, false)
+			require.NoError(t, err)
+			_, _, err = a.Call(ctx, &types.Call{}, block)
+			require.NoError(t, err)
+		}
+		for _, block := range []types.BlockNumber{
+			types.EarliestBlockNumber,
+			types.BlockNumberFromUint64(899),
+		} {
+			_, err := a.BlockByNumber(ctx, block, false)
+			require.NoError(t, err)
+			_, _, err = a.Call(ctx, &types.Call{}, block)
+			require.NoError(t, err)
+		}
+		full.AssertNumberOfCalls(t, "BlockByNumber", 4)
+		full.AssertNumberOfCalls(t, "Call", 4)
+		archive.AssertNumberOfCalls(t, "BlockByNumber", 2)
+		archive.AssertNumberOfCalls(t, "Call", 2)
+	})
+
+	t.Run("logs are routed by start of range", func(t *testing.T) {
+		full := new(mockRpcClient)
+		archive := new(mockRpcClient)
+		full.On("BlockNumber", mock.Anything).Return(big.NewInt(1000), nil)
+		recent := &types.FilterLogsQuery{FromBlock: types.BlockNumberFromUint64Ptr(950), ToBlock: types.BlockNumberFromUint64Ptr(1000)}
+		backfill := &types.FilterLogsQuery{FromBlock: types.BlockNumberFromUint64Ptr(100), ToBlock: types.BlockNumberFromUint64Ptr(1000)}
+		full.On("GetLogs", mock.Anything, recent).Return([]types.Log{}, nil).Once()
+		archive.On("GetLogs", mock.Anything, backfill).Return([]types.Log{}, nil).Once()
+
+		a := NewArchiveClient(full, archive, 100)
+		_, err := a.BlockNumber(ctx)
+		require.NoError(t, err)
+		_, err = a.GetLogs(ctx, recent)
+		require.NoError(t, err)
+		_, err = a.GetLogs(ctx, backfill)
+		require.NoError(t, err)
+		full.AssertExpectations(t)
+		archive.AssertExpectations(t)
+	})
+
+	t.Run("transactions go to full node", func(t *testing.T) {
+		full := new(mockRpcClient)
+		archive := new(mockRpcClient)
+		hash := types.MustHashFromHex("0x6e4ead0e2ad4c2a0e9b4bb2c2a8a2a5e4b1a5e3b0b0f0e3a6d7e2e8f1f0a1b2c", types.PadNone)
+		full.On("SendTransaction", mock.Anything, mock.Anything).Return(&hash, &types.Transaction{}, nil).Once()
+
+		a := NewArchiveClient(full, archive, 100)
+		_, _, err := a.SendTransaction(ctx, &types.Transaction{})
+		require.NoError(t, err)
+		full.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// AuditReport is the result of processing a fixed block range of one address.
+type AuditReport struct {
+	Address   types.Address
+	FromBlock *big.Int
+	ToBlock   *big.Int
+	// Number of pokes found in the range.
+	Pokes int
+	// Pokes with invalid signature not challenged successfully, ordered as on chain.
+	InvalidPokes []*OpPokedEvent
+}
+
+// AuditRange verifies signatures of all pokes between fromBlock and toBlock (inclusive) not challenged successfully,
+// regardless of their challenge window, and reports the invalid ones. Logs are fetched in chunks of the challenge
+// window, the range a tick scans at most, so endpoints limiting the range of log queries don't reject them.
+func (c *Challenger) AuditRange(ctx context.Context, fromBlock *big.Int, toBlock *big.Int) (*AuditReport, error) {
+	if fromBlock.Cmp(toBlock) > 0 {
+		return nil, fmt.Errorf("from block %v is after to block %v", fromBlock, toBlock)
+	}
+	period, err := c.provider.GetChallengePeriod(ctx, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge period with error: %w", err)
+	}
+	chunk := new(big.Int).SetUint64(max(c.blocksPerPeriod(period), 1))
+	var pokes []*OpPokedEvent
+	var challenges []*OpPokeChallengedSuccessfullyEvent
+	for from := fromBlock; from.Cmp(toBlock) <= 0; {
+		to := new(big.Int).Add(from, chunk)
+		to.Sub(to, big.NewInt(1))
+		if to.Cmp(toBlock) > 0 {
+			to = toBlock
+		}
+		chunkPokes, err := c.provider.GetPokes(ctx, c.address, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get OpPoked events with error: %w", err)
+		}
+		pokes = append(pokes, chunkPokes...)
+		// Challenges of pokes may be in later chunks, all of them are collected before pokes are matched.
+		chunkChallenges, err := c.provider.GetSuccessfulChallenges(ctx, c.address, from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get OpPokeChallengedSuccessfully events with error: %w", err)
+		}
+		challenges = append(challenges, chunkChallenges...)
+		from = new(big.Int).Add(to, big.NewInt(1))
+	}
+	report := &AuditReport{Address: c.address, FromBlock: fromBlock, ToBlock: toBlock, Pokes: len(pokes)}
+	for _, poke := range PickUnchallengedPokes(pokes, challenges) {
+		valid, err := c.auditPoke(ctx, poke)
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify OpPoked event from block %v with error: %w", poke.BlockNumber, err)
+		}
+		if !valid {
+			c.log().
+				WithField("pokeTxHash", poke.TxHash).
+				Warnf("Unchallenged OpPoked event with invalid signature in block %v", poke.BlockNumber)
+			report.InvalidPokes = append(report.InvalidPokes, poke)
+		}
+	}
+	return report, nil
+}
+
+// auditPoke verifies the poke signature, the verification is explained as usual.
+func (c *Challenger) auditPoke(ctx context.Context, poke *OpPokedEvent) (bool, error) {
+	if c.verifyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.verifyTimeout)
+		defer cancel()
+	}
+	var recorder *callRecorder
+	if c.explainer != nil {
+		recorder = &callRecorder{}
+		ctx = withCallRecorder(ctx, recorder)
+	}
+	valid, err := c.provider.IsPokeSignatureValid(ctx, c.address, poke)
+	c.explainVerification(poke, recorder, valid, false, err)
+	return valid, err
+}
+
+// AuditRange processes blocks up to toBlock once for each address and returns the reports of addresses processed
+// successfully, 0 means the latest block. The range starts at the block the manager was created with, or at the start
+// of the challenge window.
+func (m *Manager) AuditRange(ctx context.Context, toBlock int64) ([]*AuditReport, error) {
+	if len(m.addresses) == 0 {
+		return nil, errors.New("no addresses to monitor")
+	}
+	var reports []*AuditReport
+	var errs []error
+	for _, address := range m.addresses {
+		report, err := m.auditRange(ctx, address, toBlock)
+		if err != nil {
+			// Other addresses are processed anyway
+			errs = append(errs, fmt.Errorf("failed to process %v: %w", address, err))
+			continue
+		}
+		logger.
+			WithField("address", address).
+			WithField("fromBlock", report.FromBlock).
+			WithField("toBlock", report.ToBlock).
+			Infof("Processed %d pokes, %d unchallenged with invalid signature", report.Pokes, len(report.InvalidPokes))
+		reports = append(reports, report)
+	}
+	return reports, errors.Join(errs...)
+}
+
+func (m *Manager) auditRange(ctx context.Context, address types.Address, toBlock int64) (*AuditReport, error) {
+	c := NewChallenger(ctx, address, m.newProvider(address), m.fromBlock, nil, m.opts...)
+	to := big.NewInt(toBlock)
+	if toBlock == 0 {
+		latest, err := c.provider.BlockNumber(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get latest block number with error: %w", err)
+		}
+		to = latest
+	}
+	if c.lastProcessedBlock != nil {
+		return c.AuditRange(ctx, new(big.Int).Add(c.lastProcessedBlock, big.NewInt(1)), to)
+	}
+	period, err := c.provider.GetChallengePeriod(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get challenge period with error: %w", err)
+	}
+	return c.AuditRange(ctx, c.getEarliestBlockNumber(to, period), to)
+}
+
+// OnceReport is the result of a single tick of one address.
+type OnceReport struct {
+	Address types.Address
+	// Number of challengeable pokes with invalid signature found.
+	InvalidPokes int
+	// Number of challenges failed to be submitted.
+	FailedChallenges int
+}
+
+// RunOnce executes a single tick and waits until challenges of invalid pokes found are submitted.
+// Outcomes of the challenge transactions are not waited for.
+func (c *Challenger) RunOnce() (*OnceReport, error) {
+	c.resumePending()
+	if err := c.executeTick(c.ctx); err != nil {
+		return nil, err
+	}
+	c.submissions.Wait()
+	c.saveCheckpoint()
+	return &OnceReport{
+		Address:          c.address,
+		InvalidPokes:     c.detectedPokes,
+		FailedChallenges: int(c.failedSubmissions.Load()),
+	}, nil
+}
+
+// RunOnce executes a single tick for each address and returns the reports of addresses processed successfully.
+func (m *Manager) RunOnce(ctx context.Context) ([]*OnceReport, error) {
+	if len(m.addresses) == 0 {
+		return nil, errors.New("no addresses to monitor")
+	}
+	var reports []*OnceReport
+	var errs []error
+	for _, address := range m.addresses {
+		c := NewChallenger(ctx, address, m.newProvider(address), m.fromBlock, nil, m.opts...)
+		report, err := c.RunOnce()
+		if err != nil {
+			// Other addresses are processed anyway
+			errs = append(errs, fmt.Errorf("failed to process %v: %w", address, err))
+			continue
+		}
+		logger.
+			WithField("address", address).
+			Infof("Found %d invalid pokes, %d challenges failed", report.InvalidPokes, report.FailedChallenges)
+		reports = append(reports, report)
+	}
+	return reports, errors.Join(errs...)
+}
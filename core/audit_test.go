@@ -0,0 +1,138 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/challenger/core"
+	"github.com/chronicleprotocol/challenger/core/coretest"
+)
+
+func TestAuditRange(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	valid := &core.OpPokedEvent{BlockNumber: big.NewInt(100)}
+	invalid := &core.OpPokedEvent{BlockNumber: big.NewInt(200)}
+	challenged := &core.OpPokedEvent{BlockNumber: big.NewInt(290)}
+
+	provider := new(coretest.MockProvider)
+	provider.On("BlockNumber", mock.Anything).Return(big.NewInt(1000), nil)
+	provider.On("GetChallengePeriod", mock.Anything, address).Return(600, nil)
+	// Logs are fetched in chunks of the challenge window, 50 blocks, the challenge is in the chunk after the poke.
+	provider.On("GetPokes", mock.Anything, address, big.NewInt(100), big.NewInt(149)).Return([]*core.OpPokedEvent{valid}, nil)
+	provider.On("GetPokes", mock.Anything, address, big.NewInt(200), big.NewInt(249)).Return([]*core.OpPokedEvent{invalid}, nil)
+	provider.On("GetPokes", mock.Anything, address, big.NewInt(250), big.NewInt(299)).Return([]*core.OpPokedEvent{challenged}, nil)
+	provider.On("GetPokes", mock.Anything, address, big.NewInt(950), big.NewInt(999)).Return([]*core.OpPokedEvent{invalid}, nil)
+	provider.On("GetPokes", mock.Anything, address, mock.Anything, mock.Anything).Return([]*core.OpPokedEvent{}, nil)
+	provider.On("GetSuccessfulChallenges", mock.Anything, address, big.NewInt(300), big.NewInt(349)).
+		Return([]*core.OpPokeChallengedSuccessfullyEvent{{BlockNumber: big.NewInt(301)}}, nil)
+	provider.On("GetSuccessfulChallenges", mock.Anything, address, mock.Anything, mock.Anything).
+		Return([]*core.OpPokeChallengedSuccessfullyEvent{}, nil)
+	provider.On("IsPokeSignatureValid", mock.Anything, address, valid).Return(true, nil)
+	provider.On("IsPokeSignatureValid", mock.Anything, address, invalid).Return(false, nil)
+
+	// Challenge window is over long ago, pokes are verified anyway.
+	c := core.NewChallenger(context.TODO(), address, provider, 0, nil)
+	report, err := c.AuditRange(context.TODO(), big.NewInt(50), big.NewInt(500))
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Pokes)
+	assert.Equal(t, []*core.OpPokedEvent{invalid}, report.InvalidPokes)
+	provider.AssertNotCalled(t, "IsPokeSignatureValid", mock.Anything, address, challenged)
+	provider.AssertNumberOfCalls(t, "GetPokes", 10)
+	provider.AssertCalled(t, "GetPokes", mock.Anything, address, big.NewInt(500), big.NewInt(500))
+
+	_, err = c.AuditRange(context.TODO(), big.NewInt(500), big.NewInt(50))
+	assert.Error(t, err)
+
+	// Without explicit range, the challenge window of the latest block is processed.
+	m := core.NewManager([]types.Address{address}, func(types.Address) core.IScribeOptimisticProvider { return provider }, 0)
+	reports, err := m.AuditRange(context.TODO(), 0)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, big.NewInt(950), reports[0].FromBlock)
+	assert.Equal(t, big.NewInt(1000), reports[0].ToBlock)
+	assert.Len(t, reports[0].InvalidPokes, 1)
+
+	m = core.NewManager([]types.Address{address}, func(types.Address) core.IScribeOptimisticProvider { return provider }, 10)
+	reports, err = m.AuditRange(context.TODO(), 20)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(10), reports[0].FromBlock)
+	assert.Equal(t, big.NewInt(20), reports[0].ToBlock)
+
+	// An address failing to be processed doesn't hide reports of the others.
+	other := types.MustAddressFromHex("0x0000000000000000000000000000000000000002")
+	failing := new(coretest.MockProvider)
+	failing.On("BlockNumber", mock.Anything).Return((*big.Int)(nil), fmt.Errorf("unavailable"))
+	m = core.NewManager([]types.Address{other, address}, func(a types.Address) core.IScribeOptimisticProvider {
+		if a == other {
+			return failing
+		}
+		return provider
+	}, 0)
+	reports, err = m.AuditRange(context.TODO(), 0)
+	assert.Error(t, err)
+	require.Len(t, reports, 1)
+	assert.Equal(t, address, reports[0].Address)
+}
+
+func TestRunOnce(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	from := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+
+	newProvider := func(pokes ...*core.OpPokedEvent) *coretest.MockProvider {
+		p := new(coretest.MockProvider)
+		p.On("BlockNumber", mock.Anything).Return(big.NewInt(1000), nil)
+		p.On("GetChallengePeriod", mock.Anything, address).Return(600, nil)
+		p.On("GetPokes", mock.Anything, address, big.NewInt(100), big.NewInt(1000)).Return(pokes, nil)
+		p.On("GetSuccessfulChallenges", mock.Anything, address, mock.Anything, mock.Anything).
+			Return([]*core.OpPokeChallengedSuccessfullyEvent{}, nil)
+		p.On("BlockByNumber", mock.Anything, mock.Anything).Return(&types.Block{Timestamp: time.Now()}, nil)
+		p.On("GetFrom", mock.Anything).Return(from)
+		return p
+	}
+
+	t.Run("clean", func(t *testing.T) {
+		poke := &core.OpPokedEvent{BlockNumber: big.NewInt(500)}
+		p := newProvider(poke)
+		p.On("IsPokeSignatureValid", mock.Anything, address, poke).Return(true, nil)
+
+		report, err := core.NewChallenger(context.TODO(), address, p, 100, nil).RunOnce()
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.InvalidPokes)
+		assert.Equal(t, 0, report.FailedChallenges)
+	})
+
+	t.Run("invalid poke challenged", func(t *testing.T) {
+		poke := &core.OpPokedEvent{BlockNumber: big.NewInt(500)}
+		p := newProvider(poke)
+		p.On("IsPokeSignatureValid", mock.Anything, address, poke).Return(false, nil)
+		p.On("ChallengePoke", mock.Anything, address, poke).Return(&txHash, (*types.Transaction)(nil), nil)
+
+		report, err := core.NewChallenger(context.TODO(), address, p, 100, nil).RunOnce()
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.InvalidPokes)
+		assert.Equal(t, 0, report.FailedChallenges)
+		p.AssertCalled(t, "ChallengePoke", mock.Anything, address, poke)
+	})
+
+	t.Run("challenge submission failed", func(t *testing.T) {
+		poke := &core.OpPokedEvent{BlockNumber: big.NewInt(500)}
+		p := newProvider(poke)
+		p.On("IsPokeSignatureValid", mock.Anything, address, poke).Return(false, nil)
+		p.On("ChallengePoke", mock.Anything, address, poke).
+			Return((*types.Hash)(nil), (*types.Transaction)(nil), fmt.Errorf("insufficient funds"))
+
+		report, err := core.NewChallenger(context.TODO(), address, p, 100, nil).RunOnce()
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.InvalidPokes)
+		assert.Equal(t, 1, report.FailedChallenges)
+	})
+}
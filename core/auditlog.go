@@ -0,0 +1,186 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+)
+
+// Kinds of audit log records.
+const (
+	AuditPokeSeen         = "poke_seen"
+	AuditVerdict          = "verdict"
+	AuditChallengeSent    = "challenge_sent"
+	AuditChallengeFailed  = "challenge_failed"
+	AuditChallengeOutcome = "challenge_outcome"
+)
+
+// AuditRecord is a record of a decision made by the challenger. Each record is chained to the previous one,
+// Hash is keccak256 of the JSON encoding of the record with zero Hash, which includes PrevHash.
+type AuditRecord struct {
+	Seq        uint64        `json:"seq"`
+	Time       time.Time     `json:"time"`
+	Kind       string        `json:"kind"`
+	Address    types.Address `json:"address"`
+	PokeBlock  uint64        `json:"pokeBlock"`
+	PokeTxHash *types.Hash   `json:"pokeTxHash,omitempty"`
+	Valid      *bool         `json:"valid,omitempty"`
+	Verified   bool          `json:"preVerified,omitempty"`
+	TxHash     *types.Hash   `json:"txHash,omitempty"`
+	Result     string        `json:"result,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	PrevHash   types.Hash    `json:"prevHash"`
+	Hash       types.Hash    `json:"hash"`
+}
+
+// hash returns the hash of the record.
+func (r AuditRecord) hash() (types.Hash, error) {
+	r.Hash = types.Hash{}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return types.Hash{}, err
+	}
+	return crypto.Keccak256(b), nil
+}
+
+// AuditLog is an append-only file of JSON records, one per line, chained by hashes, so removed, reordered or
+// modified records are detected by VerifyAuditLog. Each record is synced to disk before Record returns.
+type AuditLog struct {
+	mu   sync.Mutex
+	file *os.File
+	seq  uint64
+	last types.Hash
+}
+
+// OpenAuditLog opens the audit log for appending, creating it if it doesn't exist. The chain of existing records
+// is verified first, new records continue it.
+func OpenAuditLog(path string) (*AuditLog, error) {
+	l := &AuditLog{}
+	f, err := os.Open(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	default:
+		last, err := VerifyAuditLog(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("audit log %s is broken: %w", path, err)
+		}
+		if last != nil {
+			l.seq, l.last = last.Seq, last.Hash
+		}
+	}
+	l.file, err = os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return l, nil
+}
+
+// Record appends the record to the log, its Seq, Time and hashes are set by the log.
+func (l *AuditLog) Record(r AuditRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r.Seq = l.seq + 1
+	r.Time = time.Now().UTC()
+	r.PrevHash = l.last
+	hash, err := r.hash()
+	if err != nil {
+		return fmt.Errorf("failed to hash audit record: %w", err)
+	}
+	r.Hash = hash
+	b, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit record: %w", err)
+	}
+	if _, err := l.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit record: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync audit log: %w", err)
+	}
+	l.seq, l.last = r.Seq, r.Hash
+	return nil
+}
+
+// Close closes the log file.
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// VerifyAuditLog verifies the chain of records read from r and returns the last record, nil if there is none.
+func VerifyAuditLog(r io.Reader) (*AuditRecord, error) {
+	var last *AuditRecord
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var rec AuditRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("line %d: failed to decode record: %w", line, err)
+		}
+		var prev types.Hash
+		var seq uint64
+		if last != nil {
+			prev, seq = last.Hash, last.Seq
+		}
+		if rec.Seq != seq+1 {
+			return nil, fmt.Errorf("line %d: expected record %d, got %d", line, seq+1, rec.Seq)
+		}
+		if rec.PrevHash != prev {
+			return nil, fmt.Errorf("line %d: record %d is not chained to the previous record", line, rec.Seq)
+		}
+		hash, err := rec.hash()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: failed to hash record: %w", line, err)
+		}
+		if rec.Hash != hash {
+			return nil, fmt.Errorf("line %d: hash of record %d doesn't match its content", line, rec.Seq)
+		}
+		last = &rec
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return last, nil
+}
+
+// WithAuditLog makes Challenger record pokes seen, signature verdicts and challenges in the audit log.
+func WithAuditLog(l *AuditLog) ChallengerOption {
+	return func(c *Challenger) {
+		c.auditLog = l
+	}
+}
+
+// audit records the decision about the poke in the audit log, if configured.
+func (c *Challenger) audit(kind string, poke *OpPokedEvent, r AuditRecord) {
+	if c.auditLog == nil || poke == nil || poke.BlockNumber == nil {
+		return
+	}
+	r.Kind = kind
+	r.Address = c.address
+	r.PokeBlock = poke.BlockNumber.Uint64()
+	r.PokeTxHash = poke.TxHash
+	if err := c.auditLog.Record(r); err != nil {
+		c.log().Errorf("Failed to record %s of OpPoked event from block %v in audit log: %v", kind, poke.BlockNumber, err)
+	}
+}
+
+// auditError returns the error message for the audit record, empty if err is nil.
+func auditError(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
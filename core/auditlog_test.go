@@ -0,0 +1,58 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(100), TxHash: &txHash}
+
+	l, err := OpenAuditLog(path)
+	require.NoError(t, err)
+	c := NewChallenger(context.TODO(), address, nil, 0, nil, WithAuditLog(l))
+	valid := false
+	c.audit(AuditPokeSeen, poke, AuditRecord{})
+	c.audit(AuditVerdict, poke, AuditRecord{Valid: &valid})
+	require.NoError(t, l.Close())
+
+	// Reopened log continues the chain.
+	l, err = OpenAuditLog(path)
+	require.NoError(t, err)
+	c = NewChallenger(context.TODO(), address, nil, 0, nil, WithAuditLog(l))
+	c.audit(AuditChallengeSent, poke, AuditRecord{TxHash: &txHash})
+	require.NoError(t, l.Close())
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	last, err := VerifyAuditLog(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), last.Seq)
+	assert.Equal(t, AuditChallengeSent, last.Kind)
+	assert.Equal(t, uint64(100), last.PokeBlock)
+
+	lines := strings.SplitAfter(string(b), "\n")
+	// Modified record.
+	tampered := strings.Replace(string(b), `"valid":false`, `"valid":true`, 1)
+	_, err = VerifyAuditLog(strings.NewReader(tampered))
+	assert.ErrorContains(t, err, "doesn't match its content")
+	// Removed record.
+	_, err = VerifyAuditLog(strings.NewReader(lines[0] + lines[2]))
+	assert.ErrorContains(t, err, "expected record 2")
+
+	require.NoError(t, os.WriteFile(path, []byte(tampered), 0o600))
+	_, err = OpenAuditLog(path)
+	assert.Error(t, err)
+}
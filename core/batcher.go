@@ -0,0 +1,282 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// DefaultBatchWindow is the default time challenges are collected for before they are sent in one transaction.
+const DefaultBatchWindow = 2 * time.Second
+
+// Multicall3Address is the address of the public Multicall3 deployment, challenges can't be batched through it,
+// as anyone can take rewards it receives.
+var Multicall3Address = types.MustAddressFromHex("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+var (
+	// multicallAggregate3 is the Multicall3 compatible method used to send batched challenges.
+	multicallAggregate3 = abi.MustParseMethod(
+		"aggregate3((address target, bool allowFailure, bytes callData)[] calls) returns ((bool success, bytes returnData)[] returnData)",
+	)
+	batchContractOwner = abi.MustParseMethod("owner() returns (address)")
+)
+
+type multicallCall struct {
+	Target       types.Address `abi:"target"`
+	AllowFailure bool          `abi:"allowFailure"`
+	CallData     []byte        `abi:"callData"`
+}
+
+type batchedChallenge struct {
+	address types.Address
+	poke    *OpPokedEvent
+	sent    chan batchSent
+}
+
+type batchSent struct {
+	hash *types.Hash
+	tx   *types.Transaction
+	err  error
+}
+
+// ChallengeBatcher bundles challenges of different addresses found within a short window into one
+// Multicall3 compatible `aggregate3` transaction, saving latency and per-transaction gas overhead.
+// The contract calls `opChallenge`, so it receives the challenge rewards and must forward them to its owner,
+// see ValidateBatchContract. Batched challenges are sent through the client only, they are not persisted
+// in the pending store and not sent with flashbots.
+type ChallengeBatcher struct {
+	ctx       context.Context
+	client    RPCClient
+	clock     Clock
+	multicall types.Address
+	window    time.Duration
+
+	mu       sync.Mutex
+	pending  []*batchedChallenge
+	outcomes map[types.Address]chan TxOutcome
+
+	// Tag is appended to `opChallenge` calldata, see NewChallengeTag.
+	Tag []byte
+}
+
+// NewChallengeBatcher creates a new instance of ChallengeBatcher. The batch window is measured
+// by the clock of the context.
+func NewChallengeBatcher(
+	ctx context.Context,
+	client RPCClient,
+	multicall types.Address,
+	window time.Duration,
+) *ChallengeBatcher {
+	return &ChallengeBatcher{
+		ctx:       ctx,
+		client:    client,
+		clock:     clockFromContext(ctx),
+		multicall: multicall,
+		window:    window,
+		outcomes:  make(map[types.Address]chan TxOutcome),
+	}
+}
+
+// Wrap returns provider which challenges pokes under the address through the batcher.
+func (b *ChallengeBatcher) Wrap(address types.Address, provider IScribeOptimisticProvider) IScribeOptimisticProvider {
+	return &batchingProvider{
+		IScribeOptimisticProvider: provider,
+		batcher:                   b,
+		outcomes:                  b.outcomesOf(address),
+	}
+}
+
+// Submit adds the challenge to the current batch and waits until the batch is sent.
+func (b *ChallengeBatcher) Submit(
+	ctx context.Context,
+	address types.Address,
+	poke *OpPokedEvent,
+) (*types.Hash, *types.Transaction, error) {
+	req := &batchedChallenge{address: address, poke: poke, sent: make(chan batchSent, 1)}
+
+	b.mu.Lock()
+	b.pending = append(b.pending, req)
+	if len(b.pending) == 1 {
+		timer := b.clock.NewTimer(b.window)
+		go func() {
+			select {
+			case <-timer.C():
+				b.flush()
+			case <-b.ctx.Done():
+				timer.Stop()
+			}
+		}()
+	}
+	b.mu.Unlock()
+
+	select {
+	case res := <-req.sent:
+		return res.hash, res.tx, res.err
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+}
+
+func (b *ChallengeBatcher) outcomesOf(address types.Address) chan TxOutcome {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.outcomes[address]; !ok {
+		b.outcomes[address] = make(chan TxOutcome, txOutcomesBuffer)
+	}
+	return b.outcomes[address]
+}
+
+// flush sends collected challenges and starts watching the transaction.
+func (b *ChallengeBatcher) flush() {
+	b.mu.Lock()
+	batch := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	hash, tx, err := b.send(batch)
+	for _, req := range batch {
+		req.sent <- batchSent{hash: hash, tx: tx, err: err}
+	}
+	if err != nil {
+		return
+	}
+	go b.watch(hash, batch)
+}
+
+// send sends a single challenge directly, several of them through the multicall contract.
+func (b *ChallengeBatcher) send(batch []*batchedChallenge) (*types.Hash, *types.Transaction, error) {
+	calls := make([]multicallCall, len(batch))
+	for i, req := range batch {
+		calldata, err := EncodeChallengeCalldata(req.poke, b.Tag)
+		if err != nil {
+			return nil, nil, err
+		}
+		calls[i] = multicallCall{Target: req.address, AllowFailure: true, CallData: calldata}
+	}
+
+	tx := (&types.Transaction{}).SetTo(calls[0].Target).SetInput(calls[0].CallData)
+	if len(calls) > 1 {
+		calldata, err := multicallAggregate3.EncodeArgs(calls)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode aggregate3 args: %w", err)
+		}
+		tx = (&types.Transaction{}).SetTo(b.multicall).SetInput(calldata)
+	}
+
+	hash, tx, err := b.client.SendTransaction(b.ctx, tx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to send batched challenge transaction: %w", err)
+	}
+	logger.
+		WithField("multicall", b.multicall).
+		WithField("txHash", hash).
+		Infof("Sent batch of %d challenges", len(batch))
+	return hash, tx, nil
+}
+
+// watch waits for the batch transaction and reports the outcome of each challenge in it.
+func (b *ChallengeBatcher) watch(hash *types.Hash, batch []*batchedChallenge) {
+	receipt, err := WaitForTxConfirmation(b.ctx, b.client, hash, TxConfirmationTimeout)
+	for _, req := range batch {
+		outcome := TxOutcome{Address: req.address, Poke: req.poke, Hash: hash, Receipt: receipt, Err: err}
+		if err == nil && !hasChallengedEvent(receipt, req.address) {
+			outcome.Err = b.handleRevert(req, receipt)
+		} else if errors.Is(err, ErrTxReverted) {
+			ChallengeRevertsCounter.WithLabelValues(req.address.String(), RevertReasonUnknown).Inc()
+		}
+		select {
+		case b.outcomesOf(req.address) <- outcome:
+		case <-b.ctx.Done():
+			return
+		}
+	}
+}
+
+// handleRevert replays the challenge which failed within the mined batch as called by the batch contract,
+// logs its revert reason and updates metrics. Returned error wraps ErrChallengeReverted, and ErrAlreadyChallenged
+// if the poke was already challenged.
+func (b *ChallengeBatcher) handleRevert(req *batchedChallenge, receipt *types.TransactionReceipt) error {
+	category := RevertReasonUnknown
+	var reason error
+	calldata, err := EncodeChallengeCalldata(req.poke, b.Tag)
+	if err == nil {
+		tx := types.NewTransaction().SetFrom(b.multicall).SetTo(req.address).SetInput(calldata)
+		reason = GetRevertReason(b.ctx, b.client, tx, receipt)
+		category = ClassifyRevert(reason, nil, receipt)
+	}
+
+	logger.
+		WithField("address", req.address).
+		WithField("txHash", receipt.TransactionHash).
+		WithField("reason", category).
+		Errorf("challenge failed within batch mined in block %v: %s", receipt.BlockNumber, describeRevert(reason))
+	ChallengeRevertsCounter.WithLabelValues(req.address.String(), category).Inc()
+
+	if category == RevertReasonAlreadyChallenged {
+		return fmt.Errorf("%w: challenge of %v failed within batch %v with %s: %w", ErrChallengeReverted, req.address, receipt.TransactionHash, category, ErrAlreadyChallenged)
+	}
+	return fmt.Errorf("%w: challenge of %v failed within batch %v with %s", ErrChallengeReverted, req.address, receipt.TransactionHash, category)
+}
+
+// ValidateBatchContract verifies that challenges can be batched through the contract without losing rewards.
+// The contract must be owned by the challenger account, as it receives the rewards and only its owner can take
+// them, so the public Multicall3 deployment is rejected.
+func ValidateBatchContract(ctx context.Context, client RPCClient, contract, owner types.Address) error {
+	if contract == Multicall3Address {
+		return fmt.Errorf("%w: %v is the public Multicall3 deployment, anyone can take rewards paid to it", ErrBatchContractNotOwned, contract)
+	}
+	calldata, err := batchContractOwner.EncodeArgs()
+	if err != nil {
+		return fmt.Errorf("failed to encode owner call: %w", err)
+	}
+	b, _, err := client.Call(ctx, &types.Call{To: &contract, Input: calldata}, types.LatestBlockNumber)
+	if err != nil {
+		return fmt.Errorf("%w: failed to get owner of batch contract %v: %w", ErrRPCUnavailable, contract, err)
+	}
+	var contractOwner types.Address
+	if err := batchContractOwner.DecodeValues(b, &contractOwner); err != nil {
+		return fmt.Errorf("%w: batch contract %v doesn't implement owner(): %w", ErrBatchContractNotOwned, contract, err)
+	}
+	if contractOwner != owner {
+		return fmt.Errorf("%w: batch contract %v is owned by %v", ErrBatchContractNotOwned, contract, contractOwner)
+	}
+	return nil
+}
+
+// hasChallengedEvent returns true if the receipt contains `OpPokeChallengedSuccessfully` event emitted by the address.
+func hasChallengedEvent(receipt *types.TransactionReceipt, address types.Address) bool {
+	topic := ScribeOptimisticContractABI.Events["OpPokeChallengedSuccessfully"].Topic0()
+	for _, log := range receipt.Logs {
+		if log.Address == address && len(log.Topics) > 0 && log.Topics[0] == topic {
+			return true
+		}
+	}
+	return false
+}
+
+// batchingProvider challenges pokes through ChallengeBatcher, other calls go to the wrapped provider.
+type batchingProvider struct {
+	IScribeOptimisticProvider
+	batcher  *ChallengeBatcher
+	outcomes chan TxOutcome
+}
+
+// ChallengePoke implements IScribeOptimisticProvider interface.
+func (p *batchingProvider) ChallengePoke(
+	ctx context.Context,
+	address types.Address,
+	poke *OpPokedEvent,
+) (*types.Hash, *types.Transaction, error) {
+	return p.batcher.Submit(ctx, address, poke)
+}
+
+// ChallengeOutcomes implements IScribeOptimisticProvider interface.
+func (p *batchingProvider) ChallengeOutcomes() <-chan TxOutcome {
+	return p.outcomes
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChallengeBatcher(t *testing.T) {
+	multicall := types.MustAddressFromHex("0x3F7acDa376eF37EC371235a094113dF9Cb4EfEe3")
+	address1 := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	address2 := types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEe2")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	status := uint64(1)
+	challenged := ScribeOptimisticContractABI.Events["OpPokeChallengedSuccessfully"].Topic0()
+
+	receipt := &types.TransactionReceipt{
+		TransactionHash: txHash,
+		Status:          &status,
+		BlockNumber:     big.NewInt(200),
+		// Only the first challenge succeeded.
+		Logs: []types.Log{{Address: address1, Topics: []types.Hash{challenged}}},
+	}
+	noOpPoke := ScribeOptimisticContractABI.Errors["NoOpPokeToChallenge"].FourBytes().Bytes()
+
+	next := func(t *testing.T, p IScribeOptimisticProvider) TxOutcome {
+		select {
+		case outcome := <-p.ChallengeOutcomes():
+			return outcome
+		case <-time.After(time.Second):
+			t.Fatal("no outcome")
+			return TxOutcome{}
+		}
+	}
+
+	t.Run("challenges within window are sent in one multicall", func(t *testing.T) {
+		client := new(mockRpcClient)
+		client.On("SendTransaction", mock.Anything, mock.MatchedBy(func(tx *types.Transaction) bool {
+			return *tx.To == multicall && bytes.Equal(tx.Input[:4], multicallAggregate3.FourBytes().Bytes())
+		})).Return(&txHash, &types.Transaction{}, nil).Once()
+		client.On("GetTransactionReceipt", mock.Anything, txHash).Return(receipt, nil)
+		// The failed challenge is replayed as called by the multicall contract.
+		client.On("Call", mock.Anything, mock.MatchedBy(func(call *types.Call) bool {
+			return *call.From == multicall && *call.To == address2
+		}), types.BlockNumberFromUint64(200)).
+			Return([]byte(nil), nil, transport.NewRPCError(3, "execution reverted", noOpPoke)).Once()
+
+		clock := newFakeClock()
+		batcher := NewChallengeBatcher(ContextWithClock(context.Background(), clock), client, multicall, time.Minute)
+		p1 := batcher.Wrap(address1, nil)
+		p2 := batcher.Wrap(address2, nil)
+		before := testutil.ToFloat64(ChallengeRevertsCounter.WithLabelValues(address2.String(), RevertReasonAlreadyChallenged))
+
+		var wg sync.WaitGroup
+		for _, c := range []struct {
+			p       IScribeOptimisticProvider
+			address types.Address
+		}{{p1, address1}, {p2, address2}} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				hash, _, err := c.p.ChallengePoke(context.Background(), c.address, &OpPokedEvent{BlockNumber: big.NewInt(100)})
+				assert.NoError(t, err)
+				assert.Equal(t, &txHash, hash)
+			}()
+		}
+		// The batch is sent once the window passes on the clock.
+		require.Eventually(t, func() bool {
+			batcher.mu.Lock()
+			defer batcher.mu.Unlock()
+			return len(batcher.pending) == 2
+		}, time.Second, time.Millisecond)
+		client.AssertNotCalled(t, "SendTransaction", mock.Anything, mock.Anything)
+		clock.Advance(time.Minute)
+		wg.Wait()
+		// Confirmation is polled on the clock too.
+		require.Eventually(t, func() bool { return clock.Waiters() == 2 }, time.Second, time.Millisecond)
+		clock.Advance(TxConfirmationPollInterval)
+
+		require.NoError(t, next(t, p1).Err)
+		err := next(t, p2).Err
+		assert.ErrorIs(t, err, ErrTxReverted)
+		assert.ErrorIs(t, err, ErrAlreadyChallenged)
+		assert.Equal(t, before+1, testutil.ToFloat64(ChallengeRevertsCounter.WithLabelValues(address2.String(), RevertReasonAlreadyChallenged)))
+		client.AssertExpectations(t)
+	})
+
+	t.Run("single challenge is sent directly", func(t *testing.T) {
+		client := new(mockRpcClient)
+		client.On("SendTransaction", mock.Anything, mock.MatchedBy(func(tx *types.Transaction) bool {
+			return *tx.To == address1
+		})).Return(&txHash, &types.Transaction{}, nil).Once()
+		client.On("GetTransactionReceipt", mock.Anything, txHash).Return(receipt, nil)
+
+		batcher := NewChallengeBatcher(context.Background(), client, multicall, time.Millisecond)
+		p := batcher.Wrap(address1, nil)
+
+		_, _, err := p.ChallengePoke(context.Background(), address1, &OpPokedEvent{BlockNumber: big.NewInt(100)})
+		require.NoError(t, err)
+		require.NoError(t, next(t, p).Err)
+	})
+}
+
+func TestValidateBatchContract(t *testing.T) {
+	contract := types.MustAddressFromHex("0x3F7acDa376eF37EC371235a094113dF9Cb4EfEe3")
+	owner := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
+	encodeOwner := func(t *testing.T, address types.Address) []byte {
+		b, err := abi.EncodeValues(batchContractOwner.Outputs(), address)
+		require.NoError(t, err)
+		return b
+	}
+	ownerCall := mock.MatchedBy(func(call *types.Call) bool {
+		return *call.To == contract && bytes.Equal(call.Input, batchContractOwner.FourBytes().Bytes())
+	})
+
+	t.Run("owned contract", func(t *testing.T) {
+		client := new(mockRpcClient)
+		client.On("Call", mock.Anything, ownerCall, types.LatestBlockNumber).Return(encodeOwner(t, owner), nil, nil)
+		assert.NoError(t, ValidateBatchContract(context.Background(), client, contract, owner))
+	})
+
+	t.Run("contract owned by someone else", func(t *testing.T) {
+		client := new(mockRpcClient)
+		client.On("Call", mock.Anything, ownerCall, types.LatestBlockNumber).Return(encodeOwner(t, contract), nil, nil)
+		assert.ErrorIs(t, ValidateBatchContract(context.Background(), client, contract, owner), ErrBatchContractNotOwned)
+	})
+
+	t.Run("contract without owner", func(t *testing.T) {
+		client := new(mockRpcClient)
+		client.On("Call", mock.Anything, ownerCall, types.LatestBlockNumber).Return([]byte{}, nil, nil)
+		assert.ErrorIs(t, ValidateBatchContract(context.Background(), client, contract, owner), ErrBatchContractNotOwned)
+	})
+
+	t.Run("public Multicall3 deployment", func(t *testing.T) {
+		client := new(mockRpcClient)
+		assert.ErrorIs(t, ValidateBatchContract(context.Background(), client, Multicall3Address, owner), ErrBatchContractNotOwned)
+		client.AssertNotCalled(t, "Call", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// ErrBudgetExhausted is returned when the challenge budget doesn't allow another challenge.
+var ErrBudgetExhausted = errors.New("challenge budget exhausted")
+
+// Reasons of exhausted challenge budget, used as metric labels.
+const (
+	BudgetReasonRate  = "rate"
+	BudgetReasonSpend = "spend"
+)
+
+type budgetSpend struct {
+	at     time.Time
+	hash   types.Hash
+	amount *big.Int
+}
+
+// ChallengeBudget limits the number of challenges submitted per hour and the amount spent on their fees per day,
+// so a bug or malicious contract state can't drain the wallet. It's shared by challengers of all addresses,
+// which pass the time of their Clock.
+type ChallengeBudget struct {
+	mu             sync.Mutex
+	maxPerHour     int
+	maxSpendPerDay *big.Int
+	tokens         float64
+	refilledAt     time.Time
+	spends         []budgetSpend
+	exhausted      string
+}
+
+// NewChallengeBudget creates a new instance of ChallengeBudget. Challenges are limited by a token bucket
+// holding maxPerHour tokens refilled over an hour, 0 means no limit. Fees paid by challenges within
+// the last 24 hours are limited by maxSpendPerDay in wei, nil means no limit.
+func NewChallengeBudget(maxPerHour int, maxSpendPerDay *big.Int) *ChallengeBudget {
+	return &ChallengeBudget{
+		maxPerHour:     maxPerHour,
+		maxSpendPerDay: maxSpendPerDay,
+		tokens:         float64(maxPerHour),
+	}
+}
+
+// Take reserves budget for one challenge, it returns ErrBudgetExhausted if there is none left.
+// The budget is available again once tokens refill or old spends leave the 24 hours window.
+func (b *ChallengeBudget) Take(now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.maxSpendPerDay != nil {
+		if spent := b.spent(now); spent.Cmp(b.maxSpendPerDay) >= 0 {
+			return b.exhaust(BudgetReasonSpend, fmt.Errorf("%w: spent %s within 24 hours", ErrBudgetExhausted, NativeToken.Format(spent)))
+		}
+	}
+	if b.maxPerHour > 0 {
+		if b.refilledAt.IsZero() {
+			b.refilledAt = now
+		}
+		b.tokens += now.Sub(b.refilledAt).Hours() * float64(b.maxPerHour)
+		b.tokens = min(b.tokens, float64(b.maxPerHour))
+		b.refilledAt = now
+		if b.tokens < 1 {
+			return b.exhaust(BudgetReasonRate, fmt.Errorf("%w: %d challenges per hour submitted", ErrBudgetExhausted, b.maxPerHour))
+		}
+		b.tokens--
+	}
+	if b.exhausted != "" {
+		logger.Warn("Challenge budget available again, resuming challenge submission")
+		ChallengeBudgetExhaustedGauge.Set(0)
+		b.exhausted = ""
+	}
+	return nil
+}
+
+// Spend records fees paid by the challenge transaction. Batched challenges share the transaction,
+// its fees are recorded only once.
+func (b *ChallengeBudget) Spend(receipt *types.TransactionReceipt, now time.Time) {
+	if receipt == nil || receipt.EffectiveGasPrice == nil {
+		return
+	}
+	amount := new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), receipt.EffectiveGasPrice)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, s := range b.spends {
+		if s.hash == receipt.TransactionHash {
+			return
+		}
+	}
+	b.spends = append(b.spends, budgetSpend{at: now, hash: receipt.TransactionHash, amount: amount})
+}
+
+// spent returns the amount spent within the last 24 hours, older spends are forgotten.
+func (b *ChallengeBudget) spent(now time.Time) *big.Int {
+	since := now.Add(-24 * time.Hour)
+	total := new(big.Int)
+	kept := b.spends[:0]
+	for _, s := range b.spends {
+		if s.at.Before(since) {
+			continue
+		}
+		kept = append(kept, s)
+		total.Add(total, s.amount)
+	}
+	b.spends = kept
+	return total
+}
+
+// exhaust alerts once the budget gets exhausted and returns err.
+func (b *ChallengeBudget) exhaust(reason string, err error) error {
+	ChallengeBudgetExhaustedCounter.WithLabelValues(reason).Inc()
+	if b.exhausted != reason {
+		logger.Errorf("%v, challenge submission is paused until budget is available again", err)
+		ChallengeBudgetExhaustedGauge.Set(1)
+		b.exhausted = reason
+	}
+	return err
+}
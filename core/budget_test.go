@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChallengeBudgetRate(t *testing.T) {
+	now := time.Now()
+	b := NewChallengeBudget(2, nil)
+
+	require.NoError(t, b.Take(now))
+	require.NoError(t, b.Take(now))
+	assert.ErrorIs(t, b.Take(now), ErrBudgetExhausted)
+	assert.Equal(t, 1.0, testutil.ToFloat64(ChallengeBudgetExhaustedGauge))
+
+	// One token refills in half an hour.
+	now = now.Add(30 * time.Minute)
+	require.NoError(t, b.Take(now))
+	assert.Equal(t, 0.0, testutil.ToFloat64(ChallengeBudgetExhaustedGauge))
+	assert.ErrorIs(t, b.Take(now), ErrBudgetExhausted)
+
+	// Tokens don't accumulate over the bucket size.
+	now = now.Add(24 * time.Hour)
+	require.NoError(t, b.Take(now))
+	require.NoError(t, b.Take(now))
+	assert.ErrorIs(t, b.Take(now), ErrBudgetExhausted)
+}
+
+func TestChallengeBudgetSpend(t *testing.T) {
+	now := time.Now()
+	b := NewChallengeBudget(0, big.NewInt(1000))
+	hash1 := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	hash2 := types.MustHashFromHex("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", types.PadNone)
+
+	rejections := testutil.ToFloat64(ChallengeBudgetExhaustedCounter.WithLabelValues(BudgetReasonSpend))
+	require.NoError(t, b.Take(now))
+	b.Spend(&types.TransactionReceipt{TransactionHash: hash1, GasUsed: 60, EffectiveGasPrice: big.NewInt(10)}, now)
+	// Batched challenges share the receipt.
+	b.Spend(&types.TransactionReceipt{TransactionHash: hash1, GasUsed: 60, EffectiveGasPrice: big.NewInt(10)}, now)
+	require.NoError(t, b.Take(now))
+
+	now = now.Add(time.Hour)
+	b.Spend(&types.TransactionReceipt{TransactionHash: hash2, GasUsed: 40, EffectiveGasPrice: big.NewInt(10)}, now)
+	assert.ErrorIs(t, b.Take(now), ErrBudgetExhausted)
+	assert.Equal(t, rejections+1, testutil.ToFloat64(ChallengeBudgetExhaustedCounter.WithLabelValues(BudgetReasonSpend)))
+
+	// The first spend leaves the 24 hours window.
+	now = now.Add(23*time.Hour + time.Minute)
+	require.NoError(t, b.Take(now))
+}
+
+func TestSpawnChallengeWithBudget(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	from := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	poke1 := &OpPokedEvent{BlockNumber: big.NewInt(100)}
+	poke2 := &OpPokedEvent{BlockNumber: big.NewInt(101)}
+
+	p := new(mockScribeOptimisticProvider)
+	p.On("GetFrom", mock.Anything).Return(from)
+	p.On("ChallengePoke", mock.Anything, address, poke1).Return(&txHash, (*types.Transaction)(nil), nil)
+
+	p.On("ChallengePoke", mock.Anything, address, poke2).Return(&txHash, (*types.Transaction)(nil), nil)
+
+	clock := newFakeClock()
+	c := NewChallenger(context.TODO(), address, p, 0, nil, WithChallengeBudget(NewChallengeBudget(1, nil)), WithClock(clock))
+	c.SpawnChallenge(poke1)
+	c.submissions.Wait()
+	c.SpawnChallenge(poke2)
+	c.submissions.Wait()
+
+	p.AssertNotCalled(t, "ChallengePoke", mock.Anything, address, poke2)
+	// Poke stays challengeable on next ticks.
+	c.inFlightMu.Lock()
+	assert.NotContains(t, c.inFlight, uint64(101))
+	c.inFlightMu.Unlock()
+
+	// The budget refills with the challenger's clock.
+	clock.Advance(time.Hour)
+	c.SpawnChallenge(poke2)
+	c.submissions.Wait()
+	p.AssertCalled(t, "ChallengePoke", mock.Anything, address, poke2)
+}
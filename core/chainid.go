@@ -0,0 +1,36 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/defiweb/go-eth/types"
+)
+
+// CheckChainID verifies the RPC endpoint serves the chain of the expected ID, so challenges are not signed
+// for or sent to the wrong network.
+func CheckChainID(ctx context.Context, client RPCClient, expected uint64) error {
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get chain ID with error: %w: %w", ErrRPCUnavailable, err)
+	}
+	if chainID != expected {
+		return fmt.Errorf("%w: endpoint serves chain %d, expected %d", ErrChainMismatch, chainID, expected)
+	}
+	return nil
+}
+
+// CheckDeployed verifies contracts are deployed at the addresses on the chain of the client. Addresses
+// without code are most likely meant for another chain.
+func CheckDeployed(ctx context.Context, client CodeFetcher, addresses []types.Address) error {
+	for _, address := range addresses {
+		code, err := client.GetCode(ctx, address, types.LatestBlockNumber)
+		if err != nil {
+			return fmt.Errorf("failed to get code of %v with error: %w: %w", address, ErrRPCUnavailable, err)
+		}
+		if len(code) == 0 {
+			return fmt.Errorf("%w: no contract deployed at %v", ErrChainMismatch, address)
+		}
+	}
+	return nil
+}
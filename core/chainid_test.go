@@ -0,0 +1,38 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckChainID(t *testing.T) {
+	client := new(mockRpcClient)
+	client.On("ChainID", mock.Anything).Return(uint64(1), nil).Once()
+	assert.NoError(t, CheckChainID(context.TODO(), client, 1))
+
+	client.On("ChainID", mock.Anything).Return(uint64(100), nil).Once()
+	err := CheckChainID(context.TODO(), client, 1)
+	assert.ErrorIs(t, err, ErrChainMismatch)
+	assert.ErrorContains(t, err, "endpoint serves chain 100, expected 1")
+
+	client.On("ChainID", mock.Anything).Return(uint64(0), fmt.Errorf("connection refused")).Once()
+	err = CheckChainID(context.TODO(), client, 1)
+	assert.ErrorIs(t, err, ErrRPCUnavailable)
+	assert.NotErrorIs(t, err, ErrChainMismatch)
+}
+
+func TestCheckDeployed(t *testing.T) {
+	deployed := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	missing := types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEe2")
+	fetcher := &fakeCodeFetcher{code: map[types.Address][]byte{deployed: {0x60, 0x80}}}
+
+	assert.NoError(t, CheckDeployed(context.TODO(), fetcher, []types.Address{deployed}))
+	err := CheckDeployed(context.TODO(), fetcher, []types.Address{deployed, missing})
+	assert.ErrorIs(t, err, ErrChainMismatch)
+	assert.ErrorContains(t, err, missing.String())
+}
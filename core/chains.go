@@ -0,0 +1,86 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ChainPreset holds parameters tuned for a network ScribeOptimistic contracts are deployed on.
+type ChainPreset struct {
+	// ChainID is the chain ID transactions are signed for.
+	ChainID uint64
+	// BlockTime is the average time between blocks, used to convert the challenge period to blocks.
+	BlockTime time.Duration
+	// Confirmations is the number of blocks after which a poke is not expected to be reorged out.
+	Confirmations uint64
+	// TxType is the transaction type accepted by the chain: legacy or eip1559.
+	TxType string
+	// FlashbotRPCURL is the private relay challenges can be sent through, empty if the chain has none.
+	FlashbotRPCURL string
+	// FallbackGasLimit is the gas limit used when the estimation fails, 0 aborts the challenge instead.
+	FallbackGasLimit uint64
+	// GasToken is the native token gas is paid in.
+	GasToken GasToken
+}
+
+// ChainPresets are built-in presets selectable by name.
+var ChainPresets = map[string]ChainPreset{
+	"ethereum": {
+		ChainID:        1,
+		BlockTime:      12 * time.Second,
+		Confirmations:  2,
+		TxType:         "eip1559",
+		FlashbotRPCURL: "https://rpc.flashbots.net",
+		GasToken:       DefaultGasToken,
+	},
+	"gnosis": {
+		ChainID:       100,
+		BlockTime:     5 * time.Second,
+		Confirmations: 4,
+		TxType:        "eip1559",
+		GasToken:      GasToken{Symbol: "xDAI", Decimals: 18},
+	},
+	// L2 sequencers keep their mempool private, there is no relay to send challenges through.
+	"base": {
+		ChainID:   8453,
+		BlockTime: 2 * time.Second,
+		TxType:    "eip1559",
+		GasToken:  DefaultGasToken,
+	},
+	"scroll": {
+		ChainID:   534352,
+		BlockTime: 3 * time.Second,
+		TxType:    "eip1559",
+		GasToken:  DefaultGasToken,
+	},
+	// Gas used on zkSync includes pubdata, which varies with L1 gas price, estimation failing on transient
+	// state falls back to a generous limit.
+	"zksync": {
+		ChainID:          324,
+		BlockTime:        time.Second,
+		TxType:           "eip1559",
+		FallbackGasLimit: 5_000_000,
+		GasToken:         DefaultGasToken,
+	},
+}
+
+// LookupChainPreset returns the preset of the chain by its name.
+func LookupChainPreset(name string) (ChainPreset, error) {
+	preset, ok := ChainPresets[strings.ToLower(name)]
+	if !ok {
+		return ChainPreset{}, fmt.Errorf("unknown chain %q, supported chains are: %s", name, strings.Join(ChainPresetNames(), ", "))
+	}
+	return preset, nil
+}
+
+// ChainPresetNames returns sorted names of built-in chain presets.
+func ChainPresetNames() []string {
+	names := make([]string, 0, len(ChainPresets))
+	for name := range ChainPresets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,19 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupChainPreset(t *testing.T) {
+	preset, err := LookupChainPreset("Base")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(8453), preset.ChainID)
+	assert.Equal(t, 2*time.Second, preset.BlockTime)
+
+	_, err = LookupChainPreset("unknown")
+	assert.ErrorContains(t, err, "base, ethereum, gnosis, scroll, zksync")
+}
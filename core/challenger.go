@@ -17,21 +17,48 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
+	"math/rand/v2"
+	"runtime/debug"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	logger "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github.com/defiweb/go-eth/types"
 )
 
-const slotPeriodInSec = 12
+// DefaultBlockTime is the default time between blocks, used to convert the challenge period to blocks.
+const DefaultBlockTime = 12 * time.Second
 
 const OpPokedEventSig = "0xb9dc937c5e394d0c8f76e0e324500b88251b4c909ddc56232df10e2ea42b3c63"
 
+// DefaultVerifyConcurrency is the default number of pokes verified in parallel within one tick.
+const DefaultVerifyConcurrency = 4
+
+// DefaultVerifyTimeout is the default time limit for verifying a single poke.
+const DefaultVerifyTimeout = 30 * time.Second
+
+// TickInterval is the interval new events are polled at.
+var TickInterval = 30 * time.Second
+
+// DefaultFastTickInterval is the default interval new events are polled at while an invalid poke is not
+// challenged successfully yet or a fresh poke waits for confirmations.
+const DefaultFastTickInterval = 5 * time.Second
+
+// DefaultWatchdogIntervals is the default number of tick intervals a tick may run for before its loop is restarted.
+const DefaultWatchdogIntervals = 3
+
+// ConfirmationForceWindow is the time before the challenge window closes when pokes are processed
+// even if they don't have enough confirmations yet.
+var ConfirmationForceWindow = 2 * time.Minute
+
 type Challenger struct {
 	ctx                context.Context
 	address            types.Address
@@ -40,6 +67,274 @@ type Challenger struct {
 	wg                 *sync.WaitGroup
 	inFlight           map[uint64]struct{}
 	inFlightMu         sync.Mutex
+	unconfirmed        map[uint64]time.Time
+	retry              map[uint64]*OpPokedEvent
+	verifyConcurrency  int
+	verifyTimeout      time.Duration
+	sweeper            *RewardSweeper
+	exporter           *PayloadExporter
+	executor           *ChallengeExecutor
+	readOnly           bool
+	leader             LeaderElector
+	standby            map[uint64]*OpPokedEvent
+	lock               ChallengeLock
+	trigger            chan struct{}
+	confirmations      uint64
+	mempool            *MempoolWatcher
+	ageValidation      bool
+	maxPokeStaleness   time.Duration
+	checkedBlock       *big.Int
+	lastPokeAge        uint32
+	priceSource        PriceSource
+	deviationThreshold float64
+	regularPokes       bool
+	lastUpdateAge      uint32
+	challengePeriod    *uint16
+	contractState      bool
+	stateCheckedBlock  *big.Int
+	tickOffset         time.Duration
+	tickJitter         time.Duration
+	fastTickInterval   time.Duration
+	fastPolling        bool
+	explainer          *Explainer
+	awaitingPokes      int
+	checkpoints        CheckpointStore
+	detectedPokes      int
+	submissions        sync.WaitGroup
+	failedSubmissions  atomic.Int64
+	heartbeat          *Heartbeat
+	stats              *Stats
+	requestID          atomic.Value
+	budget             *ChallengeBudget
+	codeVerifier       *CodeVerifier
+	reverify           bool
+	validPokes         map[uint64]trackedPoke
+	minPokeAge         uint64
+	expiryThreshold    float64
+	expiryAlerted      map[uint64]struct{}
+	blockTime          time.Duration
+	sequencer          *SequencerMonitor
+	auditLog           *AuditLog
+	clock              Clock
+	hook               ChallengeHook
+	watchdogIntervals  int
+	tickMu             sync.Mutex
+	tickStarted        atomic.Int64
+}
+
+// ChallengerOption configures optional behavior of Challenger.
+type ChallengerOption func(*Challenger)
+
+// WithVerifyConcurrency sets the number of pokes verified in parallel within one tick.
+func WithVerifyConcurrency(n int) ChallengerOption {
+	return func(c *Challenger) {
+		if n > 0 {
+			c.verifyConcurrency = n
+		}
+	}
+}
+
+// WithVerifyTimeout sets the time limit for verifying a single poke, 0 means no limit.
+func WithVerifyTimeout(d time.Duration) ChallengerOption {
+	return func(c *Challenger) {
+		c.verifyTimeout = d
+	}
+}
+
+// WithRewardSweeper sweeps earned rewards to the beneficiary after each successful challenge.
+func WithRewardSweeper(sweeper *RewardSweeper) ChallengerOption {
+	return func(c *Challenger) {
+		c.sweeper = sweeper
+	}
+}
+
+// WithTickJitter delays every tick by random time up to d, so ticks of different addresses don't line up.
+func WithTickJitter(d time.Duration) ChallengerOption {
+	return func(c *Challenger) {
+		c.tickJitter = d
+	}
+}
+
+// WithWatchdog restarts the processing loop when a tick runs for more than n tick intervals,
+// e.g. on an RPC call without deadline, 0 disables the watchdog.
+func WithWatchdog(n int) ChallengerOption {
+	return func(c *Challenger) {
+		c.watchdogIntervals = n
+	}
+}
+
+// WithFastTickInterval sets the interval events are polled at while there is an invalid poke not challenged
+// successfully yet or a poke waiting for confirmations, 0 keeps polling at TickInterval.
+func WithFastTickInterval(d time.Duration) ChallengerOption {
+	return func(c *Challenger) {
+		c.fastTickInterval = d
+	}
+}
+
+// WithExplainer makes Challenger record explanations of poke verifications, including the exact contract calls.
+func WithExplainer(explainer *Explainer) ChallengerOption {
+	return func(c *Challenger) {
+		c.explainer = explainer
+	}
+}
+
+// WithCheckpointStore makes Challenger persist the last processed block after each tick and resume scanning
+// from it after restart, as long as it's still within the challenge window.
+func WithCheckpointStore(store CheckpointStore) ChallengerOption {
+	return func(c *Challenger) {
+		c.checkpoints = store
+	}
+}
+
+// WithHeartbeat makes Challenger record its ticks in the heartbeat, so supervisors can tell the loop is alive.
+func WithHeartbeat(h *Heartbeat) ChallengerOption {
+	return func(c *Challenger) {
+		c.heartbeat = h
+	}
+}
+
+// WithChallengeBudget makes Challenger pause challenge submission while the budget is exhausted.
+func WithChallengeBudget(budget *ChallengeBudget) ChallengerOption {
+	return func(c *Challenger) {
+		c.budget = budget
+	}
+}
+
+// WithCodeVerifier makes Challenger verify code of the address against the allowlist before each challenge,
+// so a proxy upgraded to an unknown implementation is not challenged.
+func WithCodeVerifier(v *CodeVerifier) ChallengerOption {
+	return func(c *Challenger) {
+		c.codeVerifier = v
+	}
+}
+
+// WithReverification makes Challenger verify pokes found valid again on each tick until their challenge window
+// closes, so pokes invalidated by later changes of the signer set are challenged too.
+func WithReverification() ChallengerOption {
+	return func(c *Challenger) {
+		c.reverify = true
+	}
+}
+
+// WithSequencerMonitor makes Challenger warn about challenges sent while the sequencer of the L2 chain is down.
+func WithSequencerMonitor(m *SequencerMonitor) ChallengerOption {
+	return func(c *Challenger) {
+		c.sequencer = m
+	}
+}
+
+// WithPayloadExporter makes Challenger export challenge payloads for an external keeper network
+// instead of submitting challenge transactions itself.
+func WithPayloadExporter(exporter *PayloadExporter) ChallengerOption {
+	return func(c *Challenger) {
+		c.exporter = exporter
+	}
+}
+
+// WithChallengeExecutor makes Challenger delegate challenges to an external command
+// instead of submitting challenge transactions itself.
+func WithChallengeExecutor(executor *ChallengeExecutor) ChallengerOption {
+	return func(c *Challenger) {
+		c.executor = executor
+	}
+}
+
+// WithReadOnly makes Challenger only report invalid pokes, by logs, metrics and the hook, and never submit challenges.
+func WithReadOnly() ChallengerOption {
+	return func(c *Challenger) {
+		c.readOnly = true
+	}
+}
+
+// WithLeaderElector makes Challenger submit challenges only while this instance is the leader.
+func WithLeaderElector(leader LeaderElector) ChallengerOption {
+	return func(c *Challenger) {
+		c.leader = leader
+	}
+}
+
+// WithChallengeLock makes Challenger consult the shared lock before submitting a challenge,
+// so cooperating instances don't challenge the same poke.
+func WithChallengeLock(lock ChallengeLock) ChallengerOption {
+	return func(c *Challenger) {
+		c.lock = lock
+	}
+}
+
+// WithConfirmations makes Challenger act on pokes only after n block confirmations,
+// unless their challenge window is about to close.
+func WithConfirmations(n uint64) ChallengerOption {
+	return func(c *Challenger) {
+		c.confirmations = n
+	}
+}
+
+// WithMinPokeAge makes Challenger challenge invalid pokes only once they are n blocks deep,
+// unless their challenge window is about to close. Pokes are verified as soon as they are found.
+func WithMinPokeAge(n uint64) ChallengerOption {
+	return func(c *Challenger) {
+		c.minPokeAge = n
+	}
+}
+
+// WithClock makes the challenger read the time and schedule ticks using the clock,
+// transaction confirmations of its challenges are waited for using the clock too.
+func WithClock(clock Clock) ChallengerOption {
+	return func(c *Challenger) {
+		c.clock = clock
+		c.ctx = ContextWithClock(c.ctx, clock)
+	}
+}
+
+// WithBlockTime sets the average time between blocks of the chain, so the start of the challenge window
+// is found on chains with blocks faster or slower than Ethereum's.
+func WithBlockTime(d time.Duration) ChallengerOption {
+	return func(c *Challenger) {
+		if d > 0 {
+			c.blockTime = d
+		}
+	}
+}
+
+// WithMempoolWatcher makes Challenger use signature verdicts of pokes verified while they were pending.
+func WithMempoolWatcher(m *MempoolWatcher) ChallengerOption {
+	return func(c *Challenger) {
+		c.mempool = m
+	}
+}
+
+// WithPokeAgeValidation makes Challenger alert on pokes with poke data in the future, not newer than
+// the previous one or older than maxStaleness when poked, 0 disables the staleness check.
+func WithPokeAgeValidation(maxStaleness time.Duration) ChallengerOption {
+	return func(c *Challenger) {
+		c.ageValidation = true
+		c.maxPokeStaleness = maxStaleness
+	}
+}
+
+// WithPriceDeviationMonitor makes Challenger alert when poked value deviates from the reference price
+// of its address by more than threshold, e.g. 0.01 for 1%. Addresses without a source are not monitored.
+func WithPriceDeviationMonitor(sources map[types.Address]PriceSource, threshold float64) ChallengerOption {
+	return func(c *Challenger) {
+		c.priceSource = sources[c.address]
+		c.deviationThreshold = threshold
+	}
+}
+
+// WithRegularPokeWatching makes Challenger also fetch regular `Poked` events, so time since the last update
+// of the feed, by either kind of poke, can be monitored.
+func WithRegularPokeWatching() ChallengerOption {
+	return func(c *Challenger) {
+		c.regularPokes = true
+	}
+}
+
+// WithContractStateWatching makes Challenger alert on events changing configuration of the contract,
+// e.g. dropped optimistic poke data, lifted or dropped feeds or auth changes.
+func WithContractStateWatching() ChallengerOption {
+	return func(c *Challenger) {
+		c.contractState = true
+	}
 }
 
 // NewChallenger creates a new instance of Challenger.
@@ -49,25 +344,43 @@ func NewChallenger(
 	provider IScribeOptimisticProvider,
 	fromBlock int64,
 	wg *sync.WaitGroup,
+	opts ...ChallengerOption,
 ) *Challenger {
+	// Scanning starts at fromBlock, the block before it is considered processed.
 	var latestBlock *big.Int
 	if fromBlock != 0 {
-		latestBlock = big.NewInt(fromBlock)
+		latestBlock = big.NewInt(fromBlock - 1)
 	}
-	return &Challenger{
+	c := &Challenger{
 		ctx:                ctx,
 		address:            address,
 		provider:           provider,
 		lastProcessedBlock: latestBlock,
 		wg:                 wg,
 		inFlight:           make(map[uint64]struct{}),
+		unconfirmed:        make(map[uint64]time.Time),
+		retry:              make(map[uint64]*OpPokedEvent),
+		standby:            make(map[uint64]*OpPokedEvent),
+		validPokes:         make(map[uint64]trackedPoke),
+		expiryAlerted:      make(map[uint64]struct{}),
+		trigger:            make(chan struct{}, 1),
+		verifyConcurrency:  DefaultVerifyConcurrency,
+		verifyTimeout:      DefaultVerifyTimeout,
+		fastTickInterval:   DefaultFastTickInterval,
+		blockTime:          DefaultBlockTime,
+		clock:              SystemClock,
+		hook:               NopChallengeHook{},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // Gets earliest block number we can look `OpPoked` events from.
 func (c *Challenger) getEarliestBlockNumber(lastBlock *big.Int, period uint16) *big.Int {
 	// Calculate the earliest block number.
-	blocksPerPeriod := uint64(period) / slotPeriodInSec
+	blocksPerPeriod := c.blocksPerPeriod(period)
 	if lastBlock.Cmp(big.NewInt(int64(blocksPerPeriod))) == -1 {
 		return big.NewInt(0)
 	}
@@ -75,254 +388,1149 @@ func (c *Challenger) getEarliestBlockNumber(lastBlock *big.Int, period uint16) *
 	return res
 }
 
+// blocksPerPeriod returns the number of blocks the challenge period spans.
+func (c *Challenger) blocksPerPeriod(period uint16) uint64 {
+	return uint64(time.Duration(period) * time.Second / c.blockTime)
+}
+
 func (c *Challenger) getFromBlockNumber(latestBlockNumber *big.Int, period uint16) (*big.Int, error) {
-	if c.lastProcessedBlock != nil {
-		return c.lastProcessedBlock, nil
-	}
 	if latestBlockNumber == nil {
 		return nil, fmt.Errorf("latest block number is nil")
 	}
-
 	// Calculating earliest block number we can try to challenge OpPoked event from.
 	earliestBlockNumber := c.getEarliestBlockNumber(latestBlockNumber, period)
-	return earliestBlockNumber, nil
+	if c.lastProcessedBlock != nil {
+		// Ranges are half-open, blocks up to the last processed one are not scanned again.
+		if c.lastProcessedBlock.Cmp(latestBlockNumber) <= 0 {
+			return new(big.Int).Add(c.lastProcessedBlock, big.NewInt(1)), nil
+		}
+		// The chain was reorganized below the processed block, or the endpoint fell behind. The fork block
+		// is not known, so the whole challenge window is scanned again, pokes seen before are not processed twice.
+		c.log().
+			WithField("lastProcessedBlock", c.lastProcessedBlock).
+			WithField("latestBlock", latestBlockNumber).
+			Warnf("Latest block is behind the processed block, scanning challenge window from block %v", earliestBlockNumber)
+		return earliestBlockNumber, nil
+	}
+	return c.startBlockNumber(latestBlockNumber, earliestBlockNumber), nil
 }
 
 func (c *Challenger) isPokeChallengeable(poke *OpPokedEvent, challengePeriod uint16) bool {
+	challengeable, _ := c.verifyPoke(c.ctx, poke, challengePeriod)
+	return challengeable
+}
+
+// verifyPoke checks if the poke is challengeable and returns the time its challenge window closes.
+func (c *Challenger) verifyPoke(ctx context.Context, poke *OpPokedEvent, challengePeriod uint16) (bool, time.Time) {
 	if poke == nil || poke.BlockNumber == nil {
-		logger.
-			WithField("address", c.address).
-			Info("OpPoked or block number is nil")
-		return false
+		c.log().Info("OpPoked or block number is nil")
+		return false, time.Time{}
 	}
-	block, err := c.provider.BlockByNumber(c.ctx, poke.BlockNumber)
+
+	ctx, span := tracer.Start(ctx, "Challenger.verifyPoke", trace.WithAttributes(
+		attribute.String("address", c.address.String()),
+		attribute.Int64("pokeBlock", poke.BlockNumber.Int64()),
+	))
+	defer span.End()
+
+	if c.verifyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.verifyTimeout)
+		defer cancel()
+	}
+
+	block, err := c.provider.BlockByNumber(ctx, poke.BlockNumber)
 	if err != nil {
-		logger.
-			WithField("address", c.address).
-			Errorf("Failed to get block by number %d with error: %v", poke.BlockNumber, err)
-		return false
+		c.log().Errorf("Failed to get block by number %d with error: %v", poke.BlockNumber, err)
+		span.RecordError(err)
+		return false, time.Time{}
 	}
-	challengeableSince := time.Now().Add(-time.Second * time.Duration(challengePeriod))
+	challengeableSince := c.clock.Now().Add(-time.Second * time.Duration(challengePeriod))
+	deadline := block.Timestamp.Add(time.Second * time.Duration(challengePeriod))
 
 	// Not challengeable by time
 	if block.Timestamp.Before(challengeableSince) {
-		logger.
-			WithField("address", c.address).
-			Infof("Not challengeable by time %v", challengeableSince)
-		return false
+		c.log().Infof("Not challengeable by time %v", challengeableSince)
+		return false, deadline
 	}
 
-	valid, err := c.provider.IsPokeSignatureValid(c.ctx, c.address, poke)
-	if err != nil {
-		logger.
-			WithField("address", c.address).
-			Errorf("Failed to verify OpPoked signature with error: %v", err)
-		return false
+	valid, verified := false, false
+	// Verdicts from the mempool are given before the poke is mined, re-verification asks the chain again.
+	if c.mempool != nil && !isReverification(ctx) {
+		valid, verified = c.mempool.Verdict(c.address, poke)
 	}
-	logger.
-		WithField("address", c.address).
-		Infof("Is opPoke signature valid? %v", valid)
+	var recorder *callRecorder
+	if !verified {
+		if c.explainer != nil {
+			recorder = &callRecorder{}
+			ctx = withCallRecorder(ctx, recorder)
+		}
+		valid, err = c.provider.IsPokeSignatureValid(ctx, c.address, poke)
+		if err != nil {
+			c.log().Errorf("Failed to verify OpPoked signature with error: %v", err)
+			span.RecordError(err)
+			c.explainVerification(poke, recorder, false, false, err)
+			c.audit(AuditVerdict, poke, AuditRecord{Error: err.Error()})
+			return false, deadline
+		}
+	}
+	c.explainVerification(poke, recorder, valid, verified, nil)
+	c.audit(AuditVerdict, poke, AuditRecord{Valid: &valid, Verified: verified})
+	span.SetAttributes(attribute.Bool("preVerified", verified))
+	span.SetAttributes(attribute.Bool("signatureValid", valid))
+	c.log().Infof("Is opPoke signature valid? %v", valid)
 
 	// Only challengeable if signature is not valid
-	return !valid
+	return !valid, deadline
+}
+
+// pickChallengeablePokes verifies given pokes in parallel, bounded by verifyConcurrency,
+// and returns challengeable ones ordered by their challenge deadline, the most urgent first.
+func (c *Challenger) pickChallengeablePokes(ctx context.Context, pokes []*OpPokedEvent, challengePeriod uint16) []*OpPokedEvent {
+	concurrency := c.verifyConcurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	// Pokes queued behind the concurrency limit are counted too, so bursts are visible.
+	awaiting := PokesAwaitingVerificationGauge.WithLabelValues(c.address.String())
+	awaiting.Add(float64(len(pokes)))
+
+	challengeable := make([]bool, len(pokes))
+	deadlines := make([]time.Time, len(pokes))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, poke := range pokes {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, poke *OpPokedEvent) {
+			defer func() {
+				awaiting.Dec()
+				<-sem
+				wg.Done()
+			}()
+			challengeable[i], deadlines[i] = c.verifyPoke(ctx, poke, challengePeriod)
+		}(i, poke)
+	}
+	wg.Wait()
+
+	var idx []int
+	for i, poke := range pokes {
+		if !challengeable[i] {
+			c.log().Debugf("Event from block %v is not challengeable", poke.BlockNumber)
+			c.trackValid(poke, deadlines[i])
+			continue
+		}
+		idx = append(idx, i)
+	}
+
+	// Less time remaining before the window closes, higher the priority.
+	sort.SliceStable(idx, func(i, j int) bool {
+		return deadlines[idx[i]].Before(deadlines[idx[j]])
+	})
+
+	var result []*OpPokedEvent
+	for _, i := range idx {
+		c.trackUnconfirmed(pokes[i], deadlines[i])
+		result = append(result, pokes[i])
+	}
+	return result
+}
+
+// pickNewPokes returns pokes from blocks not seen by previous ticks, ordered as on chain.
+// Unlike challenge processing, monitoring checks of poke values are done only once for each poke.
+func (c *Challenger) pickNewPokes(pokes []*OpPokedEvent) []*OpPokedEvent {
+	var result []*OpPokedEvent
+	for _, poke := range pokes {
+		if poke == nil || poke.BlockNumber == nil {
+			continue
+		}
+		// Blocks of delayed pokes, or the challenge window after a reorg, are scanned again, pokes from them
+		// were already seen.
+		if c.checkedBlock != nil && poke.BlockNumber.Cmp(c.checkedBlock) <= 0 {
+			continue
+		}
+		result = append(result, poke)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return isEventBefore(result[i], result[j])
+	})
+	if len(result) > 0 {
+		c.checkedBlock = new(big.Int).Set(result[len(result)-1].BlockNumber)
+	}
+	return result
+}
+
+// confirmedBlockNumber returns the latest block with enough confirmations, but never goes below the block
+// before fromBlock, so blocks processed before are not scanned again.
+func (c *Challenger) confirmedBlockNumber(latestBlockNumber *big.Int, fromBlock *big.Int) *big.Int {
+	if c.confirmations == 0 {
+		return latestBlockNumber
+	}
+	confirmed := new(big.Int).Sub(latestBlockNumber, new(big.Int).SetUint64(c.confirmations))
+	if prev := new(big.Int).Sub(fromBlock, big.NewInt(1)); confirmed.Cmp(prev) < 0 {
+		return prev
+	}
+	return confirmed
+}
+
+// pickConfirmedPokes filters out pokes without enough confirmations yet, they are left for next ticks.
+// Pokes which challenge window closes within ConfirmationForceWindow are kept anyway.
+func (c *Challenger) pickConfirmedPokes(ctx context.Context, pokes []*OpPokedEvent, latestBlockNumber *big.Int, challengePeriod uint16) []*OpPokedEvent {
+	if c.confirmations == 0 {
+		return pokes
+	}
+
+	confirmed := c.confirmedBlockNumber(latestBlockNumber, big.NewInt(0))
+	var result []*OpPokedEvent
+	c.awaitingPokes = 0
+	for _, poke := range pokes {
+		if poke == nil || poke.BlockNumber == nil || poke.BlockNumber.Cmp(confirmed) <= 0 {
+			result = append(result, poke)
+			continue
+		}
+
+		block, err := c.provider.BlockByNumber(ctx, poke.BlockNumber)
+		if err != nil {
+			c.log().Errorf("Failed to get block by number %d with error: %v", poke.BlockNumber, err)
+			continue
+		}
+		deadline := block.Timestamp.Add(time.Second * time.Duration(challengePeriod))
+		if deadline.Sub(c.clock.Now()) > ConfirmationForceWindow {
+			c.log().Debugf("OpPoked event from block %v doesn't have %d confirmations yet", poke.BlockNumber, c.confirmations)
+			c.awaitingPokes++
+			continue
+		}
+		c.log().Warnf("Processing OpPoked event from block %v before %d confirmations, challenge window closes at %v", poke.BlockNumber, c.confirmations, deadline)
+		result = append(result, poke)
+	}
+	return result
+}
+
+// pickAgedPokes filters out invalid pokes less than minPokeAge blocks deep, their challenge is delayed and their
+// blocks are scanned again on next ticks, so pokes reorged out in the meantime are never challenged.
+// Pokes which challenge window closes within ConfirmationForceWindow are kept anyway.
+func (c *Challenger) pickAgedPokes(pokes []*OpPokedEvent, latestBlockNumber *big.Int) []*OpPokedEvent {
+	if c.minPokeAge == 0 {
+		return pokes
+	}
+
+	var result []*OpPokedEvent
+	for _, poke := range pokes {
+		age := new(big.Int).Sub(latestBlockNumber, poke.BlockNumber)
+		if age.Cmp(new(big.Int).SetUint64(c.minPokeAge)) >= 0 {
+			result = append(result, poke)
+			continue
+		}
+		c.inFlightMu.Lock()
+		deadline := c.unconfirmed[poke.BlockNumber.Uint64()]
+		c.inFlightMu.Unlock()
+		if deadline.Sub(c.clock.Now()) > ConfirmationForceWindow {
+			c.log().Infof("Delaying challenge of OpPoked event from block %v until it's %d blocks deep", poke.BlockNumber, c.minPokeAge)
+			if prev := new(big.Int).Sub(poke.BlockNumber, big.NewInt(1)); c.lastProcessedBlock == nil || prev.Cmp(c.lastProcessedBlock) < 0 {
+				c.lastProcessedBlock = prev
+			}
+			continue
+		}
+		c.log().Warnf("Challenging OpPoked event from block %v only %v blocks deep, challenge window closes at %v", poke.BlockNumber, age, deadline)
+		result = append(result, poke)
+	}
+	return result
+}
+
+// trackUnconfirmed remembers the invalid poke until its challenge is confirmed or its window closes.
+func (c *Challenger) trackUnconfirmed(poke *OpPokedEvent, deadline time.Time) {
+	c.inFlightMu.Lock()
+	defer c.inFlightMu.Unlock()
+	c.unconfirmed[poke.BlockNumber.Uint64()] = deadline
+}
+
+// confirmChallenge forgets the poke which challenge was confirmed.
+func (c *Challenger) confirmChallenge(poke *OpPokedEvent) {
+	c.inFlightMu.Lock()
+	delete(c.unconfirmed, poke.BlockNumber.Uint64())
+	c.inFlightMu.Unlock()
+	c.updateChallengeWindowGauge()
+}
+
+// updateChallengeWindowGauge publishes seconds remaining in the challenge window
+// of the most recent unconfirmed invalid poke, or 0 if there is none.
+func (c *Challenger) updateChallengeWindowGauge() {
+	now := c.clock.Now()
+
+	c.inFlightMu.Lock()
+	var latest uint64
+	var remaining time.Duration
+	for blockNum, deadline := range c.unconfirmed {
+		if !deadline.After(now) {
+			// Window is closed, nothing can be done anymore.
+			delete(c.unconfirmed, blockNum)
+			continue
+		}
+		if blockNum >= latest {
+			latest = blockNum
+			remaining = deadline.Sub(now)
+		}
+	}
+	c.inFlightMu.Unlock()
+
+	ChallengeWindowRemainingGauge.WithLabelValues(c.address.String()).Set(remaining.Seconds())
+}
+
+// updateLastPokeGauges publishes value and age of the latest of new pokes, so oracle health can be monitored.
+func (c *Challenger) updateLastPokeGauges(pokes []*OpPokedEvent) {
+	if len(pokes) == 0 {
+		return
+	}
+	poke := pokes[len(pokes)-1]
+	if poke.PokeData.Val != nil {
+		value, _ := scaleDown(poke.PokeData.Val, pokeValueDecimals).Float64()
+		LastPokeValueGauge.WithLabelValues(c.address.String()).Set(value)
+	}
+	LastPokeAgeGauge.WithLabelValues(c.address.String()).Set(float64(poke.PokeData.Age))
+}
+
+// requestContext returns ctx carrying the request ID of the current tick.
+func (c *Challenger) requestContext(ctx context.Context) context.Context {
+	if id, ok := c.requestID.Load().(string); ok {
+		return WithRequestID(ctx, id)
+	}
+	return ctx
+}
+
+// log returns the log entry of the address with the request ID of the current tick.
+func (c *Challenger) log() *logger.Entry {
+	entry := logger.WithField("address", c.address)
+	if id, ok := c.requestID.Load().(string); ok {
+		entry = entry.WithField("requestId", id)
+	}
+	return entry
 }
 
 // SpawnChallenge spawns new goroutine and challenges the `OpPoked` event.
 // It skips the challenge if one is already in-flight for the same block number.
+// The poke stays in-flight until the challenge outcome is handled by Run. With ChallengeExecutor, sending
+// is replaced by running the command.
 func (c *Challenger) SpawnChallenge(poke *OpPokedEvent) {
 	blockNum := poke.BlockNumber.Uint64()
 
 	c.inFlightMu.Lock()
 	if _, ok := c.inFlight[blockNum]; ok {
 		c.inFlightMu.Unlock()
-		logger.
-			WithField("address", c.address).
-			Debugf("Skipping duplicate challenge for block %v, already in-flight", poke.BlockNumber)
+		c.log().Debugf("Skipping duplicate challenge for block %v, already in-flight", poke.BlockNumber)
 		return
 	}
 	c.inFlight[blockNum] = struct{}{}
+	delete(c.retry, blockNum)
+	c.updateInFlightGauge()
 	c.inFlightMu.Unlock()
 
+	// The challenge carries the request ID of the tick it's spawned by.
+	requestCtx := c.requestContext(c.ctx)
+	c.submissions.Add(1)
 	go func() {
-		defer func() {
-			c.inFlightMu.Lock()
-			delete(c.inFlight, blockNum)
-			c.inFlightMu.Unlock()
-		}()
-
-		logger.
-			WithField("address", c.address).
+		defer c.submissions.Done()
+		defer c.recoverChallenge(poke)
+
+		if !c.acquireChallengeLock(poke) {
+			c.clearInFlight(poke)
+			return
+		}
+		if c.codeVerifier != nil {
+			if _, err := c.codeVerifier.Verify(c.ctx, c.address); err != nil {
+				c.log().Errorf("Not challenging OpPoked event from block %v: %v", poke.BlockNumber, err)
+				c.audit(AuditChallengeFailed, poke, AuditRecord{Error: err.Error()})
+				c.hook.OnChallengeFailed(c.address, poke, err)
+				c.failedSubmissions.Add(1)
+				c.releaseChallengeLock(poke)
+				c.retryLater(poke)
+				return
+			}
+		}
+		if c.budget != nil {
+			if err := c.budget.Take(c.clock.Now()); err != nil {
+				// Poke is retried on next ticks, until the budget is available again or its window closes.
+				c.log().Errorf("Not challenging OpPoked event from block %v: %v", poke.BlockNumber, err)
+				c.audit(AuditChallengeFailed, poke, AuditRecord{Error: err.Error()})
+				c.hook.OnChallengeFailed(c.address, poke, err)
+				c.failedSubmissions.Add(1)
+				c.releaseChallengeLock(poke)
+				c.retryLater(poke)
+				return
+			}
+		}
+		if c.executor != nil {
+			c.delegateChallenge(requestCtx, poke)
+			return
+		}
+
+		ctx, span := tracer.Start(requestCtx, "Challenger.challenge", trace.WithAttributes(
+			attribute.String("address", c.address.String()),
+			attribute.Int64("pokeBlock", poke.BlockNumber.Int64()),
+		))
+
+		c.log().
+			WithField("pokeTxHash", poke.TxHash).
 			Warnf("Challenging OpPoked event from block %v", poke.BlockNumber)
-		txHash, _, err := c.provider.ChallengePoke(c.ctx, c.address, poke)
+		txHash, _, err := c.provider.ChallengePoke(ctx, c.address, poke)
+		endSpan(span, err)
 		if err != nil {
-			logger.
-				WithField("address", c.address).
-				Errorf("failed to challenge OpPoked event from block %v with error: %v", poke.BlockNumber, err)
+			c.log().Errorf("failed to challenge OpPoked event from block %v with error: %v", poke.BlockNumber, err)
+			c.audit(AuditChallengeFailed, poke, AuditRecord{Error: err.Error()})
+			c.hook.OnChallengeFailed(c.address, poke, err)
+			c.failedSubmissions.Add(1)
+			c.releaseChallengeLock(poke)
+			c.retryLater(poke)
 			return
 		}
-		logger.
-			WithField("address", c.address).
+		c.countChallenge(poke, txHash, ChallengeResultSubmitted)
+		c.audit(AuditChallengeSent, poke, AuditRecord{TxHash: txHash})
+		c.hook.OnChallengeSubmitted(c.address, poke, txHash)
+		// Poke stays in-flight until the outcome of the transaction is reported by the provider.
+		c.log().
 			WithField("txHash", txHash).
-			Infof("Challenge successful")
-
-		// Adding metrics
-		ChallengeCounter.WithLabelValues(
-			c.address.String(),
-			c.provider.GetFrom(c.ctx).String(),
-			txHash.String(),
-		).Inc()
+			Infof("Challenge transaction sent for OpPoked event from block %v", poke.BlockNumber)
 	}()
 }
 
-func (c *Challenger) executeTick() error {
-	latestBlockNumber, err := c.provider.BlockNumber(c.ctx)
+// recoverChallenge keeps panic in the challenge goroutine from crashing the whole process,
+// the poke is released and retried on next ticks.
+func (c *Challenger) recoverChallenge(poke *OpPokedEvent) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	PanicsCounter.WithLabelValues(c.address.String(), "challenge").Inc()
+	c.log().Errorf("Recovered from panic while challenging OpPoked event from block %v: %v", poke.BlockNumber, r)
+	logger.Debugf("Recovered panic stack: %s", debug.Stack())
+	c.hook.OnChallengeFailed(c.address, poke, fmt.Errorf("panic while challenging: %v", r))
+	c.failedSubmissions.Add(1)
+	c.releaseChallengeLock(poke)
+	c.retryLater(poke)
+}
+
+// Results of challenges, used as metric labels.
+const (
+	ChallengeResultSubmitted = "submitted"
+	ChallengeResultConfirmed = "confirmed"
+	ChallengeResultReverted  = "reverted"
+	ChallengeResultLostRace  = "lost_race"
+	ChallengeResultDropped   = "dropped"
+)
+
+// challengeResult categorizes the outcome error of the challenge transaction.
+func challengeResult(err error) string {
+	switch {
+	case err == nil:
+		return ChallengeResultConfirmed
+	case errors.Is(err, ErrAlreadyChallenged):
+		return ChallengeResultLostRace
+	case errors.Is(err, ErrTxReverted):
+		return ChallengeResultReverted
+	default:
+		return ChallengeResultDropped
+	}
+}
+
+func (c *Challenger) countChallenge(poke *OpPokedEvent, txHash *types.Hash, result string) {
+	ChallengeCounter.WithLabelValues(c.address.String(), c.provider.GetFrom(c.ctx).String(), result).Inc()
+	if c.stats != nil {
+		c.stats.RecordChallenge(c.address, poke, txHash, result)
+	}
+}
+
+// handleChallengeOutcome finalizes the challenge once its transaction is confirmed or failed.
+func (c *Challenger) handleChallengeOutcome(outcome TxOutcome) {
+	if outcome.Poke == nil || outcome.Poke.BlockNumber == nil {
+		return
+	}
+	defer c.clearInFlight(outcome.Poke)
+
+	if c.budget != nil {
+		c.budget.Spend(outcome.Receipt, c.clock.Now())
+	}
+	c.countChallenge(outcome.Poke, outcome.Hash, challengeResult(outcome.Err))
+	c.audit(AuditChallengeOutcome, outcome.Poke, AuditRecord{
+		TxHash: outcome.Hash,
+		Result: challengeResult(outcome.Err),
+		Error:  auditError(outcome.Err),
+	})
+	if outcome.Err != nil {
+		c.log().
+			WithField("txHash", outcome.Hash).
+			Errorf("failed to challenge OpPoked event from block %v with error: %v", outcome.Poke.BlockNumber, outcome.Err)
+		c.hook.OnChallengeFailed(c.address, outcome.Poke, outcome.Err)
+		c.releaseChallengeLock(outcome.Poke)
+		// Blocks of the poke are not scanned again, the dropped challenge is sent again while the window is open.
+		if challengeResult(outcome.Err) == ChallengeResultDropped {
+			c.retryLater(outcome.Poke)
+		}
+		return
+	}
+	c.log().
+		WithField("txHash", outcome.Hash).
+		Infof("Challenge successful")
+	c.confirmChallenge(outcome.Poke)
+	c.hook.OnChallengeConfirmed(c.address, outcome.Poke, outcome.Receipt)
+
+	// Sweeping waits for its own transaction, it must not block the processing loop.
+	go c.sweepRewards()
+}
+
+// resumePending marks challenges sent before restart as in-flight, so their pokes are not challenged again
+// with a new nonce. Their outcomes are handled as usual.
+func (c *Challenger) resumePending() {
+	resumer, ok := c.provider.(PendingResumer)
+	if !ok {
+		return
+	}
+	pokes, err := resumer.ResumePending(c.ctx, c.address)
+	if err != nil {
+		c.log().Errorf("Failed to resume pending challenges with error: %v", err)
+		return
+	}
+	c.inFlightMu.Lock()
+	defer c.inFlightMu.Unlock()
+	for _, poke := range pokes {
+		c.inFlight[poke.BlockNumber.Uint64()] = struct{}{}
+	}
+	c.updateInFlightGauge()
+}
+
+// clearInFlight allows the poke to be challenged again.
+func (c *Challenger) clearInFlight(poke *OpPokedEvent) {
+	c.inFlightMu.Lock()
+	delete(c.inFlight, poke.BlockNumber.Uint64())
+	c.updateInFlightGauge()
+	c.inFlightMu.Unlock()
+}
+
+// retryLater releases the poke which challenge wasn't sent, it's challenged again on next ticks
+// as long as its challenge window is open.
+func (c *Challenger) retryLater(poke *OpPokedEvent) {
+	blockNum := poke.BlockNumber.Uint64()
+	c.inFlightMu.Lock()
+	defer c.inFlightMu.Unlock()
+	delete(c.inFlight, blockNum)
+	c.updateInFlightGauge()
+	if _, ok := c.unconfirmed[blockNum]; ok {
+		c.retry[blockNum] = poke
+	}
+}
+
+// retryChallenges challenges again pokes which challenge wasn't sent, unless they were challenged by someone else
+// in the meantime or their challenge window closed. Pokes are kept for later while the instance is in standby.
+func (c *Challenger) retryChallenges(ctx context.Context, latestBlockNumber *big.Int) error {
+	if c.leader != nil && !c.leader.IsLeader() {
+		return nil
+	}
+
+	var pokes []*OpPokedEvent
+	var fromBlock *big.Int
+	c.inFlightMu.Lock()
+	for blockNum, poke := range c.retry {
+		if _, ok := c.unconfirmed[blockNum]; !ok {
+			// Challenge window is closed.
+			c.log().Errorf("Challenge window of OpPoked event from block %v closed before its challenge was sent", poke.BlockNumber)
+			delete(c.retry, blockNum)
+			continue
+		}
+		if poke.BlockNumber.Cmp(latestBlockNumber) > 0 {
+			// Block was reorged out, the poke is challenged again if the block range rescan finds it.
+			delete(c.retry, blockNum)
+			continue
+		}
+		pokes = append(pokes, poke)
+		if fromBlock == nil || poke.BlockNumber.Cmp(fromBlock) < 0 {
+			fromBlock = poke.BlockNumber
+		}
+	}
+	c.inFlightMu.Unlock()
+	if len(pokes) == 0 {
+		return nil
+	}
+
+	challenges, err := c.provider.GetSuccessfulChallenges(ctx, c.address, fromBlock, latestBlockNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get OpPokeChallengedSuccessfully events with error: %w", err)
+	}
+	sort.Slice(pokes, func(i, j int) bool {
+		return pokes[i].BlockNumber.Cmp(pokes[j].BlockNumber) < 0
+	})
+	c.inFlightMu.Lock()
+	for _, poke := range pokes {
+		delete(c.retry, poke.BlockNumber.Uint64())
+	}
+	c.inFlightMu.Unlock()
+	for _, poke := range PickUnchallengedPokes(pokes, challenges) {
+		c.log().Warnf("Retrying challenge of OpPoked event from block %v", poke.BlockNumber)
+		c.SpawnChallenge(poke)
+	}
+	return nil
+}
+
+// updateInFlightGauge publishes the number of challenges awaiting confirmation, inFlightMu must be held.
+func (c *Challenger) updateInFlightGauge() {
+	ChallengesAwaitingConfirmationGauge.WithLabelValues(c.address.String()).Set(float64(len(c.inFlight)))
+}
+
+// acquireChallengeLock returns false if another instance already holds the lock for the poke.
+// If the lock can't be consulted, the poke is challenged anyway, missing the window costs more than duplicate gas.
+func (c *Challenger) acquireChallengeLock(poke *OpPokedEvent) bool {
+	if c.lock == nil {
+		return true
+	}
+
+	c.inFlightMu.Lock()
+	deadline := c.unconfirmed[poke.BlockNumber.Uint64()]
+	c.inFlightMu.Unlock()
+
+	var ttl time.Duration
+	if !deadline.IsZero() {
+		ttl = deadline.Sub(c.clock.Now())
+	}
+	ok, err := c.lock.TryLock(c.ctx, c.address, poke, ttl)
 	if err != nil {
-		return fmt.Errorf("failed to get latest block number with error: %v", err)
+		c.log().Errorf("Failed to acquire challenge lock for block %v, challenging anyway: %v", poke.BlockNumber, err)
+		return true
+	}
+	if !ok {
+		c.log().Infof("OpPoked event from block %v is being challenged by another instance, skipping", poke.BlockNumber)
+	}
+	return ok
+}
+
+// releaseChallengeLock lets other instances retry the challenge which failed on this instance.
+func (c *Challenger) releaseChallengeLock(poke *OpPokedEvent) {
+	if c.lock == nil {
+		return
+	}
+	if err := c.lock.Unlock(c.ctx, c.address, poke); err != nil {
+		c.log().Errorf("Failed to release challenge lock for block %v with error: %v", poke.BlockNumber, err)
+	}
+}
+
+// takeOverStandbyPokes challenges invalid pokes seen while in standby, once this instance becomes the leader,
+// unless they were challenged by the previous leader or their challenge window is already closed.
+func (c *Challenger) takeOverStandbyPokes(ctx context.Context, latestBlockNumber *big.Int) error {
+	if len(c.standby) == 0 || c.leader == nil || !c.leader.IsLeader() {
+		return nil
+	}
+
+	var pokes []*OpPokedEvent
+	var fromBlock *big.Int
+	c.inFlightMu.Lock()
+	for blockNum, poke := range c.standby {
+		if _, ok := c.unconfirmed[blockNum]; !ok {
+			// Challenge window is closed or challenge was confirmed.
+			delete(c.standby, blockNum)
+			continue
+		}
+		pokes = append(pokes, poke)
+		if fromBlock == nil || poke.BlockNumber.Cmp(fromBlock) < 0 {
+			fromBlock = poke.BlockNumber
+		}
+	}
+	c.inFlightMu.Unlock()
+	if len(pokes) == 0 {
+		return nil
+	}
+
+	challenges, err := c.provider.GetSuccessfulChallenges(ctx, c.address, fromBlock, latestBlockNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get OpPokeChallengedSuccessfully events with error: %w", err)
+	}
+	sort.Slice(pokes, func(i, j int) bool {
+		return pokes[i].BlockNumber.Cmp(pokes[j].BlockNumber) < 0
+	})
+	for _, poke := range pokes {
+		delete(c.standby, poke.BlockNumber.Uint64())
+	}
+	for _, poke := range PickUnchallengedPokes(pokes, challenges) {
+		c.log().Warnf("Taking over OpPoked event from block %v seen in standby", poke.BlockNumber)
+		if c.exporter != nil {
+			c.exportPayload(poke)
+			continue
+		}
+		c.SpawnChallenge(poke)
+	}
+	return nil
+}
+
+// challengePayload returns the executable challenge of the poke, its calldata is followed by the tag.
+func (c *Challenger) challengePayload(poke *OpPokedEvent, tag []byte) (ChallengePayload, error) {
+	calldata, err := EncodeChallengeCalldata(poke, tag)
+	if err != nil {
+		return ChallengePayload{}, err
+	}
+
+	c.inFlightMu.Lock()
+	deadline := c.unconfirmed[poke.BlockNumber.Uint64()]
+	c.inFlightMu.Unlock()
+
+	return ChallengePayload{
+		Target:      c.address,
+		Calldata:    fmt.Sprintf("0x%x", calldata),
+		BlockNumber: poke.BlockNumber.Uint64(),
+		Deadline:    deadline,
+		PokeTxHash:  poke.TxHash,
+	}, nil
+}
+
+// exportPayload hands the challenge over to the external keeper network.
+func (c *Challenger) exportPayload(poke *OpPokedEvent) {
+	payload, err := c.challengePayload(poke, c.exporter.Tag)
+	if err != nil {
+		c.log().Errorf("Failed to encode challenge payload for block %v with error: %v", poke.BlockNumber, err)
+		return
+	}
+	if err := c.exporter.Export(c.ctx, payload); err != nil {
+		c.log().Errorf("Failed to export challenge payload for block %v with error: %v", poke.BlockNumber, err)
+		return
+	}
+	c.log().Warnf("Exported challenge payload for OpPoked event from block %v", poke.BlockNumber)
+}
+
+// delegateChallenge hands the challenge over to the external command instead of sending the transaction.
+// The command owns the challenge once it succeeds, so the poke isn't kept in-flight.
+func (c *Challenger) delegateChallenge(ctx context.Context, poke *OpPokedEvent) {
+	payload, err := c.challengePayload(poke, c.executor.Tag)
+	if err == nil {
+		c.log().
+			WithField("pokeTxHash", poke.TxHash).
+			Warnf("Delegating challenge of OpPoked event from block %v to external command", poke.BlockNumber)
+		var output string
+		output, err = c.executor.Execute(ctx, payload)
+		if err == nil {
+			c.clearInFlight(poke)
+			c.log().
+				WithField("output", output).
+				Infof("Challenge of OpPoked event from block %v delegated to external command", poke.BlockNumber)
+			return
+		}
+	}
+	c.log().Errorf("Failed to delegate challenge of OpPoked event from block %v with error: %v", poke.BlockNumber, err)
+	c.audit(AuditChallengeFailed, poke, AuditRecord{Error: err.Error()})
+	c.hook.OnChallengeFailed(c.address, poke, err)
+	c.failedSubmissions.Add(1)
+	c.releaseChallengeLock(poke)
+	c.retryLater(poke)
+}
+
+// sweepRewards transfers earned rewards to the beneficiary if reward sweeper is configured.
+func (c *Challenger) sweepRewards() {
+	if c.sweeper == nil {
+		return
+	}
+	txHash, amount, err := c.sweeper.Sweep(c.ctx)
+	if err != nil {
+		c.log().Errorf("Failed to sweep rewards with error: %v", err)
+		return
+	}
+	if txHash != nil {
+		c.log().
+			WithField("txHash", txHash).
+			Infof("Swept %v wei of rewards", amount)
+	}
+}
+
+// executeTick processes events up to the latest block. Calls made by the tick are bound by ctx,
+// challenges of invalid pokes found are submitted in the background bound by the challenger context.
+func (c *Challenger) executeTick(ctx context.Context) (err error) {
+	ctx, span := tracer.Start(
+		ctx,
+		"Challenger.executeTick",
+		trace.WithAttributes(attribute.String("address", c.address.String())),
+	)
+	defer func() { endSpan(span, err) }()
+
+	latestBlockNumber, err := c.provider.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get latest block number with error: %w", err)
 	}
 
 	// Fetching challenge period.
-	period, err := c.provider.GetChallengePeriod(c.ctx, c.address)
+	period, err := c.provider.GetChallengePeriod(ctx, c.address)
 	if err != nil {
-		return fmt.Errorf("failed to get challenge period with error: %v", err)
+		return fmt.Errorf("failed to get challenge period with error: %w", err)
 	}
+	c.observeChallengePeriod(period)
+
+	span.SetAttributes(attribute.Int64("latestBlock", latestBlockNumber.Int64()))
 
 	fromBlockNumber, err := c.getFromBlockNumber(latestBlockNumber, period)
 	if err != nil {
-		return fmt.Errorf("failed to get blocknumber from period: %v", err)
+		return fmt.Errorf("failed to get blocknumber from period: %w", err)
 	}
 
-	logger.
-		WithField("address", c.address).
-		Debugf("Block number to start with: %d", fromBlockNumber)
+	sampledDebugf(LogCategoryTick, c.log(), "Block number to start with: %d", fromBlockNumber)
 
-	pokeLogs, err := c.provider.GetPokes(c.ctx, c.address, fromBlockNumber, latestBlockNumber)
-	if err != nil {
-		return fmt.Errorf("failed to get OpPoked events with error: %v", err)
+	// Without new blocks since the last tick, there is nothing to scan, pokes seen before are still re-verified
+	// and taken over below.
+	var pokeLogs []*OpPokedEvent
+	if fromBlockNumber.Cmp(latestBlockNumber) <= 0 {
+		pokeLogs, err = c.provider.GetPokes(ctx, c.address, fromBlockNumber, latestBlockNumber)
+		if err != nil {
+			return fmt.Errorf("failed to get OpPoked events with error: %w", err)
+		}
 	}
 
-	// Set updated block we processed.
-	c.lastProcessedBlock = latestBlockNumber
+	// Set updated block we processed, blocks without enough confirmations are scanned again on next tick.
+	c.lastProcessedBlock = c.confirmedBlockNumber(latestBlockNumber, fromBlockNumber)
+	defer c.updateChallengeWindowGauge()
+	defer c.alertExpiringWindows()
 
 	// Fulfill block number in metrics
 	asFloat64, _ := new(big.Float).SetInt(latestBlockNumber).Float64()
-	LastScannedBlockGauge.WithLabelValues(c.address.String(), c.provider.GetFrom(c.ctx).String()).Set(asFloat64)
+	LastScannedBlockGauge.WithLabelValues(c.address.String(), c.provider.GetFrom(ctx).String()).Set(asFloat64)
+
+	if err := c.takeOverStandbyPokes(ctx, latestBlockNumber); err != nil {
+		return err
+	}
+	if err := c.retryChallenges(ctx, latestBlockNumber); err != nil {
+		return err
+	}
+	if err := c.reverifyPokes(ctx, fromBlockNumber, latestBlockNumber, period); err != nil {
+		return err
+	}
+
+	newPokes := c.pickNewPokes(pokeLogs)
+	for _, poke := range newPokes {
+		c.audit(AuditPokeSeen, poke, AuditRecord{})
+	}
+	c.updateLastPokeGauges(newPokes)
+	if c.stats != nil {
+		c.stats.RecordPokes(c.address, newPokes)
+	}
+	c.watchRegularPokes(ctx, fromBlockNumber, latestBlockNumber, newPokes)
+	c.watchContractState(ctx, fromBlockNumber, latestBlockNumber)
 
 	if len(pokeLogs) == 0 {
-		logger.
-			WithField("address", c.address).
-			Debugf("No logs found")
+		sampledDebugf(LogCategoryTick, c.log(), "No logs found")
 		return nil
 	}
+	c.checkPokeAges(ctx, newPokes)
+	c.checkPriceDeviation(ctx, newPokes)
 
-	challenges, err := c.provider.GetSuccessfulChallenges(c.ctx, c.address, fromBlockNumber, latestBlockNumber)
+	challenges, err := c.provider.GetSuccessfulChallenges(ctx, c.address, fromBlockNumber, latestBlockNumber)
 	if err != nil {
-		return fmt.Errorf("failed to get OpPokeChallengedSuccessfully events with error: %v", err)
+		return fmt.Errorf("failed to get OpPokeChallengedSuccessfully events with error: %w", err)
 	}
 	// Filtering out pokes that were already challenged.
 	pokes := PickUnchallengedPokes(pokeLogs, challenges)
+	span.SetAttributes(attribute.Int("pokes", len(pokeLogs)), attribute.Int("unchallengedPokes", len(pokes)))
+	pokes = c.pickConfirmedPokes(ctx, pokes, latestBlockNumber, period)
 
-	for _, poke := range pokes {
-		if !c.isPokeChallengeable(poke, period) {
-			logger.
-				WithField("address", c.address).
-				Debugf("Event from block %v is not challengeable", poke.BlockNumber)
-			continue
-		}
-
-		c.SpawnChallenge(poke)
+	for _, poke := range c.pickAgedPokes(c.pickChallengeablePokes(ctx, pokes, period), latestBlockNumber) {
+		c.challengePoke(poke)
 	}
 
 	return nil
 }
 
+// challengePoke challenges the invalid poke, unless the instance is read-only or in standby, or challenges
+// are exported or delegated.
+func (c *Challenger) challengePoke(poke *OpPokedEvent) {
+	c.detectedPokes++
+	c.inFlightMu.Lock()
+	_, inFlight := c.inFlight[poke.BlockNumber.Uint64()]
+	c.inFlightMu.Unlock()
+	if !inFlight {
+		InvalidPokesCounter.WithLabelValues(c.address.String()).Inc()
+		c.hook.OnPokeDetected(c.address, poke)
+	}
+	if c.stats != nil {
+		c.stats.RecordInvalidPoke(c.address, poke)
+	}
+	if c.sequencer != nil && !c.sequencer.Up() {
+		c.log().Errorf("Sequencer is down, challenge of OpPoked event from block %v may not be included in time", poke.BlockNumber)
+	}
+	// Read-only instances never act on chain, neither directly nor through an exporter or executor.
+	if c.readOnly {
+		c.log().Errorf("Invalid OpPoked event from block %v is not challenged in read-only mode", poke.BlockNumber)
+		return
+	}
+	if c.leader != nil && !c.leader.IsLeader() {
+		c.log().Warnf("Instance is in standby, leaving OpPoked event from block %v to the leader", poke.BlockNumber)
+		c.standby[poke.BlockNumber.Uint64()] = poke
+		return
+	}
+	if c.exporter != nil {
+		c.exportPayload(poke)
+		return
+	}
+	if c.executor != nil {
+		c.SpawnChallenge(poke)
+		return
+	}
+	// Estimation takes a few RPC calls, it must not delay the challenge.
+	go c.logChallengeEstimate(poke)
+	c.SpawnChallenge(poke)
+}
+
 func (c *Challenger) handleTickError(err error) {
 	if err == nil {
 		return
 	}
-	logger.
-		WithField("address", c.address).
-		Errorf("Failed to execute tick with error: %v", err)
+	c.log().Errorf("Failed to execute tick with error: %v", err)
 	ErrorsCounter.WithLabelValues(
 		c.address.String(),
 		c.provider.GetFrom(c.ctx).String(),
 	).Inc()
 }
 
+// tick processes new events and records the tick time, so a dead loop can be detected.
+// The checkpoint is only saved after a successful tick, so a failed one is scanned again after restart.
+// Calls of the tick are bound by TickInterval, so a slow call can't spill into the next tick.
+// Each tick has its own request ID, logged and sent with its RPC requests, so they can be correlated by providers.
+func (c *Challenger) tick() {
+	c.tickWithContext(c.ctx)
+}
+
+// tickWithContext executes the tick bound by ctx. Ticks don't overlap, if the previous one is still running,
+// e.g. one left behind by a loop restarted by the watchdog, the tick is skipped.
+func (c *Challenger) tickWithContext(ctx context.Context) {
+	if !c.tickMu.TryLock() {
+		c.log().Warnf("Skipping tick, the previous one is still running")
+		return
+	}
+	defer c.tickMu.Unlock()
+	c.tickStarted.Store(c.clock.Now().UnixNano())
+	defer c.tickStarted.Store(0)
+
+	c.requestID.Store(NewRequestID())
+	ctx, cancel := context.WithTimeout(c.requestContext(ctx), TickInterval)
+	err := c.executeTick(ctx)
+	cancel()
+	c.handleTickError(err)
+	if err == nil {
+		c.saveCheckpoint()
+	}
+	LastTickTimestampGauge.WithLabelValues(c.address.String()).SetToCurrentTime()
+	if c.heartbeat != nil {
+		c.heartbeat.Beat(c.address)
+	}
+}
+
 // Run starts the challenger processing loop.
-// It polls for new events every 30 seconds.
+// It polls for new events every TickInterval and handles outcomes of sent challenges.
+// The loop is supervised, if it fails, it's restarted with backoff until the context is done,
+// so the returned error is always nil.
 func (c *Challenger) Run() error {
 	defer c.wg.Done()
 
-	// Executing first tick
-	c.handleTickError(c.executeTick())
+	c.resumePending()
+	Supervise(c.ctx, c.address, c.loop)
+	return nil
+}
 
-	logger.
-		WithField("address", c.address).
-		Infof("Started contract monitoring")
+// TriggerTick makes the processing loop execute a tick right away instead of waiting for the next one.
+// Triggers received while a tick is already pending are coalesced.
+func (c *Challenger) TriggerTick() {
+	select {
+	case c.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// nextTickDelay returns the time until the next regular tick, including random jitter.
+// Polling is accelerated to fastTickInterval while there is a poke needing attention.
+func (c *Challenger) nextTickDelay() time.Duration {
+	fast := c.fastTickInterval > 0 && c.needsAttention()
+	if fast != c.fastPolling {
+		c.fastPolling = fast
+		c.log().Infof("Polling interval changed, accelerated: %v", fast)
+	}
+	interval := TickInterval
+	if fast {
+		interval = c.fastTickInterval
+	}
+	TickIntervalGauge.WithLabelValues(c.address.String()).Set(interval.Seconds())
+	if c.tickJitter <= 0 || fast {
+		return interval
+	}
+	return interval + rand.N(c.tickJitter)
+}
+
+// needsAttention returns true if there is an invalid poke not challenged successfully yet,
+// or a poke waiting for confirmations.
+func (c *Challenger) needsAttention() bool {
+	c.inFlightMu.Lock()
+	defer c.inFlightMu.Unlock()
+	return len(c.unconfirmed) > 0 || len(c.inFlight) > 0 || c.awaitingPokes > 0
+}
+
+// watchedTick executes the tick bound by the loop context. If the watchdog cancels the loop, because the tick is
+// stuck, ErrLoopStuck is returned right away and the tick is left to finish on its own.
+func (c *Challenger) watchedTick(ctx context.Context) error {
+	if c.watchdogIntervals <= 0 {
+		c.tickWithContext(ctx)
+		return nil
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.tickWithContext(ctx)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if err := context.Cause(ctx); errors.Is(err, ErrLoopStuck) {
+			return err
+		}
+		<-done
+		return nil
+	}
+}
 
-	ticker := time.NewTicker(30 * time.Second)
+// watchdog cancels the loop with ErrLoopStuck once a tick runs for more than watchdogIntervals tick intervals,
+// so the supervisor restarts the loop.
+func (c *Challenger) watchdog(ctx context.Context, cancel context.CancelCauseFunc) {
+	limit := time.Duration(c.watchdogIntervals) * TickInterval
+	ticker := c.clock.NewTicker(TickInterval)
 	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			started := c.tickStarted.Load()
+			if started == 0 {
+				continue
+			}
+			if running := c.clock.Now().Sub(time.Unix(0, started)); running > limit {
+				c.log().Errorf("Tick is running for %v, restarting processing loop", running)
+				cancel(fmt.Errorf("%w: tick running for %v", ErrLoopStuck, running))
+				return
+			}
+		}
+	}
+}
+
+func (c *Challenger) loop(ctx context.Context) error {
+	MonitoredAddressesGauge.Inc()
+	defer MonitoredAddressesGauge.Dec()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if c.watchdogIntervals > 0 {
+		go c.watchdog(ctx, cancel)
+	}
+
+	// Executing first tick
+	if err := c.watchedTick(ctx); err != nil {
+		return err
+	}
+
+	c.log().Infof("Started contract monitoring")
+
+	// Ticks of the addresses are spread over the interval by their offset.
+	timer := c.clock.NewTimer(c.tickOffset + c.nextTickDelay())
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-c.ctx.Done():
-			logger.
-				WithField("address", c.address).
-				Infof("Terminate challenger")
+		case <-ctx.Done():
+			if err := context.Cause(ctx); errors.Is(err, ErrLoopStuck) {
+				return err
+			}
+			c.log().Infof("Terminate challenger")
 			return nil
 
-		case t := <-ticker.C:
-			logger.
-				WithField("address", c.address).
-				Debugf("Tick at: %v", t)
+		case t := <-timer.C():
+			sampledDebugf(LogCategoryTick, c.log(), "Tick at: %v", t)
 
-			c.handleTickError(c.executeTick())
+			if err := c.watchedTick(ctx); err != nil {
+				return err
+			}
+			timer.Reset(c.nextTickDelay())
+
+		case <-c.trigger:
+			c.log().Infof("Manual tick triggered")
+
+			if err := c.watchedTick(ctx); err != nil {
+				return err
+			}
+
+		case outcome := <-c.provider.ChallengeOutcomes():
+			c.handleChallengeOutcome(outcome)
 		}
 	}
 }
 
 // PickUnchallengedPokes Checks if `OpPoked` event has `OpPokeChallengedSuccessfully` event after it and before next `OpPoked` event.
 // If it does, then we don't need to challenge it.
+// Events are ordered by block number and log index, so a poke and its challenge landing in the same block are paired too.
+// Challenges with known schnorr data are matched to the poke they invalidated, regardless of the order.
 func PickUnchallengedPokes(pokes []*OpPokedEvent, challenges []*OpPokeChallengedSuccessfullyEvent) []*OpPokedEvent {
 	if len(pokes) == 0 || len(challenges) == 0 {
 		return pokes
 	}
 
-	var result []*OpPokedEvent
-
-	if len(pokes) == 1 {
-		for _, challenge := range challenges {
-			if challenge.BlockNumber.Cmp(pokes[0].BlockNumber) >= 0 {
-				return result
-			}
-		}
-		return pokes
-	}
-	sortable := make([]SortableEvent, len(pokes)+len(challenges))
-	for i, poke := range pokes {
-		sortable[i] = poke
+	challenged := make(map[*OpPokedEvent]bool)
+	sortable := make([]SortableEvent, 0, len(pokes)+len(challenges))
+	for _, poke := range pokes {
+		sortable = append(sortable, poke)
 	}
-	for i, challenge := range challenges {
-		sortable[i+len(pokes)] = challenge
+	for _, challenge := range challenges {
+		if poke := matchChallengedPoke(pokes, challenge); poke != nil {
+			challenged[poke] = true
+			continue
+		}
+		sortable = append(sortable, challenge)
 	}
-	sort.Slice(sortable, func(i, j int) bool {
-		return sortable[i].GetBlockNumber().Cmp(sortable[j].GetBlockNumber()) == -1
+	sort.SliceStable(sortable, func(i, j int) bool {
+		return isEventBefore(sortable[i], sortable[j])
 	})
+
+	var result []*OpPokedEvent
 	for i, event := range sortable {
-		switch ev := event.(type) {
-		case *OpPokedEvent:
-			if i == len(sortable)-1 {
-				result = append(result, ev)
-				continue
-			}
-			if i+1 < len(sortable) && sortable[i+1].Name() == "OpPokeChallengedSuccessfullyEvent" {
+		poke, ok := event.(*OpPokedEvent)
+		if !ok || challenged[poke] {
+			continue
+		}
+		if i+1 < len(sortable) {
+			if _, challenged := sortable[i+1].(*OpPokeChallengedSuccessfullyEvent); challenged {
 				continue
 			}
-			result = append(result, ev)
-		case *OpPokeChallengedSuccessfullyEvent:
+		}
+		result = append(result, poke)
+	}
+	return result
+}
+
+// matchChallengedPoke returns the latest poke preceding the challenge with the challenged schnorr data,
+// nil if schnorr data of the challenge is unknown or no such poke is found.
+func matchChallengedPoke(pokes []*OpPokedEvent, challenge *OpPokeChallengedSuccessfullyEvent) *OpPokedEvent {
+	if challenge.Schnorr == nil {
+		return nil
+	}
+	var match *OpPokedEvent
+	for _, poke := range pokes {
+		if poke.Schnorr.Signature != challenge.Schnorr.Signature || poke.Schnorr.Commitment != challenge.Schnorr.Commitment {
 			continue
 		}
+		if !isEventBefore(poke, challenge) {
+			continue
+		}
+		if match == nil || isEventBefore(match, poke) {
+			match = poke
+		}
 	}
+	return match
+}
 
-	return result
+// isEventBefore orders events by block number and log index. Within the same block, if log index is unknown,
+// pokes go first, as the challenge can't precede the poke it challenges.
+func isEventBefore(a, b SortableEvent) bool {
+	if c := a.GetBlockNumber().Cmp(b.GetBlockNumber()); c != 0 {
+		return c < 0
+	}
+	if ai, bi := a.GetLogIndex(), b.GetLogIndex(); ai != nil && bi != nil {
+		return *ai < *bi
+	}
+	_, aPoke := a.(*OpPokedEvent)
+	_, bPoke := b.(*OpPokedEvent)
+	return aPoke && !bPoke
 }
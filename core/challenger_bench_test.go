@@ -0,0 +1,141 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// benchProvider serves a chain on which every address gets a valid poke every pokeEvery blocks.
+// It's cheaper than mocks, so benchmarks measure the challenger, not the mock bookkeeping.
+type benchProvider struct {
+	head      atomic.Int64
+	pokeEvery int64
+	outcomes  chan TxOutcome
+}
+
+func (p *benchProvider) BlockByNumber(_ context.Context, blockNumber *big.Int) (*types.Block, error) {
+	return &types.Block{Number: blockNumber, Timestamp: time.Now()}, nil
+}
+
+func (p *benchProvider) BlockNumber(context.Context) (*big.Int, error) {
+	return big.NewInt(p.head.Load()), nil
+}
+
+func (p *benchProvider) GetChallengePeriod(context.Context, types.Address) (uint16, error) {
+	return 600, nil
+}
+
+func (p *benchProvider) GetChallengeReward(context.Context, types.Address) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+
+func (p *benchProvider) GetMaxChallengeReward(context.Context, types.Address) (*big.Int, error) {
+	return big.NewInt(0), nil
+}
+
+func (p *benchProvider) GetPokes(_ context.Context, _ types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*OpPokedEvent, error) {
+	var pokes []*OpPokedEvent
+	first := (fromBlock.Int64() + p.pokeEvery - 1) / p.pokeEvery * p.pokeEvery
+	for block := first; block <= toBlock.Int64(); block += p.pokeEvery {
+		pokes = append(pokes, &OpPokedEvent{
+			BlockNumber: big.NewInt(block),
+			PokeData:    PokeData{Val: big.NewInt(block), Age: uint32(block)},
+		})
+	}
+	return pokes, nil
+}
+
+func (p *benchProvider) GetSuccessfulChallenges(context.Context, types.Address, *big.Int, *big.Int) ([]*OpPokeChallengedSuccessfullyEvent, error) {
+	return nil, nil
+}
+
+func (p *benchProvider) GetRegularPokes(context.Context, types.Address, *big.Int, *big.Int) ([]*PokedEvent, error) {
+	return nil, nil
+}
+
+func (p *benchProvider) GetContractStateEvents(context.Context, types.Address, *big.Int, *big.Int) ([]*ContractStateEvent, error) {
+	return nil, nil
+}
+
+func (p *benchProvider) IsPokeSignatureValid(context.Context, types.Address, *OpPokedEvent) (bool, error) {
+	return true, nil
+}
+
+func (p *benchProvider) ChallengePoke(context.Context, types.Address, *OpPokedEvent) (*types.Hash, *types.Transaction, error) {
+	return nil, nil, fmt.Errorf("no invalid pokes expected")
+}
+
+func (p *benchProvider) ChallengeOutcomes() <-chan TxOutcome {
+	return p.outcomes
+}
+
+func (p *benchProvider) GetFrom(context.Context) types.Address {
+	return types.ZeroAddress
+}
+
+// newBenchChallengers creates challengers of n addresses sharing the provider, with their first tick done.
+func newBenchChallengers(n int, p *benchProvider) []*Challenger {
+	challengers := make([]*Challenger, n)
+	for i := range challengers {
+		address := types.MustAddressFromBytes(big.NewInt(int64(i + 1)).FillBytes(make([]byte, types.AddressLength)))
+		challengers[i] = NewChallenger(context.Background(), address, p, 0, nil)
+		challengers[i].tick()
+	}
+	return challengers
+}
+
+// benchmarkTicks measures one tick of every address per iteration, the chain advances by a tick worth of blocks
+// in between, so each tick scans new blocks and verifies new pokes.
+func benchmarkTicks(b *testing.B, concurrent bool) {
+	level := logger.GetLevel()
+	logger.SetLevel(logger.ErrorLevel)
+	defer logger.SetLevel(level)
+
+	blocksPerTick := int64(TickInterval / DefaultBlockTime)
+	for _, n := range []int{100, 250, 500} {
+		b.Run(fmt.Sprintf("addresses=%d", n), func(b *testing.B) {
+			p := &benchProvider{pokeEvery: blocksPerTick}
+			p.head.Store(1_000_000)
+			challengers := newBenchChallengers(n, p)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.head.Add(blocksPerTick)
+				if !concurrent {
+					for _, c := range challengers {
+						c.tick()
+					}
+					continue
+				}
+				var wg sync.WaitGroup
+				for _, c := range challengers {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						c.tick()
+					}()
+				}
+				wg.Wait()
+			}
+			b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/address")
+		})
+	}
+}
+
+// BenchmarkTick runs ticks of all addresses one after another, as a shared scheduler would.
+func BenchmarkTick(b *testing.B) {
+	benchmarkTicks(b, false)
+}
+
+// BenchmarkTickConcurrent runs ticks of all addresses at once, as per-address loops do.
+func BenchmarkTickConcurrent(b *testing.B) {
+	benchmarkTicks(b, true)
+}
@@ -0,0 +1,146 @@
+// Copyright (C) 2021-2023 Chronicle Labs, Inc.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as
+// published by the Free Software Foundation, either version 3 of the
+// License, or (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/chronicleprotocol/challenger/core"
+	"github.com/chronicleprotocol/challenger/core/coretest"
+)
+
+func TestRun(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	from := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
+
+	t.Run("context cancellation exits cleanly and calls wg.Done", func(t *testing.T) {
+		p := new(coretest.MockProvider)
+		// executeTick will run once on startup — provide happy path with no pokes.
+		p.On("BlockNumber", mock.Anything).Return(big.NewInt(1000), nil)
+		p.On("GetChallengePeriod", mock.Anything, address).Return(600, nil)
+		p.On("GetPokes", mock.Anything, address, big.NewInt(100), big.NewInt(1000)).
+			Return([]*core.OpPokedEvent{}, nil)
+		p.On("GetFrom", mock.Anything).Return(from)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		wg.Add(1)
+
+		c := core.NewChallenger(ctx, address, p, 100, &wg)
+
+		done := make(chan struct{})
+		go func() {
+			err := c.Run()
+			assert.NoError(t, err)
+			close(done)
+		}()
+
+		// Cancel context to stop the loop.
+		cancel()
+
+		// wg.Wait should return because Run calls wg.Done.
+		wg.Wait()
+		<-done
+	})
+
+	t.Run("tick error does not stop the loop", func(t *testing.T) {
+		p := new(coretest.MockProvider)
+		// First tick (startup): error.
+		p.On("BlockNumber", mock.Anything).Return((*big.Int)(nil), fmt.Errorf("rpc down"))
+		p.On("GetFrom", mock.Anything).Return(from)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		wg.Add(1)
+
+		c := core.NewChallenger(ctx, address, p, 100, &wg)
+
+		done := make(chan struct{})
+		go func() {
+			err := c.Run()
+			assert.NoError(t, err)
+			close(done)
+		}()
+
+		// Even though tick errored, Run should still be running.
+		// Cancel to exit cleanly.
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		wg.Wait()
+		<-done
+	})
+
+	t.Run("loop health is exposed in metrics", func(t *testing.T) {
+		p := new(coretest.MockProvider)
+		p.On("BlockNumber", mock.Anything).Return((*big.Int)(nil), fmt.Errorf("rpc down"))
+		p.On("GetFrom", mock.Anything).Return(from)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		wg.Add(1)
+
+		c := core.NewChallenger(ctx, address, p, 100, &wg)
+		before := testutil.ToFloat64(core.MonitoredAddressesGauge)
+		go func() {
+			assert.NoError(t, c.Run())
+		}()
+
+		assert.Eventually(t, func() bool {
+			return testutil.ToFloat64(core.MonitoredAddressesGauge) == before+1 &&
+				testutil.ToFloat64(core.LastTickTimestampGauge.WithLabelValues(address.String())) > 0
+		}, time.Second, 10*time.Millisecond)
+		assert.InDelta(t, time.Now().Unix(), testutil.ToFloat64(core.LastTickTimestampGauge.WithLabelValues(address.String())), 5)
+
+		cancel()
+		wg.Wait()
+		assert.Equal(t, before, testutil.ToFloat64(core.MonitoredAddressesGauge))
+	})
+
+	t.Run("manual tick is executed right away", func(t *testing.T) {
+		p := new(coretest.MockProvider)
+		p.On("BlockNumber", mock.Anything).Return((*big.Int)(nil), fmt.Errorf("rpc down"))
+		p.On("GetFrom", mock.Anything).Return(from)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		wg.Add(1)
+
+		c := core.NewChallenger(ctx, address, p, 100, &wg)
+		go func() {
+			assert.NoError(t, c.Run())
+		}()
+
+		ticks := func(n int) func() bool {
+			return func() bool { return p.AssertNumberOfCalls(new(testing.T), "BlockNumber", n) }
+		}
+		assert.Eventually(t, ticks(1), time.Second, 10*time.Millisecond)
+		c.TriggerTick()
+		assert.Eventually(t, ticks(2), time.Second, 10*time.Millisecond)
+
+		cancel()
+		wg.Wait()
+	})
+}
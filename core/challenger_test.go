@@ -19,18 +19,23 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"math/rand"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
+// mockScribeOptimisticProvider is coretest.MockProvider for tests inside the package, which can not import coretest.
 type mockScribeOptimisticProvider struct {
 	mock.Mock
+	outcomes chan TxOutcome
 }
 
 func (s *mockScribeOptimisticProvider) BlockByNumber(ctx context.Context, blockNumber *big.Int) (*types.Block, error) {
@@ -52,6 +57,16 @@ func (s *mockScribeOptimisticProvider) GetChallengePeriod(ctx context.Context, a
 	return uint16(args.Int(0)), args.Error(1)
 }
 
+func (s *mockScribeOptimisticProvider) GetChallengeReward(ctx context.Context, address types.Address) (*big.Int, error) {
+	args := s.Called(ctx, address)
+	return args.Get(0).(*big.Int), args.Error(1)
+}
+
+func (s *mockScribeOptimisticProvider) GetMaxChallengeReward(ctx context.Context, address types.Address) (*big.Int, error) {
+	args := s.Called(ctx, address)
+	return args.Get(0).(*big.Int), args.Error(1)
+}
+
 func (s *mockScribeOptimisticProvider) GetPokes(ctx context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*OpPokedEvent, error) {
 	args := s.Called(ctx, address, fromBlock, toBlock)
 	return args.Get(0).([]*OpPokedEvent), args.Error(1)
@@ -62,6 +77,16 @@ func (s *mockScribeOptimisticProvider) GetSuccessfulChallenges(ctx context.Conte
 	return args.Get(0).([]*OpPokeChallengedSuccessfullyEvent), args.Error(1)
 }
 
+func (s *mockScribeOptimisticProvider) GetRegularPokes(ctx context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*PokedEvent, error) {
+	args := s.Called(ctx, address, fromBlock, toBlock)
+	return args.Get(0).([]*PokedEvent), args.Error(1)
+}
+
+func (s *mockScribeOptimisticProvider) GetContractStateEvents(ctx context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*ContractStateEvent, error) {
+	args := s.Called(ctx, address, fromBlock, toBlock)
+	return args.Get(0).([]*ContractStateEvent), args.Error(1)
+}
+
 func (s *mockScribeOptimisticProvider) IsPokeSignatureValid(ctx context.Context, address types.Address, poke *OpPokedEvent) (bool, error) {
 	args := s.Called(ctx, address, poke)
 	return args.Bool(0), args.Error(1)
@@ -72,6 +97,10 @@ func (s *mockScribeOptimisticProvider) ChallengePoke(ctx context.Context, addres
 	return args.Get(0).(*types.Hash), args.Get(1).(*types.Transaction), args.Error(2)
 }
 
+func (s *mockScribeOptimisticProvider) ChallengeOutcomes() <-chan TxOutcome {
+	return s.outcomes
+}
+
 func (s *mockScribeOptimisticProvider) GetFrom(ctx context.Context) types.Address {
 	args := s.Called(ctx)
 	return args.Get(0).(types.Address)
@@ -97,6 +126,24 @@ func TestGetFromBlockNumber(t *testing.T) {
 	b, err = c.getFromBlockNumber(big.NewInt(1000), 600)
 	assert.NoError(t, err)
 	assert.Equal(t, big.NewInt(950), b)
+
+	// Scanning continues after the last processed block
+	c.lastProcessedBlock = big.NewInt(990)
+	b, err = c.getFromBlockNumber(big.NewInt(1000), 600)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(991), b)
+
+	// Nothing to scan without new blocks
+	c.lastProcessedBlock = big.NewInt(1000)
+	b, err = c.getFromBlockNumber(big.NewInt(1000), 600)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(1001), b)
+
+	// Never past the latest block, after a reorg the challenge window is scanned again
+	c.lastProcessedBlock = big.NewInt(1010)
+	b, err = c.getFromBlockNumber(big.NewInt(1000), 600)
+	assert.NoError(t, err)
+	assert.Equal(t, big.NewInt(950), b)
 }
 
 func TestIsPokeChallengeable(t *testing.T) {
@@ -255,6 +302,124 @@ func TestPickUnchallengedPokes(t *testing.T) {
 	})
 }
 
+func TestUpdateLastPokeGauges(t *testing.T) {
+	address := types.MustAddressFromHex("0x7F7acDa376eF37EC371235a094113dF9Cb4EfEe7")
+	c := NewChallenger(context.TODO(), address, nil, 0, nil)
+	val, _ := new(big.Int).SetString("2500500000000000000000", 10)
+	pokes := []*OpPokedEvent{
+		{BlockNumber: big.NewInt(100), PokeData: PokeData{Val: big.NewInt(1), Age: 1_700_000_000}},
+		{BlockNumber: big.NewInt(101), PokeData: PokeData{Val: val, Age: 1_700_000_012}},
+	}
+
+	c.updateLastPokeGauges(c.pickNewPokes(pokes))
+	assert.Equal(t, 2500.5, testutil.ToFloat64(LastPokeValueGauge.WithLabelValues(address.String())))
+	assert.Equal(t, float64(1_700_000_012), testutil.ToFloat64(LastPokeAgeGauge.WithLabelValues(address.String())))
+
+	// Without new pokes the gauges keep the latest poke.
+	c.updateLastPokeGauges(c.pickNewPokes(pokes[:1]))
+	assert.Equal(t, float64(1_700_000_012), testutil.ToFloat64(LastPokeAgeGauge.WithLabelValues(address.String())))
+}
+
+func TestPickUnchallengedPokesSameBlock(t *testing.T) {
+	idx := func(i uint64) *uint64 { return &i }
+	poke := func(block int64, i uint64) *OpPokedEvent {
+		return &OpPokedEvent{BlockNumber: big.NewInt(block), LogIndex: idx(i)}
+	}
+	challenge := func(block int64, i uint64) *OpPokeChallengedSuccessfullyEvent {
+		return &OpPokeChallengedSuccessfullyEvent{BlockNumber: big.NewInt(block), LogIndex: idx(i)}
+	}
+
+	t.Run("challenge of the previous poke in the same block as next poke", func(t *testing.T) {
+		// chain order: [Poke@100#0, Challenge@200#1, Poke@200#5]
+		pokes := []*OpPokedEvent{poke(100, 0), poke(200, 5)}
+		result := PickUnchallengedPokes(pokes, []*OpPokeChallengedSuccessfullyEvent{challenge(200, 1)})
+		assert.Equal(t, []*OpPokedEvent{pokes[1]}, result)
+	})
+
+	t.Run("poke challenged within its block", func(t *testing.T) {
+		// chain order: [Poke@100#0, Poke@200#1, Challenge@200#5]
+		pokes := []*OpPokedEvent{poke(100, 0), poke(200, 1)}
+		result := PickUnchallengedPokes(pokes, []*OpPokeChallengedSuccessfullyEvent{challenge(200, 5)})
+		assert.Equal(t, []*OpPokedEvent{pokes[0]}, result)
+	})
+}
+
+func TestPickUnchallengedPokesBySchnorr(t *testing.T) {
+	signed := func(block int64, signature byte) *OpPokedEvent {
+		return &OpPokedEvent{BlockNumber: big.NewInt(block), Schnorr: SchnorrData{Signature: [32]byte{signature}}}
+	}
+
+	t.Run("challenge of an earlier poke interleaved with later pokes", func(t *testing.T) {
+		// chain order: [Poke@100, Poke@200, Challenge(Poke@100)@300]
+		pokes := []*OpPokedEvent{signed(100, 1), signed(200, 2)}
+		challenges := []*OpPokeChallengedSuccessfullyEvent{{BlockNumber: big.NewInt(300), Schnorr: &pokes[0].Schnorr}}
+		result := PickUnchallengedPokes(pokes, challenges)
+		assert.Equal(t, []*OpPokedEvent{pokes[1]}, result)
+	})
+
+	t.Run("challenge with unknown schnorr data is paired by order", func(t *testing.T) {
+		// chain order: [Poke@100, Challenge(Poke@100)@150, Poke@200, Challenge@250, Poke@300]
+		pokes := []*OpPokedEvent{signed(100, 1), signed(200, 2), signed(300, 3)}
+		challenges := []*OpPokeChallengedSuccessfullyEvent{
+			{BlockNumber: big.NewInt(150), Schnorr: &pokes[0].Schnorr},
+			{BlockNumber: big.NewInt(250)},
+		}
+		result := PickUnchallengedPokes(pokes, challenges)
+		assert.Equal(t, []*OpPokedEvent{pokes[2]}, result)
+	})
+
+	t.Run("challenge preceding the poke with the same schnorr data is ignored", func(t *testing.T) {
+		pokes := []*OpPokedEvent{signed(200, 1)}
+		challenges := []*OpPokeChallengedSuccessfullyEvent{{BlockNumber: big.NewInt(100), Schnorr: &pokes[0].Schnorr}}
+		result := PickUnchallengedPokes(pokes, challenges)
+		assert.Equal(t, pokes, result)
+	})
+}
+
+// TestPickUnchallengedPokesProperty generates random chain histories, where each poke may be followed
+// by its challenge, often within the same block, and checks exactly unchallenged pokes are picked
+// regardless of the order events are given in.
+func TestPickUnchallengedPokesProperty(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for n := 0; n < 500; n++ {
+		var pokes []*OpPokedEvent
+		var challenges []*OpPokeChallengedSuccessfullyEvent
+		var expected []*OpPokedEvent
+		block, logIndex := int64(100), uint64(0)
+		next := func() (*big.Int, *uint64) {
+			if rnd.Intn(3) == 0 {
+				block++
+				logIndex = 0
+			} else {
+				logIndex++
+			}
+			i := logIndex
+			return big.NewInt(block), &i
+		}
+
+		for i := rnd.Intn(8); i >= 0; i-- {
+			b, li := next()
+			poke := &OpPokedEvent{BlockNumber: b, LogIndex: li}
+			pokes = append(pokes, poke)
+			if rnd.Intn(2) == 0 {
+				b, li := next()
+				challenges = append(challenges, &OpPokeChallengedSuccessfullyEvent{BlockNumber: b, LogIndex: li})
+				continue
+			}
+			expected = append(expected, poke)
+		}
+
+		rnd.Shuffle(len(pokes), func(i, j int) { pokes[i], pokes[j] = pokes[j], pokes[i] })
+		rnd.Shuffle(len(challenges), func(i, j int) { challenges[i], challenges[j] = challenges[j], challenges[i] })
+		result := PickUnchallengedPokes(pokes, challenges)
+		if len(challenges) == 0 {
+			assert.ElementsMatch(t, expected, result)
+			continue
+		}
+		require.Equal(t, expected, result, "history %d", n)
+	}
+}
+
 func TestSpawnChallengeDuplicateProtection(t *testing.T) {
 	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
 	from := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
@@ -288,17 +453,24 @@ func TestSpawnChallengeDuplicateProtection(t *testing.T) {
 		// Unblock the goroutine.
 		close(gate)
 
-		// Wait for the goroutine to finish and clean up.
+		// Wait for the goroutine to finish.
 		time.Sleep(50 * time.Millisecond)
 
-		// After completion, block 1000 should no longer be in-flight.
+		// Transaction is sent, but block 1000 stays in-flight until its outcome is known.
 		c.inFlightMu.Lock()
 		_, stillInFlight := c.inFlight[1000]
 		c.inFlightMu.Unlock()
-		assert.False(t, stillInFlight, "block 1000 should be removed from in-flight after goroutine completes")
+		assert.True(t, stillInFlight, "block 1000 should stay in-flight until the outcome is handled")
+
+		c.handleChallengeOutcome(TxOutcome{Address: address, Poke: poke, Hash: &txHash})
+
+		c.inFlightMu.Lock()
+		_, stillInFlight = c.inFlight[1000]
+		c.inFlightMu.Unlock()
+		assert.False(t, stillInFlight, "block 1000 should be removed from in-flight after the outcome is handled")
 	})
 
-	t.Run("block can be re-challenged after goroutine completes", func(t *testing.T) {
+	t.Run("block can be re-challenged after failed outcome", func(t *testing.T) {
 		mockedProvider := new(mockScribeOptimisticProvider)
 		mockedProvider.On("ChallengePoke", mock.Anything, mock.Anything, mock.Anything).
 			Return(&txHash, &types.Transaction{}, nil)
@@ -311,8 +483,14 @@ func TestSpawnChallengeDuplicateProtection(t *testing.T) {
 		c.SpawnChallenge(poke)
 		time.Sleep(50 * time.Millisecond)
 
-		// After first goroutine completes, block should be removed from in-flight.
-		// Second call should proceed normally.
+		// Duplicate is skipped while the transaction is pending.
+		c.SpawnChallenge(poke)
+		time.Sleep(50 * time.Millisecond)
+		mockedProvider.AssertNumberOfCalls(t, "ChallengePoke", 1)
+
+		// After the failed outcome, block should be removed from in-flight.
+		// Next call should proceed normally.
+		c.handleChallengeOutcome(TxOutcome{Address: address, Poke: poke, Hash: &txHash, Err: ErrTxReverted})
 		c.SpawnChallenge(poke)
 		time.Sleep(50 * time.Millisecond)
 
@@ -354,11 +532,12 @@ func TestExecuteTick(t *testing.T) {
 
 	t.Run("error on BlockNumber failure", func(t *testing.T) {
 		p := new(mockScribeOptimisticProvider)
-		p.On("BlockNumber", mock.Anything).Return((*big.Int)(nil), fmt.Errorf("rpc down"))
+		p.On("BlockNumber", mock.Anything).Return((*big.Int)(nil), fmt.Errorf("%w: rpc down", ErrRPCUnavailable))
 
 		c := NewChallenger(context.TODO(), address, p, 100, nil)
-		err := c.executeTick()
+		err := c.executeTick(c.ctx)
 		assert.ErrorContains(t, err, "failed to get latest block number")
+		assert.ErrorIs(t, err, ErrRPCUnavailable)
 		p.AssertExpectations(t)
 	})
 
@@ -368,7 +547,7 @@ func TestExecuteTick(t *testing.T) {
 		p.On("GetChallengePeriod", mock.Anything, address).Return(0, fmt.Errorf("contract error"))
 
 		c := NewChallenger(context.TODO(), address, p, 100, nil)
-		err := c.executeTick()
+		err := c.executeTick(c.ctx)
 		assert.ErrorContains(t, err, "failed to get challenge period")
 		p.AssertExpectations(t)
 	})
@@ -381,7 +560,7 @@ func TestExecuteTick(t *testing.T) {
 			Return(([]*OpPokedEvent)(nil), fmt.Errorf("logs error"))
 
 		c := NewChallenger(context.TODO(), address, p, 100, nil)
-		err := c.executeTick()
+		err := c.executeTick(c.ctx)
 		assert.ErrorContains(t, err, "failed to get OpPoked events")
 		p.AssertExpectations(t)
 	})
@@ -395,7 +574,7 @@ func TestExecuteTick(t *testing.T) {
 		p.On("GetFrom", mock.Anything).Return(from)
 
 		c := NewChallenger(context.TODO(), address, p, 100, nil)
-		err := c.executeTick()
+		err := c.executeTick(c.ctx)
 		assert.NoError(t, err)
 		assert.Equal(t, big.NewInt(1000), c.lastProcessedBlock)
 		p.AssertExpectations(t)
@@ -414,7 +593,7 @@ func TestExecuteTick(t *testing.T) {
 			Return(([]*OpPokeChallengedSuccessfullyEvent)(nil), fmt.Errorf("logs error"))
 
 		c := NewChallenger(context.TODO(), address, p, 100, nil)
-		err := c.executeTick()
+		err := c.executeTick(c.ctx)
 		assert.ErrorContains(t, err, "failed to get OpPokeChallengedSuccessfully events")
 		p.AssertExpectations(t)
 	})
@@ -435,7 +614,7 @@ func TestExecuteTick(t *testing.T) {
 			Return(&types.Block{Number: big.NewInt(500), Timestamp: ts}, nil)
 
 		c := NewChallenger(context.TODO(), address, p, 100, nil)
-		err := c.executeTick()
+		err := c.executeTick(c.ctx)
 		assert.NoError(t, err)
 		// ChallengePoke should never be called.
 		p.AssertNotCalled(t, "ChallengePoke")
@@ -461,7 +640,7 @@ func TestExecuteTick(t *testing.T) {
 			Return(&txHash, &types.Transaction{}, nil)
 
 		c := NewChallenger(context.TODO(), address, p, 100, &sync.WaitGroup{})
-		err := c.executeTick()
+		err := c.executeTick(c.ctx)
 		assert.NoError(t, err)
 
 		// Wait for the SpawnChallenge goroutine to complete.
@@ -484,7 +663,7 @@ func TestExecuteTick(t *testing.T) {
 			Return([]*OpPokeChallengedSuccessfullyEvent{{BlockNumber: big.NewInt(505)}}, nil)
 
 		c := NewChallenger(context.TODO(), address, p, 100, nil)
-		err := c.executeTick()
+		err := c.executeTick(c.ctx)
 		assert.NoError(t, err)
 		// No pokes remain after filtering, so no block lookups or challenges.
 		p.AssertNotCalled(t, "BlockByNumber")
@@ -502,82 +681,100 @@ func TestExecuteTick(t *testing.T) {
 		p.On("GetFrom", mock.Anything).Return(from)
 
 		c := NewChallenger(context.TODO(), address, p, 100, nil)
-		err := c.executeTick()
+		err := c.executeTick(c.ctx)
 		assert.NoError(t, err)
 		assert.Equal(t, big.NewInt(1000), c.lastProcessedBlock)
 
-		// Second tick: fromBlock should now be 1000 (lastProcessedBlock), latestBlock=2000.
+		// Second tick: fromBlock should now be 1001 (after lastProcessedBlock), latestBlock=2000.
 		p.On("BlockNumber", mock.Anything).Return(big.NewInt(2000), nil).Once()
-		p.On("GetPokes", mock.Anything, address, big.NewInt(1000), big.NewInt(2000)).
+		p.On("GetPokes", mock.Anything, address, big.NewInt(1001), big.NewInt(2000)).
 			Return([]*OpPokedEvent{}, nil).Once()
 
-		err = c.executeTick()
+		err = c.executeTick(c.ctx)
 		assert.NoError(t, err)
 		assert.Equal(t, big.NewInt(2000), c.lastProcessedBlock)
 		p.AssertExpectations(t)
 	})
-}
-
-func TestRun(t *testing.T) {
-	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
-	from := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
 
-	t.Run("context cancellation exits cleanly and calls wg.Done", func(t *testing.T) {
+	t.Run("tick without new blocks scans nothing", func(t *testing.T) {
 		p := new(mockScribeOptimisticProvider)
-		// executeTick will run once on startup — provide happy path with no pokes.
 		p.On("BlockNumber", mock.Anything).Return(big.NewInt(1000), nil)
 		p.On("GetChallengePeriod", mock.Anything, address).Return(600, nil)
 		p.On("GetPokes", mock.Anything, address, big.NewInt(100), big.NewInt(1000)).
-			Return([]*OpPokedEvent{}, nil)
+			Return([]*OpPokedEvent{}, nil).Once()
 		p.On("GetFrom", mock.Anything).Return(from)
 
-		ctx, cancel := context.WithCancel(context.Background())
-		var wg sync.WaitGroup
-		wg.Add(1)
-
-		c := NewChallenger(ctx, address, p, 100, &wg)
+		c := NewChallenger(context.TODO(), address, p, 100, nil)
+		require.NoError(t, c.executeTick(c.ctx))
+		require.NoError(t, c.executeTick(c.ctx))
+		assert.Equal(t, big.NewInt(1000), c.lastProcessedBlock)
+		p.AssertNumberOfCalls(t, "GetPokes", 1)
+	})
 
-		done := make(chan struct{})
-		go func() {
-			err := c.Run()
-			assert.NoError(t, err)
-			close(done)
-		}()
+	t.Run("poke of the last processed block is challenged once", func(t *testing.T) {
+		poke := &OpPokedEvent{BlockNumber: big.NewInt(1000)}
+		p := new(mockScribeOptimisticProvider)
+		p.On("BlockNumber", mock.Anything).Return(big.NewInt(1000), nil).Once()
+		p.On("GetChallengePeriod", mock.Anything, address).Return(600, nil)
+		p.On("GetPokes", mock.Anything, address, big.NewInt(100), big.NewInt(1000)).
+			Return([]*OpPokedEvent{poke}, nil).Once()
+		p.On("GetSuccessfulChallenges", mock.Anything, address, mock.Anything, mock.Anything).
+			Return([]*OpPokeChallengedSuccessfullyEvent{}, nil)
+		p.On("BlockByNumber", mock.Anything, big.NewInt(1000)).
+			Return(&types.Block{Number: big.NewInt(1000), Timestamp: time.Now()}, nil)
+		p.On("IsPokeSignatureValid", mock.Anything, address, poke).Return(false, nil)
+		p.On("GetFrom", mock.Anything).Return(from)
+		p.On("ChallengePoke", mock.Anything, address, poke).Return(&txHash, &types.Transaction{}, nil)
 
-		// Cancel context to stop the loop.
-		cancel()
+		c := NewChallenger(context.TODO(), address, p, 100, nil)
+		require.NoError(t, c.executeTick(c.ctx))
+		c.submissions.Wait()
+		c.handleChallengeOutcome(TxOutcome{Address: address, Poke: poke, Hash: &txHash, Err: ErrTxReverted})
 
-		// wg.Wait should return because Run calls wg.Done.
-		wg.Wait()
-		<-done
+		// The block of the poke is not scanned again, so the poke isn't challenged again even with nothing in flight.
+		p.On("BlockNumber", mock.Anything).Return(big.NewInt(1001), nil).Once()
+		p.On("GetPokes", mock.Anything, address, big.NewInt(1001), big.NewInt(1001)).
+			Return([]*OpPokedEvent{}, nil).Once()
+		require.NoError(t, c.executeTick(c.ctx))
+		c.submissions.Wait()
+		p.AssertNumberOfCalls(t, "ChallengePoke", 1)
+		p.AssertExpectations(t)
 	})
+}
 
-	t.Run("tick error does not stop the loop", func(t *testing.T) {
-		p := new(mockScribeOptimisticProvider)
-		// First tick (startup): error.
-		p.On("BlockNumber", mock.Anything).Return((*big.Int)(nil), fmt.Errorf("rpc down"))
-		p.On("GetFrom", mock.Anything).Return(from)
+func TestRunChallengeOutcome(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	from := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
 
-		ctx, cancel := context.WithCancel(context.Background())
-		var wg sync.WaitGroup
-		wg.Add(1)
+	p := &mockScribeOptimisticProvider{outcomes: make(chan TxOutcome)}
+	p.On("BlockNumber", mock.Anything).Return(big.NewInt(1000), nil)
+	p.On("GetChallengePeriod", mock.Anything, address).Return(600, nil)
+	p.On("GetPokes", mock.Anything, address, big.NewInt(100), big.NewInt(1000)).
+		Return([]*OpPokedEvent{}, nil)
+	p.On("GetFrom", mock.Anything).Return(from)
 
-		c := NewChallenger(ctx, address, p, 100, &wg)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
 
-		done := make(chan struct{})
-		go func() {
-			err := c.Run()
-			assert.NoError(t, err)
-			close(done)
-		}()
+	c := NewChallenger(ctx, address, p, 100, &wg)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(900)}
+	c.inFlight[900] = struct{}{}
+	c.unconfirmed[900] = time.Now().Add(time.Minute)
 
-		// Even though tick errored, Run should still be running.
-		// Cancel to exit cleanly.
-		time.Sleep(50 * time.Millisecond)
-		cancel()
-		wg.Wait()
-		<-done
-	})
+	go func() {
+		assert.NoError(t, c.Run())
+	}()
+
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	p.outcomes <- TxOutcome{Address: address, Poke: poke, Hash: &txHash}
+	cancel()
+	wg.Wait()
+
+	c.inFlightMu.Lock()
+	defer c.inFlightMu.Unlock()
+	assert.Empty(t, c.inFlight)
+	assert.Empty(t, c.unconfirmed)
 }
 
 func TestGetEarliestBlockNumber(t *testing.T) {
@@ -607,6 +804,42 @@ func TestGetEarliestBlockNumber(t *testing.T) {
 		result := c.getEarliestBlockNumber(big.NewInt(100), 12)
 		assert.Equal(t, big.NewInt(99), result)
 	})
+
+	t.Run("faster blocks", func(t *testing.T) {
+		// period=600, blocksPerPeriod = 600/2 = 300, lastBlock=1000 -> 700
+		c := NewChallenger(context.TODO(), address, nil, 0, nil, WithBlockTime(2*time.Second))
+		result := c.getEarliestBlockNumber(big.NewInt(1000), 600)
+		assert.Equal(t, big.NewInt(700), result)
+	})
+}
+
+func TestSpawnChallengePanic(t *testing.T) {
+	address := types.MustAddressFromHex("0x4F7acDa376eF37EC371235a094113dF9Cb4EfEe4")
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(6000)}
+	p := new(mockScribeOptimisticProvider)
+	p.On("ChallengePoke", mock.Anything, address, poke).Run(func(mock.Arguments) {
+		panic("malformed poke")
+	})
+
+	c := NewChallenger(context.TODO(), address, p, 0, &sync.WaitGroup{})
+	c.SpawnChallenge(poke)
+
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(PanicsCounter.WithLabelValues(address.String(), "challenge")) == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		c.inFlightMu.Lock()
+		defer c.inFlightMu.Unlock()
+		_, inFlight := c.inFlight[6000]
+		return !inFlight
+	}, time.Second, 10*time.Millisecond, "poke must be released after panic")
+}
+
+func TestChallengeResult(t *testing.T) {
+	assert.Equal(t, ChallengeResultConfirmed, challengeResult(nil))
+	assert.Equal(t, ChallengeResultReverted, challengeResult(fmt.Errorf("reverted with unknown: %w", ErrTxReverted)))
+	assert.Equal(t, ChallengeResultLostRace, challengeResult(fmt.Errorf("%w: %w", ErrChallengeReverted, ErrAlreadyChallenged)))
+	assert.Equal(t, ChallengeResultDropped, challengeResult(ErrTxNotIncluded))
 }
 
 func TestSpawnChallengeErrorPath(t *testing.T) {
@@ -634,3 +867,533 @@ func TestSpawnChallengeErrorPath(t *testing.T) {
 		assert.False(t, stillInFlight)
 	})
 }
+
+func TestRetryChallenges(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	from := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+
+	t.Run("send failure is retried on next tick", func(t *testing.T) {
+		p := new(mockScribeOptimisticProvider)
+		poke := &OpPokedEvent{BlockNumber: big.NewInt(500)}
+		p.On("GetFrom", mock.Anything).Return(from)
+		p.On("ChallengePoke", mock.Anything, address, poke).
+			Return((*types.Hash)(nil), (*types.Transaction)(nil), fmt.Errorf("tx failed")).Once()
+		p.On("ChallengePoke", mock.Anything, address, poke).Return(&txHash, (*types.Transaction)(nil), nil).Once()
+		p.On("GetSuccessfulChallenges", mock.Anything, address, big.NewInt(500), big.NewInt(1000)).
+			Return([]*OpPokeChallengedSuccessfullyEvent{}, nil)
+
+		c := NewChallenger(context.TODO(), address, p, 0, &sync.WaitGroup{})
+		c.trackUnconfirmed(poke, time.Now().Add(time.Hour))
+		c.SpawnChallenge(poke)
+		c.submissions.Wait()
+		assert.Contains(t, c.retry, uint64(500))
+
+		require.NoError(t, c.retryChallenges(context.TODO(), big.NewInt(1000)))
+		c.submissions.Wait()
+		p.AssertNumberOfCalls(t, "ChallengePoke", 2)
+		assert.Empty(t, c.retry)
+	})
+
+	t.Run("dropped challenge is retried on next tick", func(t *testing.T) {
+		p := new(mockScribeOptimisticProvider)
+		poke := &OpPokedEvent{BlockNumber: big.NewInt(500)}
+		p.On("GetFrom", mock.Anything).Return(from)
+		p.On("ChallengePoke", mock.Anything, address, poke).Return(&txHash, (*types.Transaction)(nil), nil)
+		p.On("GetSuccessfulChallenges", mock.Anything, address, big.NewInt(500), big.NewInt(1000)).
+			Return([]*OpPokeChallengedSuccessfullyEvent{}, nil)
+
+		c := NewChallenger(context.TODO(), address, p, 0, &sync.WaitGroup{})
+		c.trackUnconfirmed(poke, time.Now().Add(time.Hour))
+		c.SpawnChallenge(poke)
+		c.submissions.Wait()
+		c.handleChallengeOutcome(TxOutcome{Address: address, Poke: poke, Hash: &txHash, Err: ErrTxNotIncluded})
+		assert.Contains(t, c.retry, uint64(500))
+
+		require.NoError(t, c.retryChallenges(context.TODO(), big.NewInt(1000)))
+		c.submissions.Wait()
+		p.AssertNumberOfCalls(t, "ChallengePoke", 2)
+		assert.Empty(t, c.retry)
+	})
+
+	t.Run("budget rejection is retried once budget is available", func(t *testing.T) {
+		p := new(mockScribeOptimisticProvider)
+		poke := &OpPokedEvent{BlockNumber: big.NewInt(500)}
+		p.On("GetFrom", mock.Anything).Return(from)
+		p.On("ChallengePoke", mock.Anything, address, poke).Return(&txHash, (*types.Transaction)(nil), nil)
+		p.On("GetSuccessfulChallenges", mock.Anything, address, big.NewInt(500), big.NewInt(1000)).
+			Return([]*OpPokeChallengedSuccessfullyEvent{}, nil)
+
+		budget := NewChallengeBudget(1, nil)
+		require.NoError(t, budget.Take(time.Now()))
+		c := NewChallenger(context.TODO(), address, p, 0, &sync.WaitGroup{}, WithChallengeBudget(budget))
+		c.trackUnconfirmed(poke, time.Now().Add(time.Hour))
+		c.SpawnChallenge(poke)
+		c.submissions.Wait()
+		p.AssertNotCalled(t, "ChallengePoke", mock.Anything, mock.Anything, mock.Anything)
+
+		// Refill the budget.
+		budget.tokens = 1
+		require.NoError(t, c.retryChallenges(context.TODO(), big.NewInt(1000)))
+		c.submissions.Wait()
+		p.AssertNumberOfCalls(t, "ChallengePoke", 1)
+	})
+
+	t.Run("poke challenged by someone else is not retried", func(t *testing.T) {
+		p := new(mockScribeOptimisticProvider)
+		poke := &OpPokedEvent{BlockNumber: big.NewInt(500)}
+		p.On("ChallengePoke", mock.Anything, address, poke).
+			Return((*types.Hash)(nil), (*types.Transaction)(nil), fmt.Errorf("tx failed")).Once()
+		p.On("GetSuccessfulChallenges", mock.Anything, address, big.NewInt(500), big.NewInt(1000)).
+			Return([]*OpPokeChallengedSuccessfullyEvent{{BlockNumber: big.NewInt(510)}}, nil)
+
+		c := NewChallenger(context.TODO(), address, p, 0, &sync.WaitGroup{})
+		c.trackUnconfirmed(poke, time.Now().Add(time.Hour))
+		c.SpawnChallenge(poke)
+		c.submissions.Wait()
+
+		require.NoError(t, c.retryChallenges(context.TODO(), big.NewInt(1000)))
+		c.submissions.Wait()
+		p.AssertNumberOfCalls(t, "ChallengePoke", 1)
+		assert.Empty(t, c.retry)
+	})
+
+	t.Run("poke is dropped once its window closes", func(t *testing.T) {
+		p := new(mockScribeOptimisticProvider)
+		poke := &OpPokedEvent{BlockNumber: big.NewInt(500)}
+		p.On("ChallengePoke", mock.Anything, address, poke).
+			Return((*types.Hash)(nil), (*types.Transaction)(nil), fmt.Errorf("tx failed")).Once()
+
+		c := NewChallenger(context.TODO(), address, p, 0, &sync.WaitGroup{})
+		c.trackUnconfirmed(poke, time.Now().Add(time.Hour))
+		c.SpawnChallenge(poke)
+		c.submissions.Wait()
+		c.confirmChallenge(poke)
+
+		require.NoError(t, c.retryChallenges(context.TODO(), big.NewInt(1000)))
+		c.submissions.Wait()
+		p.AssertNumberOfCalls(t, "ChallengePoke", 1)
+		p.AssertNotCalled(t, "GetSuccessfulChallenges", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		assert.Empty(t, c.retry)
+	})
+}
+
+func TestPickChallengeablePokes(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+
+	t.Run("keeps order of challengeable pokes", func(t *testing.T) {
+		p := new(mockScribeOptimisticProvider)
+		pokes := []*OpPokedEvent{
+			{BlockNumber: big.NewInt(100)},
+			{BlockNumber: big.NewInt(200)},
+			{BlockNumber: big.NewInt(300)},
+		}
+		p.On("BlockByNumber", mock.Anything, mock.Anything).
+			Return(&types.Block{Number: big.NewInt(100), Timestamp: time.Now()}, nil)
+		p.On("IsPokeSignatureValid", mock.Anything, address, pokes[0]).Return(false, nil)
+		p.On("IsPokeSignatureValid", mock.Anything, address, pokes[1]).Return(true, nil)
+		p.On("IsPokeSignatureValid", mock.Anything, address, pokes[2]).Return(false, nil)
+
+		c := NewChallenger(context.TODO(), address, p, 0, nil, WithVerifyConcurrency(3))
+		result := c.pickChallengeablePokes(context.TODO(), pokes, 600)
+		require.Len(t, result, 2)
+		assert.Equal(t, pokes[0], result[0])
+		assert.Equal(t, pokes[2], result[1])
+	})
+
+	t.Run("orders pokes by remaining challenge time", func(t *testing.T) {
+		p := new(mockScribeOptimisticProvider)
+		pokes := []*OpPokedEvent{
+			{BlockNumber: big.NewInt(300)},
+			{BlockNumber: big.NewInt(100)},
+			{BlockNumber: big.NewInt(200)},
+		}
+		now := time.Now()
+		p.On("BlockByNumber", mock.Anything, big.NewInt(100)).
+			Return(&types.Block{Number: big.NewInt(100), Timestamp: now.Add(-500 * time.Second)}, nil)
+		p.On("BlockByNumber", mock.Anything, big.NewInt(200)).
+			Return(&types.Block{Number: big.NewInt(200), Timestamp: now.Add(-300 * time.Second)}, nil)
+		p.On("BlockByNumber", mock.Anything, big.NewInt(300)).
+			Return(&types.Block{Number: big.NewInt(300), Timestamp: now.Add(-100 * time.Second)}, nil)
+		p.On("IsPokeSignatureValid", mock.Anything, address, mock.Anything).Return(false, nil)
+
+		c := NewChallenger(context.TODO(), address, p, 0, nil)
+		result := c.pickChallengeablePokes(context.TODO(), pokes, 600)
+		require.Len(t, result, 3)
+		assert.Equal(t, big.NewInt(100), result[0].BlockNumber)
+		assert.Equal(t, big.NewInt(200), result[1].BlockNumber)
+		assert.Equal(t, big.NewInt(300), result[2].BlockNumber)
+	})
+
+	t.Run("verifies pokes concurrently", func(t *testing.T) {
+		p := new(mockScribeOptimisticProvider)
+		pokes := []*OpPokedEvent{{BlockNumber: big.NewInt(100)}, {BlockNumber: big.NewInt(200)}}
+
+		// Both verifications have to be running at the same time to pass the barrier.
+		var barrier sync.WaitGroup
+		barrier.Add(2)
+		p.On("BlockByNumber", mock.Anything, mock.Anything).
+			Return(&types.Block{Number: big.NewInt(100), Timestamp: time.Now()}, nil)
+		p.On("IsPokeSignatureValid", mock.Anything, address, mock.Anything).
+			Run(func(args mock.Arguments) {
+				barrier.Done()
+				barrier.Wait()
+			}).
+			Return(false, nil)
+
+		c := NewChallenger(context.TODO(), address, p, 0, nil, WithVerifyConcurrency(2))
+		done := make(chan []*OpPokedEvent)
+		go func() { done <- c.pickChallengeablePokes(context.TODO(), pokes, 600) }()
+
+		select {
+		case result := <-done:
+			assert.Len(t, result, 2)
+		case <-time.After(time.Second):
+			t.Fatal("pokes were not verified concurrently")
+		}
+	})
+
+	t.Run("verification timeout is applied", func(t *testing.T) {
+		p := new(mockScribeOptimisticProvider)
+		poke := &OpPokedEvent{BlockNumber: big.NewInt(100)}
+		p.On("BlockByNumber", mock.Anything, mock.Anything).
+			Return(&types.Block{Number: big.NewInt(100), Timestamp: time.Now()}, nil)
+		p.On("IsPokeSignatureValid", mock.Anything, address, poke).
+			Run(func(args mock.Arguments) {
+				ctx := args.Get(0).(context.Context)
+				<-ctx.Done()
+			}).
+			Return(false, fmt.Errorf("context deadline exceeded"))
+
+		c := NewChallenger(context.TODO(), address, p, 0, nil, WithVerifyTimeout(20*time.Millisecond))
+		assert.Empty(t, c.pickChallengeablePokes(context.TODO(), []*OpPokedEvent{poke}, 600))
+	})
+}
+
+func TestPickConfirmedPokes(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	confirmed := &OpPokedEvent{BlockNumber: big.NewInt(990)}
+	recent := &OpPokedEvent{BlockNumber: big.NewInt(998)}
+	closing := &OpPokedEvent{BlockNumber: big.NewInt(999)}
+
+	p := new(mockScribeOptimisticProvider)
+	p.On("BlockByNumber", mock.Anything, recent.BlockNumber).
+		Return(&types.Block{Number: recent.BlockNumber, Timestamp: time.Now()}, nil)
+	p.On("BlockByNumber", mock.Anything, closing.BlockNumber).
+		Return(&types.Block{Number: closing.BlockNumber, Timestamp: time.Now().Add(-550 * time.Second)}, nil)
+
+	c := NewChallenger(context.TODO(), address, p, 0, nil, WithConfirmations(5))
+	result := c.pickConfirmedPokes(context.TODO(), []*OpPokedEvent{confirmed, recent, closing}, big.NewInt(1000), 600)
+	assert.Equal(t, []*OpPokedEvent{confirmed, closing}, result)
+	p.AssertNotCalled(t, "BlockByNumber", mock.Anything, confirmed.BlockNumber)
+
+	// Unconfirmed blocks are scanned again on next tick.
+	assert.Equal(t, big.NewInt(995), c.confirmedBlockNumber(big.NewInt(1000), big.NewInt(900)))
+	assert.Equal(t, big.NewInt(997), c.confirmedBlockNumber(big.NewInt(1000), big.NewInt(998)))
+	assert.Equal(t, big.NewInt(1000), NewChallenger(context.TODO(), address, p, 0, nil).confirmedBlockNumber(big.NewInt(1000), big.NewInt(900)))
+}
+
+func TestPickAgedPokes(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	deep := &OpPokedEvent{BlockNumber: big.NewInt(990)}
+	recent := &OpPokedEvent{BlockNumber: big.NewInt(998)}
+	closing := &OpPokedEvent{BlockNumber: big.NewInt(999)}
+
+	c := NewChallenger(context.TODO(), address, nil, 0, nil, WithMinPokeAge(5))
+	c.lastProcessedBlock = big.NewInt(1000)
+	c.trackUnconfirmed(deep, time.Now().Add(time.Hour))
+	c.trackUnconfirmed(recent, time.Now().Add(time.Hour))
+	c.trackUnconfirmed(closing, time.Now().Add(time.Minute))
+
+	result := c.pickAgedPokes([]*OpPokedEvent{deep, recent, closing}, big.NewInt(1000))
+	assert.Equal(t, []*OpPokedEvent{deep, closing}, result)
+	// Delayed poke is scanned again on next tick.
+	assert.Equal(t, big.NewInt(997), c.lastProcessedBlock)
+
+	c = NewChallenger(context.TODO(), address, nil, 0, nil)
+	assert.Len(t, c.pickAgedPokes([]*OpPokedEvent{deep, recent}, big.NewInt(1000)), 2)
+}
+
+func TestChallengeWindowRemainingGauge(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	c := NewChallenger(context.TODO(), address, nil, 0, nil)
+	gauge := ChallengeWindowRemainingGauge.WithLabelValues(address.String())
+
+	older := &OpPokedEvent{BlockNumber: big.NewInt(100)}
+	newer := &OpPokedEvent{BlockNumber: big.NewInt(200)}
+	c.trackUnconfirmed(older, time.Now().Add(100*time.Second))
+	c.trackUnconfirmed(newer, time.Now().Add(300*time.Second))
+	c.updateChallengeWindowGauge()
+	assert.InDelta(t, 300, testutil.ToFloat64(gauge), 5)
+
+	// Once the most recent poke is confirmed, the older one is reported.
+	c.confirmChallenge(newer)
+	assert.InDelta(t, 100, testutil.ToFloat64(gauge), 5)
+
+	// Expired windows are dropped.
+	c.trackUnconfirmed(older, time.Now().Add(-time.Second))
+	c.updateChallengeWindowGauge()
+	assert.Equal(t, float64(0), testutil.ToFloat64(gauge))
+	assert.Empty(t, c.unconfirmed)
+}
+
+func TestQueueGauges(t *testing.T) {
+	address := types.MustAddressFromHex("0x00000000000000000000000000000000000000e1")
+	verifications := PokesAwaitingVerificationGauge.WithLabelValues(address.String())
+	challenges := ChallengesAwaitingConfirmationGauge.WithLabelValues(address.String())
+
+	// Pokes waiting for a verification slot are counted too.
+	p := new(mockScribeOptimisticProvider)
+	pokes := []*OpPokedEvent{{BlockNumber: big.NewInt(100)}, {BlockNumber: big.NewInt(200)}, {BlockNumber: big.NewInt(300)}}
+	var awaiting []float64
+	p.On("BlockByNumber", mock.Anything, mock.Anything).
+		Return(&types.Block{Number: big.NewInt(100), Timestamp: time.Now()}, nil)
+	p.On("IsPokeSignatureValid", mock.Anything, address, mock.Anything).
+		Run(func(args mock.Arguments) {
+			awaiting = append(awaiting, testutil.ToFloat64(verifications))
+		}).
+		Return(true, nil)
+
+	c := NewChallenger(context.TODO(), address, p, 0, nil, WithVerifyConcurrency(1))
+	c.pickChallengeablePokes(context.TODO(), pokes, 600)
+	assert.Equal(t, []float64{3, 2, 1}, awaiting)
+	assert.Equal(t, float64(0), testutil.ToFloat64(verifications))
+
+	// Challenges are counted until their outcome is handled.
+	c.inFlightMu.Lock()
+	c.inFlight[100] = struct{}{}
+	c.inFlight[200] = struct{}{}
+	c.updateInFlightGauge()
+	c.inFlightMu.Unlock()
+	assert.Equal(t, float64(2), testutil.ToFloat64(challenges))
+	c.clearInFlight(pokes[0])
+	assert.Equal(t, float64(1), testutil.ToFloat64(challenges))
+}
+
+type stubLeader struct {
+	leader bool
+}
+
+func (s *stubLeader) IsLeader() bool {
+	return s.leader
+}
+
+func TestStandbyTakeOver(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	from := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+
+	p := new(mockScribeOptimisticProvider)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(500)}
+	p.On("GetChallengePeriod", mock.Anything, address).Return(600, nil)
+	p.On("GetFrom", mock.Anything).Return(from)
+	p.On("BlockByNumber", mock.Anything, big.NewInt(500)).
+		Return(&types.Block{Number: big.NewInt(500), Timestamp: time.Now()}, nil)
+	p.On("IsPokeSignatureValid", mock.Anything, address, poke).Return(false, nil)
+	p.On("ChallengePoke", mock.Anything, address, poke).Return(&txHash, &types.Transaction{}, nil)
+
+	leader := &stubLeader{}
+	c := NewChallenger(context.TODO(), address, p, 100, &sync.WaitGroup{}, WithLeaderElector(leader))
+
+	// Standby: poke is verified, but not challenged.
+	p.On("BlockNumber", mock.Anything).Return(big.NewInt(1000), nil).Once()
+	p.On("GetPokes", mock.Anything, address, big.NewInt(100), big.NewInt(1000)).
+		Return([]*OpPokedEvent{poke}, nil).Once()
+	p.On("GetSuccessfulChallenges", mock.Anything, address, big.NewInt(100), big.NewInt(1000)).
+		Return([]*OpPokeChallengedSuccessfullyEvent{}, nil).Once()
+	require.NoError(t, c.executeTick(c.ctx))
+	time.Sleep(50 * time.Millisecond)
+	p.AssertNotCalled(t, "ChallengePoke", mock.Anything, mock.Anything, mock.Anything)
+
+	// Leader: poke seen in standby is taken over.
+	leader.leader = true
+	p.On("BlockNumber", mock.Anything).Return(big.NewInt(1010), nil).Once()
+	p.On("GetPokes", mock.Anything, address, big.NewInt(1001), big.NewInt(1010)).
+		Return([]*OpPokedEvent{}, nil).Once()
+	p.On("GetSuccessfulChallenges", mock.Anything, address, big.NewInt(500), big.NewInt(1010)).
+		Return([]*OpPokeChallengedSuccessfullyEvent{}, nil).Once()
+	require.NoError(t, c.executeTick(c.ctx))
+	time.Sleep(50 * time.Millisecond)
+	p.AssertCalled(t, "ChallengePoke", mock.Anything, address, poke)
+	assert.Empty(t, c.standby)
+}
+
+func TestReadOnly(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe2")
+
+	p := new(mockScribeOptimisticProvider)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(500)}
+	p.On("GetChallengePeriod", mock.Anything, address).Return(600, nil)
+	p.On("GetFrom", mock.Anything).Return(types.ZeroAddress)
+	p.On("BlockByNumber", mock.Anything, big.NewInt(500)).
+		Return(&types.Block{Number: big.NewInt(500), Timestamp: time.Now()}, nil)
+	p.On("IsPokeSignatureValid", mock.Anything, address, poke).Return(false, nil)
+	p.On("BlockNumber", mock.Anything).Return(big.NewInt(1000), nil).Once()
+	p.On("GetPokes", mock.Anything, address, big.NewInt(100), big.NewInt(1000)).
+		Return([]*OpPokedEvent{poke}, nil).Once()
+	p.On("GetSuccessfulChallenges", mock.Anything, address, big.NewInt(100), big.NewInt(1000)).
+		Return([]*OpPokeChallengedSuccessfullyEvent{}, nil).Once()
+
+	// Exported or delegated challenges would act on chain as well.
+	out := filepath.Join(t.TempDir(), "calldata")
+	executor, err := NewChallengeExecutor(writeScript(t, `echo "$1" >> `+out), nil)
+	require.NoError(t, err)
+	exporter := NewPayloadExporter("")
+
+	c := NewChallenger(context.TODO(), address, p, 100, &sync.WaitGroup{},
+		WithReadOnly(), WithPayloadExporter(exporter), WithChallengeExecutor(executor))
+	require.NoError(t, c.executeTick(c.ctx))
+	c.submissions.Wait()
+
+	// The invalid poke is reported, but not challenged.
+	p.AssertNotCalled(t, "ChallengePoke", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, 1.0, testutil.ToFloat64(InvalidPokesCounter.WithLabelValues(address.String())))
+	assert.Empty(t, exporter.Payloads())
+	assert.NoFileExists(t, out)
+}
+
+type stubLock struct {
+	locked   bool
+	unlocked bool
+}
+
+func (s *stubLock) TryLock(context.Context, types.Address, *OpPokedEvent, time.Duration) (bool, error) {
+	return !s.locked, nil
+}
+
+func (s *stubLock) Unlock(context.Context, types.Address, *OpPokedEvent) error {
+	s.unlocked = true
+	return nil
+}
+
+func TestSpawnChallengeWithLock(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(500)}
+
+	t.Run("locked by another instance", func(t *testing.T) {
+		p := new(mockScribeOptimisticProvider)
+		lock := &stubLock{locked: true}
+		c := NewChallenger(context.TODO(), address, p, 100, &sync.WaitGroup{}, WithChallengeLock(lock))
+
+		c.SpawnChallenge(poke)
+		time.Sleep(50 * time.Millisecond)
+		p.AssertNotCalled(t, "ChallengePoke", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("failed challenge releases lock", func(t *testing.T) {
+		p := new(mockScribeOptimisticProvider)
+		p.On("ChallengePoke", mock.Anything, address, poke).
+			Return((*types.Hash)(nil), (*types.Transaction)(nil), fmt.Errorf("reverted"))
+		lock := &stubLock{}
+		c := NewChallenger(context.TODO(), address, p, 100, &sync.WaitGroup{}, WithChallengeLock(lock))
+
+		c.SpawnChallenge(poke)
+		time.Sleep(50 * time.Millisecond)
+		p.AssertCalled(t, "ChallengePoke", mock.Anything, address, poke)
+		assert.True(t, lock.unlocked)
+	})
+
+	t.Run("reverted challenge releases lock", func(t *testing.T) {
+		txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+		p := new(mockScribeOptimisticProvider)
+		p.On("ChallengePoke", mock.Anything, address, poke).Return(&txHash, &types.Transaction{}, nil)
+		p.On("GetFrom", mock.Anything).Return(types.ZeroAddress)
+		lock := &stubLock{}
+		c := NewChallenger(context.TODO(), address, p, 100, &sync.WaitGroup{}, WithChallengeLock(lock))
+
+		c.SpawnChallenge(poke)
+		time.Sleep(50 * time.Millisecond)
+		assert.False(t, lock.unlocked)
+
+		reverted := ChallengeCounter.WithLabelValues(address.String(), types.ZeroAddress.String(), ChallengeResultReverted)
+		before := testutil.ToFloat64(reverted)
+		c.handleChallengeOutcome(TxOutcome{Address: address, Poke: poke, Hash: &txHash, Err: ErrTxReverted})
+		assert.True(t, lock.unlocked)
+		assert.Equal(t, before+1, testutil.ToFloat64(reverted))
+	})
+}
+
+func TestNextTickDelay(t *testing.T) {
+	interval := TickInterval
+	TickInterval = 30 * time.Second
+	defer func() { TickInterval = interval }()
+
+	c := NewChallenger(context.TODO(), types.ZeroAddress, nil, 0, nil)
+	assert.Equal(t, TickInterval, c.nextTickDelay())
+
+	// Invalid poke is being challenged.
+	c.trackUnconfirmed(&OpPokedEvent{BlockNumber: big.NewInt(100)}, time.Now().Add(time.Minute))
+	assert.Equal(t, DefaultFastTickInterval, c.nextTickDelay())
+	c.confirmChallenge(&OpPokedEvent{BlockNumber: big.NewInt(100)})
+	assert.Equal(t, TickInterval, c.nextTickDelay())
+
+	// Poke waits for confirmations.
+	c.awaitingPokes = 1
+	assert.Equal(t, DefaultFastTickInterval, c.nextTickDelay())
+
+	c = NewChallenger(context.TODO(), types.ZeroAddress, nil, 0, nil, WithFastTickInterval(0))
+	c.awaitingPokes = 1
+	assert.Equal(t, TickInterval, c.nextTickDelay())
+}
+
+func TestTickDeadline(t *testing.T) {
+	interval := TickInterval
+	TickInterval = 20 * time.Millisecond
+	defer func() { TickInterval = interval }()
+
+	p := new(mockScribeOptimisticProvider)
+	p.On("GetFrom", mock.Anything).Return(types.ZeroAddress)
+	p.On("BlockNumber", mock.Anything).
+		Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+		Return((*big.Int)(nil), context.DeadlineExceeded)
+
+	c := NewChallenger(context.Background(), types.ZeroAddress, p, 0, nil)
+	done := make(chan struct{})
+	go func() {
+		c.tick()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("slow call spilled over the tick")
+	}
+}
+
+func TestTickRequestID(t *testing.T) {
+	var ids []string
+	p := new(mockScribeOptimisticProvider)
+	p.On("GetFrom", mock.Anything).Return(types.ZeroAddress)
+	p.On("BlockNumber", mock.Anything).
+		Run(func(args mock.Arguments) { ids = append(ids, RequestIDFromContext(args.Get(0).(context.Context))) }).
+		Return((*big.Int)(nil), fmt.Errorf("rpc down"))
+
+	c := NewChallenger(context.Background(), types.ZeroAddress, p, 0, nil)
+	c.tick()
+	c.tick()
+	require.Len(t, ids, 2)
+	assert.Len(t, ids[0], 16)
+	assert.NotEqual(t, ids[0], ids[1])
+	assert.Equal(t, ids[1], c.log().Data["requestId"])
+}
+
+func TestAlertExpiringWindows(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	alerts := testutil.ToFloat64(ChallengeWindowExpiringCounter.WithLabelValues(address.String()))
+
+	c := NewChallenger(context.TODO(), address, nil, 0, nil, WithExpiryAlert(0.25))
+	c.observeChallengePeriod(600)
+	c.trackUnconfirmed(&OpPokedEvent{BlockNumber: big.NewInt(100)}, time.Now().Add(100*time.Second))
+	c.trackUnconfirmed(&OpPokedEvent{BlockNumber: big.NewInt(200)}, time.Now().Add(500*time.Second))
+
+	c.alertExpiringWindows()
+	c.alertExpiringWindows()
+	// Alerted only once for the poke.
+	assert.Equal(t, alerts+1, testutil.ToFloat64(ChallengeWindowExpiringCounter.WithLabelValues(address.String())))
+	assert.Equal(t, 1.0, testutil.ToFloat64(ChallengeWindowExpiringGauge.WithLabelValues(address.String())))
+
+	c.confirmChallenge(&OpPokedEvent{BlockNumber: big.NewInt(100)})
+	c.alertExpiringWindows()
+	assert.Equal(t, 0.0, testutil.ToFloat64(ChallengeWindowExpiringGauge.WithLabelValues(address.String())))
+	assert.Empty(t, c.expiryAlerted)
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/defiweb/go-eth/types"
+)
+
+// CheckpointStore persists the last block processed for each address, so scanning resumes from it after restart.
+type CheckpointStore interface {
+	// Save sets the last processed block of the address.
+	Save(address types.Address, block *big.Int) error
+	// Load returns the last processed block of the address, nil if there is none.
+	Load(address types.Address) (*big.Int, error)
+}
+
+// FileCheckpointStore implements CheckpointStore keeping checkpoints of all addresses in a JSON file.
+type FileCheckpointStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileCheckpointStore creates a new instance of FileCheckpointStore, the file is created on first save.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// Save implements CheckpointStore interface.
+func (f *FileCheckpointStore) Save(address types.Address, block *big.Int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	checkpoints, err := f.load()
+	if err != nil {
+		return err
+	}
+	checkpoints[address] = block.Uint64()
+	return f.write(checkpoints)
+}
+
+// Load implements CheckpointStore interface.
+func (f *FileCheckpointStore) Load(address types.Address) (*big.Int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	checkpoints, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	block, ok := checkpoints[address]
+	if !ok {
+		return nil, nil
+	}
+	return new(big.Int).SetUint64(block), nil
+}
+
+func (f *FileCheckpointStore) load() (map[types.Address]uint64, error) {
+	checkpoints := make(map[types.Address]uint64)
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoints, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoints: %w", err)
+	}
+	if err := json.Unmarshal(b, &checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoints: %w", err)
+	}
+	return checkpoints, nil
+}
+
+// write replaces the file atomically, so a crash never leaves it half written.
+func (f *FileCheckpointStore) write(checkpoints map[types.Address]uint64) error {
+	b, err := json.Marshal(checkpoints)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoints: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to write checkpoints: %w", err)
+	}
+	return nil
+}
+
+// startBlockNumber decides the block the first scan starts from: the block after the persisted checkpoint if it's
+// still within the challenge window, otherwise the start of the window, as pokes before it can't be challenged anymore.
+func (c *Challenger) startBlockNumber(latestBlockNumber *big.Int, earliestBlockNumber *big.Int) *big.Int {
+	log := c.log().
+		WithField("latestBlock", latestBlockNumber).
+		WithField("windowStartBlock", earliestBlockNumber)
+	if c.checkpoints == nil {
+		log.Infof("Scanning challenge window from block %v", earliestBlockNumber)
+		return earliestBlockNumber
+	}
+	checkpoint, err := c.checkpoints.Load(c.address)
+	switch {
+	case err != nil:
+		log.WithError(err).Warnf("Failed to load checkpoint, scanning challenge window from block %v", earliestBlockNumber)
+		return earliestBlockNumber
+	case checkpoint == nil:
+		log.Infof("No checkpoint found, scanning challenge window from block %v", earliestBlockNumber)
+		return earliestBlockNumber
+	case checkpoint.Cmp(latestBlockNumber) > 0:
+		log.WithField("checkpoint", checkpoint).
+			Warnf("Checkpoint is ahead of the latest block, scanning challenge window from block %v", earliestBlockNumber)
+		return earliestBlockNumber
+	case checkpoint.Cmp(earliestBlockNumber) < 0:
+		log.WithField("checkpoint", checkpoint).
+			Infof("Checkpoint is older than challenge window, scanning challenge window from block %v", earliestBlockNumber)
+		return earliestBlockNumber
+	default:
+		next := new(big.Int).Add(checkpoint, big.NewInt(1))
+		log.WithField("checkpoint", checkpoint).Infof("Resuming scan after checkpoint from block %v", next)
+		return next
+	}
+}
+
+// saveCheckpoint persists the last processed block if the checkpoint store is configured.
+func (c *Challenger) saveCheckpoint() {
+	if c.checkpoints == nil || c.lastProcessedBlock == nil {
+		return
+	}
+	if err := c.checkpoints.Save(c.address, c.lastProcessedBlock); err != nil {
+		c.log().
+			WithError(err).
+			Warn("Failed to save checkpoint")
+	}
+}
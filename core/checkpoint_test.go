@@ -0,0 +1,78 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCheckpointStore(t *testing.T) {
+	address1 := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	address2 := types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEe2")
+	path := filepath.Join(t.TempDir(), "checkpoints.json")
+	store := NewFileCheckpointStore(path)
+
+	block, err := store.Load(address1)
+	require.NoError(t, err)
+	assert.Nil(t, block)
+
+	require.NoError(t, store.Save(address1, big.NewInt(100)))
+	require.NoError(t, store.Save(address2, big.NewInt(200)))
+	require.NoError(t, store.Save(address1, big.NewInt(101)))
+
+	// Checkpoints survive restart.
+	store = NewFileCheckpointStore(path)
+	block, err = store.Load(address1)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(101), block)
+	block, err = store.Load(address2)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(200), block)
+}
+
+func TestGetFromBlockNumberCheckpoint(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+
+	tests := []struct {
+		name       string
+		checkpoint *big.Int
+		want       *big.Int
+	}{
+		{name: "no checkpoint", want: big.NewInt(950)},
+		{name: "checkpoint within window", checkpoint: big.NewInt(990), want: big.NewInt(991)},
+		{name: "checkpoint older than window", checkpoint: big.NewInt(900), want: big.NewInt(950)},
+		{name: "checkpoint ahead of latest block", checkpoint: big.NewInt(2000), want: big.NewInt(950)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+			if tt.checkpoint != nil {
+				require.NoError(t, store.Save(address, tt.checkpoint))
+			}
+			c := NewChallenger(context.TODO(), address, new(mockScribeOptimisticProvider), 0, nil, WithCheckpointStore(store))
+			b, err := c.getFromBlockNumber(big.NewInt(1000), 600)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, b)
+		})
+	}
+
+	// Explicit start block takes precedence over the checkpoint.
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoints.json"))
+	require.NoError(t, store.Save(address, big.NewInt(990)))
+	c := NewChallenger(context.TODO(), address, new(mockScribeOptimisticProvider), 500, nil, WithCheckpointStore(store))
+	b, err := c.getFromBlockNumber(big.NewInt(1000), 600)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(500), b)
+
+	// Last processed block is persisted.
+	c.lastProcessedBlock = big.NewInt(995)
+	c.saveCheckpoint()
+	block, err := store.Load(address)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(995), block)
+}
@@ -7,7 +7,14 @@ import (
 	"github.com/defiweb/go-eth/types"
 )
 
+// RPCClient is the JSON-RPC client used by core, implemented by rpc.Client of go-eth and FailoverClient.
+// Methods depending on the transport or node, e.g. subscriptions, are in separate interfaces
+// and detected by type assertion, except SubscribeLogs, which fails on transports without subscriptions.
 type RPCClient interface {
+	TransactionFetcher
+
+	ChainID(ctx context.Context) (uint64, error)
+
 	Accounts(ctx context.Context) ([]types.Address, error)
 
 	BlockNumber(ctx context.Context) (*big.Int, error)
@@ -21,4 +28,30 @@ type RPCClient interface {
 	GetLogs(ctx context.Context, query *types.FilterLogsQuery) ([]types.Log, error)
 
 	GetTransactionReceipt(ctx context.Context, hash types.Hash) (*types.TransactionReceipt, error)
+
+	GetBalance(ctx context.Context, address types.Address, block types.BlockNumber) (*big.Int, error)
+
+	SubscribeLogs(ctx context.Context, query *types.FilterLogsQuery) (<-chan types.Log, error)
+}
+
+// HeadSubscriber is implemented by RPC clients able to subscribe to new block headers, e.g. over websocket.
+type HeadSubscriber interface {
+	SubscribeNewHeads(ctx context.Context) (<-chan types.Block, error)
+}
+
+// TransactionFetcher is implemented by RPC clients able to fetch transactions by hash, all RPCClient are.
+type TransactionFetcher interface {
+	GetTransactionByHash(ctx context.Context, hash types.Hash) (*types.OnChainTransaction, error)
+}
+
+// CodeFetcher is implemented by RPC clients able to fetch deployed code and storage of contracts.
+type CodeFetcher interface {
+	GetCode(ctx context.Context, account types.Address, block types.BlockNumber) ([]byte, error)
+	GetStorageAt(ctx context.Context, account types.Address, key types.Hash, block types.BlockNumber) (*types.Hash, error)
+}
+
+// GasEstimator is implemented by RPC clients able to estimate gas limit and gas price of transactions.
+type GasEstimator interface {
+	EstimateGas(ctx context.Context, call *types.Call, block types.BlockNumber) (uint64, *types.Call, error)
+	GasPrice(ctx context.Context) (*big.Int, error)
 }
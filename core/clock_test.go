@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is the Clock which time only moves by Advance, firing timers and tickers which are due.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// fakeTicker adapts fakeTimer to Ticker.
+type fakeTicker struct{ *fakeTimer }
+
+func (t fakeTicker) Stop() { t.fakeTimer.Stop() }
+
+type fakeTimer struct {
+	clock  *fakeClock
+	c      chan time.Time
+	at     time.Time
+	period time.Duration
+	active bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) NewTimer(d time.Duration) Timer {
+	return f.add(d, 0)
+}
+
+func (f *fakeClock) NewTicker(d time.Duration) Ticker {
+	return fakeTicker{f.add(d, d)}
+}
+
+func (f *fakeClock) add(d, period time.Duration) *fakeTimer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{clock: f, c: make(chan time.Time, 1), at: f.now.Add(d), period: period, active: true}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+// Advance moves the time forward, timers due are fired, tickers fire at most once.
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+	for _, t := range f.timers {
+		if !t.active || t.at.After(f.now) {
+			continue
+		}
+		select {
+		case t.c <- f.now:
+		default:
+		}
+		if t.period == 0 {
+			t.active = false
+			continue
+		}
+		for !t.at.After(f.now) {
+			t.at = t.at.Add(t.period)
+		}
+	}
+}
+
+// Waiters returns the number of active timers and tickers.
+func (f *fakeClock) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, t := range f.timers {
+		if t.active {
+			n++
+		}
+	}
+	return n
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.active
+	t.active = false
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.active
+	t.active = true
+	t.at = t.clock.now.Add(d)
+	return active
+}
+
+func TestChallengerLoopTicks(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	clock := newFakeClock()
+	var ticks atomic.Int64
+	p := new(mockScribeOptimisticProvider)
+	p.On("BlockNumber", mock.Anything).
+		Run(func(mock.Arguments) { ticks.Add(1) }).
+		Return((*big.Int)(nil), assert.AnError)
+	p.On("GetFrom", mock.Anything).Return(types.ZeroAddress)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewChallenger(ctx, address, p, 0, nil, WithClock(clock))
+	done := make(chan error)
+	go func() { done <- c.loop(ctx) }()
+
+	// The first tick runs right away, the next one is scheduled.
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, int64(1), ticks.Load())
+
+	clock.Advance(TickInterval - time.Second)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int64(1), ticks.Load())
+
+	clock.Advance(time.Second)
+	require.Eventually(t, func() bool { return ticks.Load() == 2 }, time.Second, time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestWaitForTxConfirmationClockTimeout(t *testing.T) {
+	hash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	clock := newFakeClock()
+	client := new(mockRpcClient)
+	client.On("GetTransactionReceipt", mock.Anything, hash).Return((*types.TransactionReceipt)(nil), nil)
+
+	done := make(chan error)
+	go func() {
+		_, err := WaitForTxConfirmation(ContextWithClock(context.Background(), clock), client, &hash, time.Hour)
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return clock.Waiters() == 2 }, time.Second, time.Millisecond)
+
+	clock.Advance(59 * time.Minute)
+	select {
+	case err := <-done:
+		t.Fatalf("returned before timeout: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	clock.Advance(time.Minute)
+	select {
+	case err := <-done:
+		assert.ErrorContains(t, err, "failed to wait for transaction confirmation")
+	case <-time.After(time.Second):
+		t.Fatal("timeout not reached")
+	}
+}
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+)
+
+// ErrCodeNotAllowed is returned when deployed code of the address is not in the allowlist.
+var ErrCodeNotAllowed = errors.New("code hash not allowed")
+
+// EIP1967ImplementationSlot is the storage slot holding implementation address of EIP-1967 proxies.
+var EIP1967ImplementationSlot = types.MustHashFromHex("0x360894a13ba1a3210667c828492db98dca3e2076cc3735a920a3ca505d382bbc", types.PadNone)
+
+// CodeVerifier checks keccak256 hash of the code deployed at an address, or at its EIP-1967 implementation,
+// against an allowlist of known ScribeOptimistic code hashes, so typo'd or malicious addresses are never challenged.
+type CodeVerifier struct {
+	client  CodeFetcher
+	allowed map[types.Hash]struct{}
+}
+
+// NewCodeVerifier creates a new instance of CodeVerifier allowing the given code hashes.
+func NewCodeVerifier(client CodeFetcher, hashes []types.Hash) *CodeVerifier {
+	allowed := make(map[types.Hash]struct{}, len(hashes))
+	for _, h := range hashes {
+		allowed[h] = struct{}{}
+	}
+	return &CodeVerifier{client: client, allowed: allowed}
+}
+
+// Verify returns the allowed code hash of the address, or ErrCodeNotAllowed if neither the code of the address,
+// nor the code of its EIP-1967 implementation is allowed.
+func (v *CodeVerifier) Verify(ctx context.Context, address types.Address) (types.Hash, error) {
+	code, err := v.client.GetCode(ctx, address, types.LatestBlockNumber)
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("failed to get code of %v: %w", address, err)
+	}
+	if len(code) == 0 {
+		return types.Hash{}, fmt.Errorf("%w: no code deployed at %v", ErrCodeNotAllowed, address)
+	}
+	hash := crypto.Keccak256(code)
+	if _, ok := v.allowed[hash]; ok {
+		return hash, nil
+	}
+
+	slot, err := v.client.GetStorageAt(ctx, address, EIP1967ImplementationSlot, types.LatestBlockNumber)
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("failed to get implementation of %v: %w", address, err)
+	}
+	implementation := types.MustAddressFromBytes(slot[types.HashLength-types.AddressLength:])
+	if implementation.IsZero() {
+		return types.Hash{}, fmt.Errorf("%w: code hash of %v is %v", ErrCodeNotAllowed, address, hash)
+	}
+	code, err = v.client.GetCode(ctx, implementation, types.LatestBlockNumber)
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("failed to get code of implementation %v: %w", implementation, err)
+	}
+	implHash := crypto.Keccak256(code)
+	if _, ok := v.allowed[implHash]; ok {
+		return implHash, nil
+	}
+	return types.Hash{}, fmt.Errorf(
+		"%w: code hash of %v is %v, code hash of its implementation %v is %v",
+		ErrCodeNotAllowed, address, hash, implementation, implHash,
+	)
+}
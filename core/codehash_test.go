@@ -0,0 +1,73 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCodeFetcher serves code and EIP-1967 implementation slot of accounts from maps.
+type fakeCodeFetcher struct {
+	code           map[types.Address][]byte
+	implementation map[types.Address]types.Address
+}
+
+func (f *fakeCodeFetcher) GetCode(_ context.Context, account types.Address, _ types.BlockNumber) ([]byte, error) {
+	return f.code[account], nil
+}
+
+func (f *fakeCodeFetcher) GetStorageAt(_ context.Context, account types.Address, key types.Hash, _ types.BlockNumber) (*types.Hash, error) {
+	var slot types.Hash
+	if key == EIP1967ImplementationSlot {
+		copy(slot[types.HashLength-types.AddressLength:], f.implementation[account].Bytes())
+	}
+	return &slot, nil
+}
+
+func TestCodeVerifier(t *testing.T) {
+	scribe := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	proxy := types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEe2")
+	implementation := types.MustAddressFromHex("0x3F7acDa376eF37EC371235a094113dF9Cb4EfEe3")
+	unknown := types.MustAddressFromHex("0x4F7acDa376eF37EC371235a094113dF9Cb4EfEe4")
+	empty := types.MustAddressFromHex("0x5F7acDa376eF37EC371235a094113dF9Cb4EfEe5")
+	scribeCode := []byte{0x60, 0x80, 0x01}
+
+	client := &fakeCodeFetcher{
+		code: map[types.Address][]byte{
+			scribe:         scribeCode,
+			proxy:          {0x60, 0x80, 0x02},
+			implementation: scribeCode,
+			unknown:        {0x60, 0x80, 0x03},
+		},
+		implementation: map[types.Address]types.Address{proxy: implementation},
+	}
+	v := NewCodeVerifier(client, []types.Hash{crypto.Keccak256(scribeCode)})
+
+	hash, err := v.Verify(context.TODO(), scribe)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.Keccak256(scribeCode), hash)
+
+	hash, err = v.Verify(context.TODO(), proxy)
+	require.NoError(t, err)
+	assert.Equal(t, crypto.Keccak256(scribeCode), hash)
+
+	_, err = v.Verify(context.TODO(), unknown)
+	assert.ErrorIs(t, err, ErrCodeNotAllowed)
+	_, err = v.Verify(context.TODO(), empty)
+	assert.ErrorIs(t, err, ErrCodeNotAllowed)
+
+	// Address with code not allowed is never challenged.
+	p := new(mockScribeOptimisticProvider)
+	p.On("GetFrom", mock.Anything).Return(types.ZeroAddress)
+	c := NewChallenger(context.TODO(), unknown, p, 0, nil, WithCodeVerifier(v))
+	c.SpawnChallenge(&OpPokedEvent{BlockNumber: big.NewInt(100)})
+	c.submissions.Wait()
+	p.AssertNotCalled(t, "ChallengePoke", mock.Anything, mock.Anything, mock.Anything)
+	assert.Equal(t, int64(1), c.failedSubmissions.Load())
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"sort"
+)
+
+// observeChallengePeriod publishes the challenge period and alerts when it changes, e.g. optimistic poking being
+// effectively disabled with period set to 0.
+func (c *Challenger) observeChallengePeriod(period uint16) {
+	ChallengePeriodGauge.WithLabelValues(c.address.String()).Set(float64(period))
+	if c.challengePeriod != nil && *c.challengePeriod == period {
+		return
+	}
+	defer func() { c.challengePeriod = &period }()
+
+	if period == 0 {
+		c.log().Error("Challenge period is 0, optimistic pokes can't be challenged")
+		return
+	}
+	if c.challengePeriod != nil {
+		c.log().Warnf("Challenge period changed from %d to %d seconds", *c.challengePeriod, period)
+	}
+}
+
+// watchContractState alerts on events changing which defense the challenger provides, e.g. dropped poke data,
+// lifted or dropped feeds or auth changes. Each block is fetched only once.
+// Failing to fetch events doesn't fail the tick, they are fetched again on the next one.
+func (c *Challenger) watchContractState(ctx context.Context, fromBlock *big.Int, toBlock *big.Int) {
+	if !c.contractState {
+		return
+	}
+	// Range following the last fetched one, so a range failed to fetch is retried even if the tick moved on.
+	if c.stateCheckedBlock != nil {
+		fromBlock = new(big.Int).Add(c.stateCheckedBlock, big.NewInt(1))
+	}
+	if fromBlock.Cmp(toBlock) > 0 {
+		return
+	}
+
+	events, err := c.provider.GetContractStateEvents(ctx, c.address, fromBlock, toBlock)
+	if err != nil {
+		c.log().Errorf("Failed to get contract state events with error: %v", err)
+		return
+	}
+	c.stateCheckedBlock = toBlock
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return isEventBefore(events[i], events[j])
+	})
+	for _, event := range events {
+		ContractStateChangesCounter.WithLabelValues(c.address.String(), event.Event).Inc()
+		c.log().
+			WithField("txHash", event.TxHash).
+			WithField("event", event.Event).
+			Warnf("Contract state changed in block %v: %v", event.BlockNumber, event.Values)
+	}
+}
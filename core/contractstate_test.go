@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestObserveChallengePeriod(t *testing.T) {
+	address := types.MustAddressFromHex("0x9F7acDa376eF37EC371235a094113dF9Cb4EfEe9")
+	c := NewChallenger(context.TODO(), address, nil, 0, nil)
+
+	c.observeChallengePeriod(600)
+	assert.Equal(t, float64(600), testutil.ToFloat64(ChallengePeriodGauge.WithLabelValues(address.String())))
+	c.observeChallengePeriod(0)
+	assert.Equal(t, float64(0), testutil.ToFloat64(ChallengePeriodGauge.WithLabelValues(address.String())))
+	assert.Equal(t, uint16(0), *c.challengePeriod)
+}
+
+func TestWatchContractState(t *testing.T) {
+	address := types.MustAddressFromHex("0xaF7acDa376eF37EC371235a094113dF9Cb4EfEea")
+	provider := new(mockScribeOptimisticProvider)
+	c := NewChallenger(context.TODO(), address, provider, 0, nil, WithContractStateWatching())
+	counter := ContractStateChangesCounter.WithLabelValues(address.String(), "OpPokeDataDropped")
+
+	provider.On("GetContractStateEvents", mock.Anything, address, big.NewInt(1), big.NewInt(10)).
+		Return([]*ContractStateEvent{{BlockNumber: big.NewInt(5), Event: "OpPokeDataDropped"}}, nil).Once()
+	c.watchContractState(context.TODO(), big.NewInt(1), big.NewInt(10))
+	assert.Equal(t, float64(1), testutil.ToFloat64(counter))
+
+	// Rescanned block is not fetched again.
+	provider.On("GetContractStateEvents", mock.Anything, address, big.NewInt(11), big.NewInt(20)).
+		Return([]*ContractStateEvent{}, fmt.Errorf("rpc error")).Once()
+	c.watchContractState(context.TODO(), big.NewInt(10), big.NewInt(20))
+
+	// Failed range is fetched again.
+	provider.On("GetContractStateEvents", mock.Anything, address, big.NewInt(11), big.NewInt(30)).
+		Return([]*ContractStateEvent{{BlockNumber: big.NewInt(25), Event: "OpPokeDataDropped"}}, nil).Once()
+	c.watchContractState(context.TODO(), big.NewInt(20), big.NewInt(30))
+	assert.Equal(t, float64(2), testutil.ToFloat64(counter))
+
+	// Nothing new to fetch.
+	c.watchContractState(context.TODO(), big.NewInt(30), big.NewInt(30))
+	provider.AssertNumberOfCalls(t, "GetContractStateEvents", 3)
+}
@@ -0,0 +1,232 @@
+package coretest
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+
+	"github.com/chronicleprotocol/challenger/core"
+)
+
+// DefaultChainID is the chain ID of Client.
+const DefaultChainID = 1
+
+// Client is the core.RPCClient serving an in-memory chain. Logs are added by AddLog, contract calls are
+// answered by CallFunc. Sent transactions are recorded and mined in the next block, reverted if Revert is set.
+// Blocks are BlockTime apart, the head block is at the time of Clock.
+type Client struct {
+	// Clock gives the time of the head block, core.SystemClock by default.
+	Clock core.Clock
+	// BlockTime is the time between blocks, core.DefaultBlockTime by default.
+	BlockTime time.Duration
+	// ChainIDValue is returned by ChainID, DefaultChainID by default.
+	ChainIDValue uint64
+	// From is the account of the node.
+	From types.Address
+	// CallFunc answers contract calls, calls fail if it's nil.
+	CallFunc func(call *types.Call, block types.BlockNumber) ([]byte, error)
+	// SendErr is returned by SendTransaction, the transaction is not sent.
+	SendErr error
+	// Revert makes sent transactions revert.
+	Revert bool
+
+	mu       sync.Mutex
+	head     uint64
+	logs     []types.Log
+	sent     []*types.Transaction
+	txs      map[types.Hash]*types.OnChainTransaction
+	receipts map[types.Hash]*types.TransactionReceipt
+	balances map[types.Address]*big.Int
+}
+
+// NewClient creates a new instance of Client with the head at the block.
+func NewClient(head uint64) *Client {
+	return &Client{
+		Clock:        core.SystemClock,
+		BlockTime:    core.DefaultBlockTime,
+		ChainIDValue: DefaultChainID,
+		head:         head,
+		txs:          make(map[types.Hash]*types.OnChainTransaction),
+		receipts:     make(map[types.Hash]*types.TransactionReceipt),
+		balances:     make(map[types.Address]*big.Int),
+	}
+}
+
+// SetHead moves the head of the chain to the block.
+func (c *Client) SetHead(head uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.head = head
+}
+
+// AddLog adds logs returned by GetLogs, e.g. built by OpPokedLog.
+func (c *Client) AddLog(logs ...types.Log) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logs = append(c.logs, logs...)
+}
+
+// AddTransaction adds the transaction returned by GetTransactionByHash, e.g. the one emitting a log.
+func (c *Client) AddTransaction(tx *types.OnChainTransaction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.txs[*tx.Hash] = tx
+}
+
+// SetBalance sets the balance of the account.
+func (c *Client) SetBalance(account types.Address, balance *big.Int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.balances[account] = balance
+}
+
+// Sent returns transactions sent so far.
+func (c *Client) Sent() []*types.Transaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]*types.Transaction(nil), c.sent...)
+}
+
+// Accounts implements core.RPCClient interface.
+func (c *Client) Accounts(context.Context) ([]types.Address, error) {
+	return []types.Address{c.From}, nil
+}
+
+// ChainID implements core.RPCClient interface.
+func (c *Client) ChainID(context.Context) (uint64, error) {
+	return c.ChainIDValue, nil
+}
+
+// BlockNumber implements core.RPCClient interface.
+func (c *Client) BlockNumber(context.Context) (*big.Int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return new(big.Int).SetUint64(c.head), nil
+}
+
+// BlockByNumber implements core.RPCClient interface, blocks have no transactions.
+func (c *Client) BlockByNumber(_ context.Context, number types.BlockNumber, _ bool) (*types.Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	block := c.head
+	if !number.IsTag() {
+		block = number.Big().Uint64()
+	}
+	if block > c.head {
+		return nil, fmt.Errorf("block %v not found", block)
+	}
+	return &types.Block{
+		Number:    new(big.Int).SetUint64(block),
+		Timestamp: c.Clock.Now().Add(-time.Duration(c.head-block) * c.BlockTime),
+	}, nil
+}
+
+// SendTransaction implements core.RPCClient interface.
+func (c *Client) SendTransaction(_ context.Context, tx *types.Transaction) (*types.Hash, *types.Transaction, error) {
+	if c.SendErr != nil {
+		return nil, nil, c.SendErr
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.head++
+	hash := crypto.Keccak256([]byte("sent"), big.NewInt(int64(len(c.sent))).Bytes())
+	c.sent = append(c.sent, tx)
+	status := uint64(1)
+	if c.Revert {
+		status = 0
+	}
+	gasUsed := uint64(0)
+	if tx.GasLimit != nil {
+		gasUsed = *tx.GasLimit
+	}
+	c.txs[hash] = &types.OnChainTransaction{Transaction: *tx, Hash: &hash, BlockNumber: new(big.Int).SetUint64(c.head)}
+	c.receipts[hash] = &types.TransactionReceipt{
+		TransactionHash:   hash,
+		BlockNumber:       new(big.Int).SetUint64(c.head),
+		GasUsed:           gasUsed,
+		EffectiveGasPrice: big.NewInt(0),
+		Status:            &status,
+	}
+	return &hash, tx, nil
+}
+
+// Call implements core.RPCClient interface.
+func (c *Client) Call(_ context.Context, call *types.Call, block types.BlockNumber) ([]byte, *types.Call, error) {
+	if c.CallFunc == nil {
+		return nil, nil, fmt.Errorf("no contract calls expected")
+	}
+	res, err := c.CallFunc(call, block)
+	return res, call, err
+}
+
+// GetLogs implements core.RPCClient interface, logs are filtered by addresses, first topics and block range.
+func (c *Client) GetLogs(_ context.Context, query *types.FilterLogsQuery) ([]types.Log, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var logs []types.Log
+	for _, log := range c.logs {
+		if matchLog(log, query) {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+// GetTransactionReceipt implements core.RPCClient interface, receipts of unknown transactions are nil.
+func (c *Client) GetTransactionReceipt(_ context.Context, hash types.Hash) (*types.TransactionReceipt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.receipts[hash], nil
+}
+
+// GetTransactionByHash implements core.RPCClient interface, unknown transactions are nil.
+func (c *Client) GetTransactionByHash(_ context.Context, hash types.Hash) (*types.OnChainTransaction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.txs[hash], nil
+}
+
+// GetBalance implements core.RPCClient interface.
+func (c *Client) GetBalance(_ context.Context, account types.Address, _ types.BlockNumber) (*big.Int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if balance, ok := c.balances[account]; ok {
+		return new(big.Int).Set(balance), nil
+	}
+	return big.NewInt(0), nil
+}
+
+// SubscribeLogs implements core.RPCClient interface, subscriptions are not supported.
+func (c *Client) SubscribeLogs(context.Context, *types.FilterLogsQuery) (<-chan types.Log, error) {
+	return nil, fmt.Errorf("subscriptions are not supported")
+}
+
+func matchLog(log types.Log, query *types.FilterLogsQuery) bool {
+	if len(query.Address) > 0 && !contains(query.Address, log.Address) {
+		return false
+	}
+	if len(query.Topics) > 0 && len(query.Topics[0]) > 0 && (len(log.Topics) == 0 || !contains(query.Topics[0], log.Topics[0])) {
+		return false
+	}
+	if query.FromBlock != nil && !query.FromBlock.IsTag() && log.BlockNumber.Cmp(query.FromBlock.Big()) < 0 {
+		return false
+	}
+	if query.ToBlock != nil && !query.ToBlock.IsTag() && log.BlockNumber.Cmp(query.ToBlock.Big()) > 0 {
+		return false
+	}
+	return true
+}
+
+func contains[T comparable](items []T, item T) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
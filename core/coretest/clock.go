@@ -0,0 +1,113 @@
+package coretest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/chronicleprotocol/challenger/core"
+)
+
+// Clock is the core.Clock which time only moves by Advance, firing timers and tickers which are due.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*timer
+}
+
+type timer struct {
+	clock  *Clock
+	c      chan time.Time
+	at     time.Time
+	period time.Duration
+	active bool
+}
+
+// ticker adapts timer to core.Ticker.
+type ticker struct{ *timer }
+
+// NewClock creates a new instance of Clock set to the time.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now implements core.Clock interface.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer implements core.Clock interface.
+func (c *Clock) NewTimer(d time.Duration) core.Timer {
+	return c.add(d, 0)
+}
+
+// NewTicker implements core.Clock interface.
+func (c *Clock) NewTicker(d time.Duration) core.Ticker {
+	return ticker{c.add(d, d)}
+}
+
+func (c *Clock) add(d, period time.Duration) *timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &timer{clock: c, c: make(chan time.Time, 1), at: c.now.Add(d), period: period, active: true}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// Advance moves the time forward, timers due are fired, tickers fire at most once.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.timers {
+		if !t.active || t.at.After(c.now) {
+			continue
+		}
+		select {
+		case t.c <- c.now:
+		default:
+		}
+		if t.period == 0 {
+			t.active = false
+			continue
+		}
+		for !t.at.After(c.now) {
+			t.at = t.at.Add(t.period)
+		}
+	}
+}
+
+// Waiters returns the number of active timers and tickers, so tests can wait until the code under test waits.
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, t := range c.timers {
+		if t.active {
+			n++
+		}
+	}
+	return n
+}
+
+func (t *timer) C() <-chan time.Time { return t.c }
+
+func (t *timer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.active
+	t.active = false
+	return active
+}
+
+func (t *timer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	active := t.active
+	t.active = true
+	t.at = t.clock.now.Add(d)
+	return active
+}
+
+func (t ticker) Stop() { t.timer.Stop() }
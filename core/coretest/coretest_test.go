@@ -0,0 +1,134 @@
+package coretest
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/challenger/core"
+)
+
+var address = types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEe2")
+
+func TestProvider(t *testing.T) {
+	t.Run("invalid poke is challenged", func(t *testing.T) {
+		p := NewProvider(1000)
+		valid, invalid := Poke(990, 1000, 1), Poke(995, 2000, 2)
+		p.AddPoke(address, valid, true)
+		p.AddPoke(address, invalid, false)
+
+		c := core.NewChallenger(context.Background(), address, p, 0, nil)
+		report, err := c.RunOnce()
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.InvalidPokes)
+		require.Len(t, p.Challenged(), 1)
+		assert.Equal(t, invalid, p.Challenged()[0].Poke)
+
+		outcome := <-p.ChallengeOutcomes()
+		assert.NoError(t, outcome.Err)
+		challenges, err := p.GetSuccessfulChallenges(context.Background(), address, big.NewInt(0), big.NewInt(2000))
+		require.NoError(t, err)
+		assert.Len(t, challenges, 1)
+	})
+
+	t.Run("failed challenge", func(t *testing.T) {
+		p := NewProvider(1000)
+		p.SendErr = fmt.Errorf("rpc down")
+		p.AddPoke(address, Poke(995, 2000, 2), false)
+
+		c := core.NewChallenger(context.Background(), address, p, 0, nil)
+		report, err := c.RunOnce()
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.FailedChallenges)
+		assert.Empty(t, p.Challenged())
+	})
+
+	t.Run("blocks are block time apart", func(t *testing.T) {
+		p := NewProvider(1000)
+		p.Clock = NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		head, err := p.BlockByNumber(context.Background(), big.NewInt(1000))
+		require.NoError(t, err)
+		block, err := p.BlockByNumber(context.Background(), big.NewInt(990))
+		require.NoError(t, err)
+		assert.Equal(t, 10*core.DefaultBlockTime, head.Timestamp.Sub(block.Timestamp))
+
+		_, err = p.BlockByNumber(context.Background(), big.NewInt(1001))
+		assert.Error(t, err)
+	})
+}
+
+func TestClock(t *testing.T) {
+	clock := NewClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	timer := clock.NewTimer(time.Minute)
+	ticker := clock.NewTicker(time.Minute)
+	assert.Equal(t, 2, clock.Waiters())
+
+	clock.Advance(time.Minute - time.Second)
+	assert.Empty(t, timer.C())
+	clock.Advance(time.Second)
+	assert.Len(t, timer.C(), 1)
+	assert.Len(t, ticker.C(), 1)
+	assert.Equal(t, 1, clock.Waiters())
+
+	ticker.Stop()
+	assert.Equal(t, 0, clock.Waiters())
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	client := NewClient(1000)
+	poke := Poke(990, 1000, 1)
+	challenge := Challenge(poke, 992, DefaultCaller)
+	client.AddLog(
+		OpPokedLog(address, poke),
+		OpPokeChallengedSuccessfullyLog(address, challenge),
+		PokedLog(address, &core.PokedEvent{BlockNumber: big.NewInt(995), Caller: DefaultCaller, Val: big.NewInt(3000), Age: 3}),
+		OpPokedLog(types.MustAddressFromHex("0x3F7acDa376eF37EC371235a094113dF9Cb4EfEe3"), Poke(991, 1000, 1)),
+	)
+	provider := core.NewScribeOptimisticRPCProvider(client, nil)
+
+	pokes, err := provider.GetPokes(ctx, address, big.NewInt(0), big.NewInt(1000))
+	require.NoError(t, err)
+	require.Len(t, pokes, 1)
+	assert.Equal(t, poke.BlockNumber, pokes[0].BlockNumber)
+	assert.Equal(t, poke.Schnorr, pokes[0].Schnorr)
+	assert.Equal(t, poke.PokeData, pokes[0].PokeData)
+
+	pokes, err = provider.GetPokes(ctx, address, big.NewInt(991), big.NewInt(1000))
+	require.NoError(t, err)
+	assert.Empty(t, pokes)
+
+	challenges, err := provider.GetSuccessfulChallenges(ctx, address, big.NewInt(0), big.NewInt(1000))
+	require.NoError(t, err)
+	require.Len(t, challenges, 1)
+	assert.Equal(t, DefaultCaller, challenges[0].Challenger)
+
+	regular, err := provider.GetRegularPokes(ctx, address, big.NewInt(0), big.NewInt(1000))
+	require.NoError(t, err)
+	require.Len(t, regular, 1)
+	assert.Equal(t, big.NewInt(3000), regular[0].Val)
+
+	t.Run("sent transactions are mined", func(t *testing.T) {
+		gas := uint64(100_000)
+		hash, _, err := client.SendTransaction(ctx, &types.Transaction{Call: types.Call{To: &address, GasLimit: &gas}})
+		require.NoError(t, err)
+		receipt, err := client.GetTransactionReceipt(ctx, *hash)
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(1001), receipt.BlockNumber)
+		assert.Equal(t, uint64(1), *receipt.Status)
+		assert.Len(t, client.Sent(), 1)
+
+		client.Revert = true
+		hash, _, err = client.SendTransaction(ctx, &types.Transaction{Call: types.Call{To: &address}})
+		require.NoError(t, err)
+		receipt, err = client.GetTransactionReceipt(ctx, *hash)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), *receipt.Status)
+	})
+}
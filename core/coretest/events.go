@@ -0,0 +1,93 @@
+package coretest
+
+import (
+	"math/big"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+
+	"github.com/chronicleprotocol/challenger/core"
+)
+
+// DefaultCaller is the feed poking in events built by Poke.
+var DefaultCaller = types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+
+// Poke builds the OpPoked event of the poke at the block. Its signature is derived from the block,
+// so pokes of different blocks are told apart by PickUnchallengedPokes.
+func Poke(block uint64, val int64, age uint32) *core.OpPokedEvent {
+	hash := TxHash(block)
+	logIndex := uint64(0)
+	return &core.OpPokedEvent{
+		BlockNumber: new(big.Int).SetUint64(block),
+		Caller:      DefaultCaller,
+		OpFeed:      DefaultCaller,
+		Schnorr: core.SchnorrData{
+			Signature:   crypto.Keccak256(new(big.Int).SetUint64(block).Bytes()),
+			Commitment:  DefaultCaller,
+			SignersBlob: []byte{0x01},
+		},
+		PokeData: core.PokeData{Val: big.NewInt(val), Age: age},
+		TxHash:   &hash,
+		LogIndex: &logIndex,
+	}
+}
+
+// Challenge builds the OpPokeChallengedSuccessfully event of the poke, emitted by the challenge at the block.
+func Challenge(poke *core.OpPokedEvent, block uint64, challenger types.Address) *core.OpPokeChallengedSuccessfullyEvent {
+	hash := TxHash(block)
+	logIndex := uint64(1)
+	schnorr := poke.Schnorr
+	return &core.OpPokeChallengedSuccessfullyEvent{
+		BlockNumber: new(big.Int).SetUint64(block),
+		Challenger:  challenger,
+		Schnorr:     &schnorr,
+		TxHash:      &hash,
+		LogIndex:    &logIndex,
+	}
+}
+
+// TxHash returns the hash of the transaction emitting events built for the block.
+func TxHash(block uint64) types.Hash {
+	return crypto.Keccak256([]byte("tx"), new(big.Int).SetUint64(block).Bytes())
+}
+
+// OpPokedLog encodes the poke as the OpPoked log of the contract at address.
+func OpPokedLog(address types.Address, poke *core.OpPokedEvent) types.Log {
+	event := core.ScribeOptimisticContractABI.Events["OpPoked"]
+	data := abi.MustEncodeValues(event.Inputs().DataTuple(), poke.Schnorr, poke.PokeData)
+	return newLog(address, poke.BlockNumber, poke.TxHash, poke.LogIndex, data,
+		event.Topic0(), addressTopic(poke.Caller), addressTopic(poke.OpFeed))
+}
+
+// OpPokeChallengedSuccessfullyLog encodes the challenge as the OpPokeChallengedSuccessfully log
+// of the contract at address.
+func OpPokeChallengedSuccessfullyLog(address types.Address, challenge *core.OpPokeChallengedSuccessfullyEvent) types.Log {
+	event := core.ScribeOptimisticContractABI.Events["OpPokeChallengedSuccessfully"]
+	data := abi.MustEncodeValues(event.Inputs().DataTuple(), []byte{})
+	return newLog(address, challenge.BlockNumber, challenge.TxHash, challenge.LogIndex, data,
+		event.Topic0(), addressTopic(challenge.Challenger))
+}
+
+// PokedLog encodes the regular poke as the Poked log of the contract at address.
+func PokedLog(address types.Address, poke *core.PokedEvent) types.Log {
+	event := core.ScribeOptimisticContractABI.Events["Poked"]
+	data := abi.MustEncodeValues(event.Inputs().DataTuple(), poke.Val, poke.Age)
+	return newLog(address, poke.BlockNumber, poke.TxHash, poke.LogIndex, data,
+		event.Topic0(), addressTopic(poke.Caller))
+}
+
+func newLog(address types.Address, block *big.Int, txHash *types.Hash, logIndex *uint64, data []byte, topics ...types.Hash) types.Log {
+	return types.Log{
+		Address:         address,
+		Topics:          topics,
+		Data:            data,
+		BlockNumber:     block,
+		TransactionHash: txHash,
+		LogIndex:        logIndex,
+	}
+}
+
+func addressTopic(address types.Address) types.Hash {
+	return types.MustHashFromBytes(address.Bytes(), types.PadLeft)
+}
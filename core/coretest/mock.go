@@ -0,0 +1,176 @@
+package coretest
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/chronicleprotocol/challenger/core"
+)
+
+// MockProvider is the core.IScribeOptimisticProvider mocked with testify, for tests expecting exact calls.
+// Unlike Provider it serves no chain, every called method needs an expectation set with On.
+type MockProvider struct {
+	mock.Mock
+	// Outcomes is returned by ChallengeOutcomes, nil by default.
+	Outcomes chan core.TxOutcome
+}
+
+// BlockByNumber implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) BlockByNumber(ctx context.Context, blockNumber *big.Int) (*types.Block, error) {
+	args := s.Called(ctx, blockNumber)
+	block := args.Get(0)
+	if block == nil {
+		return nil, args.Error(1)
+	}
+	return block.(*types.Block), args.Error(1)
+}
+
+// BlockNumber implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) BlockNumber(ctx context.Context) (*big.Int, error) {
+	args := s.Called(ctx)
+	return args.Get(0).(*big.Int), args.Error(1)
+}
+
+// GetChallengePeriod implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) GetChallengePeriod(ctx context.Context, address types.Address) (uint16, error) {
+	args := s.Called(ctx, address)
+	return uint16(args.Int(0)), args.Error(1)
+}
+
+// GetChallengeReward implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) GetChallengeReward(ctx context.Context, address types.Address) (*big.Int, error) {
+	args := s.Called(ctx, address)
+	return args.Get(0).(*big.Int), args.Error(1)
+}
+
+// GetMaxChallengeReward implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) GetMaxChallengeReward(ctx context.Context, address types.Address) (*big.Int, error) {
+	args := s.Called(ctx, address)
+	return args.Get(0).(*big.Int), args.Error(1)
+}
+
+// GetPokes implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) GetPokes(ctx context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*core.OpPokedEvent, error) {
+	args := s.Called(ctx, address, fromBlock, toBlock)
+	return args.Get(0).([]*core.OpPokedEvent), args.Error(1)
+}
+
+// GetSuccessfulChallenges implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) GetSuccessfulChallenges(ctx context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*core.OpPokeChallengedSuccessfullyEvent, error) {
+	args := s.Called(ctx, address, fromBlock, toBlock)
+	return args.Get(0).([]*core.OpPokeChallengedSuccessfullyEvent), args.Error(1)
+}
+
+// GetRegularPokes implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) GetRegularPokes(ctx context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*core.PokedEvent, error) {
+	args := s.Called(ctx, address, fromBlock, toBlock)
+	return args.Get(0).([]*core.PokedEvent), args.Error(1)
+}
+
+// GetContractStateEvents implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) GetContractStateEvents(ctx context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*core.ContractStateEvent, error) {
+	args := s.Called(ctx, address, fromBlock, toBlock)
+	return args.Get(0).([]*core.ContractStateEvent), args.Error(1)
+}
+
+// IsPokeSignatureValid implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) IsPokeSignatureValid(ctx context.Context, address types.Address, poke *core.OpPokedEvent) (bool, error) {
+	args := s.Called(ctx, address, poke)
+	return args.Bool(0), args.Error(1)
+}
+
+// ChallengePoke implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) ChallengePoke(ctx context.Context, address types.Address, poke *core.OpPokedEvent) (*types.Hash, *types.Transaction, error) {
+	args := s.Called(ctx, address, poke)
+	return args.Get(0).(*types.Hash), args.Get(1).(*types.Transaction), args.Error(2)
+}
+
+// ChallengeOutcomes implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) ChallengeOutcomes() <-chan core.TxOutcome {
+	return s.Outcomes
+}
+
+// GetFrom implements core.IScribeOptimisticProvider interface.
+func (s *MockProvider) GetFrom(ctx context.Context) types.Address {
+	args := s.Called(ctx)
+	return args.Get(0).(types.Address)
+}
+
+// MockClient is the core.RPCClient mocked with testify, for tests expecting exact calls.
+// Unlike Client it serves no chain, every called method needs an expectation set with On.
+type MockClient struct {
+	mock.Mock
+}
+
+// Accounts implements core.RPCClient interface.
+func (m *MockClient) Accounts(ctx context.Context) ([]types.Address, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]types.Address), args.Error(1)
+}
+
+// BlockNumber implements core.RPCClient interface.
+func (m *MockClient) BlockNumber(ctx context.Context) (*big.Int, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*big.Int), args.Error(1)
+}
+
+// BlockByNumber implements core.RPCClient interface.
+func (m *MockClient) BlockByNumber(ctx context.Context, number types.BlockNumber, full bool) (*types.Block, error) {
+	args := m.Called(ctx, number, full)
+	return args.Get(0).(*types.Block), args.Error(1)
+}
+
+// SendTransaction implements core.RPCClient interface.
+func (m *MockClient) SendTransaction(ctx context.Context, tx *types.Transaction) (*types.Hash, *types.Transaction, error) {
+	args := m.Called(ctx, tx)
+	return args.Get(0).(*types.Hash), args.Get(1).(*types.Transaction), args.Error(2)
+}
+
+// Call implements core.RPCClient interface.
+func (m *MockClient) Call(ctx context.Context, call *types.Call, block types.BlockNumber) ([]byte, *types.Call, error) {
+	args := m.Called(ctx, call, block)
+	c := args.Get(1)
+	if c == nil {
+		return args.Get(0).([]byte), nil, args.Error(2)
+	}
+	return args.Get(0).([]byte), c.(*types.Call), args.Error(2)
+}
+
+// GetLogs implements core.RPCClient interface.
+func (m *MockClient) GetLogs(ctx context.Context, query *types.FilterLogsQuery) ([]types.Log, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]types.Log), args.Error(1)
+}
+
+// GetTransactionReceipt implements core.RPCClient interface.
+func (m *MockClient) GetTransactionReceipt(ctx context.Context, hash types.Hash) (*types.TransactionReceipt, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(*types.TransactionReceipt), args.Error(1)
+}
+
+// GetBalance implements core.RPCClient interface.
+func (m *MockClient) GetBalance(ctx context.Context, address types.Address, block types.BlockNumber) (*big.Int, error) {
+	args := m.Called(ctx, address, block)
+	return args.Get(0).(*big.Int), args.Error(1)
+}
+
+// GetTransactionByHash implements core.RPCClient interface.
+func (m *MockClient) GetTransactionByHash(ctx context.Context, hash types.Hash) (*types.OnChainTransaction, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(*types.OnChainTransaction), args.Error(1)
+}
+
+// ChainID implements core.RPCClient interface.
+func (m *MockClient) ChainID(ctx context.Context) (uint64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+// SubscribeLogs implements core.RPCClient interface.
+func (m *MockClient) SubscribeLogs(ctx context.Context, query *types.FilterLogsQuery) (<-chan types.Log, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(<-chan types.Log), args.Error(1)
+}
@@ -0,0 +1,297 @@
+// Package coretest provides fakes of core interfaces and builders of ScribeOptimistic events,
+// for tests of programs embedding the challenger.
+package coretest
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+
+	"github.com/chronicleprotocol/challenger/core"
+)
+
+// DefaultChallengePeriod is the challenge period of contracts served by Provider, in seconds.
+const DefaultChallengePeriod = 1200
+
+// ChallengeOutcomesSize is the number of challenge outcomes Provider buffers,
+// challenging more pokes requires the outcomes to be consumed, e.g. by the challenger loop.
+const ChallengeOutcomesSize = 64
+
+// Provider is the core.IScribeOptimisticProvider serving an in-memory chain. Pokes are added by AddPoke,
+// challenges sent by ChallengePoke are recorded and confirmed right away, unless SendErr or OutcomeErr is set.
+// Blocks are BlockTime apart, the head block is at the time of Clock.
+type Provider struct {
+	// Clock gives the time of the head block, core.SystemClock by default.
+	Clock core.Clock
+	// BlockTime is the time between blocks, core.DefaultBlockTime by default.
+	BlockTime time.Duration
+	// ChallengePeriod is the challenge period of all contracts, DefaultChallengePeriod by default.
+	ChallengePeriod uint16
+	// Reward is the challenge reward of all contracts.
+	Reward *big.Int
+	// From is the address challenges are sent from.
+	From types.Address
+	// SendErr is returned by ChallengePoke, the challenge is not sent.
+	SendErr error
+	// OutcomeErr is reported as the outcome of sent challenges, which are confirmed if it's nil.
+	OutcomeErr error
+
+	mu         sync.Mutex
+	head       uint64
+	pokes      map[types.Address][]fakePoke
+	challenges map[types.Address][]*core.OpPokeChallengedSuccessfullyEvent
+	regular    map[types.Address][]*core.PokedEvent
+	challenged []Challenged
+	outcomes   chan core.TxOutcome
+}
+
+// Challenged is the challenge sent by Provider.ChallengePoke.
+type Challenged struct {
+	Address types.Address
+	Poke    *core.OpPokedEvent
+	Hash    types.Hash
+}
+
+type fakePoke struct {
+	poke  *core.OpPokedEvent
+	valid bool
+}
+
+// NewProvider creates a new instance of Provider with the head at the block.
+func NewProvider(head uint64) *Provider {
+	return &Provider{
+		Clock:           core.SystemClock,
+		BlockTime:       core.DefaultBlockTime,
+		ChallengePeriod: DefaultChallengePeriod,
+		Reward:          big.NewInt(0),
+		head:            head,
+		pokes:           make(map[types.Address][]fakePoke),
+		challenges:      make(map[types.Address][]*core.OpPokeChallengedSuccessfullyEvent),
+		regular:         make(map[types.Address][]*core.PokedEvent),
+		outcomes:        make(chan core.TxOutcome, ChallengeOutcomesSize),
+	}
+}
+
+// SetHead moves the head of the chain to the block.
+func (p *Provider) SetHead(head uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.head = head
+}
+
+// Reorg reorganizes the chain to the head at the block, events above it are dropped. Pokes included
+// again by the new chain are added by AddPoke.
+func (p *Provider) Reorg(head uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.head = head
+	for address, pokes := range p.pokes {
+		kept := pokes[:0]
+		for _, fp := range pokes {
+			if fp.poke.BlockNumber.Uint64() <= head {
+				kept = append(kept, fp)
+			}
+		}
+		p.pokes[address] = kept
+	}
+	for address, challenges := range p.challenges {
+		kept := challenges[:0]
+		for _, challenge := range challenges {
+			if challenge.BlockNumber.Uint64() <= head {
+				kept = append(kept, challenge)
+			}
+		}
+		p.challenges[address] = kept
+	}
+	for address, pokes := range p.regular {
+		kept := pokes[:0]
+		for _, poke := range pokes {
+			if poke.BlockNumber.Uint64() <= head {
+				kept = append(kept, poke)
+			}
+		}
+		p.regular[address] = kept
+	}
+}
+
+// AddPoke adds the OpPoked event to the contract at address, valid tells if its signature is valid.
+func (p *Provider) AddPoke(address types.Address, poke *core.OpPokedEvent, valid bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pokes[address] = append(p.pokes[address], fakePoke{poke: poke, valid: valid})
+}
+
+// AddChallenge adds the OpPokeChallengedSuccessfully event to the contract at address.
+func (p *Provider) AddChallenge(address types.Address, challenge *core.OpPokeChallengedSuccessfullyEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.challenges[address] = append(p.challenges[address], challenge)
+}
+
+// AddRegularPoke adds the Poked event to the contract at address.
+func (p *Provider) AddRegularPoke(address types.Address, poke *core.PokedEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.regular[address] = append(p.regular[address], poke)
+}
+
+// Challenged returns challenges sent by ChallengePoke so far.
+func (p *Provider) Challenged() []Challenged {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Challenged(nil), p.challenged...)
+}
+
+// BlockByNumber implements core.IScribeOptimisticProvider interface.
+func (p *Provider) BlockByNumber(_ context.Context, blockNumber *big.Int) (*types.Block, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if blockNumber.Uint64() > p.head {
+		return nil, fmt.Errorf("block %v not found", blockNumber)
+	}
+	return &types.Block{Number: new(big.Int).Set(blockNumber), Timestamp: p.blockTime(blockNumber.Uint64())}, nil
+}
+
+// BlockNumber implements core.IScribeOptimisticProvider interface.
+func (p *Provider) BlockNumber(context.Context) (*big.Int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return new(big.Int).SetUint64(p.head), nil
+}
+
+// GetChallengePeriod implements core.IScribeOptimisticProvider interface.
+func (p *Provider) GetChallengePeriod(context.Context, types.Address) (uint16, error) {
+	return p.ChallengePeriod, nil
+}
+
+// GetChallengeReward implements core.IScribeOptimisticProvider interface.
+func (p *Provider) GetChallengeReward(context.Context, types.Address) (*big.Int, error) {
+	return new(big.Int).Set(p.Reward), nil
+}
+
+// GetMaxChallengeReward implements core.IScribeOptimisticProvider interface.
+func (p *Provider) GetMaxChallengeReward(context.Context, types.Address) (*big.Int, error) {
+	return new(big.Int).Set(p.Reward), nil
+}
+
+// GetPokes implements core.IScribeOptimisticProvider interface.
+func (p *Provider) GetPokes(_ context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*core.OpPokedEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := checkRange(fromBlock, toBlock); err != nil {
+		return nil, err
+	}
+	var res []*core.OpPokedEvent
+	for _, fp := range p.pokes[address] {
+		if inRange(fp.poke.BlockNumber, fromBlock, toBlock) {
+			res = append(res, fp.poke)
+		}
+	}
+	return res, nil
+}
+
+// GetSuccessfulChallenges implements core.IScribeOptimisticProvider interface.
+func (p *Provider) GetSuccessfulChallenges(_ context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*core.OpPokeChallengedSuccessfullyEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := checkRange(fromBlock, toBlock); err != nil {
+		return nil, err
+	}
+	var res []*core.OpPokeChallengedSuccessfullyEvent
+	for _, challenge := range p.challenges[address] {
+		if inRange(challenge.BlockNumber, fromBlock, toBlock) {
+			res = append(res, challenge)
+		}
+	}
+	return res, nil
+}
+
+// GetRegularPokes implements core.IScribeOptimisticProvider interface.
+func (p *Provider) GetRegularPokes(_ context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*core.PokedEvent, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err := checkRange(fromBlock, toBlock); err != nil {
+		return nil, err
+	}
+	var res []*core.PokedEvent
+	for _, poke := range p.regular[address] {
+		if inRange(poke.BlockNumber, fromBlock, toBlock) {
+			res = append(res, poke)
+		}
+	}
+	return res, nil
+}
+
+// GetContractStateEvents implements core.IScribeOptimisticProvider interface, there are none.
+func (p *Provider) GetContractStateEvents(context.Context, types.Address, *big.Int, *big.Int) ([]*core.ContractStateEvent, error) {
+	return nil, nil
+}
+
+// IsPokeSignatureValid implements core.IScribeOptimisticProvider interface, pokes unknown to the provider
+// are invalid.
+func (p *Provider) IsPokeSignatureValid(_ context.Context, address types.Address, poke *core.OpPokedEvent) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, fp := range p.pokes[address] {
+		if fp.poke.BlockNumber.Cmp(poke.BlockNumber) == 0 && fp.poke.Schnorr.Signature == poke.Schnorr.Signature {
+			return fp.valid, nil
+		}
+	}
+	return false, nil
+}
+
+// ChallengePoke implements core.IScribeOptimisticProvider interface. Confirmed challenges are mined
+// in the next block, their OpPokeChallengedSuccessfully events are added to the contract.
+func (p *Provider) ChallengePoke(_ context.Context, address types.Address, poke *core.OpPokedEvent) (*types.Hash, *types.Transaction, error) {
+	if p.SendErr != nil {
+		return nil, nil, p.SendErr
+	}
+	p.mu.Lock()
+	p.head++
+	hash := TxHash(p.head)
+	p.challenged = append(p.challenged, Challenged{Address: address, Poke: poke, Hash: hash})
+	receipt := &types.TransactionReceipt{
+		TransactionHash: hash,
+		BlockNumber:     new(big.Int).SetUint64(p.head),
+		Status:          new(uint64),
+	}
+	if p.OutcomeErr == nil {
+		*receipt.Status = 1
+		p.challenges[address] = append(p.challenges[address], Challenge(poke, p.head, p.From))
+	}
+	p.mu.Unlock()
+
+	p.outcomes <- core.TxOutcome{Address: address, Poke: poke, Hash: &hash, Receipt: receipt, Err: p.OutcomeErr}
+	return &hash, nil, nil
+}
+
+// ChallengeOutcomes implements core.IScribeOptimisticProvider interface.
+func (p *Provider) ChallengeOutcomes() <-chan core.TxOutcome {
+	return p.outcomes
+}
+
+// GetFrom implements core.IScribeOptimisticProvider interface.
+func (p *Provider) GetFrom(context.Context) types.Address {
+	return p.From
+}
+
+// blockTime returns the timestamp of the block, the head block is at the current time.
+func (p *Provider) blockTime(block uint64) time.Time {
+	return p.Clock.Now().Add(-time.Duration(p.head-block) * p.BlockTime)
+}
+
+// checkRange fails for ranges starting after their end, as nodes do.
+func checkRange(fromBlock, toBlock *big.Int) error {
+	if fromBlock != nil && toBlock != nil && fromBlock.Cmp(toBlock) > 0 {
+		return fmt.Errorf("invalid block range params: from block %v is after to block %v", fromBlock, toBlock)
+	}
+	return nil
+}
+
+func inRange(block, fromBlock, toBlock *big.Int) bool {
+	return (fromBlock == nil || block.Cmp(fromBlock) >= 0) && (toBlock == nil || block.Cmp(toBlock) <= 0)
+}
@@ -0,0 +1,74 @@
+package coretest
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chronicleprotocol/challenger/core"
+)
+
+func TestReorg(t *testing.T) {
+	t.Run("chain shrinking below the processed block is scanned again", func(t *testing.T) {
+		p := NewProvider(1000)
+		p.AddPoke(address, Poke(990, 1000, 1), true)
+		c := core.NewChallenger(context.Background(), address, p, 0, nil)
+		report, err := c.RunOnce()
+		require.NoError(t, err)
+		assert.Equal(t, 0, report.InvalidPokes)
+
+		// Blocks above 980 are replaced, the new chain has an invalid poke in a block processed before.
+		p.Reorg(980)
+		invalid := Poke(975, 2000, 2)
+		p.AddPoke(address, invalid, false)
+		report, err = c.RunOnce()
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.InvalidPokes)
+		require.Len(t, p.Challenged(), 1)
+		assert.Equal(t, invalid, p.Challenged()[0].Poke)
+	})
+
+	t.Run("poke moved to another block is challenged", func(t *testing.T) {
+		p := NewProvider(1000)
+		p.SendErr = fmt.Errorf("rpc down")
+		p.AddPoke(address, Poke(998, 2000, 2), false)
+		c := core.NewChallenger(context.Background(), address, p, 0, nil)
+		report, err := c.RunOnce()
+		require.NoError(t, err)
+		assert.Equal(t, 1, report.FailedChallenges)
+
+		p.Reorg(996)
+		p.SendErr = nil
+		moved := Poke(996, 2000, 2)
+		p.AddPoke(address, moved, false)
+		_, err = c.RunOnce()
+		require.NoError(t, err)
+		require.Len(t, p.Challenged(), 1)
+		assert.Equal(t, big.NewInt(996), p.Challenged()[0].Poke.BlockNumber)
+	})
+
+	t.Run("endpoint behind doesn't challenge twice", func(t *testing.T) {
+		p := NewProvider(1000)
+		p.AddPoke(address, Poke(995, 2000, 2), false)
+		c := core.NewChallenger(context.Background(), address, p, 0, nil)
+		_, err := c.RunOnce()
+		require.NoError(t, err)
+		require.Len(t, p.Challenged(), 1)
+
+		// The head is behind the challenge, which is still in flight.
+		p.SetHead(997)
+		_, err = c.RunOnce()
+		require.NoError(t, err)
+		assert.Len(t, p.Challenged(), 1)
+	})
+
+	t.Run("inverted ranges are rejected as by nodes", func(t *testing.T) {
+		p := NewProvider(1000)
+		_, err := p.GetPokes(context.Background(), address, big.NewInt(1000), big.NewInt(990))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/defiweb/go-eth/types"
+)
+
+// DefaultDashboardTitle is the title of the dashboard generated by GrafanaDashboard.
+const DefaultDashboardTitle = "Challenger"
+
+const (
+	dashboardPanelWidth  = 12
+	dashboardPanelHeight = 8
+	dashboardGridWidth   = 24
+)
+
+// GrafanaDashboard generates Grafana dashboard JSON with a panel of each metric of DescribeMetrics, so panels
+// always match names and labels the binary exports. Counters are shown as increases, gauges as they are.
+// Metrics of monitored contracts are filtered by the address variable, offering the addresses, or all addresses
+// seen by Prometheus if none are given. All metrics are filtered by the challenger_instance label of
+// --instance-label. The Prometheus data source is selected by the datasource variable.
+func GrafanaDashboard(title string, addresses []types.Address) ([]byte, error) {
+	metrics, err := DescribeMetrics()
+	if err != nil {
+		return nil, err
+	}
+
+	var contract, process []MetricDescription
+	for _, m := range metrics {
+		switch {
+		case m.Name == prometheusNamespace+"_build_info":
+			// Always 1, versions are read from labels rather than charted.
+			continue
+		case m.HasLabel("address"):
+			contract = append(contract, m)
+		default:
+			process = append(process, m)
+		}
+	}
+	var panels []map[string]any
+	y := 0
+	for _, row := range []struct {
+		title   string
+		metrics []MetricDescription
+	}{
+		{"Contracts", contract},
+		{"Challenger", process},
+	} {
+		panels = append(panels, map[string]any{
+			"type":      "row",
+			"id":        len(panels) + 1,
+			"title":     row.title,
+			"collapsed": false,
+			"gridPos":   gridPos(0, y, dashboardGridWidth, 1),
+			"panels":    []any{},
+		})
+		y++
+		for i, m := range row.metrics {
+			x := (i % 2) * dashboardPanelWidth
+			panels = append(panels, metricPanel(len(panels)+1, m, gridPos(x, y, dashboardPanelWidth, dashboardPanelHeight)))
+			if i%2 == 1 || i == len(row.metrics)-1 {
+				y += dashboardPanelHeight
+			}
+		}
+	}
+
+	dashboard := map[string]any{
+		"title":         title,
+		"uid":           "challenger",
+		"tags":          []string{"challenger"},
+		"schemaVersion": 39,
+		"editable":      true,
+		"refresh":       "1m",
+		"time":          map[string]any{"from": "now-6h", "to": "now"},
+		"templating":    map[string]any{"list": dashboardVariables(addresses)},
+		"panels":        panels,
+	}
+	return json.MarshalIndent(dashboard, "", "  ")
+}
+
+func dashboardVariables(addresses []types.Address) []map[string]any {
+	address := map[string]any{
+		"name":       "address",
+		"label":      "Address",
+		"multi":      true,
+		"includeAll": true,
+		"current":    map[string]any{"text": "All", "value": "$__all"},
+	}
+	if len(addresses) > 0 {
+		values := make([]string, len(addresses))
+		options := make([]map[string]any, len(addresses))
+		for i, a := range addresses {
+			values[i] = a.String()
+			options[i] = map[string]any{"text": a.String(), "value": a.String(), "selected": false}
+		}
+		address["type"] = "custom"
+		address["query"] = strings.Join(values, ",")
+		address["options"] = options
+	} else {
+		address["type"] = "query"
+		address["datasource"] = dashboardDatasource
+		address["query"] = fmt.Sprintf("label_values(%s_last_scanned_block, address)", prometheusNamespace)
+		address["refresh"] = 2
+	}
+	return []map[string]any{
+		{
+			"name":  "datasource",
+			"label": "Data source",
+			"type":  "datasource",
+			"query": "prometheus",
+		},
+		{
+			"name":       "instance",
+			"label":      "Instance",
+			"type":       "query",
+			"datasource": dashboardDatasource,
+			"query":      fmt.Sprintf("label_values(%s_build_info, %s)", prometheusNamespace, InstanceLabelName),
+			"refresh":    2,
+			"multi":      true,
+			"includeAll": true,
+			// Matches deployments without the instance label too.
+			"allValue": ".*",
+			"current":  map[string]any{"text": "All", "value": "$__all"},
+		},
+		address,
+	}
+}
+
+var dashboardDatasource = map[string]any{"type": "prometheus", "uid": "${datasource}"}
+
+// metricPanel returns the time series panel of the metric.
+func metricPanel(id int, m MetricDescription, pos map[string]any) map[string]any {
+	selector := fmt.Sprintf(`%s=~"$instance"`, InstanceLabelName)
+	if m.HasLabel("address") {
+		selector += `, address=~"$address"`
+	}
+	expr := fmt.Sprintf("%s{%s}", m.Name, selector)
+	if m.Type == MetricTypeCounter {
+		by := ""
+		if len(m.Labels) > 0 {
+			by = fmt.Sprintf(" by (%s)", strings.Join(m.Labels, ", "))
+		}
+		expr = fmt.Sprintf("sum%s (increase(%s[$__rate_interval]))", by, expr)
+	}
+	legend := make([]string, len(m.Labels))
+	for i, l := range m.Labels {
+		legend[i] = "{{" + l + "}}"
+	}
+	title := strings.TrimPrefix(m.Name, prometheusNamespace+"_")
+	if len(legend) == 0 {
+		legend = []string{title}
+	}
+	return map[string]any{
+		"type":        "timeseries",
+		"id":          id,
+		"title":       title,
+		"description": m.Help,
+		"datasource":  dashboardDatasource,
+		"gridPos":     pos,
+		"targets": []map[string]any{{
+			"refId":        "A",
+			"datasource":   dashboardDatasource,
+			"expr":         expr,
+			"legendFormat": strings.Join(legend, " "),
+		}},
+	}
+}
+
+func gridPos(x, y, w, h int) map[string]any {
+	return map[string]any{"x": x, "y": y, "w": w, "h": h}
+}
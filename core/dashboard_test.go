@@ -0,0 +1,79 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDescribeMetrics(t *testing.T) {
+	metrics, err := DescribeMetrics()
+	require.NoError(t, err)
+	require.NotEmpty(t, metrics)
+
+	var challenges *MetricDescription
+	for i, m := range metrics {
+		if m.Name == "challenger_challenges_total" {
+			challenges = &metrics[i]
+		}
+	}
+	require.NotNil(t, challenges)
+	assert.Equal(t, MetricTypeCounter, challenges.Type)
+	assert.Equal(t, []string{"address", "from", "result"}, challenges.Labels)
+	assert.NotEmpty(t, challenges.Help)
+	assert.True(t, challenges.HasLabel("address"))
+}
+
+func TestGrafanaDashboard(t *testing.T) {
+	address := types.MustAddressFromHex("0x891E368fE81cBa2aC6F6cc4b98e684c106e2EF4f")
+	b, err := GrafanaDashboard("Test", []types.Address{address})
+	require.NoError(t, err)
+
+	var dashboard struct {
+		Title      string `json:"title"`
+		Templating struct {
+			List []struct {
+				Name  string `json:"name"`
+				Type  string `json:"type"`
+				Query string `json:"query"`
+			} `json:"list"`
+		} `json:"templating"`
+		Panels []struct {
+			Type    string `json:"type"`
+			Title   string `json:"title"`
+			Targets []struct {
+				Expr string `json:"expr"`
+			} `json:"targets"`
+		} `json:"panels"`
+	}
+	require.NoError(t, json.Unmarshal(b, &dashboard))
+	assert.Equal(t, "Test", dashboard.Title)
+
+	require.Len(t, dashboard.Templating.List, 3)
+	assert.Equal(t, "address", dashboard.Templating.List[2].Name)
+	assert.Equal(t, "custom", dashboard.Templating.List[2].Type)
+	assert.Equal(t, address.String(), dashboard.Templating.List[2].Query)
+
+	exprs := map[string]string{}
+	for _, p := range dashboard.Panels {
+		if p.Type == "row" {
+			continue
+		}
+		require.Len(t, p.Targets, 1)
+		exprs[p.Title] = p.Targets[0].Expr
+	}
+	assert.NotContains(t, exprs, "build_info")
+	assert.Equal(t,
+		`sum by (address, from, result) (increase(challenger_challenges_total{challenger_instance=~"$instance", address=~"$address"}[$__rate_interval]))`,
+		exprs["challenges_total"],
+	)
+	assert.Equal(t, `challenger_leader{challenger_instance=~"$instance"}`, exprs["leader"])
+
+	b, err = GrafanaDashboard("Test", nil)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(b, &dashboard))
+	assert.Equal(t, "query", dashboard.Templating.List[2].Type)
+}
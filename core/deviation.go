@@ -0,0 +1,220 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/types"
+)
+
+// pokeValueDecimals is the number of decimals of values poked to Scribe contracts.
+const pokeValueDecimals = 18
+
+// priceSourceTimeout is the time limit for fetching price from HTTP price source.
+const priceSourceTimeout = 10 * time.Second
+
+var (
+	aggregatorLatestRoundData = abi.MustParseMethod(
+		"latestRoundData() returns (uint80 roundId, int256 answer, uint256 startedAt, uint256 updatedAt, uint80 answeredInRound)",
+	)
+	aggregatorDecimals = abi.MustParseMethod("decimals() returns (uint8)")
+)
+
+// PriceSource provides the reference price poked values are compared against.
+type PriceSource interface {
+	Price(ctx context.Context) (*big.Float, error)
+}
+
+// AggregatorPriceSource reads the price from a Chainlink compatible `latestRoundData` function.
+// Both Chainlink aggregators and Chronicle oracles implement it, Chronicle requires the challenger to be tolled.
+type AggregatorPriceSource struct {
+	client   RPCClient
+	address  types.Address
+	decimals *uint8
+	mu       sync.Mutex
+}
+
+// NewAggregatorPriceSource creates a new instance of AggregatorPriceSource.
+func NewAggregatorPriceSource(client RPCClient, address types.Address) *AggregatorPriceSource {
+	return &AggregatorPriceSource{client: client, address: address}
+}
+
+// Price returns the latest answer of the aggregator.
+func (a *AggregatorPriceSource) Price(ctx context.Context) (*big.Float, error) {
+	decimals, err := a.getDecimals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, _, err := a.client.Call(ctx, &types.Call{
+		To:    &a.address,
+		Input: aggregatorLatestRoundData.FourBytes().Bytes(),
+	}, types.LatestBlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call latestRoundData of %v with error: %w: %w", a.address, ErrRPCUnavailable, err)
+	}
+	var roundID, answer, startedAt, updatedAt, answeredInRound *big.Int
+	if err := aggregatorLatestRoundData.DecodeValues(b, &roundID, &answer, &startedAt, &updatedAt, &answeredInRound); err != nil {
+		return nil, fmt.Errorf("failed to decode latestRoundData result with error: %w: %w", ErrDecode, err)
+	}
+	return scaleDown(answer, decimals), nil
+}
+
+func (a *AggregatorPriceSource) getDecimals(ctx context.Context) (uint8, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.decimals != nil {
+		return *a.decimals, nil
+	}
+	b, _, err := a.client.Call(ctx, &types.Call{
+		To:    &a.address,
+		Input: aggregatorDecimals.FourBytes().Bytes(),
+	}, types.LatestBlockNumber)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call decimals of %v with error: %w: %w", a.address, ErrRPCUnavailable, err)
+	}
+	var decimals uint8
+	if err := aggregatorDecimals.DecodeValues(b, &decimals); err != nil {
+		return 0, fmt.Errorf("failed to decode decimals result with error: %w: %w", ErrDecode, err)
+	}
+	a.decimals = &decimals
+	return decimals, nil
+}
+
+// HTTPPriceSource fetches the price from a JSON API, the price is found by dot separated path of object keys.
+type HTTPPriceSource struct {
+	url        string
+	path       []string
+	httpClient *http.Client
+}
+
+// NewHTTPPriceSource creates a new instance of HTTPPriceSource, e.g. path "data.price" reads {"data":{"price":1.5}}.
+func NewHTTPPriceSource(url string, path string) *HTTPPriceSource {
+	return &HTTPPriceSource{
+		url:        url,
+		path:       strings.Split(path, "."),
+		httpClient: &http.Client{Timeout: priceSourceTimeout},
+	}
+}
+
+// Price fetches the price from the API, it can be either JSON number or string.
+func (h *HTTPPriceSource) Price(ctx context.Context) (*big.Float, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create price request: %w", err)
+	}
+	res, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price from %s: %w", h.url, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return nil, fmt.Errorf("price source responded with status %d", res.StatusCode)
+	}
+
+	dec := json.NewDecoder(res.Body)
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("failed to decode price response: %w", err)
+	}
+	for _, key := range h.path {
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("price response has no %q key", key)
+		}
+		value = obj[key]
+	}
+
+	var s string
+	switch v := value.(type) {
+	case json.Number:
+		s = v.String()
+	case string:
+		s = v
+	default:
+		return nil, fmt.Errorf("price %v is not a number", value)
+	}
+	price, ok := new(big.Float).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("price %q is not a number", s)
+	}
+	return price, nil
+}
+
+// ParsePriceSource parses the price source, either an aggregator contract address or an HTTP URL
+// followed by `#` and the path of the price in JSON response, e.g. https://api.example.com/eth#data.price.
+func ParsePriceSource(client RPCClient, source string) (PriceSource, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		url, path, ok := strings.Cut(source, "#")
+		if !ok || path == "" {
+			return nil, fmt.Errorf("price source %q has no JSON path, expected URL#path", source)
+		}
+		return NewHTTPPriceSource(url, path), nil
+	}
+	address, err := types.AddressFromHex(source)
+	if err != nil {
+		return nil, fmt.Errorf("price source %q is neither URL nor address: %w", source, err)
+	}
+	return NewAggregatorPriceSource(client, address), nil
+}
+
+// priceDeviation returns relative deviation of the value from the reference price.
+func priceDeviation(value *big.Float, reference *big.Float) (float64, error) {
+	if reference.Sign() == 0 {
+		return 0, fmt.Errorf("reference price is zero")
+	}
+	diff := new(big.Float).Sub(value, reference)
+	deviation, _ := new(big.Float).Quo(diff.Abs(diff), new(big.Float).Abs(reference)).Float64()
+	return deviation, nil
+}
+
+func scaleDown(value *big.Int, decimals uint8) *big.Float {
+	scale := new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+	return new(big.Float).Quo(new(big.Float).SetInt(value), scale)
+}
+
+// checkPriceDeviation alerts on new pokes which value deviates from the reference price beyond the threshold.
+// Signature check can't catch colluding feeds, deviation from an independent source can.
+// The reference price is fetched at the time of the check, not at the time of the poke.
+func (c *Challenger) checkPriceDeviation(ctx context.Context, pokes []*OpPokedEvent) {
+	if c.priceSource == nil || len(pokes) == 0 {
+		return
+	}
+
+	// Only the latest poke is compared, earlier ones are outdated anyway.
+	poke := pokes[len(pokes)-1]
+	if poke.PokeData.Val == nil {
+		return
+	}
+	reference, err := c.priceSource.Price(ctx)
+	if err != nil {
+		c.log().Errorf("Failed to fetch reference price with error: %v", err)
+		return
+	}
+	value := scaleDown(poke.PokeData.Val, pokeValueDecimals)
+	deviation, err := priceDeviation(value, reference)
+	if err != nil {
+		c.log().Errorf("Failed to calculate price deviation with error: %v", err)
+		return
+	}
+	PriceDeviationGauge.WithLabelValues(c.address.String()).Set(deviation)
+	if deviation <= c.deviationThreshold {
+		return
+	}
+	PriceDeviationAlertsCounter.WithLabelValues(c.address.String()).Inc()
+	c.log().
+		WithField("deviation", deviation).
+		Warnf(
+			"OpPoked event from block %v has value %s deviating from reference price %s",
+			poke.BlockNumber,
+			value.Text('f', 8),
+			reference.Text('f', 8),
+		)
+}
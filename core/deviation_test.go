@@ -0,0 +1,109 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type staticPriceSource float64
+
+func (s staticPriceSource) Price(context.Context) (*big.Float, error) {
+	return big.NewFloat(float64(s)), nil
+}
+
+func TestAggregatorPriceSource(t *testing.T) {
+	aggregator := types.MustAddressFromHex("0x5F4eC3Df9cbd43714FE2740f5E3616155c5b8419")
+	client := new(mockRpcClient)
+	decimals, err := abi.EncodeValues(aggregatorDecimals.Outputs(), uint8(8))
+	require.NoError(t, err)
+	round, err := abi.EncodeValues(aggregatorLatestRoundData.Outputs(), big.NewInt(1), big.NewInt(250_000_000_000), big.NewInt(0), big.NewInt(0), big.NewInt(1))
+	require.NoError(t, err)
+	client.On("Call", mock.Anything, mock.MatchedBy(func(call *types.Call) bool {
+		return string(call.Input) == string(aggregatorDecimals.FourBytes().Bytes())
+	}), types.LatestBlockNumber).Return(decimals, nil, nil).Once()
+	client.On("Call", mock.Anything, mock.MatchedBy(func(call *types.Call) bool {
+		return string(call.Input) == string(aggregatorLatestRoundData.FourBytes().Bytes())
+	}), types.LatestBlockNumber).Return(round, nil, nil)
+
+	source := NewAggregatorPriceSource(client, aggregator)
+	for range 2 {
+		price, err := source.Price(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, "2500", price.Text('f', 0))
+	}
+	client.AssertNumberOfCalls(t, "Call", 3)
+}
+
+func TestHTTPPriceSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/number":
+			w.Write([]byte(`{"data":{"price":2500.125}}`))
+		case "/string":
+			w.Write([]byte(`{"price":"2500.125"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	price, err := NewHTTPPriceSource(server.URL+"/number", "data.price").Price(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, "2500.125", price.Text('f', 3))
+
+	price, err = NewHTTPPriceSource(server.URL+"/string", "price").Price(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, "2500.125", price.Text('f', 3))
+
+	_, err = NewHTTPPriceSource(server.URL+"/number", "price").Price(context.TODO())
+	assert.ErrorContains(t, err, "is not a number")
+
+	_, err = NewHTTPPriceSource(server.URL+"/missing", "price").Price(context.TODO())
+	assert.ErrorContains(t, err, "status 404")
+}
+
+func TestParsePriceSource(t *testing.T) {
+	source, err := ParsePriceSource(nil, "https://api.example.com/price?pair=ETHUSD#data.price")
+	require.NoError(t, err)
+	assert.IsType(t, &HTTPPriceSource{}, source)
+
+	source, err = ParsePriceSource(nil, "0x5F4eC3Df9cbd43714FE2740f5E3616155c5b8419")
+	require.NoError(t, err)
+	assert.IsType(t, &AggregatorPriceSource{}, source)
+
+	_, err = ParsePriceSource(nil, "https://api.example.com/price")
+	assert.Error(t, err)
+	_, err = ParsePriceSource(nil, "chainlink")
+	assert.Error(t, err)
+}
+
+func TestCheckPriceDeviation(t *testing.T) {
+	address := types.MustAddressFromHex("0x6F7acDa376eF37EC371235a094113dF9Cb4EfEe6")
+	sources := map[types.Address]PriceSource{address: staticPriceSource(2500)}
+	c := NewChallenger(context.TODO(), address, new(mockScribeOptimisticProvider), 0, nil, WithPriceDeviationMonitor(sources, 0.01))
+
+	// 2520 is within 1% of 2500.
+	val, _ := new(big.Int).SetString("2520000000000000000000", 10)
+	c.checkPriceDeviation(context.TODO(), []*OpPokedEvent{{BlockNumber: big.NewInt(100), PokeData: PokeData{Val: val}}})
+	assert.InDelta(t, 0.008, testutil.ToFloat64(PriceDeviationGauge.WithLabelValues(address.String())), 1e-9)
+	assert.Equal(t, float64(0), testutil.ToFloat64(PriceDeviationAlertsCounter.WithLabelValues(address.String())))
+
+	val, _ = new(big.Int).SetString("2000000000000000000000", 10)
+	c.checkPriceDeviation(context.TODO(), []*OpPokedEvent{{BlockNumber: big.NewInt(101), PokeData: PokeData{Val: val}}})
+	assert.InDelta(t, 0.2, testutil.ToFloat64(PriceDeviationGauge.WithLabelValues(address.String())), 1e-9)
+	assert.Equal(t, float64(1), testutil.ToFloat64(PriceDeviationAlertsCounter.WithLabelValues(address.String())))
+
+	// Addresses without reference price are not monitored.
+	other := NewChallenger(context.TODO(), types.ZeroAddress, nil, 0, nil, WithPriceDeviationMonitor(sources, 0.01))
+	assert.Nil(t, other.priceSource)
+}
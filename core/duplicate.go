@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// DuplicateCheckInterval is the interval the challenger account is checked for use by another instance at.
+var DuplicateCheckInterval = 30 * time.Second
+
+// SendTracker records when this instance last sent a transaction of the challenger account.
+type SendTracker struct {
+	last atomic.Int64
+}
+
+// Sent records a transaction sent now.
+func (t *SendTracker) Sent() {
+	t.last.Store(time.Now().UnixNano())
+}
+
+// LastSent returns the time the last transaction was sent, zero time if none was.
+func (t *SendTracker) LastSent() time.Time {
+	n := t.last.Load()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// trackedClient records transactions sent through the client in the tracker.
+type trackedClient struct {
+	RPCClient
+	tracker *SendTracker
+}
+
+// TrackSends returns the client recording transactions sent through it in the tracker.
+// Only RPCClient methods are exposed, optional interfaces of the client are not.
+func TrackSends(client RPCClient, tracker *SendTracker) RPCClient {
+	return &trackedClient{RPCClient: client, tracker: tracker}
+}
+
+func (c *trackedClient) SendTransaction(ctx context.Context, tx *types.Transaction) (*types.Hash, *types.Transaction, error) {
+	hash, sent, err := c.RPCClient.SendTransaction(ctx, tx)
+	if err == nil {
+		c.tracker.Sent()
+	}
+	return hash, sent, err
+}
+
+// DuplicateDetector warns about another live instance using the same key, which would fight over nonces
+// with this one. The pending nonce of the account advancing while this instance hasn't sent a transaction for
+// longer than TxConfirmationTimeout gives the other instance away. Optionally, instances register themselves
+// in Redis under the account address and a key registered by another instance is reported too.
+type DuplicateDetector struct {
+	client  NonceFetcher
+	mu      sync.Mutex
+	from    types.Address
+	tracker *SendTracker
+
+	nonce *uint64
+
+	// Redis, if set, is the shared store instances register in under Key, ID has to be unique per instance.
+	Redis *RedisClient
+	Key   string
+	ID    string
+}
+
+// NewDuplicateDetector creates a new instance of DuplicateDetector.
+func NewDuplicateDetector(client NonceFetcher, from types.Address, tracker *SendTracker) *DuplicateDetector {
+	return &DuplicateDetector{client: client, from: from, tracker: tracker}
+}
+
+// Run checks for duplicate instances every DuplicateCheckInterval until ctx is done.
+func (d *DuplicateDetector) Run(ctx context.Context) {
+	ticker := time.NewTicker(DuplicateCheckInterval)
+	defer ticker.Stop()
+	for {
+		from := d.address()
+		duplicate, err := d.Check(ctx)
+		switch {
+		case err != nil:
+			logger.
+				WithField("from", from).
+				Errorf("Failed to check for duplicate instances with error: %v", err)
+		case from != d.address():
+			// Switched to another address meanwhile, metric of the old one must not be recreated.
+		case duplicate:
+			DuplicateInstanceGauge.WithLabelValues(from.String()).Set(1)
+		default:
+			DuplicateInstanceGauge.WithLabelValues(from.String()).Set(0)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// address returns the monitored address.
+func (d *DuplicateDetector) address() types.Address {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.from
+}
+
+// SetFrom switches detection to another address, e.g. after key rotation. The nonce of the old address is
+// forgotten and its metric removed, its Redis registration expires on its own.
+func (d *DuplicateDetector) SetFrom(from types.Address) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	DuplicateInstanceGauge.DeleteLabelValues(d.from.String())
+	d.from = from
+	d.nonce = nil
+}
+
+// Check returns true if another instance using the same key was detected since the previous check.
+func (d *DuplicateDetector) Check(ctx context.Context) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	registered, err := d.register(ctx)
+	if err != nil {
+		return false, err
+	}
+	nonce, err := d.client.GetTransactionCount(ctx, d.from, types.PendingBlockNumber)
+	if err != nil {
+		return false, fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+	previous := d.nonce
+	d.nonce = &nonce
+
+	duplicate := false
+	if registered != "" {
+		logger.
+			WithField("from", d.from).
+			WithField("instance", registered).
+			Warnf("Instance %s is also running with the same key, both would send challenges with the same nonces", registered)
+		duplicate = true
+	}
+	if previous != nil && nonce > *previous && time.Since(d.tracker.LastSent()) > TxConfirmationTimeout+DuplicateCheckInterval {
+		logger.
+			WithField("from", d.from).
+			Warnf("Nonce advanced from %d to %d without transactions sent by this instance, another instance may be using the same key", *previous, nonce)
+		duplicate = true
+	}
+	return duplicate, nil
+}
+
+// register registers this instance in Redis and returns ID of another instance registered for the account,
+// empty if there is none.
+func (d *DuplicateDetector) register(ctx context.Context) (string, error) {
+	if d.Redis == nil {
+		return "", nil
+	}
+	key := d.Key + ":" + d.from.String()
+	ttl := strconv.FormatInt((3 * DuplicateCheckInterval).Milliseconds(), 10)
+	res, err := d.Redis.Do(ctx, "EVAL", renewLeaseScript, "1", key, d.ID, ttl)
+	if err != nil {
+		return "", fmt.Errorf("failed to renew registration: %w", err)
+	}
+	if n, ok := res.(int64); ok && n == 1 {
+		return "", nil
+	}
+	res, err = d.Redis.Do(ctx, "SET", key, d.ID, "NX", "PX", ttl)
+	if err != nil {
+		return "", fmt.Errorf("failed to register instance: %w", err)
+	}
+	if res == "OK" {
+		return "", nil
+	}
+	owner, err := d.Redis.Do(ctx, "GET", key)
+	if err != nil {
+		return "", fmt.Errorf("failed to get registered instance: %w", err)
+	}
+	id, _ := owner.(string)
+	return id, nil
+}
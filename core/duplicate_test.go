@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDuplicateDetectorNonce(t *testing.T) {
+	from := types.MustAddressFromHex("0xbF7acDa376eF37EC371235a094113dF9Cb4EfEeb")
+	client := new(mockNonceClient)
+	nonce := func(n uint64) {
+		client.On("GetTransactionCount", mock.Anything, from, types.PendingBlockNumber).Return(n, nil).Once()
+	}
+	tracker := &SendTracker{}
+	d := NewDuplicateDetector(client, from, tracker)
+
+	nonce(5)
+	duplicate, err := d.Check(context.TODO())
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+
+	// Nonce advanced by a transaction of this instance.
+	tracker.Sent()
+	nonce(6)
+	duplicate, err = d.Check(context.TODO())
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+
+	// Nonce advanced long after the last transaction of this instance.
+	tracker.last.Store(time.Now().Add(-TxConfirmationTimeout - 2*DuplicateCheckInterval).UnixNano())
+	nonce(7)
+	duplicate, err = d.Check(context.TODO())
+	require.NoError(t, err)
+	assert.True(t, duplicate)
+
+	nonce(7)
+	duplicate, err = d.Check(context.TODO())
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+}
+
+func TestDuplicateDetectorRegistry(t *testing.T) {
+	from := types.MustAddressFromHex("0xbF7acDa376eF37EC371235a094113dF9Cb4EfEeb")
+	srv := newFakeRedis(t)
+	client := new(mockNonceClient)
+	client.On("GetTransactionCount", mock.Anything, from, types.PendingBlockNumber).Return(uint64(5), nil)
+	newDetector := func(id string) *DuplicateDetector {
+		c, err := NewRedisClient(srv.URL())
+		require.NoError(t, err)
+		t.Cleanup(func() { c.Close() })
+		d := NewDuplicateDetector(client, from, &SendTracker{})
+		d.Redis, d.Key, d.ID = c, "challenger-instance", id
+		return d
+	}
+
+	a := newDetector("a")
+	b := newDetector("b")
+	duplicate, err := a.Check(context.TODO())
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+
+	// Registration is renewed by the same instance.
+	duplicate, err = a.Check(context.TODO())
+	require.NoError(t, err)
+	assert.False(t, duplicate)
+
+	duplicate, err = b.Check(context.TODO())
+	require.NoError(t, err)
+	assert.True(t, duplicate)
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of errors returned by core, wrapped with %w so callers can branch on them using errors.Is.
+var (
+	// ErrRPCUnavailable wraps failed RPC requests, e.g. an unreachable endpoint or a failed contract call.
+	ErrRPCUnavailable = errors.New("rpc unavailable")
+
+	// ErrDecode wraps failures to decode events and contract call results.
+	ErrDecode = errors.New("decoding failed")
+
+	// ErrChallengeReverted is returned when the challenge was mined but reverted. It wraps ErrTxReverted.
+	ErrChallengeReverted = fmt.Errorf("challenge %w", ErrTxReverted)
+
+	// ErrChainMismatch is returned when an RPC endpoint serves another chain than the configured one.
+	ErrChainMismatch = errors.New("chain ID mismatch")
+
+	// ErrAlreadyChallenged is returned along with ErrChallengeReverted when the poke was challenged
+	// by someone else first.
+	ErrAlreadyChallenged = errors.New("poke already challenged")
+
+	// ErrKeyUnchanged is returned when rotating to the key signing already.
+	ErrKeyUnchanged = errors.New("key unchanged")
+
+	// ErrInsufficientBalance is returned when the balance of a key is too low to pay for challenges.
+	ErrInsufficientBalance = errors.New("insufficient balance")
+
+	// ErrReadOnly is returned when signing a transaction without a key configured.
+	ErrReadOnly = errors.New("read-only mode, no key configured")
+
+	// ErrBatchContractNotOwned is returned when the contract challenges are batched through isn't owned by the
+	// challenger account, so challenge rewards paid to it would be lost.
+	ErrBatchContractNotOwned = errors.New("batch contract not owned by challenger account")
+
+	// ErrLoopStuck is returned by the processing loop when its tick doesn't complete in time and the loop is restarted.
+	ErrLoopStuck = errors.New("processing loop stuck")
+)
@@ -27,6 +27,13 @@ type OpPokedEvent struct {
 	OpFeed      types.Address `abi:"opFeed"`      // address
 	Schnorr     SchnorrData   `abi:"schnorr"`     // (bytes32,address,bytes)
 	PokeData    PokeData      `abi:"pokeData"`    // (uint128,uint32)
+
+	// Location of the log the event was decoded from, nil if unknown.
+	TxHash   *types.Hash
+	LogIndex *uint64
+
+	// Raw log the event was decoded from, kept so the event can be re-decoded, nil if unknown.
+	Raw *types.Log
 }
 
 func (o *OpPokedEvent) Name() string {
@@ -37,9 +44,23 @@ func (o *OpPokedEvent) GetBlockNumber() *big.Int {
 	return o.BlockNumber
 }
 
+func (o *OpPokedEvent) GetLogIndex() *uint64 {
+	return o.LogIndex
+}
+
 type OpPokeChallengedSuccessfullyEvent struct {
 	BlockNumber *big.Int      `abi:"blockNumber"` //uint256
 	Challenger  types.Address `abi:"challenger"`  //address
+
+	// Schnorr data of the challenged poke decoded from `opChallenge` calldata, nil if unknown.
+	Schnorr *SchnorrData
+
+	// Location of the log the event was decoded from, nil if unknown.
+	TxHash   *types.Hash
+	LogIndex *uint64
+
+	// Raw log the event was decoded from, kept so the event can be re-decoded, nil if unknown.
+	Raw *types.Log
 }
 
 func (o *OpPokeChallengedSuccessfullyEvent) Name() string {
@@ -49,3 +70,61 @@ func (o *OpPokeChallengedSuccessfullyEvent) Name() string {
 func (o *OpPokeChallengedSuccessfullyEvent) GetBlockNumber() *big.Int {
 	return o.BlockNumber
 }
+
+func (o *OpPokeChallengedSuccessfullyEvent) GetLogIndex() *uint64 {
+	return o.LogIndex
+}
+
+// PokedEvent is emitted by regular (non-optimistic) `poke`, it's only used for liveness monitoring.
+type PokedEvent struct {
+	BlockNumber *big.Int      `abi:"blockNumber"` // uint256
+	Caller      types.Address `abi:"caller"`      // address
+	Val         *big.Int      `abi:"val"`         // uint128
+	Age         uint32        `abi:"age"`         // uint32
+
+	// Location of the log the event was decoded from, nil if unknown.
+	TxHash   *types.Hash
+	LogIndex *uint64
+
+	// Raw log the event was decoded from, kept so the event can be re-decoded, nil if unknown.
+	Raw *types.Log
+}
+
+func (o *PokedEvent) Name() string {
+	return "Poked"
+}
+
+func (o *PokedEvent) GetBlockNumber() *big.Int {
+	return o.BlockNumber
+}
+
+func (o *PokedEvent) GetLogIndex() *uint64 {
+	return o.LogIndex
+}
+
+// ContractStateEvent changes configuration or state of the contract, e.g. drops the optimistic poke data,
+// updates the challenge period or grants auth. It's only used for alerting.
+type ContractStateEvent struct {
+	BlockNumber *big.Int
+	Event       string
+	Values      map[string]any
+
+	// Location of the log the event was decoded from, nil if unknown.
+	TxHash   *types.Hash
+	LogIndex *uint64
+
+	// Raw log the event was decoded from, kept so the event can be re-decoded, nil if unknown.
+	Raw *types.Log
+}
+
+func (o *ContractStateEvent) Name() string {
+	return o.Event
+}
+
+func (o *ContractStateEvent) GetBlockNumber() *big.Int {
+	return o.BlockNumber
+}
+
+func (o *ContractStateEvent) GetLogIndex() *uint64 {
+	return o.LogIndex
+}
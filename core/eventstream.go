@@ -0,0 +1,167 @@
+package core
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// Kinds of events of EventStream.
+const (
+	EventPokeDetected       = "poke_detected"
+	EventChallengeSubmitted = "challenge_submitted"
+	EventChallengeConfirmed = "challenge_confirmed"
+	EventChallengeFailed    = "challenge_failed"
+)
+
+// EventStreamKeepAlive is the interval comments are sent at to idle subscribers, so proxies don't close
+// the connection.
+var EventStreamKeepAlive = 15 * time.Second
+
+// eventStreamBuffer is the number of events buffered for each subscriber, events of slower subscribers are dropped.
+const eventStreamBuffer = 64
+
+// StreamEvent is a lifecycle event of a poke and its challenge.
+type StreamEvent struct {
+	Kind       string        `json:"kind"`
+	Time       time.Time     `json:"time"`
+	Address    types.Address `json:"address"`
+	PokeBlock  uint64        `json:"pokeBlock"`
+	PokeTxHash *types.Hash   `json:"pokeTxHash,omitempty"`
+	TxHash     *types.Hash   `json:"txHash,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// EventStream is the ChallengeHook broadcasting lifecycle events to subscribers over Server-Sent Events,
+// so UIs and scripts can watch challenger activity live.
+type EventStream struct {
+	token string
+
+	mu          sync.Mutex
+	subscribers map[chan StreamEvent]struct{}
+}
+
+// NewEventStream creates a new instance of EventStream, subscribers must present the token.
+func NewEventStream(token string) *EventStream {
+	return &EventStream{token: token, subscribers: make(map[chan StreamEvent]struct{})}
+}
+
+// Subscribe returns the channel of published events and the function to unsubscribe.
+func (s *EventStream) Subscribe() (<-chan StreamEvent, func()) {
+	ch := make(chan StreamEvent, eventStreamBuffer)
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}
+}
+
+// Publish sends the event to all subscribers without blocking, it's dropped for subscribers not keeping up.
+func (s *EventStream) Publish(e StreamEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subscribers {
+		select {
+		case ch <- e:
+		default:
+			logger.Warnf("Event stream subscriber is not keeping up, dropping %s event", e.Kind)
+		}
+	}
+}
+
+func (s *EventStream) publish(kind string, address types.Address, poke *OpPokedEvent, txHash *types.Hash, err error) {
+	e := StreamEvent{Kind: kind, Time: time.Now(), Address: address, TxHash: txHash, Error: auditError(err)}
+	if poke != nil {
+		if poke.BlockNumber != nil {
+			e.PokeBlock = poke.BlockNumber.Uint64()
+		}
+		e.PokeTxHash = poke.TxHash
+	}
+	s.Publish(e)
+}
+
+func (s *EventStream) OnPokeDetected(address types.Address, poke *OpPokedEvent) {
+	s.publish(EventPokeDetected, address, poke, nil, nil)
+}
+
+func (s *EventStream) OnChallengeSubmitted(address types.Address, poke *OpPokedEvent, txHash *types.Hash) {
+	s.publish(EventChallengeSubmitted, address, poke, txHash, nil)
+}
+
+func (s *EventStream) OnChallengeConfirmed(address types.Address, poke *OpPokedEvent, receipt *types.TransactionReceipt) {
+	var txHash *types.Hash
+	if receipt != nil {
+		txHash = &receipt.TransactionHash
+	}
+	s.publish(EventChallengeConfirmed, address, poke, txHash, nil)
+}
+
+func (s *EventStream) OnChallengeFailed(address types.Address, poke *OpPokedEvent, err error) {
+	s.publish(EventChallengeFailed, address, poke, nil, err)
+}
+
+// authorized tells if the request presents the token as bearer token, or as the token query parameter,
+// as browsers can't set headers of EventSource requests.
+func (s *EventStream) authorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return s.token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// ServeHTTP streams events as Server-Sent Events, named by the kind of the event with JSON data,
+// until the client disconnects.
+func (s *EventStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	events, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(EventStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case e := <-events:
+			data, err := json.Marshal(e)
+			if err != nil {
+				logger.WithError(err).Error("failed to encode stream event")
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Kind, data); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
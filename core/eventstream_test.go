@@ -0,0 +1,69 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStream(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(500)}
+	stream := NewEventStream("secret")
+	server := httptest.NewServer(stream)
+	defer server.Close()
+
+	t.Run("unauthorized", func(t *testing.T) {
+		for _, url := range []string{server.URL, server.URL + "?token=wrong"} {
+			res, err := http.Get(url)
+			require.NoError(t, err)
+			res.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		}
+		assert.False(t, NewEventStream("").authorized(httptest.NewRequest(http.MethodGet, "/events?token=", nil)))
+	})
+
+	t.Run("events are streamed", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+		// The subscription is registered before the headers are flushed.
+		stream.OnPokeDetected(address, poke)
+		stream.OnChallengeFailed(address, poke, fmt.Errorf("rpc down"))
+
+		r := bufio.NewReader(res.Body)
+		for _, kind := range []string{EventPokeDetected, EventChallengeFailed} {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err)
+			assert.Equal(t, "event: "+kind+"\n", line)
+			line, err = r.ReadString('\n')
+			require.NoError(t, err)
+			var e StreamEvent
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e))
+			assert.Equal(t, kind, e.Kind)
+			assert.Equal(t, address, e.Address)
+			assert.Equal(t, uint64(500), e.PokeBlock)
+			_, err = r.ReadString('\n')
+			require.NoError(t, err)
+		}
+	})
+
+	t.Run("query token", func(t *testing.T) {
+		assert.True(t, stream.authorized(httptest.NewRequest(http.MethodGet, "/events?token=secret", nil)))
+	})
+}
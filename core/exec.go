@@ -0,0 +1,101 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultChallengeExecTimeout is the default time limit for the challenge command to complete.
+const DefaultChallengeExecTimeout = time.Minute
+
+// ChallengeExecutor delegates challenges to an external command instead of sending transactions, e.g. to a bespoke
+// signing or submission pipeline. Challenges are gated the same way as sent ones, only sending is replaced by running
+// the command. The command gets calldata of `opChallenge` as its last argument and the context in environment variables:
+//
+//	CHALLENGER_TARGET        address of the ScribeOptimistic contract the calldata is sent to
+//	CHALLENGER_CALLDATA      calldata of `opChallenge`, hex encoded
+//	CHALLENGER_POKE_BLOCK    block number of the poke
+//	CHALLENGER_POKE_TX_HASH  hash of the poke transaction, empty if not known
+//	CHALLENGER_DEADLINE      unix timestamp the challenge window closes at
+//	CHALLENGER_CHAIN_ID      chain ID, empty if not known
+//	CHALLENGER_INSTANCE      instance label, empty if not set
+//
+// The challenge is handed over if the command exits with zero status, its output is logged.
+type ChallengeExecutor struct {
+	program string
+	args    []string
+
+	// Timeout limits the time the command runs, it's killed afterwards.
+	Timeout time.Duration
+	// ChainID is passed to the command, if known.
+	ChainID uint64
+	// Instance is passed to the command, so it can tell which deployment found the poke.
+	Instance string
+	// Tag is appended to calldata passed to the command, see NewChallengeTag.
+	Tag []byte
+}
+
+// NewChallengeExecutor creates a new instance of ChallengeExecutor. The program is run with the given arguments
+// as they are, without a shell, so paths and arguments may contain spaces or quotes.
+func NewChallengeExecutor(program string, args []string) (*ChallengeExecutor, error) {
+	if strings.TrimSpace(program) == "" {
+		return nil, fmt.Errorf("challenge command is empty")
+	}
+	return &ChallengeExecutor{program: program, args: args, Timeout: DefaultChallengeExecTimeout}, nil
+}
+
+// Execute runs the command for the challenge payload and returns its trimmed standard output.
+func (e *ChallengeExecutor) Execute(ctx context.Context, payload ChallengePayload) (string, error) {
+	if e.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.Timeout)
+		defer cancel()
+	}
+	if payload.Instance == "" {
+		payload.Instance = e.Instance
+	}
+
+	args := append(append([]string{}, e.args...), payload.Calldata)
+	cmd := exec.CommandContext(ctx, e.program, args...) //nolint:gosec
+	cmd.Env = append(os.Environ(), e.env(payload)...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return "", fmt.Errorf("challenge command did not complete within %v", e.Timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("challenge command failed with error: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("challenge command failed with error: %w", err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// env returns environment variables describing the challenge.
+func (e *ChallengeExecutor) env(payload ChallengePayload) []string {
+	var pokeTxHash, chainID string
+	if payload.PokeTxHash != nil {
+		pokeTxHash = payload.PokeTxHash.String()
+	}
+	if e.ChainID != 0 {
+		chainID = strconv.FormatUint(e.ChainID, 10)
+	}
+	return []string{
+		"CHALLENGER_TARGET=" + payload.Target.String(),
+		"CHALLENGER_CALLDATA=" + payload.Calldata,
+		"CHALLENGER_POKE_BLOCK=" + strconv.FormatUint(payload.BlockNumber, 10),
+		"CHALLENGER_POKE_TX_HASH=" + pokeTxHash,
+		"CHALLENGER_DEADLINE=" + strconv.FormatInt(payload.Deadline.Unix(), 10),
+		"CHALLENGER_CHAIN_ID=" + chainID,
+		"CHALLENGER_INSTANCE=" + payload.Instance,
+	}
+}
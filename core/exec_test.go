@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// writeScript writes a shell script to a temporary directory and returns its path.
+func writeScript(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not supported on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "challenge.sh")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0o700)) //nolint:gosec
+	return path
+}
+
+func TestChallengeExecutor(t *testing.T) {
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	payload := ChallengePayload{
+		Target:      types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1"),
+		Calldata:    "0x8928a1f8",
+		BlockNumber: 500,
+		Deadline:    time.Unix(1700000000, 0),
+		PokeTxHash:  &txHash,
+	}
+
+	t.Run("calldata and context are passed to the command", func(t *testing.T) {
+		script := writeScript(t, `echo "$1 $2 $CHALLENGER_TARGET $CHALLENGER_CALLDATA $CHALLENGER_POKE_BLOCK $CHALLENGER_POKE_TX_HASH $CHALLENGER_DEADLINE $CHALLENGER_CHAIN_ID $CHALLENGER_INSTANCE"`)
+		e, err := NewChallengeExecutor(script, []string{"--dry run"})
+		require.NoError(t, err)
+		e.ChainID = 1
+		e.Instance = "eu-1"
+
+		output, err := e.Execute(context.TODO(), payload)
+		require.NoError(t, err)
+		assert.Equal(t, strings.Join([]string{
+			"--dry run",
+			"0x8928a1f8",
+			"0x1f7acda376ef37ec371235a094113df9cb4efee1",
+			"0x8928a1f8",
+			"500",
+			txHash.String(),
+			"1700000000",
+			"1",
+			"eu-1",
+		}, " "), output)
+	})
+
+	t.Run("failure includes stderr", func(t *testing.T) {
+		e, err := NewChallengeExecutor(writeScript(t, "echo 'signer unavailable' >&2\nexit 3"), nil)
+		require.NoError(t, err)
+		_, err = e.Execute(context.TODO(), payload)
+		assert.ErrorContains(t, err, "exit status 3: signer unavailable")
+	})
+
+	t.Run("command is killed after timeout", func(t *testing.T) {
+		e, err := NewChallengeExecutor(writeScript(t, "exec sleep 10"), nil)
+		require.NoError(t, err)
+		e.Timeout = 50 * time.Millisecond
+		_, err = e.Execute(context.TODO(), payload)
+		assert.ErrorContains(t, err, "did not complete within 50ms")
+	})
+
+	t.Run("empty command", func(t *testing.T) {
+		_, err := NewChallengeExecutor(" ", nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestExecuteChallenge(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe3")
+	out := filepath.Join(t.TempDir(), "calldata")
+	e, err := NewChallengeExecutor(writeScript(t, `echo "$1" >> `+out), nil)
+	require.NoError(t, err)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(500), Schnorr: SchnorrData{Signature: [32]byte{1}}}
+	calldata, err := EncodeChallengeCalldata(poke, nil)
+	require.NoError(t, err)
+
+	p := new(mockScribeOptimisticProvider)
+	budget := NewChallengeBudget(1, nil)
+	c := NewChallenger(context.TODO(), address, p, 100, &sync.WaitGroup{}, WithChallengeExecutor(e), WithChallengeBudget(budget))
+	c.trackUnconfirmed(poke, time.Now().Add(time.Hour))
+	c.challengePoke(poke)
+	c.submissions.Wait()
+
+	got, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("0x%x", calldata), strings.TrimSpace(string(got)))
+	p.AssertNotCalled(t, "ChallengePoke", mock.Anything, mock.Anything, mock.Anything)
+
+	// The delegated challenge takes the budget, so the next one waits for it.
+	poke2 := &OpPokedEvent{BlockNumber: big.NewInt(501), Schnorr: SchnorrData{Signature: [32]byte{2}}}
+	c.trackUnconfirmed(poke2, time.Now().Add(time.Hour))
+	c.challengePoke(poke2)
+	c.submissions.Wait()
+	got, err = os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Len(t, strings.Fields(string(got)), 1)
+	assert.Contains(t, c.retry, uint64(501))
+	assert.Equal(t, int64(1), c.failedSubmissions.Load())
+}
+
+func TestExecuteChallengeFailure(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe3")
+	e, err := NewChallengeExecutor(writeScript(t, "exit 1"), nil)
+	require.NoError(t, err)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(500), Schnorr: SchnorrData{Signature: [32]byte{1}}}
+
+	p := new(mockScribeOptimisticProvider)
+	hook := &recordingHook{}
+	c := NewChallenger(context.TODO(), address, p, 100, &sync.WaitGroup{}, WithChallengeExecutor(e), WithChallengeHook(hook))
+	c.trackUnconfirmed(poke, time.Now().Add(time.Hour))
+	c.challengePoke(poke)
+	c.submissions.Wait()
+
+	assert.Equal(t, []string{"detected 500", "failed 500: challenge command failed with error: exit status 1"}, hook.Events())
+	assert.Contains(t, c.retry, uint64(500))
+	c.inFlightMu.Lock()
+	assert.NotContains(t, c.inFlight, uint64(500))
+	c.inFlightMu.Unlock()
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"time"
+)
+
+// DefaultExpiryAlertThreshold is the default fraction of the challenge window remaining below which
+// an invalid poke without confirmed challenge is alerted on.
+const DefaultExpiryAlertThreshold = 0.25
+
+// ExpiringWindowAlert is the value of the alert field of the log entry emitted when the challenge window
+// of an invalid poke is about to close unchallenged.
+const ExpiringWindowAlert = "challenge_window_expiring"
+
+// WithExpiryAlert makes Challenger alert when less than the given fraction of the challenge window of an invalid
+// poke remains and its challenge is not confirmed yet, so the poke can be challenged manually in time.
+// 0 disables alerts.
+func WithExpiryAlert(threshold float64) ChallengerOption {
+	return func(c *Challenger) {
+		c.expiryThreshold = threshold
+	}
+}
+
+// alertExpiringWindows alerts once for each invalid poke which challenge window is about to close without
+// confirmed challenge and publishes the number of such pokes.
+func (c *Challenger) alertExpiringWindows() {
+	if c.expiryThreshold <= 0 || c.challengePeriod == nil {
+		return
+	}
+	period := time.Duration(*c.challengePeriod) * time.Second
+	now := c.clock.Now()
+
+	c.inFlightMu.Lock()
+	defer c.inFlightMu.Unlock()
+	expiring := 0
+	for blockNum, deadline := range c.unconfirmed {
+		remaining := deadline.Sub(now)
+		if remaining <= 0 || float64(remaining) >= float64(period)*c.expiryThreshold {
+			continue
+		}
+		expiring++
+		if _, ok := c.expiryAlerted[blockNum]; ok {
+			continue
+		}
+		c.expiryAlerted[blockNum] = struct{}{}
+		ChallengeWindowExpiringCounter.WithLabelValues(c.address.String()).Inc()
+		c.log().
+			WithField("alert", ExpiringWindowAlert).
+			WithField("pokeBlock", blockNum).
+			WithField("deadline", deadline).
+			WithField("remaining", remaining.Round(time.Second).String()).
+			Errorf("Challenge window of invalid OpPoked event from block %d closes in %v without confirmed challenge, manual intervention needed", blockNum, remaining.Round(time.Second))
+	}
+	// Forget pokes which challenge was confirmed or which window is closed.
+	for blockNum := range c.expiryAlerted {
+		if deadline, ok := c.unconfirmed[blockNum]; !ok || !deadline.After(now) {
+			delete(c.expiryAlerted, blockNum)
+		}
+	}
+	ChallengeWindowExpiringGauge.WithLabelValues(c.address.String()).Set(float64(expiring))
+}
@@ -0,0 +1,164 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// DefaultExplanationsLimit is the default number of the latest explanations kept in memory.
+const DefaultExplanationsLimit = 1000
+
+// ExplainedCall is a contract call the verdict is based on, with the `cast call` command reproducing it.
+type ExplainedCall struct {
+	Method   string        `json:"method"`
+	To       types.Address `json:"to"`
+	Block    string        `json:"block"`
+	Calldata string        `json:"calldata"`
+	Result   string        `json:"result"`
+	Cast     string        `json:"cast"`
+}
+
+// Explanation describes how the signature of a poke was verified, so the decision can be re-verified independently.
+type Explanation struct {
+	Address         types.Address   `json:"address"`
+	PokeBlockNumber uint64          `json:"pokeBlockNumber"`
+	PokeTxHash      *types.Hash     `json:"pokeTxHash,omitempty"`
+	PokeLog         *types.Log      `json:"pokeLog,omitempty"`
+	Valid           bool            `json:"valid"`
+	PreVerified     bool            `json:"preVerified"`
+	Error           string          `json:"error,omitempty"`
+	Calls           []ExplainedCall `json:"calls"`
+	VerifiedAt      time.Time       `json:"verifiedAt"`
+}
+
+// Explainer keeps explanations of the latest poke verifications and serves them as JSON list over HTTP.
+type Explainer struct {
+	mu           sync.Mutex
+	limit        int
+	explanations []Explanation
+}
+
+// NewExplainer creates a new instance of Explainer keeping up to limit latest explanations.
+func NewExplainer(limit int) *Explainer {
+	return &Explainer{limit: limit}
+}
+
+// Record adds the explanation, the oldest one is dropped once the limit is reached.
+func (e *Explainer) Record(x Explanation) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.explanations = append(e.explanations, x)
+	if e.limit > 0 && len(e.explanations) > e.limit {
+		e.explanations = e.explanations[len(e.explanations)-e.limit:]
+	}
+}
+
+// Explanations returns explanations of pokes of the address in the block, the latest first.
+// Zero address and block number match all.
+func (e *Explainer) Explanations(address types.Address, block uint64) []Explanation {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	result := make([]Explanation, 0)
+	for i := len(e.explanations) - 1; i >= 0; i-- {
+		x := e.explanations[i]
+		if (address.IsZero() || x.Address == address) && (block == 0 || x.PokeBlockNumber == block) {
+			result = append(result, x)
+		}
+	}
+	return result
+}
+
+// ServeHTTP serves explanations as JSON list, filtered by optional `address` and `block` query parameters.
+func (e *Explainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var address types.Address
+	if a := r.URL.Query().Get("address"); a != "" {
+		var err error
+		if address, err = types.AddressFromHex(a); err != nil {
+			http.Error(w, fmt.Sprintf("invalid address: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	var block uint64
+	if b := r.URL.Query().Get("block"); b != "" {
+		var err error
+		if block, err = strconv.ParseUint(b, 10, 64); err != nil {
+			http.Error(w, fmt.Sprintf("invalid block: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(e.Explanations(address, block)); err != nil {
+		logger.WithError(err).Error("failed to encode explanations")
+	}
+}
+
+// callRecorder collects contract calls made within the context.
+type callRecorder struct {
+	mu    sync.Mutex
+	calls []ExplainedCall
+}
+
+type callRecorderKey struct{}
+
+// withCallRecorder returns context in which the provider records calls to the recorder.
+func withCallRecorder(ctx context.Context, r *callRecorder) context.Context {
+	return context.WithValue(ctx, callRecorderKey{}, r)
+}
+
+// recordCall adds the call made at the block to the recorder of the context, if there is one.
+func recordCall(ctx context.Context, method string, to types.Address, calldata []byte, result []byte, block types.BlockNumber) {
+	r, ok := ctx.Value(callRecorderKey{}).(*callRecorder)
+	if !ok {
+		return
+	}
+	cast := fmt.Sprintf("cast call %v 0x%x", to, calldata)
+	blockName := block.String()
+	if !block.IsTag() {
+		blockName = block.Big().String()
+		cast += " --block " + blockName
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, ExplainedCall{
+		Method:   method,
+		To:       to,
+		Block:    blockName,
+		Calldata: fmt.Sprintf("0x%x", calldata),
+		Result:   fmt.Sprintf("0x%x", result),
+		Cast:     cast,
+	})
+}
+
+// explainVerification records explanation of the poke verification if the explainer is configured.
+func (c *Challenger) explainVerification(poke *OpPokedEvent, r *callRecorder, valid bool, preVerified bool, err error) {
+	if c.explainer == nil {
+		return
+	}
+	x := Explanation{
+		Address:         c.address,
+		PokeBlockNumber: poke.BlockNumber.Uint64(),
+		PokeTxHash:      poke.TxHash,
+		PokeLog:         poke.Raw,
+		Valid:           valid,
+		PreVerified:     preVerified,
+		Calls:           []ExplainedCall{},
+		VerifiedAt:      time.Now(),
+	}
+	if err != nil {
+		x.Error = err.Error()
+	}
+	if r != nil {
+		r.mu.Lock()
+		x.Calls = append(x.Calls, r.calls...)
+		r.mu.Unlock()
+	}
+	c.explainer.Record(x)
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExplainer(t *testing.T) {
+	address1 := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	address2 := types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEe2")
+
+	e := NewExplainer(2)
+	e.Record(Explanation{Address: address1, PokeBlockNumber: 100})
+	e.Record(Explanation{Address: address1, PokeBlockNumber: 101})
+	e.Record(Explanation{Address: address2, PokeBlockNumber: 101})
+
+	// The oldest explanation is dropped, the latest comes first.
+	all := e.Explanations(types.ZeroAddress, 0)
+	require.Len(t, all, 2)
+	assert.Equal(t, address2, all[0].Address)
+	assert.Len(t, e.Explanations(address1, 0), 1)
+	assert.Empty(t, e.Explanations(address1, 100))
+
+	res := httptest.NewRecorder()
+	e.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/explain?address="+address2.String()+"&block=101", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	var served []Explanation
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&served))
+	require.Len(t, served, 1)
+	assert.Equal(t, address2, served[0].Address)
+
+	res = httptest.NewRecorder()
+	e.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/explain?block=latest", nil))
+	assert.Equal(t, http.StatusBadRequest, res.Code)
+}
+
+func TestVerifyPokeExplained(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	poke := &OpPokedEvent{
+		BlockNumber: big.NewInt(100),
+		PokeData:    PokeData{Val: big.NewInt(1000), Age: 123},
+		Schnorr:     SchnorrData{SignersBlob: []byte{1}},
+	}
+
+	message, err := abi.EncodeValues(ScribeOptimisticContractABI.Methods["constructPokeMessage"].Outputs(), make([]byte, 32))
+	require.NoError(t, err)
+	acceptable, err := abi.EncodeValues(ScribeOptimisticContractABI.Methods["isAcceptableSchnorrSignatureNow"].Outputs(), false)
+	require.NoError(t, err)
+
+	client := new(mockRpcClient)
+	client.On("BlockByNumber", mock.Anything, mock.Anything, false).Return(&types.Block{Timestamp: time.Now()}, nil)
+	client.On("Call", mock.Anything, mock.Anything, types.LatestBlockNumber).Return(message, nil, nil).Once()
+	client.On("Call", mock.Anything, mock.Anything, types.LatestBlockNumber).Return(acceptable, nil, nil).Once()
+
+	explainer := NewExplainer(DefaultExplanationsLimit)
+	c := NewChallenger(context.TODO(), address, NewScribeOptimisticRPCProvider(client, nil), 0, nil, WithExplainer(explainer))
+	challengeable, _ := c.verifyPoke(context.TODO(), poke, 600)
+	assert.True(t, challengeable)
+
+	explanations := explainer.Explanations(address, 100)
+	require.Len(t, explanations, 1)
+	x := explanations[0]
+	assert.False(t, x.Valid)
+	require.Len(t, x.Calls, 2)
+	assert.Equal(t, "constructPokeMessage", x.Calls[0].Method)
+	assert.Equal(t, "isAcceptableSchnorrSignatureNow", x.Calls[1].Method)
+	assert.True(t, strings.HasPrefix(x.Calls[1].Cast, "cast call "+address.String()+" 0x"))
+	assert.Equal(t, x.Calls[1].Calldata, strings.Fields(x.Calls[1].Cast)[3])
+}
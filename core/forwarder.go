@@ -0,0 +1,76 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/types"
+)
+
+// Placeholders supported in forwarder argument templates.
+const (
+	ForwarderTargetPlaceholder   = "{target}"
+	ForwarderCalldataPlaceholder = "{calldata}"
+	ForwarderFromPlaceholder     = "{from}"
+)
+
+// ForwarderConfig describes a forwarder (relayer, multicall) contract `opChallenge` is routed through.
+// Method is the forwarder function to call and Args are templates of its arguments, each of them may
+// contain placeholders: {target} - ScribeOptimistic address, {calldata} - encoded `opChallenge` call,
+// {from} - challenger account address.
+type ForwarderConfig struct {
+	Address types.Address
+	Method  *abi.Method
+	Args    []string
+}
+
+// NewForwarderConfig creates forwarder configuration from the method signature, e.g. `execute(address,bytes)`,
+// and argument templates, e.g. `{target}`, `{calldata}`.
+func NewForwarderConfig(address types.Address, signature string, args []string) (*ForwarderConfig, error) {
+	method, err := abi.ParseMethod(signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse forwarder method %q: %w", signature, err)
+	}
+	if method.Inputs().Size() != len(args) {
+		return nil, fmt.Errorf(
+			"forwarder method %q expects %d arguments, %d given",
+			signature,
+			method.Inputs().Size(),
+			len(args),
+		)
+	}
+	return &ForwarderConfig{
+		Address: address,
+		Method:  method,
+		Args:    args,
+	}, nil
+}
+
+// UsesFrom returns true if any argument template refers to the challenger account address.
+func (f *ForwarderConfig) UsesFrom() bool {
+	for _, tpl := range f.Args {
+		if strings.Contains(tpl, ForwarderFromPlaceholder) {
+			return true
+		}
+	}
+	return false
+}
+
+// Encode returns calldata for the forwarder contract wrapping given `opChallenge` calldata.
+func (f *ForwarderConfig) Encode(target types.Address, calldata []byte, from types.Address) ([]byte, error) {
+	replacer := strings.NewReplacer(
+		ForwarderTargetPlaceholder, target.String(),
+		ForwarderCalldataPlaceholder, fmt.Sprintf("0x%x", calldata),
+		ForwarderFromPlaceholder, from.String(),
+	)
+	args := make([]any, len(f.Args))
+	for i, tpl := range f.Args {
+		args[i] = replacer.Replace(tpl)
+	}
+	b, err := f.Method.EncodeArgs(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode forwarder %s args: %w", f.Method.Name(), err)
+	}
+	return b, nil
+}
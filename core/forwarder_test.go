@@ -0,0 +1,48 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForwarderConfig(t *testing.T) {
+	forwarder := types.MustAddressFromHex("0x0000000000000000000000000000000000000f0f")
+	target := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	from := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
+	cold := "0x000000000000000000000000000000000000c01d"
+
+	t.Run("invalid signature", func(t *testing.T) {
+		_, err := NewForwarderConfig(forwarder, "execute(", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("arguments count mismatch", func(t *testing.T) {
+		_, err := NewForwarderConfig(forwarder, "execute(address,bytes)", []string{"{target}"})
+		assert.ErrorContains(t, err, "expects 2 arguments, 1 given")
+	})
+
+	t.Run("placeholders are substituted", func(t *testing.T) {
+		f, err := NewForwarderConfig(
+			forwarder,
+			"challengeAndSweep(address,bytes,address,address)",
+			[]string{"{target}", "{calldata}", "{from}", cold},
+		)
+		require.NoError(t, err)
+
+		b, err := f.Encode(target, []byte{0xde, 0xad}, from)
+		require.NoError(t, err)
+
+		var gotTarget, gotFrom, gotCold types.Address
+		var gotCalldata []byte
+		require.Equal(t, f.Method.FourBytes().Bytes(), b[:4])
+		require.NoError(t, abi.DecodeValues(f.Method.Inputs(), b[4:], &gotTarget, &gotCalldata, &gotFrom, &gotCold))
+		assert.Equal(t, target, gotTarget)
+		assert.Equal(t, []byte{0xde, 0xad}, gotCalldata)
+		assert.Equal(t, from, gotFrom)
+		assert.Equal(t, types.MustAddressFromHex(cold), gotCold)
+	})
+}
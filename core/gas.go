@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/txmodifier"
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// Supported transaction types of GasOptions.
+const (
+	TxTypeNone    = "none"
+	TxTypeLegacy  = "legacy"
+	TxTypeEIP1559 = "eip1559"
+)
+
+// GasOptions configures fee estimation of challenge transactions sent through one client.
+type GasOptions struct {
+	// TxType is one of `legacy`, `eip1559` or `none` to leave fees to the node.
+	TxType string
+	// GasPriceMultiplier multiplies the gas price (max fee per gas for EIP-1559) suggested by the node or Oracle.
+	GasPriceMultiplier float64
+	// PriorityFeeMultiplier multiplies the priority fee suggested by the node or Oracle, EIP-1559 only.
+	PriorityFeeMultiplier float64
+	// MaxGasPrice caps the gas price (max fee per gas for EIP-1559), nil means no cap.
+	MaxGasPrice *big.Int
+	// MaxPriorityFeePerGas caps the priority fee, EIP-1559 only, nil means no cap.
+	MaxPriorityFeePerGas *big.Int
+	// Oracle suggests fees instead of the node, nil means fees suggested by the node.
+	Oracle GasOracle
+}
+
+// GasConfig holds fee options of the public mempool and flashbots paths, configured independently.
+// Flashbots transactions only pay on inclusion, so they can bid a higher priority fee.
+type GasConfig struct {
+	Public    GasOptions
+	Flashbots GasOptions
+}
+
+// DefaultGasConfig returns fees suggested by the node for the public path and EIP-1559 fees for flashbots.
+func DefaultGasConfig() GasConfig {
+	return GasConfig{
+		Public: GasOptions{
+			TxType:                TxTypeNone,
+			GasPriceMultiplier:    1,
+			PriorityFeeMultiplier: 1,
+		},
+		Flashbots: GasOptions{
+			TxType:                TxTypeEIP1559,
+			GasPriceMultiplier:    1,
+			PriorityFeeMultiplier: 1,
+		},
+	}
+}
+
+// TxModifier returns the gas fee estimator for the options, nil if fees are left to the node.
+func (o GasOptions) TxModifier() (rpc.TXModifier, error) {
+	if o.GasPriceMultiplier <= 0 || o.PriorityFeeMultiplier <= 0 {
+		return nil, fmt.Errorf("gas price multipliers have to be positive")
+	}
+
+	if o.Oracle != nil {
+		if o.TxType != TxTypeLegacy && o.TxType != TxTypeEIP1559 {
+			return nil, fmt.Errorf("gas oracle requires transaction type legacy or eip1559")
+		}
+		return NewGasOracleFeeEstimator(o.Oracle, o), nil
+	}
+
+	switch o.TxType {
+	case TxTypeLegacy:
+		return txmodifier.NewLegacyGasFeeEstimator(txmodifier.LegacyGasFeeEstimatorOptions{
+			Multiplier:  o.GasPriceMultiplier,
+			MinGasPrice: nil,
+			MaxGasPrice: o.MaxGasPrice,
+			Replace:     false,
+		}), nil
+	case TxTypeEIP1559:
+		return txmodifier.NewEIP1559GasFeeEstimator(txmodifier.EIP1559GasFeeEstimatorOptions{
+			GasPriceMultiplier:          o.GasPriceMultiplier,
+			PriorityFeePerGasMultiplier: o.PriorityFeeMultiplier,
+			MinGasPrice:                 nil,
+			MaxGasPrice:                 o.MaxGasPrice,
+			MinPriorityFeePerGas:        nil,
+			MaxPriorityFeePerGas:        o.MaxPriorityFeePerGas,
+			Replace:                     false,
+		}), nil
+	case "", TxTypeNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown transaction type: %s. Have to be legacy, eip1559 or none", o.TxType)
+	}
+}
+
+// GasLimitFallback estimates the gas limit with the estimator and falls back to the static gas limit
+// if the estimation fails, e.g. `eth_estimateGas` reverts on transient state, instead of aborting the transaction.
+type GasLimitFallback struct {
+	estimator rpc.TXModifier
+	gasLimit  uint64
+}
+
+// NewGasLimitFallback creates a new instance of GasLimitFallback, 0 gas limit disables the fallback,
+// failures are only counted then.
+func NewGasLimitFallback(estimator rpc.TXModifier, gasLimit uint64) *GasLimitFallback {
+	return &GasLimitFallback{estimator: estimator, gasLimit: gasLimit}
+}
+
+// Modify implements rpc.TXModifier interface.
+func (g *GasLimitFallback) Modify(ctx context.Context, client rpc.RPC, tx *types.Transaction) error {
+	err := g.estimator.Modify(ctx, client, tx)
+	if err == nil {
+		return nil
+	}
+	var to types.Address
+	if tx.To != nil {
+		to = *tx.To
+	}
+	GasEstimationFailuresCounter.WithLabelValues(to.String()).Inc()
+	if g.gasLimit == 0 {
+		return err
+	}
+	logger.
+		WithField("address", to).
+		Warnf("Failed to estimate gas limit, using static gas limit %d: %v", g.gasLimit, err)
+	tx.SetGasLimit(g.gasLimit)
+	return nil
+}
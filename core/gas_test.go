@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGasOptionsTxModifier(t *testing.T) {
+	cfg := DefaultGasConfig()
+
+	modifier, err := cfg.Public.TxModifier()
+	require.NoError(t, err)
+	assert.Nil(t, modifier)
+
+	cfg.Flashbots.MaxPriorityFeePerGas = big.NewInt(1e9)
+	modifier, err = cfg.Flashbots.TxModifier()
+	require.NoError(t, err)
+	assert.NotNil(t, modifier)
+
+	cfg.Public.TxType = TxTypeLegacy
+	modifier, err = cfg.Public.TxModifier()
+	require.NoError(t, err)
+	assert.NotNil(t, modifier)
+
+	_, err = GasOptions{TxType: "blob", GasPriceMultiplier: 1, PriorityFeeMultiplier: 1}.TxModifier()
+	assert.ErrorContains(t, err, "unknown transaction type")
+
+	_, err = GasOptions{TxType: TxTypeEIP1559, GasPriceMultiplier: 0, PriorityFeeMultiplier: 1}.TxModifier()
+	assert.Error(t, err)
+}
+
+func TestGasLimitFallback(t *testing.T) {
+	to := types.MustAddressFromHex("0xcF7acDa376eF37EC371235a094113dF9Cb4EfEec")
+	failures := GasEstimationFailuresCounter.WithLabelValues(to.String())
+	estimated := uint64(60000)
+	var estimateErr error
+	estimator := rpc.TXModifierFunc(func(ctx context.Context, client rpc.RPC, tx *types.Transaction) error {
+		if estimateErr != nil {
+			return estimateErr
+		}
+		tx.SetGasLimit(estimated)
+		return nil
+	})
+
+	tx := (&types.Transaction{}).SetTo(to)
+	require.NoError(t, NewGasLimitFallback(estimator, 200000).Modify(context.TODO(), nil, tx))
+	assert.Equal(t, estimated, *tx.GasLimit)
+	assert.Equal(t, float64(0), testutil.ToFloat64(failures))
+
+	estimateErr = fmt.Errorf("execution reverted")
+	tx = (&types.Transaction{}).SetTo(to)
+	require.NoError(t, NewGasLimitFallback(estimator, 200000).Modify(context.TODO(), nil, tx))
+	assert.Equal(t, uint64(200000), *tx.GasLimit)
+	assert.Equal(t, float64(1), testutil.ToFloat64(failures))
+
+	// Disabled fallback aborts the transaction.
+	tx = (&types.Transaction{}).SetTo(to)
+	assert.ErrorContains(t, NewGasLimitFallback(estimator, 0).Modify(context.TODO(), nil, tx), "execution reverted")
+	assert.Nil(t, tx.GasLimit)
+	assert.Equal(t, float64(2), testutil.ToFloat64(failures))
+}
@@ -0,0 +1,413 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	netURL "net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// Names of gas oracles selectable by GasOracleConfig.
+const (
+	GasOracleNode        = "node"
+	GasOracleStatic      = "static"
+	GasOracleFeeHistory  = "fee-history"
+	GasOracleBlocknative = "blocknative"
+	GasOracleEtherscan   = "etherscan"
+)
+
+// Default endpoints of external gas price APIs.
+const (
+	DefaultBlocknativeURL = "https://api.blocknative.com/gasprices/blockprices"
+	DefaultEtherscanURL   = "https://api.etherscan.io/v2/api"
+)
+
+// DefaultFeeHistoryBlocks is the number of recent blocks FeeHistoryGasOracle derives the priority fee from.
+const DefaultFeeHistoryBlocks = 10
+
+const gasOracleTimeout = 5 * time.Second
+
+var gwei = big.NewFloat(1e9)
+
+// GasFees are fees suggested by a GasOracle.
+type GasFees struct {
+	// GasPrice is the gas price of legacy transactions.
+	GasPrice *big.Int
+	// MaxFeePerGas is the max fee per gas of EIP-1559 transactions.
+	MaxFeePerGas *big.Int
+	// MaxPriorityFeePerGas is the priority fee of EIP-1559 transactions.
+	MaxPriorityFeePerGas *big.Int
+}
+
+// GasOracle suggests fees of challenge transactions, so the gas policy can be shared with other bots.
+// The client is the RPC client the transaction is sent through.
+type GasOracle interface {
+	SuggestFees(ctx context.Context, client rpc.RPC) (GasFees, error)
+}
+
+// GasOracleConfig selects the GasOracle by its name and holds its parameters.
+type GasOracleConfig struct {
+	// Name is one of GasOracleNode, GasOracleStatic, GasOracleFeeHistory, GasOracleBlocknative
+	// or GasOracleEtherscan.
+	Name string
+	// GasPrice and PriorityFeePerGas are fees of the static oracle, GasPrice is also the max fee per gas.
+	GasPrice          *big.Int
+	PriorityFeePerGas *big.Int
+	// Percentile is the percentile of recent priority fees of the fee-history oracle,
+	// or the confidence of the blocknative oracle.
+	Percentile float64
+	// APIKey authenticates requests to external APIs.
+	APIKey string
+	// URL overrides the default endpoint of the external API.
+	URL string
+	// ChainID is the chain fees are requested for from external APIs.
+	ChainID uint64
+}
+
+// GasOracle returns the configured oracle of the client sending through the transport, nil for GasOracleNode
+// as fees suggested by the node are estimated by the transaction modifiers of go-eth.
+func (c GasOracleConfig) GasOracle(t transport.Transport) (GasOracle, error) {
+	if c.Percentile < 0 || c.Percentile > 100 {
+		return nil, fmt.Errorf("gas oracle percentile has to be between 0 and 100")
+	}
+	switch c.Name {
+	case "", GasOracleNode:
+		return nil, nil
+	case GasOracleStatic:
+		if c.GasPrice == nil || c.PriorityFeePerGas == nil {
+			return nil, fmt.Errorf("static gas oracle requires gas price and priority fee")
+		}
+		return &StaticGasOracle{GasPrice: c.GasPrice, PriorityFeePerGas: c.PriorityFeePerGas}, nil
+	case GasOracleFeeHistory:
+		return NewFeeHistoryGasOracle(t, DefaultFeeHistoryBlocks, c.Percentile), nil
+	case GasOracleBlocknative, GasOracleEtherscan:
+		if c.ChainID == 0 {
+			return nil, fmt.Errorf("%s gas oracle requires the chain ID", c.Name)
+		}
+		if c.Name == GasOracleBlocknative {
+			return NewBlocknativeGasOracle(c.URL, c.APIKey, c.ChainID, c.Percentile), nil
+		}
+		return NewEtherscanGasOracle(c.URL, c.APIKey, c.ChainID), nil
+	default:
+		return nil, fmt.Errorf("unknown gas oracle %q, supported are node, static, fee-history, blocknative and etherscan", c.Name)
+	}
+}
+
+// NodeGasOracle suggests fees returned by eth_gasPrice and eth_maxPriorityFeePerGas of the node.
+type NodeGasOracle struct{}
+
+// SuggestFees implements GasOracle interface.
+func (NodeGasOracle) SuggestFees(ctx context.Context, client rpc.RPC) (GasFees, error) {
+	gasPrice, err := client.GasPrice(ctx)
+	if err != nil {
+		return GasFees{}, fmt.Errorf("failed to get gas price with error: %w: %w", ErrRPCUnavailable, err)
+	}
+	priorityFee, err := client.MaxPriorityFeePerGas(ctx)
+	if err != nil {
+		return GasFees{}, fmt.Errorf("failed to get priority fee with error: %w: %w", ErrRPCUnavailable, err)
+	}
+	return GasFees{GasPrice: gasPrice, MaxFeePerGas: gasPrice, MaxPriorityFeePerGas: priorityFee}, nil
+}
+
+// StaticGasOracle suggests fixed fees, GasPrice is also the max fee per gas of EIP-1559 transactions.
+type StaticGasOracle struct {
+	GasPrice          *big.Int
+	PriorityFeePerGas *big.Int
+}
+
+// SuggestFees implements GasOracle interface.
+func (s *StaticGasOracle) SuggestFees(context.Context, rpc.RPC) (GasFees, error) {
+	return GasFees{
+		GasPrice:             new(big.Int).Set(s.GasPrice),
+		MaxFeePerGas:         new(big.Int).Set(s.GasPrice),
+		MaxPriorityFeePerGas: new(big.Int).Set(s.PriorityFeePerGas),
+	}, nil
+}
+
+// FeeHistoryGasOracle suggests the median of the percentile of priority fees paid in recent blocks,
+// as returned by eth_feeHistory, on top of the base fee of the next block. The max fee per gas leaves room
+// for the base fee to double.
+type FeeHistoryGasOracle struct {
+	transport  transport.Transport
+	blocks     uint64
+	percentile float64
+}
+
+// NewFeeHistoryGasOracle creates a new instance of FeeHistoryGasOracle querying the node through the transport,
+// the one the transaction is sent through, as eth_feeHistory is not part of rpc.RPC.
+func NewFeeHistoryGasOracle(t transport.Transport, blocks uint64, percentile float64) *FeeHistoryGasOracle {
+	return &FeeHistoryGasOracle{transport: t, blocks: blocks, percentile: percentile}
+}
+
+// SuggestFees implements GasOracle interface.
+func (f *FeeHistoryGasOracle) SuggestFees(ctx context.Context, client rpc.RPC) (GasFees, error) {
+	var history types.FeeHistory
+	err := f.transport.Call(ctx, &history, "eth_feeHistory", types.NumberFromUint64(f.blocks), types.LatestBlockNumber, []float64{f.percentile})
+	if err != nil {
+		return GasFees{}, fmt.Errorf("failed to get fee history with error: %w: %w", ErrRPCUnavailable, err)
+	}
+	if len(history.BaseFeePerGas) == 0 {
+		return GasFees{}, fmt.Errorf("fee history has no base fee, chain doesn't support EIP-1559")
+	}
+	// The last base fee is the one of the next block.
+	baseFee := history.BaseFeePerGas[len(history.BaseFeePerGas)-1]
+
+	var tips []*big.Int
+	for _, reward := range history.Reward {
+		if len(reward) > 0 && reward[0] != nil {
+			tips = append(tips, reward[0])
+		}
+	}
+	var tip *big.Int
+	if len(tips) == 0 {
+		if tip, err = client.MaxPriorityFeePerGas(ctx); err != nil {
+			return GasFees{}, fmt.Errorf("failed to get priority fee with error: %w: %w", ErrRPCUnavailable, err)
+		}
+	} else {
+		sort.Slice(tips, func(i, j int) bool { return tips[i].Cmp(tips[j]) < 0 })
+		tip = new(big.Int).Set(tips[len(tips)/2])
+	}
+	return GasFees{
+		GasPrice:             new(big.Int).Add(baseFee, tip),
+		MaxFeePerGas:         new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), tip),
+		MaxPriorityFeePerGas: tip,
+	}, nil
+}
+
+// BlocknativeGasOracle suggests fees of the Blocknative gas price API for the next block,
+// at the lowest confidence not below the configured one.
+type BlocknativeGasOracle struct {
+	url        string
+	apiKey     string
+	chainID    uint64
+	confidence float64
+	httpClient *http.Client
+}
+
+// NewBlocknativeGasOracle creates a new instance of BlocknativeGasOracle, empty url uses DefaultBlocknativeURL.
+func NewBlocknativeGasOracle(url string, apiKey string, chainID uint64, confidence float64) *BlocknativeGasOracle {
+	if url == "" {
+		url = DefaultBlocknativeURL
+	}
+	return &BlocknativeGasOracle{
+		url:        url,
+		apiKey:     apiKey,
+		chainID:    chainID,
+		confidence: confidence,
+		httpClient: &http.Client{Timeout: gasOracleTimeout},
+	}
+}
+
+// SuggestFees implements GasOracle interface.
+func (b *BlocknativeGasOracle) SuggestFees(ctx context.Context, _ rpc.RPC) (GasFees, error) {
+	var res struct {
+		BlockPrices []struct {
+			EstimatedPrices []struct {
+				Confidence           float64     `json:"confidence"`
+				Price                json.Number `json:"price"`
+				MaxFeePerGas         json.Number `json:"maxFeePerGas"`
+				MaxPriorityFeePerGas json.Number `json:"maxPriorityFeePerGas"`
+			} `json:"estimatedPrices"`
+		} `json:"blockPrices"`
+	}
+	query := netURL.Values{"chainid": {strconv.FormatUint(b.chainID, 10)}}
+	if err := getGasOracleJSON(ctx, b.httpClient, b.url+"?"+query.Encode(), b.apiKey, &res); err != nil {
+		return GasFees{}, err
+	}
+	if len(res.BlockPrices) == 0 || len(res.BlockPrices[0].EstimatedPrices) == 0 {
+		return GasFees{}, fmt.Errorf("blocknative responded with no estimated prices")
+	}
+
+	// Estimates are sorted by descending confidence.
+	prices := res.BlockPrices[0].EstimatedPrices
+	estimate := prices[0]
+	for _, p := range prices {
+		if p.Confidence >= b.confidence && p.Confidence < estimate.Confidence {
+			estimate = p
+		}
+	}
+	var fees GasFees
+	var err error
+	for _, v := range []struct {
+		value json.Number
+		dest  **big.Int
+	}{
+		{estimate.Price, &fees.GasPrice},
+		{estimate.MaxFeePerGas, &fees.MaxFeePerGas},
+		{estimate.MaxPriorityFeePerGas, &fees.MaxPriorityFeePerGas},
+	} {
+		if *v.dest, err = parseGwei(v.value.String()); err != nil {
+			return GasFees{}, err
+		}
+	}
+	return fees, nil
+}
+
+// EtherscanGasOracle suggests fees of the Etherscan gas tracker, the fast gas price is used
+// as challenges race the challenge period.
+type EtherscanGasOracle struct {
+	url        string
+	apiKey     string
+	chainID    uint64
+	httpClient *http.Client
+}
+
+// NewEtherscanGasOracle creates a new instance of EtherscanGasOracle, empty url uses DefaultEtherscanURL.
+func NewEtherscanGasOracle(url string, apiKey string, chainID uint64) *EtherscanGasOracle {
+	if url == "" {
+		url = DefaultEtherscanURL
+	}
+	return &EtherscanGasOracle{
+		url:        url,
+		apiKey:     apiKey,
+		chainID:    chainID,
+		httpClient: &http.Client{Timeout: gasOracleTimeout},
+	}
+}
+
+// SuggestFees implements GasOracle interface.
+func (e *EtherscanGasOracle) SuggestFees(ctx context.Context, _ rpc.RPC) (GasFees, error) {
+	var res struct {
+		Status  string          `json:"status"`
+		Message string          `json:"message"`
+		Result  json.RawMessage `json:"result"`
+	}
+	query := netURL.Values{
+		"chainid": {strconv.FormatUint(e.chainID, 10)},
+		"module":  {"gastracker"},
+		"action":  {"gasoracle"},
+		"apikey":  {e.apiKey},
+	}
+	if err := getGasOracleJSON(ctx, e.httpClient, e.url+"?"+query.Encode(), "", &res); err != nil {
+		return GasFees{}, err
+	}
+	if res.Status != "1" {
+		return GasFees{}, fmt.Errorf("etherscan responded with error: %s: %s", res.Message, res.Result)
+	}
+	var result struct {
+		FastGasPrice   string `json:"FastGasPrice"`
+		SuggestBaseFee string `json:"suggestBaseFee"`
+	}
+	if err := json.Unmarshal(res.Result, &result); err != nil {
+		return GasFees{}, fmt.Errorf("failed to decode etherscan gas oracle result: %w", err)
+	}
+	gasPrice, err := parseGwei(result.FastGasPrice)
+	if err != nil {
+		return GasFees{}, err
+	}
+	baseFee, err := parseGwei(result.SuggestBaseFee)
+	if err != nil {
+		return GasFees{}, err
+	}
+	tip := new(big.Int).Sub(gasPrice, baseFee)
+	if tip.Sign() < 0 {
+		tip.SetInt64(0)
+	}
+	return GasFees{
+		GasPrice:             gasPrice,
+		MaxFeePerGas:         new(big.Int).Add(new(big.Int).Mul(baseFee, big.NewInt(2)), tip),
+		MaxPriorityFeePerGas: tip,
+	}, nil
+}
+
+// getGasOracleJSON fetches the URL and decodes its JSON response into res,
+// authorization is sent as the Authorization header if not empty.
+func getGasOracleJSON(ctx context.Context, client *http.Client, url string, authorization string, res any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create gas oracle request: %w", err)
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL may hold the API key.
+		return fmt.Errorf("failed to fetch gas prices from %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("gas oracle responded with status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(res); err != nil {
+		return fmt.Errorf("failed to decode gas oracle response: %w", err)
+	}
+	return nil
+}
+
+// parseGwei parses the decimal amount of gwei into wei.
+func parseGwei(value string) (*big.Int, error) {
+	amount, ok := new(big.Float).SetPrec(256).SetString(value)
+	if !ok || amount.Sign() < 0 {
+		return nil, fmt.Errorf("invalid gas price %q, has to be amount in gwei", value)
+	}
+	// Rounded, as decimal fractions of gwei are not exact in binary.
+	wei, _ := amount.Mul(amount, gwei).Add(amount, big.NewFloat(0.5)).Int(nil)
+	return wei, nil
+}
+
+// GasOracleFeeEstimator sets fees suggested by the oracle, scaled by multipliers and capped by GasOptions.
+// If the oracle fails, e.g. an external API is down, fees suggested by the node are used instead,
+// so the challenge is not held back.
+type GasOracleFeeEstimator struct {
+	oracle  GasOracle
+	options GasOptions
+}
+
+// NewGasOracleFeeEstimator creates a new instance of GasOracleFeeEstimator.
+func NewGasOracleFeeEstimator(oracle GasOracle, options GasOptions) *GasOracleFeeEstimator {
+	return &GasOracleFeeEstimator{oracle: oracle, options: options}
+}
+
+// Modify implements rpc.TXModifier interface.
+func (g *GasOracleFeeEstimator) Modify(ctx context.Context, client rpc.RPC, tx *types.Transaction) error {
+	fees, err := g.oracle.SuggestFees(ctx, client)
+	if err != nil {
+		logger.Warnf("Gas oracle failed, using fees suggested by the node: %v", err)
+		if fees, err = (NodeGasOracle{}).SuggestFees(ctx, client); err != nil {
+			return err
+		}
+	}
+	switch g.options.TxType {
+	case TxTypeLegacy:
+		tx.GasPrice = capFee(scaleFee(fees.GasPrice, g.options.GasPriceMultiplier), g.options.MaxGasPrice)
+		tx.MaxFeePerGas = nil
+		tx.MaxPriorityFeePerGas = nil
+		tx.Type = types.LegacyTxType
+	case TxTypeEIP1559:
+		maxFee := capFee(scaleFee(fees.MaxFeePerGas, g.options.GasPriceMultiplier), g.options.MaxGasPrice)
+		tip := capFee(scaleFee(fees.MaxPriorityFeePerGas, g.options.PriorityFeeMultiplier), g.options.MaxPriorityFeePerGas)
+		if tip.Cmp(maxFee) > 0 {
+			tip = maxFee
+		}
+		tx.GasPrice = nil
+		tx.MaxFeePerGas = maxFee
+		tx.MaxPriorityFeePerGas = tip
+		tx.Type = types.DynamicFeeTxType
+	default:
+		return fmt.Errorf("gas oracle requires transaction type legacy or eip1559, got %q", g.options.TxType)
+	}
+	return nil
+}
+
+func scaleFee(fee *big.Int, multiplier float64) *big.Int {
+	scaled, _ := new(big.Float).Mul(new(big.Float).SetInt(fee), big.NewFloat(multiplier)).Int(nil)
+	return scaled
+}
+
+func capFee(fee *big.Int, limit *big.Int) *big.Int {
+	if limit != nil && fee.Cmp(limit) > 0 {
+		return new(big.Int).Set(limit)
+	}
+	return fee
+}
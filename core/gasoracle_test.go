@@ -0,0 +1,187 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFeeNode answers gas price requests of rpc.RPC, other methods are not expected.
+type fakeFeeNode struct {
+	rpc.RPC
+	gasPrice    *big.Int
+	priorityFee *big.Int
+}
+
+func (f *fakeFeeNode) GasPrice(context.Context) (*big.Int, error) {
+	return f.gasPrice, nil
+}
+
+func (f *fakeFeeNode) MaxPriorityFeePerGas(context.Context) (*big.Int, error) {
+	return f.priorityFee, nil
+}
+
+// fakeTransport answers JSON-RPC calls with the JSON result.
+type fakeTransport struct {
+	result string
+	method string
+}
+
+func (f *fakeTransport) Call(_ context.Context, result any, method string, _ ...any) error {
+	f.method = method
+	return json.Unmarshal([]byte(f.result), result)
+}
+
+type failingGasOracle struct{}
+
+func (failingGasOracle) SuggestFees(context.Context, rpc.RPC) (GasFees, error) {
+	return GasFees{}, fmt.Errorf("api down")
+}
+
+func TestGasOracleConfig(t *testing.T) {
+	oracle, err := GasOracleConfig{Name: GasOracleNode}.GasOracle(nil)
+	require.NoError(t, err)
+	assert.Nil(t, oracle)
+
+	oracle, err = GasOracleConfig{Name: GasOracleStatic, GasPrice: big.NewInt(2e9), PriorityFeePerGas: big.NewInt(1e9)}.GasOracle(nil)
+	require.NoError(t, err)
+	assert.IsType(t, &StaticGasOracle{}, oracle)
+
+	_, err = GasOracleConfig{Name: GasOracleStatic}.GasOracle(nil)
+	assert.ErrorContains(t, err, "requires gas price")
+	_, err = GasOracleConfig{Name: GasOracleEtherscan}.GasOracle(nil)
+	assert.ErrorContains(t, err, "requires the chain ID")
+	_, err = GasOracleConfig{Name: GasOracleFeeHistory, Percentile: 101}.GasOracle(nil)
+	assert.Error(t, err)
+	_, err = GasOracleConfig{Name: "owlracle"}.GasOracle(nil)
+	assert.ErrorContains(t, err, "unknown gas oracle")
+
+	_, err = GasOptions{TxType: TxTypeNone, GasPriceMultiplier: 1, PriorityFeeMultiplier: 1, Oracle: oracle}.TxModifier()
+	assert.ErrorContains(t, err, "requires transaction type")
+}
+
+func TestFeeHistoryGasOracle(t *testing.T) {
+	tr := &fakeTransport{result: `{
+		"oldestBlock": "0x10",
+		"baseFeePerGas": ["0x3b9aca00", "0x3b9aca00", "0x77359400"],
+		"gasUsedRatio": [0.5, 0.6],
+		"reward": [["0x5f5e100"], ["0x2faf080"]]
+	}`}
+	fees, err := NewFeeHistoryGasOracle(tr, 2, 50).SuggestFees(context.TODO(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "eth_feeHistory", tr.method)
+
+	// Base fee of the next block is 2 gwei, the median of tips is 0.1 gwei.
+	assert.Equal(t, big.NewInt(100_000_000), fees.MaxPriorityFeePerGas)
+	assert.Equal(t, big.NewInt(2_100_000_000), fees.GasPrice)
+	assert.Equal(t, big.NewInt(4_100_000_000), fees.MaxFeePerGas)
+
+	tr.result = `{"oldestBlock": "0x10", "baseFeePerGas": [], "gasUsedRatio": [], "reward": []}`
+	_, err = NewFeeHistoryGasOracle(tr, 2, 50).SuggestFees(context.TODO(), nil)
+	assert.ErrorContains(t, err, "no base fee")
+}
+
+func TestBlocknativeGasOracle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key", r.Header.Get("Authorization"))
+		assert.Equal(t, "1", r.URL.Query().Get("chainid"))
+		_, _ = w.Write([]byte(`{"blockPrices": [{"estimatedPrices": [
+			{"confidence": 99, "price": 30, "maxPriorityFeePerGas": 2.5, "maxFeePerGas": 50.5},
+			{"confidence": 90, "price": 25, "maxPriorityFeePerGas": 1.5, "maxFeePerGas": 45},
+			{"confidence": 70, "price": 20, "maxPriorityFeePerGas": 0.5, "maxFeePerGas": 40}
+		]}]}`))
+	}))
+	defer server.Close()
+
+	fees, err := NewBlocknativeGasOracle(server.URL, "key", 1, 80).SuggestFees(context.TODO(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(25e9), fees.GasPrice)
+	assert.Equal(t, big.NewInt(45e9), fees.MaxFeePerGas)
+	assert.Equal(t, big.NewInt(1_500_000_000), fees.MaxPriorityFeePerGas)
+
+	fees, err = NewBlocknativeGasOracle(server.URL, "key", 1, 100).SuggestFees(context.TODO(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(30e9), fees.GasPrice)
+}
+
+func TestEtherscanGasOracle(t *testing.T) {
+	status := "1"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "gasoracle", r.URL.Query().Get("action"))
+		assert.Equal(t, "key", r.URL.Query().Get("apikey"))
+		if status != "1" {
+			_, _ = w.Write([]byte(`{"status": "0", "message": "NOTOK", "result": "Invalid API Key"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"status": "1", "message": "OK", "result": {
+			"SafeGasPrice": "1.2", "ProposeGasPrice": "1.5", "FastGasPrice": "2.5", "suggestBaseFee": "1.1"
+		}}`))
+	}))
+	defer server.Close()
+
+	fees, err := NewEtherscanGasOracle(server.URL, "key", 1).SuggestFees(context.TODO(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(2_500_000_000), fees.GasPrice)
+	assert.Equal(t, big.NewInt(1_400_000_000), fees.MaxPriorityFeePerGas)
+	assert.Equal(t, big.NewInt(3_600_000_000), fees.MaxFeePerGas)
+
+	status = "0"
+	_, err = NewEtherscanGasOracle(server.URL, "key", 1).SuggestFees(context.TODO(), nil)
+	assert.ErrorContains(t, err, "Invalid API Key")
+}
+
+func TestGasOracleFeeEstimator(t *testing.T) {
+	oracle := &StaticGasOracle{GasPrice: big.NewInt(10e9), PriorityFeePerGas: big.NewInt(2e9)}
+
+	t.Run("eip1559 fees are multiplied and capped", func(t *testing.T) {
+		modifier, err := GasOptions{
+			TxType:                TxTypeEIP1559,
+			GasPriceMultiplier:    2,
+			PriorityFeeMultiplier: 2,
+			MaxPriorityFeePerGas:  big.NewInt(3e9),
+			Oracle:                oracle,
+		}.TxModifier()
+		require.NoError(t, err)
+		tx := &types.Transaction{}
+		require.NoError(t, modifier.Modify(context.TODO(), nil, tx))
+		assert.Equal(t, types.DynamicFeeTxType, tx.Type)
+		assert.Equal(t, big.NewInt(20e9), tx.MaxFeePerGas)
+		assert.Equal(t, big.NewInt(3e9), tx.MaxPriorityFeePerGas)
+		assert.Nil(t, tx.GasPrice)
+	})
+
+	t.Run("legacy gas price", func(t *testing.T) {
+		modifier := NewGasOracleFeeEstimator(oracle, GasOptions{
+			TxType:                TxTypeLegacy,
+			GasPriceMultiplier:    1,
+			PriorityFeeMultiplier: 1,
+			MaxGasPrice:           big.NewInt(8e9),
+		})
+		tx := &types.Transaction{}
+		require.NoError(t, modifier.Modify(context.TODO(), nil, tx))
+		assert.Equal(t, types.LegacyTxType, tx.Type)
+		assert.Equal(t, big.NewInt(8e9), tx.GasPrice)
+	})
+
+	t.Run("failing oracle falls back to the node", func(t *testing.T) {
+		modifier := NewGasOracleFeeEstimator(failingGasOracle{}, GasOptions{
+			TxType:                TxTypeEIP1559,
+			GasPriceMultiplier:    1,
+			PriorityFeeMultiplier: 1,
+		})
+		node := &fakeFeeNode{gasPrice: big.NewInt(5e9), priorityFee: big.NewInt(1e9)}
+		tx := &types.Transaction{}
+		require.NoError(t, modifier.Modify(context.TODO(), node, tx))
+		assert.Equal(t, big.NewInt(5e9), tx.MaxFeePerGas)
+		assert.Equal(t, big.NewInt(1e9), tx.MaxPriorityFeePerGas)
+	})
+}
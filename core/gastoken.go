@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// GasToken is the native token gas is paid in, e.g. ETH on Ethereum or xDAI on Gnosis.
+type GasToken struct {
+	Symbol   string
+	Decimals uint8
+}
+
+// DefaultGasToken is the gas token of Ethereum and most rollups.
+var DefaultGasToken = GasToken{Symbol: "ETH", Decimals: 18}
+
+// NativeToken is the gas token of the chain, balances and costs in metrics and logs are denominated in it.
+var NativeToken = DefaultGasToken
+
+// Float64 converts the amount in the smallest unit of the token, e.g. wei, to whole tokens.
+func (t GasToken) Float64(amount *big.Int) float64 {
+	f, _ := scaleDown(amount, t.Decimals).Float64()
+	return f
+}
+
+// Format returns the amount in the smallest unit of the token as whole tokens followed by the symbol.
+func (t GasToken) Format(amount *big.Int) string {
+	return fmt.Sprintf("%s %s", scaleDown(amount, t.Decimals).Text('f', -1), t.Symbol)
+}
+
+// BalanceCheckInterval is the interval the challenger account balance is checked at.
+var BalanceCheckInterval = 30 * time.Second
+
+// BalanceMonitor publishes the gas token balance of the challenger account and alerts when it drops below
+// the minimum, so the account is topped up before challenges fail to pay for gas.
+type BalanceMonitor struct {
+	client  RPCClient
+	from    types.Address
+	minimum *big.Int
+
+	// Stats keeps the balance if set.
+	Stats *Stats
+
+	mu  sync.Mutex
+	low bool
+}
+
+// NewBalanceMonitor creates a new instance of BalanceMonitor, nil minimum disables the alert.
+func NewBalanceMonitor(client RPCClient, from types.Address, minimum *big.Int) *BalanceMonitor {
+	return &BalanceMonitor{client: client, from: from, minimum: minimum}
+}
+
+// Run checks the balance every BalanceCheckInterval until ctx is done.
+func (b *BalanceMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(BalanceCheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := b.Check(ctx); err != nil {
+			logger.
+				WithField("from", b.address()).
+				Errorf("Failed to check balance with error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// address returns the monitored address.
+func (b *BalanceMonitor) address() types.Address {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.from
+}
+
+// SetFrom switches monitoring to another address, e.g. after key rotation. Metrics of the old address are removed.
+func (b *BalanceMonitor) SetFrom(from types.Address) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	AccountBalanceGauge.DeleteLabelValues(b.from.String(), NativeToken.Symbol)
+	AccountBalanceLowGauge.DeleteLabelValues(b.from.String())
+	b.from = from
+	b.low = false
+}
+
+// Check publishes the balance and alerts once when it drops below the minimum.
+func (b *BalanceMonitor) Check(ctx context.Context) error {
+	b.mu.Lock()
+	from := b.from
+	b.mu.Unlock()
+
+	balance, err := b.client.GetBalance(ctx, from, types.LatestBlockNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get balance of %v: %w", from, err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if from != b.from {
+		// Switched to another address meanwhile.
+		return nil
+	}
+	AccountBalanceGauge.WithLabelValues(b.from.String(), NativeToken.Symbol).Set(NativeToken.Float64(balance))
+	if b.Stats != nil {
+		b.Stats.RecordBalance(b.from, balance)
+	}
+	low := b.minimum != nil && balance.Cmp(b.minimum) < 0
+	if low {
+		AccountBalanceLowGauge.WithLabelValues(b.from.String()).Set(1)
+		if !b.low {
+			logger.
+				WithField("from", b.from).
+				Errorf("Balance %s is below %s, challenges may fail to pay for gas", NativeToken.Format(balance), NativeToken.Format(b.minimum))
+		}
+	} else {
+		AccountBalanceLowGauge.WithLabelValues(b.from.String()).Set(0)
+		if b.low {
+			logger.
+				WithField("from", b.from).
+				Infof("Balance %s is above the minimum again", NativeToken.Format(balance))
+		}
+	}
+	b.low = low
+	return nil
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGasToken(t *testing.T) {
+	xdai := GasToken{Symbol: "xDAI", Decimals: 18}
+	amount, _ := new(big.Int).SetString("1500000000000000000", 10)
+	assert.Equal(t, "1.5 xDAI", xdai.Format(amount))
+	assert.Equal(t, 1.5, xdai.Float64(amount))
+	assert.Equal(t, "0.015 USDC", GasToken{Symbol: "USDC", Decimals: 6}.Format(big.NewInt(15000)))
+}
+
+func TestBalanceMonitor(t *testing.T) {
+	from := types.MustAddressFromHex("0xbF7acDa376eF37EC371235a094113dF9Cb4EfEeb")
+	client := new(mockRpcClient)
+	call := client.On("GetBalance", mock.Anything, from, types.LatestBlockNumber).Return(big.NewInt(5e17), nil)
+
+	b := NewBalanceMonitor(client, from, big.NewInt(1e18))
+	require.NoError(t, b.Check(context.TODO()))
+	assert.Equal(t, 0.5, testutil.ToFloat64(AccountBalanceGauge.WithLabelValues(from.String(), NativeToken.Symbol)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(AccountBalanceLowGauge.WithLabelValues(from.String())))
+
+	call.Unset()
+	client.On("GetBalance", mock.Anything, from, types.LatestBlockNumber).Return(big.NewInt(2e18), nil)
+	require.NoError(t, b.Check(context.TODO()))
+	assert.Equal(t, 0.0, testutil.ToFloat64(AccountBalanceLowGauge.WithLabelValues(from.String())))
+}
+
+func TestBalanceMonitorSetFrom(t *testing.T) {
+	old := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEeb")
+	next := types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEeb")
+	client := new(mockRpcClient)
+	client.On("GetBalance", mock.Anything, old, types.LatestBlockNumber).Return(big.NewInt(5e17), nil)
+	client.On("GetBalance", mock.Anything, next, types.LatestBlockNumber).Return(big.NewInt(2e18), nil)
+
+	b := NewBalanceMonitor(client, old, big.NewInt(1e18))
+	require.NoError(t, b.Check(context.TODO()))
+	assert.Equal(t, 1.0, testutil.ToFloat64(AccountBalanceLowGauge.WithLabelValues(old.String())))
+
+	b.SetFrom(next)
+	require.NoError(t, b.Check(context.TODO()))
+	// Metrics of the old address are removed, so it doesn't alert anymore.
+	assert.False(t, AccountBalanceLowGauge.DeleteLabelValues(old.String()))
+	assert.False(t, AccountBalanceGauge.DeleteLabelValues(old.String(), NativeToken.Symbol))
+	assert.Equal(t, 2.0, testutil.ToFloat64(AccountBalanceGauge.WithLabelValues(next.String(), NativeToken.Symbol)))
+	assert.Equal(t, 0.0, testutil.ToFloat64(AccountBalanceLowGauge.WithLabelValues(next.String())))
+}
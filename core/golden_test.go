@@ -0,0 +1,256 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	goldenUpdate  = flag.Bool("golden.update", false, "Rewrite golden files of the corpus in testdata/golden")
+	goldenRecord  = flag.String("golden.record", "", "RPC URL to record a new corpus case from, see testdata/golden/README.md")
+	goldenName    = flag.String("golden.name", "", "Name of the recorded corpus case")
+	goldenAddress = flag.String("golden.address", "", "ScribeOptimistic address of the recorded corpus case")
+	goldenFrom    = flag.Uint64("golden.from", 0, "First block of the recorded corpus case")
+	goldenTo      = flag.Uint64("golden.to", 0, "Last block of the recorded corpus case")
+)
+
+const goldenDir = "testdata/golden"
+
+// goldenCase holds logs of a ScribeOptimistic contract within a block range, together with contract calls
+// and transactions needed to verify them, recorded from a real chain.
+type goldenCase struct {
+	Address      types.Address              `json:"address"`
+	FromBlock    uint64                     `json:"fromBlock"`
+	ToBlock      uint64                     `json:"toBlock"`
+	Logs         []types.Log                `json:"logs"`
+	Calls        []goldenCall               `json:"calls"`
+	Transactions []types.OnChainTransaction `json:"transactions"`
+}
+
+type goldenCall struct {
+	Block  types.BlockNumber `json:"block"`
+	Input  types.Bytes       `json:"input"`
+	Result types.Bytes       `json:"result"`
+}
+
+// goldenResult is what the challenger makes of the corpus case, compared against the golden file.
+type goldenResult struct {
+	Pokes      []goldenPoke      `json:"pokes"`
+	Challenges []goldenChallenge `json:"challenges"`
+}
+
+type goldenPoke struct {
+	BlockNumber   *big.Int      `json:"blockNumber"`
+	TxHash        *types.Hash   `json:"txHash"`
+	Caller        types.Address `json:"caller"`
+	OpFeed        types.Address `json:"opFeed"`
+	Val           *big.Int      `json:"val"`
+	Age           uint32        `json:"age"`
+	Signature     types.Hash    `json:"signature"`
+	Commitment    types.Address `json:"commitment"`
+	SignersBlob   types.Bytes   `json:"signersBlob"`
+	Valid         bool          `json:"valid"`
+	Challenged    bool          `json:"challenged"`
+	Challengeable bool          `json:"challengeable"`
+}
+
+type goldenChallenge struct {
+	BlockNumber *big.Int      `json:"blockNumber"`
+	TxHash      *types.Hash   `json:"txHash"`
+	Challenger  types.Address `json:"challenger"`
+	Signature   *types.Hash   `json:"signature,omitempty"`
+}
+
+// replayClient serves a recorded corpus case, anything not recorded is an error.
+type replayClient struct {
+	mockRpcClient
+	c *goldenCase
+}
+
+func (r *replayClient) GetLogs(_ context.Context, query *types.FilterLogsQuery) ([]types.Log, error) {
+	var logs []types.Log
+	for _, log := range r.c.Logs {
+		if len(log.Topics) > 0 && len(query.Topics) > 0 && containsHash(query.Topics[0], log.Topics[0]) {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func (r *replayClient) Call(_ context.Context, call *types.Call, block types.BlockNumber) ([]byte, *types.Call, error) {
+	for _, c := range r.c.Calls {
+		if c.Block.String() == block.String() && string(c.Input) == string(call.Input) {
+			return c.Result, call, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("call 0x%x at %v not recorded", call.Input, block)
+}
+
+func (r *replayClient) GetTransactionByHash(_ context.Context, hash types.Hash) (*types.OnChainTransaction, error) {
+	for _, tx := range r.c.Transactions {
+		if tx.Hash != nil && *tx.Hash == hash {
+			return &tx, nil
+		}
+	}
+	return nil, fmt.Errorf("transaction %v not recorded", hash)
+}
+
+// recordingClient records logs, calls and transactions fetched from the chain into the corpus case.
+type recordingClient struct {
+	*rpc.Client
+	mu sync.Mutex
+	c  *goldenCase
+}
+
+func (r *recordingClient) GetLogs(ctx context.Context, query *types.FilterLogsQuery) ([]types.Log, error) {
+	logs, err := r.Client.GetLogs(ctx, query)
+	r.mu.Lock()
+	r.c.Logs = append(r.c.Logs, logs...)
+	r.mu.Unlock()
+	return logs, err
+}
+
+func (r *recordingClient) Call(ctx context.Context, call *types.Call, block types.BlockNumber) ([]byte, *types.Call, error) {
+	res, c, err := r.Client.Call(ctx, call, block)
+	if err == nil {
+		r.mu.Lock()
+		r.c.Calls = append(r.c.Calls, goldenCall{Block: block, Input: call.Input, Result: res})
+		r.mu.Unlock()
+	}
+	return res, c, err
+}
+
+func (r *recordingClient) GetTransactionByHash(ctx context.Context, hash types.Hash) (*types.OnChainTransaction, error) {
+	tx, err := r.Client.GetTransactionByHash(ctx, hash)
+	if err == nil && tx != nil {
+		r.mu.Lock()
+		r.c.Transactions = append(r.c.Transactions, *tx)
+		r.mu.Unlock()
+	}
+	return tx, err
+}
+
+func containsHash(hashes []types.Hash, hash types.Hash) bool {
+	for _, h := range hashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateGolden decodes events of the corpus case and gives verdicts on its pokes.
+// Signatures are verified at the block of the poke, so verdicts don't depend on the time of recording.
+func evaluateGolden(t *testing.T, client RPCClient, c *goldenCase) goldenResult {
+	ctx := context.Background()
+	provider := NewScribeOptimisticRPCProvider(client, nil)
+	from, to := new(big.Int).SetUint64(c.FromBlock), new(big.Int).SetUint64(c.ToBlock)
+
+	pokes, err := provider.GetPokes(ctx, c.Address, from, to)
+	require.NoError(t, err)
+	challenges, err := provider.GetSuccessfulChallenges(ctx, c.Address, from, to)
+	require.NoError(t, err)
+	unchallenged := PickUnchallengedPokes(pokes, challenges)
+
+	res := goldenResult{Pokes: []goldenPoke{}, Challenges: []goldenChallenge{}}
+	for _, poke := range pokes {
+		valid, err := provider.isPokeSignatureValidAt(ctx, c.Address, poke, types.BlockNumberFromBigInt(poke.BlockNumber))
+		require.NoError(t, err)
+		challenged := true
+		for _, p := range unchallenged {
+			if p == poke {
+				challenged = false
+			}
+		}
+		res.Pokes = append(res.Pokes, goldenPoke{
+			BlockNumber:   poke.BlockNumber,
+			TxHash:        poke.TxHash,
+			Caller:        poke.Caller,
+			OpFeed:        poke.OpFeed,
+			Val:           poke.PokeData.Val,
+			Age:           poke.PokeData.Age,
+			Signature:     types.Hash(poke.Schnorr.Signature),
+			Commitment:    poke.Schnorr.Commitment,
+			SignersBlob:   poke.Schnorr.SignersBlob,
+			Valid:         valid,
+			Challenged:    challenged,
+			Challengeable: !valid && !challenged,
+		})
+	}
+	for _, challenge := range challenges {
+		gc := goldenChallenge{BlockNumber: challenge.BlockNumber, TxHash: challenge.TxHash, Challenger: challenge.Challenger}
+		if challenge.Schnorr != nil {
+			sig := types.Hash(challenge.Schnorr.Signature)
+			gc.Signature = &sig
+		}
+		res.Challenges = append(res.Challenges, gc)
+	}
+	return res
+}
+
+func writeJSON(t *testing.T, path string, v any) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, append(b, '\n'), 0o644))
+}
+
+func TestGolden(t *testing.T) {
+	cases, err := filepath.Glob(filepath.Join(goldenDir, "*.case.json"))
+	require.NoError(t, err)
+	require.NotEmpty(t, cases)
+
+	for _, path := range cases {
+		name := strings.TrimSuffix(filepath.Base(path), ".case.json")
+		t.Run(name, func(t *testing.T) {
+			b, err := os.ReadFile(path)
+			require.NoError(t, err)
+			var c goldenCase
+			require.NoError(t, json.Unmarshal(b, &c))
+
+			res := evaluateGolden(t, &replayClient{c: &c}, &c)
+			goldenPath := filepath.Join(goldenDir, name+".golden.json")
+			if *goldenUpdate {
+				writeJSON(t, goldenPath, res)
+			}
+			expected, err := os.ReadFile(goldenPath)
+			require.NoError(t, err)
+			actual, err := json.Marshal(res)
+			require.NoError(t, err)
+			require.JSONEq(t, string(expected), string(actual))
+		})
+	}
+}
+
+// TestGoldenRecord records a new corpus case from the chain, it's skipped unless -golden.record is given.
+func TestGoldenRecord(t *testing.T) {
+	if *goldenRecord == "" {
+		t.Skip("-golden.record not given")
+	}
+	require.NotEmpty(t, *goldenName, "-golden.name is required")
+	tr, err := transport.New(context.Background(), *goldenRecord)
+	require.NoError(t, err)
+	client, err := rpc.NewClient(rpc.WithTransport(tr))
+	require.NoError(t, err)
+
+	c := &goldenCase{
+		Address:   types.MustAddressFromHex(*goldenAddress),
+		FromBlock: *goldenFrom,
+		ToBlock:   *goldenTo,
+	}
+	res := evaluateGolden(t, &recordingClient{Client: client, c: c}, c)
+	writeJSON(t, filepath.Join(goldenDir, *goldenName+".case.json"), c)
+	writeJSON(t, filepath.Join(goldenDir, *goldenName+".golden.json"), res)
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// DefaultHeartbeatTimeout is the default time a tick of each address has to finish within for the process
+// to be considered alive.
+const DefaultHeartbeatTimeout = 5 * time.Minute
+
+// Heartbeat tracks ticks of all monitored addresses, so supervisors can restart the process once they stop progressing.
+type Heartbeat struct {
+	mu        sync.Mutex
+	timeout   time.Duration
+	startedAt time.Time
+	ticks     map[types.Address]time.Time
+}
+
+// NewHeartbeat creates a new instance of Heartbeat for the addresses, each of them has to tick within timeout.
+// Addresses which didn't tick yet are given timeout since the creation.
+func NewHeartbeat(addresses []types.Address, timeout time.Duration) *Heartbeat {
+	ticks := make(map[types.Address]time.Time, len(addresses))
+	for _, address := range addresses {
+		ticks[address] = time.Time{}
+	}
+	return &Heartbeat{timeout: timeout, startedAt: time.Now(), ticks: ticks}
+}
+
+// Beat records the tick of the address.
+func (h *Heartbeat) Beat(address types.Address) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ticks[address] = time.Now()
+}
+
+// Healthy returns true if all addresses ticked within the timeout.
+func (h *Heartbeat) Healthy() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, t := range h.ticks {
+		if t.IsZero() {
+			t = h.startedAt
+		}
+		if time.Since(t) > h.timeout {
+			return false
+		}
+	}
+	return true
+}
+
+type heartbeatStatus struct {
+	Healthy bool                        `json:"healthy"`
+	Ticks   map[types.Address]time.Time `json:"ticks"`
+}
+
+// ServeHTTP serves time of the last tick of each address as JSON, with 503 status if ticks stopped progressing.
+func (h *Heartbeat) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	status := heartbeatStatus{Healthy: h.Healthy(), Ticks: make(map[types.Address]time.Time)}
+	h.mu.Lock()
+	for address, t := range h.ticks {
+		status.Ticks[address] = t
+	}
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if !status.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		logger.WithError(err).Error("failed to encode heartbeat")
+	}
+}
+
+// RunWatchdog pings systemd watchdog while ticks keep progressing, so systemd restarts the process once they stop.
+// It returns right away if the watchdog is not enabled for the process.
+func (h *Heartbeat) RunWatchdog(ctx context.Context) {
+	interval := SdWatchdogInterval()
+	if interval == 0 {
+		return
+	}
+	logger.Infof("Systemd watchdog enabled, pinging every %v", interval/2)
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !h.Healthy() {
+				logger.Warn("Ticks stopped progressing, not pinging systemd watchdog")
+				continue
+			}
+			if _, err := SdNotify("WATCHDOG=1"); err != nil {
+				logger.WithError(err).Warn("Failed to ping systemd watchdog")
+			}
+		}
+	}
+}
+
+// SdNotify sends the state, e.g. `READY=1`, to systemd over the socket in NOTIFY_SOCKET.
+// It returns false if the process is not run by systemd with notify support.
+func SdNotify(state string) (bool, error) {
+	path := os.Getenv("NOTIFY_SOCKET")
+	if path == "" {
+		return false, nil
+	}
+	// Abstract socket names start with @, which stands for the leading null byte.
+	if path[0] == '@' {
+		path = "\x00" + path[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to systemd notify socket: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("failed to notify systemd: %w", err)
+	}
+	return true, nil
+}
+
+// SdWatchdogInterval returns the watchdog timeout set by systemd `WatchdogSec`, 0 if it's not enabled for the process.
+func SdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
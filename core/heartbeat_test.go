@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeat(t *testing.T) {
+	address1 := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	address2 := types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEe2")
+
+	h := NewHeartbeat([]types.Address{address1, address2}, 50*time.Millisecond)
+	// Addresses are given the timeout to tick for the first time.
+	assert.True(t, h.Healthy())
+
+	time.Sleep(60 * time.Millisecond)
+	h.Beat(address1)
+	assert.False(t, h.Healthy())
+
+	res := httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, res.Code)
+
+	h.Beat(address2)
+	assert.True(t, h.Healthy())
+
+	res = httptest.NewRecorder()
+	h.ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/health", nil))
+	require.Equal(t, http.StatusOK, res.Code)
+	var status heartbeatStatus
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&status))
+	assert.True(t, status.Healthy)
+	assert.Len(t, status.Ticks, 2)
+}
+
+func TestChallengerBeats(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	p := new(mockScribeOptimisticProvider)
+	p.On("BlockNumber", mock.Anything).Return((*big.Int)(nil), assert.AnError)
+	p.On("GetFrom", mock.Anything).Return(types.ZeroAddress)
+
+	h := NewHeartbeat([]types.Address{address}, time.Minute)
+	c := NewChallenger(context.TODO(), address, p, 0, nil, WithHeartbeat(h))
+	c.tick()
+
+	// Failed ticks count too, the loop is alive.
+	h.mu.Lock()
+	assert.False(t, h.ticks[address].IsZero())
+	h.mu.Unlock()
+}
+
+func TestSdNotify(t *testing.T) {
+	dir, err := os.MkdirTemp("", "sd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	t.Setenv("NOTIFY_SOCKET", "")
+	ok, err := SdNotify("READY=1")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	path := filepath.Join(dir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", path)
+	ok, err = SdNotify("READY=1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	buf := make([]byte, 64)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "READY=1", string(buf[:n]))
+
+	// Watchdog pings are sent while ticks progress.
+	t.Setenv("WATCHDOG_USEC", "20000")
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	assert.Equal(t, 20*time.Millisecond, SdWatchdogInterval())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewHeartbeat(nil, time.Minute).RunWatchdog(ctx)
+	n, err = conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "WATCHDOG=1", string(buf[:n]))
+
+	t.Setenv("WATCHDOG_PID", "1")
+	assert.Equal(t, time.Duration(0), SdWatchdogInterval())
+}
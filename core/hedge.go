@@ -0,0 +1,161 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// HedgeMaxLogRange is the maximum number of blocks of hedged GetLogs requests. Longer ranges, e.g. scanning the
+// whole challenge window on startup, aren't latency critical and would only double the load of the endpoints.
+const HedgeMaxLogRange = 100
+
+// TransactionSigner is implemented by RPC clients able to sign transactions and send them signed,
+// e.g. rpc.Client of go-eth.
+type TransactionSigner interface {
+	SignTransaction(ctx context.Context, tx *types.Transaction) ([]byte, *types.Transaction, error)
+	SendRawTransaction(ctx context.Context, data []byte) (*types.Hash, error)
+}
+
+// hedgeEndpoint returns the endpoint requests of the given one are hedged to: the next one not flagged unhealthy.
+// Returns nil if there is none.
+func (f *FailoverClient) hedgeEndpoint(e *endpointState) *endpointState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i, endpoint := range f.endpoints {
+		if endpoint != e {
+			continue
+		}
+		for j := 1; j < len(f.endpoints); j++ {
+			if next := f.endpoints[(i+j)%len(f.endpoints)]; next.healthy {
+				return next
+			}
+		}
+	}
+	return nil
+}
+
+// hedge sends the request to the endpoint and, if it doesn't succeed within HedgeDelay, to the next healthy
+// endpoint too. The first success is returned, the other request is canceled. If both fail, the error of the
+// first endpoint is returned. Without HedgeDelay or another healthy endpoint, the request is sent as usual.
+func hedge[T any](
+	ctx context.Context,
+	f *FailoverClient,
+	e *endpointState,
+	method string,
+	req func(ctx context.Context, client RPCClient) (T, error),
+) (T, error) {
+	alternate := f.hedgeEndpoint(e)
+	if f.HedgeDelay <= 0 || alternate == nil {
+		return req(ctx, e.Client)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		endpoint *endpointState
+		value    T
+		err      error
+	}
+	results := make(chan result, 2)
+	send := func(e *endpointState) {
+		v, err := req(ctx, e.Client)
+		results <- result{endpoint: e, value: v, err: err}
+	}
+
+	go send(e)
+	timer := time.NewTimer(f.HedgeDelay)
+	defer timer.Stop()
+
+	var (
+		hedgeC  = timer.C
+		hedged  bool
+		pending = 1
+		errs    = map[*endpointState]error{}
+	)
+	hedgeRequest := func() {
+		logger.
+			WithField("method", method).
+			WithField("endpoint", alternate.Name).
+			Debugf("Hedging RPC request")
+		hedgeC = nil
+		hedged = true
+		pending++
+		go send(alternate)
+	}
+	for {
+		select {
+		case <-hedgeC:
+			hedgeRequest()
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				if hedged {
+					RPCHedgedRequestsCounter.WithLabelValues(method, r.endpoint.Name).Inc()
+				}
+				return r.value, nil
+			}
+			errs[r.endpoint] = r.err
+			if !hedged {
+				// The endpoint failed before the delay, the other one is asked right away.
+				hedgeRequest()
+				continue
+			}
+			if pending == 0 {
+				var zero T
+				return zero, errs[e]
+			}
+		}
+	}
+}
+
+// sendTransaction sends the transaction through the active endpoint, hedged if HedgeDelay is set. The transaction
+// is signed once, so hedged requests broadcast the same transaction and can't replace each other.
+func (f *FailoverClient) sendTransaction(ctx context.Context, tx *types.Transaction) (*types.Hash, *types.Transaction, error) {
+	e := f.current()
+	signer, ok := e.Client.(TransactionSigner)
+	if f.HedgeDelay <= 0 || !ok {
+		return e.Client.SendTransaction(ctx, tx)
+	}
+	raw, signed, err := signer.SignTransaction(ctx, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	hash, err := hedge(ctx, f, e, "eth_sendRawTransaction", func(ctx context.Context, client RPCClient) (*types.Hash, error) {
+		s, ok := client.(TransactionSigner)
+		if !ok {
+			return nil, fmt.Errorf("endpoint does not support sending raw transactions")
+		}
+		return s.SendRawTransaction(ctx, raw)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return hash, signed, nil
+}
+
+// isHedgedLogQuery tells if the query is latency critical, i.e. it fetches logs of a few recent blocks.
+func isHedgedLogQuery(query *types.FilterLogsQuery) bool {
+	switch {
+	case query == nil:
+		return false
+	case query.BlockHash != nil:
+		return true
+	case query.FromBlock == nil:
+		// Range starts at the latest block.
+		return true
+	case query.FromBlock.IsTag():
+		return !query.FromBlock.IsEarliest()
+	case query.ToBlock == nil || query.ToBlock.IsTag():
+		// Length of the range is not known.
+		return false
+	}
+	span := new(big.Int).Sub(query.ToBlock.Big(), query.FromBlock.Big())
+	return span.Cmp(big.NewInt(HedgeMaxLogRange)) < 0
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTxSigner struct {
+	mockRpcClient
+}
+
+func (m *mockTxSigner) SignTransaction(ctx context.Context, tx *types.Transaction) ([]byte, *types.Transaction, error) {
+	args := m.Called(ctx, tx)
+	return args.Get(0).([]byte), args.Get(1).(*types.Transaction), args.Error(2)
+}
+
+func (m *mockTxSigner) SendRawTransaction(ctx context.Context, data []byte) (*types.Hash, error) {
+	args := m.Called(ctx, data)
+	return args.Get(0).(*types.Hash), args.Error(1)
+}
+
+func newHedgedClient(t *testing.T, primary, alternate RPCClient) *FailoverClient {
+	f, err := NewFailoverClient(
+		RPCEndpoint{Name: "primary", Client: primary},
+		RPCEndpoint{Name: "alternate", Client: alternate},
+	)
+	require.NoError(t, err)
+	f.HedgeDelay = 10 * time.Millisecond
+	return f
+}
+
+func TestHedgedGetLogs(t *testing.T) {
+	query := &types.FilterLogsQuery{
+		FromBlock: types.BlockNumberFromUint64Ptr(1000),
+		ToBlock:   types.BlockNumberFromUint64Ptr(1001),
+	}
+	logs := []types.Log{{Data: []byte{1}}}
+
+	t.Run("fast endpoint is not hedged", func(t *testing.T) {
+		primary := new(mockRpcClient)
+		alternate := new(mockRpcClient)
+		primary.On("GetLogs", mock.Anything, query).Return(logs, nil).Once()
+
+		got, err := newHedgedClient(t, primary, alternate).GetLogs(context.TODO(), query)
+		require.NoError(t, err)
+		assert.Equal(t, logs, got)
+		alternate.AssertNotCalled(t, "GetLogs", mock.Anything, mock.Anything)
+	})
+
+	t.Run("slow endpoint is hedged", func(t *testing.T) {
+		primary := new(mockRpcClient)
+		alternate := new(mockRpcClient)
+		primary.On("GetLogs", mock.Anything, query).Return([]types.Log{}, nil).After(time.Second).Once()
+		alternate.On("GetLogs", mock.Anything, query).Return(logs, nil).Once()
+
+		before := testutil.ToFloat64(RPCHedgedRequestsCounter.WithLabelValues("eth_getLogs", "alternate"))
+		started := time.Now()
+		got, err := newHedgedClient(t, primary, alternate).GetLogs(context.TODO(), query)
+		require.NoError(t, err)
+		assert.Equal(t, logs, got)
+		assert.Less(t, time.Since(started), time.Second)
+		assert.Equal(t, before+1, testutil.ToFloat64(RPCHedgedRequestsCounter.WithLabelValues("eth_getLogs", "alternate")))
+	})
+
+	t.Run("failed endpoint is hedged right away", func(t *testing.T) {
+		primary := new(mockRpcClient)
+		alternate := new(mockRpcClient)
+		primary.On("GetLogs", mock.Anything, query).Return([]types.Log{}, fmt.Errorf("rate limited")).Once()
+		alternate.On("GetLogs", mock.Anything, query).Return(logs, nil).Once()
+
+		got, err := newHedgedClient(t, primary, alternate).GetLogs(context.TODO(), query)
+		require.NoError(t, err)
+		assert.Equal(t, logs, got)
+	})
+
+	t.Run("error of active endpoint is returned if both fail", func(t *testing.T) {
+		primary := new(mockRpcClient)
+		alternate := new(mockRpcClient)
+		primary.On("GetLogs", mock.Anything, query).Return([]types.Log{}, fmt.Errorf("primary failed")).After(50 * time.Millisecond).Once()
+		alternate.On("GetLogs", mock.Anything, query).Return([]types.Log{}, fmt.Errorf("alternate failed")).Once()
+
+		_, err := newHedgedClient(t, primary, alternate).GetLogs(context.TODO(), query)
+		assert.EqualError(t, err, "primary failed")
+	})
+
+	t.Run("long ranges are not hedged", func(t *testing.T) {
+		backfill := &types.FilterLogsQuery{
+			FromBlock: types.BlockNumberFromUint64Ptr(100),
+			ToBlock:   types.BlockNumberFromUint64Ptr(1000),
+		}
+		primary := new(mockRpcClient)
+		alternate := new(mockRpcClient)
+		primary.On("GetLogs", mock.Anything, backfill).Return(logs, nil).After(50 * time.Millisecond).Once()
+
+		_, err := newHedgedClient(t, primary, alternate).GetLogs(context.TODO(), backfill)
+		require.NoError(t, err)
+		alternate.AssertNotCalled(t, "GetLogs", mock.Anything, mock.Anything)
+	})
+
+	t.Run("not hedged without delay", func(t *testing.T) {
+		primary := new(mockRpcClient)
+		alternate := new(mockRpcClient)
+		primary.On("GetLogs", mock.Anything, query).Return([]types.Log{}, fmt.Errorf("failed")).Once()
+
+		f := newHedgedClient(t, primary, alternate)
+		f.HedgeDelay = 0
+		_, err := f.GetLogs(context.TODO(), query)
+		assert.Error(t, err)
+		alternate.AssertNotCalled(t, "GetLogs", mock.Anything, mock.Anything)
+	})
+}
+
+func TestHedgedSendTransaction(t *testing.T) {
+	raw := []byte{0x02, 0x01}
+	signed := &types.Transaction{}
+	hash := types.MustHashFromHex("0x6e4ead0e2ad4c2a0e9b4bb2c2a8a2a5e4b1a5e3b0b0f0e3a6d7e2e8f1f0a1b2c", types.PadNone)
+
+	primary := new(mockTxSigner)
+	alternate := new(mockTxSigner)
+	primary.On("SignTransaction", mock.Anything, mock.Anything).Return(raw, signed, nil).Once()
+	primary.On("SendRawTransaction", mock.Anything, raw).Return(&hash, nil).After(time.Second).Once()
+	alternate.On("SendRawTransaction", mock.Anything, raw).Return(&hash, nil).Once()
+
+	f := newHedgedClient(t, primary, alternate)
+	f.SendTracker = &SendTracker{}
+	got, tx, err := f.SendTransaction(context.TODO(), &types.Transaction{})
+	require.NoError(t, err)
+	assert.Equal(t, &hash, got)
+	assert.Same(t, signed, tx)
+
+	// The transaction is signed once, both endpoints broadcast the same one.
+	primary.AssertNumberOfCalls(t, "SignTransaction", 1)
+	alternate.AssertNotCalled(t, "SignTransaction", mock.Anything, mock.Anything)
+	alternate.AssertExpectations(t)
+}
+
+func TestIsHedgedLogQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query *types.FilterLogsQuery
+		want  bool
+	}{
+		{name: "latest", query: &types.FilterLogsQuery{}, want: true},
+		{name: "recent blocks", query: &types.FilterLogsQuery{FromBlock: types.BlockNumberFromUint64Ptr(990), ToBlock: types.BlockNumberFromUint64Ptr(1000)}, want: true},
+		{name: "long range", query: &types.FilterLogsQuery{FromBlock: types.BlockNumberFromUint64Ptr(100), ToBlock: types.BlockNumberFromUint64Ptr(1000)}, want: false},
+		{name: "from earliest", query: &types.FilterLogsQuery{FromBlock: &types.EarliestBlockNumber}, want: false},
+		{name: "open range", query: &types.FilterLogsQuery{FromBlock: types.BlockNumberFromUint64Ptr(100), ToBlock: &types.LatestBlockNumber}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isHedgedLogQuery(tt.query))
+		})
+	}
+}
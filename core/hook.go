@@ -0,0 +1,45 @@
+package core
+
+import (
+	"github.com/defiweb/go-eth/types"
+)
+
+// ChallengeHook is notified about invalid pokes and their challenges, so programs embedding core can keep
+// their own bookkeeping without parsing logs or metrics. Methods are called synchronously by the challenger,
+// possibly concurrently for different pokes, so they must be safe for concurrent use and return quickly.
+type ChallengeHook interface {
+	// OnPokeDetected is called when the challengeable poke with invalid signature is found, on every tick
+	// it's found while its challenge is not in flight.
+	OnPokeDetected(address types.Address, poke *OpPokedEvent)
+
+	// OnChallengeSubmitted is called once the challenge transaction of the poke is sent.
+	OnChallengeSubmitted(address types.Address, poke *OpPokedEvent, txHash *types.Hash)
+
+	// OnChallengeConfirmed is called once the challenge transaction of the poke succeeded.
+	OnChallengeConfirmed(address types.Address, poke *OpPokedEvent, receipt *types.TransactionReceipt)
+
+	// OnChallengeFailed is called when the challenge of the poke couldn't be submitted or its transaction failed.
+	// Challenges which couldn't be submitted or which transactions were dropped are retried on next ticks while
+	// the challenge window is open, so it may be called several times for the same poke. Reverted transactions
+	// are not retried.
+	OnChallengeFailed(address types.Address, poke *OpPokedEvent, err error)
+}
+
+// NopChallengeHook is the ChallengeHook doing nothing, embed it to implement only some of the methods.
+type NopChallengeHook struct{}
+
+func (NopChallengeHook) OnPokeDetected(types.Address, *OpPokedEvent) {}
+
+func (NopChallengeHook) OnChallengeSubmitted(types.Address, *OpPokedEvent, *types.Hash) {}
+
+func (NopChallengeHook) OnChallengeConfirmed(types.Address, *OpPokedEvent, *types.TransactionReceipt) {
+}
+
+func (NopChallengeHook) OnChallengeFailed(types.Address, *OpPokedEvent, error) {}
+
+// WithChallengeHook makes Challenger notify the hook about invalid pokes and their challenges.
+func WithChallengeHook(hook ChallengeHook) ChallengerOption {
+	return func(c *Challenger) {
+		c.hook = hook
+	}
+}
@@ -0,0 +1,110 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingHook struct {
+	NopChallengeHook
+	mu     sync.Mutex
+	events []string
+}
+
+func (h *recordingHook) record(format string, args ...any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, fmt.Sprintf(format, args...))
+}
+
+func (h *recordingHook) Events() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.events...)
+}
+
+func (h *recordingHook) OnPokeDetected(_ types.Address, poke *OpPokedEvent) {
+	h.record("detected %v", poke.BlockNumber)
+}
+
+func (h *recordingHook) OnChallengeSubmitted(_ types.Address, poke *OpPokedEvent, txHash *types.Hash) {
+	h.record("submitted %v", poke.BlockNumber)
+}
+
+func (h *recordingHook) OnChallengeConfirmed(_ types.Address, poke *OpPokedEvent, _ *types.TransactionReceipt) {
+	h.record("confirmed %v", poke.BlockNumber)
+}
+
+func (h *recordingHook) OnChallengeFailed(_ types.Address, poke *OpPokedEvent, err error) {
+	h.record("failed %v: %v", poke.BlockNumber, err)
+}
+
+func TestChallengeHook(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(500)}
+
+	newProvider := func() *mockScribeOptimisticProvider {
+		p := new(mockScribeOptimisticProvider)
+		p.On("BlockNumber", mock.Anything).Return(big.NewInt(1000), nil)
+		p.On("GetChallengePeriod", mock.Anything, address).Return(600, nil)
+		p.On("GetPokes", mock.Anything, address, mock.Anything, mock.Anything).Return([]*OpPokedEvent{poke}, nil)
+		p.On("GetSuccessfulChallenges", mock.Anything, address, mock.Anything, mock.Anything).
+			Return([]*OpPokeChallengedSuccessfullyEvent{}, nil)
+		p.On("BlockByNumber", mock.Anything, big.NewInt(500)).
+			Return(&types.Block{Number: big.NewInt(500), Timestamp: time.Now()}, nil)
+		p.On("IsPokeSignatureValid", mock.Anything, address, poke).Return(false, nil)
+		p.On("GetFrom", mock.Anything).Return(types.ZeroAddress)
+		return p
+	}
+
+	t.Run("confirmed challenge", func(t *testing.T) {
+		p := newProvider()
+		p.On("ChallengePoke", mock.Anything, address, poke).Return(&txHash, &types.Transaction{}, nil)
+		hook := &recordingHook{}
+		c := NewChallenger(context.TODO(), address, p, 100, nil, WithChallengeHook(hook))
+
+		require.NoError(t, c.executeTick(c.ctx))
+		c.submissions.Wait()
+		// Poke in flight is not reported again.
+		require.NoError(t, c.executeTick(c.ctx))
+		c.submissions.Wait()
+		c.handleChallengeOutcome(TxOutcome{Address: address, Poke: poke, Hash: &txHash})
+
+		assert.Equal(t, []string{"detected 500", "submitted 500", "confirmed 500"}, hook.Events())
+	})
+
+	t.Run("failed submission", func(t *testing.T) {
+		p := newProvider()
+		p.On("ChallengePoke", mock.Anything, address, poke).Return((*types.Hash)(nil), (*types.Transaction)(nil), fmt.Errorf("rpc down"))
+		hook := &recordingHook{}
+		c := NewChallenger(context.TODO(), address, p, 100, nil, WithChallengeHook(hook))
+
+		require.NoError(t, c.executeTick(c.ctx))
+		c.submissions.Wait()
+
+		assert.Equal(t, []string{"detected 500", "failed 500: rpc down"}, hook.Events())
+	})
+
+	t.Run("reverted challenge", func(t *testing.T) {
+		p := newProvider()
+		p.On("ChallengePoke", mock.Anything, address, poke).Return(&txHash, &types.Transaction{}, nil)
+		hook := &recordingHook{}
+		c := NewChallenger(context.TODO(), address, p, 100, nil, WithChallengeHook(hook))
+
+		require.NoError(t, c.executeTick(c.ctx))
+		c.submissions.Wait()
+		c.handleChallengeOutcome(TxOutcome{Address: address, Poke: poke, Hash: &txHash, Err: ErrChallengeReverted})
+
+		assert.Equal(t, []string{"detected 500", "submitted 500", "failed 500: challenge transaction reverted"}, hook.Events())
+	})
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// webhookTimeout is the time limit for delivering a payload to the webhook.
+const webhookTimeout = 10 * time.Second
+
+// ChallengePayload is an executable `opChallenge` call, so an external keeper network can submit the challenge.
+type ChallengePayload struct {
+	Target      types.Address `json:"target"`
+	Calldata    string        `json:"calldata"`
+	BlockNumber uint64        `json:"pokeBlockNumber"`
+	Deadline    time.Time     `json:"deadline"`
+	PokeTxHash  *types.Hash   `json:"pokeTxHash,omitempty"`
+	Instance    string        `json:"instance,omitempty"`
+}
+
+// MaxChallengeTagLength is the maximum length of the challenge tag in bytes.
+const MaxChallengeTagLength = 64
+
+// NewChallengeTag returns the tag identifying challenges of the operator sent by this version of the challenger.
+// The tag is appended to `opChallenge` calldata, so on-chain analytics can attribute challenges to operators,
+// the contract ignores calldata beyond ABI encoded arguments.
+func NewChallengeTag(operator string) ([]byte, error) {
+	tag := []byte(fmt.Sprintf("challenger/%s/%s", GetBuildInfo().Version, operator))
+	if len(tag) > MaxChallengeTagLength {
+		return nil, fmt.Errorf("challenge tag %q is longer than %d bytes", tag, MaxChallengeTagLength)
+	}
+	return tag, nil
+}
+
+// EncodeChallengeCalldata returns calldata of `opChallenge` contract function for the given poke,
+// followed by the tag created by NewChallengeTag, nil for none.
+func EncodeChallengeCalldata(poke *OpPokedEvent, tag []byte) ([]byte, error) {
+	calldata, err := ScribeOptimisticContractABI.Methods["opChallenge"].EncodeArgs(poke.Schnorr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode opChallenge args: %w", err)
+	}
+	return append(calldata, tag...), nil
+}
+
+// PayloadExporter collects challenge payloads instead of submitting transactions.
+// Payloads are served as JSON list over HTTP until their challenge window closes,
+// and optionally pushed to the webhook as soon as they are found.
+type PayloadExporter struct {
+	webhookURL string
+	httpClient *http.Client
+	mu         sync.Mutex
+	payloads   map[string]ChallengePayload
+
+	now func() time.Time
+
+	// Instance is added to payloads, so receivers can tell which deployment found them.
+	Instance string
+	// Tag is appended to calldata of payloads, see NewChallengeTag.
+	Tag []byte
+}
+
+// NewPayloadExporter creates a new instance of PayloadExporter, webhookURL is optional.
+func NewPayloadExporter(webhookURL string) *PayloadExporter {
+	return &PayloadExporter{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: webhookTimeout},
+		payloads:   make(map[string]ChallengePayload),
+		now:        time.Now,
+	}
+}
+
+// Export stores the payload and delivers it to the webhook if one is configured.
+func (e *PayloadExporter) Export(ctx context.Context, payload ChallengePayload) error {
+	key := fmt.Sprintf("%s:%d", payload.Target, payload.BlockNumber)
+	if payload.Instance == "" {
+		payload.Instance = e.Instance
+	}
+
+	e.mu.Lock()
+	_, exists := e.payloads[key]
+	e.payloads[key] = payload
+	e.mu.Unlock()
+
+	if exists || e.webhookURL == "" {
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal challenge payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver challenge payload to webhook: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("webhook responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
+// Payloads returns payloads which challenge window is still open, the most urgent first.
+func (e *PayloadExporter) Payloads() []ChallengePayload {
+	now := e.now()
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]ChallengePayload, 0, len(e.payloads))
+	for key, p := range e.payloads {
+		if !p.Deadline.After(now) {
+			delete(e.payloads, key)
+			continue
+		}
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Deadline.Before(result[j].Deadline)
+	})
+	return result
+}
+
+// ServeHTTP serves pending payloads as JSON list.
+func (e *PayloadExporter) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(e.Payloads()); err != nil {
+		logger.WithError(err).Error("failed to encode challenge payloads")
+	}
+}
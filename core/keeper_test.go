@@ -0,0 +1,72 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPayloadExporter(t *testing.T) {
+	target := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	now := time.Now()
+
+	t.Run("payloads are served until deadline", func(t *testing.T) {
+		e := NewPayloadExporter("")
+		require.NoError(t, e.Export(context.TODO(), ChallengePayload{Target: target, BlockNumber: 2, Deadline: now.Add(time.Minute)}))
+		require.NoError(t, e.Export(context.TODO(), ChallengePayload{Target: target, BlockNumber: 1, Deadline: now.Add(time.Second)}))
+		require.NoError(t, e.Export(context.TODO(), ChallengePayload{Target: target, BlockNumber: 3, Deadline: now.Add(-time.Second)}))
+
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/payloads", nil))
+
+		var payloads []ChallengePayload
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &payloads))
+		require.Len(t, payloads, 2)
+		assert.Equal(t, uint64(1), payloads[0].BlockNumber)
+		assert.Equal(t, uint64(2), payloads[1].BlockNumber)
+	})
+
+	t.Run("payload is delivered to webhook once", func(t *testing.T) {
+		var received []ChallengePayload
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var p ChallengePayload
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+			received = append(received, p)
+		}))
+		defer srv.Close()
+
+		e := NewPayloadExporter(srv.URL)
+		e.Instance = "eu-1"
+		payload := ChallengePayload{Target: target, Calldata: "0x01", BlockNumber: 1, Deadline: now.Add(time.Minute)}
+		require.NoError(t, e.Export(context.TODO(), payload))
+		require.NoError(t, e.Export(context.TODO(), payload))
+		require.Len(t, received, 1)
+		assert.Equal(t, "0x01", received[0].Calldata)
+		assert.Equal(t, "eu-1", received[0].Instance)
+	})
+
+	t.Run("webhook error is returned", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer srv.Close()
+
+		e := NewPayloadExporter(srv.URL)
+		err := e.Export(context.TODO(), ChallengePayload{Target: target, BlockNumber: 1, Deadline: now.Add(time.Minute)})
+		assert.ErrorContains(t, err, "status 500")
+	})
+}
+
+func TestEncodeChallengeCalldata(t *testing.T) {
+	calldata, err := EncodeChallengeCalldata(&OpPokedEvent{BlockNumber: big.NewInt(1)}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, ScribeOptimisticContractABI.Methods["opChallenge"].FourBytes().Bytes(), calldata[:4])
+}
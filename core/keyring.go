@@ -0,0 +1,300 @@
+package core
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	logger "github.com/sirupsen/logrus"
+)
+
+// KeyDrainPollInterval is the interval pending transactions of a rotated out key are checked at.
+var KeyDrainPollInterval = 15 * time.Second
+
+// KeyRing holds the key transactions are sent from by default. Keys rotated out stay in the ring, so transactions
+// sent from their address before, e.g. replacements of stuck challenges reusing their nonce, can still be signed.
+type KeyRing struct {
+	mu      sync.RWMutex
+	current wallet.Key
+	keys    map[types.Address]wallet.Key
+}
+
+// NewKeyRing creates a new instance of KeyRing with the key as the current one. Without a key the ring is read-only,
+// nothing can be signed and its address is the zero address.
+func NewKeyRing(key wallet.Key) *KeyRing {
+	k := &KeyRing{current: key, keys: map[types.Address]wallet.Key{}}
+	if key != nil {
+		k.keys[key.Address()] = key
+	}
+	return k
+}
+
+// Address returns the address of the current key.
+func (k *KeyRing) Address() types.Address {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.current == nil {
+		return types.ZeroAddress
+	}
+	return k.current.Address()
+}
+
+// ReadOnly returns true if the ring has no key.
+func (k *KeyRing) ReadOnly() bool {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.current == nil
+}
+
+// Key returns the key of the address, nil if it's not in the ring.
+func (k *KeyRing) Key(address types.Address) wallet.Key {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.keys[address]
+}
+
+// Use makes the key the current one, transactions without sender are sent from its address from now on.
+func (k *KeyRing) Use(key wallet.Key) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.current = key
+	k.keys[key.Address()] = key
+}
+
+// SigningClient is rpc.Client signing transactions with keys of the KeyRing. Transactions and calls without sender
+// are sent from the current key, so they switch to a new key as soon as it's rotated in.
+// The client must not have keys or default address of its own.
+type SigningClient struct {
+	*rpc.Client
+	Keys *KeyRing
+}
+
+// NewSigningClient creates a new instance of SigningClient.
+func NewSigningClient(client *rpc.Client, keys *KeyRing) *SigningClient {
+	return &SigningClient{Client: client, Keys: keys}
+}
+
+// withSender returns the address given or the one of the current key, if any.
+func (c *SigningClient) withSender(from *types.Address) *types.Address {
+	if from != nil || c.Keys.ReadOnly() {
+		return from
+	}
+	address := c.Keys.Address()
+	return &address
+}
+
+// Accounts returns the address of the current key, none in read-only mode.
+func (c *SigningClient) Accounts(context.Context) ([]types.Address, error) {
+	if c.Keys.ReadOnly() {
+		return nil, nil
+	}
+	return []types.Address{c.Keys.Address()}, nil
+}
+
+// SignTransaction applies transaction modifiers of the client and signs the transaction with the key of its sender.
+func (c *SigningClient) SignTransaction(ctx context.Context, tx *types.Transaction) ([]byte, *types.Transaction, error) {
+	if tx == nil {
+		return nil, nil, fmt.Errorf("transaction is nil")
+	}
+	if c.Keys.ReadOnly() {
+		return nil, nil, ErrReadOnly
+	}
+	tx = tx.Copy()
+	tx.Call.From = c.withSender(tx.Call.From)
+	tx, err := c.Client.PrepareTransaction(ctx, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := c.Keys.Key(*tx.Call.From)
+	if key == nil {
+		return nil, nil, fmt.Errorf("no key found for address %v", tx.Call.From)
+	}
+	if err := key.SignTransaction(ctx, tx); err != nil {
+		return nil, nil, err
+	}
+	raw, err := tx.Raw()
+	if err != nil {
+		return nil, nil, err
+	}
+	return raw, tx, nil
+}
+
+// SendTransaction signs the transaction and sends it.
+func (c *SigningClient) SendTransaction(ctx context.Context, tx *types.Transaction) (*types.Hash, *types.Transaction, error) {
+	raw, tx, err := c.SignTransaction(ctx, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	hash, err := c.SendRawTransaction(ctx, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hash, tx, nil
+}
+
+func (c *SigningClient) Call(ctx context.Context, call *types.Call, block types.BlockNumber) ([]byte, *types.Call, error) {
+	if call != nil {
+		call = call.Copy()
+		call.From = c.withSender(call.From)
+	}
+	return c.Client.Call(ctx, call, block)
+}
+
+func (c *SigningClient) EstimateGas(ctx context.Context, call *types.Call, block types.BlockNumber) (uint64, *types.Call, error) {
+	if call != nil {
+		call = call.Copy()
+		call.From = c.withSender(call.From)
+	}
+	return c.Client.EstimateGas(ctx, call, block)
+}
+
+// KeyRotator switches signing to a new key once its balance is confirmed, without restarting. Transactions pending
+// from the old key are still tracked and replaced with it until they are mined, which is logged.
+type KeyRotator struct {
+	ctx     context.Context
+	client  RPCClient
+	keys    *KeyRing
+	minimum *big.Int
+	mu      sync.Mutex
+
+	// Load, if set, loads the key Reload rotates to, e.g. from the keystore file.
+	Load func() (wallet.Key, error)
+	// OnRotate, if set, is called with the address of the new key once signing switched to it.
+	OnRotate func(address types.Address)
+	// Token is the bearer token requests to the admin API have to present, the API is disabled without it.
+	Token string
+}
+
+// NewKeyRotator creates a new instance of KeyRotator. The balance of a new key has to be at least minimum,
+// or positive if minimum is nil. Old keys are drained until ctx is done.
+func NewKeyRotator(ctx context.Context, client RPCClient, keys *KeyRing, minimum *big.Int) *KeyRotator {
+	return &KeyRotator{ctx: ctx, client: client, keys: keys, minimum: minimum}
+}
+
+// Rotate switches signing to the key if its balance is enough to pay for challenges.
+func (r *KeyRotator) Rotate(ctx context.Context, key wallet.Key) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.keys.Address()
+	if key.Address() == old {
+		return ErrKeyUnchanged
+	}
+	balance, err := r.client.GetBalance(ctx, key.Address(), types.LatestBlockNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get balance of %v: %w: %w", key.Address(), ErrRPCUnavailable, err)
+	}
+	minimum := r.minimum
+	if minimum == nil {
+		minimum = big.NewInt(1)
+	}
+	if balance.Cmp(minimum) < 0 {
+		return fmt.Errorf("%w: balance %s of %v is below %s", ErrInsufficientBalance, NativeToken.Format(balance), key.Address(), NativeToken.Format(minimum))
+	}
+
+	r.keys.Use(key)
+	logger.
+		WithField("from", old).
+		WithField("to", key.Address()).
+		Infof("Signing switched to the new key with balance %s", NativeToken.Format(balance))
+	if r.OnRotate != nil {
+		r.OnRotate(key.Address())
+	}
+	go r.drain(old)
+	return nil
+}
+
+// Reload loads the key using Load and rotates to it.
+func (r *KeyRotator) Reload(ctx context.Context) (types.Address, error) {
+	if r.Load == nil {
+		return types.ZeroAddress, fmt.Errorf("key loading is not configured")
+	}
+	key, err := r.Load()
+	if err != nil {
+		return types.ZeroAddress, fmt.Errorf("failed to load key: %w", err)
+	}
+	if err := r.Rotate(ctx, key); err != nil {
+		return types.ZeroAddress, err
+	}
+	return key.Address(), nil
+}
+
+// drain waits until the rotated out key has no pending transactions.
+func (r *KeyRotator) drain(address types.Address) {
+	fetcher, ok := r.client.(NonceFetcher)
+	if !ok {
+		logger.
+			WithField("from", address).
+			Warnf("Pending transactions of the old key can't be watched, keep it funded until they are mined")
+		return
+	}
+	ticker := time.NewTicker(KeyDrainPollInterval)
+	defer ticker.Stop()
+	for {
+		pending, err := fetcher.GetTransactionCount(r.ctx, address, types.PendingBlockNumber)
+		if err == nil {
+			var mined uint64
+			mined, err = fetcher.GetTransactionCount(r.ctx, address, types.LatestBlockNumber)
+			if err == nil && mined >= pending {
+				logger.
+					WithField("from", address).
+					Infof("All transactions of the old key are mined, it can be retired")
+				return
+			}
+			if err == nil {
+				logger.
+					WithField("from", address).
+					Infof("Waiting for %d pending transactions of the old key", pending-mined)
+			}
+		}
+		if err != nil {
+			logger.
+				WithField("from", address).
+				Errorf("Failed to get transaction count of the old key with error: %v", err)
+		}
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServeHTTP reloads the key on POST requests presenting the Token as bearer token.
+// The address of the new key is returned as JSON, 409 Conflict if the key is unchanged or its balance is too low.
+func (r *KeyRotator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if r.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(r.Token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	address, err := r.Reload(req.Context())
+	switch {
+	case errors.Is(err, ErrKeyUnchanged) || errors.Is(err, ErrInsufficientBalance):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		logger.Errorf("Failed to rotate key with error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(struct {
+		Address types.Address `json:"address"`
+	}{address})
+}
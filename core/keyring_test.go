@@ -0,0 +1,197 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/rpc"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// nopTransport fails every request, transactions in tests are complete and only signed.
+type nopTransport struct{}
+
+func (nopTransport) Call(context.Context, any, string, ...any) error {
+	return fmt.Errorf("not connected")
+}
+
+func newTestTransaction(from *types.Address) *types.Transaction {
+	tx := types.NewTransaction().
+		SetType(types.LegacyTxType).
+		SetTo(types.MustAddressFromHex("0x1234567890123456789012345678901234567890")).
+		SetGasLimit(21000).
+		SetGasPrice(big.NewInt(1)).
+		SetNonce(1).
+		SetChainID(1)
+	tx.Call.From = from
+	return tx
+}
+
+func TestSigningClient(t *testing.T) {
+	old := wallet.NewRandomKey()
+	current := wallet.NewRandomKey()
+	keys := NewKeyRing(old)
+	keys.Use(current)
+
+	client, err := rpc.NewClient(rpc.WithTransport(nopTransport{}))
+	require.NoError(t, err)
+	c := NewSigningClient(client, keys)
+
+	accounts, err := c.Accounts(context.TODO())
+	require.NoError(t, err)
+	assert.Equal(t, []types.Address{current.Address()}, accounts)
+
+	t.Run("transaction without sender is signed by current key", func(t *testing.T) {
+		_, tx, err := c.SignTransaction(context.TODO(), newTestTransaction(nil))
+		require.NoError(t, err)
+		signer, err := crypto.ECRecoverer.RecoverTransaction(tx)
+		require.NoError(t, err)
+		assert.Equal(t, current.Address(), *signer)
+	})
+
+	t.Run("replacement is signed by old key", func(t *testing.T) {
+		from := old.Address()
+		_, tx, err := c.SignTransaction(context.TODO(), newTestTransaction(&from))
+		require.NoError(t, err)
+		signer, err := crypto.ECRecoverer.RecoverTransaction(tx)
+		require.NoError(t, err)
+		assert.Equal(t, old.Address(), *signer)
+	})
+
+	t.Run("unknown sender", func(t *testing.T) {
+		from := wallet.NewRandomKey().Address()
+		_, _, err := c.SignTransaction(context.TODO(), newTestTransaction(&from))
+		assert.ErrorContains(t, err, "no key found")
+	})
+}
+
+func TestKeyRotator(t *testing.T) {
+	minimum := big.NewInt(1000)
+
+	t.Run("rotates to funded key", func(t *testing.T) {
+		old := wallet.NewRandomKey()
+		next := wallet.NewRandomKey()
+		client := new(mockRpcClient)
+		client.On("GetBalance", mock.Anything, next.Address(), types.LatestBlockNumber).Return(big.NewInt(1000), nil)
+
+		keys := NewKeyRing(old)
+		r := NewKeyRotator(context.TODO(), client, keys, minimum)
+		var rotated types.Address
+		r.OnRotate = func(address types.Address) { rotated = address }
+		require.NoError(t, r.Rotate(context.TODO(), next))
+		assert.Equal(t, next.Address(), keys.Address())
+		assert.Equal(t, next.Address(), rotated)
+		assert.NotNil(t, keys.Key(old.Address()))
+	})
+
+	t.Run("refuses key with low balance", func(t *testing.T) {
+		old := wallet.NewRandomKey()
+		next := wallet.NewRandomKey()
+		client := new(mockRpcClient)
+		client.On("GetBalance", mock.Anything, next.Address(), types.LatestBlockNumber).Return(big.NewInt(999), nil)
+
+		keys := NewKeyRing(old)
+		err := NewKeyRotator(context.TODO(), client, keys, minimum).Rotate(context.TODO(), next)
+		assert.ErrorIs(t, err, ErrInsufficientBalance)
+		assert.Equal(t, old.Address(), keys.Address())
+		assert.Nil(t, keys.Key(next.Address()))
+	})
+
+	t.Run("refuses unchanged key", func(t *testing.T) {
+		key := wallet.NewRandomKey()
+		client := new(mockRpcClient)
+		err := NewKeyRotator(context.TODO(), client, NewKeyRing(key), minimum).Rotate(context.TODO(), key)
+		assert.ErrorIs(t, err, ErrKeyUnchanged)
+		client.AssertNotCalled(t, "GetBalance", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestKeyRotatorServeHTTP(t *testing.T) {
+	old := wallet.NewRandomKey()
+	next := wallet.NewRandomKey()
+	client := new(mockRpcClient)
+	client.On("GetBalance", mock.Anything, next.Address(), types.LatestBlockNumber).Return(big.NewInt(1), nil)
+
+	r := NewKeyRotator(context.TODO(), client, NewKeyRing(old), nil)
+	r.Token = "secret"
+	r.Load = func() (wallet.Key, error) { return next, nil }
+
+	tests := []struct {
+		name   string
+		method string
+		token  string
+		want   int
+	}{
+		{name: "missing token", method: http.MethodPost, want: http.StatusUnauthorized},
+		{name: "wrong token", method: http.MethodPost, token: "wrong", want: http.StatusUnauthorized},
+		{name: "wrong method", method: http.MethodGet, token: "secret", want: http.StatusMethodNotAllowed},
+		{name: "rotated", method: http.MethodPost, token: "secret", want: http.StatusOK},
+		{name: "unchanged", method: http.MethodPost, token: "secret", want: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/admin/reload-key", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			assert.Equal(t, tt.want, rec.Code)
+			if tt.want == http.StatusOK {
+				assert.JSONEq(t, fmt.Sprintf(`{"address":%q}`, next.Address().String()), rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestSigningClientReadOnly(t *testing.T) {
+	client, err := rpc.NewClient(rpc.WithTransport(nopTransport{}))
+	require.NoError(t, err)
+	c := NewSigningClient(client, NewKeyRing(nil))
+
+	accounts, err := c.Accounts(context.TODO())
+	require.NoError(t, err)
+	assert.Empty(t, accounts)
+	assert.Equal(t, types.ZeroAddress, c.Keys.Address())
+
+	_, _, err = c.SendTransaction(context.TODO(), newTestTransaction(nil))
+	assert.ErrorIs(t, err, ErrReadOnly)
+}
+
+func TestKeyRotationMonitors(t *testing.T) {
+	old := wallet.NewRandomKey()
+	next := wallet.NewRandomKey()
+	client := new(mockNonceClient)
+	client.On("GetBalance", mock.Anything, next.Address(), types.LatestBlockNumber).Return(big.NewInt(1), nil)
+	client.On("BlockNumber", mock.Anything).Return(big.NewInt(100), nil)
+	client.On("GetTransactionCount", mock.Anything, next.Address(), mock.Anything).Return(uint64(3), nil)
+
+	nonceMonitor, err := NewNonceMonitor(client, old.Address(), DefaultNonceGapBlocks, NonceRepairAlert)
+	require.NoError(t, err)
+	detector := NewDuplicateDetector(client, old.Address(), &SendTracker{})
+
+	// The rotator's client can't fetch nonces, so the old key isn't drained in the background.
+	r := NewKeyRotator(context.TODO(), &client.mockRpcClient, NewKeyRing(old), nil)
+	r.OnRotate = func(address types.Address) {
+		nonceMonitor.SetFrom(address)
+		detector.SetFrom(address)
+	}
+	require.NoError(t, r.Rotate(context.TODO(), next))
+
+	require.NoError(t, nonceMonitor.Check(context.TODO()))
+	_, err = detector.Check(context.TODO())
+	require.NoError(t, err)
+	client.AssertCalled(t, "GetTransactionCount", mock.Anything, next.Address(), types.LatestBlockNumber)
+	client.AssertCalled(t, "GetTransactionCount", mock.Anything, next.Address(), types.PendingBlockNumber)
+	client.AssertNotCalled(t, "GetTransactionCount", mock.Anything, old.Address(), mock.Anything)
+	client.AssertNumberOfCalls(t, "GetTransactionCount", 3)
+}
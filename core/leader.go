@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+// DefaultLeaderLease is the default time the leadership is held without renewal.
+const DefaultLeaderLease = 15 * time.Second
+
+// renewLeaseScript extends the lease only if it's still held by the given instance.
+const renewLeaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`
+
+// releaseLeaseScript releases the lease only if it's still held by the given instance.
+const releaseLeaseScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+
+// LeaderElector decides whether this instance is allowed to submit challenge transactions.
+// Instances which are not leaders keep scanning and verifying pokes in standby.
+type LeaderElector interface {
+	// IsLeader returns true if this instance currently holds the leadership.
+	IsLeader() bool
+}
+
+// RedisLeaderElector implements LeaderElector using a lease key in Redis.
+// The leader renews the lease every third of its duration, if it dies, the lease expires and
+// a standby instance takes over within one lease duration.
+type RedisLeaderElector struct {
+	client *RedisClient
+	key    string
+	id     string
+	lease  time.Duration
+	leader atomic.Bool
+}
+
+// NewRedisLeaderElector creates a new instance of RedisLeaderElector, id has to be unique per instance.
+func NewRedisLeaderElector(client *RedisClient, key, id string, lease time.Duration) *RedisLeaderElector {
+	if lease <= 0 {
+		lease = DefaultLeaderLease
+	}
+	return &RedisLeaderElector{
+		client: client,
+		key:    key,
+		id:     id,
+		lease:  lease,
+	}
+}
+
+// IsLeader implements LeaderElector interface.
+func (e *RedisLeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run campaigns for the leadership until the context is canceled, then releases the lease if held.
+func (e *RedisLeaderElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		e.campaign(ctx)
+
+		select {
+		case <-ctx.Done():
+			e.release()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign acquires or renews the lease and updates leadership state.
+func (e *RedisLeaderElector) campaign(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, e.lease/3)
+	defer cancel()
+
+	leader, err := e.tryAcquire(ctx)
+	if err != nil {
+		// We can't prove we still hold the lease, so step down to avoid duplicate submissions.
+		logger.
+			WithField("instance", e.id).
+			Errorf("Failed to campaign for leadership with error: %v", err)
+		leader = false
+	}
+	if leader != e.leader.Swap(leader) {
+		if leader {
+			logger.WithField("instance", e.id).Warnf("Became leader, challenges will be submitted by this instance")
+		} else {
+			logger.WithField("instance", e.id).Warnf("Lost leadership, switching to standby")
+		}
+	}
+	if leader {
+		LeaderGauge.WithLabelValues(e.id).Set(1)
+	} else {
+		LeaderGauge.WithLabelValues(e.id).Set(0)
+	}
+}
+
+func (e *RedisLeaderElector) tryAcquire(ctx context.Context) (bool, error) {
+	ttl := strconv.FormatInt(e.lease.Milliseconds(), 10)
+
+	if e.leader.Load() {
+		res, err := e.client.Do(ctx, "EVAL", renewLeaseScript, "1", e.key, e.id, ttl)
+		if err != nil {
+			return false, fmt.Errorf("failed to renew lease: %w", err)
+		}
+		if n, ok := res.(int64); ok && n == 1 {
+			return true, nil
+		}
+		// Lease expired and was taken by another instance, try to acquire it again below.
+	}
+
+	res, err := e.client.Do(ctx, "SET", e.key, e.id, "NX", "PX", ttl)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lease: %w", err)
+	}
+	return res == "OK", nil
+}
+
+func (e *RedisLeaderElector) release() {
+	if !e.leader.Swap(false) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), e.lease/3)
+	defer cancel()
+
+	if _, err := e.client.Do(ctx, "EVAL", releaseLeaseScript, "1", e.key, e.id); err != nil {
+		logger.
+			WithField("instance", e.id).
+			Errorf("Failed to release leadership with error: %v", err)
+	}
+	LeaderGauge.WithLabelValues(e.id).Set(0)
+}
@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisLeaderElector(t *testing.T) {
+	srv := newFakeRedis(t)
+	newElector := func(id string) *RedisLeaderElector {
+		c, err := NewRedisClient(srv.URL())
+		require.NoError(t, err)
+		t.Cleanup(func() { c.Close() })
+		return NewRedisLeaderElector(c, "challenger-leader", id, 300*time.Millisecond)
+	}
+
+	a := newElector("a")
+	b := newElector("b")
+
+	// First instance acquires the lease, second one stays in standby.
+	a.campaign(context.TODO())
+	b.campaign(context.TODO())
+	assert.True(t, a.IsLeader())
+	assert.False(t, b.IsLeader())
+
+	// Leader keeps the lease by renewing it.
+	a.campaign(context.TODO())
+	assert.True(t, a.IsLeader())
+
+	// Standby takes over once the leader stops renewing and the lease expires.
+	time.Sleep(350 * time.Millisecond)
+	b.campaign(context.TODO())
+	assert.True(t, b.IsLeader())
+
+	a.campaign(context.TODO())
+	assert.False(t, a.IsLeader())
+
+	// Released lease can be acquired immediately.
+	b.release()
+	assert.False(t, b.IsLeader())
+	a.campaign(context.TODO())
+	assert.True(t, a.IsLeader())
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"context"
+	"math/big"
+)
+
+// watchRegularPokes publishes the age of the latest update of the feed, by either regular or optimistic poke.
+// Failing to fetch regular pokes doesn't fail the tick, only the liveness gauge isn't updated.
+func (c *Challenger) watchRegularPokes(ctx context.Context, fromBlock *big.Int, toBlock *big.Int, opPokes []*OpPokedEvent) {
+	if !c.regularPokes || fromBlock.Cmp(toBlock) > 0 {
+		return
+	}
+
+	pokes, err := c.provider.GetRegularPokes(ctx, c.address, fromBlock, toBlock)
+	if err != nil {
+		c.log().Errorf("Failed to get Poked events with error: %v", err)
+		return
+	}
+	for _, poke := range pokes {
+		c.lastUpdateAge = max(c.lastUpdateAge, poke.Age)
+	}
+	for _, poke := range opPokes {
+		c.lastUpdateAge = max(c.lastUpdateAge, poke.PokeData.Age)
+	}
+	if c.lastUpdateAge == 0 {
+		// No update seen yet, leaving the gauge unset rather than reporting the feed stale since 1970.
+		return
+	}
+	LastUpdateAgeGauge.WithLabelValues(c.address.String()).Set(float64(c.lastUpdateAge))
+}
@@ -0,0 +1,42 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestWatchRegularPokes(t *testing.T) {
+	address := types.MustAddressFromHex("0x8F7acDa376eF37EC371235a094113dF9Cb4EfEe8")
+	provider := new(mockScribeOptimisticProvider)
+	c := NewChallenger(context.TODO(), address, provider, 0, nil, WithRegularPokeWatching())
+	gauge := LastUpdateAgeGauge.WithLabelValues(address.String())
+
+	provider.On("GetRegularPokes", mock.Anything, address, big.NewInt(1), big.NewInt(10)).
+		Return([]*PokedEvent{{BlockNumber: big.NewInt(5), Age: 1_700_000_000}}, nil).Once()
+	c.watchRegularPokes(context.TODO(), big.NewInt(1), big.NewInt(10), nil)
+	assert.Equal(t, float64(1_700_000_000), testutil.ToFloat64(gauge))
+
+	// Optimistic poke is an update too.
+	provider.On("GetRegularPokes", mock.Anything, address, big.NewInt(10), big.NewInt(20)).
+		Return([]*PokedEvent{}, nil).Once()
+	opPokes := []*OpPokedEvent{{BlockNumber: big.NewInt(15), PokeData: PokeData{Age: 1_700_000_100}}}
+	c.watchRegularPokes(context.TODO(), big.NewInt(10), big.NewInt(20), opPokes)
+	assert.Equal(t, float64(1_700_000_100), testutil.ToFloat64(gauge))
+
+	// Failure keeps the last known update.
+	provider.On("GetRegularPokes", mock.Anything, address, big.NewInt(20), big.NewInt(30)).
+		Return([]*PokedEvent{}, fmt.Errorf("rpc error")).Once()
+	c.watchRegularPokes(context.TODO(), big.NewInt(20), big.NewInt(30), nil)
+	assert.Equal(t, float64(1_700_000_100), testutil.ToFloat64(gauge))
+
+	// Regular pokes are not fetched unless enabled.
+	NewChallenger(context.TODO(), address, provider, 0, nil).watchRegularPokes(context.TODO(), big.NewInt(1), big.NewInt(10), nil)
+	provider.AssertNumberOfCalls(t, "GetRegularPokes", 3)
+}
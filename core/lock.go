@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+)
+
+// DefaultChallengeLockTTL is used when the remaining challenge window of the poke is unknown.
+const DefaultChallengeLockTTL = 30 * time.Minute
+
+// ChallengeLock is consulted by cooperating challenger instances before submitting a challenge,
+// so only one of them spends gas on the same poke.
+type ChallengeLock interface {
+	// TryLock returns true if this instance acquired the right to challenge the poke.
+	TryLock(ctx context.Context, address types.Address, poke *OpPokedEvent, ttl time.Duration) (bool, error)
+	// Unlock releases the lock held by this instance, so other instances can retry the challenge.
+	Unlock(ctx context.Context, address types.Address, poke *OpPokedEvent) error
+}
+
+// RedisChallengeLock implements ChallengeLock using SET NX keys in Redis
+// keyed by contract address, poke block and schnorr signature hash.
+type RedisChallengeLock struct {
+	client *RedisClient
+	prefix string
+	id     string
+}
+
+// NewRedisChallengeLock creates a new instance of RedisChallengeLock, id has to be unique per instance.
+func NewRedisChallengeLock(client *RedisClient, prefix, id string) *RedisChallengeLock {
+	return &RedisChallengeLock{
+		client: client,
+		prefix: prefix,
+		id:     id,
+	}
+}
+
+// TryLock implements ChallengeLock interface.
+func (l *RedisChallengeLock) TryLock(
+	ctx context.Context,
+	address types.Address,
+	poke *OpPokedEvent,
+	ttl time.Duration,
+) (bool, error) {
+	if ttl <= 0 {
+		ttl = DefaultChallengeLockTTL
+	}
+	res, err := l.client.Do(ctx, "SET", l.key(address, poke), l.id, "NX", "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire challenge lock: %w", err)
+	}
+	return res == "OK", nil
+}
+
+// Unlock implements ChallengeLock interface.
+func (l *RedisChallengeLock) Unlock(ctx context.Context, address types.Address, poke *OpPokedEvent) error {
+	_, err := l.client.Do(ctx, "EVAL", releaseLeaseScript, "1", l.key(address, poke), l.id)
+	if err != nil {
+		return fmt.Errorf("failed to release challenge lock: %w", err)
+	}
+	return nil
+}
+
+func (l *RedisChallengeLock) key(address types.Address, poke *OpPokedEvent) string {
+	return fmt.Sprintf("%s:%s:%d:%s", l.prefix, address, poke.BlockNumber, SchnorrHash(poke.Schnorr))
+}
+
+// SchnorrHash returns keccak256 hash of the schnorr data, it identifies the poke signature.
+func SchnorrHash(s SchnorrData) types.Hash {
+	return crypto.Keccak256(s.Signature[:], s.Commitment.Bytes(), s.SignersBlob)
+}
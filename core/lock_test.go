@@ -0,0 +1,63 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisChallengeLock(t *testing.T) {
+	srv := newFakeRedis(t)
+	newLock := func(id string) *RedisChallengeLock {
+		c, err := NewRedisClient(srv.URL())
+		require.NoError(t, err)
+		t.Cleanup(func() { c.Close() })
+		return NewRedisChallengeLock(c, "challenger-lock", id)
+	}
+
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(100), Schnorr: SchnorrData{Signature: [32]byte{1}}}
+	otherSig := &OpPokedEvent{BlockNumber: big.NewInt(100), Schnorr: SchnorrData{Signature: [32]byte{2}}}
+
+	a := newLock("a")
+	b := newLock("b")
+
+	ok, err := a.TryLock(context.TODO(), address, poke, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Same poke is locked for other instances.
+	ok, err = b.TryLock(context.TODO(), address, poke, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Different signature in the same block is a different poke.
+	ok, err = b.TryLock(context.TODO(), address, otherSig, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Only the holder can release the lock.
+	require.NoError(t, b.Unlock(context.TODO(), address, poke))
+	ok, err = b.TryLock(context.TODO(), address, poke, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, a.Unlock(context.TODO(), address, poke))
+	ok, err = b.TryLock(context.TODO(), address, poke, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// Lock expires with the challenge window.
+	ok, err = a.TryLock(context.TODO(), address, &OpPokedEvent{BlockNumber: big.NewInt(101)}, 50*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	time.Sleep(100 * time.Millisecond)
+	ok, err = b.TryLock(context.TODO(), address, &OpPokedEvent{BlockNumber: big.NewInt(101)}, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+// Categories of noisy debug messages which can be sampled.
+const (
+	// LogCategoryTick covers per-tick messages of the processing loop, e.g. "No logs found".
+	LogCategoryTick = "tick"
+	// LogCategoryCall covers `cast call` equivalents of contract calls.
+	LogCategoryCall = "call"
+)
+
+// LogSampleOnChange is the sampling rule logging the message only when it differs from the previous one.
+const LogSampleOnChange = "change"
+
+type logSampleState struct {
+	count uint64
+	last  string
+}
+
+// logSampler suppresses repeated debug messages according to rules configured per category.
+type logSampler struct {
+	mu     sync.Mutex
+	every  map[string]uint64
+	change map[string]bool
+	states map[string]*logSampleState
+}
+
+var sampler = &logSampler{
+	every:  make(map[string]uint64),
+	change: make(map[string]bool),
+	states: make(map[string]*logSampleState),
+}
+
+// SetLogSampling configures sampling of debug messages of the category.
+// Rule is either a number N to log every Nth message, or "change" to log the message only when it changes.
+// Messages are sampled separately for each address.
+func SetLogSampling(category string, rule string) error {
+	if category != LogCategoryTick && category != LogCategoryCall {
+		return fmt.Errorf("unknown log category %q, possible values are: %s, %s", category, LogCategoryTick, LogCategoryCall)
+	}
+
+	sampler.mu.Lock()
+	defer sampler.mu.Unlock()
+	delete(sampler.every, category)
+	delete(sampler.change, category)
+	if rule == LogSampleOnChange {
+		sampler.change[category] = true
+		return nil
+	}
+	n, err := strconv.ParseUint(rule, 10, 64)
+	if err != nil || n == 0 {
+		return fmt.Errorf("invalid log sampling rule %q, expected positive number or %q", rule, LogSampleOnChange)
+	}
+	sampler.every[category] = n
+	return nil
+}
+
+// ParseLogSampling parses "category=rule" pairs and applies them with SetLogSampling.
+func ParseLogSampling(pairs []string) error {
+	for _, p := range pairs {
+		category, rule, ok := strings.Cut(p, "=")
+		if !ok {
+			return fmt.Errorf("invalid log sampling %q, expected category=rule", p)
+		}
+		if err := SetLogSampling(category, rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sampledDebugf logs the debug message unless it's suppressed by the sampling rule of the category.
+func sampledDebugf(category string, entry *logger.Entry, format string, args ...any) {
+	if !entry.Logger.IsLevelEnabled(logger.DebugLevel) {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if sampler.allow(category, fmt.Sprint(entry.Data["address"])+"|"+format, msg) {
+		entry.Debug(msg)
+	}
+}
+
+func (s *logSampler) allow(category, key, msg string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	every, change := s.every[category], s.change[category]
+	if every == 0 && !change {
+		return true
+	}
+	st, ok := s.states[category+"|"+key]
+	if !ok {
+		st = &logSampleState{}
+		s.states[category+"|"+key] = st
+	}
+	if change {
+		if ok && st.last == msg {
+			return false
+		}
+		st.last = msg
+		return true
+	}
+	st.count++
+	return (st.count-1)%every == 0
+}
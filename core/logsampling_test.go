@@ -0,0 +1,60 @@
+package core
+
+import (
+	"testing"
+
+	logger "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogSampling(t *testing.T) {
+	t.Cleanup(func() {
+		sampler.mu.Lock()
+		defer sampler.mu.Unlock()
+		clear(sampler.every)
+		clear(sampler.change)
+		clear(sampler.states)
+	})
+
+	log, hook := test.NewNullLogger()
+	log.SetLevel(logger.DebugLevel)
+	messages := func() []string {
+		var result []string
+		for _, e := range hook.AllEntries() {
+			result = append(result, e.Message)
+		}
+		hook.Reset()
+		return result
+	}
+
+	t.Run("every Nth message per address", func(t *testing.T) {
+		require.NoError(t, ParseLogSampling([]string{"tick=3"}))
+		for i := 0; i < 4; i++ {
+			sampledDebugf(LogCategoryTick, log.WithField("address", "a"), "No logs found %d", i)
+			sampledDebugf(LogCategoryTick, log.WithField("address", "b"), "No logs found %d", i)
+		}
+		assert.Equal(t, []string{"No logs found 0", "No logs found 0", "No logs found 3", "No logs found 3"}, messages())
+
+		// Other categories are not sampled.
+		sampledDebugf(LogCategoryCall, log.WithField("address", "a"), "cast call")
+		sampledDebugf(LogCategoryCall, log.WithField("address", "a"), "cast call")
+		assert.Len(t, messages(), 2)
+	})
+
+	t.Run("only changes", func(t *testing.T) {
+		require.NoError(t, SetLogSampling(LogCategoryCall, LogSampleOnChange))
+		for _, v := range []int{1, 1, 2, 2, 1} {
+			sampledDebugf(LogCategoryCall, log.WithField("address", "a"), "cast call %d", v)
+		}
+		assert.Equal(t, []string{"cast call 1", "cast call 2", "cast call 1"}, messages())
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		assert.Error(t, ParseLogSampling([]string{"tick"}))
+		assert.Error(t, SetLogSampling("unknown", "2"))
+		assert.Error(t, SetLogSampling(LogCategoryTick, "0"))
+		assert.Error(t, SetLogSampling(LogCategoryTick, "often"))
+	})
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ProviderFactory creates the provider used by the Challenger of the address.
+type ProviderFactory func(address types.Address) IScribeOptimisticProvider
+
+// Manager owns the lifecycle of Challenger instances, one per monitored address.
+type Manager struct {
+	addresses   []types.Address
+	newProvider ProviderFactory
+	fromBlock   int64
+	opts        []ChallengerOption
+	challengers []*Challenger
+	mu          sync.Mutex
+
+	// Registerer receives all challenger metrics when Run starts, nil skips the registration.
+	Registerer prometheus.Registerer
+}
+
+// NewManager creates a new instance of Manager, opts are applied to every Challenger it creates.
+func NewManager(
+	addresses []types.Address,
+	newProvider ProviderFactory,
+	fromBlock int64,
+	opts ...ChallengerOption,
+) *Manager {
+	return &Manager{
+		addresses:   addresses,
+		newProvider: newProvider,
+		fromBlock:   fromBlock,
+		opts:        opts,
+	}
+}
+
+// Metrics returns all collectors of the challenger metrics.
+func Metrics() []prometheus.Collector {
+	return []prometheus.Collector{
+		ChallengeCounter,
+		ErrorsCounter,
+		LastScannedBlockGauge,
+		RPCHealthyGauge,
+		RPCFailoverCounter,
+		ChallengeWindowRemainingGauge,
+		SweptRewardsCounter,
+		LeaderGauge,
+		BuildInfoGauge,
+		ChallengeRevertsCounter,
+		MonitoredAddressesGauge,
+		LastTickTimestampGauge,
+		TickIntervalGauge,
+		LoopDegradedGauge,
+		LoopRestartsCounter,
+		PanicsCounter,
+		LastPokeValueGauge,
+		LastPokeAgeGauge,
+		LastUpdateAgeGauge,
+		SuspiciousPokesCounter,
+		ChallengePeriodGauge,
+		ContractStateChangesCounter,
+		NonceGapGauge,
+		NonceRepairsCounter,
+		GasEstimationFailuresCounter,
+		PriceDeviationGauge,
+		PriceDeviationAlertsCounter,
+		SafePendingProposalsGauge,
+		SafeDeadlineAlertsCounter,
+		ChallengeBudgetExhaustedGauge,
+		ChallengeBudgetExhaustedCounter,
+		SignatureDiscrepanciesCounter,
+		ChallengeWindowExpiringGauge,
+		ChallengeWindowExpiringCounter,
+		SequencerUpGauge,
+		AccountBalanceGauge,
+		AccountBalanceLowGauge,
+		DuplicateInstanceGauge,
+		ChallengeEstimatedRewardGauge,
+		ChallengeEstimatedCostGauge,
+		ChallengeEstimatedNetGauge,
+		RPCHedgedRequestsCounter,
+		PokesAwaitingVerificationGauge,
+		ChallengesAwaitingConfirmationGauge,
+		InvalidPokesCounter,
+	}
+}
+
+// RegisterMetrics registers all challenger metrics, metrics registered before are skipped.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	for _, c := range Metrics() {
+		err := registerer.Register(c)
+		if err != nil && !errors.As(err, &prometheus.AlreadyRegisteredError{}) {
+			return fmt.Errorf("failed to register metrics: %w", err)
+		}
+	}
+	return nil
+}
+
+// Run starts a supervised Challenger for each address and blocks until ctx is done
+// and all of them have stopped.
+func (m *Manager) Run(ctx context.Context) error {
+	if len(m.addresses) == 0 {
+		return errors.New("no addresses to monitor")
+	}
+	if m.Registerer != nil {
+		if err := RegisterMetrics(m.Registerer); err != nil {
+			return err
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i, address := range m.addresses {
+		wg.Add(1)
+
+		c := NewChallenger(ctx, address, m.newProvider(address), m.fromBlock, &wg, m.opts...)
+		c.tickOffset = tickOffset(i, len(m.addresses))
+		m.mu.Lock()
+		m.challengers = append(m.challengers, c)
+		m.mu.Unlock()
+		// Failures of the loop are restarted by the challenger itself, so Run only returns once ctx is done.
+		go c.Run() //nolint:errcheck
+	}
+	wg.Wait()
+	return nil
+}
+
+// tickOffset spreads ticks of n addresses evenly over TickInterval, so they don't hit the RPC at the same time.
+func tickOffset(i int, n int) time.Duration {
+	return TickInterval * time.Duration(i) / time.Duration(n)
+}
+
+// TriggerTick makes challengers of all addresses execute a tick right away, e.g. after recovering from RPC outage.
+func (m *Manager) TriggerTick() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range m.challengers {
+		c.TriggerTick()
+	}
+}
@@ -0,0 +1,106 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager(t *testing.T) {
+	addresses := []types.Address{
+		types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1"),
+		types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEe2"),
+	}
+
+	t.Run("runs challenger for each address until ctx is done", func(t *testing.T) {
+		var created []types.Address
+		newProvider := func(address types.Address) IScribeOptimisticProvider {
+			created = append(created, address)
+			p := new(mockScribeOptimisticProvider)
+			p.On("BlockNumber", mock.Anything).Return((*big.Int)(nil), fmt.Errorf("rpc down"))
+			p.On("GetFrom", mock.Anything).Return(types.ZeroAddress)
+			return p
+		}
+
+		registry := prometheus.NewRegistry()
+		m := NewManager(addresses, newProvider, 100)
+		m.Registerer = registry
+
+		ctx, cancel := context.WithCancel(context.Background())
+		before := testutil.ToFloat64(MonitoredAddressesGauge)
+		done := make(chan error)
+		go func() {
+			done <- m.Run(ctx)
+		}()
+
+		assert.Eventually(t, func() bool {
+			return testutil.ToFloat64(MonitoredAddressesGauge) == before+2
+		}, time.Second, 10*time.Millisecond)
+		cancel()
+		select {
+		case err := <-done:
+			require.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("manager didn't stop")
+		}
+		assert.Equal(t, before, testutil.ToFloat64(MonitoredAddressesGauge))
+		assert.Equal(t, addresses, created)
+
+		// Metrics registered before are skipped.
+		require.NoError(t, RegisterMetrics(registry))
+	})
+
+	t.Run("instance label is added to all metrics", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		require.NoError(t, RegisterMetrics(WithInstanceLabel(registry, "eu-1")))
+
+		families, err := registry.Gather()
+		require.NoError(t, err)
+		require.NotEmpty(t, families)
+		for _, f := range families {
+			for _, m := range f.GetMetric() {
+				var instance string
+				for _, l := range m.GetLabel() {
+					if l.GetName() == InstanceLabelName {
+						instance = l.GetValue()
+					}
+				}
+				assert.Equal(t, "eu-1", instance, f.GetName())
+			}
+		}
+	})
+
+	t.Run("fails without addresses", func(t *testing.T) {
+		m := NewManager(nil, nil, 0)
+		assert.Error(t, m.Run(context.Background()))
+	})
+}
+
+func TestTickOffset(t *testing.T) {
+	interval := TickInterval
+	TickInterval = 30 * time.Second
+	defer func() { TickInterval = interval }()
+
+	assert.Equal(t, time.Duration(0), tickOffset(0, 1))
+	assert.Equal(t, []time.Duration{0, 10 * time.Second, 20 * time.Second}, []time.Duration{
+		tickOffset(0, 3),
+		tickOffset(1, 3),
+		tickOffset(2, 3),
+	})
+
+	c := NewChallenger(context.TODO(), types.ZeroAddress, nil, 0, nil, WithTickJitter(5*time.Second))
+	for range 100 {
+		d := c.nextTickDelay()
+		assert.GreaterOrEqual(t, d, TickInterval)
+		assert.Less(t, d, TickInterval+5*time.Second)
+	}
+}
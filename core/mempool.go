@@ -0,0 +1,180 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// MempoolVerdictTTL is the time a signature verdict of a pending poke is kept for.
+var MempoolVerdictTTL = time.Hour
+
+// MempoolInclusionTimeout is the time an invalid pending poke is waited for to land on chain.
+var MempoolInclusionTimeout = 5 * time.Minute
+
+// MempoolClient is implemented by RPC clients able to watch pending transactions, e.g. over websocket.
+type MempoolClient interface {
+	RPCClient
+
+	SubscribeNewPendingTransactions(ctx context.Context) (<-chan types.Hash, error)
+}
+
+type pokeKey struct {
+	address    types.Address
+	signature  [32]byte
+	commitment types.Address
+	val        string
+	age        uint32
+}
+
+func newPokeKey(address types.Address, poke *OpPokedEvent) pokeKey {
+	k := pokeKey{
+		address:    address,
+		signature:  poke.Schnorr.Signature,
+		commitment: poke.Schnorr.Commitment,
+		age:        poke.PokeData.Age,
+	}
+	if poke.PokeData.Val != nil {
+		k.val = poke.PokeData.Val.String()
+	}
+	return k
+}
+
+type pokeVerdict struct {
+	valid bool
+	at    time.Time
+}
+
+// MempoolWatcher decodes `opPoke` transactions sent to monitored addresses while they are still pending
+// and verifies their signatures ahead of time, so invalid pokes are challenged the instant they land.
+type MempoolWatcher struct {
+	client    MempoolClient
+	verifier  IScribeOptimisticProvider
+	addresses map[types.Address]struct{}
+	verdicts  map[pokeKey]pokeVerdict
+	mu        sync.Mutex
+
+	// OnInvalidPokeLanded is called when a pending poke with invalid signature is mined.
+	OnInvalidPokeLanded func(address types.Address)
+}
+
+// NewMempoolWatcher creates a new instance of MempoolWatcher, signatures are verified by verifier.
+func NewMempoolWatcher(client MempoolClient, verifier IScribeOptimisticProvider, addresses []types.Address) *MempoolWatcher {
+	m := &MempoolWatcher{
+		client:    client,
+		verifier:  verifier,
+		addresses: make(map[types.Address]struct{}, len(addresses)),
+		verdicts:  make(map[pokeKey]pokeVerdict),
+	}
+	for _, address := range addresses {
+		m.addresses[address] = struct{}{}
+	}
+	return m
+}
+
+// Verdict returns the signature verdict of the poke if it was verified while pending.
+func (m *MempoolWatcher) Verdict(address types.Address, poke *OpPokedEvent) (valid bool, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.verdicts[newPokeKey(address, poke)]
+	return v.valid, ok
+}
+
+// Run watches pending transactions until ctx is done or the subscription fails.
+func (m *MempoolWatcher) Run(ctx context.Context) error {
+	hashes, err := m.client.SubscribeNewPendingTransactions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to pending transactions: %w", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case hash, ok := <-hashes:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("pending transactions subscription closed")
+			}
+			go m.inspect(ctx, hash)
+		}
+	}
+}
+
+// inspect verifies the pending transaction if it's an `opPoke` call to one of the monitored addresses.
+func (m *MempoolWatcher) inspect(ctx context.Context, hash types.Hash) {
+	tx, err := m.client.GetTransactionByHash(ctx, hash)
+	if err != nil || tx == nil || tx.To == nil {
+		return
+	}
+	address := *tx.To
+	if _, ok := m.addresses[address]; !ok {
+		return
+	}
+	poke, ok := decodeOpPoke(tx.Input)
+	if !ok {
+		return
+	}
+
+	valid, err := m.verifier.IsPokeSignatureValid(ctx, address, poke)
+	if err != nil {
+		logger.
+			WithField("address", address).
+			WithField("txHash", hash).
+			Errorf("Failed to verify pending OpPoke signature with error: %v", err)
+		return
+	}
+	m.remember(address, poke, valid)
+	if valid {
+		return
+	}
+
+	logger.
+		WithField("address", address).
+		WithField("txHash", hash).
+		Warnf("Pending OpPoke with invalid signature detected, waiting for it to land")
+
+	receipt, err := WaitForTxConfirmation(ctx, m.client, &hash, MempoolInclusionTimeout)
+	if err != nil || receipt.Status == nil || *receipt.Status != 1 {
+		return
+	}
+	if m.OnInvalidPokeLanded != nil {
+		m.OnInvalidPokeLanded(address)
+	}
+}
+
+func (m *MempoolWatcher) remember(address types.Address, poke *OpPokedEvent, valid bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	for k, v := range m.verdicts {
+		if now.Sub(v.at) > MempoolVerdictTTL {
+			delete(m.verdicts, k)
+		}
+	}
+	m.verdicts[newPokeKey(address, poke)] = pokeVerdict{valid: valid, at: now}
+}
+
+// decodeOpPoke decodes poke data and schnorr signature from `opPoke` calldata.
+func decodeOpPoke(input []byte) (*OpPokedEvent, bool) {
+	opPoke := ScribeOptimisticContractABI.Methods["opPoke"]
+	if len(input) < 4 || !bytes.Equal(input[:4], opPoke.FourBytes().Bytes()) {
+		return nil, false
+	}
+	var poke OpPokedEvent
+	var ecdsa struct {
+		V uint8    `abi:"v"`
+		R [32]byte `abi:"r"`
+		S [32]byte `abi:"s"`
+	}
+	if err := opPoke.DecodeArgs(input, &poke.PokeData, &poke.Schnorr, &ecdsa); err != nil {
+		return nil, false
+	}
+	return &poke, true
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockMempoolClient struct {
+	mockRpcClient
+	pending chan types.Hash
+}
+
+func (m *mockMempoolClient) SubscribeNewPendingTransactions(ctx context.Context) (<-chan types.Hash, error) {
+	return m.pending, nil
+}
+
+func TestMempoolWatcher(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	status := uint64(1)
+	poke := &OpPokedEvent{
+		Schnorr:  SchnorrData{Signature: [32]byte{1}, Commitment: address, SignersBlob: []byte{2, 3}},
+		PokeData: PokeData{Val: big.NewInt(1000), Age: 42},
+	}
+	ecdsa := struct {
+		V uint8    `abi:"v"`
+		R [32]byte `abi:"r"`
+		S [32]byte `abi:"s"`
+	}{}
+	input, err := ScribeOptimisticContractABI.Methods["opPoke"].EncodeArgs(poke.PokeData, poke.Schnorr, ecdsa)
+	require.NoError(t, err)
+
+	client := &mockMempoolClient{pending: make(chan types.Hash)}
+	client.On("GetTransactionByHash", mock.Anything, txHash).
+		Return(&types.OnChainTransaction{Transaction: types.Transaction{Call: types.Call{To: &address, Input: input}}}, nil)
+	client.On("GetTransactionReceipt", mock.Anything, txHash).
+		Return(&types.TransactionReceipt{TransactionHash: txHash, Status: &status, BlockNumber: big.NewInt(200)}, nil)
+	verifier := new(mockScribeOptimisticProvider)
+	verifier.On("IsPokeSignatureValid", mock.Anything, address, poke).Return(false, nil).Once()
+
+	m := NewMempoolWatcher(client, verifier, []types.Address{address})
+	landed := make(chan types.Address, 1)
+	m.OnInvalidPokeLanded = func(address types.Address) { landed <- address }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		assert.NoError(t, m.Run(ctx))
+	}()
+	client.pending <- txHash
+
+	select {
+	case a := <-landed:
+		assert.Equal(t, address, a)
+	case <-time.After(time.Second):
+		t.Fatal("invalid poke landing not reported")
+	}
+
+	// Poke emitted on chain is recognized by its data, the signature isn't verified again.
+	onChain := *poke
+	onChain.BlockNumber = big.NewInt(200)
+	valid, ok := m.Verdict(address, &onChain)
+	assert.True(t, ok)
+	assert.False(t, valid)
+
+	c := NewChallenger(context.TODO(), address, verifier, 0, nil, WithMempoolWatcher(m))
+	verifier.On("BlockByNumber", mock.Anything, onChain.BlockNumber).
+		Return(&types.Block{Number: onChain.BlockNumber, Timestamp: time.Now()}, nil)
+	assert.True(t, c.isPokeChallengeable(&onChain, 600))
+	verifier.AssertNumberOfCalls(t, "IsPokeSignatureValid", 1)
+}
+
+func TestDecodeOpPoke(t *testing.T) {
+	_, ok := decodeOpPoke(nil)
+	assert.False(t, ok)
+
+	calldata, err := ScribeOptimisticContractABI.Methods["opChallenge"].EncodeArgs(SchnorrData{})
+	require.NoError(t, err)
+	_, ok = decodeOpPoke(calldata)
+	assert.False(t, ok)
+}
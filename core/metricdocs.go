@@ -0,0 +1,120 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+// metricLabelHelp describes labels of the challenger metrics. Values of labels specific to a metric, e.g. result
+// or reason, are listed in the help of the metric.
+var metricLabelHelp = map[string]string{
+	"address":         "ScribeOptimistic contract address",
+	"from":            "Address of the challenger account",
+	"result":          "Outcome of the challenge",
+	"reason":          "Reason of the event, values are listed in the metric help",
+	"component":       "Component which panicked: loop or challenge",
+	"event":           "Name of the contract event",
+	"action":          "Action taken on the stuck transaction",
+	"endpoint":        "Name of the RPC endpoint, e.g. primary or fallback-1",
+	"method":          "JSON-RPC method, e.g. eth_getLogs",
+	"beneficiary":     "Address the rewards are swept to",
+	"symbol":          "Symbol of the gas token of the chain",
+	"instance":        "Id of the instance of --instance-id",
+	"version":         "Version of the binary",
+	"commit":          "Git commit the binary is built from",
+	"build_date":      "Date the binary is built at",
+	"abi_hash":        "Keccak256 hash of the embedded ScribeOptimistic ABI",
+	"go_version":      "Go version the binary is built with",
+	InstanceLabelName: "Name of the deployment of --instance-label",
+}
+
+// metricTypeUsage tells how metrics of each type are queried.
+var metricTypeUsage = map[string]string{
+	MetricTypeCounter: "Only increases and resets on restart, query with rate() or increase()",
+	MetricTypeGauge:   "Current value, query as it is",
+}
+
+// MetricLabelDoc documents a label of a metric.
+type MetricLabelDoc struct {
+	Name string `json:"name"`
+	Help string `json:"help"`
+}
+
+// MetricDoc documents an exported metric.
+type MetricDoc struct {
+	Name   string           `json:"name"`
+	Type   string           `json:"type"`
+	Help   string           `json:"help"`
+	Usage  string           `json:"usage"`
+	Labels []MetricLabelDoc `json:"labels"`
+}
+
+// MetricDocs documents all exported metrics, so alert rules can be written without reading the source.
+type MetricDocs struct {
+	Metrics []MetricDoc `json:"metrics"`
+}
+
+// NewMetricDocs generates docs of the metrics of DescribeMetrics. If instance is not empty, the challenger_instance
+// label added by WithInstanceLabel is documented on all metrics.
+func NewMetricDocs(instance string) (*MetricDocs, error) {
+	metrics, err := DescribeMetrics()
+	if err != nil {
+		return nil, err
+	}
+	docs := &MetricDocs{Metrics: make([]MetricDoc, len(metrics))}
+	for i, m := range metrics {
+		labels := m.Labels
+		if instance != "" {
+			labels = append(labels[:len(labels):len(labels)], InstanceLabelName)
+		}
+		doc := MetricDoc{
+			Name:   m.Name,
+			Type:   m.Type,
+			Help:   m.Help,
+			Usage:  metricTypeUsage[m.Type],
+			Labels: make([]MetricLabelDoc, len(labels)),
+		}
+		for j, l := range labels {
+			doc.Labels[j] = MetricLabelDoc{Name: l, Help: metricLabelHelp[l]}
+		}
+		docs.Metrics[i] = doc
+	}
+	return docs, nil
+}
+
+// Markdown renders the docs as Markdown table.
+func (d *MetricDocs) Markdown() string {
+	var b strings.Builder
+	b.WriteString("| Metric | Type | Labels | Description |\n")
+	b.WriteString("|---|---|---|---|\n")
+	for _, m := range d.Metrics {
+		labels := make([]string, len(m.Labels))
+		for i, l := range m.Labels {
+			labels[i] = fmt.Sprintf("`%s`: %s", l.Name, l.Help)
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s. %s. |\n", m.Name, m.Type, strings.Join(labels, "<br>"), m.Help, m.Usage)
+	}
+	return b.String()
+}
+
+// ServeHTTP serves the docs as JSON, or as Markdown table with the format=markdown query parameter.
+func (d *MetricDocs) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(d); err != nil {
+			logger.WithError(err).Error("failed to encode metric docs")
+		}
+	case "markdown":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		if _, err := w.Write([]byte(d.Markdown())); err != nil {
+			logger.WithError(err).Error("failed to write metric docs")
+		}
+	default:
+		http.Error(w, "unsupported format, supported are json and markdown", http.StatusBadRequest)
+	}
+}
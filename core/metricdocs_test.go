@@ -0,0 +1,57 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricDocs(t *testing.T) {
+	metrics, err := DescribeMetrics()
+	require.NoError(t, err)
+	docs, err := NewMetricDocs("eu-1")
+	require.NoError(t, err)
+	require.Len(t, docs.Metrics, len(metrics))
+
+	for _, m := range docs.Metrics {
+		assert.NotEmpty(t, m.Help, m.Name)
+		assert.NotEmpty(t, m.Usage, m.Name)
+		for _, l := range m.Labels {
+			assert.NotEmpty(t, l.Help, "label %s of %s is not documented", l.Name, m.Name)
+		}
+		assert.Equal(t, InstanceLabelName, m.Labels[len(m.Labels)-1].Name)
+	}
+
+	t.Run("json", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		docs.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics/docs", nil))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		var served MetricDocs
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+		assert.Equal(t, *docs, served)
+	})
+
+	t.Run("markdown", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		docs.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics/docs?format=markdown", nil))
+		assert.Contains(t, rec.Body.String(), "| `challenger_challenges_total` | counter | `address`: ScribeOptimistic contract address<br>")
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		docs.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics/docs?format=html", nil))
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	docs, err = NewMetricDocs("")
+	require.NoError(t, err)
+	for _, m := range docs.Metrics {
+		for _, l := range m.Labels {
+			assert.NotEqual(t, InstanceLabelName, l.Name)
+		}
+	}
+}
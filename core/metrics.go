@@ -1,9 +1,28 @@
 package core
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 const prometheusNamespace = "challenger"
 
+// InstanceLabelName is the label distinguishing metrics of challenger deployments scraped into one Prometheus.
+const InstanceLabelName = "challenger_instance"
+
+// WithInstanceLabel wraps the registerer, so all metrics registered with it carry the instance label.
+func WithInstanceLabel(registerer prometheus.Registerer, instance string) prometheus.Registerer {
+	if instance == "" {
+		return registerer
+	}
+	return prometheus.WrapRegistererWith(prometheus.Labels{InstanceLabelName: instance}, registerer)
+}
+
 var ErrorsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 	Namespace: prometheusNamespace,
 	Name:      "errors_total",
@@ -13,11 +32,330 @@ var ErrorsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 var ChallengeCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
 	Namespace: prometheusNamespace,
 	Name:      "challenges_total",
-	Help:      "Number of challenges made",
-}, []string{"address", "from", "tx"})
+	Help:      "Number of challenges by result: submitted, confirmed, reverted, lost_race or dropped",
+}, []string{"address", "from", "result"})
 
 var LastScannedBlockGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
 	Namespace: prometheusNamespace,
 	Name:      "last_scanned_block",
 	Help:      "Last scanned block",
 }, []string{"address", "from"})
+
+var RPCHealthyGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "rpc_healthy",
+	Help:      "Whether RPC endpoint is considered healthy (1) or stale (0)",
+}, []string{"endpoint"})
+
+var RPCFailoverCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "rpc_failovers_total",
+	Help:      "Number of failovers to the given RPC endpoint",
+}, []string{"endpoint"})
+
+var ChallengeWindowRemainingGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "challenge_window_remaining_seconds",
+	Help:      "Seconds remaining in the challenge window of the most recent unconfirmed invalid poke, 0 if there is none",
+}, []string{"address"})
+
+var SweptRewardsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "swept_rewards_eth_total",
+	Help:      "Amount of the gas token (ETH, or the native token of the chain) swept from challenger account to the beneficiary",
+}, []string{"from", "beneficiary", "symbol"})
+
+var MonitoredAddressesGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "monitored_addresses",
+	Help:      "Number of addresses with running processing loop",
+})
+
+var TickIntervalGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "tick_interval_seconds",
+	Help:      "Current polling interval of the address, shorter while an invalid poke needs attention",
+}, []string{"address"})
+
+var LastTickTimestampGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "last_tick_timestamp_seconds",
+	Help:      "Unix timestamp of the last processing tick of the address, successful or not",
+}, []string{"address"})
+
+var LoopDegradedGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "loop_degraded",
+	Help:      "Whether processing loop of the address failed recently and is being restarted (1) or runs fine (0)",
+}, []string{"address"})
+
+var LoopRestartsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "loop_restarts_total",
+	Help:      "Number of restarts of the failed processing loop of the address",
+}, []string{"address"})
+
+var PanicsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "panics_total",
+	Help:      "Number of recovered panics by component: loop or challenge",
+}, []string{"address", "component"})
+
+var LastPokeValueGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "last_poke_value",
+	Help:      "Value of the latest OpPoked event, scaled down by 18 decimals",
+}, []string{"address"})
+
+var LastPokeAgeGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "last_poke_age_seconds",
+	Help:      "Age of the poke data of the latest OpPoked event as unix timestamp",
+}, []string{"address"})
+
+var LastUpdateAgeGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "last_update_age_seconds",
+	Help:      "Age of the poke data of the latest regular or optimistic poke as unix timestamp",
+}, []string{"address"})
+
+var ChallengePeriodGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "challenge_period_seconds",
+	Help:      "Challenge period of the contract, 0 means optimistic pokes can't be challenged",
+}, []string{"address"})
+
+var ContractStateChangesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "contract_state_changes_total",
+	Help:      "Number of events changing configuration of the contract by event name",
+}, []string{"address", "event"})
+
+var SuspiciousPokesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "suspicious_pokes_total",
+	Help:      "Number of pokes with suspicious age of the poke data by reason: future_age, not_newer or stale",
+}, []string{"address", "reason"})
+
+var PriceDeviationGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "poke_price_deviation_ratio",
+	Help:      "Relative deviation of the latest poked value from the reference price",
+}, []string{"address"})
+
+var PriceDeviationAlertsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "price_deviation_alerts_total",
+	Help:      "Number of pokes which value deviated from the reference price beyond the threshold",
+}, []string{"address"})
+
+var NonceGapGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "nonce_gap",
+	Help:      "Number of pending transactions of the challenger account not mined yet",
+}, []string{"from"})
+
+var NonceRepairsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "nonce_repairs_total",
+	Help:      "Number of stuck transactions replaced by action: rebroadcast or cancel",
+}, []string{"from", "action"})
+
+var GasEstimationFailuresCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "gas_estimation_failures_total",
+	Help:      "Number of failed gas limit estimations by the transaction target address",
+}, []string{"address"})
+
+var LeaderGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "leader",
+	Help:      "Whether this instance is the leader submitting challenges (1) or standby (0)",
+}, []string{"instance"})
+
+var BuildInfoGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "build_info",
+	Help:      "Build information of the running binary, value is always 1",
+}, []string{"version", "commit", "build_date", "abi_hash", "go_version"})
+
+var ChallengeRevertsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "challenge_reverts_total",
+	Help:      "Number of reverted challenge transactions by reason: already_challenged, period_expired, out_of_gas or unknown",
+}, []string{"address", "reason"})
+
+var SafePendingProposalsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "safe_pending_proposals",
+	Help:      "Number of challenges proposed to the Safe which are not executed yet",
+}, []string{"address"})
+
+var SafeDeadlineAlertsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "safe_deadline_alerts_total",
+	Help:      "Number of challenges proposed to the Safe still not executed close to the challenge deadline",
+}, []string{"address"})
+
+var ChallengeBudgetExhaustedGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "challenge_budget_exhausted",
+	Help:      "Whether challenge submission is paused because the challenge budget is exhausted (1) or not (0)",
+})
+
+var ChallengeBudgetExhaustedCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "challenge_budget_rejections_total",
+	Help:      "Number of challenges not submitted because the challenge budget is exhausted, by reason: rate or spend",
+}, []string{"reason"})
+
+var SignatureDiscrepanciesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "signature_discrepancies_total",
+	Help:      "Number of pokes which signature verdict at the block of the poke differs from the verdict at the latest block",
+}, []string{"address"})
+
+var ChallengeWindowExpiringGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "challenge_window_expiring",
+	Help:      "Number of invalid pokes which challenge window is about to close without confirmed challenge",
+}, []string{"address"})
+
+var ChallengeWindowExpiringCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "challenge_window_expiring_alerts_total",
+	Help:      "Number of alerts on invalid pokes which challenge window was about to close without confirmed challenge",
+}, []string{"address"})
+
+var SequencerUpGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "sequencer_up",
+	Help:      "Whether the sequencer of the L2 chain is considered up (1) or down (0)",
+})
+
+var AccountBalanceGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "account_balance",
+	Help:      "Balance of the challenger account in the gas token of the chain",
+}, []string{"from", "symbol"})
+
+var AccountBalanceLowGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "account_balance_low",
+	Help:      "Whether balance of the challenger account is below the minimum (1) or not (0)",
+}, []string{"from"})
+
+var DuplicateInstanceGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "duplicate_instance",
+	Help:      "Whether another instance using the same key was detected by the last check (1) or not (0)",
+}, []string{"from"})
+
+var ChallengeEstimatedRewardGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "challenge_estimated_reward",
+	Help:      "Expected reward of the last challengeable poke in the gas token of the chain",
+}, []string{"address"})
+
+var ChallengeEstimatedCostGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "challenge_estimated_cost",
+	Help:      "Estimated gas cost of challenging the last challengeable poke in the gas token of the chain",
+}, []string{"address"})
+
+var ChallengeEstimatedNetGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "challenge_estimated_net",
+	Help:      "Expected reward minus estimated gas cost of challenging the last challengeable poke in the gas token of the chain",
+}, []string{"address"})
+
+var RPCHedgedRequestsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "rpc_hedged_requests_total",
+	Help:      "Number of requests hedged to a second RPC endpoint by the endpoint which answered first",
+}, []string{"method", "endpoint"})
+
+var PokesAwaitingVerificationGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "pokes_awaiting_verification",
+	Help:      "Number of pokes queued for or in signature verification",
+}, []string{"address"})
+
+var InvalidPokesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: prometheusNamespace,
+	Name:      "invalid_pokes_total",
+	Help:      "Number of challengeable pokes with invalid signature detected, whether challenged or not",
+}, []string{"address"})
+
+var ChallengesAwaitingConfirmationGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: prometheusNamespace,
+	Name:      "challenges_awaiting_confirmation",
+	Help:      "Number of challenges being submitted or sent and not confirmed, failed or dropped yet",
+}, []string{"address"})
+
+// Metric types of MetricDescription.
+const (
+	MetricTypeCounter = "counter"
+	MetricTypeGauge   = "gauge"
+)
+
+// MetricDescription describes a metric as exported, so dashboards and docs refer to exact names and labels.
+type MetricDescription struct {
+	Name   string
+	Type   string
+	Help   string
+	Labels []string
+}
+
+// descPattern matches prometheus.Desc.String(), there are no accessors of its fields.
+var descPattern = regexp.MustCompile(`^Desc\{fqName: ("(?:[^"\\]|\\.)*"), help: ("(?:[^"\\]|\\.)*"), constLabels: \{.*\}, variableLabels: \{(.*)\}\}$`)
+
+// DescribeMetrics returns descriptions of all collectors of Metrics, sorted by name.
+func DescribeMetrics() ([]MetricDescription, error) {
+	var descriptions []MetricDescription
+	for _, c := range Metrics() {
+		var typ string
+		switch c.(type) {
+		case *prometheus.GaugeVec, prometheus.Gauge:
+			typ = MetricTypeGauge
+		case *prometheus.CounterVec, prometheus.Counter:
+			typ = MetricTypeCounter
+		default:
+			return nil, fmt.Errorf("unsupported collector %T", c)
+		}
+		ch := make(chan *prometheus.Desc, 1)
+		c.Describe(ch)
+		close(ch)
+		for desc := range ch {
+			m := descPattern.FindStringSubmatch(desc.String())
+			if m == nil {
+				return nil, fmt.Errorf("unexpected metric description %s", desc)
+			}
+			name, err := strconv.Unquote(m[1])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected metric name %s: %w", m[1], err)
+			}
+			help, err := strconv.Unquote(m[2])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected help of metric %s: %w", name, err)
+			}
+			var labels []string
+			if m[3] != "" {
+				labels = strings.Split(m[3], ",")
+			}
+			descriptions = append(descriptions, MetricDescription{Name: name, Type: typ, Help: help, Labels: labels})
+		}
+	}
+	sort.Slice(descriptions, func(i, j int) bool { return descriptions[i].Name < descriptions[j].Name })
+	return descriptions, nil
+}
+
+// HasLabel tells if the metric has the label.
+func (m MetricDescription) HasLabel(label string) bool {
+	for _, l := range m.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
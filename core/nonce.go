@@ -0,0 +1,223 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// DefaultNonceGapBlocks is the default number of blocks a nonce gap is tolerated for before it's considered stuck.
+const DefaultNonceGapBlocks = 10
+
+// NonceCheckInterval is the interval the account nonce is checked at.
+var NonceCheckInterval = 30 * time.Second
+
+// Actions taken on a stuck transaction.
+const (
+	// NonceRepairAlert only logs the stuck transaction.
+	NonceRepairAlert = "alert"
+	// NonceRepairRebroadcast re-sends the stuck challenge persisted in the pending store with bumped fees.
+	NonceRepairRebroadcast = "rebroadcast"
+	// NonceRepairCancel replaces the stuck transaction with zero-value transfer to self.
+	NonceRepairCancel = "cancel"
+)
+
+// NonceFetcher is implemented by RPC clients able to fetch the transaction count of an account.
+type NonceFetcher interface {
+	GetTransactionCount(ctx context.Context, account types.Address, block types.BlockNumber) (uint64, error)
+}
+
+// NonceClient is implemented by RPC clients able to send transactions and fetch nonces.
+type NonceClient interface {
+	RPCClient
+	NonceFetcher
+}
+
+// NonceMonitor detects transactions of the challenger account stuck in the mempool, e.g. left by an older run,
+// which block all following challenges. The gap between pending and confirmed nonce is tolerated for a number
+// of blocks, after that the transaction is considered stuck and repaired according to the action.
+type NonceMonitor struct {
+	client    NonceClient
+	mu        sync.Mutex
+	from      types.Address
+	gapBlocks uint64
+	action    string
+
+	gapNonce *uint64
+	gapSince *big.Int
+
+	// Store is used to find the stuck challenge to rebroadcast, and fees of the stuck transaction to replace.
+	Store PendingStore
+}
+
+// NewNonceMonitor creates a new instance of NonceMonitor.
+func NewNonceMonitor(client NonceClient, from types.Address, gapBlocks uint64, action string) (*NonceMonitor, error) {
+	switch action {
+	case NonceRepairAlert, NonceRepairRebroadcast, NonceRepairCancel:
+	default:
+		return nil, fmt.Errorf(
+			"unknown nonce repair action %q, possible values are: %s, %s, %s",
+			action, NonceRepairAlert, NonceRepairRebroadcast, NonceRepairCancel,
+		)
+	}
+	return &NonceMonitor{client: client, from: from, gapBlocks: gapBlocks, action: action}, nil
+}
+
+// Run checks the nonce every NonceCheckInterval until ctx is done.
+func (n *NonceMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(NonceCheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := n.Check(ctx); err != nil {
+			logger.
+				WithField("from", n.address()).
+				Errorf("Failed to check nonce with error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// address returns the monitored address.
+func (n *NonceMonitor) address() types.Address {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.from
+}
+
+// SetFrom switches monitoring to another address, e.g. after key rotation. The gap of the old address is forgotten
+// and its metric removed.
+func (n *NonceMonitor) SetFrom(from types.Address) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	NonceGapGauge.DeleteLabelValues(n.from.String())
+	n.from = from
+	n.gapNonce, n.gapSince = nil, nil
+}
+
+// Check compares pending and confirmed nonce and repairs the stuck transaction once the gap is old enough.
+func (n *NonceMonitor) Check(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	block, err := n.client.BlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get block number: %w", err)
+	}
+	confirmed, err := n.client.GetTransactionCount(ctx, n.from, types.LatestBlockNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get confirmed nonce: %w", err)
+	}
+	pending, err := n.client.GetTransactionCount(ctx, n.from, types.PendingBlockNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get pending nonce: %w", err)
+	}
+
+	if pending <= confirmed {
+		NonceGapGauge.WithLabelValues(n.from.String()).Set(0)
+		n.gapNonce, n.gapSince = nil, nil
+		return nil
+	}
+	NonceGapGauge.WithLabelValues(n.from.String()).Set(float64(pending - confirmed))
+
+	// The gap is measured since the lowest pending nonce stopped moving.
+	if n.gapNonce == nil || *n.gapNonce != confirmed {
+		n.gapNonce, n.gapSince = &confirmed, block
+		return nil
+	}
+	stuckFor := new(big.Int).Sub(block, n.gapSince)
+	if stuckFor.Cmp(new(big.Int).SetUint64(n.gapBlocks)) < 0 {
+		return nil
+	}
+
+	logger.
+		WithField("from", n.from).
+		Warnf("Transaction with nonce %d is stuck for %v blocks, %d transactions are pending", confirmed, stuckFor, pending-confirmed)
+
+	hash, err := n.repair(ctx, confirmed)
+	if err != nil {
+		return fmt.Errorf("failed to repair stuck transaction with nonce %d: %w", confirmed, err)
+	}
+	if hash == nil {
+		return nil
+	}
+	NonceRepairsCounter.WithLabelValues(n.from.String(), n.action).Inc()
+	logger.
+		WithField("from", n.from).
+		WithField("txHash", hash).
+		Warnf("Replaced stuck transaction with nonce %d, action: %s", confirmed, n.action)
+
+	// Giving the replacement time to be mined before acting again.
+	n.gapSince = block
+	return nil
+}
+
+// repair replaces the stuck transaction with the nonce, returns nil hash if nothing was sent.
+func (n *NonceMonitor) repair(ctx context.Context, nonce uint64) (*types.Hash, error) {
+	if n.action == NonceRepairAlert {
+		return nil, nil
+	}
+
+	stuck := n.findPending(nonce)
+	tx := (&types.Transaction{}).SetFrom(n.from).SetNonce(nonce)
+	if stuck != nil {
+		// Replacement has to pay more than the stuck transaction, otherwise the node rejects it.
+		tx.GasPrice = bumpFee(stuck.GasPrice)
+		tx.MaxFeePerGas = bumpFee(stuck.MaxFeePerGas)
+		tx.MaxPriorityFeePerGas = bumpFee(stuck.MaxPriorityFeePerGas)
+	}
+
+	switch {
+	case n.action == NonceRepairRebroadcast && stuck != nil && stuck.To != nil:
+		// The stuck call is sent again as it is, e.g. through the forwarder and with the challenge tag.
+		tx.SetTo(*stuck.To).SetInput(stuck.Input)
+	case n.action == NonceRepairRebroadcast && stuck != nil:
+		// Challenges persisted by older versions don't keep the call, it's a direct `opChallenge` call.
+		calldata, err := EncodeChallengeCalldata(stuck.Poke, nil)
+		if err != nil {
+			return nil, err
+		}
+		tx.SetTo(stuck.Address).SetInput(calldata)
+	case n.action == NonceRepairRebroadcast:
+		logger.
+			WithField("from", n.from).
+			Warnf("Stuck transaction with nonce %d is not in the pending store, it can't be rebroadcast", nonce)
+		return nil, nil
+	default:
+		tx.SetTo(n.from).SetValue(big.NewInt(0)).SetGasLimit(sweepGasLimit)
+	}
+
+	hash, _, err := n.client.SendTransaction(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send replacement transaction: %w", err)
+	}
+	return hash, nil
+}
+
+// findPending returns the persisted challenge sent with the nonce, nil if there is none.
+func (n *NonceMonitor) findPending(nonce uint64) *PendingChallenge {
+	if n.Store == nil {
+		return nil
+	}
+	challenges, err := n.Store.Load()
+	if err != nil {
+		logger.
+			WithField("from", n.from).
+			Errorf("Failed to load pending challenges with error: %v", err)
+		return nil
+	}
+	for _, c := range challenges {
+		if c.Nonce != nil && *c.Nonce == nonce && c.Poke != nil {
+			return &c
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockNonceClient struct {
+	mockRpcClient
+}
+
+func (m *mockNonceClient) GetTransactionCount(ctx context.Context, account types.Address, block types.BlockNumber) (uint64, error) {
+	args := m.Called(ctx, account, block)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+type memoryPendingStore []PendingChallenge
+
+func (m memoryPendingStore) Save(PendingChallenge) error       { return nil }
+func (m memoryPendingStore) Remove(types.Hash) error           { return nil }
+func (m memoryPendingStore) Load() ([]PendingChallenge, error) { return m, nil }
+
+func TestNewNonceMonitor(t *testing.T) {
+	_, err := NewNonceMonitor(nil, types.ZeroAddress, DefaultNonceGapBlocks, "replace")
+	assert.ErrorContains(t, err, "unknown nonce repair action")
+}
+
+func TestNonceMonitor(t *testing.T) {
+	from := types.MustAddressFromHex("0xbF7acDa376eF37EC371235a094113dF9Cb4EfEeb")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+
+	// Checks nonce at the given blocks, the lowest pending nonce is stuck at 5.
+	check := func(t *testing.T, monitor *NonceMonitor, client *mockNonceClient, blocks ...int64) {
+		client.On("GetTransactionCount", mock.Anything, from, types.LatestBlockNumber).Return(uint64(5), nil)
+		client.On("GetTransactionCount", mock.Anything, from, types.PendingBlockNumber).Return(uint64(7), nil)
+		for _, block := range blocks {
+			client.On("BlockNumber", mock.Anything).Return(big.NewInt(block), nil).Once()
+			require.NoError(t, monitor.Check(context.TODO()))
+		}
+	}
+
+	t.Run("stuck transaction is cancelled after gap blocks", func(t *testing.T) {
+		client := new(mockNonceClient)
+		monitor, err := NewNonceMonitor(client, from, 10, NonceRepairCancel)
+		require.NoError(t, err)
+		client.On("SendTransaction", mock.Anything, mock.MatchedBy(func(tx *types.Transaction) bool {
+			return *tx.Nonce == 5 && *tx.To == from && tx.Value.Sign() == 0 && tx.Input == nil
+		})).Return(&txHash, (*types.Transaction)(nil), nil).Once()
+
+		check(t, monitor, client, 100, 105, 110)
+		assert.Equal(t, float64(2), testutil.ToFloat64(NonceGapGauge.WithLabelValues(from.String())))
+		client.AssertNumberOfCalls(t, "SendTransaction", 1)
+
+		// Replacement is given time to be mined.
+		check(t, monitor, client, 115)
+		client.AssertNumberOfCalls(t, "SendTransaction", 1)
+	})
+
+	t.Run("persisted challenge is rebroadcast with bumped fees", func(t *testing.T) {
+		client := new(mockNonceClient)
+		monitor, err := NewNonceMonitor(client, from, 10, NonceRepairRebroadcast)
+		require.NoError(t, err)
+		nonce := uint64(5)
+		address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+		poke := &OpPokedEvent{BlockNumber: big.NewInt(90), Schnorr: SchnorrData{Signature: [32]byte{1}}}
+		monitor.Store = memoryPendingStore{{Address: address, Poke: poke, Nonce: &nonce, GasPrice: big.NewInt(800)}}
+		calldata, err := EncodeChallengeCalldata(poke, nil)
+		require.NoError(t, err)
+		client.On("SendTransaction", mock.Anything, mock.MatchedBy(func(tx *types.Transaction) bool {
+			return *tx.Nonce == 5 && *tx.To == address && string(tx.Input) == string(calldata) && tx.GasPrice.Int64() == 900
+		})).Return(&txHash, (*types.Transaction)(nil), nil).Once()
+
+		check(t, monitor, client, 100, 110)
+		client.AssertNumberOfCalls(t, "SendTransaction", 1)
+		assert.Equal(t, float64(1), testutil.ToFloat64(NonceRepairsCounter.WithLabelValues(from.String(), NonceRepairRebroadcast)))
+	})
+
+	t.Run("persisted call is rebroadcast as it was sent", func(t *testing.T) {
+		client := new(mockNonceClient)
+		monitor, err := NewNonceMonitor(client, from, 10, NonceRepairRebroadcast)
+		require.NoError(t, err)
+		nonce := uint64(5)
+		address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+		forwarder := types.MustAddressFromHex("0x3F7acDa376eF37EC371235a094113dF9Cb4EfEe3")
+		poke := &OpPokedEvent{BlockNumber: big.NewInt(90), Schnorr: SchnorrData{Signature: [32]byte{1}}}
+		input := []byte{1, 2, 3, 4}
+		monitor.Store = memoryPendingStore{{Address: address, Poke: poke, To: &forwarder, Input: input, Nonce: &nonce, GasPrice: big.NewInt(800)}}
+		client.On("SendTransaction", mock.Anything, mock.MatchedBy(func(tx *types.Transaction) bool {
+			return *tx.Nonce == 5 && *tx.To == forwarder && string(tx.Input) == string(input) && tx.GasPrice.Int64() == 900
+		})).Return(&txHash, (*types.Transaction)(nil), nil).Once()
+
+		check(t, monitor, client, 100, 110)
+		client.AssertNumberOfCalls(t, "SendTransaction", 1)
+	})
+
+	t.Run("alert only", func(t *testing.T) {
+		client := new(mockNonceClient)
+		monitor, err := NewNonceMonitor(client, from, 10, NonceRepairAlert)
+		require.NoError(t, err)
+
+		check(t, monitor, client, 100, 110, 120)
+		client.AssertNotCalled(t, "SendTransaction", mock.Anything, mock.Anything)
+	})
+
+	t.Run("gap closed", func(t *testing.T) {
+		client := new(mockNonceClient)
+		monitor, err := NewNonceMonitor(client, from, 10, NonceRepairCancel)
+		require.NoError(t, err)
+		client.On("BlockNumber", mock.Anything).Return(big.NewInt(100), nil)
+		client.On("GetTransactionCount", mock.Anything, from, mock.Anything).Return(uint64(5), nil)
+
+		require.NoError(t, monitor.Check(context.TODO()))
+		assert.Equal(t, float64(0), testutil.ToFloat64(NonceGapGauge.WithLabelValues(from.String())))
+		assert.Nil(t, monitor.gapSince)
+	})
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+)
+
+// PendingChallenge is a sent challenge transaction persisted until its outcome is known. Call fields are kept,
+// so the transaction can be replayed to classify its revert and replaced after restart.
+type PendingChallenge struct {
+	Address              types.Address  `json:"address"`
+	Poke                 *OpPokedEvent  `json:"poke"`
+	Hash                 types.Hash     `json:"hash"`
+	From                 *types.Address `json:"from,omitempty"`
+	To                   *types.Address `json:"to,omitempty"`
+	Input                types.Bytes    `json:"input,omitempty"`
+	GasLimit             *uint64        `json:"gasLimit,omitempty"`
+	Nonce                *uint64        `json:"nonce,omitempty"`
+	GasPrice             *big.Int       `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *big.Int       `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *big.Int       `json:"maxPriorityFeePerGas,omitempty"`
+	// Replaces are hashes of transactions resubmitted by this one with the same nonce, the latest first.
+	// Any of them may still be mined instead.
+	Replaces []types.Hash `json:"replaces,omitempty"`
+	SentAt   time.Time    `json:"sentAt"`
+}
+
+// PendingStore persists pending challenges, so they can be resumed after restart.
+type PendingStore interface {
+	// Save adds or replaces the pending challenge with the same hash.
+	Save(challenge PendingChallenge) error
+	// Remove forgets the pending challenge with the given hash.
+	Remove(hash types.Hash) error
+	// Load returns all pending challenges.
+	Load() ([]PendingChallenge, error)
+}
+
+// PendingResumer is implemented by providers able to resume tracking of challenges sent before restart.
+type PendingResumer interface {
+	// ResumePending starts tracking persisted challenges under the address and returns their pokes.
+	ResumePending(ctx context.Context, address types.Address) ([]*OpPokedEvent, error)
+}
+
+// FilePendingStore implements PendingStore keeping pending challenges in a JSON file.
+type FilePendingStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFilePendingStore creates a new instance of FilePendingStore, the file is created on first save.
+func NewFilePendingStore(path string) *FilePendingStore {
+	return &FilePendingStore{path: path}
+}
+
+// Save implements PendingStore interface.
+func (f *FilePendingStore) Save(challenge PendingChallenge) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	challenges, err := f.load()
+	if err != nil {
+		return err
+	}
+	for i := range challenges {
+		if challenges[i].Hash == challenge.Hash {
+			challenges[i] = challenge
+			return f.write(challenges)
+		}
+	}
+	return f.write(append(challenges, challenge))
+}
+
+// Remove implements PendingStore interface.
+func (f *FilePendingStore) Remove(hash types.Hash) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	challenges, err := f.load()
+	if err != nil {
+		return err
+	}
+	result := challenges[:0]
+	for _, c := range challenges {
+		if c.Hash != hash {
+			result = append(result, c)
+		}
+	}
+	return f.write(result)
+}
+
+// Load implements PendingStore interface.
+func (f *FilePendingStore) Load() ([]PendingChallenge, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.load()
+}
+
+func (f *FilePendingStore) load() ([]PendingChallenge, error) {
+	b, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pending challenges: %w", err)
+	}
+	var challenges []PendingChallenge
+	if err := json.Unmarshal(b, &challenges); err != nil {
+		return nil, fmt.Errorf("failed to decode pending challenges: %w", err)
+	}
+	return challenges, nil
+}
+
+// write replaces the file atomically, so a crash never leaves it half written.
+func (f *FilePendingStore) write(challenges []PendingChallenge) error {
+	b, err := json.Marshal(challenges)
+	if err != nil {
+		return fmt.Errorf("failed to encode pending challenges: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write pending challenges: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write pending challenges: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write pending challenges: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("failed to write pending challenges: %w", err)
+	}
+	return nil
+}
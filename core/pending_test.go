@@ -0,0 +1,258 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilePendingStore(t *testing.T) {
+	store := NewFilePendingStore(filepath.Join(t.TempDir(), "pending.json"))
+	hash1 := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	hash2 := types.MustHashFromHex("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", types.PadNone)
+	nonce := uint64(7)
+	poke := &OpPokedEvent{
+		BlockNumber: big.NewInt(100),
+		Schnorr:     SchnorrData{Signature: [32]byte{1}, SignersBlob: []byte{2, 3}},
+		PokeData:    PokeData{Val: big.NewInt(1000), Age: 42},
+	}
+
+	challenges, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, challenges)
+
+	require.NoError(t, store.Save(PendingChallenge{Hash: hash1, Poke: poke, Nonce: &nonce, MaxFeePerGas: big.NewInt(10)}))
+	require.NoError(t, store.Save(PendingChallenge{Hash: hash2, Poke: poke}))
+	require.NoError(t, store.Remove(hash2))
+
+	challenges, err = store.Load()
+	require.NoError(t, err)
+	require.Len(t, challenges, 1)
+	assert.Equal(t, hash1, challenges[0].Hash)
+	assert.Equal(t, poke, challenges[0].Poke)
+	assert.Equal(t, &nonce, challenges[0].Nonce)
+	assert.Equal(t, big.NewInt(10), challenges[0].MaxFeePerGas)
+}
+
+func TestResumePending(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	other := types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEe2")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	status := uint64(1)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(100)}
+
+	store := NewFilePendingStore(filepath.Join(t.TempDir(), "pending.json"))
+	require.NoError(t, store.Save(PendingChallenge{Address: address, Poke: poke, Hash: txHash}))
+	require.NoError(t, store.Save(PendingChallenge{
+		Address: other,
+		Poke:    poke,
+		Hash:    types.MustHashFromHex("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", types.PadNone),
+	}))
+
+	client := new(mockRpcClient)
+	client.On("GetTransactionReceipt", mock.Anything, txHash).
+		Return(&types.TransactionReceipt{TransactionHash: txHash, Status: &status, BlockNumber: big.NewInt(200)}, nil)
+	provider := NewScribeOptimisticRPCProvider(client, nil, WithPendingStore(store))
+
+	c := NewChallenger(context.Background(), address, provider, 100, &sync.WaitGroup{})
+	c.resumePending()
+	c.inFlightMu.Lock()
+	_, inFlight := c.inFlight[100]
+	c.inFlightMu.Unlock()
+	assert.True(t, inFlight, "resumed poke must not be challenged again")
+
+	outcome := waitOutcome(t, provider)
+	require.NoError(t, outcome.Err)
+	assert.Equal(t, &txHash, outcome.Hash)
+
+	// Only the challenge of the other address is left.
+	assert.Eventually(t, func() bool {
+		challenges, err := store.Load()
+		return err == nil && len(challenges) == 1 && challenges[0].Address == other
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestResumePendingReverted(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	from := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	status := uint64(0)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(100), Schnorr: SchnorrData{Signature: [32]byte{1}}}
+	calldata, err := EncodeChallengeCalldata(poke, nil)
+	require.NoError(t, err)
+	pokeTime := time.Unix(1_700_000_000, 0)
+
+	// The challenge is persisted when sent, before the restart.
+	store := NewFilePendingStore(filepath.Join(t.TempDir(), "pending.json"))
+	tracker := NewTxTracker(nil)
+	tracker.store = store
+	sent := types.NewTransaction().SetFrom(from).SetTo(address).SetInput(calldata).SetGasLimit(100000).SetNonce(7)
+	tracker.persist(context.Background(), &TrackedTx{Address: address, Poke: poke, Hash: &txHash, Tx: sent})
+
+	client := new(mockRpcClient)
+	client.On("GetTransactionReceipt", mock.Anything, txHash).
+		Return(&types.TransactionReceipt{TransactionHash: txHash, Status: &status, BlockNumber: big.NewInt(200), GasUsed: 50000}, nil)
+	// The reverted challenge is replayed with the persisted call.
+	client.On("Call", mock.Anything, mock.MatchedBy(func(call *types.Call) bool {
+		return *call.From == from && *call.To == address && bytes.Equal(call.Input, calldata)
+	}), types.BlockNumberFromUint64(200)).
+		Return([]byte(nil), nil, transport.NewRPCError(3, "execution reverted", ScribeOptimisticContractABI.Errors["NoOpPokeToChallenge"].FourBytes().Bytes()))
+	client.On("Call", mock.Anything, mock.Anything, types.LatestBlockNumber).
+		Return(big.NewInt(600).FillBytes(make([]byte, 32)), nil, nil)
+	client.On("BlockByNumber", mock.Anything, types.BlockNumberFromUint64(100), false).
+		Return(&types.Block{Timestamp: pokeTime}, nil)
+	client.On("BlockByNumber", mock.Anything, types.BlockNumberFromUint64(200), false).
+		Return(&types.Block{Timestamp: pokeTime.Add(time.Minute)}, nil)
+	provider := NewScribeOptimisticRPCProvider(client, nil, WithPendingStore(store))
+
+	pokes, err := provider.ResumePending(context.Background(), address)
+	require.NoError(t, err)
+	require.Len(t, pokes, 1)
+
+	err = waitOutcome(t, provider).Err
+	assert.ErrorIs(t, err, ErrChallengeReverted)
+	assert.ErrorIs(t, err, ErrAlreadyChallenged)
+	assert.ErrorContains(t, err, RevertReasonAlreadyChallenged)
+}
+
+func TestResumePendingReplaced(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	original := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	resubmitted := types.MustHashFromHex("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", types.PadNone)
+	status := uint64(1)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(100)}
+	sent := types.NewTransaction().SetTo(address).SetNonce(7)
+	store := NewFilePendingStore(filepath.Join(t.TempDir(), "pending.json"))
+
+	// The original transaction is not included in time and resubmitted, then the process shuts down.
+	client := new(mockRpcClient)
+	client.On("GetTransactionReceipt", mock.Anything, mock.Anything).Return((*types.TransactionReceipt)(nil), nil)
+	client.On("BlockNumber", mock.Anything).Return(big.NewInt(151), nil)
+	tracker := NewTxTracker(nil)
+	tracker.store = store
+	clock := newFakeClock()
+	ctx, cancel := context.WithCancel(ContextWithClock(context.Background(), clock))
+	done := make(chan struct{})
+	go func() {
+		tracker.watch(ctx, &TrackedTx{
+			Address:  address,
+			Poke:     poke,
+			Hash:     &original,
+			Tx:       sent,
+			Client:   client,
+			Deadline: big.NewInt(150),
+			Fallback: func(_ context.Context, tx *TrackedTx) (*TrackedTx, error) {
+				return &TrackedTx{Address: address, Poke: poke, Hash: &resubmitted, Tx: sent, Client: client, Replaces: tx}, nil
+			},
+		})
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return clock.Waiters() == 2 }, time.Second, time.Millisecond)
+	clock.Advance(TxConfirmationPollInterval)
+	require.Eventually(t, func() bool {
+		pending, err := store.Load()
+		return err == nil && len(pending) == 1 && pending[0].Hash == resubmitted
+	}, time.Second, time.Millisecond)
+	cancel()
+	<-done
+	pending, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, []types.Hash{original}, pending[0].Replaces)
+
+	// After restart, the original transaction turns out to be mined instead.
+	client = new(mockRpcClient)
+	client.On("GetTransactionReceipt", mock.Anything, resubmitted).Return((*types.TransactionReceipt)(nil), nil)
+	client.On("GetTransactionReceipt", mock.Anything, original).
+		Return(&types.TransactionReceipt{TransactionHash: original, Status: &status, BlockNumber: big.NewInt(151)}, nil)
+	provider := NewScribeOptimisticRPCProvider(client, nil, WithPendingStore(store))
+	clock = newFakeClock()
+	pokes, err := provider.ResumePending(ContextWithClock(context.Background(), clock), address)
+	require.NoError(t, err)
+	require.Len(t, pokes, 1)
+	require.Eventually(t, func() bool { return clock.Waiters() == 2 }, time.Second, time.Millisecond)
+	clock.Advance(TxConfirmationTimeout)
+
+	outcome := waitOutcome(t, provider)
+	require.NoError(t, outcome.Err)
+	assert.Equal(t, &original, outcome.Hash)
+	pending, err = store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestTxTrackerKeepsPendingOnShutdown(t *testing.T) {
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	client := new(mockRpcClient)
+	client.On("GetTransactionReceipt", mock.Anything, txHash).
+		Return((*types.TransactionReceipt)(nil), nil)
+
+	store := NewFilePendingStore(filepath.Join(t.TempDir(), "pending.json"))
+	tracker := NewTxTracker(nil)
+	tracker.store = store
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		tracker.watch(ctx, &TrackedTx{Poke: &OpPokedEvent{BlockNumber: big.NewInt(100)}, Hash: &txHash, Client: client})
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	challenges, err := store.Load()
+	require.NoError(t, err)
+	require.Len(t, challenges, 1)
+	assert.Equal(t, txHash, challenges[0].Hash)
+}
+
+func TestBumpFee(t *testing.T) {
+	assert.Nil(t, bumpFee(nil))
+	assert.Equal(t, big.NewInt(1125), bumpFee(big.NewInt(1000)))
+}
+
+func TestFilePendingStoreKeepsRawLog(t *testing.T) {
+	store := NewFilePendingStore(filepath.Join(t.TempDir(), "pending.json"))
+	hash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	blockHash := types.MustHashFromHex("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", types.PadNone)
+	logIndex := uint64(2)
+	poke, err := DecodeOpPokeEvent(types.Log{
+		BlockNumber:     big.NewInt(123),
+		BlockHash:       &blockHash,
+		TransactionHash: &hash,
+		LogIndex:        &logIndex,
+		Topics: []types.Hash{
+			types.MustHashFromHex("0xb9dc937c5e394d0c8f76e0e324500b88251b4c909ddc56232df10e2ea42b3c63", types.PadNone),
+			types.MustHashFromHex("0x0000000000000000000000001f7acda376ef37ec371235a094113df9cb4efee1", types.PadNone),
+			types.MustHashFromHex("0x0000000000000000000000006813eb9362372eef6200f3b1dbc3f819671cba69", types.PadNone),
+		},
+		Data: testOpPokedData(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, store.Save(PendingChallenge{Hash: hash, Poke: poke}))
+
+	challenges, err := store.Load()
+	require.NoError(t, err)
+	require.Len(t, challenges, 1)
+	raw := challenges[0].Poke.Raw
+	require.NotNil(t, raw)
+	assert.Equal(t, &blockHash, raw.BlockHash)
+	assert.Equal(t, poke.Raw.Topics, raw.Topics)
+
+	// The persisted log decodes to the same event.
+	decoded, err := DecodeOpPokeEvent(*raw)
+	require.NoError(t, err)
+	assert.Equal(t, poke.Caller, decoded.Caller)
+	assert.Equal(t, poke.OpFeed, decoded.OpFeed)
+	assert.Equal(t, poke.LogIndex, decoded.LogIndex)
+}
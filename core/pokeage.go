@@ -0,0 +1,62 @@
+package core
+
+import (
+	"context"
+	"time"
+)
+
+// Reasons a poke with valid signature is considered suspicious.
+const (
+	// PokeAgeFuture means the poke data is newer than the block it was poked in.
+	PokeAgeFuture = "future_age"
+	// PokeAgeNotNewer means the poke data is not newer than the data of the previous poke.
+	PokeAgeNotNewer = "not_newer"
+	// PokeAgeStale means the poke data was older than the allowed staleness when it was poked.
+	PokeAgeStale = "stale"
+)
+
+// pokeAgeIssue returns the reason the age of the poke data is suspicious, empty string if it's fine.
+// Previous is the age of the preceding poke data, 0 if unknown. Staleness is not checked if maxStaleness is 0.
+func pokeAgeIssue(age uint32, previous uint32, pokedAt time.Time, maxStaleness time.Duration) string {
+	ageTime := time.Unix(int64(age), 0)
+	switch {
+	case ageTime.After(pokedAt):
+		return PokeAgeFuture
+	case previous != 0 && age <= previous:
+		return PokeAgeNotNewer
+	case maxStaleness > 0 && pokedAt.Sub(ageTime) > maxStaleness:
+		return PokeAgeStale
+	}
+	return ""
+}
+
+// checkPokeAges alerts on new pokes, ordered as on chain, with suspicious age of the poke data. Such pokes can't
+// be challenged, as their signature may be valid, but they give an early warning of a misbehaving feed.
+func (c *Challenger) checkPokeAges(ctx context.Context, pokes []*OpPokedEvent) {
+	if !c.ageValidation {
+		return
+	}
+
+	for _, poke := range pokes {
+		previous := c.lastPokeAge
+		c.lastPokeAge = poke.PokeData.Age
+		block, err := c.provider.BlockByNumber(ctx, poke.BlockNumber)
+		if err != nil {
+			c.log().Errorf("Failed to get block by number %d to check poke age with error: %v", poke.BlockNumber, err)
+			continue
+		}
+		reason := pokeAgeIssue(poke.PokeData.Age, previous, block.Timestamp, c.maxPokeStaleness)
+		if reason == "" {
+			continue
+		}
+		SuspiciousPokesCounter.WithLabelValues(c.address.String(), reason).Inc()
+		c.log().
+			WithField("reason", reason).
+			Warnf(
+				"OpPoked event from block %v has suspicious age %v, poked at %v",
+				poke.BlockNumber,
+				time.Unix(int64(poke.PokeData.Age), 0).UTC(),
+				block.Timestamp.UTC(),
+			)
+	}
+}
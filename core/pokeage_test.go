@@ -0,0 +1,61 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPokeAgeIssue(t *testing.T) {
+	pokedAt := time.Unix(1_700_000_000, 0)
+	age := uint32(pokedAt.Unix())
+
+	tests := []struct {
+		name         string
+		age          uint32
+		previous     uint32
+		maxStaleness time.Duration
+		want         string
+	}{
+		{name: "fresh", age: age - 10, previous: age - 100, maxStaleness: time.Minute},
+		{name: "same second as the block", age: age},
+		{name: "future", age: age + 1, want: PokeAgeFuture},
+		{name: "same as previous", age: age - 10, previous: age - 10, want: PokeAgeNotNewer},
+		{name: "older than previous", age: age - 10, previous: age - 5, want: PokeAgeNotNewer},
+		{name: "stale", age: age - 120, maxStaleness: time.Minute, want: PokeAgeStale},
+		{name: "staleness not checked", age: age - 120},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, pokeAgeIssue(tt.age, tt.previous, pokedAt, tt.maxStaleness))
+		})
+	}
+}
+
+func TestCheckPokeAges(t *testing.T) {
+	address := types.MustAddressFromHex("0x5F7acDa376eF37EC371235a094113dF9Cb4EfEe5")
+	pokedAt := time.Unix(1_700_000_000, 0)
+	provider := new(mockScribeOptimisticProvider)
+	provider.On("BlockByNumber", mock.Anything, mock.Anything).
+		Return(&types.Block{Timestamp: pokedAt}, nil)
+
+	c := NewChallenger(context.TODO(), address, provider, 0, nil, WithPokeAgeValidation(time.Minute))
+	pokes := []*OpPokedEvent{
+		{BlockNumber: big.NewInt(101), PokeData: PokeData{Age: uint32(pokedAt.Unix()) - 5}},
+		{BlockNumber: big.NewInt(100), PokeData: PokeData{Age: uint32(pokedAt.Unix()) - 10}},
+		{BlockNumber: big.NewInt(102), PokeData: PokeData{Age: uint32(pokedAt.Unix()) - 5}},
+	}
+	c.checkPokeAges(context.TODO(), c.pickNewPokes(pokes))
+	assert.Equal(t, float64(1), testutil.ToFloat64(SuspiciousPokesCounter.WithLabelValues(address.String(), PokeAgeNotNewer)))
+
+	// Pokes from the rescanned block are not checked again.
+	c.checkPokeAges(context.TODO(), c.pickNewPokes(pokes[2:]))
+	assert.Equal(t, float64(1), testutil.ToFloat64(SuspiciousPokesCounter.WithLabelValues(address.String(), PokeAgeNotNewer)))
+	provider.AssertNumberOfCalls(t, "BlockByNumber", 3)
+}
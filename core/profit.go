@@ -0,0 +1,58 @@
+package core
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/defiweb/go-eth/types"
+)
+
+// ChallengeEstimate is the expected reward and gas cost of challenging a poke, in the smallest unit of the gas token.
+type ChallengeEstimate struct {
+	Reward   *big.Int
+	GasLimit uint64
+	GasPrice *big.Int
+}
+
+// Cost returns the estimated fee paid by the challenge transaction.
+func (e *ChallengeEstimate) Cost() *big.Int {
+	return new(big.Int).Mul(new(big.Int).SetUint64(e.GasLimit), e.GasPrice)
+}
+
+// Net returns the reward minus the cost, negative if the challenge is expected to lose money.
+func (e *ChallengeEstimate) Net() *big.Int {
+	return new(big.Int).Sub(e.Reward, e.Cost())
+}
+
+// ChallengeEstimator is implemented by providers able to estimate reward and gas cost of challenges.
+type ChallengeEstimator interface {
+	// EstimateChallenge returns the expected reward and gas cost of challenging the poke under the address.
+	EstimateChallenge(ctx context.Context, address types.Address, poke *OpPokedEvent) (*ChallengeEstimate, error)
+}
+
+// logChallengeEstimate logs and publishes the expected reward and gas cost of challenging the poke,
+// to inform the profitability policy and its post-hoc tuning. Failed estimations never block the challenge.
+func (c *Challenger) logChallengeEstimate(poke *OpPokedEvent) {
+	estimator, ok := c.provider.(ChallengeEstimator)
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.ctx, TickInterval)
+	defer cancel()
+	estimate, err := estimator.EstimateChallenge(ctx, c.address, poke)
+	if err != nil {
+		c.log().Warnf("Failed to estimate reward and cost of challenging OpPoked event from block %v: %v", poke.BlockNumber, err)
+		return
+	}
+	net := estimate.Net()
+	c.log().
+		WithField("reward", NativeToken.Format(estimate.Reward)).
+		WithField("cost", NativeToken.Format(estimate.Cost())).
+		WithField("gasLimit", estimate.GasLimit).
+		WithField("gasPrice", estimate.GasPrice).
+		WithField("net", NativeToken.Format(net)).
+		Infof("Estimated reward and cost of challenging OpPoked event from block %v", poke.BlockNumber)
+	ChallengeEstimatedRewardGauge.WithLabelValues(c.address.String()).Set(NativeToken.Float64(estimate.Reward))
+	ChallengeEstimatedCostGauge.WithLabelValues(c.address.String()).Set(NativeToken.Float64(estimate.Cost()))
+	ChallengeEstimatedNetGauge.WithLabelValues(c.address.String()).Set(NativeToken.Float64(net))
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/hexutil"
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockGasClient struct {
+	mockRpcClient
+}
+
+func (m *mockGasClient) EstimateGas(ctx context.Context, call *types.Call, block types.BlockNumber) (uint64, *types.Call, error) {
+	args := m.Called(ctx, call, block)
+	return args.Get(0).(uint64), call, args.Error(1)
+}
+
+func (m *mockGasClient) GasPrice(ctx context.Context) (*big.Int, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(*big.Int), args.Error(1)
+}
+
+func TestEstimateChallenge(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	from := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(100), Schnorr: SchnorrData{Commitment: address}}
+
+	client := new(mockGasClient)
+	client.On("Accounts", mock.Anything).Return([]types.Address{from}, nil)
+	// 0.1 ETH
+	client.On("Call", mock.Anything, mock.Anything, types.LatestBlockNumber).Return(
+		hexutil.MustHexToBytes("0x000000000000000000000000000000000000000000000000016345785d8a0000"),
+		&types.Call{},
+		nil,
+	)
+	client.On("EstimateGas", mock.Anything, mock.MatchedBy(func(call *types.Call) bool {
+		return call.From != nil && *call.From == from && *call.To == address
+	}), types.LatestBlockNumber).Return(uint64(200_000), nil)
+	client.On("GasPrice", mock.Anything).Return(big.NewInt(100_000_000_000), nil)
+
+	provider := NewScribeOptimisticRPCProvider(client, nil)
+	estimate, err := provider.EstimateChallenge(context.TODO(), address, poke)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(100_000_000_000_000_000), estimate.Reward)
+	assert.Equal(t, big.NewInt(20_000_000_000_000_000), estimate.Cost())
+	assert.Equal(t, big.NewInt(80_000_000_000_000_000), estimate.Net())
+
+	p := &estimatingProvider{mockScribeOptimisticProvider: new(mockScribeOptimisticProvider), estimate: estimate}
+	p.On("GetFrom", mock.Anything).Return(from)
+	c := NewChallenger(context.TODO(), address, p, 0, nil)
+	c.logChallengeEstimate(poke)
+	assert.InDelta(t, 0.1, testutil.ToFloat64(ChallengeEstimatedRewardGauge.WithLabelValues(address.String())), 1e-9)
+	assert.InDelta(t, 0.02, testutil.ToFloat64(ChallengeEstimatedCostGauge.WithLabelValues(address.String())), 1e-9)
+	assert.InDelta(t, 0.08, testutil.ToFloat64(ChallengeEstimatedNetGauge.WithLabelValues(address.String())), 1e-9)
+
+	// Clients unable to estimate gas fail the estimation only.
+	provider = NewScribeOptimisticRPCProvider(new(mockRpcClient), nil)
+	_, err = provider.EstimateChallenge(context.TODO(), address, poke)
+	assert.Error(t, err)
+}
+
+type estimatingProvider struct {
+	*mockScribeOptimisticProvider
+	estimate *ChallengeEstimate
+}
+
+func (p *estimatingProvider) EstimateChallenge(context.Context, types.Address, *OpPokedEvent) (*ChallengeEstimate, error) {
+	return p.estimate, nil
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis is an in-memory Redis server supporting commands used by challenger.
+type fakeRedis struct {
+	ln      net.Listener
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	f := &fakeRedis{ln: ln, values: map[string]string{}, expires: map[string]time.Time{}}
+	go f.serve()
+	t.Cleanup(func() { ln.Close() })
+	return f
+}
+
+func (f *fakeRedis) URL() string {
+	return "redis://" + f.ln.Addr().String()
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.ln.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for {
+		req, err := readRESP(rd)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, a := range req.([]any) {
+			args = append(args, a.(string))
+		}
+		fmt.Fprint(conn, f.exec(args))
+	}
+}
+
+func (f *fakeRedis) get(key string) (string, bool) {
+	if exp, ok := f.expires[key]; ok && time.Now().After(exp) {
+		delete(f.values, key)
+		delete(f.expires, key)
+	}
+	v, ok := f.values[key]
+	return v, ok
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "GET":
+		if v, ok := f.get(args[1]); ok {
+			return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+		}
+		return "$-1\r\n"
+	case "SET":
+		_, exists := f.get(args[1])
+		var ttl time.Duration
+		for i := 3; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				if exists {
+					return "$-1\r\n"
+				}
+			case "PX":
+				ms, _ := strconv.Atoi(args[i+1])
+				ttl = time.Duration(ms) * time.Millisecond
+				i++
+			}
+		}
+		f.values[args[1]] = args[2]
+		delete(f.expires, args[1])
+		if ttl > 0 {
+			f.expires[args[1]] = time.Now().Add(ttl)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		_, exists := f.get(args[1])
+		delete(f.values, args[1])
+		if exists {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "EVAL":
+		// Only compare-and-X scripts are supported: KEYS[1] == ARGV[1].
+		key, id := args[3], args[4]
+		if v, ok := f.get(key); !ok || v != id {
+			return ":0\r\n"
+		}
+		switch {
+		case strings.Contains(args[1], "PEXPIRE"):
+			ms, _ := strconv.Atoi(args[5])
+			f.expires[key] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		case strings.Contains(args[1], "DEL"):
+			delete(f.values, key)
+			delete(f.expires, key)
+		}
+		return ":1\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func TestRedisClient(t *testing.T) {
+	srv := newFakeRedis(t)
+	c, err := NewRedisClient(srv.URL())
+	require.NoError(t, err)
+	defer c.Close()
+
+	res, err := c.Do(context.TODO(), "SET", "key", "value", "NX")
+	require.NoError(t, err)
+	assert.Equal(t, "OK", res)
+
+	res, err = c.Do(context.TODO(), "SET", "key", "other", "NX")
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	res, err = c.Do(context.TODO(), "GET", "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", res)
+
+	_, err = c.Do(context.TODO(), "UNKNOWN")
+	assert.ErrorContains(t, err, "unknown command")
+
+	// Connection is still usable after error reply.
+	res, err = c.Do(context.TODO(), "DEL", "key")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), res)
+
+	_, err = NewRedisClient("http://localhost:6379")
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIDHeader is the HTTP header the request ID is sent to RPC endpoints in.
+const RequestIDHeader = "X-Request-Id"
+
+// UserAgent is the User-Agent of requests to RPC endpoints, DefaultUserAgent if empty. Endpoints with User-Agent
+// in headers of their TransportConfig send that one.
+var UserAgent = ""
+
+// DefaultUserAgent returns the User-Agent with the version of the binary, so providers can tell
+// the challenger traffic apart.
+func DefaultUserAgent() string {
+	return "challenger/" + GetBuildInfo().Version + " (+https://github.com/chronicleprotocol/challenger)"
+}
+
+func userAgent() string {
+	if UserAgent != "" {
+		return UserAgent
+	}
+	return DefaultUserAgent()
+}
+
+type requestIDKey struct{}
+
+// NewRequestID returns a random ID correlating logs of a tick with its RPC requests.
+func NewRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns the context whose RPC requests carry the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID of the context, empty if it has none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// requestTransport sets User-Agent and the request ID of the request context on requests to RPC endpoints.
+// Websocket endpoints only get them on the handshake.
+type requestTransport struct {
+	base http.RoundTripper
+}
+
+func (t requestTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	id := RequestIDFromContext(r.Context())
+	set := r.Header.Get("User-Agent") == ""
+	if !set && id == "" {
+		return t.base.RoundTrip(r)
+	}
+	// Round trippers must not modify the request.
+	r = r.Clone(r.Context())
+	if set {
+		r.Header.Set("User-Agent", userAgent())
+	}
+	if id != "" {
+		r.Header.Set(RequestIDHeader, id)
+	}
+	return t.base.RoundTrip(r)
+}
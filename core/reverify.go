@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// trackedPoke is a poke found valid, verified again until its challenge window closes.
+type trackedPoke struct {
+	poke     *OpPokedEvent
+	deadline time.Time
+}
+
+type reverificationKey struct{}
+
+// isReverification reports whether pokes are verified again by reverifyPokes.
+func isReverification(ctx context.Context) bool {
+	return ctx.Value(reverificationKey{}) != nil
+}
+
+// trackValid keeps the poke for re-verification if enabled and its challenge window is still open.
+func (c *Challenger) trackValid(poke *OpPokedEvent, deadline time.Time) {
+	if !c.reverify || poke == nil || poke.BlockNumber == nil || !c.clock.Now().Before(deadline) {
+		return
+	}
+	c.validPokes[poke.BlockNumber.Uint64()] = trackedPoke{poke: poke, deadline: deadline}
+}
+
+// reverifyPokes verifies pokes found valid on previous ticks again and challenges the ones which became invalid.
+// Pokes from blocks scanned by this tick are left to the scan.
+func (c *Challenger) reverifyPokes(ctx context.Context, fromBlock *big.Int, latestBlockNumber *big.Int, period uint16) error {
+	if len(c.validPokes) == 0 {
+		return nil
+	}
+
+	var pokes []*OpPokedEvent
+	var earliest *big.Int
+	now := c.clock.Now()
+	for blockNum, tracked := range c.validPokes {
+		if !now.Before(tracked.deadline) || tracked.poke.BlockNumber.Cmp(fromBlock) >= 0 {
+			delete(c.validPokes, blockNum)
+			continue
+		}
+		pokes = append(pokes, tracked.poke)
+		if earliest == nil || tracked.poke.BlockNumber.Cmp(earliest) < 0 {
+			earliest = tracked.poke.BlockNumber
+		}
+	}
+	if len(pokes) == 0 {
+		return nil
+	}
+
+	challenges, err := c.provider.GetSuccessfulChallenges(ctx, c.address, earliest, latestBlockNumber)
+	if err != nil {
+		return fmt.Errorf("failed to get OpPokeChallengedSuccessfully events with error: %w", err)
+	}
+	sort.Slice(pokes, func(i, j int) bool {
+		return pokes[i].BlockNumber.Cmp(pokes[j].BlockNumber) < 0
+	})
+	// Pokes still valid are tracked again by the verification.
+	for _, poke := range pokes {
+		delete(c.validPokes, poke.BlockNumber.Uint64())
+	}
+	sampledDebugf(LogCategoryTick, c.log(), "Re-verifying %d pokes", len(pokes))
+	ctx = context.WithValue(ctx, reverificationKey{}, true)
+	for _, poke := range c.pickChallengeablePokes(ctx, PickUnchallengedPokes(pokes, challenges), period) {
+		c.log().
+			WithField("pokeTxHash", poke.TxHash).
+			Warnf("OpPoked event from block %v found valid before is invalid now", poke.BlockNumber)
+		c.challengePoke(poke)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestReverifyPokes(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(100)}
+	expired := &OpPokedEvent{BlockNumber: big.NewInt(90)}
+
+	p := new(mockScribeOptimisticProvider)
+	p.On("GetFrom", mock.Anything).Return(types.ZeroAddress)
+	p.On("BlockByNumber", mock.Anything, poke.BlockNumber).
+		Return(&types.Block{Number: poke.BlockNumber, Timestamp: time.Now()}, nil)
+	p.On("GetSuccessfulChallenges", mock.Anything, address, poke.BlockNumber, big.NewInt(120)).
+		Return([]*OpPokeChallengedSuccessfullyEvent{}, nil)
+	validCall := p.On("IsPokeSignatureValid", mock.Anything, address, poke).Return(true, nil)
+
+	c := NewChallenger(context.TODO(), address, p, 0, nil, WithReverification())
+	c.trackValid(poke, time.Now().Add(time.Minute))
+	c.trackValid(expired, time.Now().Add(-time.Minute))
+	assert.Len(t, c.validPokes, 1)
+
+	// Still valid poke is tracked until its window closes.
+	assert.NoError(t, c.reverifyPokes(context.TODO(), big.NewInt(110), big.NewInt(120), 600))
+	assert.Contains(t, c.validPokes, uint64(100))
+	p.AssertNotCalled(t, "ChallengePoke", mock.Anything, mock.Anything, mock.Anything)
+
+	// Signer set changed, the poke is challenged.
+	validCall.Unset()
+	p.On("IsPokeSignatureValid", mock.Anything, address, poke).Return(false, nil)
+	p.On("ChallengePoke", mock.Anything, address, poke).Return(&txHash, (*types.Transaction)(nil), nil)
+	assert.NoError(t, c.reverifyPokes(context.TODO(), big.NewInt(110), big.NewInt(120), 600))
+	c.submissions.Wait()
+	p.AssertCalled(t, "ChallengePoke", mock.Anything, address, poke)
+	assert.NotContains(t, c.validPokes, uint64(100))
+
+	// Pokes are not tracked unless enabled.
+	c = NewChallenger(context.TODO(), address, p, 0, nil)
+	c.trackValid(poke, time.Now().Add(time.Minute))
+	assert.Empty(t, c.validPokes)
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/types"
+)
+
+// Categories of reverted challenge transactions, used as metric labels.
+const (
+	RevertReasonAlreadyChallenged = "already_challenged"
+	RevertReasonPeriodExpired     = "period_expired"
+	RevertReasonOutOfGas          = "out_of_gas"
+	RevertReasonUnknown           = "unknown"
+)
+
+// ErrTxReverted is returned by WaitForTxConfirmation when the transaction was mined with status 0.
+var ErrTxReverted = errors.New("transaction reverted")
+
+// ErrLostRace is the former name of ErrAlreadyChallenged.
+//
+// Deprecated: Use ErrAlreadyChallenged.
+var ErrLostRace = ErrAlreadyChallenged
+
+// GetRevertReason replays the reverted transaction using `eth_call` at the block it was mined in
+// and returns the decoded revert error, or nil if the replay doesn't revert.
+func GetRevertReason(
+	ctx context.Context,
+	client RPCClient,
+	tx *types.Transaction,
+	receipt *types.TransactionReceipt,
+) error {
+	call := tx.Call
+	// Fees are irrelevant for the replay and may fail the call if balance changed since.
+	call.GasPrice = nil
+	call.MaxFeePerGas = nil
+	call.MaxPriorityFeePerGas = nil
+
+	_, _, err := client.Call(ctx, &call, types.BlockNumberFromBigInt(receipt.BlockNumber))
+	return ScribeOptimisticContractABI.HandleError(err)
+}
+
+// ClassifyRevert returns the category of the reverted challenge transaction.
+// Challenges reverted because there is no poke to challenge are classified as already challenged,
+// callers knowing the challenge window can reclassify them as expired.
+func ClassifyRevert(reason error, tx *types.Transaction, receipt *types.TransactionReceipt) string {
+	if tx != nil && tx.GasLimit != nil && receipt.GasUsed >= *tx.GasLimit {
+		return RevertReasonOutOfGas
+	}
+	var customErr abi.CustomError
+	if errors.As(reason, &customErr) {
+		switch customErr.Type.Name() {
+		case "NoOpPokeToChallenge", "SchnorrDataMismatch":
+			return RevertReasonAlreadyChallenged
+		}
+	}
+	return RevertReasonUnknown
+}
+
+// describeRevert formats the revert reason for logs.
+func describeRevert(reason error) string {
+	var customErr abi.CustomError
+	switch {
+	case reason == nil:
+		return "no revert reason, replay succeeded"
+	case errors.As(reason, &customErr) && len(customErr.Data) > 4:
+		return fmt.Sprintf("%s(0x%x)", customErr.Type.Name(), customErr.Data[4:])
+	case errors.As(reason, &customErr):
+		return customErr.Type.Name()
+	default:
+		return reason.Error()
+	}
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetRevertReason(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	gasPrice := big.NewInt(100)
+	tx := &types.Transaction{Call: types.Call{To: &address, GasPrice: gasPrice, Input: []byte{1, 2, 3, 4}}}
+	receipt := &types.TransactionReceipt{BlockNumber: big.NewInt(200)}
+
+	t.Run("custom error", func(t *testing.T) {
+		client := new(mockRpcClient)
+		data := ScribeOptimisticContractABI.Errors["SchnorrSignatureInvalid"].FourBytes().Bytes()
+		client.On("Call", mock.Anything, mock.MatchedBy(func(c *types.Call) bool {
+			// Replay is done without fees.
+			return c.GasPrice == nil && *c.To == address
+		}), types.BlockNumberFromUint64(200)).
+			Return([]byte(nil), nil, transport.NewRPCError(3, "execution reverted", data))
+
+		reason := GetRevertReason(context.TODO(), client, tx, receipt)
+		var customErr abi.CustomError
+		assert.ErrorAs(t, reason, &customErr)
+		assert.Equal(t, "SchnorrSignatureInvalid", describeRevert(reason))
+		// Original transaction is not modified.
+		assert.Equal(t, gasPrice, tx.GasPrice)
+	})
+
+	t.Run("replay succeeds", func(t *testing.T) {
+		client := new(mockRpcClient)
+		client.On("Call", mock.Anything, mock.Anything, mock.Anything).Return([]byte{}, nil, nil)
+
+		reason := GetRevertReason(context.TODO(), client, tx, receipt)
+		assert.NoError(t, reason)
+		assert.Equal(t, RevertReasonUnknown, ClassifyRevert(reason, tx, receipt))
+	})
+}
+
+func TestClassifyRevert(t *testing.T) {
+	gasLimit := uint64(100000)
+	tx := &types.Transaction{Call: types.Call{GasLimit: &gasLimit}}
+	errorType := func(name string) error {
+		e := ScribeOptimisticContractABI.Errors[name]
+		return abi.CustomError{Type: e, Data: e.FourBytes().Bytes()}
+	}
+
+	tests := []struct {
+		name     string
+		reason   error
+		gasUsed  uint64
+		expected string
+	}{
+		{"no poke to challenge", errorType("NoOpPokeToChallenge"), 50000, RevertReasonAlreadyChallenged},
+		{"schnorr data mismatch", errorType("SchnorrDataMismatch"), 50000, RevertReasonAlreadyChallenged},
+		{"all gas used", fmt.Errorf("out of gas"), gasLimit, RevertReasonOutOfGas},
+		{"other error", abi.RevertError{Reason: "boom"}, 50000, RevertReasonUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt := &types.TransactionReceipt{GasUsed: tt.gasUsed}
+			assert.Equal(t, tt.expected, ClassifyRevert(tt.reason, tx, receipt))
+		})
+	}
+}
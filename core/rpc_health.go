@@ -0,0 +1,323 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// DefaultMaxBlockDrift is the maximum allowed lag between the head block timestamp and wall clock.
+var DefaultMaxBlockDrift = 2 * time.Minute
+
+// DefaultStaleHeadTimeout is the maximum time the head block number may stay unchanged.
+var DefaultStaleHeadTimeout = 2 * time.Minute
+
+// RPCEndpoint is a named RPC client used by FailoverClient.
+// Name is used in logs and metrics, so it must not contain secrets (e.g. API keys in URL).
+type RPCEndpoint struct {
+	Name   string
+	Client RPCClient
+}
+
+type endpointState struct {
+	RPCEndpoint
+	healthy        bool
+	chainVerified  bool
+	lastHead       *big.Int
+	lastHeadChange time.Time
+}
+
+// FailoverClient implements RPCClient interface and delegates all calls to the currently active endpoint.
+// On every BlockNumber call it fetches the head block and checks that the endpoint is not stale:
+// head number has to advance within StaleHeadTimeout and head timestamp must not be older than MaxBlockDrift.
+// If the active endpoint is stale, it is flagged unhealthy and the next endpoint becomes active.
+// If ExpectedChainID is set, endpoints serving another chain are treated as unhealthy too.
+type FailoverClient struct {
+	endpoints        []*endpointState
+	active           int
+	mu               sync.Mutex
+	MaxBlockDrift    time.Duration
+	StaleHeadTimeout time.Duration
+	// SendTracker, if set, records transactions sent through the client.
+	SendTracker *SendTracker
+	// ExpectedChainID, if set, is verified the first time each endpoint is active, before it's used.
+	ExpectedChainID uint64
+	// HedgeDelay, if set, is the time after which latency critical requests, i.e. GetLogs of recent blocks and
+	// SendTransaction, are sent to the next healthy endpoint too, the first success is used.
+	HedgeDelay time.Duration
+
+	now func() time.Time
+}
+
+// NewFailoverClient creates a new instance of FailoverClient.
+// First endpoint is the primary one, the rest are used as alternates in the given order.
+func NewFailoverClient(endpoints ...RPCEndpoint) (*FailoverClient, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("at least one RPC endpoint is required")
+	}
+	states := make([]*endpointState, len(endpoints))
+	for i, e := range endpoints {
+		states[i] = &endpointState{RPCEndpoint: e, healthy: true}
+		RPCHealthyGauge.WithLabelValues(e.Name).Set(1)
+	}
+	return &FailoverClient{
+		endpoints:        states,
+		MaxBlockDrift:    DefaultMaxBlockDrift,
+		StaleHeadTimeout: DefaultStaleHeadTimeout,
+		now:              time.Now,
+	}, nil
+}
+
+func (f *FailoverClient) current() *endpointState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.endpoints[f.active]
+}
+
+// Active returns the name of the currently active endpoint.
+func (f *FailoverClient) Active() string {
+	return f.current().Name
+}
+
+// checkHead evaluates head block of the endpoint and returns reason why it's stale, or empty string if it's healthy.
+func (f *FailoverClient) checkHead(e *endpointState, block *types.Block) string {
+	now := f.now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if e.lastHead == nil || block.Number.Cmp(e.lastHead) != 0 {
+		e.lastHead = block.Number
+		e.lastHeadChange = now
+	}
+
+	if f.MaxBlockDrift > 0 && now.Sub(block.Timestamp) > f.MaxBlockDrift {
+		return fmt.Sprintf("head block %v timestamp %v drifts %v behind wall clock", block.Number, block.Timestamp, now.Sub(block.Timestamp))
+	}
+	if f.StaleHeadTimeout > 0 && now.Sub(e.lastHeadChange) > f.StaleHeadTimeout {
+		return fmt.Sprintf("head block %v unchanged for %v", block.Number, now.Sub(e.lastHeadChange))
+	}
+	return ""
+}
+
+// checkChainID verifies the endpoint serves the ExpectedChainID, once it succeeds it's not checked again.
+func (f *FailoverClient) checkChainID(ctx context.Context, e *endpointState) error {
+	f.mu.Lock()
+	verified := f.ExpectedChainID == 0 || e.chainVerified
+	f.mu.Unlock()
+	if verified {
+		return nil
+	}
+	if err := CheckChainID(ctx, e.Client, f.ExpectedChainID); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	e.chainVerified = true
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *FailoverClient) setHealthy(e *endpointState, healthy bool) {
+	f.mu.Lock()
+	e.healthy = healthy
+	f.mu.Unlock()
+
+	if healthy {
+		RPCHealthyGauge.WithLabelValues(e.Name).Set(1)
+	} else {
+		RPCHealthyGauge.WithLabelValues(e.Name).Set(0)
+	}
+}
+
+// failover switches active endpoint to the next one, preferring endpoints not flagged unhealthy.
+// Returns false if there is no alternate endpoint.
+func (f *FailoverClient) failover() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if len(f.endpoints) == 1 {
+		return false
+	}
+	next := (f.active + 1) % len(f.endpoints)
+	for i := 0; i < len(f.endpoints)-1; i++ {
+		idx := (f.active + 1 + i) % len(f.endpoints)
+		if f.endpoints[idx].healthy {
+			next = idx
+			break
+		}
+	}
+	logger.
+		WithField("from", f.endpoints[f.active].Name).
+		WithField("to", f.endpoints[next].Name).
+		Warnf("Failing over to alternate RPC endpoint")
+	f.active = next
+	RPCFailoverCounter.WithLabelValues(f.endpoints[next].Name).Inc()
+	return true
+}
+
+// BlockNumber returns the head block number of the active endpoint, checking its health first.
+// If the active endpoint is stale, it fails over and tries the alternates.
+func (f *FailoverClient) BlockNumber(ctx context.Context) (*big.Int, error) {
+	var lastErr error
+	for attempt := 0; attempt < len(f.endpoints); attempt++ {
+		e := f.current()
+
+		if err := f.checkChainID(ctx, e); err != nil {
+			lastErr = fmt.Errorf("failed to verify chain of %s: %w", e.Name, err)
+			if errors.Is(err, ErrChainMismatch) {
+				logger.
+					WithField("endpoint", e.Name).
+					Errorf("RPC endpoint serves the wrong chain: %v", err)
+			}
+			f.setHealthy(e, false)
+			if !f.failover() {
+				break
+			}
+			continue
+		}
+
+		block, err := e.Client.BlockByNumber(ctx, types.LatestBlockNumber, false)
+		if err != nil {
+			lastErr = fmt.Errorf("failed to get head block from %s: %w", e.Name, err)
+			f.setHealthy(e, false)
+			if !f.failover() {
+				break
+			}
+			continue
+		}
+
+		reason := f.checkHead(e, block)
+		if reason == "" {
+			f.setHealthy(e, true)
+			return block.Number, nil
+		}
+
+		logger.
+			WithField("endpoint", e.Name).
+			Warnf("RPC endpoint is stale: %s", reason)
+		f.setHealthy(e, false)
+		lastErr = fmt.Errorf("RPC endpoint %s is stale: %s", e.Name, reason)
+		if !f.failover() {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func (f *FailoverClient) Accounts(ctx context.Context) ([]types.Address, error) {
+	return f.current().Client.Accounts(ctx)
+}
+
+func (f *FailoverClient) BlockByNumber(ctx context.Context, number types.BlockNumber, full bool) (*types.Block, error) {
+	return f.current().Client.BlockByNumber(ctx, number, full)
+}
+
+func (f *FailoverClient) SendTransaction(ctx context.Context, tx *types.Transaction) (*types.Hash, *types.Transaction, error) {
+	hash, sent, err := f.sendTransaction(ctx, tx)
+	if err == nil && f.SendTracker != nil {
+		f.SendTracker.Sent()
+	}
+	return hash, sent, err
+}
+
+func (f *FailoverClient) Call(ctx context.Context, call *types.Call, block types.BlockNumber) ([]byte, *types.Call, error) {
+	return f.current().Client.Call(ctx, call, block)
+}
+
+// GetLogs fetches logs from the active endpoint, queries of recent blocks are hedged if HedgeDelay is set.
+func (f *FailoverClient) GetLogs(ctx context.Context, query *types.FilterLogsQuery) ([]types.Log, error) {
+	e := f.current()
+	if !isHedgedLogQuery(query) {
+		return e.Client.GetLogs(ctx, query)
+	}
+	return hedge(ctx, f, e, "eth_getLogs", func(ctx context.Context, client RPCClient) ([]types.Log, error) {
+		return client.GetLogs(ctx, query)
+	})
+}
+
+func (f *FailoverClient) GetTransactionReceipt(ctx context.Context, hash types.Hash) (*types.TransactionReceipt, error) {
+	return f.current().Client.GetTransactionReceipt(ctx, hash)
+}
+
+func (f *FailoverClient) GetBalance(ctx context.Context, address types.Address, block types.BlockNumber) (*big.Int, error) {
+	return f.current().Client.GetBalance(ctx, address, block)
+}
+
+// SubscribeNewHeads implements HeadSubscriber interface if the active endpoint supports subscriptions.
+func (f *FailoverClient) SubscribeNewHeads(ctx context.Context) (<-chan types.Block, error) {
+	e := f.current()
+	s, ok := e.Client.(HeadSubscriber)
+	if !ok {
+		return nil, fmt.Errorf("endpoint %s does not support subscriptions", e.Name)
+	}
+	return s.SubscribeNewHeads(ctx)
+}
+
+func (f *FailoverClient) GetTransactionByHash(ctx context.Context, hash types.Hash) (*types.OnChainTransaction, error) {
+	return f.current().Client.GetTransactionByHash(ctx, hash)
+}
+
+func (f *FailoverClient) ChainID(ctx context.Context) (uint64, error) {
+	return f.current().Client.ChainID(ctx)
+}
+
+// SubscribeLogs subscribes to logs using the active endpoint, the subscription doesn't follow later failovers.
+func (f *FailoverClient) SubscribeLogs(ctx context.Context, query *types.FilterLogsQuery) (<-chan types.Log, error) {
+	return f.current().Client.SubscribeLogs(ctx, query)
+}
+
+// GetTransactionCount implements NonceFetcher interface if the active endpoint supports it.
+func (f *FailoverClient) GetTransactionCount(ctx context.Context, account types.Address, block types.BlockNumber) (uint64, error) {
+	e := f.current()
+	n, ok := e.Client.(NonceFetcher)
+	if !ok {
+		return 0, fmt.Errorf("endpoint %s does not support fetching transaction count", e.Name)
+	}
+	return n.GetTransactionCount(ctx, account, block)
+}
+
+// GetCode implements CodeFetcher interface if the active endpoint supports it.
+func (f *FailoverClient) GetCode(ctx context.Context, account types.Address, block types.BlockNumber) ([]byte, error) {
+	e := f.current()
+	c, ok := e.Client.(CodeFetcher)
+	if !ok {
+		return nil, fmt.Errorf("endpoint %s does not support fetching code", e.Name)
+	}
+	return c.GetCode(ctx, account, block)
+}
+
+// GetStorageAt implements CodeFetcher interface if the active endpoint supports it.
+func (f *FailoverClient) GetStorageAt(ctx context.Context, account types.Address, key types.Hash, block types.BlockNumber) (*types.Hash, error) {
+	e := f.current()
+	c, ok := e.Client.(CodeFetcher)
+	if !ok {
+		return nil, fmt.Errorf("endpoint %s does not support fetching storage", e.Name)
+	}
+	return c.GetStorageAt(ctx, account, key, block)
+}
+
+// EstimateGas implements GasEstimator interface if the active endpoint supports it.
+func (f *FailoverClient) EstimateGas(ctx context.Context, call *types.Call, block types.BlockNumber) (uint64, *types.Call, error) {
+	e := f.current()
+	g, ok := e.Client.(GasEstimator)
+	if !ok {
+		return 0, nil, fmt.Errorf("endpoint %s does not support gas estimation", e.Name)
+	}
+	return g.EstimateGas(ctx, call, block)
+}
+
+// GasPrice implements GasEstimator interface if the active endpoint supports it.
+func (f *FailoverClient) GasPrice(ctx context.Context) (*big.Int, error) {
+	e := f.current()
+	g, ok := e.Client.(GasEstimator)
+	if !ok {
+		return nil, fmt.Errorf("endpoint %s does not support gas estimation", e.Name)
+	}
+	return g.GasPrice(ctx)
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailoverClient(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+
+	t.Run("requires at least one endpoint", func(t *testing.T) {
+		_, err := NewFailoverClient()
+		assert.Error(t, err)
+	})
+
+	t.Run("healthy endpoint returns head block number", func(t *testing.T) {
+		primary := new(mockRpcClient)
+		primary.On("BlockByNumber", mock.Anything, types.LatestBlockNumber, false).
+			Return(&types.Block{Number: big.NewInt(100), Timestamp: now.Add(-10 * time.Second)}, nil)
+
+		f, err := NewFailoverClient(RPCEndpoint{Name: "primary", Client: primary})
+		require.NoError(t, err)
+		f.now = func() time.Time { return now }
+
+		n, err := f.BlockNumber(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(100), n)
+		assert.Equal(t, "primary", f.Active())
+	})
+
+	t.Run("timestamp drift fails over to alternate", func(t *testing.T) {
+		primary := new(mockRpcClient)
+		primary.On("BlockByNumber", mock.Anything, types.LatestBlockNumber, false).
+			Return(&types.Block{Number: big.NewInt(100), Timestamp: now.Add(-time.Hour)}, nil)
+		alternate := new(mockRpcClient)
+		alternate.On("BlockByNumber", mock.Anything, types.LatestBlockNumber, false).
+			Return(&types.Block{Number: big.NewInt(400), Timestamp: now}, nil)
+
+		f, err := NewFailoverClient(
+			RPCEndpoint{Name: "primary", Client: primary},
+			RPCEndpoint{Name: "alternate", Client: alternate},
+		)
+		require.NoError(t, err)
+		f.now = func() time.Time { return now }
+
+		n, err := f.BlockNumber(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(400), n)
+		assert.Equal(t, "alternate", f.Active())
+
+		// Calls go to the endpoint failed over to.
+		alternate.On("ChainID", mock.Anything).Return(uint64(1), nil)
+		chainID, err := f.ChainID(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, uint64(1), chainID)
+		primary.AssertNotCalled(t, "ChainID", mock.Anything)
+	})
+
+	t.Run("unchanged head is detected as stale", func(t *testing.T) {
+		primary := new(mockRpcClient)
+		primary.On("BlockByNumber", mock.Anything, types.LatestBlockNumber, false).
+			Return(&types.Block{Number: big.NewInt(100), Timestamp: now}, nil)
+
+		f, err := NewFailoverClient(RPCEndpoint{Name: "primary", Client: primary})
+		require.NoError(t, err)
+		f.MaxBlockDrift = 0
+
+		clock := now
+		f.now = func() time.Time { return clock }
+
+		_, err = f.BlockNumber(context.TODO())
+		require.NoError(t, err)
+
+		clock = now.Add(f.StaleHeadTimeout + time.Second)
+		_, err = f.BlockNumber(context.TODO())
+		assert.ErrorContains(t, err, "unchanged")
+	})
+
+	t.Run("error on head block fails over", func(t *testing.T) {
+		primary := new(mockRpcClient)
+		primary.On("BlockByNumber", mock.Anything, types.LatestBlockNumber, false).
+			Return((*types.Block)(nil), fmt.Errorf("connection refused"))
+		alternate := new(mockRpcClient)
+		alternate.On("BlockByNumber", mock.Anything, types.LatestBlockNumber, false).
+			Return(&types.Block{Number: big.NewInt(101), Timestamp: now}, nil)
+
+		f, err := NewFailoverClient(
+			RPCEndpoint{Name: "primary", Client: primary},
+			RPCEndpoint{Name: "alternate", Client: alternate},
+		)
+		require.NoError(t, err)
+		f.now = func() time.Time { return now }
+
+		n, err := f.BlockNumber(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(101), n)
+	})
+	t.Run("endpoint of another chain fails over", func(t *testing.T) {
+		primary := new(mockRpcClient)
+		primary.On("ChainID", mock.Anything).Return(uint64(100), nil)
+		alternate := new(mockRpcClient)
+		alternate.On("ChainID", mock.Anything).Return(uint64(1), nil).Once()
+		alternate.On("BlockByNumber", mock.Anything, types.LatestBlockNumber, false).
+			Return(&types.Block{Number: big.NewInt(101), Timestamp: now}, nil)
+
+		f, err := NewFailoverClient(
+			RPCEndpoint{Name: "primary", Client: primary},
+			RPCEndpoint{Name: "alternate", Client: alternate},
+		)
+		require.NoError(t, err)
+		f.now = func() time.Time { return now }
+		f.ExpectedChainID = 1
+
+		n, err := f.BlockNumber(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, big.NewInt(101), n)
+		assert.Equal(t, "alternate", f.Active())
+		primary.AssertNotCalled(t, "BlockByNumber", mock.Anything, mock.Anything, mock.Anything)
+
+		// Chain of the endpoint is verified once.
+		_, err = f.BlockNumber(context.TODO())
+		require.NoError(t, err)
+		alternate.AssertNumberOfCalls(t, "ChainID", 1)
+	})
+
+	t.Run("no endpoint of the expected chain", func(t *testing.T) {
+		primary := new(mockRpcClient)
+		primary.On("ChainID", mock.Anything).Return(uint64(100), nil)
+
+		f, err := NewFailoverClient(RPCEndpoint{Name: "primary", Client: primary})
+		require.NoError(t, err)
+		f.ExpectedChainID = 1
+
+		_, err = f.BlockNumber(context.TODO())
+		assert.ErrorIs(t, err, ErrChainMismatch)
+	})
+}
@@ -0,0 +1,398 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	logger "github.com/sirupsen/logrus"
+)
+
+// DefaultSafeAlertBefore is the default time before the challenge deadline an unexecuted proposal is alerted on.
+const DefaultSafeAlertBefore = 10 * time.Minute
+
+// safeServiceTimeout is the time limit for requests to the Safe Transaction Service.
+const safeServiceTimeout = 10 * time.Second
+
+// SafeCheckInterval is the interval proposed transactions are checked at in the Safe Transaction Service.
+var SafeCheckInterval = 30 * time.Second
+
+var (
+	safeNonce              = abi.MustParseMethod("nonce() returns (uint256)")
+	safeGetTransactionHash = abi.MustParseMethod(
+		"getTransactionHash(address to, uint256 value, bytes data, uint8 operation, uint256 safeTxGas, uint256 baseGas, uint256 gasPrice, address gasToken, address refundReceiver, uint256 nonce) returns (bytes32)",
+	)
+)
+
+// safeProposal is the body of multisig transaction proposal of the Safe Transaction Service API.
+type safeProposal struct {
+	To                      string `json:"to"`
+	Value                   string `json:"value"`
+	Data                    string `json:"data"`
+	Operation               int    `json:"operation"`
+	SafeTxGas               string `json:"safeTxGas"`
+	BaseGas                 string `json:"baseGas"`
+	GasPrice                string `json:"gasPrice"`
+	GasToken                string `json:"gasToken"`
+	RefundReceiver          string `json:"refundReceiver"`
+	Nonce                   string `json:"nonce"`
+	ContractTransactionHash string `json:"contractTransactionHash"`
+	Sender                  string `json:"sender"`
+	Signature               string `json:"signature"`
+	Origin                  string `json:"origin"`
+}
+
+// safeTransaction is the state of a proposed multisig transaction in the Safe Transaction Service.
+type safeTransaction struct {
+	IsExecuted            bool        `json:"isExecuted"`
+	IsSuccessful          *bool       `json:"isSuccessful"`
+	TransactionHash       *types.Hash `json:"transactionHash"`
+	ConfirmationsRequired int         `json:"confirmationsRequired"`
+	Confirmations         []struct {
+		Owner types.Address `json:"owner"`
+	} `json:"confirmations"`
+}
+
+// SafeProposer proposes challenges to a Safe multisig through the Safe Transaction Service instead of sending
+// them, so owners of the Safe co-sign each challenge. The key has to be an owner or a delegate of the Safe,
+// its signature is the first confirmation. Proposals still waiting for confirmations close to the challenge
+// deadline are alerted on. Challenge rewards are paid to the Safe.
+type SafeProposer struct {
+	ctx        context.Context
+	client     RPCClient
+	signer     wallet.KeyWithHashSigner
+	safe       types.Address
+	serviceURL string
+	httpClient *http.Client
+
+	mu       sync.Mutex
+	outcomes map[types.Address]chan TxOutcome
+
+	// pending holds nonces of proposals being watched, proposing is serialized by proposeMu.
+	proposeMu sync.Mutex
+	pending   map[uint64]struct{}
+
+	now func() time.Time
+
+	// APIKey is sent as bearer token to the Safe Transaction Service, optional.
+	APIKey string
+	// AlertBefore is the time before the challenge deadline unexecuted proposals are alerted on.
+	AlertBefore time.Duration
+	// Tag is appended to `opChallenge` calldata, see NewChallengeTag.
+	Tag []byte
+}
+
+// NewSafeProposer creates a new instance of SafeProposer,
+// serviceURL is the Safe Transaction Service of the chain, e.g. https://safe-transaction-mainnet.safe.global.
+func NewSafeProposer(
+	ctx context.Context,
+	client RPCClient,
+	signer wallet.KeyWithHashSigner,
+	safe types.Address,
+	serviceURL string,
+) *SafeProposer {
+	return &SafeProposer{
+		ctx:         ctx,
+		client:      client,
+		signer:      signer,
+		safe:        safe,
+		serviceURL:  strings.TrimSuffix(serviceURL, "/"),
+		httpClient:  &http.Client{Timeout: safeServiceTimeout},
+		outcomes:    make(map[types.Address]chan TxOutcome),
+		pending:     make(map[uint64]struct{}),
+		now:         time.Now,
+		AlertBefore: DefaultSafeAlertBefore,
+	}
+}
+
+// Wrap returns provider which proposes challenges of pokes under the address to the Safe.
+func (s *SafeProposer) Wrap(address types.Address, provider IScribeOptimisticProvider) IScribeOptimisticProvider {
+	return &safeProvider{
+		IScribeOptimisticProvider: provider,
+		proposer:                  s,
+		outcomes:                  s.outcomesOf(address),
+	}
+}
+
+func (s *SafeProposer) outcomesOf(address types.Address) chan TxOutcome {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.outcomes[address]; !ok {
+		s.outcomes[address] = make(chan TxOutcome, txOutcomesBuffer)
+	}
+	return s.outcomes[address]
+}
+
+// Propose proposes the challenge to the Safe and watches it until it's executed or the deadline passes.
+// Returned hash is the Safe transaction hash.
+func (s *SafeProposer) Propose(
+	ctx context.Context,
+	address types.Address,
+	poke *OpPokedEvent,
+	deadline time.Time,
+) (*types.Hash, error) {
+	calldata, err := EncodeChallengeCalldata(poke, s.Tag)
+	if err != nil {
+		return nil, err
+	}
+
+	// Proposals are serialized, so each of them gets its own Safe nonce.
+	s.proposeMu.Lock()
+	defer s.proposeMu.Unlock()
+
+	nonce, err := s.getNonce(ctx)
+	if err != nil {
+		return nil, err
+	}
+	safeTxHash, err := s.getTransactionHash(ctx, address, calldata, nonce)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := s.signer.SignHash(ctx, safeTxHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign Safe transaction: %w", err)
+	}
+
+	proposal := safeProposal{
+		To:                      address.Checksum(crypto.Keccak256),
+		Value:                   "0",
+		Data:                    fmt.Sprintf("0x%x", calldata),
+		SafeTxGas:               "0",
+		BaseGas:                 "0",
+		GasPrice:                "0",
+		GasToken:                types.ZeroAddress.String(),
+		RefundReceiver:          types.ZeroAddress.String(),
+		Nonce:                   nonce.String(),
+		ContractTransactionHash: safeTxHash.String(),
+		Sender:                  s.signer.Address().Checksum(crypto.Keccak256),
+		Signature:               signature.String(),
+		Origin:                  "challenger",
+	}
+	path := fmt.Sprintf("/api/v1/safes/%s/multisig-transactions/", s.safe.Checksum(crypto.Keccak256))
+	if err := s.request(ctx, http.MethodPost, path, proposal, nil); err != nil {
+		return nil, fmt.Errorf("failed to propose Safe transaction: %w", err)
+	}
+	s.pending[nonce.Uint64()] = struct{}{}
+
+	logger.
+		WithField("address", address).
+		WithField("safe", s.safe).
+		WithField("safeTxHash", safeTxHash).
+		Warnf("Proposed challenge of OpPoked event from block %v to Safe with nonce %v, it has to be confirmed by owners before %v", poke.BlockNumber, nonce, deadline.UTC())
+
+	go func() {
+		s.watch(address, poke, safeTxHash, deadline)
+		s.proposeMu.Lock()
+		delete(s.pending, nonce.Uint64())
+		s.proposeMu.Unlock()
+	}()
+	return &safeTxHash, nil
+}
+
+// getNonce returns the nonce of the next proposal, nonces of watched proposals are skipped. Nonce of a proposal
+// which wasn't executed before its deadline is reused, so the stale proposal doesn't block the following ones.
+func (s *SafeProposer) getNonce(ctx context.Context) (*big.Int, error) {
+	b, _, err := s.client.Call(ctx, &types.Call{
+		To:    &s.safe,
+		Input: safeNonce.FourBytes().Bytes(),
+	}, types.LatestBlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce of Safe %v: %w", s.safe, err)
+	}
+	var nonce *big.Int
+	if err := safeNonce.DecodeValues(b, &nonce); err != nil {
+		return nil, fmt.Errorf("failed to decode Safe nonce: %w", err)
+	}
+	for {
+		if _, ok := s.pending[nonce.Uint64()]; !ok {
+			return nonce, nil
+		}
+		nonce.Add(nonce, big.NewInt(1))
+	}
+}
+
+// getTransactionHash returns the hash of the Safe transaction calling the address, signed by the owners.
+func (s *SafeProposer) getTransactionHash(
+	ctx context.Context,
+	address types.Address,
+	calldata []byte,
+	nonce *big.Int,
+) (types.Hash, error) {
+	input, err := safeGetTransactionHash.EncodeArgs(
+		address, big.NewInt(0), calldata, uint8(0), big.NewInt(0), big.NewInt(0), big.NewInt(0),
+		types.ZeroAddress, types.ZeroAddress, nonce,
+	)
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("failed to encode getTransactionHash args: %w", err)
+	}
+	b, _, err := s.client.Call(ctx, &types.Call{To: &s.safe, Input: input}, types.LatestBlockNumber)
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("failed to get Safe transaction hash: %w", err)
+	}
+	var hash types.Hash
+	if err := safeGetTransactionHash.DecodeValues(b, &hash); err != nil {
+		return types.Hash{}, fmt.Errorf("failed to decode Safe transaction hash: %w", err)
+	}
+	return hash, nil
+}
+
+// watch checks the proposal every SafeCheckInterval and reports the outcome once it's executed or the deadline
+// passes. The proposal is alerted on once, when less than AlertBefore is left and it's still not executed.
+func (s *SafeProposer) watch(address types.Address, poke *OpPokedEvent, safeTxHash types.Hash, deadline time.Time) {
+	ticker := time.NewTicker(SafeCheckInterval)
+	defer ticker.Stop()
+
+	SafePendingProposalsGauge.WithLabelValues(address.String()).Inc()
+	defer SafePendingProposalsGauge.WithLabelValues(address.String()).Dec()
+
+	outcome := TxOutcome{Address: address, Poke: poke, Hash: &safeTxHash}
+	alerted := false
+	for {
+		tx, err := s.getTransaction(s.ctx, safeTxHash)
+		switch {
+		case err != nil:
+			logger.
+				WithField("address", address).
+				WithField("safeTxHash", safeTxHash).
+				Errorf("Failed to check Safe transaction with error: %v", err)
+		case tx.IsExecuted && tx.TransactionHash != nil:
+			outcome.Hash = tx.TransactionHash
+			outcome.Receipt, outcome.Err = s.client.GetTransactionReceipt(s.ctx, *tx.TransactionHash)
+			if outcome.Err == nil && !hasChallengedEvent(outcome.Receipt, address) {
+				outcome.Err = fmt.Errorf("%w: Safe transaction %v executed without successful challenge", ErrChallengeReverted, safeTxHash)
+			}
+			s.report(outcome)
+			return
+		case !alerted && s.now().Add(s.AlertBefore).After(deadline):
+			alerted = true
+			SafeDeadlineAlertsCounter.WithLabelValues(address.String()).Inc()
+			logger.
+				WithField("address", address).
+				WithField("safe", s.safe).
+				WithField("safeTxHash", safeTxHash).
+				Warnf(
+					"Challenge proposal of OpPoked event from block %v is not executed, %d of %d confirmations, deadline at %v",
+					poke.BlockNumber,
+					len(tx.Confirmations),
+					tx.ConfirmationsRequired,
+					deadline.UTC(),
+				)
+		}
+
+		if !s.now().Before(deadline) {
+			outcome.Err = fmt.Errorf("%w: Safe transaction %v not executed before deadline %v", ErrTxNotIncluded, safeTxHash, deadline.UTC())
+			s.report(outcome)
+			return
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *SafeProposer) report(outcome TxOutcome) {
+	select {
+	case s.outcomesOf(outcome.Address) <- outcome:
+	case <-s.ctx.Done():
+	}
+}
+
+func (s *SafeProposer) getTransaction(ctx context.Context, safeTxHash types.Hash) (*safeTransaction, error) {
+	var tx safeTransaction
+	if err := s.request(ctx, http.MethodGet, fmt.Sprintf("/api/v1/multisig-transactions/%s/", safeTxHash), nil, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// request sends the request to the Safe Transaction Service, body and result are JSON encoded, both optional.
+func (s *SafeProposer) request(ctx context.Context, method string, path string, body any, result any) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.serviceURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	}
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Safe Transaction Service: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("Safe Transaction Service responded with status %d", res.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode Safe Transaction Service response: %w", err)
+	}
+	return nil
+}
+
+// safeProvider proposes challenges through SafeProposer, other calls go to the wrapped provider.
+type safeProvider struct {
+	IScribeOptimisticProvider
+	proposer *SafeProposer
+	outcomes chan TxOutcome
+}
+
+// ChallengePoke implements IScribeOptimisticProvider interface, the challenge is only proposed to the Safe.
+func (p *safeProvider) ChallengePoke(
+	ctx context.Context,
+	address types.Address,
+	poke *OpPokedEvent,
+) (*types.Hash, *types.Transaction, error) {
+	deadline, err := p.challengeDeadline(ctx, address, poke)
+	if err != nil {
+		return nil, nil, err
+	}
+	hash, err := p.proposer.Propose(ctx, address, poke, deadline)
+	return hash, nil, err
+}
+
+// challengeDeadline returns the time the challenge window of the poke closes.
+func (p *safeProvider) challengeDeadline(ctx context.Context, address types.Address, poke *OpPokedEvent) (time.Time, error) {
+	period, err := p.GetChallengePeriod(ctx, address)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get challenge period: %w", err)
+	}
+	block, err := p.BlockByNumber(ctx, poke.BlockNumber)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get block %v: %w", poke.BlockNumber, err)
+	}
+	return block.Timestamp.Add(time.Duration(period) * time.Second), nil
+}
+
+// ChallengeOutcomes implements IScribeOptimisticProvider interface.
+func (p *safeProvider) ChallengeOutcomes() <-chan TxOutcome {
+	return p.outcomes
+}
+
+// GetFrom implements IScribeOptimisticProvider interface, challenges are executed by the Safe.
+func (p *safeProvider) GetFrom(context.Context) types.Address {
+	return p.proposer.safe
+}
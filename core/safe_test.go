@@ -0,0 +1,160 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSafeService records proposals and reports them executed after the given number of checks.
+type fakeSafeService struct {
+	mu         sync.Mutex
+	proposals  []safeProposal
+	checks     int
+	executeAt  int
+	executedTx types.Hash
+}
+
+func (f *fakeSafeService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/multisig-transactions/"):
+		var p safeProposal
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.proposals = append(f.proposals, p)
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/api/v1/multisig-transactions/"):
+		f.checks++
+		tx := safeTransaction{ConfirmationsRequired: 2}
+		if f.executeAt > 0 && f.checks >= f.executeAt {
+			tx.IsExecuted = true
+			tx.TransactionHash = &f.executedTx
+		}
+		json.NewEncoder(w).Encode(tx)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestSafeProposer(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	safe := types.MustAddressFromHex("0x5afE3855358E112B5647B952709E6165e1c1eEEe")
+	signer := wallet.NewKeyFromBytes(types.MustBytesFromHex("0x0101010101010101010101010101010101010101010101010101010101010101"))
+	safeTxHash := types.MustHashFromHex("0xcccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc", types.PadNone)
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	challenged := ScribeOptimisticContractABI.Events["OpPokeChallengedSuccessfully"].Topic0()
+	status := uint64(1)
+
+	checkInterval := SafeCheckInterval
+	SafeCheckInterval = 10 * time.Millisecond
+	defer func() { SafeCheckInterval = checkInterval }()
+
+	next := func(t *testing.T, p IScribeOptimisticProvider) TxOutcome {
+		select {
+		case outcome := <-p.ChallengeOutcomes():
+			return outcome
+		case <-time.After(time.Second):
+			t.Fatal("no outcome")
+			return TxOutcome{}
+		}
+	}
+	newClient := func(t *testing.T, nonce int64) *mockRpcClient {
+		client := new(mockRpcClient)
+		n, err := abi.EncodeValues(safeNonce.Outputs(), big.NewInt(nonce))
+		require.NoError(t, err)
+		h, err := abi.EncodeValues(safeGetTransactionHash.Outputs(), safeTxHash)
+		require.NoError(t, err)
+		client.On("Call", mock.Anything, mock.MatchedBy(func(call *types.Call) bool {
+			return string(call.Input) == string(safeNonce.FourBytes().Bytes())
+		}), types.LatestBlockNumber).Return(n, nil, nil)
+		client.On("Call", mock.Anything, mock.MatchedBy(func(call *types.Call) bool {
+			return string(call.Input[:4]) == string(safeGetTransactionHash.FourBytes().Bytes())
+		}), types.LatestBlockNumber).Return(h, nil, nil)
+		return client
+	}
+	newProvider := func(pokedAt time.Time) *mockScribeOptimisticProvider {
+		provider := new(mockScribeOptimisticProvider)
+		provider.On("GetChallengePeriod", mock.Anything, address).Return(600, nil)
+		provider.On("BlockByNumber", mock.Anything, mock.Anything).Return(&types.Block{Timestamp: pokedAt}, nil)
+		return provider
+	}
+
+	t.Run("proposal executed by owners", func(t *testing.T) {
+		service := &fakeSafeService{executeAt: 2, executedTx: txHash}
+		server := httptest.NewServer(service)
+		defer server.Close()
+
+		client := newClient(t, 5)
+		client.On("GetTransactionReceipt", mock.Anything, txHash).Return(&types.TransactionReceipt{
+			TransactionHash: txHash,
+			Status:          &status,
+			Logs:            []types.Log{{Address: address, Topics: []types.Hash{challenged}}},
+		}, nil)
+
+		proposer := NewSafeProposer(context.Background(), client, signer, safe, server.URL+"/")
+		proposer.AlertBefore = time.Minute
+		p := proposer.Wrap(address, newProvider(time.Now()))
+		assert.Equal(t, safe, p.GetFrom(context.TODO()))
+
+		hash, _, err := p.ChallengePoke(context.TODO(), address, &OpPokedEvent{BlockNumber: big.NewInt(100)})
+		require.NoError(t, err)
+		assert.Equal(t, safeTxHash, *hash)
+
+		outcome := next(t, p)
+		require.NoError(t, outcome.Err)
+		assert.Equal(t, txHash, *outcome.Hash)
+
+		require.Len(t, service.proposals, 1)
+		proposal := service.proposals[0]
+		assert.Equal(t, "5", proposal.Nonce)
+		assert.Equal(t, address.Checksum(crypto.Keccak256), proposal.To)
+		assert.Equal(t, safeTxHash.String(), proposal.ContractTransactionHash)
+		signature, err := types.SignatureFromHex(proposal.Signature)
+		require.NoError(t, err)
+		assert.True(t, signer.VerifyHash(context.TODO(), safeTxHash, signature))
+	})
+
+	t.Run("proposal not executed before deadline", func(t *testing.T) {
+		service := &fakeSafeService{}
+		server := httptest.NewServer(service)
+		defer server.Close()
+
+		alerts := testutil.ToFloat64(SafeDeadlineAlertsCounter.WithLabelValues(address.String()))
+		proposer := NewSafeProposer(context.Background(), newClient(t, 5), signer, safe, server.URL)
+		// Challenge period of 600s is over in 50ms, which is within AlertBefore.
+		p := proposer.Wrap(address, newProvider(time.Now().Add(-600*time.Second+50*time.Millisecond)))
+
+		_, _, err := p.ChallengePoke(context.TODO(), address, &OpPokedEvent{BlockNumber: big.NewInt(100)})
+		require.NoError(t, err)
+		// The second proposal is queued after the first one.
+		_, _, err = p.ChallengePoke(context.TODO(), address, &OpPokedEvent{BlockNumber: big.NewInt(101)})
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, next(t, p).Err, ErrTxNotIncluded)
+		assert.ErrorIs(t, next(t, p).Err, ErrTxNotIncluded)
+		assert.Equal(t, alerts+2, testutil.ToFloat64(SafeDeadlineAlertsCounter.WithLabelValues(address.String())))
+
+		require.Len(t, service.proposals, 2)
+		assert.Equal(t, "5", service.proposals[0].Nonce)
+		assert.Equal(t, "6", service.proposals[1].Nonce)
+	})
+}
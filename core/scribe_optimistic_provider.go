@@ -16,10 +16,12 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	_ "embed"
 	"fmt"
 	"math/big"
+	"math/rand/v2"
 	"sync"
 	"time"
 
@@ -29,6 +31,22 @@ import (
 )
 
 var MaxFlashbotGasLimit = uint64(200000)
+
+// FlashbotInclusionBlocks is the number of blocks flashbots has to include the challenge transaction in,
+// after that it's resubmitted to the public mempool with the same nonce. Default of BundleTarget.Blocks.
+var FlashbotInclusionBlocks = uint64(10)
+
+// BundleTarget is the strategy of blocks challenges sent to the flashbots relay are targeted at.
+type BundleTarget struct {
+	// Delay is the number of blocks skipped before the first targeted one, 0 targets the next block.
+	// The challenge is sent to the relay only once the block before the first targeted one is mined.
+	Delay uint64
+	// Blocks is the number of blocks the challenge has to be included in, starting at the first targeted one.
+	// After them it's resubmitted, resubmissions target the next Blocks blocks without delay.
+	Blocks uint64
+}
+
+// TxConfirmationTimeout is the time limit for a challenge or sweep transaction to be mined.
 var TxConfirmationTimeout = 5 * time.Minute
 
 //go:embed ScribeOptimistic.json
@@ -41,67 +59,201 @@ var ScribeOptimisticContractABI = abi.MustParseJSON(scribeOptimisticContractJSON
 type ScribeOptimisticRpcProvider struct {
 	client         RPCClient
 	flashbotClient RPCClient
-	fromOnce       sync.Once
+	fromMu         sync.Mutex
 	fromAddr       types.Address
+	keys           *KeyRing
+	forwarder      *ForwarderConfig
+	tracker        *TxTracker
+	store          PendingStore
+	privateOnly    bool
+	jitter         time.Duration
+	target         BundleTarget
+	tag            []byte
+
+	pokeBlockVerification bool
+}
+
+// ProviderOption configures optional behavior of ScribeOptimisticRpcProvider.
+type ProviderOption func(*ScribeOptimisticRpcProvider)
+
+// WithForwarder routes `opChallenge` calls through the given forwarder contract.
+func WithForwarder(forwarder *ForwarderConfig) ProviderOption {
+	return func(s *ScribeOptimisticRpcProvider) {
+		s.forwarder = forwarder
+	}
+}
+
+// WithFrom sets the address challenges are sent from, usually derived from the signing key.
+// Without it, the address is fetched using `eth_accounts`, which remote signers may not support.
+func WithFrom(address types.Address) ProviderOption {
+	return func(s *ScribeOptimisticRpcProvider) {
+		s.fromAddr = address
+	}
+}
+
+// WithKeyRing makes challenges sent from the current key of the ring, so the address follows key rotations.
+func WithKeyRing(keys *KeyRing) ProviderOption {
+	return func(s *ScribeOptimisticRpcProvider) {
+		s.keys = keys
+	}
+}
+
+// WithPokeBlockVerification makes the provider verify poke signatures also against the state at the block
+// of the poke, which requires an archive node, and flag discrepancies with the verdict against the latest state.
+func WithPokeBlockVerification() ProviderOption {
+	return func(s *ScribeOptimisticRpcProvider) {
+		s.pokeBlockVerification = true
+	}
+}
+
+// WithPrivateOnly makes challenges go only through the flashbots relay, they are never sent to the public mempool,
+// where they could be front-run for the reward. Challenges not included in time are resubmitted to the relay
+// until the challenge window closes.
+func WithPrivateOnly() ProviderOption {
+	return func(s *ScribeOptimisticRpcProvider) {
+		s.privateOnly = true
+	}
+}
+
+// WithSubmissionJitter delays each challenge by random time up to d, so its timing is harder to predict.
+func WithSubmissionJitter(d time.Duration) ProviderOption {
+	return func(s *ScribeOptimisticRpcProvider) {
+		s.jitter = d
+	}
+}
+
+// WithBundleTarget sets blocks challenges sent to the flashbots relay target, by default the next
+// FlashbotInclusionBlocks blocks.
+func WithBundleTarget(target BundleTarget) ProviderOption {
+	return func(s *ScribeOptimisticRpcProvider) {
+		s.target = target
+	}
+}
+
+// WithChallengeTag appends the tag created by NewChallengeTag to `opChallenge` calldata.
+func WithChallengeTag(tag []byte) ProviderOption {
+	return func(s *ScribeOptimisticRpcProvider) {
+		s.tag = tag
+	}
+}
+
+// WithPendingStore persists sent challenges until their outcome is known, so they can be resumed after restart.
+func WithPendingStore(store PendingStore) ProviderOption {
+	return func(s *ScribeOptimisticRpcProvider) {
+		s.store = store
+	}
 }
 
 // NewScribeOptimisticRPCProvider creates a new instance of ScribeOptimisticRpcProvider.
 // Two clients are required: one for the mainnet and one for the flashbots relay.
-// Logic is simple, try to send with flashbots first, if it fails, send with the mainnet client.
-func NewScribeOptimisticRPCProvider(client RPCClient, flashbotClient RPCClient) *ScribeOptimisticRpcProvider {
-	return &ScribeOptimisticRpcProvider{
+// Logic is simple, try to send with flashbots first, if it fails or is not included, send with the mainnet client.
+func NewScribeOptimisticRPCProvider(
+	client RPCClient,
+	flashbotClient RPCClient,
+	opts ...ProviderOption,
+) *ScribeOptimisticRpcProvider {
+	s := &ScribeOptimisticRpcProvider{
 		client:         client,
 		flashbotClient: flashbotClient,
+		target:         BundleTarget{Blocks: FlashbotInclusionBlocks},
+	}
+	s.tracker = NewTxTracker(s.handleRevert)
+	for _, opt := range opts {
+		opt(s)
 	}
+	s.tracker.store = s.store
+	return s
 }
 
+// GetFrom returns the address challenges are sent from. Unless set by WithKeyRing or WithFrom, it's the first account
+// of the node, fetched until it's known, so the zero address is returned only while accounts are unavailable.
 func (s *ScribeOptimisticRpcProvider) GetFrom(ctx context.Context) types.Address {
-	s.fromOnce.Do(func() {
-		accs, err := s.client.Accounts(ctx)
-		if err != nil {
-			logger.Errorf("failed to get accounts with error: %v", err)
-			return
-		}
-		if len(accs) == 0 {
-			logger.Errorf("no accounts found")
-			return
-		}
-		s.fromAddr = accs[0]
-	})
+	if s.keys != nil {
+		return s.keys.Address()
+	}
+	s.fromMu.Lock()
+	defer s.fromMu.Unlock()
+	if s.fromAddr != types.ZeroAddress {
+		return s.fromAddr
+	}
+	accs, err := s.client.Accounts(ctx)
+	if err != nil {
+		logger.Errorf("failed to get accounts with error: %v", err)
+		return types.ZeroAddress
+	}
+	if len(accs) == 0 {
+		logger.Errorf("no accounts found")
+		return types.ZeroAddress
+	}
+	s.fromAddr = accs[0]
 	return s.fromAddr
 }
 
 func (s *ScribeOptimisticRpcProvider) BlockByNumber(ctx context.Context, blockNumber *big.Int) (*types.Block, error) {
-	return s.client.BlockByNumber(ctx, types.BlockNumberFromBigInt(blockNumber), false)
+	block, err := s.client.BlockByNumber(ctx, types.BlockNumberFromBigInt(blockNumber), false)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRPCUnavailable, err)
+	}
+	return block, nil
 }
 
 func (s *ScribeOptimisticRpcProvider) BlockNumber(ctx context.Context) (*big.Int, error) {
-	return s.client.BlockNumber(ctx)
+	number, err := s.client.BlockNumber(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRPCUnavailable, err)
+	}
+	return number, nil
 }
 
 // GetChallengePeriod returns the challenge period of the contract using call.
 func (s *ScribeOptimisticRpcProvider) GetChallengePeriod(ctx context.Context, address types.Address) (uint16, error) {
-	opChallengePeriod := ScribeOptimisticContractABI.Methods["opChallengePeriod"]
-	calldata, err := opChallengePeriod.EncodeArgs()
+	var period uint16
+	if err := s.callView(ctx, address, "opChallengePeriod", &period); err != nil {
+		return 0, err
+	}
+	return period, nil
+}
+
+// GetChallengeReward returns the reward paid for a successful challenge using call, i.e. the maximum
+// challenge reward capped by the balance of the contract.
+func (s *ScribeOptimisticRpcProvider) GetChallengeReward(ctx context.Context, address types.Address) (*big.Int, error) {
+	var reward *big.Int
+	if err := s.callView(ctx, address, "challengeReward", &reward); err != nil {
+		return nil, err
+	}
+	return reward, nil
+}
+
+// GetMaxChallengeReward returns the maximum challenge reward configured in the contract using call.
+func (s *ScribeOptimisticRpcProvider) GetMaxChallengeReward(ctx context.Context, address types.Address) (*big.Int, error) {
+	var reward *big.Int
+	if err := s.callView(ctx, address, "maxChallengeReward", &reward); err != nil {
+		return nil, err
+	}
+	return reward, nil
+}
+
+// callView calls the contract function without arguments at the latest block and decodes its result into res.
+func (s *ScribeOptimisticRpcProvider) callView(ctx context.Context, address types.Address, name string, res any) error {
+	method := ScribeOptimisticContractABI.Methods[name]
+	calldata, err := method.EncodeArgs()
 	if err != nil {
-		return 0, fmt.Errorf("failed to encode opChallengePeriod args: %v", err)
+		return fmt.Errorf("failed to encode %s args: %w", name, err)
 	}
 	b, _, err := s.client.Call(ctx, &types.Call{
 		To:    &address,
 		Input: calldata,
 	}, types.LatestBlockNumber)
-
 	if err != nil {
-		return 0, fmt.Errorf("failed to call opChallengePeriod with error: %v", err)
+		return fmt.Errorf("failed to call %s with error: %w: %w", name, ErrRPCUnavailable, err)
 	}
 
 	// Decode the result.
-	var period uint16
-	err = opChallengePeriod.DecodeValues(b, &period)
+	err = method.DecodeValues(b, res)
 	if err != nil {
-		return 0, fmt.Errorf("failed to decode opChallengePeriod result with error: %v", err)
+		return fmt.Errorf("failed to decode %s result with error: %w: %w", name, ErrDecode, err)
 	}
-	return period, nil
+	return nil
 }
 
 // GetPokes returns list of the `OpPoked` events within the given block range under `address`.
@@ -122,12 +274,18 @@ func (s *ScribeOptimisticRpcProvider) GetPokes(
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get OpPoked events with error: %v", err)
+		return nil, fmt.Errorf("failed to get OpPoked events with error: %w: %w", ErrRPCUnavailable, err)
 	}
 
 	var result []*OpPokedEvent
 	for _, poke := range pokeLogs {
 		decoded, err := DecodeOpPokeEvent(poke)
+		if err != nil {
+			logger.
+				WithField("address", address).
+				Warnf("Failed to decode OpPoked event with error: %v, decoding it from transaction calldata", err)
+			decoded, err = s.decodeOpPokeFromTx(ctx, address, poke)
+		}
 		if err != nil {
 			logger.
 				WithField("address", address).
@@ -139,6 +297,41 @@ func (s *ScribeOptimisticRpcProvider) GetPokes(
 	return result, nil
 }
 
+// decodeOpPokeFromTx reconstructs the OpPoked event from `opPoke` calldata of the transaction which emitted the log,
+// used when the log data returned by the provider is malformed. Only direct `opPoke` calls can be decoded.
+func (s *ScribeOptimisticRpcProvider) decodeOpPokeFromTx(
+	ctx context.Context,
+	address types.Address,
+	log types.Log,
+) (*OpPokedEvent, error) {
+	if log.TransactionHash == nil {
+		return nil, fmt.Errorf("log has no transaction hash")
+	}
+	tx, err := s.client.GetTransactionByHash(ctx, *log.TransactionHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %v with error: %w: %w", log.TransactionHash, ErrRPCUnavailable, err)
+	}
+	if tx == nil || tx.To == nil || *tx.To != address {
+		return nil, fmt.Errorf("transaction %v is not a direct call of %v", log.TransactionHash, address)
+	}
+	poke, ok := decodeOpPoke(tx.Input)
+	if !ok {
+		return nil, fmt.Errorf("transaction %v is not an opPoke call", log.TransactionHash)
+	}
+
+	poke.BlockNumber = log.BlockNumber
+	poke.TxHash = log.TransactionHash
+	poke.LogIndex = log.LogIndex
+	// Indexed caller and opFeed are kept in topics, which are usually intact.
+	if len(log.Topics) == 3 {
+		poke.Caller = types.MustAddressFromBytes(log.Topics[1].Bytes()[12:])
+		poke.OpFeed = types.MustAddressFromBytes(log.Topics[2].Bytes()[12:])
+	} else if tx.From != nil {
+		poke.Caller = *tx.From
+	}
+	return poke, nil
+}
+
 // GetSuccessfulChallenges returns list of the `OpPokeChallengedSuccessfully` events within the given block range under `address`.
 func (s *ScribeOptimisticRpcProvider) GetSuccessfulChallenges(
 	ctx context.Context,
@@ -157,7 +350,7 @@ func (s *ScribeOptimisticRpcProvider) GetSuccessfulChallenges(
 	})
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to get OpPokeChallengedSuccessfully events with error: %v", err)
+		return nil, fmt.Errorf("failed to get OpPokeChallengedSuccessfully events with error: %w: %w", ErrRPCUnavailable, err)
 	}
 	var result []*OpPokeChallengedSuccessfullyEvent
 	for _, challenge := range challenges {
@@ -168,44 +361,166 @@ func (s *ScribeOptimisticRpcProvider) GetSuccessfulChallenges(
 				Errorf("Failed to decode OpPokeChallengedSuccessfully event with error: %v", err)
 			continue
 		}
+		if schnorr, err := s.decodeChallengedSchnorr(ctx, challenge); err == nil {
+			decoded.Schnorr = schnorr
+		} else {
+			logger.
+				WithField("address", address).
+				Debugf("Unable to decode challenged schnorr data, pairing challenge by order: %v", err)
+		}
+		result = append(result, decoded)
+	}
+	return result, nil
+}
+
+// GetRegularPokes returns list of the `Poked` events within the given block range under `address`.
+func (s *ScribeOptimisticRpcProvider) GetRegularPokes(
+	ctx context.Context,
+	address types.Address,
+	fromBlock *big.Int,
+	toBlock *big.Int,
+) ([]*PokedEvent, error) {
+	event := ScribeOptimisticContractABI.Events["Poked"]
+
+	// Fetch logs for Poked events.
+	pokes, err := s.client.GetLogs(ctx, &types.FilterLogsQuery{
+		Address:   []types.Address{address},
+		FromBlock: types.BlockNumberFromBigIntPtr(fromBlock),
+		ToBlock:   types.BlockNumberFromBigIntPtr(toBlock),
+		Topics:    [][]types.Hash{{event.Topic0()}},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Poked events with error: %w: %w", ErrRPCUnavailable, err)
+	}
+	var result []*PokedEvent
+	for _, poke := range pokes {
+		decoded, err := DecodePokedEvent(poke)
+		if err != nil {
+			logger.
+				WithField("address", address).
+				Errorf("Failed to decode Poked event with error: %v", err)
+			continue
+		}
+		result = append(result, decoded)
+	}
+	return result, nil
+}
+
+// GetContractStateEvents returns list of the events of ContractStateEventNames within the given block range under `address`.
+func (s *ScribeOptimisticRpcProvider) GetContractStateEvents(
+	ctx context.Context,
+	address types.Address,
+	fromBlock *big.Int,
+	toBlock *big.Int,
+) ([]*ContractStateEvent, error) {
+	var topics []types.Hash
+	for _, name := range ContractStateEventNames {
+		topics = append(topics, ScribeOptimisticContractABI.Events[name].Topic0())
+	}
+
+	// Fetch logs of all the events at once.
+	logs, err := s.client.GetLogs(ctx, &types.FilterLogsQuery{
+		Address:   []types.Address{address},
+		FromBlock: types.BlockNumberFromBigIntPtr(fromBlock),
+		ToBlock:   types.BlockNumberFromBigIntPtr(toBlock),
+		Topics:    [][]types.Hash{topics},
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contract state events with error: %w: %w", ErrRPCUnavailable, err)
+	}
+	var result []*ContractStateEvent
+	for _, log := range logs {
+		decoded, err := DecodeContractStateEvent(log)
+		if err != nil {
+			logger.
+				WithField("address", address).
+				Errorf("Failed to decode contract state event with error: %v", err)
+			continue
+		}
 		result = append(result, decoded)
 	}
 	return result, nil
 }
 
+// decodeChallengedSchnorr returns schnorr data of the poke challenged by the transaction which emitted the log.
+// Besides direct `opChallenge` calls, calls wrapped by a forwarder or multicall contract are found in calldata.
+func (s *ScribeOptimisticRpcProvider) decodeChallengedSchnorr(ctx context.Context, log types.Log) (*SchnorrData, error) {
+	if log.TransactionHash == nil {
+		return nil, fmt.Errorf("log has no transaction hash")
+	}
+	tx, err := s.client.GetTransactionByHash(ctx, *log.TransactionHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %v with error: %w: %w", log.TransactionHash, ErrRPCUnavailable, err)
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %v not found", log.TransactionHash)
+	}
+	schnorr, ok := decodeOpChallenge(tx.Input)
+	if !ok {
+		return nil, fmt.Errorf("transaction %v has no opChallenge call", log.TransactionHash)
+	}
+	return schnorr, nil
+}
+
+// decodeOpChallenge decodes schnorr data from `opChallenge` calldata. If the input is not `opChallenge` call itself,
+// it's looked up in ABI encoded arguments, where nested calldata starts at 32 byte word boundary.
+func decodeOpChallenge(input []byte) (*SchnorrData, bool) {
+	opChallenge := ScribeOptimisticContractABI.Methods["opChallenge"]
+	selector := opChallenge.FourBytes().Bytes()
+	// Arguments of the outer call start after its selector, so nested calldata starts at 4+32k offset.
+	offsets := []int{0}
+	for offset := 4 + 32; offset+4 <= len(input); offset += 32 {
+		offsets = append(offsets, offset)
+	}
+	for _, offset := range offsets {
+		if len(input) < offset+4 || !bytes.Equal(input[offset:offset+4], selector) {
+			continue
+		}
+		var schnorr SchnorrData
+		if err := opChallenge.DecodeArgs(input[offset:], &schnorr); err == nil {
+			return &schnorr, true
+		}
+	}
+	return nil, false
+}
+
 func (s *ScribeOptimisticRpcProvider) constructPokeMessage(
 	ctx context.Context,
 	address types.Address,
 	poke *OpPokedEvent,
+	block types.BlockNumber,
 ) ([]byte, error) {
 	constructMessage := ScribeOptimisticContractABI.Methods["constructPokeMessage"]
 	calldata, err := constructMessage.EncodeArgs(poke.PokeData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to encode constructOpPokeMessage args: %v", err)
+		return nil, fmt.Errorf("failed to encode constructOpPokeMessage args: %w", err)
 	}
 	b, _, err := s.client.Call(ctx, &types.Call{
 		To:    &address,
 		Input: calldata,
-	}, types.LatestBlockNumber)
+	}, block)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to call constructOpPokeMessage with error: %v", err)
+		return nil, fmt.Errorf("failed to call constructOpPokeMessage with error: %w: %w", ErrRPCUnavailable, err)
 	}
+	recordCall(ctx, "constructPokeMessage", address, calldata, b, block)
 
 	// Decode the result.
 	var message []byte
 	err = constructMessage.DecodeValues(b, &message)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode constructOpPokeMessage result with error: %v", err)
+		return nil, fmt.Errorf("failed to decode constructOpPokeMessage result with error: %w: %w", ErrDecode, err)
 	}
-	logger.
-		WithField("address", address).
-		Debugf(
-			"cast call %v 'constructPokeMessage((uint128,uint32))' '(%v,%v)'",
-			address,
-			poke.PokeData.Val,
-			poke.PokeData.Age,
-		)
+	sampledDebugf(
+		LogCategoryCall,
+		logger.WithField("address", address),
+		"cast call %v 'constructPokeMessage((uint128,uint32))' '(%v,%v)'",
+		address,
+		poke.PokeData.Val,
+		poke.PokeData.Age,
+	)
 	return message, nil
 }
 
@@ -214,163 +529,508 @@ func (s *ScribeOptimisticRpcProvider) isSchnorrSignatureAcceptable(
 	address types.Address,
 	poke *OpPokedEvent,
 	message []byte,
+	block types.BlockNumber,
 ) (bool, error) {
 	isAcceptableSignature := ScribeOptimisticContractABI.Methods["isAcceptableSchnorrSignatureNow"]
 	calldata, err := isAcceptableSignature.EncodeArgs(message, poke.Schnorr)
 	if err != nil {
-		return false, fmt.Errorf("failed to encode isAcceptableSchnorrSignatureNow args: %v", err)
+		return false, fmt.Errorf("failed to encode isAcceptableSchnorrSignatureNow args: %w", err)
 	}
 	b, _, err := s.client.Call(ctx, &types.Call{
 		To:    &address,
 		Input: calldata,
-	}, types.LatestBlockNumber)
+	}, block)
 
 	if err != nil {
-		return false, fmt.Errorf("failed to call isAcceptableSchnorrSignatureNow with error: %v", err)
+		return false, fmt.Errorf("failed to call isAcceptableSchnorrSignatureNow with error: %w: %w", ErrRPCUnavailable, err)
 	}
+	recordCall(ctx, "isAcceptableSchnorrSignatureNow", address, calldata, b, block)
 
 	// Decode the result.
 	var res bool
 	err = isAcceptableSignature.DecodeValues(b, &res)
 	if err != nil {
-		return false, fmt.Errorf("failed to decode isAcceptableSchnorrSignatureNow result with error: %v", err)
+		return false, fmt.Errorf("failed to decode isAcceptableSchnorrSignatureNow result with error: %w: %w", ErrDecode, err)
 	}
 
-	logger.
-		WithField("address", address).
-		Debugf(
-			"cast call %v 'isAcceptableSchnorrSignatureNow(bytes32,(bytes32,address,bytes))(bool)' %s '(%s,%v,%s)'",
-			address,
-			fmt.Sprintf("0x%x", message),
-			fmt.Sprintf("0x%x", poke.Schnorr.Signature),
-			poke.Schnorr.Commitment,
-			fmt.Sprintf("0x%x", poke.Schnorr.SignersBlob),
-		)
+	sampledDebugf(
+		LogCategoryCall,
+		logger.WithField("address", address),
+		"cast call %v 'isAcceptableSchnorrSignatureNow(bytes32,(bytes32,address,bytes))(bool)' %s '(%s,%v,%s)'",
+		address,
+		fmt.Sprintf("0x%x", message),
+		fmt.Sprintf("0x%x", poke.Schnorr.Signature),
+		poke.Schnorr.Commitment,
+		fmt.Sprintf("0x%x", poke.Schnorr.SignersBlob),
+	)
 
 	return res, nil
 }
 
 // IsPokeSignatureValid returns true if the given poke signature is valid.
 // Signature validation flow described here: https://github.com/chronicleprotocol/scribe/blob/main/docs/Scribe.md#verifying-optimistic-pokes
-func (s *ScribeOptimisticRpcProvider) IsPokeSignatureValid(ctx context.Context, address types.Address, poke *OpPokedEvent) (bool, error) {
-	message, err := s.constructPokeMessage(ctx, address, poke)
+// The signature is verified against the latest state, as the contract does when challenged. With poke block
+// verification enabled, it's also verified against the state at the block of the poke and discrepancies are flagged.
+func (s *ScribeOptimisticRpcProvider) IsPokeSignatureValid(
+	ctx context.Context,
+	address types.Address,
+	poke *OpPokedEvent,
+) (valid bool, err error) {
+	ctx, span := tracer.Start(ctx, "ScribeOptimisticRpcProvider.IsPokeSignatureValid")
+	defer func() { endSpan(span, err) }()
+
+	valid, err = s.isPokeSignatureValidAt(ctx, address, poke, types.LatestBlockNumber)
+	if err != nil || !s.pokeBlockVerification || poke.BlockNumber == nil {
+		return valid, err
+	}
+	validAtPoke, err := s.isPokeSignatureValidAt(ctx, address, poke, types.BlockNumberFromBigInt(poke.BlockNumber))
+	if err != nil {
+		// Archive state may not be available, the latest verdict still holds.
+		logger.
+			WithField("address", address).
+			Warnf("Failed to verify OpPoked signature at block %v with error: %v", poke.BlockNumber, err)
+		return valid, nil
+	}
+	if validAtPoke != valid {
+		logger.
+			WithField("address", address).
+			WithField("pokeTxHash", poke.TxHash).
+			Warnf("Signature of OpPoked event from block %v is valid at its block: %v, but valid now: %v", poke.BlockNumber, validAtPoke, valid)
+		SignatureDiscrepanciesCounter.WithLabelValues(address.String()).Inc()
+	}
+	return valid, nil
+}
+
+func (s *ScribeOptimisticRpcProvider) isPokeSignatureValidAt(
+	ctx context.Context,
+	address types.Address,
+	poke *OpPokedEvent,
+	block types.BlockNumber,
+) (bool, error) {
+	message, err := s.constructPokeMessage(ctx, address, poke, block)
 	if err != nil {
 		return false, err
 	}
-	return s.isSchnorrSignatureAcceptable(ctx, address, poke, message)
+	return s.isSchnorrSignatureAcceptable(ctx, address, poke, message, block)
 }
 
-// Sends a transaction for `opChallenge` contract function using the mainnet client.
-func (s *ScribeOptimisticRpcProvider) challengePokeUsingMainnet(
+// EstimateChallenge implements ChallengeEstimator interface. The gas cost is estimated at the gas price
+// suggested by the node, i.e. the current base fee plus the priority fee.
+func (s *ScribeOptimisticRpcProvider) EstimateChallenge(
 	ctx context.Context,
 	address types.Address,
 	poke *OpPokedEvent,
-) (*types.Hash, *types.Transaction, error) {
-	opChallenge := ScribeOptimisticContractABI.Methods["opChallenge"]
+) (*ChallengeEstimate, error) {
+	estimator, ok := s.client.(GasEstimator)
+	if !ok {
+		return nil, fmt.Errorf("client does not support gas estimation")
+	}
+	reward, err := s.GetChallengeReward(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.newChallengeTx(ctx, address, poke)
+	if err != nil {
+		return nil, err
+	}
+	call := tx.Call
+	from := s.GetFrom(ctx)
+	call.From = &from
+	gasLimit, _, err := estimator.EstimateGas(ctx, &call, types.LatestBlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate gas of opChallenge with error: %w: %w", ErrRPCUnavailable, err)
+	}
+	gasPrice, err := estimator.GasPrice(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get gas price with error: %w: %w", ErrRPCUnavailable, err)
+	}
+	return &ChallengeEstimate{Reward: reward, GasLimit: gasLimit, GasPrice: gasPrice}, nil
+}
 
-	calldata, err := opChallenge.EncodeArgs(poke.Schnorr)
+// Prepares a transaction for `opChallenge` contract function.
+// If forwarder is configured, the call is wrapped into the forwarder contract call.
+func (s *ScribeOptimisticRpcProvider) newChallengeTx(
+	ctx context.Context,
+	address types.Address,
+	poke *OpPokedEvent,
+) (*types.Transaction, error) {
+	calldata, err := EncodeChallengeCalldata(poke, s.tag)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to encode opChallenge args: %w", err)
+		return nil, err
 	}
 
-	// Prepare a transaction.
-	tx := (&types.Transaction{}).
-		SetTo(address).
-		SetInput(calldata)
+	if s.forwarder == nil {
+		return (&types.Transaction{}).
+			SetTo(address).
+			SetInput(calldata), nil
+	}
 
-	// Try to send with the mainnet client.
-	hash, tx, err := s.client.SendTransaction(ctx, tx)
+	var from types.Address
+	if s.forwarder.UsesFrom() {
+		from = s.GetFrom(ctx)
+	}
+	calldata, err = s.forwarder.Encode(address, calldata, from)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to send challenge transaction: %w", err)
+		return nil, err
 	}
+	logger.
+		WithField("address", address).
+		WithField("forwarder", s.forwarder.Address).
+		Debugf("routing opChallenge through forwarder %s", s.forwarder.Method.Signature())
+
+	return (&types.Transaction{}).
+		SetTo(s.forwarder.Address).
+		SetInput(calldata), nil
+}
 
-	receipt, err := WaitForTxConfirmation(ctx, s.client, hash, TxConfirmationTimeout)
+// Sends a transaction for `opChallenge` contract function using the mainnet client.
+// If replaced is given, the transaction reuses its nonce, so only one of them can be mined.
+// Fees are bumped if the replaced transaction was sent to the public mempool, otherwise the node would reject it.
+func (s *ScribeOptimisticRpcProvider) challengePokeUsingMainnet(
+	ctx context.Context,
+	address types.Address,
+	poke *OpPokedEvent,
+	replaced *types.Transaction,
+	bumpFees bool,
+) (*TrackedTx, error) {
+	tx, err := s.newChallengeTx(ctx, address, poke)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to wait for challenge transaction confirmation on mainnet: %w", err)
+		return nil, err
+	}
+	if replaced != nil {
+		// Replacements are sent from the same key, even if signing switched to another one since.
+		tx.Call.From = replaced.Call.From
+		tx.Nonce = replaced.Nonce
+		if bumpFees {
+			tx.GasPrice = bumpFee(replaced.GasPrice)
+			tx.MaxFeePerGas = bumpFee(replaced.MaxFeePerGas)
+			tx.MaxPriorityFeePerGas = bumpFee(replaced.MaxPriorityFeePerGas)
+		}
 	}
 
+	// Try to send with the mainnet client.
+	hash, tx, err := s.client.SendTransaction(ctx, tx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send challenge transaction: %w", err)
+	}
 	logger.
 		WithField("address", address).
 		WithField("txHash", hash).
-		WithField("status", receipt.Status).
-		Infof("challenge transaction confirmed in block %s", receipt.BlockHash)
+		Debugf("challenge transaction sent, waiting for confirmation")
 
-	return hash, tx, nil
+	return &TrackedTx{
+		Address: address,
+		Poke:    poke,
+		Hash:    hash,
+		Tx:      tx,
+		Client:  s.client,
+	}, nil
 }
 
 func (s *ScribeOptimisticRpcProvider) challengePokeUsingFlashbots(
 	ctx context.Context,
 	address types.Address,
 	poke *OpPokedEvent,
-) (*types.Hash, *types.Transaction, error) {
+	replaced *types.Transaction,
+) (*TrackedTx, error) {
 	if s.flashbotClient == nil {
-		return nil, nil, fmt.Errorf("flashbot client is not provided")
+		return nil, fmt.Errorf("flashbot client is not provided")
 	}
-	opChallenge := ScribeOptimisticContractABI.Methods["opChallenge"]
 
-	calldata, err := opChallenge.EncodeArgs(poke.Schnorr)
+	tx, err := s.newChallengeTx(ctx, address, poke)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to encode opChallenge args: %w", err)
+		return nil, err
+	}
+	if replaced != nil {
+		// Replacements are sent from the same key, even if signing switched to another one since.
+		tx.Call.From = replaced.Call.From
+		tx.Nonce = replaced.Nonce
+		tx.GasPrice = bumpFee(replaced.GasPrice)
+		tx.MaxFeePerGas = bumpFee(replaced.MaxFeePerGas)
+		tx.MaxPriorityFeePerGas = bumpFee(replaced.MaxPriorityFeePerGas)
 	}
+	// NOTE: for flashbots, we need to set the gas limit manually, and it might be more than normally.
+	tx.SetGasLimit(MaxFlashbotGasLimit)
 
-	// Prepare a transaction.
-	tx := (&types.Transaction{}).
-		SetTo(address).
-		SetInput(calldata).
-		// NOTE: for flashbots, we need to set the gas limit manually, and it might be more than normally.
-		SetGasLimit(MaxFlashbotGasLimit)
+	// Without the current block only TxConfirmationTimeout limits the inclusion, and the challenge is sent right away.
+	var deadline *big.Int
+	if latest, err := s.client.BlockNumber(ctx); err == nil {
+		if replaced == nil && s.target.Delay > 0 {
+			latest, err = s.waitForBlock(ctx, new(big.Int).Add(latest, new(big.Int).SetUint64(s.target.Delay)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to wait for the first targeted block: %w", err)
+			}
+		}
+		deadline = new(big.Int).Add(latest, new(big.Int).SetUint64(s.target.Blocks))
+	} else {
+		logger.
+			WithField("address", address).
+			Warnf("failed to get block number for flashbots inclusion deadline: %v", err)
+	}
 
 	// Try to send with the flashbots client.
 	// NOTE: because we have signer keys configured for provider,
 	// it will sign the transaction and send it using `eth_sendRawTransaction`.
 	hash, tx, err := s.flashbotClient.SendTransaction(ctx, tx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to send challenge transaction: %w", err)
+		return nil, fmt.Errorf("failed to send challenge transaction: %w", err)
 	}
 	logger.
 		WithField("address", address).
 		WithField("txHash", hash).
-		Debugf("flashbots challenge transaction sent, waiting for confirmation")
+		Debugf("flashbots challenge transaction sent, waiting for inclusion until block %v", deadline)
+
+	fallback := s.resendUsingMainnet
+	if s.privateOnly {
+		fallback = s.resendUsingFlashbots
+	}
+	return &TrackedTx{
+		Address:  address,
+		Poke:     poke,
+		Hash:     hash,
+		Tx:       tx,
+		Client:   s.flashbotClient,
+		Deadline: deadline,
+		Fallback: fallback,
+	}, nil
+}
+
+// waitForBlock waits until the block with the given number is mined and returns the latest block number.
+// The block number is polled every TxConfirmationPollInterval.
+func (s *ScribeOptimisticRpcProvider) waitForBlock(ctx context.Context, number *big.Int) (*big.Int, error) {
+	ticker := clockFromContext(ctx).NewTicker(TxConfirmationPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C():
+		}
+		latest, err := s.client.BlockNumber(ctx)
+		if err != nil {
+			logger.Warnf("failed to get block number while waiting for block %v: %v", number, err)
+			continue
+		}
+		if latest.Cmp(number) >= 0 {
+			return latest, nil
+		}
+	}
+}
 
-	receipt, err := WaitForTxConfirmation(ctx, s.flashbotClient, hash, TxConfirmationTimeout)
+// resendUsingFlashbots resubmits the challenge which flashbots didn't include to the relay again,
+// with the same nonce and bumped fees. It gives up once the challenge window of the poke closes.
+func (s *ScribeOptimisticRpcProvider) resendUsingFlashbots(ctx context.Context, tx *TrackedTx) (*TrackedTx, error) {
+	if !s.isChallengeWindowOpen(ctx, tx.Address, tx.Poke) {
+		return nil, fmt.Errorf("challenge window of OpPoked event from block %v closed", tx.Poke.BlockNumber)
+	}
+	next, err := s.challengePokeUsingFlashbots(ctx, tx.Address, tx.Poke, tx.Tx)
+	if err != nil {
+		return nil, err
+	}
+	next.Replaces = tx
+	return next, nil
+}
+
+// resendUsingMainnet resubmits the challenge which flashbots didn't include to the public mempool.
+// The same nonce is used, so only one of the transactions can be mined.
+func (s *ScribeOptimisticRpcProvider) resendUsingMainnet(ctx context.Context, tx *TrackedTx) (*TrackedTx, error) {
+	next, err := s.challengePokeUsingMainnet(ctx, tx.Address, tx.Poke, tx.Tx, false)
+	if err != nil {
+		return nil, err
+	}
+	next.Replaces = tx
+	return next, nil
+}
+
+// replaceUsingMainnet replaces the challenge stuck in the public mempool with the same nonce and bumped fees.
+func (s *ScribeOptimisticRpcProvider) replaceUsingMainnet(ctx context.Context, tx *TrackedTx) (*TrackedTx, error) {
+	next, err := s.challengePokeUsingMainnet(ctx, tx.Address, tx.Poke, tx.Tx, true)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to wait for challenge transaction confirmation: %w", err)
+		return nil, err
+	}
+	next.Replaces = tx
+	return next, nil
+}
+
+// bumpFee increases the fee by 12.5%, above 10% minimum required by nodes to replace a pending transaction.
+func bumpFee(fee *big.Int) *big.Int {
+	if fee == nil {
+		return nil
+	}
+	bumped := new(big.Int).Mul(fee, big.NewInt(9))
+	return bumped.Div(bumped, big.NewInt(8))
+}
+
+// ResumePending implements PendingResumer interface.
+// Persisted challenges under the address are tracked again and replaced with bumped fees if they are not mined in time.
+func (s *ScribeOptimisticRpcProvider) ResumePending(ctx context.Context, address types.Address) ([]*OpPokedEvent, error) {
+	if s.store == nil {
+		return nil, nil
+	}
+	challenges, err := s.store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	fallback := s.replaceUsingMainnet
+	if s.privateOnly {
+		fallback = s.resendUsingFlashbots
+	}
+	var pokes []*OpPokedEvent
+	for _, c := range challenges {
+		if c.Address != address || c.Poke == nil {
+			continue
+		}
+		hash := c.Hash
+		tx := &types.Transaction{}
+		tx.From = c.From
+		tx.To = c.To
+		tx.Input = c.Input
+		tx.GasLimit = c.GasLimit
+		tx.Nonce = c.Nonce
+		tx.GasPrice = c.GasPrice
+		tx.MaxFeePerGas = c.MaxFeePerGas
+		tx.MaxPriorityFeePerGas = c.MaxPriorityFeePerGas
+
+		logger.
+			WithField("address", address).
+			WithField("txHash", hash).
+			Warnf("Resuming challenge of OpPoked event from block %v sent at %v", c.Poke.BlockNumber, c.SentAt)
+		// Receipts of mined flashbots transactions are public as well.
+		tracked := &TrackedTx{
+			Address:  address,
+			Poke:     c.Poke,
+			Hash:     &hash,
+			Tx:       tx,
+			Client:   s.client,
+			Fallback: fallback,
+		}
+		// Transactions replaced before restart are watched as well, any of them may be mined instead.
+		// They only differ in fees, so the call is shared to classify their reverts.
+		last := tracked
+		for _, replaced := range c.Replaces {
+			last.Replaces = &TrackedTx{Address: address, Poke: c.Poke, Hash: &replaced, Tx: tx, Client: s.client}
+			last = last.Replaces
+		}
+		s.tracker.Track(ctx, tracked)
+		pokes = append(pokes, c.Poke)
+	}
+	return pokes, nil
+}
+
+// handleRevert fetches the revert reason of the mined challenge transaction, logs it and updates metrics.
+// Returned error wraps ErrChallengeReverted, and ErrAlreadyChallenged if the poke was already challenged.
+func (s *ScribeOptimisticRpcProvider) handleRevert(
+	ctx context.Context,
+	tx *TrackedTx,
+	receipt *types.TransactionReceipt,
+) error {
+	address := tx.Address
+	reason := GetRevertReason(ctx, tx.Client, tx.Tx, receipt)
+	category := ClassifyRevert(reason, tx.Tx, receipt)
+	if category == RevertReasonAlreadyChallenged && s.isChallengePeriodOver(ctx, address, tx.Poke, receipt) {
+		category = RevertReasonPeriodExpired
 	}
 
 	logger.
 		WithField("address", address).
-		WithField("txHash", hash).
-		Infof("challenge transaction confirmed in block %s", receipt.BlockHash)
-	return hash, tx, nil
+		WithField("txHash", receipt.TransactionHash).
+		WithField("reason", category).
+		Errorf("challenge transaction reverted in block %v: %s", receipt.BlockNumber, describeRevert(reason))
+	ChallengeRevertsCounter.WithLabelValues(address.String(), category).Inc()
+
+	if category == RevertReasonAlreadyChallenged {
+		return fmt.Errorf("%w: %v with %s: %w", ErrChallengeReverted, receipt.TransactionHash, category, ErrAlreadyChallenged)
+	}
+	return fmt.Errorf("%w: %v with %s", ErrChallengeReverted, receipt.TransactionHash, category)
+}
+
+// isChallengePeriodOver returns true if the transaction was mined after the challenge window of the poke closed.
+func (s *ScribeOptimisticRpcProvider) isChallengePeriodOver(
+	ctx context.Context,
+	address types.Address,
+	poke *OpPokedEvent,
+	receipt *types.TransactionReceipt,
+) bool {
+	period, err := s.GetChallengePeriod(ctx, address)
+	if err != nil {
+		return false
+	}
+	pokeBlock, err := s.BlockByNumber(ctx, poke.BlockNumber)
+	if err != nil {
+		return false
+	}
+	txBlock, err := s.BlockByNumber(ctx, receipt.BlockNumber)
+	if err != nil {
+		return false
+	}
+	return txBlock.Timestamp.After(pokeBlock.Timestamp.Add(time.Duration(period) * time.Second))
+}
+
+// isChallengeWindowOpen returns true if the poke can still be challenged, or its state is unknown.
+func (s *ScribeOptimisticRpcProvider) isChallengeWindowOpen(ctx context.Context, address types.Address, poke *OpPokedEvent) bool {
+	period, err := s.GetChallengePeriod(ctx, address)
+	if err != nil {
+		return true
+	}
+	pokeBlock, err := s.BlockByNumber(ctx, poke.BlockNumber)
+	if err != nil {
+		return true
+	}
+	return clockFromContext(ctx).Now().Before(pokeBlock.Timestamp.Add(time.Duration(period) * time.Second))
 }
 
 // ChallengePoke challenges the given poke by sending transaction for `opChallenge` contract function.
-// Makes several attempts to send a transaction, first with flashbots, then with the mainnet client.
-// NOTE: Probably, it's better to run challenge in a separate goroutine and wait for the confirmation.
+// Tries to send the transaction with flashbots first, then with the mainnet client, unless in private-only mode.
+// It returns as soon as the transaction is broadcast, the result is delivered through ChallengeOutcomes.
 func (s *ScribeOptimisticRpcProvider) ChallengePoke(
 	ctx context.Context,
 	address types.Address,
 	poke *OpPokedEvent,
-) (*types.Hash, *types.Transaction, error) {
-	if s.flashbotClient == nil {
+) (hash *types.Hash, tx *types.Transaction, err error) {
+	ctx, span := tracer.Start(ctx, "ScribeOptimisticRpcProvider.ChallengePoke")
+	defer func() { endSpan(span, err) }()
+
+	if s.jitter > 0 {
+		timer := clockFromContext(ctx).NewTimer(rand.N(s.jitter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, ctx.Err()
+		case <-timer.C():
+		}
+	}
+
+	var tracked *TrackedTx
+	if s.privateOnly {
+		tracked, err = s.challengePokeUsingFlashbots(ctx, address, poke, nil)
+	} else if s.flashbotClient == nil {
 		logger.
 			WithField("address", address).
 			Infof("flashbot client is not provided, trying to send with the mainnet client")
-		return s.challengePokeUsingMainnet(ctx, address, poke)
-	}
-
-	logger.
-		WithField("address", address).
-		Debugf("trying to send transaction with flashbots")
+		tracked, err = s.challengePokeUsingMainnet(ctx, address, poke, nil, false)
+	} else {
+		logger.
+			WithField("address", address).
+			Debugf("trying to send transaction with flashbots")
 
-	txHash, tx, err := s.challengePokeUsingFlashbots(ctx, address, poke)
-	if err == nil {
-		return txHash, tx, nil
+		tracked, err = s.challengePokeUsingFlashbots(ctx, address, poke, nil)
+		if err != nil {
+			logger.
+				WithField("address", address).
+				Warnf("failed to send transaction with flashbots, trying to send with the mainnet client, error: %v", err)
+			tracked, err = s.challengePokeUsingMainnet(ctx, address, poke, nil, false)
+		}
+	}
+	if err != nil {
+		return nil, nil, err
 	}
 
-	logger.
-		WithField("address", address).
-		Warnf("failed to send transaction with flashbots, trying to send with the mainnet client, error: %v", err)
+	s.tracker.Track(ctx, tracked)
+	return tracked.Hash, tracked.Tx, nil
+}
 
-	return s.challengePokeUsingMainnet(ctx, address, poke)
+// ChallengeOutcomes returns channel with results of the challenge transactions sent by ChallengePoke.
+func (s *ScribeOptimisticRpcProvider) ChallengeOutcomes() <-chan TxOutcome {
+	return s.tracker.Outcomes()
 }
@@ -0,0 +1,68 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/chronicleprotocol/challenger/core"
+	"github.com/chronicleprotocol/challenger/core/coretest"
+)
+
+func TestGetFrom(t *testing.T) {
+	// gets zero address if no accounts
+	mockClient1 := new(coretest.MockClient)
+	provider1 := core.NewScribeOptimisticRPCProvider(mockClient1, nil)
+	call := mockClient1.On("Accounts", mock.Anything).Return([]types.Address{}, nil)
+	addr := provider1.GetFrom(context.TODO())
+	assert.Equal(t, types.ZeroAddress, addr)
+	mockClient1.AssertExpectations(t)
+	call.Unset()
+
+	// zero address on error
+	mockClient2 := new(coretest.MockClient)
+	provider2 := core.NewScribeOptimisticRPCProvider(mockClient2, nil)
+	call = mockClient2.On("Accounts", mock.Anything).Return([]types.Address{}, fmt.Errorf("error"))
+	addr = provider2.GetFrom(context.TODO())
+	assert.Equal(t, types.ZeroAddress, addr)
+	mockClient2.AssertExpectations(t)
+	call.Unset()
+
+	// gets first account
+	mockClient3 := new(coretest.MockClient)
+	provider3 := core.NewScribeOptimisticRPCProvider(mockClient3, nil)
+	call = mockClient3.On("Accounts", mock.Anything).Return([]types.Address{{0x1}}, nil)
+	addr = provider3.GetFrom(context.TODO())
+	assert.Equal(t, types.Address{0x1}, addr)
+	mockClient3.AssertExpectations(t)
+	call.Unset()
+
+	// cached result is returned on subsequent calls
+	mockClient4 := new(coretest.MockClient)
+	provider4 := core.NewScribeOptimisticRPCProvider(mockClient4, nil)
+	mockClient4.On("Accounts", mock.Anything).Return([]types.Address{{0x2}}, nil).Once()
+	addr = provider4.GetFrom(context.TODO())
+	assert.Equal(t, types.Address{0x2}, addr)
+	addr = provider4.GetFrom(context.TODO())
+	assert.Equal(t, types.Address{0x2}, addr)
+	mockClient4.AssertExpectations(t)
+
+	// accounts are fetched again after an error
+	mockClient5 := new(coretest.MockClient)
+	provider5 := core.NewScribeOptimisticRPCProvider(mockClient5, nil)
+	mockClient5.On("Accounts", mock.Anything).Return([]types.Address{}, fmt.Errorf("error")).Once()
+	mockClient5.On("Accounts", mock.Anything).Return([]types.Address{{0x3}}, nil).Once()
+	assert.Equal(t, types.ZeroAddress, provider5.GetFrom(context.TODO()))
+	assert.Equal(t, types.Address{0x3}, provider5.GetFrom(context.TODO()))
+	mockClient5.AssertExpectations(t)
+
+	// address set at construction needs no RPC call
+	mockClient6 := new(coretest.MockClient)
+	provider6 := core.NewScribeOptimisticRPCProvider(mockClient6, nil, core.WithFrom(types.Address{0x4}))
+	assert.Equal(t, types.Address{0x4}, provider6.GetFrom(context.TODO()))
+	mockClient6.AssertNotCalled(t, "Accounts", mock.Anything)
+}
@@ -1,18 +1,25 @@
 package core
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"math/big"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/defiweb/go-eth/abi"
 	"github.com/defiweb/go-eth/hexutil"
+	"github.com/defiweb/go-eth/rpc/transport"
 	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
+// mockRpcClient is coretest.MockClient for tests inside the package, which can not import coretest.
 type mockRpcClient struct {
 	mock.Mock
 }
@@ -56,43 +63,24 @@ func (m *mockRpcClient) GetTransactionReceipt(ctx context.Context, hash types.Ha
 	return args.Get(0).(*types.TransactionReceipt), args.Error(1)
 }
 
-func TestGetFrom(t *testing.T) {
-	// gets zero address if no accounts
-	mockClient1 := new(mockRpcClient)
-	provider1 := NewScribeOptimisticRPCProvider(mockClient1, nil)
-	call := mockClient1.On("Accounts", mock.Anything).Return([]types.Address{}, nil)
-	addr := provider1.GetFrom(context.TODO())
-	assert.Equal(t, types.ZeroAddress, addr)
-	mockClient1.AssertExpectations(t)
-	call.Unset()
+func (m *mockRpcClient) GetBalance(ctx context.Context, address types.Address, block types.BlockNumber) (*big.Int, error) {
+	args := m.Called(ctx, address, block)
+	return args.Get(0).(*big.Int), args.Error(1)
+}
 
-	// zero address on error
-	mockClient2 := new(mockRpcClient)
-	provider2 := NewScribeOptimisticRPCProvider(mockClient2, nil)
-	call = mockClient2.On("Accounts", mock.Anything).Return([]types.Address{}, fmt.Errorf("error"))
-	addr = provider2.GetFrom(context.TODO())
-	assert.Equal(t, types.ZeroAddress, addr)
-	mockClient2.AssertExpectations(t)
-	call.Unset()
+func (m *mockRpcClient) GetTransactionByHash(ctx context.Context, hash types.Hash) (*types.OnChainTransaction, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(*types.OnChainTransaction), args.Error(1)
+}
 
-	// gets first account
-	mockClient3 := new(mockRpcClient)
-	provider3 := NewScribeOptimisticRPCProvider(mockClient3, nil)
-	call = mockClient3.On("Accounts", mock.Anything).Return([]types.Address{{0x1}}, nil)
-	addr = provider3.GetFrom(context.TODO())
-	assert.Equal(t, types.Address{0x1}, addr)
-	mockClient3.AssertExpectations(t)
-	call.Unset()
+func (m *mockRpcClient) ChainID(ctx context.Context) (uint64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(uint64), args.Error(1)
+}
 
-	// cached result is returned on subsequent calls
-	mockClient4 := new(mockRpcClient)
-	provider4 := NewScribeOptimisticRPCProvider(mockClient4, nil)
-	mockClient4.On("Accounts", mock.Anything).Return([]types.Address{{0x2}}, nil).Once()
-	addr = provider4.GetFrom(context.TODO())
-	assert.Equal(t, types.Address{0x2}, addr)
-	addr = provider4.GetFrom(context.TODO())
-	assert.Equal(t, types.Address{0x2}, addr)
-	mockClient4.AssertExpectations(t)
+func (m *mockRpcClient) SubscribeLogs(ctx context.Context, query *types.FilterLogsQuery) (<-chan types.Log, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(<-chan types.Log), args.Error(1)
 }
 
 func TestGetChallengePeriod(t *testing.T) {
@@ -117,12 +105,38 @@ func TestGetChallengePeriod(t *testing.T) {
 	call = mockRpcClient.On("Call", mock.Anything, mock.Anything, mock.Anything).
 		Return([]byte{}, nil, fmt.Errorf("error"))
 	period, err = provider.GetChallengePeriod(context.TODO(), address)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrRPCUnavailable)
 	assert.Equal(t, uint16(0), period)
 	mockRpcClient.AssertExpectations(t)
 	call.Unset()
 }
 
+func TestGetChallengeReward(t *testing.T) {
+	mockRpcClient := new(mockRpcClient)
+	provider := NewScribeOptimisticRPCProvider(mockRpcClient, nil)
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	challengeReward := ScribeOptimisticContractABI.Methods["challengeReward"]
+	maxChallengeReward := ScribeOptimisticContractABI.Methods["maxChallengeReward"]
+
+	mockRpcClient.On("Call", mock.Anything, mock.MatchedBy(func(call *types.Call) bool {
+		return bytes.Equal(call.Input, challengeReward.FourBytes().Bytes())
+	}), types.LatestBlockNumber).
+		Return(hexutil.MustHexToBytes("0x00000000000000000000000000000000000000000000000000b1a2bc2ec50000"), &types.Call{}, nil)
+	mockRpcClient.On("Call", mock.Anything, mock.MatchedBy(func(call *types.Call) bool {
+		return bytes.Equal(call.Input, maxChallengeReward.FourBytes().Bytes())
+	}), types.LatestBlockNumber).
+		Return([]byte{}, nil, fmt.Errorf("error"))
+
+	reward, err := provider.GetChallengeReward(context.TODO(), address)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(50_000_000_000_000_000), reward)
+
+	_, err = provider.GetMaxChallengeReward(context.TODO(), address)
+	assert.ErrorContains(t, err, "failed to call maxChallengeReward")
+	assert.ErrorIs(t, err, ErrRPCUnavailable)
+	assert.NotErrorIs(t, err, ErrDecode)
+}
+
 func TestGetPokes(t *testing.T) {
 	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
 
@@ -134,6 +148,7 @@ func TestGetPokes(t *testing.T) {
 
 		result, err := provider.GetPokes(context.TODO(), address, big.NewInt(0), big.NewInt(100))
 		assert.ErrorContains(t, err, "failed to get OpPoked events")
+		assert.ErrorIs(t, err, ErrRPCUnavailable)
 		assert.Nil(t, result)
 	})
 
@@ -165,6 +180,43 @@ func TestGetPokes(t *testing.T) {
 		assert.Empty(t, result)
 	})
 
+	t.Run("malformed log is decoded from transaction calldata", func(t *testing.T) {
+		txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+		client := new(mockRpcClient)
+		provider := NewScribeOptimisticRPCProvider(client, nil)
+		badLog := types.Log{
+			BlockNumber:     big.NewInt(50),
+			TransactionHash: &txHash,
+			Topics: []types.Hash{
+				types.MustHashFromHex("0xb9dc937c5e394d0c8f76e0e324500b88251b4c909ddc56232df10e2ea42b3c63", types.PadNone),
+				types.MustHashFromHex("0x0000000000000000000000001f7acda376ef37ec371235a094113df9cb4efee1", types.PadNone),
+				types.MustHashFromHex("0x0000000000000000000000006813eb9362372eef6200f3b1dbc3f819671cba69", types.PadNone),
+			},
+			Data: []byte{0x01},
+		}
+		pokeData := PokeData{Val: big.NewInt(1000), Age: 42}
+		schnorr := SchnorrData{Signature: [32]byte{1}, Commitment: address, SignersBlob: []byte{2, 3}}
+		input, err := ScribeOptimisticContractABI.Methods["opPoke"].EncodeArgs(pokeData, schnorr, struct {
+			V uint8    `abi:"v"`
+			R [32]byte `abi:"r"`
+			S [32]byte `abi:"s"`
+		}{})
+		require.NoError(t, err)
+		client.On("GetLogs", mock.Anything, mock.Anything).
+			Return([]types.Log{badLog}, nil)
+		client.On("GetTransactionByHash", mock.Anything, txHash).
+			Return(&types.OnChainTransaction{Transaction: types.Transaction{Call: types.Call{To: &address, Input: input}}}, nil)
+
+		result, err := provider.GetPokes(context.TODO(), address, big.NewInt(0), big.NewInt(100))
+		assert.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.Equal(t, big.NewInt(50), result[0].BlockNumber)
+		assert.Equal(t, address, result[0].Caller)
+		assert.Equal(t, types.MustAddressFromHex("0x6813eb9362372eef6200f3b1dbc3f819671cba69"), result[0].OpFeed)
+		assert.Equal(t, pokeData, result[0].PokeData)
+		assert.Equal(t, schnorr, result[0].Schnorr)
+	})
+
 	t.Run("successful decode", func(t *testing.T) {
 		client := new(mockRpcClient)
 		provider := NewScribeOptimisticRPCProvider(client, nil)
@@ -175,6 +227,7 @@ func TestGetPokes(t *testing.T) {
 				types.MustHashFromHex("0x0000000000000000000000001f7acda376ef37ec371235a094113df9cb4efee1", types.PadNone),
 				types.MustHashFromHex("0x0000000000000000000000006813eb9362372eef6200f3b1dbc3f819671cba69", types.PadNone),
 			},
+			Data: testOpPokedData(),
 		}
 		client.On("GetLogs", mock.Anything, mock.Anything).
 			Return([]types.Log{validLog}, nil)
@@ -245,6 +298,129 @@ func TestGetSuccessfulChallenges(t *testing.T) {
 		assert.NoError(t, err)
 		require.Len(t, result, 1)
 		assert.Equal(t, big.NewInt(50), result[0].BlockNumber)
+		assert.Nil(t, result[0].Schnorr)
+	})
+
+	t.Run("challenged schnorr data is decoded from transaction calldata", func(t *testing.T) {
+		txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+		client := new(mockRpcClient)
+		provider := NewScribeOptimisticRPCProvider(client, nil)
+		validLog := types.Log{
+			BlockNumber:     big.NewInt(50),
+			TransactionHash: &txHash,
+			Topics: []types.Hash{
+				types.MustHashFromHex("0xac50cef58b3aef7f7c30349f5e4a342a29d2325a02eafc8dacfdba391e6d5db3", types.PadNone),
+				types.MustHashFromHex("0x0000000000000000000000001f7acda376ef37ec371235a094113df9cb4efee1", types.PadNone),
+			},
+			Data: types.MustBytesFromHex("0x00000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000004bd2a556b00000000000000000000000000000000000000000000000000000000"),
+		}
+		schnorr := SchnorrData{Signature: [32]byte{1}, Commitment: address, SignersBlob: []byte{2, 3}}
+		calldata, err := EncodeChallengeCalldata(&OpPokedEvent{Schnorr: schnorr}, nil)
+		require.NoError(t, err)
+		client.On("GetLogs", mock.Anything, mock.Anything).
+			Return([]types.Log{validLog}, nil)
+		client.On("GetTransactionByHash", mock.Anything, txHash).
+			Return(&types.OnChainTransaction{Transaction: types.Transaction{Call: types.Call{To: &address, Input: calldata}}}, nil)
+
+		result, err := provider.GetSuccessfulChallenges(context.TODO(), address, big.NewInt(0), big.NewInt(100))
+		assert.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.Equal(t, &schnorr, result[0].Schnorr)
+	})
+}
+
+func TestGetRegularPokes(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+
+	t.Run("GetLogs error", func(t *testing.T) {
+		client := new(mockRpcClient)
+		provider := NewScribeOptimisticRPCProvider(client, nil)
+		client.On("GetLogs", mock.Anything, mock.Anything).
+			Return([]types.Log{}, fmt.Errorf("rpc error"))
+
+		result, err := provider.GetRegularPokes(context.TODO(), address, big.NewInt(0), big.NewInt(100))
+		assert.ErrorContains(t, err, "failed to get Poked events")
+		assert.Nil(t, result)
+	})
+
+	t.Run("decode error skips bad log", func(t *testing.T) {
+		client := new(mockRpcClient)
+		provider := NewScribeOptimisticRPCProvider(client, nil)
+		topic := ScribeOptimisticContractABI.Events["Poked"].Topic0()
+		validLog := types.Log{
+			BlockNumber: big.NewInt(60),
+			Topics: []types.Hash{
+				topic,
+				types.MustHashFromHex("0x0000000000000000000000001f7acda376ef37ec371235a094113df9cb4efee1", types.PadNone),
+			},
+			Data: types.MustBytesFromHex("0x00000000000000000000000000000000000000000000000000000000000003e8000000000000000000000000000000000000000000000000000000000000002a"),
+		}
+		badLog := types.Log{BlockNumber: big.NewInt(50), Topics: []types.Hash{topic}, Data: []byte{0x01}}
+		client.On("GetLogs", mock.Anything, mock.MatchedBy(func(q *types.FilterLogsQuery) bool {
+			return q.Topics[0][0] == topic
+		})).Return([]types.Log{badLog, validLog}, nil)
+
+		result, err := provider.GetRegularPokes(context.TODO(), address, big.NewInt(0), big.NewInt(100))
+		assert.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.Equal(t, big.NewInt(60), result[0].BlockNumber)
+		assert.Equal(t, uint32(42), result[0].Age)
+	})
+}
+
+func TestDecodeOpChallenge(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	schnorr := SchnorrData{Signature: [32]byte{1}, Commitment: address, SignersBlob: []byte{2, 3}}
+	calldata, err := EncodeChallengeCalldata(&OpPokedEvent{Schnorr: schnorr}, nil)
+	require.NoError(t, err)
+
+	t.Run("direct call", func(t *testing.T) {
+		decoded, ok := decodeOpChallenge(calldata)
+		require.True(t, ok)
+		assert.Equal(t, schnorr, *decoded)
+	})
+
+	t.Run("call wrapped by multicall", func(t *testing.T) {
+		other := types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEe2")
+		input, err := multicallAggregate3.EncodeArgs([]multicallCall{
+			{Target: other, AllowFailure: true, CallData: []byte{1, 2, 3, 4, 5}},
+			{Target: address, AllowFailure: true, CallData: calldata},
+		})
+		require.NoError(t, err)
+		decoded, ok := decodeOpChallenge(input)
+		require.True(t, ok)
+		assert.Equal(t, schnorr, *decoded)
+	})
+
+	t.Run("tagged call", func(t *testing.T) {
+		tag, err := NewChallengeTag("operator-1")
+		require.NoError(t, err)
+
+		tagged, err := EncodeChallengeCalldata(&OpPokedEvent{Schnorr: schnorr}, tag)
+		require.NoError(t, err)
+		assert.Equal(t, calldata, tagged[:len(calldata)])
+		assert.Equal(t, tag, tagged[len(calldata):])
+		decoded, ok := decodeOpChallenge(tagged)
+		require.True(t, ok)
+		assert.Equal(t, schnorr, *decoded)
+
+		// Only the provider with the tag appends it.
+		tx, err := NewScribeOptimisticRPCProvider(nil, nil, WithChallengeTag(tag)).newChallengeTx(context.TODO(), address, &OpPokedEvent{Schnorr: schnorr})
+		require.NoError(t, err)
+		assert.Equal(t, tagged, tx.Input)
+		tx, err = NewScribeOptimisticRPCProvider(nil, nil).newChallengeTx(context.TODO(), address, &OpPokedEvent{Schnorr: schnorr})
+		require.NoError(t, err)
+		assert.Equal(t, calldata, tx.Input)
+
+		_, err = NewChallengeTag(strings.Repeat("x", MaxChallengeTagLength))
+		assert.Error(t, err)
+	})
+
+	t.Run("not a challenge", func(t *testing.T) {
+		_, ok := decodeOpChallenge(nil)
+		assert.False(t, ok)
+		_, ok = decodeOpChallenge(calldata[4:])
+		assert.False(t, ok)
 	})
 }
 
@@ -282,6 +458,37 @@ func TestIsPokeSignatureValid(t *testing.T) {
 		assert.False(t, valid)
 		call1.Unset()
 	})
+
+	t.Run("verdict at poke block differs", func(t *testing.T) {
+		message, err := abi.EncodeValues(ScribeOptimisticContractABI.Methods["constructPokeMessage"].Outputs(), make([]byte, 32))
+		require.NoError(t, err)
+		acceptable := func(ok bool) []byte {
+			b, err := abi.EncodeValues(ScribeOptimisticContractABI.Methods["isAcceptableSchnorrSignatureNow"].Outputs(), ok)
+			require.NoError(t, err)
+			return b
+		}
+		pokeBlock := types.BlockNumberFromBigInt(poke.BlockNumber)
+		isMessage := mock.MatchedBy(func(call *types.Call) bool {
+			return string(call.Input[:4]) == string(ScribeOptimisticContractABI.Methods["constructPokeMessage"].FourBytes().Bytes())
+		})
+		isAcceptable := mock.MatchedBy(func(call *types.Call) bool {
+			return string(call.Input[:4]) == string(ScribeOptimisticContractABI.Methods["isAcceptableSchnorrSignatureNow"].FourBytes().Bytes())
+		})
+
+		client := new(mockRpcClient)
+		client.On("Call", mock.Anything, isMessage, mock.Anything).Return(message, nil, nil)
+		client.On("Call", mock.Anything, isAcceptable, types.LatestBlockNumber).Return(acceptable(true), nil, nil)
+		client.On("Call", mock.Anything, isAcceptable, pokeBlock).Return(acceptable(false), nil, nil)
+
+		discrepancies := testutil.ToFloat64(SignatureDiscrepanciesCounter.WithLabelValues(address.String()))
+		provider := NewScribeOptimisticRPCProvider(client, nil, WithPokeBlockVerification())
+		valid, err := provider.IsPokeSignatureValid(context.TODO(), address, poke)
+		require.NoError(t, err)
+		// Latest verdict decides.
+		assert.True(t, valid)
+		assert.Equal(t, discrepancies+1, testutil.ToFloat64(SignatureDiscrepanciesCounter.WithLabelValues(address.String())))
+		client.AssertCalled(t, "Call", mock.Anything, isAcceptable, pokeBlock)
+	})
 }
 
 func TestChallengePoke(t *testing.T) {
@@ -310,6 +517,7 @@ func TestChallengePoke(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, &txHash, hash)
 		assert.NotNil(t, tx)
+		require.NoError(t, waitOutcome(t, provider).Err)
 		client.AssertExpectations(t)
 	})
 
@@ -317,6 +525,7 @@ func TestChallengePoke(t *testing.T) {
 		client := new(mockRpcClient)
 		flashbot := new(mockRpcClient)
 		provider := NewScribeOptimisticRPCProvider(client, flashbot)
+		client.On("BlockNumber", mock.Anything).Return(big.NewInt(150), nil)
 		flashbot.On("SendTransaction", mock.Anything, mock.Anything).
 			Return(&txHash, &types.Transaction{}, nil)
 		flashbot.On("GetTransactionReceipt", mock.Anything, txHash).
@@ -326,6 +535,7 @@ func TestChallengePoke(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, &txHash, hash)
 		assert.NotNil(t, tx)
+		require.NoError(t, waitOutcome(t, provider).Err)
 		// Mainnet client should not be called.
 		client.AssertNotCalled(t, "SendTransaction")
 		flashbot.AssertExpectations(t)
@@ -335,6 +545,7 @@ func TestChallengePoke(t *testing.T) {
 		client := new(mockRpcClient)
 		flashbot := new(mockRpcClient)
 		provider := NewScribeOptimisticRPCProvider(client, flashbot)
+		client.On("BlockNumber", mock.Anything).Return(big.NewInt(150), nil)
 		// Flashbot send fails.
 		flashbot.On("SendTransaction", mock.Anything, mock.Anything).
 			Return((*types.Hash)(nil), (*types.Transaction)(nil), fmt.Errorf("flashbot down"))
@@ -348,6 +559,7 @@ func TestChallengePoke(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, &txHash, hash)
 		assert.NotNil(t, tx)
+		require.NoError(t, waitOutcome(t, provider).Err)
 		flashbot.AssertExpectations(t)
 		client.AssertExpectations(t)
 	})
@@ -356,6 +568,7 @@ func TestChallengePoke(t *testing.T) {
 		client := new(mockRpcClient)
 		flashbot := new(mockRpcClient)
 		provider := NewScribeOptimisticRPCProvider(client, flashbot)
+		client.On("BlockNumber", mock.Anything).Return(big.NewInt(150), nil)
 		flashbot.On("SendTransaction", mock.Anything, mock.Anything).
 			Return((*types.Hash)(nil), (*types.Transaction)(nil), fmt.Errorf("flashbot down"))
 		client.On("SendTransaction", mock.Anything, mock.Anything).
@@ -366,4 +579,268 @@ func TestChallengePoke(t *testing.T) {
 		assert.Nil(t, hash)
 		assert.Nil(t, tx)
 	})
+
+	t.Run("flashbot not included in time falls back to mainnet with the same nonce", func(t *testing.T) {
+		bundleHash := types.MustHashFromHex("0xcccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc", types.PadNone)
+		nonce := uint64(7)
+		client := new(mockRpcClient)
+		flashbot := new(mockRpcClient)
+		provider := NewScribeOptimisticRPCProvider(client, flashbot)
+		// Deadline is block 150 + FlashbotInclusionBlocks, chain moves past it while bundle is pending.
+		client.On("BlockNumber", mock.Anything).Return(big.NewInt(150), nil).Once()
+		flashbot.On("BlockNumber", mock.Anything).Return(big.NewInt(150+int64(FlashbotInclusionBlocks)), nil)
+		flashbot.On("SendTransaction", mock.Anything, mock.Anything).
+			Return(&bundleHash, (&types.Transaction{}).SetNonce(nonce), nil)
+		flashbot.On("GetTransactionReceipt", mock.Anything, bundleHash).
+			Return((*types.TransactionReceipt)(nil), nil)
+		client.On("SendTransaction", mock.Anything, mock.MatchedBy(func(tx *types.Transaction) bool {
+			return tx.Nonce != nil && *tx.Nonce == nonce
+		})).Return(&txHash, &types.Transaction{}, nil)
+		client.On("GetTransactionReceipt", mock.Anything, txHash).
+			Return(receipt, nil)
+
+		hash, _, err := provider.ChallengePoke(context.TODO(), address, poke)
+		require.NoError(t, err)
+		assert.Equal(t, &bundleHash, hash)
+
+		outcome := waitOutcome(t, provider)
+		require.NoError(t, outcome.Err)
+		assert.Equal(t, &txHash, outcome.Hash)
+		assert.Equal(t, poke, outcome.Poke)
+		client.AssertExpectations(t)
+	})
+
+	t.Run("flashbot mined while resubmitting", func(t *testing.T) {
+		bundleHash := types.MustHashFromHex("0xcccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc", types.PadNone)
+		client := new(mockRpcClient)
+		flashbot := new(mockRpcClient)
+		provider := NewScribeOptimisticRPCProvider(client, flashbot)
+		client.On("BlockNumber", mock.Anything).Return(big.NewInt(150), nil).Once()
+		flashbot.On("BlockNumber", mock.Anything).Return(big.NewInt(150+int64(FlashbotInclusionBlocks)), nil)
+		flashbot.On("SendTransaction", mock.Anything, mock.Anything).
+			Return(&bundleHash, (&types.Transaction{}).SetNonce(7), nil)
+		// Bundle lands right after the deadline check.
+		flashbot.On("GetTransactionReceipt", mock.Anything, bundleHash).
+			Return((*types.TransactionReceipt)(nil), nil).Once()
+		flashbot.On("GetTransactionReceipt", mock.Anything, bundleHash).
+			Return(&types.TransactionReceipt{TransactionHash: bundleHash, Status: &status, BlockNumber: big.NewInt(160)}, nil)
+		client.On("SendTransaction", mock.Anything, mock.Anything).
+			Return((*types.Hash)(nil), (*types.Transaction)(nil), fmt.Errorf("nonce too low"))
+
+		_, _, err := provider.ChallengePoke(context.TODO(), address, poke)
+		require.NoError(t, err)
+
+		outcome := waitOutcome(t, provider)
+		require.NoError(t, outcome.Err)
+		assert.Equal(t, &bundleHash, outcome.Hash)
+	})
+
+	t.Run("private-only never falls back to mainnet", func(t *testing.T) {
+		client := new(mockRpcClient)
+		flashbot := new(mockRpcClient)
+		provider := NewScribeOptimisticRPCProvider(client, flashbot, WithPrivateOnly())
+		client.On("BlockNumber", mock.Anything).Return(big.NewInt(150), nil)
+		flashbot.On("SendTransaction", mock.Anything, mock.Anything).
+			Return((*types.Hash)(nil), (*types.Transaction)(nil), fmt.Errorf("flashbot down"))
+
+		_, _, err := provider.ChallengePoke(context.TODO(), address, poke)
+		assert.Error(t, err)
+		client.AssertNotCalled(t, "SendTransaction", mock.Anything, mock.Anything)
+	})
+
+	t.Run("private-only resubmits to flashbots with the same nonce", func(t *testing.T) {
+		bundleHash := types.MustHashFromHex("0xcccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc", types.PadNone)
+		nonce := uint64(7)
+		client := new(mockRpcClient)
+		flashbot := new(mockRpcClient)
+		provider := NewScribeOptimisticRPCProvider(client, flashbot, WithPrivateOnly(), WithSubmissionJitter(time.Millisecond))
+		client.On("BlockNumber", mock.Anything).Return(big.NewInt(150), nil)
+		// Challenge period is unknown, so the window is considered open.
+		client.On("Call", mock.Anything, mock.Anything, mock.Anything).
+			Return([]byte(nil), (*types.Call)(nil), fmt.Errorf("rpc down"))
+		flashbot.On("BlockNumber", mock.Anything).Return(big.NewInt(150+int64(FlashbotInclusionBlocks)), nil)
+		flashbot.On("SendTransaction", mock.Anything, mock.Anything).
+			Return(&bundleHash, (&types.Transaction{}).SetNonce(nonce), nil).Once()
+		flashbot.On("SendTransaction", mock.Anything, mock.MatchedBy(func(tx *types.Transaction) bool {
+			return tx.Nonce != nil && *tx.Nonce == nonce
+		})).Return(&txHash, &types.Transaction{}, nil).Once()
+		flashbot.On("GetTransactionReceipt", mock.Anything, bundleHash).
+			Return((*types.TransactionReceipt)(nil), nil)
+		flashbot.On("GetTransactionReceipt", mock.Anything, txHash).
+			Return(receipt, nil)
+
+		_, _, err := provider.ChallengePoke(context.TODO(), address, poke)
+		require.NoError(t, err)
+
+		outcome := waitOutcome(t, provider)
+		require.NoError(t, outcome.Err)
+		assert.Equal(t, &txHash, outcome.Hash)
+		client.AssertNotCalled(t, "SendTransaction", mock.Anything, mock.Anything)
+		flashbot.AssertExpectations(t)
+	})
+
+	t.Run("forwarder wraps opChallenge call", func(t *testing.T) {
+		client := new(mockRpcClient)
+		forwarderAddr := types.MustAddressFromHex("0x0000000000000000000000000000000000000f0f")
+		forwarder, err := NewForwarderConfig(forwarderAddr, "execute(address,bytes)", []string{"{target}", "{calldata}"})
+		require.NoError(t, err)
+
+		provider := NewScribeOptimisticRPCProvider(client, nil, WithForwarder(forwarder))
+		client.On("SendTransaction", mock.Anything, mock.MatchedBy(func(tx *types.Transaction) bool {
+			return tx.To != nil && *tx.To == forwarderAddr &&
+				string(tx.Input[:4]) == string(forwarder.Method.FourBytes().Bytes())
+		})).Return(&txHash, &types.Transaction{}, nil)
+		client.On("GetTransactionReceipt", mock.Anything, txHash).
+			Return(receipt, nil)
+
+		hash, _, err := provider.ChallengePoke(context.TODO(), address, poke)
+		require.NoError(t, err)
+		assert.Equal(t, &txHash, hash)
+		require.NoError(t, waitOutcome(t, provider).Err)
+		client.AssertExpectations(t)
+	})
+}
+
+func TestChallengePokeReverted(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	status := uint64(0)
+	gasLimit := uint64(100000)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(100)}
+	sentTx := &types.Transaction{Call: types.Call{To: &address, GasLimit: &gasLimit}}
+	receipt := &types.TransactionReceipt{
+		TransactionHash: txHash,
+		Status:          &status,
+		BlockNumber:     big.NewInt(200),
+		GasUsed:         50000,
+	}
+	noOpPoke := ScribeOptimisticContractABI.Errors["NoOpPokeToChallenge"].FourBytes().Bytes()
+	periodResult := big.NewInt(600).FillBytes(make([]byte, 32))
+	pokeTime := time.Unix(1_700_000_000, 0)
+
+	newClient := func(txTime time.Time) *mockRpcClient {
+		client := new(mockRpcClient)
+		client.On("SendTransaction", mock.Anything, mock.Anything).Return(&txHash, sentTx, nil)
+		client.On("GetTransactionReceipt", mock.Anything, txHash).Return(receipt, nil)
+		client.On("Call", mock.Anything, mock.Anything, types.BlockNumberFromUint64(200)).
+			Return([]byte(nil), nil, transport.NewRPCError(3, "execution reverted", noOpPoke))
+		client.On("Call", mock.Anything, mock.Anything, types.LatestBlockNumber).
+			Return(periodResult, nil, nil)
+		client.On("BlockByNumber", mock.Anything, types.BlockNumberFromUint64(100), false).
+			Return(&types.Block{Timestamp: pokeTime}, nil)
+		client.On("BlockByNumber", mock.Anything, types.BlockNumberFromUint64(200), false).
+			Return(&types.Block{Timestamp: txTime}, nil)
+		return client
+	}
+
+	t.Run("already challenged", func(t *testing.T) {
+		provider := NewScribeOptimisticRPCProvider(newClient(pokeTime.Add(time.Minute)), nil)
+		before := testutil.ToFloat64(ChallengeRevertsCounter.WithLabelValues(address.String(), RevertReasonAlreadyChallenged))
+
+		_, _, err := provider.ChallengePoke(context.TODO(), address, poke)
+		require.NoError(t, err)
+		err = waitOutcome(t, provider).Err
+		assert.ErrorIs(t, err, ErrTxReverted)
+		assert.ErrorIs(t, err, ErrChallengeReverted)
+		assert.ErrorIs(t, err, ErrAlreadyChallenged)
+		assert.ErrorContains(t, err, RevertReasonAlreadyChallenged)
+		assert.Equal(t, before+1, testutil.ToFloat64(ChallengeRevertsCounter.WithLabelValues(address.String(), RevertReasonAlreadyChallenged)))
+	})
+
+	t.Run("period expired", func(t *testing.T) {
+		provider := NewScribeOptimisticRPCProvider(newClient(pokeTime.Add(time.Hour)), nil)
+
+		_, _, err := provider.ChallengePoke(context.TODO(), address, poke)
+		require.NoError(t, err)
+		err = waitOutcome(t, provider).Err
+		assert.ErrorIs(t, err, ErrChallengeReverted)
+		assert.NotErrorIs(t, err, ErrAlreadyChallenged)
+		assert.ErrorContains(t, err, RevertReasonPeriodExpired)
+	})
+
+	t.Run("flashbots revert does not fall back to mainnet", func(t *testing.T) {
+		client := newClient(pokeTime.Add(time.Minute))
+		client.On("BlockNumber", mock.Anything).Return(big.NewInt(150), nil)
+		provider := NewScribeOptimisticRPCProvider(client, newClient(pokeTime.Add(time.Minute)))
+
+		_, _, err := provider.ChallengePoke(context.TODO(), address, poke)
+		require.NoError(t, err)
+		err = waitOutcome(t, provider).Err
+		assert.ErrorIs(t, err, ErrTxReverted)
+		client.AssertNotCalled(t, "SendTransaction", mock.Anything, mock.Anything)
+	})
+}
+
+// waitOutcome returns the next challenge outcome of the provider.
+func TestBundleTarget(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	bundleHash := types.MustHashFromHex("0xcccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccccc", types.PadNone)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(100)}
+	clock := newFakeClock()
+	ctx := ContextWithClock(context.Background(), clock)
+
+	polled := make(chan struct{}, 1)
+	client := new(mockRpcClient)
+	flashbot := new(mockRpcClient)
+	client.On("BlockNumber", mock.Anything).Return(big.NewInt(150), nil).Once()
+	client.On("BlockNumber", mock.Anything).Return(big.NewInt(151), nil).
+		Run(func(mock.Arguments) { polled <- struct{}{} }).Once()
+	client.On("BlockNumber", mock.Anything).Return(big.NewInt(152), nil)
+	flashbot.On("SendTransaction", mock.Anything, mock.Anything).
+		Return(&bundleHash, (&types.Transaction{}).SetNonce(7), nil)
+	provider := NewScribeOptimisticRPCProvider(client, flashbot, WithBundleTarget(BundleTarget{Delay: 2, Blocks: 3}))
+
+	sent := make(chan *TrackedTx)
+	go func() {
+		tracked, err := provider.challengePokeUsingFlashbots(ctx, address, poke, nil)
+		assert.NoError(t, err)
+		sent <- tracked
+	}()
+
+	// The challenge is held back until block 152, the one before the first targeted block, is mined.
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	clock.Advance(TxConfirmationPollInterval)
+	<-polled
+	flashbot.AssertNotCalled(t, "SendTransaction", mock.Anything, mock.Anything)
+	clock.Advance(TxConfirmationPollInterval)
+	tracked := <-sent
+	require.NotNil(t, tracked)
+	assert.Equal(t, big.NewInt(155), tracked.Deadline)
+
+	// Resubmissions target the next blocks without delay.
+	next, err := provider.challengePokeUsingFlashbots(ctx, address, poke, tracked.Tx)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(155), next.Deadline)
+	flashbot.AssertNumberOfCalls(t, "SendTransaction", 2)
+}
+
+func TestIsChallengeWindowOpen(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(100)}
+	clock := newFakeClock()
+	ctx := ContextWithClock(context.Background(), clock)
+
+	client := new(mockRpcClient)
+	client.On("Call", mock.Anything, mock.Anything, types.LatestBlockNumber).
+		Return(big.NewInt(600).FillBytes(make([]byte, 32)), nil, nil)
+	client.On("BlockByNumber", mock.Anything, types.BlockNumberFromUint64(100), false).
+		Return(&types.Block{Timestamp: clock.Now()}, nil)
+	provider := NewScribeOptimisticRPCProvider(client, nil)
+
+	// The window is measured by the clock of the context.
+	clock.Advance(599 * time.Second)
+	assert.True(t, provider.isChallengeWindowOpen(ctx, address, poke))
+	clock.Advance(time.Second)
+	assert.False(t, provider.isChallengeWindowOpen(ctx, address, poke))
+}
+
+func waitOutcome(t *testing.T, provider *ScribeOptimisticRpcProvider) TxOutcome {
+	t.Helper()
+	select {
+	case outcome := <-provider.ChallengeOutcomes():
+		return outcome
+	case <-time.After(time.Second):
+		t.Fatal("no challenge outcome")
+		return TxOutcome{}
+	}
 }
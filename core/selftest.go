@@ -0,0 +1,341 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/defiweb/go-eth/rpc/transport"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	logger "github.com/sirupsen/logrus"
+)
+
+// anvilStartTimeout is the time limit for Anvil to fork the chain and start serving requests.
+const anvilStartTimeout = time.Minute
+
+// selfTestWardBalance is the balance given to the impersonated ward to pay for setup transactions.
+var selfTestWardBalance = new(big.Int).Exp(big.NewInt(10), big.NewInt(20), nil)
+
+type secp256k1Point struct {
+	X *big.Int `abi:"x"` // uint256
+	Y *big.Int `abi:"y"` // uint256
+}
+
+type ecdsaData struct {
+	V uint8    `abi:"v"` // uint8
+	R [32]byte `abi:"r"` // bytes32
+	S [32]byte `abi:"s"` // bytes32
+}
+
+// SelfTest verifies the full challenge path against a fork of the target chain.
+// It lifts a throwaway feed, injects a synthetic poke with invalid Schnorr signature and challenges it
+// with the configured key, gas settings and relay path.
+// Fork node must support Anvil cheat codes, it MUST NOT be used against a live chain.
+type SelfTest struct {
+	transport transport.Transport
+	client    RPCClient
+	provider  IScribeOptimisticProvider
+	address   types.Address
+}
+
+// NewSelfTest creates a new instance of SelfTest.
+// transport is used for cheat codes, client and provider have to be connected to the same fork node.
+func NewSelfTest(
+	t transport.Transport,
+	client RPCClient,
+	provider IScribeOptimisticProvider,
+	address types.Address,
+) *SelfTest {
+	return &SelfTest{
+		transport: t,
+		client:    client,
+		provider:  provider,
+		address:   address,
+	}
+}
+
+// Run injects the invalid poke and challenges it, returns error if any step of the challenge path fails.
+func (s *SelfTest) Run(ctx context.Context) error {
+	ward, err := s.ward(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.cheat(ctx, "anvil_impersonateAccount", ward); err != nil {
+		return err
+	}
+	if err := s.cheat(ctx, "anvil_setBalance", ward, "0x"+selfTestWardBalance.Text(16)); err != nil {
+		return err
+	}
+
+	feed := wallet.NewRandomKey()
+	feedIndex, err := s.liftFeed(ctx, ward, feed)
+	if err != nil {
+		return err
+	}
+	logger.WithField("address", s.address).Infof("Lifted self-test feed %v with index %d", feed.Address(), feedIndex)
+
+	// Previous optimistic poke might still be in its challenge period, which blocks new ones.
+	period, err := s.provider.GetChallengePeriod(ctx, s.address)
+	if err != nil {
+		return err
+	}
+	if err := s.cheat(ctx, "evm_increaseTime", int64(period)+1); err != nil {
+		return err
+	}
+	if err := s.cheat(ctx, "evm_mine"); err != nil {
+		return err
+	}
+
+	poke, err := s.injectInvalidPoke(ctx, ward, feed, feedIndex)
+	if err != nil {
+		return err
+	}
+	logger.WithField("address", s.address).Infof("Injected invalid poke in block %v", poke.BlockNumber)
+
+	valid, err := s.provider.IsPokeSignatureValid(ctx, s.address, poke)
+	if err != nil {
+		return fmt.Errorf("failed to verify injected poke: %w", err)
+	}
+	if valid {
+		return fmt.Errorf("injected poke with invalid signature was verified as valid")
+	}
+
+	txHash, _, err := s.provider.ChallengePoke(ctx, s.address, poke)
+	if err != nil {
+		return fmt.Errorf("failed to challenge injected poke: %w", err)
+	}
+	logger.WithField("address", s.address).WithField("txHash", txHash).Infof("Challenge transaction sent")
+
+	select {
+	case outcome := <-s.provider.ChallengeOutcomes():
+		if outcome.Err != nil {
+			return fmt.Errorf("failed to challenge injected poke: %w", outcome.Err)
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	logger.WithField("address", s.address).WithField("txHash", txHash).Infof("Challenge transaction confirmed")
+
+	latest, err := s.provider.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	challenges, err := s.provider.GetSuccessfulChallenges(ctx, s.address, poke.BlockNumber, latest)
+	if err != nil {
+		return err
+	}
+	if len(challenges) == 0 {
+		return fmt.Errorf("challenge transaction %v did not emit OpPokeChallengedSuccessfully event", txHash)
+	}
+	return nil
+}
+
+// ward returns one of the addresses authorized to lift feeds.
+func (s *SelfTest) ward(ctx context.Context) (types.Address, error) {
+	authed := ScribeOptimisticContractABI.MethodsBySignature["authed()"]
+	b, err := s.call(ctx, authed.FourBytes().Bytes())
+	if err != nil {
+		return types.ZeroAddress, fmt.Errorf("failed to call authed with error: %w: %w", ErrRPCUnavailable, err)
+	}
+	var wards []types.Address
+	if err := authed.DecodeValues(b, &wards); err != nil {
+		return types.ZeroAddress, fmt.Errorf("failed to decode authed result with error: %w: %w", ErrDecode, err)
+	}
+	if len(wards) == 0 {
+		return types.ZeroAddress, fmt.Errorf("contract %v has no wards", s.address)
+	}
+	return wards[0], nil
+}
+
+// liftFeed registers the feed key on the contract on behalf of the ward and returns its feed index.
+func (s *SelfTest) liftFeed(ctx context.Context, ward types.Address, feed *wallet.PrivateKey) (uint8, error) {
+	registrationMessage := ScribeOptimisticContractABI.Methods["feedRegistrationMessage"]
+	b, err := s.call(ctx, registrationMessage.FourBytes().Bytes())
+	if err != nil {
+		return 0, fmt.Errorf("failed to call feedRegistrationMessage with error: %w: %w", ErrRPCUnavailable, err)
+	}
+	var message types.Hash
+	if err := registrationMessage.DecodeValues(b, &message); err != nil {
+		return 0, fmt.Errorf("failed to decode feedRegistrationMessage result with error: %w: %w", ErrDecode, err)
+	}
+	sig, err := signHash(ctx, feed, message)
+	if err != nil {
+		return 0, err
+	}
+	pub := feed.PublicKey()
+	lift := ScribeOptimisticContractABI.MethodsBySignature["lift((uint256,uint256),(uint8,bytes32,bytes32))"]
+	calldata, err := lift.EncodeArgs(secp256k1Point{X: pub.X, Y: pub.Y}, sig)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode lift args: %w", err)
+	}
+	if err := s.sendAs(ctx, ward, calldata); err != nil {
+		return 0, fmt.Errorf("failed to lift feed: %w", err)
+	}
+
+	feeds := ScribeOptimisticContractABI.MethodsBySignature["feeds(address)"]
+	calldata, err = feeds.EncodeArgs(feed.Address())
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode feeds args: %w", err)
+	}
+	b, err = s.call(ctx, calldata)
+	if err != nil {
+		return 0, fmt.Errorf("failed to call feeds with error: %w: %w", ErrRPCUnavailable, err)
+	}
+	var isFeed bool
+	var index *big.Int
+	if err := feeds.DecodeValues(b, &isFeed, &index); err != nil {
+		return 0, fmt.Errorf("failed to decode feeds result with error: %w: %w", ErrDecode, err)
+	}
+	if !isFeed {
+		return 0, fmt.Errorf("feed %v was not lifted", feed.Address())
+	}
+	return uint8(index.Uint64()), nil
+}
+
+// injectInvalidPoke submits the optimistic poke signed by the feed, but with random Schnorr signature.
+func (s *SelfTest) injectInvalidPoke(
+	ctx context.Context,
+	ward types.Address,
+	feed *wallet.PrivateKey,
+	feedIndex uint8,
+) (*OpPokedEvent, error) {
+	block, err := s.client.BlockByNumber(ctx, types.LatestBlockNumber, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	pokeData := PokeData{Val: big.NewInt(1), Age: uint32(block.Timestamp.Unix())}
+	schnorrData := SchnorrData{SignersBlob: []byte{feedIndex}}
+	if _, err := rand.Read(schnorrData.Signature[:]); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(schnorrData.Commitment[:]); err != nil {
+		return nil, err
+	}
+
+	constructMessage := ScribeOptimisticContractABI.Methods["constructOpPokeMessage"]
+	calldata, err := constructMessage.EncodeArgs(pokeData, schnorrData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode constructOpPokeMessage args: %w", err)
+	}
+	b, err := s.call(ctx, calldata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call constructOpPokeMessage with error: %w: %w", ErrRPCUnavailable, err)
+	}
+	var message types.Hash
+	if err := constructMessage.DecodeValues(b, &message); err != nil {
+		return nil, fmt.Errorf("failed to decode constructOpPokeMessage result with error: %w: %w", ErrDecode, err)
+	}
+	sig, err := signHash(ctx, feed, message)
+	if err != nil {
+		return nil, err
+	}
+
+	opPoke := ScribeOptimisticContractABI.Methods["opPoke"]
+	calldata, err = opPoke.EncodeArgs(pokeData, schnorrData, sig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode opPoke args: %w", err)
+	}
+	if err := s.sendAs(ctx, ward, calldata); err != nil {
+		return nil, fmt.Errorf("failed to opPoke: %w", err)
+	}
+
+	latest, err := s.provider.BlockNumber(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pokes, err := s.provider.GetPokes(ctx, s.address, latest, latest)
+	if err != nil {
+		return nil, err
+	}
+	if len(pokes) == 0 {
+		return nil, fmt.Errorf("injected poke not found in block %v", latest)
+	}
+	return pokes[len(pokes)-1], nil
+}
+
+func (s *SelfTest) call(ctx context.Context, calldata []byte) ([]byte, error) {
+	b, _, err := s.client.Call(ctx, &types.Call{To: &s.address, Input: calldata}, types.LatestBlockNumber)
+	return b, err
+}
+
+// sendAs sends the transaction from impersonated account and checks it succeeded.
+func (s *SelfTest) sendAs(ctx context.Context, from types.Address, calldata []byte) error {
+	var txHash types.Hash
+	call := types.NewCall().SetFrom(from).SetTo(s.address).SetInput(calldata)
+	if err := s.transport.Call(ctx, &txHash, "eth_sendTransaction", call); err != nil {
+		return err
+	}
+	receipt, err := s.client.GetTransactionReceipt(ctx, txHash)
+	if err != nil {
+		return fmt.Errorf("failed to get receipt of %v: %w", txHash, err)
+	}
+	if receipt.Status == nil || *receipt.Status == 0 {
+		return fmt.Errorf("transaction %v reverted", txHash)
+	}
+	return nil
+}
+
+func (s *SelfTest) cheat(ctx context.Context, method string, args ...any) error {
+	if err := s.transport.Call(ctx, nil, method, args...); err != nil {
+		return fmt.Errorf("failed to call %s on fork node, is it Anvil? %w", method, err)
+	}
+	return nil
+}
+
+// signHash signs the already prefixed message hash the way ecrecover expects it.
+func signHash(ctx context.Context, key *wallet.PrivateKey, hash types.Hash) (ecdsaData, error) {
+	sig, err := key.SignHash(ctx, hash)
+	if err != nil {
+		return ecdsaData{}, fmt.Errorf("failed to sign hash: %w", err)
+	}
+	var data ecdsaData
+	data.V = uint8(sig.V.Uint64() + 27)
+	sig.R.FillBytes(data.R[:])
+	sig.S.FillBytes(data.S[:])
+	return data, nil
+}
+
+// StartAnvilFork starts Anvil forking the chain behind forkURL on a free local port.
+// Returns URL of the fork node and function stopping it.
+func StartAnvilFork(ctx context.Context, anvilPath, forkURL string) (string, func(), error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to find free port: %w", err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	cmd := exec.CommandContext(ctx, anvilPath, "--fork-url", forkURL, "--port", strconv.Itoa(port), "--silent")
+	if err := cmd.Start(); err != nil {
+		return "", nil, fmt.Errorf("failed to start anvil: %w", err)
+	}
+	stop := func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}
+
+	url := fmt.Sprintf("http://127.0.0.1:%d", port)
+	t, err := transport.NewHTTP(transport.HTTPOptions{URL: url})
+	if err != nil {
+		stop()
+		return "", nil, err
+	}
+	deadline := time.Now().Add(anvilStartTimeout)
+	for {
+		var chainID types.Number
+		if err := t.Call(ctx, &chainID, "eth_chainId"); err == nil {
+			return url, stop, nil
+		}
+		if time.Now().After(deadline) {
+			stop()
+			return "", nil, fmt.Errorf("anvil did not start within %v", anvilStartTimeout)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
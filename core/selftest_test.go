@@ -0,0 +1,45 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignHash(t *testing.T) {
+	key := wallet.NewRandomKey()
+	hash := crypto.Keccak256([]byte("Chronicle Feed Registration"))
+
+	data, err := signHash(context.TODO(), key, hash)
+	require.NoError(t, err)
+	assert.Contains(t, []uint8{27, 28}, data.V)
+
+	// Signature has to be recoverable the same way as with `ecrecover(hash, v, r, s)`.
+	sig := types.Signature{
+		V: big.NewInt(int64(data.V)),
+		R: new(big.Int).SetBytes(data.R[:]),
+		S: new(big.Int).SetBytes(data.S[:]),
+	}
+	addr, err := crypto.ECRecoverer.RecoverHash(hash, sig)
+	require.NoError(t, err)
+	assert.Equal(t, key.Address(), *addr)
+}
+
+func TestSelfTestLiftCalldata(t *testing.T) {
+	key := wallet.NewRandomKey()
+	pub := key.PublicKey()
+	sig, err := signHash(context.TODO(), key, crypto.Keccak256([]byte("message")))
+	require.NoError(t, err)
+
+	lift := ScribeOptimisticContractABI.MethodsBySignature["lift((uint256,uint256),(uint8,bytes32,bytes32))"]
+	calldata, err := lift.EncodeArgs(secp256k1Point{X: pub.X, Y: pub.Y}, sig)
+	require.NoError(t, err)
+	assert.Len(t, calldata, 4+5*32)
+	assert.Equal(t, lift.FourBytes().Bytes(), calldata[:4])
+}
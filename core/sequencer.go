@@ -0,0 +1,119 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// SequencerCheckInterval is the interval the sequencer health is checked at.
+var SequencerCheckInterval = 30 * time.Second
+
+// Reasons of the sequencer being considered down.
+const (
+	SequencerReasonFeed = "uptime_feed"
+	SequencerReasonLag  = "block_lag"
+)
+
+// SequencerMonitor detects downtime of the sequencer of an L2 chain, during which challenges can't be included.
+// The sequencer is considered down if the Chainlink compatible sequencer uptime feed reports it (answer 1),
+// or if the latest block is older than the maximum lag. Forced inclusion through L1 takes hours on rollups,
+// longer than challenge periods, so the downtime is only alerted on.
+type SequencerMonitor struct {
+	client RPCClient
+	feed   *types.Address
+	maxLag time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	reason string
+}
+
+// NewSequencerMonitor creates a new instance of SequencerMonitor. Uptime feed is not checked if nil,
+// block lag is not checked if maxLag is 0.
+func NewSequencerMonitor(client RPCClient, feed *types.Address, maxLag time.Duration) *SequencerMonitor {
+	return &SequencerMonitor{client: client, feed: feed, maxLag: maxLag, now: time.Now}
+}
+
+// Run checks the sequencer every SequencerCheckInterval until ctx is done.
+func (s *SequencerMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(SequencerCheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.Check(ctx); err != nil {
+			logger.Errorf("Failed to check sequencer health with error: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check updates the sequencer health, alerting when the sequencer goes down and when it recovers.
+func (s *SequencerMonitor) Check(ctx context.Context) error {
+	reason, detail, err := s.check(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if reason != "" {
+		SequencerUpGauge.Set(0)
+		if s.reason != reason {
+			logger.
+				WithField("reason", reason).
+				Errorf("Sequencer is down (%s), challenges may not be included before their challenge window closes", detail)
+		}
+	} else {
+		SequencerUpGauge.Set(1)
+		if s.reason != "" {
+			logger.Warn("Sequencer is up again")
+		}
+	}
+	s.reason = reason
+	return nil
+}
+
+// Up returns false if the sequencer was found down by the last check.
+func (s *SequencerMonitor) Up() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reason == ""
+}
+
+func (s *SequencerMonitor) check(ctx context.Context) (string, string, error) {
+	if s.feed != nil {
+		b, _, err := s.client.Call(ctx, &types.Call{
+			To:    s.feed,
+			Input: aggregatorLatestRoundData.FourBytes().Bytes(),
+		}, types.LatestBlockNumber)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to call latestRoundData of %v with error: %w: %w", s.feed, ErrRPCUnavailable, err)
+		}
+		var roundID, answer, startedAt, updatedAt, answeredInRound *big.Int
+		if err := aggregatorLatestRoundData.DecodeValues(b, &roundID, &answer, &startedAt, &updatedAt, &answeredInRound); err != nil {
+			return "", "", fmt.Errorf("failed to decode latestRoundData result with error: %w: %w", ErrDecode, err)
+		}
+		if answer.Sign() != 0 {
+			return SequencerReasonFeed, fmt.Sprintf("reported by uptime feed %v since %v", s.feed, time.Unix(startedAt.Int64(), 0)), nil
+		}
+	}
+	if s.maxLag > 0 {
+		block, err := s.client.BlockByNumber(ctx, types.LatestBlockNumber, false)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get latest block with error: %w: %w", ErrRPCUnavailable, err)
+		}
+		if lag := s.now().Sub(block.Timestamp); lag > s.maxLag {
+			return SequencerReasonLag, fmt.Sprintf("latest block %v is %v old", block.Number, lag.Round(time.Second)), nil
+		}
+	}
+	return "", "", nil
+}
@@ -0,0 +1,50 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSequencerMonitor(t *testing.T) {
+	feed := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	round := func(answer int64) []byte {
+		b, err := abi.EncodeValues(aggregatorLatestRoundData.Outputs(), big.NewInt(1), big.NewInt(answer), big.NewInt(1700000000), big.NewInt(1700000000), big.NewInt(1))
+		require.NoError(t, err)
+		return b
+	}
+	now := time.Now()
+
+	client := new(mockRpcClient)
+	feedCall := client.On("Call", mock.Anything, mock.Anything, types.LatestBlockNumber).Return(round(0), nil, nil)
+	client.On("BlockByNumber", mock.Anything, types.LatestBlockNumber, false).
+		Return(&types.Block{Number: big.NewInt(100), Timestamp: now.Add(-10 * time.Second)}, nil).Once()
+
+	s := NewSequencerMonitor(client, &feed, time.Minute)
+	s.now = func() time.Time { return now }
+	require.NoError(t, s.Check(context.TODO()))
+	assert.True(t, s.Up())
+	assert.Equal(t, 1.0, testutil.ToFloat64(SequencerUpGauge))
+
+	// Blocks stopped being produced.
+	client.On("BlockByNumber", mock.Anything, types.LatestBlockNumber, false).
+		Return(&types.Block{Number: big.NewInt(100), Timestamp: now.Add(-5 * time.Minute)}, nil).Once()
+	require.NoError(t, s.Check(context.TODO()))
+	assert.False(t, s.Up())
+	assert.Equal(t, 0.0, testutil.ToFloat64(SequencerUpGauge))
+
+	// Uptime feed reports the downtime without checking blocks.
+	feedCall.Unset()
+	client.On("Call", mock.Anything, mock.Anything, types.LatestBlockNumber).Return(round(1), nil, nil)
+	require.NoError(t, s.Check(context.TODO()))
+	assert.False(t, s.Up())
+	client.AssertNumberOfCalls(t, "BlockByNumber", 2)
+}
@@ -0,0 +1,247 @@
+package core
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// DefaultStatsHistoryLimit is the default number of the latest pokes and challenges kept by Stats.
+const DefaultStatsHistoryLimit = 100
+
+// PokeRecord is a poke seen by the challenger.
+type PokeRecord struct {
+	Address     types.Address `json:"address"`
+	BlockNumber uint64        `json:"blockNumber"`
+	TxHash      *types.Hash   `json:"txHash,omitempty"`
+	Value       string        `json:"value,omitempty"`
+	Age         uint64        `json:"age"`
+	Invalid     bool          `json:"invalid"`
+}
+
+// ChallengeRecord is a challenge sent by the challenger with its latest result.
+type ChallengeRecord struct {
+	Address   types.Address `json:"address"`
+	PokeBlock uint64        `json:"pokeBlock"`
+	TxHash    *types.Hash   `json:"txHash,omitempty"`
+	Result    string        `json:"result"`
+	Time      time.Time     `json:"time"`
+}
+
+// AddressStats are statistics of a monitored address since the start of the process.
+type AddressStats struct {
+	PokesSeen           uint64 `json:"pokesSeen"`
+	InvalidPokes        uint64 `json:"invalidPokes"`
+	ChallengesSent      uint64 `json:"challengesSent"`
+	ChallengesConfirmed uint64 `json:"challengesConfirmed"`
+	ChallengesLost      uint64 `json:"challengesLost"`
+	ChallengesFailed    uint64 `json:"challengesFailed"`
+	// LastPokeValue is the value of the latest poke scaled down by 18 decimals, empty if no poke was seen.
+	LastPokeValue string `json:"lastPokeValue,omitempty"`
+	// LastPokeAge is the age (unix timestamp) of the latest poke, zero if no poke was seen.
+	LastPokeAge uint64 `json:"lastPokeAge,omitempty"`
+}
+
+// StatsSnapshot is the operational snapshot served by Stats.
+type StatsSnapshot struct {
+	StartedAt     time.Time                       `json:"startedAt"`
+	UptimeSeconds float64                         `json:"uptimeSeconds"`
+	Balances      map[types.Address]string        `json:"balances"`
+	Addresses     map[types.Address]*AddressStats `json:"addresses"`
+	// Pokes and Challenges are the latest pokes and challenges of all addresses, the latest first.
+	Pokes      []PokeRecord      `json:"pokes"`
+	Challenges []ChallengeRecord `json:"challenges"`
+}
+
+// Stats counts pokes and challenges of each address and keeps the latest balance of challenger accounts,
+// so a quick operational snapshot is available without Prometheus queries.
+type Stats struct {
+	mu        sync.Mutex
+	startedAt time.Time
+	balances  map[types.Address]*big.Int
+	addresses map[types.Address]*AddressStats
+	// invalid holds block numbers of invalid pokes of each address, pokes are detected again on later ticks.
+	invalid    map[types.Address]map[uint64]struct{}
+	limit      int
+	pokes      []PokeRecord
+	challenges []ChallengeRecord
+}
+
+// NewStats creates a new instance of Stats for the addresses keeping up to limit latest pokes and challenges.
+func NewStats(addresses []types.Address, limit int) *Stats {
+	s := &Stats{
+		startedAt: time.Now(),
+		limit:     limit,
+		balances:  make(map[types.Address]*big.Int),
+		addresses: make(map[types.Address]*AddressStats, len(addresses)),
+		invalid:   make(map[types.Address]map[uint64]struct{}, len(addresses)),
+	}
+	for _, address := range addresses {
+		s.addresses[address] = &AddressStats{}
+	}
+	return s
+}
+
+// address returns stats of the address, must be called with mu held.
+func (s *Stats) address(address types.Address) *AddressStats {
+	a, ok := s.addresses[address]
+	if !ok {
+		a = &AddressStats{}
+		s.addresses[address] = a
+	}
+	return a
+}
+
+// trim drops the oldest records above the limit.
+func trim[T any](records []T, limit int) []T {
+	if limit > 0 && len(records) > limit {
+		return records[len(records)-limit:]
+	}
+	return records
+}
+
+// RecordPokes counts new pokes of the address and keeps the latest of them.
+func (s *Stats) RecordPokes(address types.Address, pokes []*OpPokedEvent) {
+	if len(pokes) == 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.address(address)
+	a.PokesSeen += uint64(len(pokes))
+	for _, poke := range pokes {
+		r := PokeRecord{Address: address, TxHash: poke.TxHash, Age: uint64(poke.PokeData.Age)}
+		if poke.BlockNumber != nil {
+			r.BlockNumber = poke.BlockNumber.Uint64()
+		}
+		if poke.PokeData.Val != nil {
+			r.Value = scaleDown(poke.PokeData.Val, pokeValueDecimals).Text('f', -1)
+		}
+		s.pokes = append(s.pokes, r)
+		a.LastPokeValue, a.LastPokeAge = r.Value, r.Age
+	}
+	s.pokes = trim(s.pokes, s.limit)
+}
+
+// RecordInvalidPoke counts the invalid poke of the address once, however many times it's detected.
+func (s *Stats) RecordInvalidPoke(address types.Address, poke *OpPokedEvent) {
+	if poke == nil || poke.BlockNumber == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	blocks, ok := s.invalid[address]
+	if !ok {
+		blocks = make(map[uint64]struct{})
+		s.invalid[address] = blocks
+	}
+	if _, ok := blocks[poke.BlockNumber.Uint64()]; ok {
+		return
+	}
+	blocks[poke.BlockNumber.Uint64()] = struct{}{}
+	s.address(address).InvalidPokes++
+	for i := range s.pokes {
+		if s.pokes[i].Address == address && s.pokes[i].BlockNumber == poke.BlockNumber.Uint64() {
+			s.pokes[i].Invalid = true
+		}
+	}
+}
+
+// RecordChallenge counts the challenge of the poke of the address by its ChallengeResult and keeps it in the history,
+// the result of a challenge already kept is updated.
+func (s *Stats) RecordChallenge(address types.Address, poke *OpPokedEvent, txHash *types.Hash, result string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if poke != nil && poke.BlockNumber != nil {
+		s.recordChallenge(ChallengeRecord{
+			Address:   address,
+			PokeBlock: poke.BlockNumber.Uint64(),
+			TxHash:    txHash,
+			Result:    result,
+			Time:      time.Now(),
+		})
+	}
+	a := s.address(address)
+	switch result {
+	case ChallengeResultSubmitted:
+		a.ChallengesSent++
+	case ChallengeResultConfirmed:
+		a.ChallengesConfirmed++
+	case ChallengeResultLostRace:
+		a.ChallengesLost++
+	default:
+		a.ChallengesFailed++
+	}
+}
+
+// recordChallenge updates the latest record of the same challenge, or appends a new one, must be called with mu held.
+func (s *Stats) recordChallenge(r ChallengeRecord) {
+	if r.Result != ChallengeResultSubmitted {
+		for i := len(s.challenges) - 1; i >= 0; i-- {
+			c := &s.challenges[i]
+			if c.Address == r.Address && c.PokeBlock == r.PokeBlock && c.Result == ChallengeResultSubmitted {
+				c.Result, c.Time = r.Result, r.Time
+				if r.TxHash != nil {
+					c.TxHash = r.TxHash
+				}
+				return
+			}
+		}
+	}
+	s.challenges = trim(append(s.challenges, r), s.limit)
+}
+
+// RecordBalance keeps the balance of the challenger account.
+func (s *Stats) RecordBalance(from types.Address, balance *big.Int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.balances[from] = new(big.Int).Set(balance)
+}
+
+// Snapshot returns a copy of the current stats.
+func (s *Stats) Snapshot() StatsSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshot := StatsSnapshot{
+		StartedAt:     s.startedAt,
+		UptimeSeconds: time.Since(s.startedAt).Seconds(),
+		Balances:      make(map[types.Address]string, len(s.balances)),
+		Addresses:     make(map[types.Address]*AddressStats, len(s.addresses)),
+		Pokes:         make([]PokeRecord, 0, len(s.pokes)),
+		Challenges:    make([]ChallengeRecord, 0, len(s.challenges)),
+	}
+	for i := len(s.pokes) - 1; i >= 0; i-- {
+		snapshot.Pokes = append(snapshot.Pokes, s.pokes[i])
+	}
+	for i := len(s.challenges) - 1; i >= 0; i-- {
+		snapshot.Challenges = append(snapshot.Challenges, s.challenges[i])
+	}
+	for from, balance := range s.balances {
+		snapshot.Balances[from] = NativeToken.Format(balance)
+	}
+	for address, a := range s.addresses {
+		c := *a
+		snapshot.Addresses[address] = &c
+	}
+	return snapshot
+}
+
+// ServeHTTP serves the snapshot of stats as JSON.
+func (s *Stats) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.Snapshot()); err != nil {
+		logger.WithError(err).Error("failed to encode stats")
+	}
+}
+
+// WithStats makes Challenger count its pokes and challenges in the stats.
+func WithStats(stats *Stats) ChallengerOption {
+	return func(c *Challenger) {
+		c.stats = stats
+	}
+}
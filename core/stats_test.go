@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	from := types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEe2")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	value, _ := new(big.Int).SetString("1500500000000000000000", 10)
+	poke := &OpPokedEvent{BlockNumber: big.NewInt(500), PokeData: PokeData{Val: value, Age: 1700000000}}
+
+	p := new(mockScribeOptimisticProvider)
+	p.On("BlockNumber", mock.Anything).Return(big.NewInt(1000), nil)
+	p.On("GetChallengePeriod", mock.Anything, address).Return(600, nil)
+	p.On("GetPokes", mock.Anything, address, mock.Anything, mock.Anything).Return([]*OpPokedEvent{poke}, nil)
+	p.On("GetSuccessfulChallenges", mock.Anything, address, mock.Anything, mock.Anything).
+		Return([]*OpPokeChallengedSuccessfullyEvent{}, nil)
+	p.On("BlockByNumber", mock.Anything, big.NewInt(500)).
+		Return(&types.Block{Number: big.NewInt(500), Timestamp: time.Now()}, nil)
+	p.On("IsPokeSignatureValid", mock.Anything, address, poke).Return(false, nil)
+	p.On("GetFrom", mock.Anything).Return(from)
+	p.On("ChallengePoke", mock.Anything, address, poke).Return(&txHash, &types.Transaction{}, nil)
+
+	stats := NewStats([]types.Address{address}, DefaultStatsHistoryLimit)
+	c := NewChallenger(context.TODO(), address, p, 100, nil, WithStats(stats))
+	require.NoError(t, c.executeTick(c.ctx))
+	c.submissions.Wait()
+	// The poke in flight is not counted again.
+	require.NoError(t, c.executeTick(c.ctx))
+	c.handleChallengeOutcome(TxOutcome{Address: address, Poke: poke, Hash: &txHash, Err: ErrAlreadyChallenged})
+	stats.RecordBalance(from, big.NewInt(2e18))
+
+	rec := httptest.NewRecorder()
+	stats.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var snapshot StatsSnapshot
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &snapshot))
+
+	assert.Equal(t, &AddressStats{
+		PokesSeen:      1,
+		InvalidPokes:   1,
+		ChallengesSent: 1,
+		ChallengesLost: 1,
+		LastPokeValue:  "1500.5",
+		LastPokeAge:    1700000000,
+	}, snapshot.Addresses[address])
+	assert.Equal(t, map[types.Address]string{from: "2 ETH"}, snapshot.Balances)
+	assert.Positive(t, snapshot.UptimeSeconds)
+
+	require.Len(t, snapshot.Pokes, 1)
+	assert.Equal(t, uint64(500), snapshot.Pokes[0].BlockNumber)
+	assert.True(t, snapshot.Pokes[0].Invalid)
+	require.Len(t, snapshot.Challenges, 1)
+	assert.Equal(t, ChallengeResultLostRace, snapshot.Challenges[0].Result)
+	assert.Equal(t, &txHash, snapshot.Challenges[0].TxHash)
+}
+
+func TestStatsHistoryLimit(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	stats := NewStats(nil, 2)
+	for block := int64(1); block <= 3; block++ {
+		poke := &OpPokedEvent{BlockNumber: big.NewInt(block)}
+		stats.RecordPokes(address, []*OpPokedEvent{poke})
+		stats.RecordChallenge(address, poke, nil, ChallengeResultSubmitted)
+	}
+	stats.RecordChallenge(address, &OpPokedEvent{BlockNumber: big.NewInt(3)}, nil, ChallengeResultConfirmed)
+
+	snapshot := stats.Snapshot()
+	require.Len(t, snapshot.Pokes, 2)
+	assert.Equal(t, uint64(3), snapshot.Pokes[0].BlockNumber)
+	assert.Equal(t, uint64(2), snapshot.Pokes[1].BlockNumber)
+	require.Len(t, snapshot.Challenges, 2)
+	assert.Equal(t, ChallengeResultConfirmed, snapshot.Challenges[0].Result)
+	assert.Equal(t, ChallengeResultSubmitted, snapshot.Challenges[1].Result)
+	assert.Equal(t, uint64(3), snapshot.Addresses[address].PokesSeen)
+}
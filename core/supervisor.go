@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// SupervisorMinBackoff is the delay before the first restart of a failed processing loop.
+var SupervisorMinBackoff = time.Second
+
+// SupervisorMaxBackoff caps the delay between restarts, it doubles with each consecutive failure.
+var SupervisorMaxBackoff = 5 * time.Minute
+
+// errPanic marks errors of loops which panicked.
+var errPanic = errors.New("panic")
+
+// SupervisorHealthyAfter is the time a restarted loop has to keep running to be considered healthy again.
+var SupervisorHealthyAfter = time.Minute
+
+// Supervise runs the processing loop of the address until ctx is done, restarting it with exponential backoff
+// if it fails or panics, so one failing address doesn't take down monitoring of the others.
+// While the loop keeps failing, the address is marked degraded in LoopDegradedGauge.
+// The backoff and the time the loop runs for are measured by the clock of the context.
+func Supervise(ctx context.Context, address types.Address, run func(ctx context.Context) error) {
+	clock := clockFromContext(ctx)
+	degraded := LoopDegradedGauge.WithLabelValues(address.String())
+	degraded.Set(0)
+
+	backoff := SupervisorMinBackoff
+	for {
+		started := clock.Now()
+		stopHealthy := markHealthyAfter(clock, degraded.Set)
+		err := runSafely(ctx, run)
+		stopHealthy()
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = errors.New("processing loop exited unexpectedly")
+		}
+		if clock.Now().Sub(started) >= SupervisorHealthyAfter {
+			backoff = SupervisorMinBackoff
+		}
+
+		if errors.Is(err, errPanic) {
+			PanicsCounter.WithLabelValues(address.String(), "loop").Inc()
+		}
+		degraded.Set(1)
+		LoopRestartsCounter.WithLabelValues(address.String()).Inc()
+		logger.
+			WithField("address", address).
+			Errorf("Processing loop failed, restarting in %v: %v", backoff, err)
+
+		timer := clock.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+		backoff = min(backoff*2, SupervisorMaxBackoff)
+	}
+}
+
+// markHealthyAfter sets the degraded gauge to 0 once the loop runs for SupervisorHealthyAfter. The returned function
+// is called once the loop exits, it stops the timer and waits for the gauge not to be updated anymore.
+func markHealthyAfter(clock Clock, set func(float64)) (stop func()) {
+	timer := clock.NewTimer(SupervisorHealthyAfter)
+	exited := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		select {
+		case <-timer.C():
+			set(0)
+		case <-exited:
+		}
+	}()
+	return func() {
+		timer.Stop()
+		close(exited)
+		<-done
+	}
+}
+
+// runSafely turns panic of the loop into an error wrapping errPanic.
+func runSafely(ctx context.Context, run func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Debugf("Recovered panic stack: %s", debug.Stack())
+			err = fmt.Errorf("%w: %v", errPanic, r)
+		}
+	}()
+	return run(ctx)
+}
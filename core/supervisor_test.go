@@ -0,0 +1,118 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupervise(t *testing.T) {
+	address := types.MustAddressFromHex("0x3F7acDa376eF37EC371235a094113dF9Cb4EfEe3")
+	restarts := testutil.ToFloat64(LoopRestartsCounter.WithLabelValues(address.String()))
+	panics := testutil.ToFloat64(PanicsCounter.WithLabelValues(address.String(), "loop"))
+	clock := newFakeClock()
+	ctx, cancel := context.WithCancel(ContextWithClock(context.Background(), clock))
+	defer cancel()
+
+	var runs atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		Supervise(ctx, address, func(ctx context.Context) error {
+			switch runs.Add(1) {
+			case 1:
+				return fmt.Errorf("rpc misconfigured")
+			case 2:
+				panic("nil pointer")
+			default:
+				<-ctx.Done()
+				return nil
+			}
+		})
+		close(done)
+	}()
+
+	// Restarts wait for the backoff, which doubles with each failure.
+	for i, backoff := range []time.Duration{SupervisorMinBackoff, 2 * SupervisorMinBackoff} {
+		require.Eventually(t, func() bool {
+			failed := testutil.ToFloat64(LoopRestartsCounter.WithLabelValues(address.String())) == restarts+float64(i+1)
+			return failed && clock.Waiters() == 1
+		}, time.Second, time.Millisecond)
+		clock.Advance(backoff - time.Nanosecond)
+		assert.Equal(t, int32(i+1), runs.Load())
+		clock.Advance(time.Nanosecond)
+	}
+	require.Eventually(t, func() bool { return runs.Load() == 3 }, time.Second, time.Millisecond)
+	assert.Equal(t, restarts+2, testutil.ToFloat64(LoopRestartsCounter.WithLabelValues(address.String())))
+	assert.Equal(t, panics+1, testutil.ToFloat64(PanicsCounter.WithLabelValues(address.String(), "loop")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(LoopDegradedGauge.WithLabelValues(address.String())))
+
+	// Restarted loop keeps running, so it's healthy again.
+	clock.Advance(SupervisorHealthyAfter)
+	assert.Eventually(t, func() bool {
+		return testutil.ToFloat64(LoopDegradedGauge.WithLabelValues(address.String())) == 0
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+	assert.Equal(t, int32(3), runs.Load())
+}
+
+func TestWatchdog(t *testing.T) {
+	address := types.MustAddressFromHex("0x3F7acDa376eF37EC371235a094113dF9Cb4EfEe4")
+	clock := newFakeClock()
+
+	// The first call hangs regardless of its deadline.
+	release := make(chan struct{})
+	var calls atomic.Int32
+	p := new(mockScribeOptimisticProvider)
+	p.On("BlockNumber", mock.Anything).
+		Run(func(mock.Arguments) {
+			if calls.Add(1) == 1 {
+				<-release
+			}
+		}).
+		Return((*big.Int)(nil), assert.AnError)
+	p.On("GetFrom", mock.Anything).Return(types.ZeroAddress)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := NewChallenger(ctx, address, p, 0, nil, WithClock(clock), WithWatchdog(2))
+	done := make(chan error, 1)
+	go func() { done <- c.loop(ctx) }()
+	require.Eventually(t, func() bool { return calls.Load() == 1 && clock.Waiters() == 1 }, time.Second, time.Millisecond)
+
+	// The tick may run for the given number of intervals.
+	clock.Advance(2 * TickInterval)
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("loop restarted too early: %v", err)
+	default:
+	}
+
+	clock.Advance(TickInterval)
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrLoopStuck)
+	case <-time.After(time.Second):
+		t.Fatal("stuck loop was not restarted")
+	}
+
+	// Ticks don't overlap with the stuck one.
+	c.tick()
+	assert.Equal(t, int32(1), calls.Load())
+
+	close(release)
+	require.Eventually(t, func() bool { return c.tickStarted.Load() == 0 }, time.Second, time.Millisecond)
+	c.tick()
+	assert.Equal(t, int32(2), calls.Load())
+}
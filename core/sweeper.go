@@ -0,0 +1,94 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// sweepGasLimit is the gas limit of plain transfer of the gas token.
+const sweepGasLimit = uint64(21000)
+
+// RewardSweeper transfers challenger account balance above the configured threshold to the beneficiary address.
+// The threshold stays on the hot wallet to pay gas for future challenges and for the sweep transaction itself.
+type RewardSweeper struct {
+	client      RPCClient
+	from        types.Address
+	beneficiary types.Address
+	threshold   *big.Int
+	mu          sync.Mutex
+}
+
+// NewRewardSweeper creates a new instance of RewardSweeper.
+func NewRewardSweeper(client RPCClient, from, beneficiary types.Address, threshold *big.Int) *RewardSweeper {
+	if threshold == nil {
+		threshold = big.NewInt(0)
+	}
+	return &RewardSweeper{
+		client:      client,
+		from:        from,
+		beneficiary: beneficiary,
+		threshold:   threshold,
+	}
+}
+
+// SetFrom switches sweeping to another address, e.g. after key rotation.
+func (r *RewardSweeper) SetFrom(from types.Address) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.from = from
+}
+
+// Sweep sends balance above the threshold to the beneficiary and waits for the confirmation.
+// Returns nil hash if there is nothing to sweep.
+func (r *RewardSweeper) Sweep(ctx context.Context) (*types.Hash, *big.Int, error) {
+	// Only one sweep at a time, otherwise concurrent sweeps would transfer the same balance.
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	balance, err := r.client.GetBalance(ctx, r.from, types.LatestBlockNumber)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get balance of %v: %w", r.from, err)
+	}
+	if balance.Cmp(r.threshold) <= 0 {
+		logger.
+			WithField("from", r.from).
+			Debugf("Balance %s is below sweep threshold %s, nothing to sweep", NativeToken.Format(balance), NativeToken.Format(r.threshold))
+		return nil, nil, nil
+	}
+
+	amount := new(big.Int).Sub(balance, r.threshold)
+	tx := (&types.Transaction{}).
+		SetFrom(r.from).
+		SetTo(r.beneficiary).
+		SetValue(amount).
+		SetGasLimit(sweepGasLimit)
+
+	hash, _, err := r.client.SendTransaction(ctx, tx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to send sweep transaction: %w", err)
+	}
+
+	_, err = WaitForTxConfirmation(ctx, r.client, hash, TxConfirmationTimeout)
+	if errors.Is(err, ErrTxReverted) {
+		return hash, nil, fmt.Errorf("sweep transaction %v reverted", hash)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wait for sweep transaction confirmation: %w", err)
+	}
+
+	logger.
+		WithField("from", r.from).
+		WithField("beneficiary", r.beneficiary).
+		WithField("txHash", hash).
+		Infof("Swept %s to beneficiary", NativeToken.Format(amount))
+
+	SweptRewardsCounter.WithLabelValues(r.from.String(), r.beneficiary.String(), NativeToken.Symbol).Add(NativeToken.Float64(amount))
+
+	return hash, amount, nil
+}
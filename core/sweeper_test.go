@@ -0,0 +1,59 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewardSweeper(t *testing.T) {
+	from := types.MustAddressFromHex("0x0000000000000000000000000000000000000001")
+	beneficiary := types.MustAddressFromHex("0x000000000000000000000000000000000000c01d")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	status := uint64(1)
+	receipt := &types.TransactionReceipt{TransactionHash: txHash, Status: &status, BlockNumber: big.NewInt(1)}
+
+	t.Run("balance below threshold is not swept", func(t *testing.T) {
+		client := new(mockRpcClient)
+		client.On("GetBalance", mock.Anything, from, types.LatestBlockNumber).Return(big.NewInt(100), nil)
+
+		s := NewRewardSweeper(client, from, beneficiary, big.NewInt(100))
+		hash, amount, err := s.Sweep(context.TODO())
+		require.NoError(t, err)
+		assert.Nil(t, hash)
+		assert.Nil(t, amount)
+		client.AssertNotCalled(t, "SendTransaction")
+	})
+
+	t.Run("balance above threshold is swept", func(t *testing.T) {
+		client := new(mockRpcClient)
+		client.On("GetBalance", mock.Anything, from, types.LatestBlockNumber).Return(big.NewInt(250), nil)
+		client.On("SendTransaction", mock.Anything, mock.MatchedBy(func(tx *types.Transaction) bool {
+			return *tx.To == beneficiary && tx.Value.Cmp(big.NewInt(150)) == 0 && *tx.GasLimit == sweepGasLimit
+		})).Return(&txHash, &types.Transaction{}, nil)
+		client.On("GetTransactionReceipt", mock.Anything, txHash).Return(receipt, nil)
+
+		s := NewRewardSweeper(client, from, beneficiary, big.NewInt(100))
+		hash, amount, err := s.Sweep(context.TODO())
+		require.NoError(t, err)
+		assert.Equal(t, &txHash, hash)
+		assert.Equal(t, big.NewInt(150), amount)
+		client.AssertExpectations(t)
+	})
+
+	t.Run("balance error", func(t *testing.T) {
+		client := new(mockRpcClient)
+		client.On("GetBalance", mock.Anything, from, types.LatestBlockNumber).
+			Return((*big.Int)(nil), fmt.Errorf("rpc error"))
+
+		s := NewRewardSweeper(client, from, beneficiary, nil)
+		_, _, err := s.Sweep(context.TODO())
+		assert.ErrorContains(t, err, "failed to get balance")
+	})
+}
@@ -0,0 +1,20 @@
+package core
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer is used to instrument detection and submission of challenges.
+// It's a no-op until global tracer provider is configured (see otel.SetTracerProvider).
+var tracer = otel.Tracer("github.com/chronicleprotocol/challenger/core")
+
+// endSpan records the error, if any, and ends the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	netURL "net/url"
+	"os"
+	"strings"
+
+	"github.com/defiweb/go-eth/rpc/transport"
+)
+
+// DefaultTransportConfig is the name of the TransportConfig applied to endpoints without their own.
+const DefaultTransportConfig = "default"
+
+// TransportConfig configures the connection to an RPC endpoint, e.g. credentials of a private RPC gateway,
+// so they don't have to be embedded in the URL.
+type TransportConfig struct {
+	// Headers are sent with each HTTP request, or with the handshake of websocket connections.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// BasicAuth holds credentials of HTTP basic authentication, nil if not used.
+	BasicAuth *BasicAuth `json:"basicAuth,omitempty"`
+
+	// Proxy is the URL of the proxy connections are routed through, e.g. http://proxy:3128 or socks5://127.0.0.1:9050
+	// for Tor. If empty, the proxy of HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables is used.
+	Proxy string `json:"proxy,omitempty"`
+
+	// TLS configures mutual TLS of https and wss endpoints, nil if not used.
+	TLS *TLSConfig `json:"tls,omitempty"`
+}
+
+// TLSConfig holds paths of PEM files for mutual TLS.
+type TLSConfig struct {
+	// CertFile and KeyFile are the client certificate and its private key presented to the endpoint.
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// CAFile holds certificates of authorities the endpoint's certificate is verified against,
+	// system roots if empty.
+	CAFile string `json:"caFile,omitempty"`
+}
+
+// BasicAuth holds credentials of HTTP basic authentication.
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// TransportConfigs holds transport configurations of RPC endpoints by their name,
+// e.g. "primary", "fallback-1" or "flashbots".
+type TransportConfigs map[string]TransportConfig
+
+// For returns the configuration of the endpoint, or the DefaultTransportConfig if it has none.
+func (t TransportConfigs) For(name string) TransportConfig {
+	if cfg, ok := t[name]; ok {
+		return cfg
+	}
+	return t[DefaultTransportConfig]
+}
+
+// LoadTransportConfigs reads transport configurations of RPC endpoints from the JSON file. Header values and
+// credentials may reference environment variables as ${NAME}, so secrets don't have to be stored in the file.
+func LoadTransportConfigs(path string) (TransportConfigs, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RPC config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	var configs TransportConfigs
+	if err := dec.Decode(&configs); err != nil {
+		return nil, fmt.Errorf("failed to parse RPC config %s: %w", path, err)
+	}
+	for name, cfg := range configs {
+		for key, value := range cfg.Headers {
+			cfg.Headers[key] = os.ExpandEnv(value)
+		}
+		if cfg.BasicAuth != nil {
+			cfg.BasicAuth.Username = os.ExpandEnv(cfg.BasicAuth.Username)
+			cfg.BasicAuth.Password = os.ExpandEnv(cfg.BasicAuth.Password)
+		}
+		cfg.Proxy = os.ExpandEnv(cfg.Proxy)
+		if cfg.TLS != nil {
+			cfg.TLS.CertFile = os.ExpandEnv(cfg.TLS.CertFile)
+			cfg.TLS.KeyFile = os.ExpandEnv(cfg.TLS.KeyFile)
+			cfg.TLS.CAFile = os.ExpandEnv(cfg.TLS.CAFile)
+		}
+		configs[name] = cfg
+	}
+	return configs, nil
+}
+
+// header returns HTTP headers of the configuration.
+func (cfg TransportConfig) header() http.Header {
+	h := http.Header{}
+	for key, value := range cfg.Headers {
+		h.Set(key, value)
+	}
+	if cfg.BasicAuth != nil {
+		r := http.Request{Header: h}
+		r.SetBasicAuth(cfg.BasicAuth.Username, cfg.BasicAuth.Password)
+	}
+	return h
+}
+
+// httpClient returns the HTTP client connecting as configured, with User-Agent and request IDs set on requests.
+func (cfg TransportConfig) httpClient() (*http.Client, error) {
+	if cfg.Proxy == "" && cfg.TLS == nil {
+		return &http.Client{Transport: requestTransport{base: http.DefaultTransport}}, nil
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Proxy != "" {
+		proxy, err := netURL.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		switch proxy.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("unsupported proxy scheme %q, supported are http, https, socks5 and socks5h", proxy.Scheme)
+		}
+		t.Proxy = http.ProxyURL(proxy)
+	}
+	if cfg.TLS != nil {
+		tlsConfig, err := cfg.TLS.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		t.TLSClientConfig = tlsConfig
+	}
+	return &http.Client{Transport: requestTransport{base: t}}, nil
+}
+
+// tlsConfig loads the certificates into the TLS configuration of the client.
+func (cfg TLSConfig) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, fmt.Errorf("both certFile and keyFile of TLS client certificate are required")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in TLS CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// NewTransport creates the transport of the RPC endpoint, HTTP, websocket or IPC depending on the URL scheme,
+// configured by cfg. IPC endpoints are given as ipc:///path/geth.ipc, or as the plain path of the socket,
+// cfg doesn't apply to them.
+func NewTransport(ctx context.Context, url string, cfg TransportConfig) (transport.Transport, error) {
+	u, err := netURL.Parse(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid RPC URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		client, err := cfg.httpClient()
+		if err != nil {
+			return nil, err
+		}
+		return transport.NewHTTP(transport.HTTPOptions{URL: url, HTTPClient: client, HTTPHeader: cfg.header()})
+	case "ws", "wss":
+		client, err := cfg.httpClient()
+		if err != nil {
+			return nil, err
+		}
+		return transport.NewWebsocket(transport.WebsocketOptions{Context: ctx, URL: url, HTTPClient: client, HTTPHeader: cfg.header()})
+	case "ipc":
+		path := strings.TrimPrefix(url, "ipc://")
+		if path == "" || path == url {
+			return nil, fmt.Errorf("invalid IPC URL %q, expected ipc:///path/to/node.ipc", url)
+		}
+		return transport.NewIPC(transport.IPCOptions{Context: ctx, Path: path})
+	default:
+		return transport.New(ctx, url)
+	}
+}
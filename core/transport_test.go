@@ -0,0 +1,209 @@
+package core
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTransportConfigs(t *testing.T) {
+	t.Setenv("TEST_RPC_TOKEN", "secret")
+	path := filepath.Join(t.TempDir(), "rpc.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"primary": {"headers": {"Authorization": "Bearer ${TEST_RPC_TOKEN}"}},
+		"default": {"basicAuth": {"username": "user", "password": "${TEST_RPC_TOKEN}"}}
+	}`), 0o600))
+
+	configs, err := LoadTransportConfigs(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer secret", configs.For("primary").Headers["Authorization"])
+	assert.Equal(t, &BasicAuth{Username: "user", Password: "secret"}, configs.For("fallback-1").BasicAuth)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"primary": {"header": {}}}`), 0o600))
+	_, err = LoadTransportConfigs(path)
+	assert.ErrorContains(t, err, "unknown field")
+}
+
+func TestNewTransport(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Clone()
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer server.Close()
+
+	tr, err := NewTransport(context.Background(), server.URL, TransportConfig{
+		Headers:   map[string]string{"X-Api-Key": "key"},
+		BasicAuth: &BasicAuth{Username: "user", Password: "pass"},
+	})
+	require.NoError(t, err)
+	var n types.Number
+	require.NoError(t, tr.Call(context.Background(), &n, "eth_chainId"))
+
+	assert.Equal(t, "key", header.Get("X-Api-Key"))
+	user, pass, ok := (&http.Request{Header: header}).BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", user)
+	assert.Equal(t, "pass", pass)
+
+	_, err = NewTransport(context.Background(), "ftp://localhost", TransportConfig{})
+	assert.Error(t, err)
+}
+
+func TestNewTransportProxy(t *testing.T) {
+	var host string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.URL.Host
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer proxy.Close()
+
+	tr, err := NewTransport(context.Background(), "http://rpc.invalid", TransportConfig{Proxy: proxy.URL})
+	require.NoError(t, err)
+	var n types.Number
+	require.NoError(t, tr.Call(context.Background(), &n, "eth_chainId"))
+	assert.Equal(t, "rpc.invalid", host)
+
+	_, err = NewTransport(context.Background(), "http://rpc.invalid", TransportConfig{Proxy: "ftp://proxy:21"})
+	assert.ErrorContains(t, err, "unsupported proxy scheme")
+}
+
+func TestNewTransportRequestHeaders(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	defer server.Close()
+
+	tr, err := NewTransport(context.Background(), server.URL, TransportConfig{})
+	require.NoError(t, err)
+	var n types.Number
+	require.NoError(t, tr.Call(WithRequestID(context.Background(), "abc"), &n, "eth_chainId"))
+	assert.Equal(t, DefaultUserAgent(), header.Get("User-Agent"))
+	assert.Equal(t, "abc", header.Get(RequestIDHeader))
+
+	require.NoError(t, tr.Call(context.Background(), &n, "eth_chainId"))
+	assert.Empty(t, header.Get(RequestIDHeader))
+
+	defer func(ua string) { UserAgent = ua }(UserAgent)
+	UserAgent = "monitoring/1.0"
+	require.NoError(t, tr.Call(context.Background(), &n, "eth_chainId"))
+	assert.Equal(t, "monitoring/1.0", header.Get("User-Agent"))
+
+	// User-Agent of the endpoint configuration takes precedence.
+	tr, err = NewTransport(context.Background(), server.URL, TransportConfig{Headers: map[string]string{"User-Agent": "gateway"}})
+	require.NoError(t, err)
+	require.NoError(t, tr.Call(context.Background(), &n, "eth_chainId"))
+	assert.Equal(t, "gateway", header.Get("User-Agent"))
+}
+
+func TestNewTransportTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCert := writeTestCertificate(t, dir)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x1"}`))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0o600))
+
+	var n types.Number
+	tr, err := NewTransport(context.Background(), server.URL, TransportConfig{TLS: &TLSConfig{
+		CertFile: filepath.Join(dir, "cert.pem"),
+		KeyFile:  filepath.Join(dir, "key.pem"),
+		CAFile:   caFile,
+	}})
+	require.NoError(t, err)
+	require.NoError(t, tr.Call(context.Background(), &n, "eth_chainId"))
+
+	// Without the client certificate, the server refuses the handshake.
+	tr, err = NewTransport(context.Background(), server.URL, TransportConfig{TLS: &TLSConfig{CAFile: caFile}})
+	require.NoError(t, err)
+	assert.Error(t, tr.Call(context.Background(), &n, "eth_chainId"))
+
+	_, err = NewTransport(context.Background(), server.URL, TransportConfig{TLS: &TLSConfig{CertFile: filepath.Join(dir, "cert.pem")}})
+	assert.ErrorContains(t, err, "keyFile")
+}
+
+func TestNewTransportIPC(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "node.ipc")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var req struct {
+			ID json.RawMessage `json:"id"`
+		}
+		dec := json.NewDecoder(conn)
+		for dec.Decode(&req) == nil {
+			_, _ = fmt.Fprintf(conn, `{"jsonrpc":"2.0","id":%s,"result":"0x1"}`, req.ID)
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tr, err := NewTransport(ctx, "ipc://"+path, TransportConfig{})
+	require.NoError(t, err)
+	var n types.Number
+	require.NoError(t, tr.Call(ctx, &n, "eth_chainId"))
+	assert.Equal(t, uint64(1), n.Big().Uint64())
+
+	_, err = NewTransport(ctx, "ipc://", TransportConfig{})
+	assert.ErrorContains(t, err, "invalid IPC URL")
+}
+
+// writeTestCertificate writes a self-signed client certificate and its key to cert.pem and key.pem in dir.
+func writeTestCertificate(t *testing.T, dir string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "challenger"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "cert.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
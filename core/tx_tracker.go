@@ -0,0 +1,190 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/defiweb/go-eth/types"
+	logger "github.com/sirupsen/logrus"
+)
+
+// txOutcomesBuffer is the number of outcomes buffered before the tracker blocks on delivery.
+const txOutcomesBuffer = 16
+
+// TrackedTx is a broadcast challenge transaction watched by TxTracker.
+type TrackedTx struct {
+	Address types.Address
+	Poke    *OpPokedEvent
+	Hash    *types.Hash
+	Tx      *types.Transaction
+	// Client is used to fetch the transaction receipt.
+	Client RPCClient
+	// Deadline is the block by which the transaction has to be mined, otherwise Fallback is used. Optional.
+	Deadline *big.Int
+	// Fallback resubmits the challenge if the transaction was not included in time,
+	// e.g. through the public mempool when flashbots didn't include it. Optional.
+	Fallback func(ctx context.Context, tx *TrackedTx) (*TrackedTx, error)
+	// Replaces is the transaction resubmitted by Fallback with the same nonce, it still may be mined instead.
+	Replaces *TrackedTx
+}
+
+// TxOutcome is emitted by TxTracker once the tracked transaction is confirmed or failed.
+type TxOutcome struct {
+	Address types.Address
+	Poke    *OpPokedEvent
+	Hash    *types.Hash
+	Receipt *types.TransactionReceipt
+	// Err is nil if the transaction was confirmed successfully.
+	Err error
+}
+
+// TxTracker watches broadcast transactions in the background, so callers don't have to wait
+// for confirmations. Outcomes are emitted on Outcomes channel, which has to be consumed.
+type TxTracker struct {
+	onRevert func(ctx context.Context, tx *TrackedTx, receipt *types.TransactionReceipt) error
+	outcomes chan TxOutcome
+	// store persists tracked transactions until their outcome is known, optional.
+	store PendingStore
+}
+
+// NewTxTracker creates a new instance of TxTracker.
+// onRevert is called for reverted transactions and its result is used as outcome error, it's optional.
+func NewTxTracker(onRevert func(ctx context.Context, tx *TrackedTx, receipt *types.TransactionReceipt) error) *TxTracker {
+	return &TxTracker{
+		onRevert: onRevert,
+		outcomes: make(chan TxOutcome, txOutcomesBuffer),
+	}
+}
+
+// Track starts watching the transaction until it's confirmed, reverted or TxConfirmationTimeout passes.
+func (t *TxTracker) Track(ctx context.Context, tx *TrackedTx) {
+	go t.watch(ctx, tx)
+}
+
+// Outcomes returns channel of tracked transaction outcomes.
+func (t *TxTracker) Outcomes() <-chan TxOutcome {
+	return t.outcomes
+}
+
+func (t *TxTracker) watch(ctx context.Context, tx *TrackedTx) {
+	t.persist(ctx, tx)
+	tracked := tx
+	receipt, err := WaitForTxInclusion(ctx, tx.Client, tx.Hash, tx.Deadline, TxConfirmationTimeout)
+	if ctx.Err() != nil {
+		// Shutting down, the transaction stays persisted to be resumed after restart.
+		return
+	}
+	if err != nil && !errors.Is(err, ErrTxReverted) {
+		// Resubmitted transaction was dropped, it happens if one of the replaced ones was mined first.
+		for replaced := tx.Replaces; replaced != nil; replaced = replaced.Replaces {
+			if included := includedReceipt(ctx, replaced); included != nil {
+				tx, receipt, err = replaced, included, nil
+				if *included.Status == 0 {
+					err = fmt.Errorf("%w: %v", ErrTxReverted, tx.Hash)
+				}
+				break
+			}
+		}
+	}
+
+	switch {
+	case errors.Is(err, ErrTxReverted):
+		if t.onRevert != nil {
+			err = t.onRevert(ctx, tx, receipt)
+		}
+	case err != nil && tx.Fallback != nil:
+		logger.
+			WithField("address", tx.Address).
+			WithField("txHash", tx.Hash).
+			Warnf("challenge transaction was not included, resubmitting: %v", err)
+		next, fallbackErr := tx.Fallback(ctx, tx)
+		if fallbackErr == nil {
+			// The entry of the resubmitted transaction keeps the replaced ones, so they are watched after restart.
+			t.persist(ctx, next)
+			t.forget(tx)
+			t.watch(ctx, next)
+			return
+		}
+		if included := includedReceipt(ctx, tx); included != nil {
+			// Fallback failed because the original transaction was mined meanwhile, e.g. nonce too low.
+			t.watch(ctx, &TrackedTx{Address: tx.Address, Poke: tx.Poke, Hash: tx.Hash, Tx: tx.Tx, Client: tx.Client})
+			return
+		}
+		err = fmt.Errorf("%v, fallback failed: %w", err, fallbackErr)
+	case err == nil:
+		logger.
+			WithField("address", tx.Address).
+			WithField("txHash", tx.Hash).
+			Infof("challenge transaction confirmed in block %v", receipt.BlockNumber)
+	}
+
+	outcome := TxOutcome{
+		Address: tx.Address,
+		Poke:    tx.Poke,
+		Hash:    tx.Hash,
+		Receipt: receipt,
+		Err:     err,
+	}
+	select {
+	case t.outcomes <- outcome:
+		t.forget(tracked)
+	case <-ctx.Done():
+	}
+}
+
+// persist saves the tracked transaction, so it can be resumed after restart. It's sent at the time of the clock
+// of the context.
+func (t *TxTracker) persist(ctx context.Context, tx *TrackedTx) {
+	if t.store == nil {
+		return
+	}
+	pending := PendingChallenge{
+		Address: tx.Address,
+		Poke:    tx.Poke,
+		Hash:    *tx.Hash,
+		SentAt:  clockFromContext(ctx).Now(),
+	}
+	for replaced := tx.Replaces; replaced != nil; replaced = replaced.Replaces {
+		pending.Replaces = append(pending.Replaces, *replaced.Hash)
+	}
+	if tx.Tx != nil {
+		pending.From = tx.Tx.From
+		pending.To = tx.Tx.To
+		pending.Input = tx.Tx.Input
+		pending.GasLimit = tx.Tx.GasLimit
+		pending.Nonce = tx.Tx.Nonce
+		pending.GasPrice = tx.Tx.GasPrice
+		pending.MaxFeePerGas = tx.Tx.MaxFeePerGas
+		pending.MaxPriorityFeePerGas = tx.Tx.MaxPriorityFeePerGas
+	}
+	if err := t.store.Save(pending); err != nil {
+		logger.
+			WithField("address", tx.Address).
+			WithField("txHash", tx.Hash).
+			Errorf("Failed to persist pending challenge with error: %v", err)
+	}
+}
+
+// forget removes the transaction which outcome is known from the store.
+func (t *TxTracker) forget(tx *TrackedTx) {
+	if t.store == nil {
+		return
+	}
+	if err := t.store.Remove(*tx.Hash); err != nil {
+		logger.
+			WithField("address", tx.Address).
+			WithField("txHash", tx.Hash).
+			Errorf("Failed to remove pending challenge with error: %v", err)
+	}
+}
+
+// includedReceipt returns the receipt of the transaction if it's already mined, nil otherwise.
+func includedReceipt(ctx context.Context, tx *TrackedTx) *types.TransactionReceipt {
+	receipt, err := tx.Client.GetTransactionReceipt(ctx, *tx.Hash)
+	if err != nil || receipt == nil || receipt.Status == nil || receipt.TransactionHash.IsZero() {
+		return nil
+	}
+	return receipt
+}
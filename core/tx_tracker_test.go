@@ -0,0 +1,77 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxTracker(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	poke := &OpPokedEvent{}
+
+	next := func(t *testing.T, tracker *TxTracker) TxOutcome {
+		select {
+		case outcome := <-tracker.Outcomes():
+			return outcome
+		case <-time.After(time.Second):
+			t.Fatal("no outcome")
+			return TxOutcome{}
+		}
+	}
+
+	t.Run("reverted transaction uses onRevert error", func(t *testing.T) {
+		status := uint64(0)
+		client := new(mockRpcClient)
+		client.On("GetTransactionReceipt", mock.Anything, txHash).
+			Return(&types.TransactionReceipt{TransactionHash: txHash, Status: &status}, nil)
+
+		var reverted *TrackedTx
+		tracker := NewTxTracker(func(_ context.Context, tx *TrackedTx, _ *types.TransactionReceipt) error {
+			reverted = tx
+			return fmt.Errorf("classified: %w", ErrTxReverted)
+		})
+		tracked := &TrackedTx{Address: address, Poke: poke, Hash: &txHash, Client: client}
+		tracker.Track(context.Background(), tracked)
+
+		outcome := next(t, tracker)
+		assert.ErrorIs(t, outcome.Err, ErrTxReverted)
+		assert.ErrorContains(t, outcome.Err, "classified")
+		assert.Equal(t, tracked, reverted)
+		assert.NotNil(t, outcome.Receipt)
+	})
+
+	t.Run("failed fallback is reported", func(t *testing.T) {
+		defer func(timeout, interval time.Duration) {
+			TxConfirmationTimeout, TxConfirmationPollInterval = timeout, interval
+		}(TxConfirmationTimeout, TxConfirmationPollInterval)
+		TxConfirmationTimeout, TxConfirmationPollInterval = 50*time.Millisecond, 10*time.Millisecond
+
+		client := new(mockRpcClient)
+		client.On("GetTransactionReceipt", mock.Anything, txHash).
+			Return((*types.TransactionReceipt)(nil), nil)
+
+		tracker := NewTxTracker(nil)
+		tracker.Track(context.Background(), &TrackedTx{
+			Address: address,
+			Poke:    poke,
+			Hash:    &txHash,
+			Client:  client,
+			Fallback: func(context.Context, *TrackedTx) (*TrackedTx, error) {
+				return nil, fmt.Errorf("mainnet down")
+			},
+		})
+
+		outcome := next(t, tracker)
+		require.Error(t, outcome.Err)
+		assert.ErrorContains(t, outcome.Err, "mainnet down")
+		assert.Equal(t, poke, outcome.Poke)
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"math/big"
 
+	"github.com/defiweb/go-eth/abi"
 	"github.com/defiweb/go-eth/types"
 )
 
@@ -39,6 +40,20 @@ type SortableEvent interface {
 	Name() string
 	// GetBlockNumber returns the block number of the event.
 	GetBlockNumber() *big.Int
+	// GetLogIndex returns the index of the event log in the block, nil if unknown.
+	GetLogIndex() *uint64
+}
+
+// ContractStateEventNames are events changing which defense the challenger provides, operators are alerted on them.
+var ContractStateEventNames = []string{
+	"OpPokeDataDropped",
+	"OpChallengePeriodUpdated",
+	"AuthGranted",
+	"AuthRenounced",
+	"FeedLifted",
+	"FeedDropped",
+	"BarUpdated",
+	"MaxChallengeRewardUpdated",
 }
 
 // IScribeOptimisticProvider is the interface for the ScribeOptimistic contract with required functions for challenger.
@@ -52,22 +67,55 @@ type IScribeOptimisticProvider interface {
 	// GetChallengePeriod returns the challenge period of the contract.
 	GetChallengePeriod(ctx context.Context, address types.Address) (uint16, error)
 
+	// GetChallengeReward returns the reward paid for a successful challenge, capped by the balance of the contract.
+	GetChallengeReward(ctx context.Context, address types.Address) (*big.Int, error)
+
+	// GetMaxChallengeReward returns the maximum challenge reward configured in the contract.
+	GetMaxChallengeReward(ctx context.Context, address types.Address) (*big.Int, error)
+
 	// GetPokes returns the `OpPoked` events within the given block range.
 	GetPokes(ctx context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*OpPokedEvent, error)
 
 	// GetSuccessfulChallenges returns the `OpPokeChallengedSuccessfully` events within the given block range.
 	GetSuccessfulChallenges(ctx context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*OpPokeChallengedSuccessfullyEvent, error)
 
+	// GetRegularPokes returns the `Poked` events of regular (non-optimistic) pokes within the given block range.
+	GetRegularPokes(ctx context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*PokedEvent, error)
+
+	// GetContractStateEvents returns the events of ContractStateEventNames within the given block range.
+	GetContractStateEvents(ctx context.Context, address types.Address, fromBlock *big.Int, toBlock *big.Int) ([]*ContractStateEvent, error)
+
 	// IsPokeSignatureValid returns true if the given poke signature is valid.
 	IsPokeSignatureValid(ctx context.Context, address types.Address, poke *OpPokedEvent) (bool, error)
 
-	// ChallengePoke challenges the given poke.
+	// ChallengePoke challenges the given poke, it returns once the transaction is sent.
 	ChallengePoke(ctx context.Context, address types.Address, poke *OpPokedEvent) (*types.Hash, *types.Transaction, error)
 
+	// ChallengeOutcomes returns channel with results of the challenge transactions sent by ChallengePoke.
+	ChallengeOutcomes() <-chan TxOutcome
+
 	// GetFrom returns the address of the challenger account.
 	GetFrom(ctx context.Context) types.Address
 }
 
+// decodeEvent decodes values of the event from the log. Logs come from untrusted RPC providers, so malformed
+// topics or data are reported as errors: data shorter than one word per non-indexed argument is rejected,
+// otherwise missing data would be decoded as zero values, and panics of the decoder are recovered.
+func decodeEvent(event *abi.Event, log types.Log, vals ...any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed %s event: %v", event.Name(), r)
+		}
+	}()
+	if log.BlockNumber == nil {
+		return fmt.Errorf("%s event has no block number", event.Name())
+	}
+	if minSize := event.Inputs().DataSize() * abi.WordLength; len(log.Data) < minSize {
+		return fmt.Errorf("%s event data has %d bytes, expected at least %d", event.Name(), len(log.Data), minSize)
+	}
+	return event.DecodeValues(log.Topics, log.Data, vals...)
+}
+
 // DecodeOpPokeEvent Decodes the OpPoked event from the given log.
 func DecodeOpPokeEvent(log types.Log) (*OpPokedEvent, error) {
 	var schnorrData SchnorrData
@@ -76,9 +124,9 @@ func DecodeOpPokeEvent(log types.Log) (*OpPokedEvent, error) {
 
 	event := ScribeOptimisticContractABI.Events["OpPoked"]
 	// OpPoked(address,address,(bytes32,address,bytes),(uint128,uint32))
-	err := event.DecodeValues(log.Topics, log.Data, &caller, &opFeed, &schnorrData, &pokeData)
+	err := decodeEvent(event, log, &caller, &opFeed, &schnorrData, &pokeData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode event data with error: %v\n", err)
+		return nil, fmt.Errorf("failed to decode event data with error: %w: %w", ErrDecode, err)
 	}
 	return &OpPokedEvent{
 		BlockNumber: log.BlockNumber,
@@ -86,6 +134,9 @@ func DecodeOpPokeEvent(log types.Log) (*OpPokedEvent, error) {
 		OpFeed:      opFeed,
 		Schnorr:     schnorrData,
 		PokeData:    pokeData,
+		TxHash:      log.TransactionHash,
+		LogIndex:    log.LogIndex,
+		Raw:         &log,
 	}, nil
 }
 
@@ -96,12 +147,64 @@ func DecodeOpPokeChallengedSuccessfullyEvent(log types.Log) (*OpPokeChallengedSu
 
 	event := ScribeOptimisticContractABI.Events["OpPokeChallengedSuccessfully"]
 
-	err := event.DecodeValues(log.Topics, log.Data, &challenger, &b)
+	err := decodeEvent(event, log, &challenger, &b)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode event data with error: %v\n", err)
+		return nil, fmt.Errorf("failed to decode event data with error: %w: %w", ErrDecode, err)
 	}
 	return &OpPokeChallengedSuccessfullyEvent{
 		BlockNumber: log.BlockNumber,
 		Challenger:  challenger,
+		TxHash:      log.TransactionHash,
+		LogIndex:    log.LogIndex,
+		Raw:         &log,
 	}, nil
 }
+
+// DecodePokedEvent Decodes the Poked event from the given log.
+func DecodePokedEvent(log types.Log) (*PokedEvent, error) {
+	var caller types.Address
+	var val *big.Int
+	var age uint32
+
+	event := ScribeOptimisticContractABI.Events["Poked"]
+	// Poked(address,uint128,uint32)
+	err := decodeEvent(event, log, &caller, &val, &age)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode event data with error: %w: %w", ErrDecode, err)
+	}
+	return &PokedEvent{
+		BlockNumber: log.BlockNumber,
+		Caller:      caller,
+		Val:         val,
+		Age:         age,
+		TxHash:      log.TransactionHash,
+		LogIndex:    log.LogIndex,
+		Raw:         &log,
+	}, nil
+}
+
+// DecodeContractStateEvent Decodes one of ContractStateEventNames events from the given log.
+func DecodeContractStateEvent(log types.Log) (*ContractStateEvent, error) {
+	if len(log.Topics) == 0 {
+		return nil, fmt.Errorf("%w: log has no topics", ErrDecode)
+	}
+	for _, name := range ContractStateEventNames {
+		event := ScribeOptimisticContractABI.Events[name]
+		if event.Topic0() != log.Topics[0] {
+			continue
+		}
+		values := make(map[string]any)
+		if err := event.DecodeValue(log.Topics, log.Data, &values); err != nil {
+			return nil, fmt.Errorf("failed to decode %s event data with error: %w: %w", name, ErrDecode, err)
+		}
+		return &ContractStateEvent{
+			BlockNumber: log.BlockNumber,
+			Event:       name,
+			Values:      values,
+			TxHash:      log.TransactionHash,
+			LogIndex:    log.LogIndex,
+			Raw:         &log,
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown event %v", ErrDecode, log.Topics[0])
+}
@@ -19,19 +19,24 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/defiweb/go-eth/abi"
 	"github.com/defiweb/go-eth/types"
 	"github.com/stretchr/testify/require"
 )
 
 func TestDecodeOpPokeChallengedSuccessfullyEvent(t *testing.T) {
 	blockNumber := big.NewInt(123)
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	logIndex := uint64(3)
 	log := types.Log{
 		Topics: []types.Hash{
 			types.MustHashFromHex("0xac50cef58b3aef7f7c30349f5e4a342a29d2325a02eafc8dacfdba391e6d5db3", types.PadNone),
 			types.MustHashFromHex("0x0000000000000000000000001f7acda376ef37ec371235a094113df9cb4efee1", types.PadNone),
 		},
-		Data:        types.MustBytesFromHex("0x00000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000004bd2a556b00000000000000000000000000000000000000000000000000000000"),
-		BlockNumber: blockNumber,
+		Data:            types.MustBytesFromHex("0x00000000000000000000000000000000000000000000000000000000000000200000000000000000000000000000000000000000000000000000000000000004bd2a556b00000000000000000000000000000000000000000000000000000000"),
+		BlockNumber:     blockNumber,
+		TransactionHash: &txHash,
+		LogIndex:        &logIndex,
 	}
 
 	event, err := DecodeOpPokeChallengedSuccessfullyEvent(log)
@@ -39,17 +44,24 @@ func TestDecodeOpPokeChallengedSuccessfullyEvent(t *testing.T) {
 	require.NotNil(t, event)
 	require.Equal(t, blockNumber, event.BlockNumber)
 	require.Equal(t, types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1"), event.Challenger)
+	require.Equal(t, &txHash, event.TxHash)
+	require.Equal(t, &logIndex, event.LogIndex)
 }
 
 func TestDecodeOpPokeEvent(t *testing.T) {
 	blockNumber := big.NewInt(123)
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	logIndex := uint64(2)
 	log := types.Log{
-		BlockNumber: blockNumber,
+		BlockNumber:     blockNumber,
+		TransactionHash: &txHash,
+		LogIndex:        &logIndex,
 		Topics: []types.Hash{
 			types.MustHashFromHex("0xb9dc937c5e394d0c8f76e0e324500b88251b4c909ddc56232df10e2ea42b3c63", types.PadNone),
 			types.MustHashFromHex("0x0000000000000000000000001f7acda376ef37ec371235a094113df9cb4efee1", types.PadNone),
 			types.MustHashFromHex("0x0000000000000000000000006813eb9362372eef6200f3b1dbc3f819671cba69", types.PadNone),
 		},
+		Data: testOpPokedData(),
 	}
 
 	event, err := DecodeOpPokeEvent(log)
@@ -58,4 +70,122 @@ func TestDecodeOpPokeEvent(t *testing.T) {
 	require.Equal(t, blockNumber, event.BlockNumber)
 	require.Equal(t, types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1"), event.Caller)
 	require.Equal(t, types.MustAddressFromHex("0x6813eb9362372eef6200f3b1dbc3f819671cba69"), event.OpFeed)
+	require.Equal(t, &txHash, event.TxHash)
+	require.Equal(t, &logIndex, event.LogIndex)
+	require.Equal(t, PokeData{Val: big.NewInt(1000), Age: 123}, event.PokeData)
+
+	// Logs without data are malformed, missing values are not decoded as zeros.
+	log.Data = nil
+	_, err = DecodeOpPokeEvent(log)
+	require.ErrorIs(t, err, ErrDecode)
+	log.Data = testOpPokedData()
+	log.BlockNumber = nil
+	_, err = DecodeOpPokeEvent(log)
+	require.ErrorIs(t, err, ErrDecode)
+}
+
+func TestDecodePokedEvent(t *testing.T) {
+	blockNumber := big.NewInt(123)
+	log := types.Log{
+		Topics: []types.Hash{
+			ScribeOptimisticContractABI.Events["Poked"].Topic0(),
+			types.MustHashFromHex("0x0000000000000000000000001f7acda376ef37ec371235a094113df9cb4efee1", types.PadNone),
+		},
+		Data:        types.MustBytesFromHex("0x00000000000000000000000000000000000000000000000000000000000003e8000000000000000000000000000000000000000000000000000000000000002a"),
+		BlockNumber: blockNumber,
+	}
+
+	event, err := DecodePokedEvent(log)
+	require.NoError(t, err)
+	require.Equal(t, blockNumber, event.BlockNumber)
+	require.Equal(t, types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1"), event.Caller)
+	require.Equal(t, big.NewInt(1000), event.Val)
+	require.Equal(t, uint32(42), event.Age)
+
+	_, err = DecodePokedEvent(types.Log{Topics: log.Topics, Data: []byte{0x01}})
+	require.ErrorIs(t, err, ErrDecode)
+}
+
+func TestDecodeContractStateEvent(t *testing.T) {
+	event := ScribeOptimisticContractABI.Events["FeedDropped"]
+	log := types.Log{
+		Topics: []types.Hash{
+			event.Topic0(),
+			types.MustHashFromHex("0x0000000000000000000000001f7acda376ef37ec371235a094113df9cb4efee1", types.PadNone),
+			types.MustHashFromHex("0x0000000000000000000000002f7acda376ef37ec371235a094113df9cb4efee2", types.PadNone),
+			types.MustHashFromHex("0x0000000000000000000000000000000000000000000000000000000000000005", types.PadNone),
+		},
+		BlockNumber: big.NewInt(123),
+	}
+
+	decoded, err := DecodeContractStateEvent(log)
+	require.NoError(t, err)
+	require.Equal(t, "FeedDropped", decoded.Name())
+	require.Equal(t, big.NewInt(123), decoded.BlockNumber)
+	require.Equal(t, types.MustAddressFromHex("0x2F7acDa376eF37EC371235a094113dF9Cb4EfEe2"), decoded.Values["feed"])
+
+	_, err = DecodeContractStateEvent(types.Log{Topics: []types.Hash{ScribeOptimisticContractABI.Events["OpPoked"].Topic0()}})
+	require.ErrorContains(t, err, "unknown event")
+	require.ErrorIs(t, err, ErrDecode)
+}
+
+// fuzzLog builds the log of the event with n topics, topic0 of another event if wrongTopic0 is set.
+func fuzzLog(event *abi.Event, n uint8, wrongTopic0 bool, data []byte) types.Log {
+	topics := make([]types.Hash, n%5)
+	for i := range topics {
+		topics[i] = types.MustHashFromHex("0x0000000000000000000000001f7acda376ef37ec371235a094113df9cb4efee1", types.PadNone)
+	}
+	if len(topics) > 0 && !wrongTopic0 {
+		topics[0] = event.Topic0()
+	}
+	return types.Log{Topics: topics, Data: data, BlockNumber: big.NewInt(123)}
+}
+
+// testOpPokedData returns data of the OpPoked event log, the real event always carries it.
+func testOpPokedData() []byte {
+	return abi.MustEncodeValues(ScribeOptimisticContractABI.Events["OpPoked"].Inputs().DataTuple(),
+		SchnorrData{Signature: [32]byte{1}, Commitment: types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1"), SignersBlob: []byte{2, 3}},
+		PokeData{Val: big.NewInt(1000), Age: 123},
+	)
+}
+
+func FuzzDecodeOpPokeEvent(f *testing.F) {
+	event := ScribeOptimisticContractABI.Events["OpPoked"]
+	data := testOpPokedData()
+	f.Add(uint8(3), false, data)
+	f.Add(uint8(3), false, data[:len(data)-1])
+	f.Add(uint8(3), false, data[:64])
+	f.Add(uint8(3), false, []byte{})
+	f.Add(uint8(2), false, data)
+	f.Add(uint8(3), true, data)
+
+	f.Fuzz(func(t *testing.T, n uint8, wrongTopic0 bool, data []byte) {
+		poke, err := DecodeOpPokeEvent(fuzzLog(event, n, wrongTopic0, data))
+		if err != nil {
+			require.Nil(t, poke)
+			return
+		}
+		require.NotNil(t, poke.PokeData.Val)
+		require.NotNil(t, poke.BlockNumber)
+	})
+}
+
+func FuzzDecodeOpPokeChallengedSuccessfullyEvent(f *testing.F) {
+	event := ScribeOptimisticContractABI.Events["OpPokeChallengedSuccessfully"]
+	data, err := abi.EncodeValues(event.Inputs().DataTuple(), []byte{0xbd, 0x2a, 0x55, 0x6b})
+	require.NoError(f, err)
+	f.Add(uint8(2), false, data)
+	f.Add(uint8(2), false, data[:40])
+	f.Add(uint8(2), false, []byte{})
+	f.Add(uint8(1), false, data)
+	f.Add(uint8(2), true, data)
+
+	f.Fuzz(func(t *testing.T, n uint8, wrongTopic0 bool, data []byte) {
+		challenge, err := DecodeOpPokeChallengedSuccessfullyEvent(fuzzLog(event, n, wrongTopic0, data))
+		if err != nil {
+			require.Nil(t, challenge)
+			return
+		}
+		require.NotNil(t, challenge.BlockNumber)
+	})
 }
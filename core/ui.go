@@ -0,0 +1,25 @@
+package core
+
+import (
+	_ "embed"
+	"net/http"
+
+	logger "github.com/sirupsen/logrus"
+)
+
+//go:embed ui/index.html
+var uiHTML []byte
+
+// UI is the single-page web UI showing monitored addresses, latest pokes, challenge history and wallet balance
+// from Stats, for deployments without Grafana. It must be served next to /stats, and /events if the page is opened
+// with the token query parameter of the event stream, so it's refreshed live.
+type UI struct{}
+
+// ServeHTTP serves the page.
+func (UI) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	if _, err := w.Write(uiHTML); err != nil {
+		logger.WithError(err).Error("failed to write UI")
+	}
+}
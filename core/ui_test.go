@@ -0,0 +1,19 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUI(t *testing.T) {
+	rec := httptest.NewRecorder()
+	UI{}.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ui", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	// Data is loaded relative to the page, so it works behind a path prefix.
+	assert.Contains(t, rec.Body.String(), `fetch("stats"`)
+	assert.Contains(t, rec.Body.String(), `new EventSource("events?token="`)
+}
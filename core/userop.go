@@ -0,0 +1,391 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	logger "github.com/sirupsen/logrus"
+)
+
+// DefaultEntryPoint is the address of ERC-4337 EntryPoint v0.6 contract, the same on all chains.
+var DefaultEntryPoint = types.MustAddressFromHex("0x5FF137D4b0FDCD49DcA30c7CF57E578a026d2789")
+
+var (
+	entryPointGetNonce = abi.MustParseMethod("getNonce(address sender, uint192 key) returns (uint256 nonce)")
+	// smartAccountExecute is the execute method of SimpleAccount and compatible smart accounts.
+	smartAccountExecute = abi.MustParseMethod("execute(address dest, uint256 value, bytes func)")
+
+	userOpPackType = abi.MustParseType(
+		"(address,uint256,bytes32,bytes32,uint256,uint256,uint256,uint256,uint256,bytes32)",
+	)
+	userOpHashType = abi.MustParseType("(bytes32,address,uint256)")
+)
+
+// dummyUserOpSignature is a well-formed ECDSA signature used for gas estimation, before the operation is signed.
+var dummyUserOpSignature = types.MustBytesFromHex(
+	"0xfffffffffffffffffffffffffffffff0000000000000000000000000000000007aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa1c",
+)
+
+// UserOperation is ERC-4337 user operation as defined by EntryPoint v0.6.
+type UserOperation struct {
+	Sender               types.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+type jsonUserOperation struct {
+	Sender               types.Address `json:"sender"`
+	Nonce                types.Number  `json:"nonce"`
+	InitCode             types.Bytes   `json:"initCode"`
+	CallData             types.Bytes   `json:"callData"`
+	CallGasLimit         types.Number  `json:"callGasLimit"`
+	VerificationGasLimit types.Number  `json:"verificationGasLimit"`
+	PreVerificationGas   types.Number  `json:"preVerificationGas"`
+	MaxFeePerGas         types.Number  `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas types.Number  `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     types.Bytes   `json:"paymasterAndData"`
+	Signature            types.Bytes   `json:"signature"`
+}
+
+// MarshalJSON implements json.Marshaler interface, the format is expected by bundler RPC methods.
+func (u UserOperation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonUserOperation{
+		Sender:               u.Sender,
+		Nonce:                types.NumberFromBigInt(u.Nonce),
+		InitCode:             u.InitCode,
+		CallData:             u.CallData,
+		CallGasLimit:         types.NumberFromBigInt(u.CallGasLimit),
+		VerificationGasLimit: types.NumberFromBigInt(u.VerificationGasLimit),
+		PreVerificationGas:   types.NumberFromBigInt(u.PreVerificationGas),
+		MaxFeePerGas:         types.NumberFromBigInt(u.MaxFeePerGas),
+		MaxPriorityFeePerGas: types.NumberFromBigInt(u.MaxPriorityFeePerGas),
+		PaymasterAndData:     u.PaymasterAndData,
+		Signature:            u.Signature,
+	})
+}
+
+// Hash returns the hash of the user operation signed by the smart account owner.
+func (u *UserOperation) Hash(entryPoint types.Address, chainID uint64) (types.Hash, error) {
+	packed, err := abi.EncodeValues(
+		userOpPackType,
+		u.Sender,
+		bigOrZero(u.Nonce),
+		crypto.Keccak256(u.InitCode),
+		crypto.Keccak256(u.CallData),
+		bigOrZero(u.CallGasLimit),
+		bigOrZero(u.VerificationGasLimit),
+		bigOrZero(u.PreVerificationGas),
+		bigOrZero(u.MaxFeePerGas),
+		bigOrZero(u.MaxPriorityFeePerGas),
+		crypto.Keccak256(u.PaymasterAndData),
+	)
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("failed to pack user operation: %w", err)
+	}
+	encoded, err := abi.EncodeValues(userOpHashType, crypto.Keccak256(packed), entryPoint, new(big.Int).SetUint64(chainID))
+	if err != nil {
+		return types.Hash{}, fmt.Errorf("failed to encode user operation hash: %w", err)
+	}
+	return crypto.Keccak256(encoded), nil
+}
+
+func bigOrZero(x *big.Int) *big.Int {
+	if x == nil {
+		return new(big.Int)
+	}
+	return x
+}
+
+// userOpGas is the result of gas estimation and paymaster sponsorship of the user operation.
+type userOpGas struct {
+	PreVerificationGas   types.Number `json:"preVerificationGas"`
+	VerificationGasLimit types.Number `json:"verificationGasLimit"`
+	CallGasLimit         types.Number `json:"callGasLimit"`
+	PaymasterAndData     types.Bytes  `json:"paymasterAndData"`
+}
+
+type userOpReceipt struct {
+	UserOpHash types.Hash                `json:"userOpHash"`
+	Success    bool                      `json:"success"`
+	Reason     string                    `json:"reason"`
+	Receipt    *types.TransactionReceipt `json:"receipt"`
+}
+
+// BundlerClient is JSON-RPC client of ERC-4337 bundler or paymaster, go-eth transports implement it.
+type BundlerClient interface {
+	Call(ctx context.Context, result any, method string, args ...any) error
+}
+
+// UserOpSubmitter sends challenges as ERC-4337 user operations of a smart account through a bundler,
+// so the challenger can use funds held in the smart account instead of a separate EOA.
+// The owner key signs user operations, the smart account has to be deployed and implement
+// SimpleAccount compatible `execute(address,uint256,bytes)`. Challenge rewards are paid to the smart account.
+type UserOpSubmitter struct {
+	ctx        context.Context
+	client     RPCClient
+	bundler    BundlerClient
+	owner      wallet.Key
+	sender     types.Address
+	entryPoint types.Address
+	chainID    uint64
+
+	mu       sync.Mutex
+	outcomes map[types.Address]chan TxOutcome
+
+	// Paymaster, if set, is asked to sponsor user operations with `pm_sponsorUserOperation`.
+	Paymaster BundlerClient
+	// PaymasterAndData is static paymasterAndData of user operations, used if Paymaster is not set.
+	PaymasterAndData []byte
+	// PriorityFee overrides the priority fee suggested by the bundler.
+	PriorityFee *big.Int
+	// Tag is appended to `opChallenge` calldata, see NewChallengeTag.
+	Tag []byte
+}
+
+// NewUserOpSubmitter creates a new instance of UserOpSubmitter.
+func NewUserOpSubmitter(
+	ctx context.Context,
+	client RPCClient,
+	bundler BundlerClient,
+	owner wallet.Key,
+	sender types.Address,
+	entryPoint types.Address,
+	chainID uint64,
+) *UserOpSubmitter {
+	return &UserOpSubmitter{
+		ctx:        ctx,
+		client:     client,
+		bundler:    bundler,
+		owner:      owner,
+		sender:     sender,
+		entryPoint: entryPoint,
+		chainID:    chainID,
+		outcomes:   make(map[types.Address]chan TxOutcome),
+	}
+}
+
+// Wrap returns provider which challenges pokes under the address with user operations.
+func (s *UserOpSubmitter) Wrap(address types.Address, provider IScribeOptimisticProvider) IScribeOptimisticProvider {
+	return &userOpProvider{
+		IScribeOptimisticProvider: provider,
+		submitter:                 s,
+		outcomes:                  s.outcomesOf(address),
+	}
+}
+
+func (s *UserOpSubmitter) outcomesOf(address types.Address) chan TxOutcome {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.outcomes[address]; !ok {
+		s.outcomes[address] = make(chan TxOutcome, txOutcomesBuffer)
+	}
+	return s.outcomes[address]
+}
+
+// Submit sends the challenge as user operation and starts watching it, returned hash is the user operation hash.
+func (s *UserOpSubmitter) Submit(
+	ctx context.Context,
+	address types.Address,
+	poke *OpPokedEvent,
+) (*types.Hash, *types.Transaction, error) {
+	op, err := s.buildUserOp(ctx, address, poke)
+	if err != nil {
+		return nil, nil, err
+	}
+	var hash types.Hash
+	if err := s.bundler.Call(ctx, &hash, "eth_sendUserOperation", op, s.entryPoint); err != nil {
+		return nil, nil, fmt.Errorf("failed to send user operation: %w", err)
+	}
+	logger.
+		WithField("address", address).
+		WithField("sender", s.sender).
+		WithField("userOpHash", hash).
+		Infof("Sent challenge as user operation")
+
+	go s.watch(address, poke, hash)
+	return &hash, nil, nil
+}
+
+// buildUserOp creates signed user operation calling `opChallenge` from the smart account.
+func (s *UserOpSubmitter) buildUserOp(ctx context.Context, address types.Address, poke *OpPokedEvent) (*UserOperation, error) {
+	calldata, err := EncodeChallengeCalldata(poke, s.Tag)
+	if err != nil {
+		return nil, err
+	}
+	callData, err := smartAccountExecute.EncodeArgs(address, big.NewInt(0), calldata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode execute args: %w", err)
+	}
+	nonce, err := s.getNonce(ctx)
+	if err != nil {
+		return nil, err
+	}
+	maxFee, priorityFee, err := s.getFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	op := &UserOperation{
+		Sender:               s.sender,
+		Nonce:                nonce,
+		CallData:             callData,
+		MaxFeePerGas:         maxFee,
+		MaxPriorityFeePerGas: priorityFee,
+		PaymasterAndData:     s.PaymasterAndData,
+		Signature:            dummyUserOpSignature,
+	}
+
+	// Sponsoring paymaster estimates gas itself, as its data changes with the limits.
+	var gas userOpGas
+	if s.Paymaster != nil {
+		err = s.Paymaster.Call(ctx, &gas, "pm_sponsorUserOperation", op, s.entryPoint)
+	} else {
+		err = s.bundler.Call(ctx, &gas, "eth_estimateUserOperationGas", op, s.entryPoint)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate user operation gas: %w", err)
+	}
+	op.PreVerificationGas = gas.PreVerificationGas.Big()
+	op.VerificationGasLimit = gas.VerificationGasLimit.Big()
+	op.CallGasLimit = gas.CallGasLimit.Big()
+	if len(gas.PaymasterAndData) > 0 {
+		op.PaymasterAndData = gas.PaymasterAndData
+	}
+
+	hash, err := op.Hash(s.entryPoint, s.chainID)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := s.owner.SignMessage(ctx, hash.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign user operation: %w", err)
+	}
+	op.Signature = signature.Bytes()
+	return op, nil
+}
+
+func (s *UserOpSubmitter) getNonce(ctx context.Context) (*big.Int, error) {
+	calldata, err := entryPointGetNonce.EncodeArgs(s.sender, big.NewInt(0))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode getNonce args: %w", err)
+	}
+	b, _, err := s.client.Call(ctx, &types.Call{To: &s.entryPoint, Input: calldata}, types.LatestBlockNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce of %v from entry point: %w", s.sender, err)
+	}
+	var nonce *big.Int
+	if err := entryPointGetNonce.DecodeValues(b, &nonce); err != nil {
+		return nil, fmt.Errorf("failed to decode getNonce result: %w", err)
+	}
+	return nonce, nil
+}
+
+// getFees returns max fee and priority fee of the user operation, max fee leaves room for two times higher gas price.
+func (s *UserOpSubmitter) getFees(ctx context.Context) (*big.Int, *big.Int, error) {
+	var gasPrice types.Number
+	if err := s.bundler.Call(ctx, &gasPrice, "eth_gasPrice"); err != nil {
+		return nil, nil, fmt.Errorf("failed to get gas price: %w", err)
+	}
+	priorityFee := s.PriorityFee
+	if priorityFee == nil {
+		var tip types.Number
+		if err := s.bundler.Call(ctx, &tip, "eth_maxPriorityFeePerGas"); err != nil {
+			return nil, nil, fmt.Errorf("failed to get priority fee: %w", err)
+		}
+		priorityFee = tip.Big()
+	}
+	maxFee := new(big.Int).Mul(gasPrice.Big(), big.NewInt(2))
+	return maxFee.Add(maxFee, priorityFee), priorityFee, nil
+}
+
+// watch polls the bundler for the user operation receipt and reports the outcome.
+func (s *UserOpSubmitter) watch(address types.Address, poke *OpPokedEvent, hash types.Hash) {
+	outcome := TxOutcome{Address: address, Poke: poke, Hash: &hash}
+	receipt, err := s.waitForReceipt(hash)
+	switch {
+	case err != nil:
+		outcome.Err = err
+	case !receipt.Success || receipt.Receipt == nil:
+		outcome.Err = fmt.Errorf("%w: user operation %v failed: %s", ErrChallengeReverted, hash, receipt.Reason)
+	case !hasChallengedEvent(receipt.Receipt, address):
+		outcome.Err = fmt.Errorf("%w: challenge of %v failed within user operation %v", ErrChallengeReverted, address, hash)
+	}
+	if receipt != nil && receipt.Receipt != nil {
+		outcome.Hash = &receipt.Receipt.TransactionHash
+		outcome.Receipt = receipt.Receipt
+	}
+	if errors.Is(outcome.Err, ErrTxReverted) {
+		ChallengeRevertsCounter.WithLabelValues(address.String(), RevertReasonUnknown).Inc()
+	}
+	select {
+	case s.outcomesOf(address) <- outcome:
+	case <-s.ctx.Done():
+	}
+}
+
+func (s *UserOpSubmitter) waitForReceipt(hash types.Hash) (*userOpReceipt, error) {
+	ctx, cancel := context.WithTimeout(s.ctx, TxConfirmationTimeout)
+	defer cancel()
+	ticker := time.NewTicker(TxConfirmationPollInterval)
+	defer ticker.Stop()
+	for {
+		var receipt *userOpReceipt
+		err := s.bundler.Call(ctx, &receipt, "eth_getUserOperationReceipt", hash)
+		if err != nil {
+			logger.
+				WithField("userOpHash", hash).
+				Debugf("Failed to get user operation receipt: %v", err)
+		}
+		if receipt != nil {
+			return receipt, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("user operation %v not included: %w", hash, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// userOpProvider challenges pokes through UserOpSubmitter, other calls go to the wrapped provider.
+type userOpProvider struct {
+	IScribeOptimisticProvider
+	submitter *UserOpSubmitter
+	outcomes  chan TxOutcome
+}
+
+// ChallengePoke implements IScribeOptimisticProvider interface.
+func (p *userOpProvider) ChallengePoke(
+	ctx context.Context,
+	address types.Address,
+	poke *OpPokedEvent,
+) (*types.Hash, *types.Transaction, error) {
+	return p.submitter.Submit(ctx, address, poke)
+}
+
+// ChallengeOutcomes implements IScribeOptimisticProvider interface.
+func (p *userOpProvider) ChallengeOutcomes() <-chan TxOutcome {
+	return p.outcomes
+}
+
+// GetFrom implements IScribeOptimisticProvider interface, challenges are sent from the smart account.
+func (p *userOpProvider) GetFrom(context.Context) types.Address {
+	return p.submitter.sender
+}
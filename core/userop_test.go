@@ -0,0 +1,171 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/defiweb/go-eth/abi"
+	"github.com/defiweb/go-eth/types"
+	"github.com/defiweb/go-eth/wallet"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBundler answers JSON-RPC calls with responses round-tripped through JSON, as a real transport would.
+type fakeBundler struct {
+	mu        sync.Mutex
+	responses map[string]func(args ...any) any
+	calls     map[string][]json.RawMessage
+}
+
+func (b *fakeBundler) Call(_ context.Context, result any, method string, args ...any) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	params, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	if b.calls == nil {
+		b.calls = make(map[string][]json.RawMessage)
+	}
+	b.calls[method] = append(b.calls[method], params)
+	res, err := json.Marshal(b.responses[method](args...))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(res, result)
+}
+
+func TestUserOpSubmitter(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	sender := types.MustAddressFromHex("0x3F7acDa376eF37EC371235a094113dF9Cb4EfEe3")
+	owner := wallet.NewKeyFromBytes(types.MustBytesFromHex("0x0101010101010101010101010101010101010101010101010101010101010101"))
+	userOpHash := types.MustHashFromHex("0xbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", types.PadNone)
+	txHash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+	challenged := ScribeOptimisticContractABI.Events["OpPokeChallengedSuccessfully"].Topic0()
+	status := uint64(1)
+
+	pollInterval := TxConfirmationPollInterval
+	TxConfirmationPollInterval = 10 * time.Millisecond
+	defer func() { TxConfirmationPollInterval = pollInterval }()
+
+	client := new(mockRpcClient)
+	nonce, err := abi.EncodeValues(entryPointGetNonce.Outputs(), big.NewInt(7))
+	require.NoError(t, err)
+	client.On("Call", mock.Anything, mock.MatchedBy(func(call *types.Call) bool {
+		return *call.To == DefaultEntryPoint
+	}), types.LatestBlockNumber).Return(nonce, nil, nil)
+
+	var sent UserOperation
+	polls := 0
+	bundler := &fakeBundler{responses: map[string]func(args ...any) any{
+		"eth_gasPrice":             func(...any) any { return types.NumberFromUint64(10) },
+		"eth_maxPriorityFeePerGas": func(...any) any { return types.NumberFromUint64(1) },
+		"eth_estimateUserOperationGas": func(...any) any {
+			return userOpGas{
+				PreVerificationGas:   types.NumberFromUint64(50_000),
+				VerificationGasLimit: types.NumberFromUint64(100_000),
+				CallGasLimit:         types.NumberFromUint64(200_000),
+			}
+		},
+		"eth_sendUserOperation": func(args ...any) any {
+			sent = *args[0].(*UserOperation)
+			return userOpHash
+		},
+		"eth_getUserOperationReceipt": func(...any) any {
+			// Not included on the first poll.
+			if polls++; polls == 1 {
+				return nil
+			}
+			return userOpReceipt{UserOpHash: userOpHash, Success: true, Receipt: &types.TransactionReceipt{
+				TransactionHash: txHash,
+				Status:          &status,
+				BlockNumber:     big.NewInt(200),
+				Logs:            []types.Log{{Address: address, Topics: []types.Hash{challenged}}},
+			}}
+		},
+	}}
+
+	submitter := NewUserOpSubmitter(context.Background(), client, bundler, owner, sender, DefaultEntryPoint, 1)
+	p := submitter.Wrap(address, nil)
+	assert.Equal(t, sender, p.GetFrom(context.TODO()))
+
+	hash, _, err := p.ChallengePoke(context.TODO(), address, &OpPokedEvent{BlockNumber: big.NewInt(100)})
+	require.NoError(t, err)
+	assert.Equal(t, userOpHash, *hash)
+
+	assert.Equal(t, sender, sent.Sender)
+	assert.Equal(t, big.NewInt(7), sent.Nonce)
+	assert.Equal(t, big.NewInt(21), sent.MaxFeePerGas)
+	assert.Equal(t, big.NewInt(1), sent.MaxPriorityFeePerGas)
+	assert.Equal(t, big.NewInt(200_000), sent.CallGasLimit)
+	assert.Equal(t, smartAccountExecute.FourBytes().Bytes(), sent.CallData[:4])
+
+	// Signature of the user operation hash recovers to the owner.
+	opHash, err := sent.Hash(DefaultEntryPoint, 1)
+	require.NoError(t, err)
+	signature, err := types.SignatureFromBytes(sent.Signature)
+	require.NoError(t, err)
+	assert.True(t, owner.VerifyMessage(context.TODO(), opHash.Bytes(), signature))
+
+	select {
+	case outcome := <-p.ChallengeOutcomes():
+		require.NoError(t, outcome.Err)
+		assert.Equal(t, txHash, *outcome.Hash)
+	case <-time.After(time.Second):
+		t.Fatal("no outcome")
+	}
+
+	// Gas is estimated with the dummy signature, the signed hash depends on the chain.
+	var params []json.RawMessage
+	require.NoError(t, json.Unmarshal(bundler.calls["eth_estimateUserOperationGas"][0], &params))
+	var estimated struct {
+		Signature types.Bytes `json:"signature"`
+	}
+	require.NoError(t, json.Unmarshal(params[0], &estimated))
+	assert.Equal(t, dummyUserOpSignature, estimated.Signature)
+	otherHash, err := sent.Hash(DefaultEntryPoint, 10)
+	require.NoError(t, err)
+	assert.NotEqual(t, opHash, otherHash)
+}
+
+func TestUserOpSubmitterPaymaster(t *testing.T) {
+	address := types.MustAddressFromHex("0x1F7acDa376eF37EC371235a094113dF9Cb4EfEe1")
+	sender := types.MustAddressFromHex("0x3F7acDa376eF37EC371235a094113dF9Cb4EfEe3")
+	owner := wallet.NewKeyFromBytes(types.MustBytesFromHex("0x0101010101010101010101010101010101010101010101010101010101010101"))
+	paymasterData := types.MustBytesFromHex("0x4F7acDa376eF37EC371235a094113dF9Cb4EfEe4aabbcc")
+
+	client := new(mockRpcClient)
+	nonce, err := abi.EncodeValues(entryPointGetNonce.Outputs(), big.NewInt(0))
+	require.NoError(t, err)
+	client.On("Call", mock.Anything, mock.Anything, types.LatestBlockNumber).Return(nonce, nil, nil)
+
+	bundler := &fakeBundler{responses: map[string]func(args ...any) any{
+		"eth_gasPrice": func(...any) any { return types.NumberFromUint64(10) },
+	}}
+	paymaster := &fakeBundler{responses: map[string]func(args ...any) any{
+		"pm_sponsorUserOperation": func(...any) any {
+			return userOpGas{
+				PreVerificationGas:   types.NumberFromUint64(50_000),
+				VerificationGasLimit: types.NumberFromUint64(100_000),
+				CallGasLimit:         types.NumberFromUint64(200_000),
+				PaymasterAndData:     paymasterData,
+			}
+		},
+	}}
+
+	submitter := NewUserOpSubmitter(context.Background(), client, bundler, owner, sender, DefaultEntryPoint, 1)
+	submitter.Paymaster = paymaster
+	submitter.PriorityFee = big.NewInt(2)
+	op, err := submitter.buildUserOp(context.TODO(), address, &OpPokedEvent{BlockNumber: big.NewInt(100)})
+	require.NoError(t, err)
+	assert.Equal(t, []byte(paymasterData), op.PaymasterAndData)
+	assert.Equal(t, big.NewInt(22), op.MaxFeePerGas)
+	assert.NotContains(t, bundler.calls, "eth_maxPriorityFeePerGas")
+	assert.NotContains(t, bundler.calls, "eth_estimateUserOperationGas")
+}
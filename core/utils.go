@@ -2,24 +2,48 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/big"
 	"time"
 
 	"github.com/defiweb/go-eth/types"
 	logger "github.com/sirupsen/logrus"
 )
 
-// txConfirmationPollInterval is the polling interval for checking transaction confirmations.
-// Defaults to ~1 block time. Overridden in tests for fast execution.
-var txConfirmationPollInterval = 12 * time.Second
+// TxConfirmationPollInterval is the polling interval for checking transaction confirmations.
+// Defaults to ~1 block time on mainnet. Overridden in tests for fast execution.
+var TxConfirmationPollInterval = 12 * time.Second
+
+// ErrTxNotIncluded is returned by WaitForTxInclusion when the transaction was not mined before the deadline block.
+var ErrTxNotIncluded = errors.New("transaction not included")
 
 // WaitForTxConfirmation waits for the transaction to be confirmed.
+// If the transaction reverted, the receipt is returned together with ErrTxReverted.
+// If the client supports new heads subscription, the receipt is checked on every new block,
+// polling is kept as a fallback in case the subscription drops. Polling and the timeout use the clock
+// of the context, see ContextWithClock.
 func WaitForTxConfirmation(
 	ctx context.Context,
 	client RPCClient,
 	txHash *types.Hash,
 	timeout time.Duration,
 ) (*types.TransactionReceipt, error) {
+	return WaitForTxInclusion(ctx, client, txHash, nil, timeout)
+}
+
+// WaitForTxInclusion works as WaitForTxConfirmation, but if deadline is given, it also gives up
+// with ErrTxNotIncluded once the chain reaches the deadline block without the transaction being mined.
+func WaitForTxInclusion(
+	ctx context.Context,
+	client RPCClient,
+	txHash *types.Hash,
+	deadline *big.Int,
+	timeout time.Duration,
+) (receipt *types.TransactionReceipt, err error) {
+	ctx, span := tracer.Start(ctx, "WaitForTxConfirmation")
+	defer func() { endSpan(span, err) }()
+
 	if client == nil {
 		return nil, fmt.Errorf("ethereum client not set")
 	}
@@ -28,33 +52,68 @@ func WaitForTxConfirmation(
 	}
 
 	// check +- every block
-	ticker := time.NewTicker(txConfirmationPollInterval)
+	clock := clockFromContext(ctx)
+	ticker := clock.NewTicker(TxConfirmationPollInterval)
 	defer ticker.Stop()
 
-	ctx, cancel := context.WithTimeout(ctx, timeout)
+	timer := clock.NewTimer(timeout)
+	defer timer.Stop()
+
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	var heads <-chan types.Block
+	if s, ok := client.(HeadSubscriber); ok {
+		var subErr error
+		heads, subErr = s.SubscribeNewHeads(ctx)
+		if subErr != nil {
+			logger.WithField("txHash", txHash).Debugf("new heads subscription not available, polling: %v", subErr)
+			heads = nil
+		}
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to wait for transaction confirmation: %w", ctx.Err())
+		case <-timer.C():
 			return nil, fmt.Errorf("failed to wait for transaction confirmation")
-		case <-ticker.C:
-			logger.WithField("txHash", txHash).Tracef("checking transaction confirmation")
-
-			receipt, err := client.GetTransactionReceipt(ctx, *txHash)
-			if err != nil {
-				logger.WithField("txHash", txHash).Errorf("failed to get transaction receipt: %v", err)
-				continue
-			}
-			if receipt == nil {
+		case _, ok := <-heads:
+			if !ok {
+				// Subscription closed, keep polling.
+				heads = nil
 				continue
 			}
+		case <-ticker.C():
+		}
 
-			if receipt.Status == nil || receipt.TransactionHash.IsZero() {
-				logger.WithField("txHash", txHash).Tracef("transaction is not yet confirmed")
-				continue
+		logger.WithField("txHash", txHash).Tracef("checking transaction confirmation")
+
+		receipt, err := client.GetTransactionReceipt(ctx, *txHash)
+		if err != nil {
+			logger.WithField("txHash", txHash).Errorf("failed to get transaction receipt: %v", err)
+			continue
+		}
+		if receipt == nil || receipt.Status == nil || receipt.TransactionHash.IsZero() {
+			logger.WithField("txHash", txHash).Tracef("transaction is not yet confirmed")
+			if deadline != nil && isBlockReached(ctx, client, deadline) {
+				return nil, fmt.Errorf("%w: %v until block %v", ErrTxNotIncluded, txHash, deadline)
 			}
-			return receipt, nil
+			continue
+		}
+		if *receipt.Status == 0 {
+			return receipt, fmt.Errorf("%w: %v", ErrTxReverted, txHash)
 		}
+		return receipt, nil
+	}
+}
+
+// isBlockReached returns true if the latest block is at or after the given block.
+func isBlockReached(ctx context.Context, client RPCClient, block *big.Int) bool {
+	latest, err := client.BlockNumber(ctx)
+	if err != nil {
+		logger.Errorf("failed to get latest block number: %v", err)
+		return false
 	}
+	return latest.Cmp(block) >= 0
 }
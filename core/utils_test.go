@@ -15,7 +15,7 @@ import (
 
 func init() {
 	// Speed up polling for tests.
-	txConfirmationPollInterval = 10 * time.Millisecond
+	TxConfirmationPollInterval = 10 * time.Millisecond
 }
 
 func TestWaitForTxConfirmation(t *testing.T) {
@@ -46,6 +46,17 @@ func TestWaitForTxConfirmation(t *testing.T) {
 		assert.ErrorContains(t, err, "failed to wait for transaction confirmation")
 	})
 
+	t.Run("cancellation returns context error", func(t *testing.T) {
+		client := new(mockRpcClient)
+		client.On("GetTransactionReceipt", mock.Anything, hash).
+			Return((*types.TransactionReceipt)(nil), nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		_, err := WaitForTxConfirmation(ctx, client, &hash, time.Hour)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
 	t.Run("successful receipt returned", func(t *testing.T) {
 		client := new(mockRpcClient)
 		expected := &types.TransactionReceipt{
@@ -160,4 +171,69 @@ func TestWaitForTxConfirmation(t *testing.T) {
 		assert.Nil(t, receipt)
 		assert.ErrorContains(t, err, "failed to wait for transaction confirmation")
 	})
+	t.Run("new head triggers receipt check", func(t *testing.T) {
+		orig := TxConfirmationPollInterval
+		TxConfirmationPollInterval = time.Hour
+		defer func() { TxConfirmationPollInterval = orig }()
+
+		heads := make(chan types.Block, 1)
+		client := &mockSubscribingClient{heads: heads}
+		confirmed := &types.TransactionReceipt{TransactionHash: hash, Status: &status}
+		client.On("GetTransactionReceipt", mock.Anything, hash).Return(confirmed, nil)
+
+		heads <- types.Block{Number: big.NewInt(101)}
+		receipt, err := WaitForTxConfirmation(context.TODO(), client, &hash, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, confirmed, receipt)
+	})
+
+	t.Run("falls back to polling if subscription is not supported", func(t *testing.T) {
+		client := &mockSubscribingClient{}
+		confirmed := &types.TransactionReceipt{TransactionHash: hash, Status: &status}
+		client.On("GetTransactionReceipt", mock.Anything, hash).Return(confirmed, nil)
+
+		receipt, err := WaitForTxConfirmation(context.TODO(), client, &hash, time.Second)
+		require.NoError(t, err)
+		assert.Equal(t, confirmed, receipt)
+	})
+
+	t.Run("reverted receipt returns error", func(t *testing.T) {
+		client := new(mockRpcClient)
+		failed := uint64(0)
+		reverted := &types.TransactionReceipt{TransactionHash: hash, Status: &failed}
+		client.On("GetTransactionReceipt", mock.Anything, hash).Return(reverted, nil)
+
+		receipt, err := WaitForTxConfirmation(context.TODO(), client, &hash, time.Second)
+		assert.ErrorIs(t, err, ErrTxReverted)
+		assert.Equal(t, reverted, receipt)
+	})
+}
+
+type mockSubscribingClient struct {
+	mockRpcClient
+	heads chan types.Block
+}
+
+func (m *mockSubscribingClient) SubscribeNewHeads(context.Context) (<-chan types.Block, error) {
+	if m.heads == nil {
+		return nil, fmt.Errorf("transport does not support subscriptions")
+	}
+	return m.heads, nil
+}
+
+func TestWaitForTxInclusion(t *testing.T) {
+	hash := types.MustHashFromHex("0xaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", types.PadNone)
+
+	t.Run("deadline block reached returns error", func(t *testing.T) {
+		client := new(mockRpcClient)
+		client.On("GetTransactionReceipt", mock.Anything, hash).
+			Return((*types.TransactionReceipt)(nil), nil)
+		client.On("BlockNumber", mock.Anything).Return(big.NewInt(99), nil).Once()
+		client.On("BlockNumber", mock.Anything).Return(big.NewInt(100), nil)
+
+		receipt, err := WaitForTxInclusion(context.TODO(), client, &hash, big.NewInt(100), time.Second)
+		assert.Nil(t, receipt)
+		assert.ErrorIs(t, err, ErrTxNotIncluded)
+		client.AssertNumberOfCalls(t, "BlockNumber", 2)
+	})
 }
@@ -0,0 +1,65 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/defiweb/go-eth/crypto"
+	logger "github.com/sirupsen/logrus"
+)
+
+// Build information, set at build time using:
+// -ldflags "-X github.com/chronicleprotocol/challenger/core.Version=... -X ...core.Commit=... -X ...core.BuildDate=..."
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// BuildInfo describes the running binary, so operators can audit what is actually deployed.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	ABIHash   string `json:"abiHash"`
+	GoVersion string `json:"goVersion"`
+}
+
+// GetBuildInfo returns build information of the binary.
+// If commit or build date were not set at build time, VCS information embedded by Go toolchain is used.
+func GetBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		ABIHash:   crypto.Keccak256(scribeOptimisticContractJSON).String(),
+		GoVersion: runtime.Version(),
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = s.Value
+			}
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
+
+// ServeHTTP serves build information as JSON.
+func (b BuildInfo) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(b); err != nil {
+		logger.WithError(err).Error("failed to encode build info")
+	}
+}
@@ -0,0 +1,32 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/defiweb/go-eth/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBuildInfo(t *testing.T) {
+	origVersion, origCommit, origDate := Version, Commit, BuildDate
+	defer func() { Version, Commit, BuildDate = origVersion, origCommit, origDate }()
+
+	Version, Commit, BuildDate = "1.2.3", "abcdef", "2024-01-01T00:00:00Z"
+	info := GetBuildInfo()
+	assert.Equal(t, "1.2.3", info.Version)
+	assert.Equal(t, "abcdef", info.Commit)
+	assert.Equal(t, "2024-01-01T00:00:00Z", info.BuildDate)
+	assert.Equal(t, crypto.Keccak256(scribeOptimisticContractJSON).String(), info.ABIHash)
+
+	rec := httptest.NewRecorder()
+	info.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/version", nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var served BuildInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &served))
+	assert.Equal(t, info, served)
+}